@@ -1,12 +1,16 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"healthsecure/configs"
 	"healthsecure/internal/database"
+	"healthsecure/internal/encryption"
+	"healthsecure/internal/storage"
 )
 
 func main() {
@@ -34,6 +38,10 @@ func main() {
 			checkMigrationStatus()
 		case "clean":
 			runCleanupTasks()
+		case "rekey":
+			runRekey(config, os.Args[2:])
+		case "reindex":
+			runReindex(os.Args[2:])
 		default:
 			printUsage()
 		}
@@ -71,6 +79,102 @@ func runCleanupTasks() {
 	log.Println("✅ Cleanup tasks completed successfully")
 }
 
+func runRekey(config *configs.Config, args []string) {
+	flags := flag.NewFlagSet("rekey", flag.ExitOnError)
+	rotate := flags.Bool("rotate", false, "create a new data key before re-encrypting")
+	rotateMaster := flags.Bool("rotate-master", false, "rotate the KMS master key and rewrap data keys")
+	batchSize := flags.Int("batch", 500, "rows per batch")
+	pause := flags.Duration("pause", 100*time.Millisecond, "pause between batches")
+	encryptPlaintext := flags.Bool("encrypt-plaintext", false, "encrypt values stored before field encryption was enabled")
+	flags.Parse(args)
+
+	keyring := encryption.Default()
+
+	if *rotateMaster {
+		kms, err := encryption.NewKMS(config.Encryption.KMSProvider, config.Encryption.MasterKeyPath, false)
+		if err != nil {
+			log.Fatalf("❌ Failed to open KMS: %v", err)
+		}
+		rotator, ok := kms.(encryption.MasterKeyRotator)
+		if !ok {
+			log.Fatalf("❌ KMS provider %s does not support master key rotation", config.Encryption.KMSProvider)
+		}
+		if err := rotator.RotateMasterKey(); err != nil {
+			log.Fatalf("❌ Master key rotation failed: %v", err)
+		}
+
+		keyring, err = encryption.LoadKeyring(database.GetDB(), kms, config.Encryption.Tenant)
+		if err != nil {
+			log.Fatalf("❌ Failed to reload data keys: %v", err)
+		}
+		encryption.SetDefault(keyring)
+
+		rewrapped, err := keyring.RewrapKeys()
+		if err != nil {
+			log.Fatalf("❌ Rewrapping data keys failed: %v", err)
+		}
+		log.Printf("Rewrapped %d data keys under master key %s", rewrapped, kms.KeyID())
+	}
+
+	if *rotate {
+		version, err := keyring.Rotate()
+		if err != nil {
+			log.Fatalf("❌ Data key rotation failed: %v", err)
+		}
+		log.Printf("Rotated to data key version %d", version)
+	}
+
+	log.Printf("Re-encrypting fields with data key version %d...", keyring.ActiveVersion())
+
+	opts := encryption.RekeyOptions{
+		BatchSize:        *batchSize,
+		Pause:            *pause,
+		EncryptPlaintext: *encryptPlaintext,
+		Progress: func(column encryption.EncryptedColumn, scanned, rekeyed int) {
+			log.Printf("  %s.%s: %d scanned, %d re-encrypted", column.Table, column.Column, scanned, rekeyed)
+		},
+	}
+	for _, column := range database.EncryptedColumns {
+		if _, err := keyring.RekeyColumn(database.GetDB(), column, opts); err != nil {
+			log.Fatalf("❌ Rekey failed: %v", err)
+		}
+	}
+
+	// Attachment blobs use the same data keys, so they must move too before
+	// the old keys are retired
+	attachmentStore, err := storage.Open(config.Storage)
+	if err != nil {
+		log.Fatalf("❌ Failed to open attachment storage: %v", err)
+	}
+	if _, err := keyring.RekeyBlobs(database.GetDB(), attachmentStore, database.EncryptedBlobs, opts); err != nil {
+		log.Fatalf("❌ Rekey failed: %v", err)
+	}
+
+	if err := keyring.RetireInactiveKeys(); err != nil {
+		log.Fatalf("❌ Failed to retire old data keys: %v", err)
+	}
+
+	log.Println("✅ Rekey completed successfully")
+}
+
+func runReindex(args []string) {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	batchSize := flags.Int("batch", 500, "rows per batch")
+	pause := flags.Duration("pause", 100*time.Millisecond, "pause between batches")
+	flags.Parse(args)
+
+	log.Println("Recomputing patient blind indexes...")
+
+	indexed, err := database.ReindexPatients(*batchSize, *pause, func(scanned int) {
+		log.Printf("  patients: %d indexed", scanned)
+	})
+	if err != nil {
+		log.Fatalf("❌ Reindex failed: %v", err)
+	}
+
+	log.Printf("✅ Reindexed %d patients", indexed)
+}
+
 func printUsage() {
 	fmt.Println("Usage: go run cmd/migrate/main.go [command]")
 	fmt.Println("")
@@ -78,10 +182,16 @@ func printUsage() {
 	fmt.Println("  up      Run database migrations (default)")
 	fmt.Println("  status  Check database status and connectivity")
 	fmt.Println("  clean   Run database cleanup tasks")
+	fmt.Println("  rekey   Re-encrypt PHI fields with the active data key")
+	fmt.Println("          [-rotate] [-rotate-master] [-encrypt-plaintext] [-batch 500] [-pause 100ms]")
+	fmt.Println("  reindex Recompute patient blind indexes for encrypted identifiers")
+	fmt.Println("          [-batch 500] [-pause 100ms]")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  go run cmd/migrate/main.go")
 	fmt.Println("  go run cmd/migrate/main.go up")
 	fmt.Println("  go run cmd/migrate/main.go status")
 	fmt.Println("  go run cmd/migrate/main.go clean")
+	fmt.Println("  go run cmd/migrate/main.go rekey -rotate")
+	fmt.Println("  go run cmd/migrate/main.go reindex")
 }
\ No newline at end of file
@@ -10,9 +10,14 @@ import (
 
 	"healthsecure/configs"
 	"healthsecure/internal/auth"
+	"healthsecure/internal/cds"
 	"healthsecure/internal/database"
 	"healthsecure/internal/handlers"
+	"healthsecure/internal/identifiers"
+	"healthsecure/internal/models"
 	"healthsecure/internal/services"
+	"healthsecure/internal/storage"
+	"healthsecure/internal/terminology"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,14 +38,69 @@ func main() {
 	// Start database cleanup scheduler
 	database.StartCleanupScheduler()
 
+	// Load the per-role field visibility policy
+	fieldPolicy, err := models.LoadFieldPolicy(config.Security.FieldPolicyPath)
+	if err != nil {
+		log.Fatalf("Failed to load field policy: %v", err)
+	}
+
+	// Load the offline drug interaction and allergy dataset
+	cdsDataset, err := cds.LoadDataset(config.CDS.DatasetPath)
+	if err != nil {
+		log.Fatalf("Failed to load CDS dataset: %v", err)
+	}
+	log.Printf("Loaded CDS dataset %s", cdsDataset.Version)
+
+	// Load the local ICD-10-CM and SNOMED CT code systems
+	terms, err := terminology.Load(config.Terminology.ICD10CMPath, config.Terminology.SNOMEDPath)
+	if err != nil {
+		log.Fatalf("Failed to load terminology: %v", err)
+	}
+	for system, version := range terms.Versions() {
+		log.Printf("Loaded %s code system %s", system, version)
+	}
+
+	// Open the attachment blob store
+	attachmentStore, err := storage.Open(config.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open attachment storage: %v", err)
+	}
+	log.Printf("Using %s attachment storage", config.Storage.Backend)
+
+	// Build the MRN format used for new patients
+	mrns, err := identifiers.NewMRNGenerator(config.Identifiers.MRNFormat, config.Identifiers.MRNCheckDigit, config.Identifiers.MRNFacility)
+	if err != nil {
+		log.Fatalf("Invalid MRN configuration: %v", err)
+	}
+
 	// Initialize services
 	jwtService := auth.NewJWTService(config)
 	oauthService := auth.NewOAuthService(config)
-	auditService := services.NewAuditService(database.GetDB())
+	auditService := services.NewAuditService(database.GetDB(), config.Accounting.IncludeTPO)
 	userService := services.NewUserService(database.GetDB(), jwtService, auditService)
-	patientService := services.NewPatientService(database.GetDB(), auditService)
-	medicalRecordService := services.NewMedicalRecordService(database.GetDB(), auditService)
+	careTeamService := services.NewCareTeamService(database.GetDB(), auditService)
+	consentService := services.NewConsentService(database.GetDB(), auditService, careTeamService)
+	patientService := services.NewPatientService(database.GetDB(), auditService, consentService, careTeamService, fieldPolicy, config, mrns)
+	patientIdentifierService := services.NewPatientIdentifierService(database.GetDB(), auditService, mrns)
+	medicalRecordService := services.NewMedicalRecordService(database.GetDB(), auditService, consentService, careTeamService, fieldPolicy, terms)
 	emergencyService := services.NewEmergencyService(database.GetDB(), auditService, config)
+	delegationService := services.NewDelegationService(database.GetDB(), auditService, careTeamService, config)
+	patientPurgeService := services.NewPatientPurgeService(database.GetDB(), auditService, attachmentStore, config)
+	patientMergeService := services.NewPatientMergeService(database.GetDB(), auditService, config)
+	recordSignoffService := services.NewRecordSignoffService(database.GetDB(), auditService, medicalRecordService, config)
+	amendmentService := services.NewAmendmentService(database.GetDB(), auditService, medicalRecordService)
+	notificationService := services.NewNotificationService(database.GetDB())
+	observationService := services.NewObservationService(database.GetDB(), auditService, careTeamService, fieldPolicy, notificationService)
+	encounterService := services.NewEncounterService(database.GetDB(), auditService, careTeamService, fieldPolicy)
+	terminologyService := services.NewTerminologyService(database.GetDB(), auditService, terms)
+	clinicalListService := services.NewClinicalListService(database.GetDB(), auditService, careTeamService, fieldPolicy, cds.NewChecker(cdsDataset))
+	noteTemplateService := services.NewNoteTemplateService(database.GetDB(), auditService)
+	attachmentService := services.NewAttachmentService(database.GetDB(), auditService, careTeamService, consentService, medicalRecordService, fieldPolicy, attachmentStore, config.Storage.MaxUploadSize)
+	proxyRules := models.ProxyAgeRules{RestrictedAge: config.Proxy.RestrictedAge, AdultAge: config.Proxy.AdultAge}
+	patientProxyService := services.NewPatientProxyService(database.GetDB(), auditService, proxyRules)
+	portalService := services.NewPortalService(database.GetDB(), auditService, fieldPolicy, proxyRules)
+	chartExportService := services.NewChartExportService(database.GetDB(), auditService, consentService, careTeamService, fieldPolicy)
+	fhirService := services.NewFHIRService(database.GetDB(), auditService, patientService, clinicalListService, observationService)
 
 	// Set Gin mode based on environment
 	if config.IsProduction() {
@@ -86,6 +146,25 @@ func main() {
 	emergencyHandler := handlers.NewEmergencyHandler(emergencyService, jwtService)
 	auditHandler := handlers.NewAuditHandler(auditService, jwtService)
 	adminHandler := handlers.NewAdminHandler(userService, auditService, jwtService)
+	consentHandler := handlers.NewConsentHandler(consentService, jwtService)
+	careTeamHandler := handlers.NewCareTeamHandler(careTeamService, jwtService)
+	delegationHandler := handlers.NewDelegationHandler(delegationService, jwtService)
+	patientPurgeHandler := handlers.NewPatientPurgeHandler(patientPurgeService, jwtService)
+	patientMergeHandler := handlers.NewPatientMergeHandler(patientMergeService, jwtService)
+	patientIdentifierHandler := handlers.NewPatientIdentifierHandler(patientIdentifierService, jwtService)
+	recordSignoffHandler := handlers.NewRecordSignoffHandler(recordSignoffService, jwtService)
+	amendmentHandler := handlers.NewAmendmentHandler(amendmentService, jwtService)
+	clinicalListHandler := handlers.NewClinicalListHandler(clinicalListService, jwtService)
+	observationHandler := handlers.NewObservationHandler(observationService, jwtService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, jwtService)
+	encounterHandler := handlers.NewEncounterHandler(encounterService, jwtService)
+	terminologyHandler := handlers.NewTerminologyHandler(terminologyService, jwtService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, jwtService)
+	noteTemplateHandler := handlers.NewNoteTemplateHandler(noteTemplateService, jwtService)
+	portalHandler := handlers.NewPortalHandler(portalService, jwtService)
+	patientProxyHandler := handlers.NewPatientProxyHandler(patientProxyService, jwtService)
+	chartExportHandler := handlers.NewChartExportHandler(chartExportService, jwtService)
+	fhirHandler := handlers.NewFHIRHandler(fhirService)
 
 	// API routes
 	api := router.Group("/api")
@@ -108,25 +187,155 @@ func main() {
 		// Patient routes
 		patients := api.Group("/patients")
 		patients.Use(auth.AuthMiddleware(jwtService))
-		patients.Use(auth.MedicalStaffOnly())
+		patients.Use(auth.PatientDataOnly())
+		patients.Use(auth.ResolvePatientID(config, patientService))
 		{
 			patients.GET("", patientHandler.GetPatients)
-			patients.POST("", patientHandler.CreatePatient)
-			patients.GET("/:id", patientHandler.GetPatient)
-			patients.PUT("/:id", patientHandler.UpdatePatient)
-			patients.DELETE("/:id", auth.AdminOnly(), patientHandler.DeletePatient)
-			patients.GET("/:id/records", medicalRecordHandler.GetPatientMedicalRecords)
+			patients.POST("", auth.MedicalStaffOnly(), patientHandler.CreatePatient)
+			patients.GET("/:id", auth.RequirePurposeOfUse(config, models.ResourcePatient), patientHandler.GetPatient)
+			patients.PUT("/:id", auth.MedicalStaffOnly(), patientHandler.UpdatePatient)
+			patients.GET("/:id/revisions", auth.RequirePurposeOfUse(config, models.ResourcePatient), patientHandler.GetPatientRevisions)
+			patients.GET("/:id/as-of", auth.RequirePurposeOfUse(config, models.ResourcePatient), patientHandler.GetPatientAsOf)
+			patients.GET("/:id/diff", auth.RequirePurposeOfUse(config, models.ResourcePatient), patientHandler.DiffPatientRevisions)
+			patients.GET("/:id/records", auth.MedicalRecordsOnly(), auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), medicalRecordHandler.GetPatientMedicalRecords)
 			patients.POST("/:id/records", auth.DoctorOnly(), medicalRecordHandler.CreateMedicalRecord)
+			patients.GET("/:id/consents", auth.MedicalStaffOnly(), consentHandler.GetPatientConsents)
+			patients.POST("/:id/consents", auth.DoctorOnly(), consentHandler.RecordConsent)
+			patients.GET("/:id/care-team", auth.MedicalStaffOnly(), careTeamHandler.GetCareTeam)
+			patients.POST("/:id/care-team", auth.DoctorOnly(), careTeamHandler.AddMember)
+			patients.DELETE("/:id/care-team/:userId", auth.DoctorOnly(), careTeamHandler.RemoveMember)
+			patients.GET("/:id/medications", auth.RequirePurposeOfUse(config, models.ResourceMedication), clinicalListHandler.GetMedications)
+			patients.POST("/:id/medications", auth.DoctorOnly(), clinicalListHandler.CreateMedication)
+			patients.PUT("/:id/medications/:medicationId", auth.DoctorOnly(), clinicalListHandler.UpdateMedication)
+			patients.DELETE("/:id/medications/:medicationId", auth.DoctorOnly(), clinicalListHandler.DeleteMedication)
+			patients.GET("/:id/allergies", auth.RequirePurposeOfUse(config, models.ResourceAllergy), clinicalListHandler.GetAllergies)
+			patients.POST("/:id/allergies", auth.DoctorOnly(), clinicalListHandler.CreateAllergy)
+			patients.PUT("/:id/allergies/:allergyId", auth.DoctorOnly(), clinicalListHandler.UpdateAllergy)
+			patients.DELETE("/:id/allergies/:allergyId", auth.DoctorOnly(), clinicalListHandler.DeleteAllergy)
+			patients.GET("/:id/problems", auth.RequirePurposeOfUse(config, models.ResourceProblem), clinicalListHandler.GetProblems)
+			patients.POST("/:id/problems", auth.DoctorOnly(), clinicalListHandler.CreateProblem)
+			patients.PUT("/:id/problems/:problemId", auth.DoctorOnly(), clinicalListHandler.UpdateProblem)
+			patients.DELETE("/:id/problems/:problemId", auth.DoctorOnly(), clinicalListHandler.DeleteProblem)
+			patients.GET("/:id/observations", auth.RequirePurposeOfUse(config, models.ResourceObservation), observationHandler.GetObservations)
+			patients.GET("/:id/observations/trend", auth.RequirePurposeOfUse(config, models.ResourceObservation), observationHandler.GetObservationTrend)
+			patients.POST("/:id/observations", auth.MedicalStaffOnly(), observationHandler.RecordObservation)
+			patients.DELETE("/:id/observations/:observationId", auth.MedicalStaffOnly(), observationHandler.DeleteObservation)
+			patients.GET("/:id/encounters", auth.RequirePurposeOfUse(config, models.ResourceEncounter), encounterHandler.GetPatientEncounters)
+			patients.POST("/:id/encounters", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), encounterHandler.CreateEncounter)
+			patients.GET("/:id/attachments", auth.RequirePurposeOfUse(config, models.ResourceAttachment), attachmentHandler.GetAttachments)
+			patients.POST("/:id/attachments", auth.MedicalStaffOnly(), attachmentHandler.UploadAttachment)
+			patients.GET("/:id/attachments/:attachmentId", auth.RequirePurposeOfUse(config, models.ResourceAttachment), attachmentHandler.DownloadAttachment)
+			patients.DELETE("/:id/attachments/:attachmentId", auth.MedicalStaffOnly(), attachmentHandler.DeleteAttachment)
+			patients.POST("/:id/identifiers", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), patientIdentifierHandler.AddIdentifier)
+			patients.POST("/:id/identifiers/mrn", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), patientIdentifierHandler.AssignMRN)
+			patients.DELETE("/:id/identifiers/:identifierId", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), patientIdentifierHandler.DeleteIdentifier)
+			patients.GET("/:id/proxies", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), patientProxyHandler.GetProxies)
+			patients.POST("/:id/proxies", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), patientProxyHandler.CreateProxy)
+			patients.POST("/:id/proxies/:proxyId/revoke", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), patientProxyHandler.RevokeProxy)
+			patients.POST("/:id/export", auth.MedicalStaffOnly(), chartExportHandler.ExportChart)
 			patients.GET("/search", patientHandler.SearchPatients)
+			patients.GET("/lookup", auth.RequirePurposeOfUse(config, models.ResourcePatient), patientHandler.LookupPatient)
+		}
+
+		// Patient lifecycle routes are admin-only, outside the patient data roles
+		patientAdmin := api.Group("/patients")
+		patientAdmin.Use(auth.AuthMiddleware(jwtService))
+		patientAdmin.Use(auth.AdminOnly())
+		patientAdmin.Use(auth.ResolvePatientID(config, patientService))
+		{
+			patientAdmin.DELETE("/:id", patientHandler.DeletePatient)
+			patientAdmin.POST("/:id/restore", patientHandler.RestorePatient)
+			patientAdmin.POST("/:id/purge", patientPurgeHandler.RequestPurge)
 		}
 
 		// Medical records routes
 		records := api.Group("/records")
 		records.Use(auth.AuthMiddleware(jwtService))
-		records.Use(auth.MedicalStaffOnly())
+		records.Use(auth.MedicalRecordsOnly())
 		{
-			records.GET("/:id", medicalRecordHandler.GetMedicalRecord)
+			records.GET("/:id", auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), medicalRecordHandler.GetMedicalRecord)
 			records.PUT("/:id", auth.DoctorOnly(), medicalRecordHandler.UpdateMedicalRecord)
+			records.GET("/:id/revisions", auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), medicalRecordHandler.GetMedicalRecordRevisions)
+			records.GET("/:id/as-of", auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), medicalRecordHandler.GetMedicalRecordAsOf)
+			records.GET("/:id/diff", auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), medicalRecordHandler.DiffMedicalRecordRevisions)
+			records.GET("/work-queue", auth.DoctorOnly(), recordSignoffHandler.GetWorkQueue)
+			records.POST("/:id/sign", auth.DoctorOnly(), recordSignoffHandler.SignMedicalRecord)
+			records.POST("/:id/cosign", auth.DoctorOnly(), recordSignoffHandler.CosignMedicalRecord)
+			records.GET("/:id/addenda", auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), recordSignoffHandler.GetAddenda)
+			records.POST("/:id/addenda", auth.DoctorOnly(), recordSignoffHandler.AddAddendum)
+			records.GET("/:id/amendments", auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), amendmentHandler.GetRecordAmendments)
+			records.POST("/:id/amendments", auth.MedicalStaffOnly(), amendmentHandler.SubmitAmendment)
+		}
+
+		// Patient amendment request routes
+		amendments := api.Group("/amendments")
+		amendments.Use(auth.AuthMiddleware(jwtService))
+		amendments.Use(auth.StaffOnly())
+		{
+			amendments.GET("", auth.RequireRole(models.RoleDoctor, models.RoleAdmin), amendmentHandler.GetAmendments)
+			amendments.GET("/:id", auth.MedicalRecordsOnly(), auth.RequirePurposeOfUse(config, models.ResourceMedicalRecord), amendmentHandler.GetAmendment)
+			amendments.POST("/:id/review", auth.DoctorOnly(), amendmentHandler.StartReview)
+			amendments.POST("/:id/extend", auth.DoctorOnly(), amendmentHandler.ExtendDeadline)
+			amendments.POST("/:id/accept", auth.DoctorOnly(), amendmentHandler.AcceptAmendment)
+			amendments.POST("/:id/deny", auth.DoctorOnly(), amendmentHandler.DenyAmendment)
+			amendments.POST("/:id/disagreement", auth.MedicalStaffOnly(), amendmentHandler.FileDisagreement)
+		}
+
+		// Encounter routes
+		encounters := api.Group("/encounters")
+		encounters.Use(auth.AuthMiddleware(jwtService))
+		encounters.Use(auth.PatientDataOnly())
+		{
+			encounters.GET("/census", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), encounterHandler.GetCensus)
+			encounters.GET("/:id", auth.RequirePurposeOfUse(config, models.ResourceEncounter), encounterHandler.GetEncounter)
+			encounters.PUT("/:id", auth.RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk), encounterHandler.UpdateEncounter)
+			encounters.POST("/:id/discharge", auth.MedicalStaffOnly(), encounterHandler.DischargeEncounter)
+		}
+
+		// Terminology routes; codes and aggregate reports carry no patient data
+		terminologyRoutes := api.Group("/terminology")
+		terminologyRoutes.Use(auth.AuthMiddleware(jwtService))
+		terminologyRoutes.Use(auth.StaffOnly())
+		{
+			terminologyRoutes.GET("/search", terminologyHandler.SearchCodes)
+			terminologyRoutes.GET("/reports/diagnoses", auth.RequireRole(models.RoleDoctor, models.RoleBilling), terminologyHandler.GetDiagnosisReport)
+			terminologyRoutes.GET("/:system/:code", terminologyHandler.LookupCode)
+		}
+
+		// Note template routes; definitions carry no patient data
+		noteTemplates := api.Group("/note-templates")
+		noteTemplates.Use(auth.AuthMiddleware(jwtService))
+		noteTemplates.Use(auth.StaffOnly())
+		{
+			noteTemplates.GET("", noteTemplateHandler.GetTemplates)
+			noteTemplates.GET("/:id", noteTemplateHandler.GetTemplate)
+		}
+
+		// Notification routes
+		notifications := api.Group("/notifications")
+		notifications.Use(auth.AuthMiddleware(jwtService))
+		notifications.Use(auth.StaffOnly())
+		{
+			notifications.GET("", notificationHandler.GetNotifications)
+			notifications.POST("/:id/read", notificationHandler.MarkNotificationRead)
+		}
+
+		// Consent routes
+		consents := api.Group("/consents")
+		consents.Use(auth.AuthMiddleware(jwtService))
+		consents.Use(auth.DoctorOnly())
+		{
+			consents.POST("/:id/revoke", consentHandler.RevokeConsent)
+		}
+
+		// Delegated access routes
+		delegations := api.Group("/delegations")
+		delegations.Use(auth.AuthMiddleware(jwtService))
+		delegations.Use(auth.MedicalStaffOnly())
+		{
+			delegations.GET("", delegationHandler.GetMyDelegations)
+			delegations.POST("", auth.DoctorOnly(), delegationHandler.CreateDelegation)
+			delegations.POST("/:id/revoke", delegationHandler.RevokeDelegation)
 		}
 
 		// Emergency access routes
@@ -145,10 +354,12 @@ func main() {
 		// Audit routes
 		audit := api.Group("/audit")
 		audit.Use(auth.AuthMiddleware(jwtService))
+		audit.Use(auth.StaffOnly())
 		{
 			audit.GET("/logs", auditHandler.GetAuditLogs)
 			audit.GET("/users/:id", auditHandler.GetUserAuditHistory)
 			audit.GET("/patients/:id", auth.MedicalStaffOnly(), auditHandler.GetPatientAuditHistory)
+			audit.GET("/patients/:id/disclosures", auth.AdminOnly(), auth.ResolvePatientID(config, patientService), auditHandler.GetDisclosureReport)
 			audit.GET("/security-events", auth.AdminOnly(), auditHandler.GetSecurityEvents)
 			audit.POST("/security-events/:id/resolve", auth.AdminOnly(), auditHandler.ResolveSecurityEvent)
 			audit.GET("/statistics", auth.AdminOnly(), auditHandler.GetAuditStatistics)
@@ -166,6 +377,46 @@ func main() {
 			admin.POST("/users/:id/deactivate", adminHandler.DeactivateUser)
 			admin.GET("/users/:id/sessions", adminHandler.GetUserSessions)
 			admin.GET("/dashboard/stats", adminHandler.GetDashboardStats)
+			admin.GET("/delegations", delegationHandler.GetDelegations)
+			admin.POST("/delegations/:id/approve", delegationHandler.ApproveDelegation)
+			admin.POST("/delegations/:id/reject", delegationHandler.RejectDelegation)
+			admin.POST("/delegations/:id/revoke", delegationHandler.RevokeDelegation)
+			admin.GET("/patients/deleted", patientPurgeHandler.GetDeletedPatients)
+			admin.GET("/purge-requests", patientPurgeHandler.GetPurgeRequests)
+			admin.POST("/purge-requests/:id/approve", patientPurgeHandler.ApprovePurge)
+			admin.POST("/purge-requests/:id/reject", patientPurgeHandler.RejectPurge)
+			admin.GET("/patients/:id/duplicates", auth.ResolvePatientID(config, patientService), patientMergeHandler.GetDuplicates)
+			admin.POST("/patients/:id/care-team", auth.ResolvePatientID(config, patientService), careTeamHandler.AddMember)
+			admin.DELETE("/patients/:id/care-team/:userId", auth.ResolvePatientID(config, patientService), careTeamHandler.RemoveMember)
+			admin.POST("/patient-merges", patientMergeHandler.MergePatients)
+			admin.GET("/patient-merges", patientMergeHandler.GetMerges)
+			admin.POST("/patient-merges/:id/undo", patientMergeHandler.UndoMerge)
+			admin.POST("/note-templates", noteTemplateHandler.CreateTemplate)
+			admin.POST("/note-templates/:key/versions", noteTemplateHandler.PublishVersion)
+			admin.POST("/note-templates/:key/retire", noteTemplateHandler.RetireTemplate)
+		}
+
+		// Patient portal routes; a portal account only ever sees its own chart
+		portal := api.Group("/portal")
+		portal.Use(auth.AuthMiddleware(jwtService))
+		portal.Use(auth.PatientPortalOnly())
+		{
+			portal.GET("/me", portalHandler.GetMyPatient)
+			portal.GET("/records", portalHandler.GetMyRecords)
+			portal.GET("/medications", portalHandler.GetMyMedications)
+			portal.GET("/results", portalHandler.GetMyResults)
+			portal.GET("/access-log", portalHandler.GetMyAccessLog)
+			portal.GET("/dependents", portalHandler.GetMyDependents)
+
+			// Charts viewed as a proxy; each read is checked against the grant
+			dependents := portal.Group("/dependents/:id")
+			dependents.Use(auth.ResolvePatientID(config, patientService))
+			{
+				dependents.GET("", portalHandler.GetMyPatient)
+				dependents.GET("/records", portalHandler.GetMyRecords)
+				dependents.GET("/medications", portalHandler.GetMyMedications)
+				dependents.GET("/results", portalHandler.GetMyResults)
+			}
 		}
 
 		// User profile routes
@@ -179,6 +430,31 @@ func main() {
 		}
 	}
 
+	// FHIR R4 routes for partner systems. The capability statement is public;
+	// everything else takes the same tokens, roles and purposes as /api.
+	fhirRoutes := router.Group("/fhir/R4")
+	{
+		fhirRoutes.GET("/metadata", fhirHandler.GetCapabilityStatement)
+
+		resources := fhirRoutes.Group("")
+		resources.Use(auth.AuthMiddleware(jwtService))
+		{
+			resources.GET("/Patient", auth.PatientDataOnly(), fhirHandler.SearchPatients)
+			resources.GET("/Patient/:id", auth.PatientDataOnly(), auth.RequirePurposeOfUse(config, models.ResourcePatient), fhirHandler.ReadPatient)
+			resources.POST("/Patient", auth.MedicalStaffOnly(), fhirHandler.CreatePatient)
+			resources.PUT("/Patient/:id", auth.MedicalStaffOnly(), fhirHandler.UpdatePatient)
+			resources.GET("/Condition", auth.PatientDataOnly(), auth.RequirePurposeOfUse(config, models.ResourceProblem), fhirHandler.SearchConditions)
+			resources.GET("/Condition/:id", auth.PatientDataOnly(), auth.RequirePurposeOfUse(config, models.ResourceProblem), fhirHandler.ReadCondition)
+			resources.GET("/Observation", auth.PatientDataOnly(), auth.RequirePurposeOfUse(config, models.ResourceObservation), fhirHandler.SearchObservations)
+			resources.GET("/Observation/:id", auth.PatientDataOnly(), auth.RequirePurposeOfUse(config, models.ResourceObservation), fhirHandler.ReadObservation)
+			resources.POST("/Observation", auth.MedicalStaffOnly(), fhirHandler.CreateObservation)
+			resources.GET("/MedicationStatement", auth.PatientDataOnly(), auth.RequirePurposeOfUse(config, models.ResourceMedication), fhirHandler.SearchMedicationStatements)
+			resources.GET("/MedicationStatement/:id", auth.PatientDataOnly(), auth.RequirePurposeOfUse(config, models.ResourceMedication), fhirHandler.ReadMedicationStatement)
+			resources.GET("/AuditEvent", auth.StaffOnly(), fhirHandler.SearchAuditEvents)
+			resources.GET("/AuditEvent/:id", auth.StaffOnly(), fhirHandler.ReadAuditEvent)
+		}
+	}
+
 	// Metrics endpoint (if monitoring is enabled)
 	if config.Monitoring.Enabled {
 		router.GET("/metrics", func(c *gin.Context) {
@@ -29,6 +29,39 @@ type Config struct {
 	// Emergency access configuration
 	Emergency EmergencyConfig `mapstructure:"emergency"`
 	
+	// Delegated access configuration
+	Delegation DelegationConfig `mapstructure:"delegation"`
+	
+	// Field encryption configuration
+	Encryption EncryptionConfig `mapstructure:"encryption"`
+	
+	// Data retention configuration
+	Retention RetentionConfig `mapstructure:"retention"`
+	
+	// Accounting of disclosures configuration
+	Accounting AccountingConfig `mapstructure:"accounting"`
+	
+	// Medical record sign-off configuration
+	Records RecordsConfig `mapstructure:"records"`
+	
+	// Clinical decision support configuration
+	CDS CDSConfig `mapstructure:"cds"`
+	
+	// Terminology configuration
+	Terminology TerminologyConfig `mapstructure:"terminology"`
+	
+	// Attachment storage configuration
+	Storage StorageConfig `mapstructure:"storage"`
+	
+	// Master patient index configuration
+	MPI MPIConfig `mapstructure:"mpi"`
+	
+	// Patient identifier configuration
+	Identifiers IdentifierConfig `mapstructure:"identifiers"`
+	
+	// Patient portal proxy configuration
+	Proxy ProxyConfig `mapstructure:"proxy"`
+	
 	// Application configuration
 	App AppConfig `mapstructure:"app"`
 	
@@ -68,9 +101,11 @@ type OAuth2Config struct {
 }
 
 type SecurityConfig struct {
-	BCryptCost         int           `mapstructure:"bcrypt_cost"`
-	RateLimitRequests  int           `mapstructure:"rate_limit_requests"`
-	RateLimitWindow    time.Duration `mapstructure:"rate_limit_window"`
+	BCryptCost               int           `mapstructure:"bcrypt_cost"`
+	RateLimitRequests        int           `mapstructure:"rate_limit_requests"`
+	RateLimitWindow          time.Duration `mapstructure:"rate_limit_window"`
+	FieldPolicyPath          string        `mapstructure:"field_policy_path"`
+	PurposeRequiredResources []string      `mapstructure:"purpose_required_resources"`
 }
 
 type EmergencyConfig struct {
@@ -78,6 +113,65 @@ type EmergencyConfig struct {
 	NotificationEmail   string        `mapstructure:"notification_email"`
 }
 
+type DelegationConfig struct {
+	RequireApproval bool          `mapstructure:"require_approval"`
+	MaxDuration     time.Duration `mapstructure:"max_duration"`
+}
+
+type EncryptionConfig struct {
+	KMSProvider   string `mapstructure:"kms_provider"`
+	MasterKeyPath string `mapstructure:"master_key_path"`
+	Tenant        string `mapstructure:"tenant"`
+}
+
+type RetentionConfig struct {
+	PatientPurgeAfter time.Duration `mapstructure:"patient_purge_after"`
+}
+
+type AccountingConfig struct {
+	IncludeTPO bool `mapstructure:"include_tpo"`
+}
+
+type RecordsConfig struct {
+	UnsignedDraftAfter time.Duration `mapstructure:"unsigned_draft_after"`
+}
+
+type CDSConfig struct {
+	DatasetPath string `mapstructure:"dataset_path"`
+}
+
+type TerminologyConfig struct {
+	ICD10CMPath string `mapstructure:"icd10cm_path"`
+	SNOMEDPath  string `mapstructure:"snomed_path"`
+}
+
+type StorageConfig struct {
+	Backend       string `mapstructure:"backend"`
+	LocalPath     string `mapstructure:"local_path"`
+	S3Endpoint    string `mapstructure:"s3_endpoint"`
+	S3Region      string `mapstructure:"s3_region"`
+	S3Bucket      string `mapstructure:"s3_bucket"`
+	S3AccessKey   string `mapstructure:"s3_access_key"`
+	S3SecretKey   string `mapstructure:"s3_secret_key"`
+	MaxUploadSize int64  `mapstructure:"max_upload_size"`
+}
+
+type MPIConfig struct {
+	MatchThreshold int `mapstructure:"match_threshold"`
+}
+
+type IdentifierConfig struct {
+	MRNFacility      string `mapstructure:"mrn_facility"`
+	MRNFormat        string `mapstructure:"mrn_format"`
+	MRNCheckDigit    string `mapstructure:"mrn_check_digit"`
+	RequirePublicIDs bool   `mapstructure:"require_public_ids"`
+}
+
+type ProxyConfig struct {
+	RestrictedAge int `mapstructure:"restricted_age"`
+	AdultAge      int `mapstructure:"adult_age"`
+}
+
 type AppConfig struct {
 	ServerPort  int      `mapstructure:"server_port"`
 	Environment string   `mapstructure:"environment"`
@@ -137,9 +231,11 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config.Security = SecurityConfig{
-		BCryptCost:        getEnvAsInt("BCRYPT_COST", 12),
-		RateLimitRequests: getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
-		RateLimitWindow:   getEnvAsDuration("RATE_LIMIT_WINDOW", "1h"),
+		BCryptCost:               getEnvAsInt("BCRYPT_COST", 12),
+		RateLimitRequests:        getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
+		RateLimitWindow:          getEnvAsDuration("RATE_LIMIT_WINDOW", "1h"),
+		FieldPolicyPath:          getEnv("FIELD_POLICY_PATH", ""),
+		PurposeRequiredResources: getEnvAsSlice("PURPOSE_REQUIRED_RESOURCES", nil),
 	}
 
 	config.Emergency = EmergencyConfig{
@@ -147,6 +243,78 @@ func LoadConfig() (*Config, error) {
 		NotificationEmail: getEnv("EMERGENCY_NOTIFICATION_EMAIL", "security@example.com"),
 	}
 
+	config.Delegation = DelegationConfig{
+		RequireApproval: getEnvAsBool("DELEGATION_REQUIRE_APPROVAL", false),
+		MaxDuration:     getEnvAsDuration("DELEGATION_MAX_DURATION", "168h"),
+	}
+
+	config.Encryption = EncryptionConfig{
+		KMSProvider:   getEnv("ENCRYPTION_KMS_PROVIDER", "local"),
+		MasterKeyPath: getEnv("ENCRYPTION_MASTER_KEY_PATH", "./keys/master.key"),
+		Tenant:        getEnv("ENCRYPTION_TENANT", "default"),
+	}
+
+	// Deleted charts are kept for six years before they may be purged
+	config.Retention = RetentionConfig{
+		PatientPurgeAfter: getEnvAsDuration("PATIENT_PURGE_AFTER", "52560h"),
+	}
+
+	// Treatment, payment and operations accesses are left out of a patient's
+	// accounting of disclosures unless local policy requires them
+	config.Accounting = AccountingConfig{
+		IncludeTPO: getEnvAsBool("ACCOUNTING_INCLUDE_TPO", false),
+	}
+
+	// Drafts left unsigned this long surface on the author's work queue
+	config.Records = RecordsConfig{
+		UnsignedDraftAfter: getEnvAsDuration("UNSIGNED_DRAFT_AFTER", "24h"),
+	}
+
+	// Interaction and allergy data; the built-in starter set when unset
+	config.CDS = CDSConfig{
+		DatasetPath: getEnv("CDS_DATASET_PATH", ""),
+	}
+
+	// Local code system files; the built-in starter subsets when unset
+	config.Terminology = TerminologyConfig{
+		ICD10CMPath: getEnv("TERMINOLOGY_ICD10CM_PATH", ""),
+		SNOMEDPath:  getEnv("TERMINOLOGY_SNOMED_PATH", ""),
+	}
+
+	// Attachment blobs; a local directory unless an S3-compatible store is set
+	config.Storage = StorageConfig{
+		Backend:       getEnv("STORAGE_BACKEND", "local"),
+		LocalPath:     getEnv("STORAGE_LOCAL_PATH", "./data/attachments"),
+		S3Endpoint:    getEnv("STORAGE_S3_ENDPOINT", ""),
+		S3Region:      getEnv("STORAGE_S3_REGION", "us-east-1"),
+		S3Bucket:      getEnv("STORAGE_S3_BUCKET", ""),
+		S3AccessKey:   getEnv("STORAGE_S3_ACCESS_KEY", ""),
+		S3SecretKey:   getEnv("STORAGE_S3_SECRET_KEY", ""),
+		MaxUploadSize: int64(getEnvAsInt("ATTACHMENT_MAX_SIZE_MB", 20)) << 20,
+	}
+
+	// Patients scoring at least this (out of 100) are reported as likely
+	// duplicates
+	config.MPI = MPIConfig{
+		MatchThreshold: getEnvAsInt("MPI_MATCH_THRESHOLD", 70),
+	}
+
+	// New patients get an MRN at the default facility. Requiring public IDs
+	// stops patient URLs accepting sequential database IDs.
+	config.Identifiers = IdentifierConfig{
+		MRNFacility:      getEnv("MRN_FACILITY", "MAIN"),
+		MRNFormat:        getEnv("MRN_FORMAT", "{facility}-{seq:7}{check}"),
+		MRNCheckDigit:    getEnv("MRN_CHECK_DIGIT", "luhn"),
+		RequirePublicIDs: getEnvAsBool("REQUIRE_PUBLIC_IDS", false),
+	}
+
+	// Parents and guardians get limited access from the restricted age and
+	// none once the patient is an adult
+	config.Proxy = ProxyConfig{
+		RestrictedAge: getEnvAsInt("PROXY_RESTRICTED_AGE", 13),
+		AdultAge:      getEnvAsInt("PROXY_ADULT_AGE", 18),
+	}
+
 	config.App = AppConfig{
 		ServerPort:  getEnvAsInt("SERVER_PORT", 8080),
 		Environment: getEnv("ENVIRONMENT", "development"),
@@ -222,6 +390,40 @@ func validateConfig(config *Config) error {
 		log.Printf("WARNING: BCrypt cost %d is very high, may impact performance", config.Security.BCryptCost)
 	}
 
+	// Storage validation
+	switch config.Storage.Backend {
+	case "local":
+		if config.Storage.LocalPath == "" {
+			return fmt.Errorf("local storage path is required")
+		}
+	case "s3":
+		if config.Storage.S3Endpoint == "" || config.Storage.S3Bucket == "" {
+			return fmt.Errorf("S3 storage endpoint and bucket are required")
+		}
+	default:
+		return fmt.Errorf("unknown storage backend %q", config.Storage.Backend)
+	}
+	if config.Storage.MaxUploadSize <= 0 {
+		return fmt.Errorf("attachment size limit must be positive")
+	}
+
+	// Duplicate matching validation
+	if config.MPI.MatchThreshold < 1 || config.MPI.MatchThreshold > 100 {
+		return fmt.Errorf("MPI match threshold must be between 1 and 100")
+	}
+
+	// MRN validation; the format itself is checked when the generator is built
+	switch config.Identifiers.MRNCheckDigit {
+	case "luhn", "mod11", "none":
+	default:
+		return fmt.Errorf("unknown MRN check digit algorithm %q", config.Identifiers.MRNCheckDigit)
+	}
+
+	// Proxy age validation
+	if config.Proxy.RestrictedAge < 0 || config.Proxy.RestrictedAge > config.Proxy.AdultAge {
+		return fmt.Errorf("proxy restricted age must be between 0 and the adult age")
+	}
+
 	// Production environment validation
 	if config.App.Environment == "production" {
 		if config.Database.TLSMode != "required" {
@@ -230,6 +432,9 @@ func validateConfig(config *Config) error {
 		if config.SSL.CertPath == "" || config.SSL.KeyPath == "" {
 			log.Println("WARNING: SSL certificates not configured for production")
 		}
+		if config.Encryption.KMSProvider == "local" {
+			log.Println("WARNING: Local file KMS is intended for development only")
+		}
 	}
 
 	return nil
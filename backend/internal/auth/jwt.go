@@ -23,11 +23,12 @@ const (
 )
 
 type Claims struct {
-	UserID   uint             `json:"user_id"`
-	Email    string           `json:"email"`
-	Role     models.UserRole  `json:"role"`
-	TokenID  string           `json:"token_id"`
-	Type     TokenType        `json:"type"`
+	UserID   uint                `json:"user_id"`
+	Email    string              `json:"email"`
+	Role     models.UserRole     `json:"role"`
+	TokenID  string              `json:"token_id"`
+	Type     TokenType           `json:"type"`
+	Purpose  models.PurposeOfUse `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -48,8 +49,9 @@ func NewJWTService(config *configs.Config) *JWTService {
 	}
 }
 
-// GenerateTokens creates both access and refresh tokens for a user
-func (j *JWTService) GenerateTokens(user *models.User) (*AuthResponse, error) {
+// GenerateTokens creates both access and refresh tokens for a user. The
+// purpose of use chosen at login, if any, is carried in both tokens.
+func (j *JWTService) GenerateTokens(user *models.User, purpose models.PurposeOfUse) (*AuthResponse, error) {
 	now := time.Now()
 	accessTokenID := uuid.New().String()
 	refreshTokenID := uuid.New().String()
@@ -61,6 +63,7 @@ func (j *JWTService) GenerateTokens(user *models.User) (*AuthResponse, error) {
 		Role:    user.Role,
 		TokenID: accessTokenID,
 		Type:    AccessToken,
+		Purpose: purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.config.JWT.Expires)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -78,6 +81,7 @@ func (j *JWTService) GenerateTokens(user *models.User) (*AuthResponse, error) {
 		Role:    user.Role,
 		TokenID: refreshTokenID,
 		Type:    RefreshToken,
+		Purpose: purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.config.JWT.RefreshTokenExpires)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -167,7 +171,7 @@ func (j *JWTService) RefreshAccessToken(refreshTokenString string) (*AuthRespons
 	}
 
 	// Generate new tokens (both access and refresh for security)
-	tokens, err := j.GenerateTokens(&user)
+	tokens, err := j.GenerateTokens(&user, claims.Purpose)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new tokens: %w", err)
 	}
@@ -45,11 +45,28 @@ func AuthMiddleware(jwtService *JWTService) gin.HandlerFunc {
 			return
 		}
 
+		// A purpose declared on the request overrides the one chosen at login
+		purpose := claims.Purpose
+		if header := c.GetHeader("X-Purpose-Of-Use"); header != "" {
+			purpose, err = models.ParsePurposeOfUse(header)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+		}
+		if purpose == "" && c.GetHeader("X-Emergency-Access-Token") != "" {
+			purpose = models.PurposeTreatment
+		}
+
 		// Set user context for downstream handlers
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", string(claims.Role))
 		c.Set("token_id", claims.TokenID)
+		c.Set("purpose_of_use", string(purpose))
 
 		c.Next()
 	}
@@ -154,16 +171,100 @@ func AdminOnly() gin.HandlerFunc {
 	return RequireRole(models.RoleAdmin)
 }
 
+// StaffOnly middleware admits workforce roles and keeps patient portal
+// accounts out of staff endpoints
+func StaffOnly() gin.HandlerFunc {
+	return RequireRole(models.StaffRoles...)
+}
+
+// PatientPortalOnly middleware restricts access to patient portal accounts
+func PatientPortalOnly() gin.HandlerFunc {
+	return RequireRole(models.RolePatient)
+}
+
 // MedicalStaffOnly middleware restricts access to doctors and nurses only
 func MedicalStaffOnly() gin.HandlerFunc {
 	return RequireRole(models.RoleDoctor, models.RoleNurse)
 }
 
+// PatientDataOnly middleware admits every role that may read some patient
+// fields; what each role actually sees is decided by the field policy
+func PatientDataOnly() gin.HandlerFunc {
+	return RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk, models.RoleBilling)
+}
+
+// MedicalRecordsOnly middleware admits roles that may read medical records
+func MedicalRecordsOnly() gin.HandlerFunc {
+	return RequireRole(models.RoleDoctor, models.RoleNurse, models.RoleBilling)
+}
+
+// RequirePurposeOfUse middleware rejects requests for resource that carry no
+// declared purpose when configuration requires one for it
+func RequirePurposeOfUse(config *configs.Config, resource string) gin.HandlerFunc {
+	required := false
+	for _, r := range config.Security.PurposeRequiredResources {
+		if r == resource {
+			required = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		if required && c.GetString("purpose_of_use") == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "A purpose of use is required for this resource",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // DoctorOnly middleware restricts access to doctors only
 func DoctorOnly() gin.HandlerFunc {
 	return RequireRole(models.RoleDoctor)
 }
 
+// PatientIDResolver maps an opaque public patient ID to the database ID
+type PatientIDResolver interface {
+	ResolvePatientPublicID(publicID string) (uint, error)
+}
+
+// ResolvePatientID lets patient URLs name the patient by public ID instead of
+// the sequential database ID, rewriting the :id parameter for the handlers.
+// When public IDs are required, numeric IDs are refused so that charts cannot
+// be found by counting.
+func ResolvePatientID(config *configs.Config, resolver PatientIDResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for i, param := range c.Params {
+			if param.Key != "id" {
+				continue
+			}
+
+			if !models.IsPatientPublicID(param.Value) {
+				if config.Identifiers.RequirePublicIDs {
+					c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+					c.Abort()
+					return
+				}
+				break
+			}
+
+			patientID, err := resolver.ResolvePatientPublicID(param.Value)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+				c.Abort()
+				return
+			}
+			c.Params[i].Value = fmt.Sprintf("%d", patientID)
+			break
+		}
+
+		c.Next()
+	}
+}
+
 // RateLimitMiddleware implements basic rate limiting
 func RateLimitMiddleware(config *configs.Config) gin.HandlerFunc {
 	// This is a simplified rate limiter
@@ -206,7 +307,7 @@ func CORSMiddleware(config *configs.Config) gin.HandlerFunc {
 		}
 		
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Emergency-Access-Token, X-Access-Reason, X-Purpose-Of-Use")
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -198,7 +198,7 @@ func (o *OAuthService) HandleCallback(c *gin.Context) (*AuthResponse, error) {
 
 	// Generate JWT tokens
 	jwtService := NewJWTService(o.config)
-	tokens, err := jwtService.GenerateTokens(user)
+	tokens, err := jwtService.GenerateTokens(user, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
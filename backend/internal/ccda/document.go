@@ -0,0 +1,305 @@
+// Package ccda writes a patient's chart as an HL7 C-CDA R2.1 Continuity of
+// Care Document. Sections use the "entries optional" templates and carry the
+// chart as human-readable narrative; coded data is exchanged as FHIR.
+package ccda
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// OIDs of the code systems named in the document
+const (
+	oidLOINC           = "2.16.840.1.113883.6.1"
+	oidConfidentiality = "2.16.840.1.113883.5.25"
+	oidGender          = "2.16.840.1.113883.5.1"
+)
+
+// custodianName is the organisation that keeps the original record
+const custodianName = "HealthSecure"
+
+// Chart is the sanitized content of a document. A nil list was withheld from
+// the export; an empty one has nothing recorded.
+type Chart struct {
+	Patient      *models.Patient
+	Author       string
+	Recipient    string
+	Allergies    []models.Allergy
+	Medications  []models.MedicationOrder
+	Problems     []models.Problem
+	Observations []models.Observation
+	Records      []models.MedicalRecord
+	CreatedAt    time.Time
+}
+
+// Render writes the chart as a CCD
+func Render(chart *Chart) ([]byte, error) {
+	patient := chart.Patient
+	doc := clinicalDocument{
+		RealmCode: code{Code: "US"},
+		TypeID:    ii{Root: "2.16.840.1.113883.1.3", Extension: "POCD_HD000040"},
+		TemplateIDs: []ii{
+			{Root: "2.16.840.1.113883.10.20.22.1.1", Extension: "2015-08-01"},
+			{Root: "2.16.840.1.113883.10.20.22.1.2", Extension: "2015-08-01"},
+		},
+		ID:                  ii{Root: uuid.NewString()},
+		Code:                code{Code: "34133-9", CodeSystem: oidLOINC, CodeSystemName: "LOINC", DisplayName: "Summarization of Episode Note"},
+		Title:               "Continuity of Care Document",
+		EffectiveTime:       timestamp(chart.CreatedAt),
+		ConfidentialityCode: code{Code: "N", CodeSystem: oidConfidentiality, DisplayName: "normal"},
+		LanguageCode:        code{Code: "en-US"},
+		RecordTarget:        recordTarget{PatientRole: newPatientRole(patient)},
+		Author: author{
+			Time: timestamp(chart.CreatedAt),
+			AssignedAuthor: assignedAuthor{
+				ID:             ii{NullFlavor: "NI"},
+				AssignedPerson: &person{Name: personName{Text: chart.Author}},
+			},
+		},
+		Custodian: custodian{AssignedCustodian: assignedCustodian{RepresentedCustodianOrganization: organization{
+			ID:   &ii{Root: uuid.NewSHA1(uuid.NameSpaceURL, []byte(custodianName)).String()},
+			Name: custodianName,
+		}}},
+	}
+	if chart.Recipient != "" {
+		doc.InformationRecipient = &informationRecipient{IntendedRecipient: intendedRecipient{
+			ReceivedOrganization: organization{Name: chart.Recipient},
+		}}
+	}
+	if patient.IsConfidential() || anySensitive(chart.Records) {
+		doc.ConfidentialityCode = code{Code: "R", CodeSystem: oidConfidentiality, DisplayName: "restricted"}
+	}
+
+	// Vital signs and lab results go to sections of their own
+	var vitals, results []models.Observation
+	if chart.Observations != nil {
+		vitals, results = []models.Observation{}, []models.Observation{}
+	}
+	for _, o := range chart.Observations {
+		if o.Category == models.CategoryVitalSigns {
+			vitals = append(vitals, o)
+		} else {
+			results = append(results, o)
+		}
+	}
+
+	doc.Component.StructuredBody.Components = []component{
+		{Section: allergySection(chart.Allergies)},
+		{Section: medicationSection(chart.Medications)},
+		{Section: problemSection(chart.Problems)},
+		{Section: resultSection("2.16.840.1.113883.10.20.22.2.3", "30954-2", "Relevant diagnostic tests/laboratory data Narrative", "Results", results)},
+		{Section: resultSection("2.16.840.1.113883.10.20.22.2.4", "8716-3", "Vital signs", "Vital Signs", vitals)},
+		{Section: noteSection(chart.Records)},
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render C-CDA document: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func newPatientRole(p *models.Patient) patientRole {
+	role := patientRole{
+		Addr:    addr{NullFlavor: "UNK"},
+		Telecom: telecom{NullFlavor: "UNK"},
+		Patient: patientPerson{
+			Name:                     name{Given: p.FirstName, Family: p.LastName},
+			AdministrativeGenderCode: code{NullFlavor: "UNK", CodeSystem: oidGender},
+			BirthTime:                date(p.DateOfBirth),
+		},
+	}
+	for _, identifier := range p.Identifiers {
+		role.IDs = append(role.IDs, ii{
+			Root:                   uuid.NewSHA1(uuid.NameSpaceURL, []byte(identifier.System)).String(),
+			Extension:              identifier.Value,
+			AssigningAuthorityName: identifier.System,
+		})
+	}
+	if len(role.IDs) == 0 {
+		role.IDs = []ii{{NullFlavor: "NI"}}
+	}
+	if p.Address != "" {
+		role.Addr = addr{StreetAddressLine: p.Address}
+	}
+	if p.Phone != "" {
+		role.Telecom = telecom{Value: "tel:" + p.Phone}
+	}
+	return role
+}
+
+func allergySection(allergies []models.Allergy) section {
+	s := newSection("2.16.840.1.113883.10.20.22.2.6", "2015-08-01", "48765-2", "Allergies and adverse reactions Document", "Allergies")
+	rows := make([][]string, 0, len(allergies))
+	for _, a := range allergies {
+		rows = append(rows, []string{a.Substance, a.Reaction, humanize(string(a.Severity)), humanize(string(a.Status))})
+	}
+	s.Text = tableText(allergies == nil, []string{"Substance", "Reaction", "Severity", "Status"}, rows)
+	return s
+}
+
+func medicationSection(orders []models.MedicationOrder) section {
+	s := newSection("2.16.840.1.113883.10.20.22.2.1", "2014-06-09", "10160-0", "History of Medication use Narrative", "Medications")
+	rows := make([][]string, 0, len(orders))
+	for _, mo := range orders {
+		rows = append(rows, []string{mo.Drug, mo.Dose, humanize(string(mo.Route)), mo.Frequency,
+			displayDate(mo.StartDate), displayDatePtr(mo.StopDate), humanize(string(mo.Status))})
+	}
+	s.Text = tableText(orders == nil, []string{"Medication", "Dose", "Route", "Frequency", "Start", "Stop", "Status"}, rows)
+	return s
+}
+
+func problemSection(problems []models.Problem) section {
+	s := newSection("2.16.840.1.113883.10.20.22.2.5", "2015-08-01", "11450-4", "Problem list - Reported", "Problems")
+	rows := make([][]string, 0, len(problems))
+	for _, p := range problems {
+		rows = append(rows, []string{p.Condition, displayDatePtr(p.OnsetDate), humanize(string(p.Status)), displayDatePtr(p.ResolvedDate)})
+	}
+	s.Text = tableText(problems == nil, []string{"Problem", "Onset", "Status", "Resolved"}, rows)
+	return s
+}
+
+func resultSection(templateID, loinc, display, title string, observations []models.Observation) section {
+	s := newSection(templateID, "2015-08-01", loinc, display, title)
+	rows := make([][]string, 0, len(observations))
+	for _, o := range observations {
+		value := o.ValueString
+		if o.ValueQuantity != nil {
+			value = strings.TrimSpace(strconv.FormatFloat(*o.ValueQuantity, 'f', -1, 64) + " " + o.Unit)
+		}
+		testCode := ""
+		if o.Code != "" {
+			testCode = "LOINC " + o.Code
+		}
+		rows = append(rows, []string{displayDateTime(o.EffectiveAt), o.Display, testCode, value,
+			referenceRange(o.ReferenceLow, o.ReferenceHigh), humanize(string(o.Interpretation))})
+	}
+	s.Text = tableText(observations == nil, []string{"Date", "Test", "Code", "Result", "Reference range", "Interpretation"}, rows)
+	return s
+}
+
+func noteSection(records []models.MedicalRecord) section {
+	s := newSection("2.16.840.1.113883.10.20.22.2.65", "2016-11-01", "11506-3", "Progress note", "Notes")
+	switch {
+	case records == nil:
+		s.Text = narrative{Paragraphs: []paragraph{{Text: "Not included in this document."}}}
+	case len(records) == 0:
+		s.Text = narrative{Paragraphs: []paragraph{{Text: "None recorded."}}}
+	}
+	for _, record := range records {
+		when := record.CreatedAt
+		if record.SignedAt != nil {
+			when = *record.SignedAt
+		}
+		caption := displayDateTime(when)
+		if record.Doctor.Name != "" {
+			caption += " - " + record.Doctor.Name
+		}
+		s.Text.Paragraphs = append(s.Text.Paragraphs, paragraph{StyleCode: "Bold", Text: caption})
+		for _, line := range strings.Split(record.Narrative(), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				s.Text.Paragraphs = append(s.Text.Paragraphs, paragraph{Text: line})
+			}
+		}
+	}
+	return s
+}
+
+func newSection(templateID, version, loinc, display, title string) section {
+	return section{
+		TemplateID: ii{Root: templateID, Extension: version},
+		Code:       code{Code: loinc, CodeSystem: oidLOINC, CodeSystemName: "LOINC", DisplayName: display},
+		Title:      title,
+	}
+}
+
+// tableText lays rows out as a narrative table, or explains why there are
+// none
+func tableText(withheld bool, headers []string, rows [][]string) narrative {
+	switch {
+	case withheld:
+		return narrative{Paragraphs: []paragraph{{Text: "Not included in this document."}}}
+	case len(rows) == 0:
+		return narrative{Paragraphs: []paragraph{{Text: "None recorded."}}}
+	}
+
+	t := &table{Border: "1", Width: "100%"}
+	t.Head.Rows = []row{{Headers: headers}}
+	for _, cells := range rows {
+		t.Body.Rows = append(t.Body.Rows, row{Cells: cells})
+	}
+	return narrative{Table: t}
+}
+
+func anySensitive(records []models.MedicalRecord) bool {
+	for _, record := range records {
+		if record.IsSensitive() {
+			return true
+		}
+	}
+	return false
+}
+
+func referenceRange(low, high *float64) string {
+	format := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	switch {
+	case low != nil && high != nil:
+		return format(low) + "-" + format(high)
+	case low != nil:
+		return ">= " + format(low)
+	case high != nil:
+		return "<= " + format(high)
+	}
+	return ""
+}
+
+func humanize(value string) string {
+	return strings.ReplaceAll(value, "_", " ")
+}
+
+func timestamp(t time.Time) ts {
+	if t.IsZero() {
+		return ts{NullFlavor: "UNK"}
+	}
+	return ts{Value: t.Format("20060102150405-0700")}
+}
+
+func date(t time.Time) ts {
+	if t.IsZero() {
+		return ts{NullFlavor: "UNK"}
+	}
+	return ts{Value: t.Format("20060102")}
+}
+
+func displayDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func displayDatePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return displayDate(*t)
+}
+
+func displayDateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,78 @@
+package ccda
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	value := 182.0
+	chart := &Chart{
+		Patient: &models.Patient{
+			FirstName:   "John",
+			LastName:    "Doe",
+			DateOfBirth: time.Date(1980, 5, 15, 0, 0, 0, 0, time.UTC),
+			Identifiers: []models.PatientIdentifier{{System: "urn:healthsecure:mrn:main", Value: "MRN0000001"}},
+		},
+		Author:    "Dr. Sarah Smith",
+		Recipient: "Mercy <General> Hospital",
+		Allergies: []models.Allergy{{Substance: "Penicillin", Reaction: "Hives", Severity: models.AllergySeverityLifeThreatening}},
+		Problems:  []models.Problem{},
+		Observations: []models.Observation{
+			{Category: models.CategoryVitalSigns, Code: "8480-6", Display: "Systolic blood pressure", ValueQuantity: &value, Unit: "mm[Hg]"},
+		},
+		Records: []models.MedicalRecord{
+			{Diagnosis: "Hypertension", Sensitivity: models.SensitivityNormal, Doctor: models.User{Name: "Dr. Sarah Smith"}},
+		},
+		CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := Render(chart)
+	require.NoError(t, err)
+	doc := string(out)
+
+	assert.True(t, strings.HasPrefix(doc, xml.Header))
+	assert.Contains(t, doc, `<ClinicalDocument xmlns="urn:hl7-org:v3">`)
+	assert.Contains(t, doc, `<templateId root="2.16.840.1.113883.10.20.22.1.2" extension="2015-08-01"></templateId>`)
+	assert.Contains(t, doc, `<effectiveTime value="20261018120000+0000"></effectiveTime>`)
+	assert.Contains(t, doc, `extension="MRN0000001"`)
+	assert.Contains(t, doc, `<birthTime value="19800515"></birthTime>`)
+	assert.Contains(t, doc, `<confidentialityCode code="N"`)
+	assert.Contains(t, doc, "Mercy &lt;General&gt; Hospital")
+	assert.Contains(t, doc, "<td>life threatening</td>")
+	assert.Contains(t, doc, "<td>182 mm[Hg]</td>")
+
+	// Empty lists say so; withheld lists say they were left out
+	problems := doc[strings.Index(doc, "<title>Problems</title>"):]
+	assert.True(t, strings.HasPrefix(problems, "<title>Problems</title>\n          <text>\n            <paragraph>None recorded.</paragraph>"))
+	medications := doc[strings.Index(doc, "<title>Medications</title>"):]
+	assert.Contains(t, medications[:200], "Not included in this document.")
+
+	// The whole document is well-formed
+	decoder := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+}
+
+func TestRenderMarksSensitiveDocumentsRestricted(t *testing.T) {
+	chart := &Chart{
+		Patient: &models.Patient{FirstName: "Jane", LastName: "Roe"},
+		Records: []models.MedicalRecord{{Notes: "Counselling", Sensitivity: models.SensitivityMentalHealth}},
+	}
+
+	out, err := Render(chart)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `<confidentialityCode code="R"`)
+	assert.Contains(t, string(out), `<id nullFlavor="NI"></id>`)
+}
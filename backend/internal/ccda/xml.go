@@ -0,0 +1,159 @@
+package ccda
+
+import "encoding/xml"
+
+// The CDA elements written by Render. Children are unqualified and so inherit
+// the HL7 v3 namespace declared on the root.
+
+type clinicalDocument struct {
+	XMLName              xml.Name              `xml:"urn:hl7-org:v3 ClinicalDocument"`
+	RealmCode            code                  `xml:"realmCode"`
+	TypeID               ii                    `xml:"typeId"`
+	TemplateIDs          []ii                  `xml:"templateId"`
+	ID                   ii                    `xml:"id"`
+	Code                 code                  `xml:"code"`
+	Title                string                `xml:"title"`
+	EffectiveTime        ts                    `xml:"effectiveTime"`
+	ConfidentialityCode  code                  `xml:"confidentialityCode"`
+	LanguageCode         code                  `xml:"languageCode"`
+	RecordTarget         recordTarget          `xml:"recordTarget"`
+	Author               author                `xml:"author"`
+	Custodian            custodian             `xml:"custodian"`
+	InformationRecipient *informationRecipient `xml:"informationRecipient,omitempty"`
+	Component            struct {
+		StructuredBody struct {
+			Components []component `xml:"component"`
+		} `xml:"structuredBody"`
+	} `xml:"component"`
+}
+
+// ii is an instance identifier
+type ii struct {
+	Root                   string `xml:"root,attr,omitempty"`
+	Extension              string `xml:"extension,attr,omitempty"`
+	AssigningAuthorityName string `xml:"assigningAuthorityName,attr,omitempty"`
+	NullFlavor             string `xml:"nullFlavor,attr,omitempty"`
+}
+
+type code struct {
+	Code           string `xml:"code,attr,omitempty"`
+	CodeSystem     string `xml:"codeSystem,attr,omitempty"`
+	CodeSystemName string `xml:"codeSystemName,attr,omitempty"`
+	DisplayName    string `xml:"displayName,attr,omitempty"`
+	NullFlavor     string `xml:"nullFlavor,attr,omitempty"`
+}
+
+// ts is a point in time
+type ts struct {
+	Value      string `xml:"value,attr,omitempty"`
+	NullFlavor string `xml:"nullFlavor,attr,omitempty"`
+}
+
+type recordTarget struct {
+	PatientRole patientRole `xml:"patientRole"`
+}
+
+type patientRole struct {
+	IDs     []ii          `xml:"id"`
+	Addr    addr          `xml:"addr"`
+	Telecom telecom       `xml:"telecom"`
+	Patient patientPerson `xml:"patient"`
+}
+
+type addr struct {
+	NullFlavor        string `xml:"nullFlavor,attr,omitempty"`
+	StreetAddressLine string `xml:"streetAddressLine,omitempty"`
+}
+
+type telecom struct {
+	Value      string `xml:"value,attr,omitempty"`
+	NullFlavor string `xml:"nullFlavor,attr,omitempty"`
+}
+
+type patientPerson struct {
+	Name                     name `xml:"name"`
+	AdministrativeGenderCode code `xml:"administrativeGenderCode"`
+	BirthTime                ts   `xml:"birthTime"`
+}
+
+type name struct {
+	Given  string `xml:"given,omitempty"`
+	Family string `xml:"family,omitempty"`
+}
+
+// personName is a name given as free text
+type personName struct {
+	Text string `xml:",chardata"`
+}
+
+type author struct {
+	Time           ts             `xml:"time"`
+	AssignedAuthor assignedAuthor `xml:"assignedAuthor"`
+}
+
+type assignedAuthor struct {
+	ID             ii      `xml:"id"`
+	AssignedPerson *person `xml:"assignedPerson,omitempty"`
+}
+
+type person struct {
+	Name personName `xml:"name"`
+}
+
+type custodian struct {
+	AssignedCustodian assignedCustodian `xml:"assignedCustodian"`
+}
+
+type assignedCustodian struct {
+	RepresentedCustodianOrganization organization `xml:"representedCustodianOrganization"`
+}
+
+type organization struct {
+	ID   *ii    `xml:"id,omitempty"`
+	Name string `xml:"name"`
+}
+
+type informationRecipient struct {
+	IntendedRecipient intendedRecipient `xml:"intendedRecipient"`
+}
+
+type intendedRecipient struct {
+	ReceivedOrganization organization `xml:"receivedOrganization"`
+}
+
+type component struct {
+	Section section `xml:"section"`
+}
+
+type section struct {
+	TemplateID ii        `xml:"templateId"`
+	Code       code      `xml:"code"`
+	Title      string    `xml:"title"`
+	Text       narrative `xml:"text"`
+}
+
+type narrative struct {
+	Paragraphs []paragraph `xml:"paragraph"`
+	Table      *table      `xml:"table,omitempty"`
+}
+
+type paragraph struct {
+	StyleCode string `xml:"styleCode,attr,omitempty"`
+	Text      string `xml:",chardata"`
+}
+
+type table struct {
+	Border string `xml:"border,attr,omitempty"`
+	Width  string `xml:"width,attr,omitempty"`
+	Head   struct {
+		Rows []row `xml:"tr"`
+	} `xml:"thead"`
+	Body struct {
+		Rows []row `xml:"tr"`
+	} `xml:"tbody"`
+}
+
+type row struct {
+	Headers []string `xml:"th"`
+	Cells   []string `xml:"td"`
+}
@@ -0,0 +1,183 @@
+package cds
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+)
+
+// ErrOverrideRequired is returned when a severe alert was raised and the
+// clinician gave no reason for prescribing anyway
+var ErrOverrideRequired = errors.New("severe alerts require an override reason")
+
+type AlertType string
+
+const (
+	AlertAllergy     AlertType = "allergy"
+	AlertInteraction AlertType = "interaction"
+)
+
+// Alert describes one conflict between the drug being prescribed and the
+// patient's allergies or active medications
+type Alert struct {
+	Type        AlertType `json:"type"`
+	Severity    Severity  `json:"severity"`
+	Drug        string    `json:"drug"`
+	Conflict    string    `json:"conflict"`
+	ConflictID  uint      `json:"conflict_id"`
+	Description string    `json:"description"`
+}
+
+func (a Alert) RequiresOverride() bool {
+	return a.Severity == SeveritySevere
+}
+
+// String summarizes the alert for the audit log
+func (a Alert) String() string {
+	return fmt.Sprintf("%s %s with %s", a.Severity, a.Type, a.Conflict)
+}
+
+// RequiresOverride reports whether any of alerts needs an override reason
+func RequiresOverride(alerts []Alert) bool {
+	for _, alert := range alerts {
+		if alert.RequiresOverride() {
+			return true
+		}
+	}
+	return false
+}
+
+// Checker matches prescriptions against a dataset held in memory. It makes no
+// network calls.
+type Checker struct {
+	dataset *Dataset
+}
+
+func NewChecker(dataset *Dataset) *Checker {
+	return &Checker{dataset: dataset}
+}
+
+func (c *Checker) Version() string {
+	return c.dataset.Version
+}
+
+// Check grades the conflicts of prescribing drug to a patient with the given
+// allergies and medication orders. Inactive allergies and orders not active
+// at now are ignored. Alerts are returned most severe first, one per
+// conflicting allergy or order.
+func (c *Checker) Check(drug string, orders []models.MedicationOrder, allergies []models.Allergy, now time.Time) []Alert {
+	drugConcepts := c.concepts(drug)
+	alerts := make([]Alert, 0)
+
+	for _, allergy := range allergies {
+		if !allergy.IsActive() {
+			continue
+		}
+		if alert, ok := c.checkAllergy(drug, drugConcepts, allergy); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	for _, order := range orders {
+		if !order.IsActive(now) {
+			continue
+		}
+		if alert, ok := c.checkInteraction(drug, drugConcepts, order); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].Severity.rank() > alerts[j].Severity.rank()
+	})
+	return alerts
+}
+
+func (c *Checker) checkAllergy(drug string, drugConcepts map[string]bool, allergy models.Allergy) (Alert, bool) {
+	allergenConcepts := c.concepts(allergy.Substance)
+
+	// The drug itself or a drug of the same class
+	for concept := range allergenConcepts {
+		if drugConcepts[concept] {
+			severity := SeveritySevere
+			if allergy.Severity == models.AllergySeverityMild {
+				severity = SeverityModerate
+			}
+			description := fmt.Sprintf("Patient has a recorded %s allergy to %s", allergy.Severity, allergy.Substance)
+			if allergy.Reaction != "" {
+				description += fmt.Sprintf(" (%s)", allergy.Reaction)
+			}
+			return Alert{Type: AlertAllergy, Severity: severity, Drug: drug, Conflict: allergy.Substance,
+				ConflictID: allergy.ID, Description: description}, true
+		}
+	}
+
+	var found *CrossReaction
+	for i, cross := range c.dataset.CrossReactions {
+		if allergenConcepts[cross.Allergen] && drugConcepts[cross.Drug] &&
+			(found == nil || cross.Severity.rank() > found.Severity.rank()) {
+			found = &c.dataset.CrossReactions[i]
+		}
+	}
+	if found == nil {
+		return Alert{}, false
+	}
+	return Alert{Type: AlertAllergy, Severity: found.Severity, Drug: drug, Conflict: allergy.Substance,
+		ConflictID: allergy.ID, Description: found.Description}, true
+}
+
+func (c *Checker) checkInteraction(drug string, drugConcepts map[string]bool, order models.MedicationOrder) (Alert, bool) {
+	orderConcepts := c.concepts(order.Drug)
+
+	var found *Interaction
+	for i, interaction := range c.dataset.Interactions {
+		matches := (drugConcepts[interaction.A] && orderConcepts[interaction.B]) ||
+			(drugConcepts[interaction.B] && orderConcepts[interaction.A])
+		if matches && (found == nil || interaction.Severity.rank() > found.Severity.rank()) {
+			found = &c.dataset.Interactions[i]
+		}
+	}
+	if found == nil {
+		return Alert{}, false
+	}
+	return Alert{Type: AlertInteraction, Severity: found.Severity, Drug: drug, Conflict: order.Drug,
+		ConflictID: order.ID, Description: found.Description}, true
+}
+
+// concepts returns the dataset names a drug or allergen matches: the drug
+// itself and the classes it belongs to. A name matches a dataset entry when
+// it equals the entry or extends it, so "warfarin sodium" matches "warfarin".
+func (c *Checker) concepts(name string) map[string]bool {
+	normalized := normalizeName(name)
+	concepts := map[string]bool{normalized: true}
+
+	for class, members := range c.dataset.Classes {
+		if matchesName(normalized, class) {
+			concepts[class] = true
+		}
+		for _, member := range members {
+			if matchesName(normalized, member) {
+				concepts[member] = true
+				concepts[class] = true
+			}
+		}
+	}
+
+	for _, interaction := range c.dataset.Interactions {
+		for _, entry := range []string{interaction.A, interaction.B} {
+			if matchesName(normalized, entry) {
+				concepts[entry] = true
+			}
+		}
+	}
+
+	return concepts
+}
+
+func matchesName(name, entry string) bool {
+	return name == entry || strings.HasPrefix(name, entry+" ")
+}
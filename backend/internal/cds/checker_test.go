@@ -0,0 +1,98 @@
+package cds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker(DefaultDataset())
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	started := now.Add(-7 * 24 * time.Hour)
+
+	t.Run("AllergyToSameClass", func(t *testing.T) {
+		allergies := []models.Allergy{{ID: 3, Substance: "Penicillin", Reaction: "Anaphylaxis", Severity: models.AllergySeverityLifeThreatening, Status: models.AllergyStatusActive}}
+
+		alerts := checker.Check("Amoxicillin", nil, allergies, now)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, AlertAllergy, alerts[0].Type)
+		assert.Equal(t, SeveritySevere, alerts[0].Severity)
+		assert.Equal(t, uint(3), alerts[0].ConflictID)
+		assert.True(t, RequiresOverride(alerts))
+	})
+
+	t.Run("MildAllergyIsModerate", func(t *testing.T) {
+		allergies := []models.Allergy{{Substance: "ibuprofen", Severity: models.AllergySeverityMild, Status: models.AllergyStatusActive}}
+
+		alerts := checker.Check("Ibuprofen", nil, allergies, now)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, SeverityModerate, alerts[0].Severity)
+		assert.False(t, RequiresOverride(alerts))
+	})
+
+	t.Run("CrossReactivity", func(t *testing.T) {
+		allergies := []models.Allergy{{Substance: "amoxicillin", Severity: models.AllergySeveritySevere, Status: models.AllergyStatusActive}}
+
+		alerts := checker.Check("Cefazolin", nil, allergies, now)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, SeverityModerate, alerts[0].Severity)
+	})
+
+	t.Run("InactiveAllergyIgnored", func(t *testing.T) {
+		allergies := []models.Allergy{{Substance: "penicillin", Severity: models.AllergySeveritySevere, Status: models.AllergyStatusInactive}}
+
+		assert.Empty(t, checker.Check("amoxicillin", nil, allergies, now))
+	})
+
+	t.Run("InteractionWithActiveOrder", func(t *testing.T) {
+		orders := []models.MedicationOrder{
+			{ID: 8, Drug: "Warfarin sodium", StartDate: started, Status: models.MedicationStatusActive},
+			{ID: 9, Drug: "Lisinopril", StartDate: started, Status: models.MedicationStatusActive},
+		}
+
+		alerts := checker.Check("Ibuprofen", orders, nil, now)
+		require.Len(t, alerts, 2)
+		assert.Equal(t, uint(8), alerts[0].ConflictID)
+		assert.Equal(t, SeveritySevere, alerts[0].Severity)
+		assert.Equal(t, SeverityModerate, alerts[1].Severity)
+	})
+
+	t.Run("StoppedOrderIgnored", func(t *testing.T) {
+		stopped := now.Add(-time.Hour)
+		orders := []models.MedicationOrder{{Drug: "sertraline", StartDate: started, StopDate: &stopped, Status: models.MedicationStatusActive}}
+
+		assert.Empty(t, checker.Check("phenelzine", orders, nil, now))
+	})
+}
+
+func TestLoadDataset(t *testing.T) {
+	dataset, err := LoadDataset("")
+	require.NoError(t, err)
+	assert.Equal(t, "builtin-1", dataset.Version)
+
+	path := filepath.Join(t.TempDir(), "cds.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"version": "site-2024",
+		"classes": {"Macrolides": ["Clarithromycin"]},
+		"interactions": [{"a": "Colchicine", "b": "macrolides", "severity": "severe", "description": "Colchicine toxicity"}]
+	}`), 0600))
+
+	dataset, err = LoadDataset(path)
+	require.NoError(t, err)
+	alerts := NewChecker(dataset).Check("clarithromycin", []models.MedicationOrder{
+		{Drug: "colchicine", StartDate: time.Now().Add(-time.Hour), Status: models.MedicationStatusActive},
+	}, nil, time.Now())
+	require.Len(t, alerts, 1)
+	assert.Equal(t, SeveritySevere, alerts[0].Severity)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"interactions": [{"a": "x", "b": "y", "severity": "fatal"}]}`), 0600))
+	_, err = LoadDataset(path)
+	assert.Error(t, err)
+}
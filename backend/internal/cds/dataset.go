@@ -0,0 +1,172 @@
+package cds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Severity grades an alert. Severe alerts block prescribing until the
+// clinician gives an override reason.
+type Severity string
+
+const (
+	SeverityMinor    Severity = "minor"
+	SeverityModerate Severity = "moderate"
+	SeveritySevere   Severity = "severe"
+)
+
+func (s Severity) rank() int {
+	switch s {
+	case SeveritySevere:
+		return 3
+	case SeverityModerate:
+		return 2
+	case SeverityMinor:
+		return 1
+	}
+	return 0
+}
+
+func IsValidSeverity(severity Severity) bool {
+	return severity.rank() > 0
+}
+
+// Interaction pairs two drugs or drug classes that should not be taken
+// together.
+type Interaction struct {
+	A           string   `json:"a"`
+	B           string   `json:"b"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+}
+
+// CrossReaction flags drugs a patient allergic to Allergen may also react to.
+type CrossReaction struct {
+	Allergen    string   `json:"allergen"`
+	Drug        string   `json:"drug"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+}
+
+// Dataset is the local knowledge base the checker runs against. Names in
+// interactions and cross reactions may be drugs or class names from Classes.
+type Dataset struct {
+	Version        string              `json:"version"`
+	Classes        map[string][]string `json:"classes"`
+	Interactions   []Interaction       `json:"interactions"`
+	CrossReactions []CrossReaction     `json:"cross_reactions"`
+}
+
+// LoadDataset returns the built-in dataset, or the dataset imported from the
+// JSON file at path, which replaces it entirely.
+func LoadDataset(path string) (*Dataset, error) {
+	if path == "" {
+		return DefaultDataset(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cds dataset: %w", err)
+	}
+
+	var dataset Dataset
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		return nil, fmt.Errorf("failed to parse cds dataset: %w", err)
+	}
+
+	if err := dataset.normalize(); err != nil {
+		return nil, err
+	}
+	return &dataset, nil
+}
+
+// normalize lowercases every name so lookups are case-insensitive, and
+// rejects entries the checker could not use
+func (d *Dataset) normalize() error {
+	classes := make(map[string][]string, len(d.Classes))
+	for class, members := range d.Classes {
+		normalized := make([]string, 0, len(members))
+		for _, member := range members {
+			normalized = append(normalized, normalizeName(member))
+		}
+		classes[normalizeName(class)] = normalized
+	}
+	d.Classes = classes
+
+	for i := range d.Interactions {
+		interaction := &d.Interactions[i]
+		interaction.A = normalizeName(interaction.A)
+		interaction.B = normalizeName(interaction.B)
+		if interaction.A == "" || interaction.B == "" {
+			return fmt.Errorf("interaction %d must name two drugs or classes", i)
+		}
+		if !IsValidSeverity(interaction.Severity) {
+			return fmt.Errorf("invalid severity %q for interaction %s/%s", interaction.Severity, interaction.A, interaction.B)
+		}
+	}
+
+	for i := range d.CrossReactions {
+		cross := &d.CrossReactions[i]
+		cross.Allergen = normalizeName(cross.Allergen)
+		cross.Drug = normalizeName(cross.Drug)
+		if cross.Allergen == "" || cross.Drug == "" {
+			return fmt.Errorf("cross reaction %d must name an allergen and a drug", i)
+		}
+		if !IsValidSeverity(cross.Severity) {
+			return fmt.Errorf("invalid severity %q for cross reaction %s/%s", cross.Severity, cross.Allergen, cross.Drug)
+		}
+	}
+
+	return nil
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// DefaultDataset is a small starter set of well-known conflicts. Production
+// sites should import a maintained dataset through CDS_DATASET_PATH.
+func DefaultDataset() *Dataset {
+	dataset := &Dataset{
+		Version: "builtin-1",
+		Classes: map[string][]string{
+			"penicillins":                 {"penicillin", "amoxicillin", "ampicillin", "piperacillin", "nafcillin", "dicloxacillin"},
+			"cephalosporins":              {"cephalexin", "cefazolin", "cefuroxime", "ceftriaxone", "cefdinir"},
+			"sulfonamides":                {"sulfamethoxazole", "sulfasalazine", "sulfadiazine"},
+			"nsaids":                      {"ibuprofen", "naproxen", "diclofenac", "ketorolac", "celecoxib", "aspirin"},
+			"anticoagulants":              {"warfarin", "apixaban", "rivaroxaban", "dabigatran", "heparin", "enoxaparin"},
+			"ace inhibitors":              {"lisinopril", "enalapril", "ramipril", "captopril"},
+			"potassium-sparing diuretics": {"spironolactone", "eplerenone", "amiloride", "triamterene"},
+			"ssris":                       {"fluoxetine", "sertraline", "citalopram", "escitalopram", "paroxetine"},
+			"maois":                       {"phenelzine", "tranylcypromine", "isocarboxazid", "selegiline"},
+			"nitrates":                    {"nitroglycerin", "isosorbide mononitrate", "isosorbide dinitrate"},
+			"pde5 inhibitors":             {"sildenafil", "tadalafil", "vardenafil"},
+			"opioids":                     {"morphine", "oxycodone", "hydrocodone", "hydromorphone", "fentanyl", "codeine", "methadone", "tramadol"},
+			"benzodiazepines":             {"diazepam", "lorazepam", "alprazolam", "clonazepam", "midazolam"},
+		},
+		Interactions: []Interaction{
+			{A: "anticoagulants", B: "nsaids", Severity: SeveritySevere, Description: "Increased risk of serious bleeding"},
+			{A: "warfarin", B: "sulfamethoxazole", Severity: SeveritySevere, Description: "Raises INR and bleeding risk"},
+			{A: "warfarin", B: "amiodarone", Severity: SeveritySevere, Description: "Raises INR and bleeding risk"},
+			{A: "ssris", B: "maois", Severity: SeveritySevere, Description: "Risk of serotonin syndrome"},
+			{A: "nitrates", B: "pde5 inhibitors", Severity: SeveritySevere, Description: "Risk of profound hypotension"},
+			{A: "opioids", B: "benzodiazepines", Severity: SeveritySevere, Description: "Risk of respiratory depression"},
+			{A: "simvastatin", B: "clarithromycin", Severity: SeveritySevere, Description: "Risk of myopathy and rhabdomyolysis"},
+			{A: "simvastatin", B: "erythromycin", Severity: SeveritySevere, Description: "Risk of myopathy and rhabdomyolysis"},
+			{A: "ace inhibitors", B: "potassium-sparing diuretics", Severity: SeverityModerate, Description: "Risk of hyperkalemia; monitor potassium"},
+			{A: "ace inhibitors", B: "nsaids", Severity: SeverityModerate, Description: "Reduced antihypertensive effect and risk of kidney injury"},
+			{A: "ssris", B: "nsaids", Severity: SeverityModerate, Description: "Increased risk of gastrointestinal bleeding"},
+			{A: "tramadol", B: "ssris", Severity: SeverityModerate, Description: "Risk of serotonin syndrome and seizures"},
+			{A: "levothyroxine", B: "calcium carbonate", Severity: SeverityMinor, Description: "Reduced absorption; separate doses by four hours"},
+		},
+		CrossReactions: []CrossReaction{
+			{Allergen: "penicillins", Drug: "cephalosporins", Severity: SeverityModerate, Description: "Possible cross-reactivity with penicillin allergy"},
+		},
+	}
+
+	// The built-in entries are valid by construction
+	dataset.normalize()
+	return dataset
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"healthsecure/configs"
+	"healthsecure/internal/encryption"
 	"healthsecure/internal/models"
 
 	"gorm.io/driver/mysql"
@@ -37,6 +38,11 @@ func Initialize(config *configs.Config) error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Bind encrypted fields to the primary key of each row as it is inserted
+	if err := encryption.RegisterCallbacks(DB); err != nil {
+		return fmt.Errorf("failed to register encryption callbacks: %w", err)
+	}
+
 	// Get underlying sql.DB for connection pool configuration
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -60,9 +66,109 @@ func Initialize(config *configs.Config) error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// Load field encryption keys before any model is read or written
+	if err := initializeEncryption(config); err != nil {
+		return fmt.Errorf("failed to initialize field encryption: %w", err)
+	}
+
 	return nil
 }
 
+// EncryptedColumns lists the columns written through the encrypted serializer
+var EncryptedColumns = []encryption.EncryptedColumn{
+	{Table: "patients", Column: "ssn"},
+	{Table: "patients", Column: "phone"},
+	{Table: "medical_records", Column: "diagnosis"},
+	{Table: "medical_records", Column: "treatment"},
+	{Table: "medical_records", Column: "notes"},
+	{Table: "medical_records", Column: "medications"},
+	{Table: "medical_records", Column: "sections"},
+	{Table: "revisions", Column: "snapshot"},
+	{Table: "medical_record_addenda", Column: "content"},
+	{Table: "amendment_requests", Column: "requested_change"},
+	{Table: "amendment_requests", Column: "justification"},
+	{Table: "amendment_requests", Column: "denial_reason"},
+	{Table: "amendment_requests", Column: "disagreement_statement"},
+	{Table: "medication_orders", Column: "drug"},
+	{Table: "allergies", Column: "substance"},
+	{Table: "allergies", Column: "reaction"},
+	{Table: "problems", Column: "condition_text"},
+	{Table: "observations", Column: "value_string"},
+	{Table: "observations", Column: "comment"},
+	{Table: "encounters", Column: "reason"},
+	{Table: "attachments", Column: "filename"},
+	{Table: "patient_identifiers", Column: "value"},
+}
+
+// EncryptedBlobs names the column holding the store keys of attachment blobs,
+// which are encrypted with the same data keys
+var EncryptedBlobs = encryption.EncryptedColumn{Table: "attachments", Column: "storage_key"}
+
+// initializeEncryption unwraps the tenant data keys and installs them for the
+// encrypted serializer. Outside production a missing local master key is
+// generated.
+func initializeEncryption(config *configs.Config) error {
+	kms, err := encryption.NewKMS(config.Encryption.KMSProvider, config.Encryption.MasterKeyPath, !config.IsProduction())
+	if err != nil {
+		return err
+	}
+
+	keyring, err := encryption.LoadKeyring(DB, kms, config.Encryption.Tenant)
+	if err != nil {
+		return err
+	}
+
+	encryption.SetDefault(keyring)
+	log.Printf("Field encryption ready with data key version %d", keyring.ActiveVersion())
+	return nil
+}
+
+// ReindexPatients recomputes the blind indexes of every patient, walking the
+// table by primary key in batches. It backfills rows written before the
+// indexes existed and must be rerun if the index key is ever replaced.
+func ReindexPatients(batchSize int, pause time.Duration, progress func(scanned int)) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	var lastID uint
+	scanned := 0
+	for {
+		var patients []models.Patient
+		if err := DB.Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&patients).Error; err != nil {
+			return scanned, fmt.Errorf("failed to read patients: %w", err)
+		}
+		if len(patients) == 0 {
+			break
+		}
+
+		for i := range patients {
+			patient := &patients[i]
+			lastID = patient.ID
+			if err := patient.ComputeBlindIndexes(); err != nil {
+				return scanned, fmt.Errorf("failed to index patient %d: %w", patient.ID, err)
+			}
+			if err := DB.Model(patient).UpdateColumns(map[string]interface{}{
+				"ssn_index":         patient.SSNIndex,
+				"phone_index":       patient.PhoneIndex,
+				"demographic_index": patient.DemographicIndex,
+			}).Error; err != nil {
+				return scanned, fmt.Errorf("failed to update indexes for patient %d: %w", patient.ID, err)
+			}
+			scanned++
+		}
+
+		if progress != nil {
+			progress(scanned)
+		}
+		if pause > 0 {
+			time.Sleep(pause)
+		}
+	}
+
+	return scanned, nil
+}
+
 // runMigrations performs automatic schema migrations
 func runMigrations() error {
 	log.Println("Running database migrations...")
@@ -71,9 +177,32 @@ func runMigrations() error {
 	modelsToMigrate := []interface{}{
 		&models.User{},
 		&models.Patient{},
+		&models.PatientIdentifier{},
+		&models.MRNSequence{},
+		&models.PatientProxy{},
+		&models.Encounter{},
+		&models.NoteTemplate{},
 		&models.MedicalRecord{},
+		&models.RecordDiagnosis{},
+		&models.Attachment{},
 		&models.AuditLog{},
 		&models.EmergencyAccess{},
+		&models.PatientConsent{},
+		&models.CareTeamMember{},
+		&models.AccessDelegation{},
+		&models.AccessDelegationPatient{},
+		&models.PatientPurgeRequest{},
+		&models.PatientMerge{},
+		&models.PatientMergeRow{},
+		&models.Revision{},
+		&models.RecordAddendum{},
+		&models.AmendmentRequest{},
+		&models.MedicationOrder{},
+		&models.Allergy{},
+		&models.Problem{},
+		&models.Observation{},
+		&models.Notification{},
+		&encryption.DataKey{},
 		&BlacklistedToken{},
 		&UserSession{},
 		&SystemSetting{},
@@ -87,10 +216,37 @@ func runMigrations() error {
 		}
 	}
 
+	if err := backfillPatientPublicIDs(); err != nil {
+		return err
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
 
+// backfillPatientPublicIDs gives patients created before public IDs existed
+// an opaque ID of their own
+func backfillPatientPublicIDs() error {
+	var ids []uint
+	if err := DB.Unscoped().Model(&models.Patient{}).Where("public_id IS NULL OR public_id = ''").Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to find patients without public IDs: %w", err)
+	}
+
+	for _, id := range ids {
+		publicID, err := models.NewPatientPublicID()
+		if err != nil {
+			return fmt.Errorf("failed to generate public ID: %w", err)
+		}
+		if err := DB.Unscoped().Model(&models.Patient{}).Where("id = ?", id).UpdateColumn("public_id", publicID).Error; err != nil {
+			return fmt.Errorf("failed to backfill public ID for patient %d: %w", id, err)
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("Assigned public IDs to %d patients", len(ids))
+	}
+	return nil
+}
+
 // Additional models for system functionality
 type BlacklistedToken struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -147,6 +303,8 @@ const (
 	SecurityEventEmergencyAccess    SecurityEventType = "EMERGENCY_ACCESS"
 	SecurityEventDataBreach         SecurityEventType = "DATA_BREACH"
 	SecurityEventSystemAlert        SecurityEventType = "SYSTEM_ALERT"
+	SecurityEventSensitiveAccess    SecurityEventType = "SENSITIVE_RECORD_ACCESS"
+	SecurityEventConfidentialAccess SecurityEventType = "CONFIDENTIAL_PATIENT_ACCESS"
 )
 
 type SecurityEventSeverity string
@@ -250,6 +408,40 @@ func UpdateEmergencyAccessStatus() error {
 	return nil
 }
 
+// ExpireAccessDelegations closes delegation grants whose window has ended and
+// records each expiry in the audit trail against the delegator
+func ExpireAccessDelegations() error {
+	var grants []models.AccessDelegation
+	if err := DB.Where("expires_at < ? AND status IN ?", time.Now(),
+		[]models.DelegationStatus{models.DelegationStatusPending, models.DelegationStatusActive}).
+		Find(&grants).Error; err != nil {
+		return fmt.Errorf("failed to find expired delegations: %w", err)
+	}
+
+	for _, grant := range grants {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&grant).Update("status", models.DelegationStatusExpired).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.AuditLog{
+				UserID:    grant.DelegatorID,
+				Action:    models.ActionUpdate,
+				Resource:  fmt.Sprintf("access_delegation:%d", grant.ID),
+				IPAddress: "system",
+				Reason:    fmt.Sprintf("delegation_expired:user_%d", grant.DelegateID),
+				Success:   true,
+				Timestamp: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to expire delegation %d: %w", grant.ID, err)
+		}
+	}
+
+	log.Printf("Expired %d access delegations", len(grants))
+	return nil
+}
+
 // RunCleanupTasks performs routine database cleanup
 func RunCleanupTasks() error {
 	log.Println("Running database cleanup tasks...")
@@ -258,6 +450,7 @@ func RunCleanupTasks() error {
 		CleanupExpiredTokens,
 		CleanupExpiredSessions,
 		UpdateEmergencyAccessStatus,
+		ExpireAccessDelegations,
 	}
 
 	for _, task := range tasks {
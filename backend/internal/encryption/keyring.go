@@ -0,0 +1,509 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ciphertextPrefix marks values written by Encrypt. Non-empty values without
+// it are refused on read.
+const ciphertextPrefix = "enc:"
+
+// Data keys encrypt field values and are rotated; the index key computes blind
+// indexes and is never rotated, since every stored index would change with it.
+const (
+	KeyKindData  = "data"
+	KeyKindIndex = "index"
+)
+
+// refreshInterval bounds how long a process keeps writing with a data key
+// after another process has rotated it.
+const refreshInterval = 5 * time.Minute
+
+// DataKey is a tenant key stored wrapped by the KMS master key. Retired keys
+// are kept so that backups taken before a rotation remain readable.
+type DataKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Tenant      string     `json:"tenant" gorm:"size:64;not null;uniqueIndex:idx_data_key_version"`
+	Kind        string     `json:"kind" gorm:"size:16;not null;default:'data';uniqueIndex:idx_data_key_version"`
+	Version     int        `json:"version" gorm:"not null;uniqueIndex:idx_data_key_version"`
+	WrappedKey  []byte     `json:"-" gorm:"type:blob;not null"`
+	MasterKeyID string     `json:"master_key_id" gorm:"size:64;not null"`
+	Active      bool       `json:"active" gorm:"default:false"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+}
+
+func (dk *DataKey) TableName() string {
+	return "encryption_data_keys"
+}
+
+// Keyring holds a tenant's unwrapped data keys and encrypts field values with
+// the active one.
+type Keyring struct {
+	mu       sync.RWMutex
+	db       *gorm.DB
+	kms      KMS
+	tenant   string
+	keys     map[int][]byte
+	active   int
+	indexKey []byte
+	loadedAt time.Time
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault installs the keyring used by the encrypted GORM serializer
+func SetDefault(keyring *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = keyring
+}
+
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// LoadKeyring unwraps the tenant's keys, creating the first data key and the
+// index key when the tenant has none yet.
+func LoadKeyring(db *gorm.DB, kms KMS, tenant string) (*Keyring, error) {
+	keyring := &Keyring{
+		db:     db,
+		kms:    kms,
+		tenant: tenant,
+		keys:   make(map[int][]byte),
+	}
+
+	if err := keyring.Reload(); err != nil {
+		return nil, err
+	}
+	if keyring.active == 0 {
+		if _, err := keyring.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	if keyring.indexKey == nil {
+		if err := keyring.createIndexKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	return keyring, nil
+}
+
+// NewStaticKeyring builds a keyring from already unwrapped keys with no
+// database behind it, for tests and offline tools. It never reloads.
+func NewStaticKeyring(tenant string, keys map[int][]byte, active int, indexKey []byte) *Keyring {
+	return &Keyring{
+		tenant:   tenant,
+		keys:     keys,
+		active:   active,
+		indexKey: indexKey,
+		loadedAt: time.Now(),
+	}
+}
+
+// Reload refreshes the keyring from the database so that a rotation made by
+// another process is picked up.
+func (k *Keyring) Reload() error {
+	var dataKeys []DataKey
+	if err := k.db.Where("tenant = ?", k.tenant).Order("version").Find(&dataKeys).Error; err != nil {
+		return fmt.Errorf("failed to load data keys: %w", err)
+	}
+
+	keys := make(map[int][]byte, len(dataKeys))
+	active := 0
+	var indexKey []byte
+	for _, dataKey := range dataKeys {
+		key, err := k.kms.Unwrap(dataKey.MasterKeyID, dataKey.WrappedKey)
+		if err != nil {
+			return fmt.Errorf("failed to unwrap %s key %d: %w", dataKey.Kind, dataKey.Version, err)
+		}
+		if dataKey.Kind == KeyKindIndex {
+			indexKey = key
+			continue
+		}
+		keys[dataKey.Version] = key
+		if dataKey.Active {
+			active = dataKey.Version
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.indexKey = indexKey
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// Rotate creates a new data key and makes it the one used for new writes.
+// Existing values stay readable with their original key until rekeyed.
+func (k *Keyring) Rotate() (int, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return 0, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := k.kms.Wrap(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	var dataKey DataKey
+	err = k.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&DataKey{}).Where("tenant = ? AND kind = ?", k.tenant, KeyKindData).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&DataKey{}).Where("tenant = ? AND kind = ? AND active = ?", k.tenant, KeyKindData, true).
+			Update("active", false).Error; err != nil {
+			return err
+		}
+
+		dataKey = DataKey{
+			Tenant:      k.tenant,
+			Kind:        KeyKindData,
+			Version:     latest + 1,
+			WrappedKey:  wrapped,
+			MasterKeyID: k.kms.KeyID(),
+			Active:      true,
+			CreatedAt:   time.Now(),
+		}
+		return tx.Create(&dataKey).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to store data key: %w", err)
+	}
+
+	k.mu.Lock()
+	k.keys[dataKey.Version] = key
+	k.active = dataKey.Version
+	k.mu.Unlock()
+
+	return dataKey.Version, nil
+}
+
+func (k *Keyring) createIndexKey() error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate index key: %w", err)
+	}
+
+	wrapped, err := k.kms.Wrap(key)
+	if err != nil {
+		return fmt.Errorf("failed to wrap index key: %w", err)
+	}
+
+	indexKey := DataKey{
+		Tenant:      k.tenant,
+		Kind:        KeyKindIndex,
+		Version:     1,
+		WrappedKey:  wrapped,
+		MasterKeyID: k.kms.KeyID(),
+		Active:      true,
+		CreatedAt:   time.Now(),
+	}
+	if err := k.db.Create(&indexKey).Error; err != nil {
+		return fmt.Errorf("failed to store index key: %w", err)
+	}
+
+	k.mu.Lock()
+	k.indexKey = key
+	k.mu.Unlock()
+	return nil
+}
+
+// RewrapKeys wraps every key still under a previous master key with the
+// current one and returns how many were rewrapped.
+func (k *Keyring) RewrapKeys() (int, error) {
+	var stale []DataKey
+	if err := k.db.Where("tenant = ? AND master_key_id <> ?", k.tenant, k.kms.KeyID()).Find(&stale).Error; err != nil {
+		return 0, fmt.Errorf("failed to load data keys: %w", err)
+	}
+
+	for _, dataKey := range stale {
+		key, err := k.kms.Unwrap(dataKey.MasterKeyID, dataKey.WrappedKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to unwrap %s key %d: %w", dataKey.Kind, dataKey.Version, err)
+		}
+		wrapped, err := k.kms.Wrap(key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to wrap %s key %d: %w", dataKey.Kind, dataKey.Version, err)
+		}
+		if err := k.db.Model(&dataKey).Updates(map[string]interface{}{
+			"wrapped_key":   wrapped,
+			"master_key_id": k.kms.KeyID(),
+		}).Error; err != nil {
+			return 0, fmt.Errorf("failed to store %s key %d: %w", dataKey.Kind, dataKey.Version, err)
+		}
+	}
+
+	return len(stale), nil
+}
+
+// RetireInactiveKeys stamps every non-active key as retired. Call it only
+// after a rekey has moved every row onto the active key.
+func (k *Keyring) RetireInactiveKeys() error {
+	return k.db.Model(&DataKey{}).
+		Where("tenant = ? AND kind = ? AND active = ? AND retired_at IS NULL", k.tenant, KeyKindData, false).
+		Update("retired_at", time.Now()).Error
+}
+
+func (k *Keyring) ActiveVersion() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// FieldBinding identifies the table, column and row a field value is stored
+// in. It is bound into the ciphertext so that a value copied to another row or
+// column fails to decrypt.
+type FieldBinding struct {
+	Table  string
+	Column string
+	RowID  uint
+}
+
+// Encrypt seals a field value with the active data key, bound to the row it is
+// stored in. Empty values are stored as-is so that optional fields stay empty.
+func (k *Keyring) Encrypt(plaintext string, binding FieldBinding) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) > refreshInterval
+	k.mu.RUnlock()
+	if stale && k.db != nil {
+		// Keep the current keys if the database is briefly unavailable
+		_ = k.Reload()
+	}
+
+	k.mu.RLock()
+	version, key := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+	if key == nil {
+		return "", fmt.Errorf("no active data key")
+	}
+
+	sealed, err := seal(key, []byte(plaintext), k.fieldAdditionalData(version, binding))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%d:%s", ciphertextPrefix, version, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// Decrypt opens a value written by Encrypt for the same row. Empty values stay
+// empty; any other value that is not a ciphertext is an error.
+func (k *Keyring) Decrypt(value string, binding FieldBinding) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+
+	version, payload, err := parseCiphertext(value)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := k.key(version)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	plaintext, err := open(key, sealed, k.fieldAdditionalData(version, binding))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// blobPrefix marks blobs written by EncryptBlob; the key version follows as a
+// decimal and a colon, then the sealed bytes.
+const blobPrefix = "encblob:"
+
+// EncryptBlob seals a whole file with the active data key. The name is bound
+// into the ciphertext so that a blob cannot be swapped for another one.
+func (k *Keyring) EncryptBlob(name string, plaintext []byte) ([]byte, error) {
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) > refreshInterval
+	k.mu.RUnlock()
+	if stale && k.db != nil {
+		_ = k.Reload()
+	}
+
+	k.mu.RLock()
+	version, key := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+	if key == nil {
+		return nil, fmt.Errorf("no active data key")
+	}
+
+	sealed, err := seal(key, plaintext, k.blobAdditionalData(version, name))
+	if err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("%s%d:", blobPrefix, version)
+	return append([]byte(header), sealed...), nil
+}
+
+// DecryptBlob opens a blob written by EncryptBlob under the same name
+func (k *Keyring) DecryptBlob(name string, blob []byte) ([]byte, error) {
+	version, sealed, err := parseBlob(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := k.key(version)
+	if err != nil {
+		return nil, err
+	}
+	return open(key, sealed, k.blobAdditionalData(version, name))
+}
+
+// BlobNeedsRekey reports whether a blob is sealed with a key other than the
+// active one
+func (k *Keyring) BlobNeedsRekey(blob []byte) bool {
+	version, _, err := parseBlob(blob)
+	return err != nil || version != k.ActiveVersion()
+}
+
+func parseBlob(blob []byte) (int, []byte, error) {
+	if !bytes.HasPrefix(blob, []byte(blobPrefix)) {
+		return 0, nil, fmt.Errorf("blob is not encrypted")
+	}
+	rest := blob[len(blobPrefix):]
+	end := bytes.IndexByte(rest, ':')
+	if end < 1 {
+		return 0, nil, fmt.Errorf("malformed blob header")
+	}
+	version, err := strconv.Atoi(string(rest[:end]))
+	if err != nil {
+		return 0, nil, fmt.Errorf("malformed blob version")
+	}
+	return version, rest[end+1:], nil
+}
+
+// BlindIndex computes a keyed HMAC of a normalised value for exact-match
+// lookups on encrypted columns. The domain keeps equal values in different
+// fields from producing the same index. Empty values have no index.
+func (k *Keyring) BlindIndex(domain, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	k.mu.RLock()
+	key := k.indexKey
+	k.mu.RUnlock()
+	if key == nil {
+		return "", fmt.Errorf("no index key")
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(domain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// BlindIndex computes a blind index with the default keyring
+func BlindIndex(domain, value string) (string, error) {
+	keyring := Default()
+	if keyring == nil {
+		return "", fmt.Errorf("field encryption is not configured")
+	}
+	return keyring.BlindIndex(domain, value)
+}
+
+// NeedsRekey reports whether a stored value is plaintext or sealed with a key
+// other than the active one.
+func (k *Keyring) NeedsRekey(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	version, _, err := parseCiphertext(value)
+	return err != nil || version != k.ActiveVersion()
+}
+
+func (k *Keyring) key(version int) ([]byte, error) {
+	k.mu.RLock()
+	key := k.keys[version]
+	k.mu.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+	if k.db == nil {
+		return nil, fmt.Errorf("data key %d not found", version)
+	}
+
+	// The key may have been created by another process since we loaded
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if key = k.keys[version]; key == nil {
+		return nil, fmt.Errorf("data key %d not found", version)
+	}
+	return key, nil
+}
+
+// fieldAdditionalData binds field ciphertexts to their tenant, key version and
+// row
+func (k *Keyring) fieldAdditionalData(version int, binding FieldBinding) []byte {
+	return []byte(fmt.Sprintf("%s:%d:field:%s.%s:%d", k.tenant, version, binding.Table, binding.Column, binding.RowID))
+}
+
+func (k *Keyring) blobAdditionalData(version int, name string) []byte {
+	return []byte(fmt.Sprintf("%s:%d:blob:%s", k.tenant, version, name))
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+func parseCiphertext(value string) (int, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(value, ciphertextPrefix), ":", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("malformed ciphertext")
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("malformed ciphertext version")
+	}
+	return version, parts[1], nil
+}
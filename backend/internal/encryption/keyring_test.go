@@ -0,0 +1,184 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyring(tenant string) *Keyring {
+	keys := map[int][]byte{1: make([]byte, 32), 2: []byte("0123456789abcdef0123456789abcdef")}
+	return NewStaticKeyring(tenant, keys, 2, []byte("fedcba9876543210fedcba9876543210"))
+}
+
+func TestLocalKMS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "master.key")
+
+	t.Run("CreatesMissingKeyFile", func(t *testing.T) {
+		_, err := NewLocalKMS(path, false)
+		assert.Error(t, err)
+
+		kms, err := NewLocalKMS(path, true)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(kms.KeyID(), "local:"))
+	})
+
+	t.Run("RotationKeepsOldKeysReadable", func(t *testing.T) {
+		kms, err := NewLocalKMS(path, false)
+		require.NoError(t, err)
+
+		dataKey := []byte("0123456789abcdef0123456789abcdef")
+		oldID := kms.KeyID()
+		wrapped, err := kms.Wrap(dataKey)
+		require.NoError(t, err)
+
+		require.NoError(t, kms.RotateMasterKey())
+		assert.NotEqual(t, oldID, kms.KeyID())
+
+		reopened, err := NewLocalKMS(path, false)
+		require.NoError(t, err)
+		unwrapped, err := reopened.Unwrap(oldID, wrapped)
+		require.NoError(t, err)
+		assert.Equal(t, dataKey, unwrapped)
+	})
+}
+
+func TestKeyringEncryption(t *testing.T) {
+	keyring := newTestKeyring("default")
+	ssn := FieldBinding{Table: "patients", Column: "ssn", RowID: 7}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		ciphertext, err := keyring.Encrypt("123-45-6789", ssn)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(ciphertext, "enc:2:"))
+		assert.NotContains(t, ciphertext, "6789")
+
+		plaintext, err := keyring.Decrypt(ciphertext, ssn)
+		require.NoError(t, err)
+		assert.Equal(t, "123-45-6789", plaintext)
+	})
+
+	t.Run("RandomNonce", func(t *testing.T) {
+		first, _ := keyring.Encrypt("Hypertension", ssn)
+		second, _ := keyring.Encrypt("Hypertension", ssn)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("EmptyAndPlaintextValues", func(t *testing.T) {
+		ciphertext, err := keyring.Encrypt("", ssn)
+		require.NoError(t, err)
+		assert.Empty(t, ciphertext)
+
+		plaintext, err := keyring.Decrypt("", ssn)
+		require.NoError(t, err)
+		assert.Empty(t, plaintext)
+
+		_, err = keyring.Decrypt("123-45-6789", ssn)
+		assert.Error(t, err)
+	})
+
+	t.Run("NeedsRekey", func(t *testing.T) {
+		current, _ := keyring.Encrypt("Lisinopril", ssn)
+		keyring.active = 1
+		old, _ := keyring.Encrypt("Lisinopril", ssn)
+		keyring.active = 2
+
+		assert.False(t, keyring.NeedsRekey(current))
+		assert.True(t, keyring.NeedsRekey(old))
+		assert.True(t, keyring.NeedsRekey("plaintext"))
+		assert.False(t, keyring.NeedsRekey(""))
+
+		plaintext, err := keyring.Decrypt(old, ssn)
+		require.NoError(t, err)
+		assert.Equal(t, "Lisinopril", plaintext)
+	})
+
+	t.Run("RejectsUnboundValues", func(t *testing.T) {
+		sealed, err := seal(keyring.keys[2], []byte("Lisinopril"), []byte("default:2"))
+		require.NoError(t, err)
+
+		_, err = keyring.Decrypt("enc:2:"+base64.StdEncoding.EncodeToString(sealed), ssn)
+		assert.Error(t, err)
+	})
+
+	t.Run("BoundToTenant", func(t *testing.T) {
+		ciphertext, _ := keyring.Encrypt("123-45-6789", ssn)
+
+		_, err := newTestKeyring("other").Decrypt(ciphertext, ssn)
+		assert.Error(t, err)
+	})
+
+	t.Run("BoundToRowAndColumn", func(t *testing.T) {
+		ciphertext, _ := keyring.Encrypt("123-45-6789", ssn)
+
+		_, err := keyring.Decrypt(ciphertext, FieldBinding{Table: "patients", Column: "ssn", RowID: 8})
+		assert.Error(t, err)
+		_, err = keyring.Decrypt(ciphertext, FieldBinding{Table: "patients", Column: "phone", RowID: 7})
+		assert.Error(t, err)
+		_, err = keyring.Decrypt(ciphertext, FieldBinding{Table: "patient_identifiers", Column: "ssn", RowID: 7})
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectsTampering", func(t *testing.T) {
+		ciphertext, _ := keyring.Encrypt("123-45-6789", ssn)
+		tampered := ciphertext[:len(ciphertext)-4] + "AAA="
+
+		_, err := keyring.Decrypt(tampered, ssn)
+		assert.Error(t, err)
+	})
+}
+
+func TestBlindIndex(t *testing.T) {
+	keyring := newTestKeyring("default")
+
+	first, err := keyring.BlindIndex("patient.ssn", "123456789")
+	require.NoError(t, err)
+	second, _ := keyring.BlindIndex("patient.ssn", "123456789")
+	assert.Equal(t, first, second)
+	assert.Len(t, first, 64)
+	assert.NotContains(t, first, "6789")
+
+	other, _ := keyring.BlindIndex("patient.ssn", "123456780")
+	assert.NotEqual(t, first, other)
+
+	otherDomain, _ := keyring.BlindIndex("patient.phone", "123456789")
+	assert.NotEqual(t, first, otherDomain)
+
+	empty, err := keyring.BlindIndex("patient.ssn", "")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = (&Keyring{}).BlindIndex("patient.ssn", "123456789")
+	assert.Error(t, err)
+}
+
+func TestBlobEncryption(t *testing.T) {
+	keyring := newTestKeyring("default")
+	pdf := []byte("%PDF-1.4 referral letter")
+
+	blob, err := keyring.EncryptBlob("attachments/1/abc", pdf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(blob), "encblob:2:"))
+	assert.NotContains(t, string(blob), "referral")
+
+	plaintext, err := keyring.DecryptBlob("attachments/1/abc", blob)
+	require.NoError(t, err)
+	assert.Equal(t, pdf, plaintext)
+
+	_, err = keyring.DecryptBlob("attachments/1/other", blob)
+	assert.Error(t, err, "blob is bound to its name")
+
+	_, err = keyring.DecryptBlob("attachments/1/abc", pdf)
+	assert.Error(t, err, "plaintext is not accepted")
+
+	assert.False(t, keyring.BlobNeedsRekey(blob))
+	keyring.active = 1
+	old, _ := keyring.EncryptBlob("attachments/1/abc", pdf)
+	keyring.active = 2
+	assert.True(t, keyring.BlobNeedsRekey(old))
+	assert.True(t, keyring.BlobNeedsRekey(pdf))
+}
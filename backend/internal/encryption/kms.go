@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KMS wraps and unwraps tenant data keys with a master key that never leaves
+// the key management system.
+type KMS interface {
+	// KeyID identifies the master key Wrap currently uses
+	KeyID() string
+	Wrap(dataKey []byte) ([]byte, error)
+	Unwrap(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// MasterKeyRotator is implemented by KMS backends whose master key can be
+// rotated from this application. Data keys wrapped under a previous master key
+// stay readable until they are rewrapped.
+type MasterKeyRotator interface {
+	RotateMasterKey() error
+}
+
+// NewKMS builds the configured KMS backend. Only the local file backend ships
+// with the application; it is meant for development and tests.
+func NewKMS(provider, masterKeyPath string, createIfMissing bool) (KMS, error) {
+	switch provider {
+	case "", "local":
+		return NewLocalKMS(masterKeyPath, createIfMissing)
+	default:
+		return nil, fmt.Errorf("unsupported KMS provider %q", provider)
+	}
+}
+
+// LocalKMS keeps master keys in a file, one base64 key per line with the
+// current key first.
+type LocalKMS struct {
+	path string
+	keys [][]byte
+}
+
+func NewLocalKMS(path string, createIfMissing bool) (*LocalKMS, error) {
+	kms := &LocalKMS{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && createIfMissing {
+		if err := kms.RotateMasterKey(); err != nil {
+			return nil, err
+		}
+		return kms, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("master key file contains an invalid key")
+		}
+		kms.keys = append(kms.keys, key)
+	}
+	if len(kms.keys) == 0 {
+		return nil, fmt.Errorf("master key file is empty")
+	}
+
+	return kms, nil
+}
+
+func (k *LocalKMS) KeyID() string {
+	return localKeyID(k.keys[0])
+}
+
+func (k *LocalKMS) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(k.keys[0], dataKey, []byte(k.KeyID()))
+}
+
+func (k *LocalKMS) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	for _, key := range k.keys {
+		if localKeyID(key) == keyID {
+			return open(key, wrapped, []byte(keyID))
+		}
+	}
+	return nil, fmt.Errorf("master key %s not found", keyID)
+}
+
+// RotateMasterKey generates a new current master key and keeps the previous
+// ones for unwrapping.
+func (k *LocalKMS) RotateMasterKey() error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate master key: %w", err)
+	}
+	keys := append([][]byte{key}, k.keys...)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, base64.StdEncoding.EncodeToString(key))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("failed to create master key directory: %w", err)
+	}
+	if err := os.WriteFile(k.path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+
+	k.keys = keys
+	return nil
+}
+
+func localKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "local:" + hex.EncodeToString(sum[:8])
+}
+
+// seal encrypts plaintext with AES-256-GCM and prefixes the random nonce
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,172 @@
+package encryption
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"healthsecure/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// EncryptedColumn names a table column written through the encrypted
+// serializer.
+type EncryptedColumn struct {
+	Table  string
+	Column string
+}
+
+// RekeyOptions throttles a rekey so it can run beside live traffic.
+// EncryptPlaintext seals values stored before field encryption was enabled;
+// without it a plaintext value stops the rekey.
+type RekeyOptions struct {
+	BatchSize        int
+	Pause            time.Duration
+	EncryptPlaintext bool
+	Progress         func(column EncryptedColumn, scanned, rekeyed int)
+}
+
+// RekeyColumn re-encrypts every value in the column that is plaintext or
+// sealed with an inactive key, walking the table by primary key in batches.
+// Each value is updated only if it has not changed since it was read, so
+// concurrent writes from the application are never overwritten.
+func (k *Keyring) RekeyColumn(db *gorm.DB, column EncryptedColumn, opts RekeyOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	var lastID uint
+	scanned, rekeyed := 0, 0
+	for {
+		type row struct {
+			ID    uint
+			Value sql.NullString
+		}
+		var rows []row
+		if err := db.Table(column.Table).
+			Select(fmt.Sprintf("id, %s AS value", column.Column)).
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(opts.BatchSize).
+			Scan(&rows).Error; err != nil {
+			return rekeyed, fmt.Errorf("failed to read %s.%s: %w", column.Table, column.Column, err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		for _, r := range rows {
+			lastID = r.ID
+			scanned++
+			if !r.Value.Valid || !k.NeedsRekey(r.Value.String) {
+				continue
+			}
+
+			binding := FieldBinding{Table: column.Table, Column: column.Column, RowID: r.ID}
+			plaintext := r.Value.String
+			if IsEncrypted(r.Value.String) {
+				decrypted, err := k.Decrypt(r.Value.String, binding)
+				if err != nil {
+					return rekeyed, fmt.Errorf("failed to decrypt %s.%s for id %d: %w", column.Table, column.Column, r.ID, err)
+				}
+				plaintext = decrypted
+			} else if !opts.EncryptPlaintext {
+				return rekeyed, fmt.Errorf("%s.%s for id %d is not encrypted", column.Table, column.Column, r.ID)
+			}
+			ciphertext, err := k.Encrypt(plaintext, binding)
+			if err != nil {
+				return rekeyed, err
+			}
+
+			result := db.Table(column.Table).
+				Where(fmt.Sprintf("id = ? AND %s = ?", column.Column), r.ID, r.Value.String).
+				UpdateColumn(column.Column, ciphertext)
+			if result.Error != nil {
+				return rekeyed, fmt.Errorf("failed to update %s.%s for id %d: %w", column.Table, column.Column, r.ID, result.Error)
+			}
+			rekeyed += int(result.RowsAffected)
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(column, scanned, rekeyed)
+		}
+		if opts.Pause > 0 {
+			time.Sleep(opts.Pause)
+		}
+	}
+
+	return rekeyed, nil
+}
+
+// RekeyBlobs re-encrypts the stored blobs named by column that are sealed with
+// an inactive key, so that no blob still depends on a key about to be retired.
+// Blobs never change once written, so each is overwritten under its own name.
+// Blobs already removed from the store are skipped.
+func (k *Keyring) RekeyBlobs(db *gorm.DB, store storage.Store, column EncryptedColumn, opts RekeyOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	ctx := context.Background()
+	var lastID uint
+	scanned, rekeyed := 0, 0
+	for {
+		type row struct {
+			ID   uint
+			Name string
+		}
+		var rows []row
+		if err := db.Table(column.Table).
+			Select(fmt.Sprintf("id, %s AS name", column.Column)).
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(opts.BatchSize).
+			Scan(&rows).Error; err != nil {
+			return rekeyed, fmt.Errorf("failed to read %s.%s: %w", column.Table, column.Column, err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		for _, r := range rows {
+			lastID = r.ID
+			scanned++
+
+			blob, err := store.Get(ctx, r.Name)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return rekeyed, fmt.Errorf("failed to read blob for %s %d: %w", column.Table, r.ID, err)
+			}
+			if !k.BlobNeedsRekey(blob) {
+				continue
+			}
+
+			plaintext, err := k.DecryptBlob(r.Name, blob)
+			if err != nil {
+				return rekeyed, fmt.Errorf("failed to decrypt blob for %s %d: %w", column.Table, r.ID, err)
+			}
+			sealed, err := k.EncryptBlob(r.Name, plaintext)
+			if err != nil {
+				return rekeyed, err
+			}
+			if err := store.Put(ctx, r.Name, sealed); err != nil {
+				return rekeyed, fmt.Errorf("failed to write blob for %s %d: %w", column.Table, r.ID, err)
+			}
+			rekeyed++
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(column, scanned, rekeyed)
+		}
+		if opts.Pause > 0 {
+			time.Sleep(opts.Pause)
+		}
+	}
+
+	return rekeyed, nil
+}
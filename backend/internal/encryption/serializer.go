@@ -0,0 +1,232 @@
+package encryption
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Serializer encrypts string fields tagged `serializer:encrypted` with the
+// default keyring on write and decrypts them on read. Values are bound to the
+// table, column and primary key of their row, so the primary key must be
+// selected ahead of any encrypted column.
+type Serializer struct{}
+
+func init() {
+	schema.RegisterSerializer("encrypted", Serializer{})
+	schema.RegisterSerializer("encryptedjson", JSONSerializer{})
+}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("unsupported encrypted value type %T for %s", dbValue, field.Name)
+	}
+
+	if value != "" {
+		keyring := Default()
+		if keyring == nil {
+			return fmt.Errorf("field encryption is not configured")
+		}
+
+		binding, err := bindingFor(ctx, field, dst)
+		if err != nil {
+			return err
+		}
+		plaintext, err := keyring.Decrypt(value, binding)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", field.Name, err)
+		}
+		value = plaintext
+	}
+
+	return field.Set(ctx, dst, value)
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string", field.Name)
+	}
+
+	keyring := Default()
+	if keyring == nil {
+		return nil, fmt.Errorf("field encryption is not configured")
+	}
+
+	binding, err := bindingFor(ctx, field, dst)
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Encrypt(value, binding)
+}
+
+// JSONSerializer stores fields tagged `serializer:encryptedjson` as encrypted
+// JSON, for structured content that is as sensitive as the text fields. Zero
+// values are stored empty.
+type JSONSerializer struct{}
+
+func (JSONSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("unsupported encrypted value type %T for %s", dbValue, field.Name)
+	}
+	if value == "" {
+		return nil
+	}
+
+	keyring := Default()
+	if keyring == nil {
+		return fmt.Errorf("field encryption is not configured")
+	}
+
+	binding, err := bindingFor(ctx, field, dst)
+	if err != nil {
+		return err
+	}
+	plaintext, err := keyring.Decrypt(value, binding)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", field.Name, err)
+	}
+	value = plaintext
+
+	decoded := reflect.New(field.FieldType)
+	if err := json.Unmarshal([]byte(value), decoded.Interface()); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", field.Name, err)
+	}
+	return field.Set(ctx, dst, decoded.Elem().Interface())
+}
+
+func (JSONSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	if fieldValue == nil || reflect.ValueOf(fieldValue).IsZero() {
+		return "", nil
+	}
+
+	data, err := json.Marshal(fieldValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", field.Name, err)
+	}
+
+	keyring := Default()
+	if keyring == nil {
+		return nil, fmt.Errorf("field encryption is not configured")
+	}
+
+	binding, err := bindingFor(ctx, field, dst)
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Encrypt(string(data), binding)
+}
+
+// bindingFor identifies the row an encrypted field belongs to. The primary key
+// is still zero while a row is being inserted; RegisterCallbacks seals the
+// value again once the database has assigned it.
+func bindingFor(ctx context.Context, field *schema.Field, dst reflect.Value) (FieldBinding, error) {
+	binding := FieldBinding{Table: field.Schema.Table, Column: field.DBName}
+
+	primary := field.Schema.PrioritizedPrimaryField
+	if primary == nil {
+		return binding, fmt.Errorf("encrypted field %s has no primary key to bind to", field.Name)
+	}
+	id, ok := primary.ReflectValueOf(ctx, dst).Interface().(uint)
+	if !ok {
+		return binding, fmt.Errorf("encrypted field %s needs an unsigned integer primary key", field.Name)
+	}
+	binding.RowID = id
+	return binding, nil
+}
+
+// RegisterCallbacks adds the create callback that binds encrypted fields to
+// the primary key of each inserted row. It must be registered on every
+// connection that writes encrypted models.
+func RegisterCallbacks(db *gorm.DB) error {
+	return db.Callback().Create().After("gorm:create").Register("encryption:bind_rows", bindCreatedRows)
+}
+
+func bindCreatedRows(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+
+	var fields []*schema.Field
+	selected, restricted := db.Statement.SelectAndOmitColumns(true, false)
+	for _, field := range db.Statement.Schema.Fields {
+		if !isEncryptedField(field) {
+			continue
+		}
+		if v, ok := selected[field.DBName]; (ok && !v) || (!ok && restricted) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	ctx := db.Statement.Context
+	rebind := func(row reflect.Value) {
+		primary := db.Statement.Schema.PrioritizedPrimaryField
+		id, zero := primary.ValueOf(ctx, row)
+		if zero {
+			db.AddError(fmt.Errorf("cannot bind encrypted fields of %s without a primary key", db.Statement.Schema.Table))
+			return
+		}
+
+		updates := map[string]interface{}{}
+		for _, field := range fields {
+			value, zero := field.ValueOf(ctx, row)
+			if zero {
+				continue
+			}
+			sealed, err := value.(driver.Valuer).Value()
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+			updates[field.DBName] = sealed
+		}
+		if len(updates) == 0 {
+			return
+		}
+
+		db.AddError(db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+			Where(clause.Eq{Column: clause.Column{Name: primary.DBName}, Value: id}).
+			UpdateColumns(updates).Error)
+	}
+
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len() && db.Error == nil; i++ {
+			rebind(reflect.Indirect(db.Statement.ReflectValue.Index(i)))
+		}
+	case reflect.Struct:
+		rebind(db.Statement.ReflectValue)
+	}
+}
+
+func isEncryptedField(field *schema.Field) bool {
+	switch field.Serializer.(type) {
+	case Serializer, *Serializer, JSONSerializer, *JSONSerializer:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package fhir
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewCollection starts a collection bundle, the form used to hand over a set
+// of resources with no server to resolve references against
+func NewCollection(at time.Time) *Bundle {
+	return &Bundle{
+		ResourceType: "Bundle",
+		ID:           uuid.NewString(),
+		Type:         "collection",
+		Timestamp:    dateTime(at),
+	}
+}
+
+// NewEntryURL returns a fresh fullUrl for a bundle entry. Resources in a
+// collection reference each other by these URLs.
+func NewEntryURL() string {
+	return "urn:uuid:" + uuid.NewString()
+}
+
+// Add appends a resource under its fullUrl
+func (b *Bundle) Add(fullURL string, resource interface{}) {
+	b.Entry = append(b.Entry, BundleEntry{FullURL: fullURL, Resource: resource})
+}
+
+// NewSearchSet starts the bundle returned by a search, with the total number
+// of matches across all pages
+func NewSearchSet(total int64, at time.Time) *Bundle {
+	return &Bundle{
+		ResourceType: "Bundle",
+		ID:           uuid.NewString(),
+		Meta:         &Meta{LastUpdated: dateTime(at)},
+		Type:         "searchset",
+		Total:        &total,
+	}
+}
+
+// AddMatch appends a resource that matched the search
+func (b *Bundle) AddMatch(fullURL string, resource interface{}) {
+	b.Entry = append(b.Entry, BundleEntry{FullURL: fullURL, Resource: resource, Search: &BundleEntrySearch{Mode: "match"}})
+}
+
+// AddLink adds a paging link such as self or next
+func (b *Bundle) AddLink(relation, url string) {
+	b.Link = append(b.Link, BundleLink{Relation: relation, URL: url})
+}
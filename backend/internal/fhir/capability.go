@@ -0,0 +1,134 @@
+package fhir
+
+import "time"
+
+// FHIRVersion is the FHIR release the server implements
+const FHIRVersion = "4.0.1"
+
+type CapabilitySoftware struct {
+	Name string `json:"name"`
+}
+
+type CapabilityInteraction struct {
+	Code string `json:"code"`
+}
+
+type CapabilitySearchParam struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+type CapabilityResource struct {
+	Type        string                  `json:"type"`
+	Interaction []CapabilityInteraction `json:"interaction"`
+	SearchParam []CapabilitySearchParam `json:"searchParam,omitempty"`
+}
+
+type CapabilitySecurity struct {
+	Description string `json:"description,omitempty"`
+}
+
+type CapabilityRest struct {
+	Mode     string               `json:"mode"`
+	Security *CapabilitySecurity  `json:"security,omitempty"`
+	Resource []CapabilityResource `json:"resource"`
+}
+
+type CapabilityStatement struct {
+	ResourceType string              `json:"resourceType"`
+	Status       string              `json:"status"`
+	Date         string              `json:"date"`
+	Kind         string              `json:"kind"`
+	Software     *CapabilitySoftware `json:"software,omitempty"`
+	FHIRVersion  string              `json:"fhirVersion"`
+	Format       []string            `json:"format"`
+	Rest         []CapabilityRest    `json:"rest"`
+}
+
+// lastUpdatedParam is supported by every resource
+var lastUpdatedParam = CapabilitySearchParam{Name: "_lastUpdated", Type: "date", Documentation: "When the resource last changed"}
+
+// capabilities lists the interactions and search parameters the server
+// implements. Searches other than Patient and AuditEvent are within one
+// patient's chart, so they require patient.
+var capabilities = []CapabilityResource{
+	{
+		Type:        "Patient",
+		Interaction: interactions("read", "search-type", "create", "update"),
+		SearchParam: []CapabilitySearchParam{
+			{Name: "name", Type: "string", Documentation: "Part of the given or family name"},
+			{Name: "family", Type: "string"},
+			{Name: "given", Type: "string"},
+			{Name: "birthdate", Type: "date"},
+			{Name: "identifier", Type: "token", Documentation: "system|value, or one of our MRNs without a system"},
+			lastUpdatedParam,
+		},
+	},
+	{
+		Type:        "Condition",
+		Interaction: interactions("read", "search-type"),
+		SearchParam: []CapabilitySearchParam{
+			{Name: "patient", Type: "reference"},
+			{Name: "clinical-status", Type: "token"},
+			lastUpdatedParam,
+		},
+	},
+	{
+		Type:        "Observation",
+		Interaction: interactions("read", "search-type", "create"),
+		SearchParam: []CapabilitySearchParam{
+			{Name: "patient", Type: "reference"},
+			{Name: "category", Type: "token", Documentation: "vital-signs or laboratory"},
+			{Name: "code", Type: "token", Documentation: "A LOINC code"},
+			{Name: "date", Type: "date", Documentation: "When the observation was made"},
+			lastUpdatedParam,
+		},
+	},
+	{
+		Type:        "MedicationStatement",
+		Interaction: interactions("read", "search-type"),
+		SearchParam: []CapabilitySearchParam{
+			{Name: "patient", Type: "reference"},
+			{Name: "status", Type: "token", Documentation: "active or stopped"},
+			lastUpdatedParam,
+		},
+	},
+	{
+		Type:        "AuditEvent",
+		Interaction: interactions("read", "search-type"),
+		SearchParam: []CapabilitySearchParam{
+			{Name: "patient", Type: "reference"},
+			{Name: "date", Type: "date", Documentation: "When the event was recorded"},
+			lastUpdatedParam,
+		},
+	},
+}
+
+// NewCapabilityStatement describes the server as of date
+func NewCapabilityStatement(date time.Time) *CapabilityStatement {
+	return &CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         dateTime(date),
+		Kind:         "instance",
+		Software:     &CapabilitySoftware{Name: "HealthSecure"},
+		FHIRVersion:  FHIRVersion,
+		Format:       []string{"application/fhir+json", "json"},
+		Rest: []CapabilityRest{{
+			Mode: "server",
+			Security: &CapabilitySecurity{
+				Description: "Bearer tokens issued by /api/auth/login. Declare a purpose of use with the X-Purpose-Of-Use header.",
+			},
+			Resource: capabilities,
+		}},
+	}
+}
+
+func interactions(codes ...string) []CapabilityInteraction {
+	result := make([]CapabilityInteraction, len(codes))
+	for i, code := range codes {
+		result[i] = CapabilityInteraction{Code: code}
+	}
+	return result
+}
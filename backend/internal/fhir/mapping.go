@@ -0,0 +1,458 @@
+package fhir
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+)
+
+// patientIDPrefix replaces the underscore of public patient IDs, which FHIR
+// resource ids may not contain
+const patientIDPrefix = "pt-"
+
+// PatientID returns the FHIR id of a patient. Patients are exposed only under
+// their opaque public ID.
+func PatientID(publicID string) string {
+	return patientIDPrefix + strings.TrimPrefix(publicID, models.PatientPublicIDPrefix)
+}
+
+// PatientPublicID reverses PatientID, returning "" for ids that are not ours
+func PatientPublicID(id string) string {
+	if !strings.HasPrefix(id, patientIDPrefix) {
+		return ""
+	}
+	return models.PatientPublicIDPrefix + strings.TrimPrefix(id, patientIDPrefix)
+}
+
+// NewPatient maps demographics. SSNs are never mapped: other organisations
+// identify the patient by our MRNs.
+func NewPatient(p *models.Patient) *Patient {
+	resource := &Patient{
+		ResourceType: "Patient",
+		ID:           PatientID(p.PublicID),
+		Meta:         meta(p.UpdatedAt),
+		BirthDate:    date(p.DateOfBirth),
+	}
+	if p.IsConfidential() {
+		resource.Meta = withSecurity(resource.Meta, Coding{System: SystemConfidential, Code: "R", Display: "restricted"})
+	}
+
+	for _, identifier := range p.Identifiers {
+		resource.Identifier = append(resource.Identifier, newIdentifier(identifier))
+	}
+	if p.FirstName != "" || p.LastName != "" {
+		name := HumanName{Use: "official", Family: p.LastName}
+		if p.FirstName != "" {
+			name.Given = []string{p.FirstName}
+		}
+		resource.Name = []HumanName{name}
+	}
+	if p.Phone != "" {
+		resource.Telecom = []ContactPoint{{System: "phone", Value: p.Phone}}
+	}
+	if p.Address != "" {
+		resource.Address = []Address{{Text: p.Address}}
+	}
+	if p.EmergencyContact != "" {
+		resource.Contact = []PatientContact{{Name: &HumanName{Text: p.EmergencyContact}}}
+	}
+	return resource
+}
+
+func newIdentifier(pi models.PatientIdentifier) Identifier {
+	identifier := Identifier{System: pi.System, Value: pi.Value}
+	switch pi.Type {
+	case models.IdentifierMRN:
+		identifier.Use = "usual"
+		identifier.Type = concept(SystemIdentifierType, "MR", "Medical record number")
+	case models.IdentifierExternalMRN:
+		identifier.Use = "secondary"
+		identifier.Type = concept(SystemIdentifierType, "MR", "Medical record number")
+	case models.IdentifierInsuranceMember:
+		identifier.Type = concept(SystemIdentifierType, "MB", "Member Number")
+	}
+	return identifier
+}
+
+// NewProblemCondition maps an entry on the problem list
+func NewProblemCondition(p *models.Problem, subject Reference) *Condition {
+	resource := &Condition{
+		ResourceType:       "Condition",
+		ID:                 fmt.Sprintf("problem-%d", p.ID),
+		Meta:               meta(p.UpdatedAt),
+		VerificationStatus: concept(SystemCondVerify, "confirmed", "Confirmed"),
+		Category:           []CodeableConcept{*concept(SystemCondCategory, "problem-list-item", "Problem List Item")},
+		Subject:            subject,
+		OnsetDateTime:      dateTimePtr(p.OnsetDate),
+		AbatementDateTime:  dateTimePtr(p.ResolvedDate),
+		RecordedDate:       dateTime(p.CreatedAt),
+	}
+	if p.Status != "" {
+		resource.ClinicalStatus = concept(SystemCondClinical, string(p.Status), "")
+	}
+	if p.Condition != "" {
+		resource.Code = &CodeableConcept{Text: p.Condition}
+	}
+	return resource
+}
+
+// NewDiagnosisConditions maps the coded diagnoses of a medical record, in the
+// order they were entered
+func NewDiagnosisConditions(record *models.MedicalRecord, subject Reference) []*Condition {
+	conditions := make([]*Condition, 0, len(record.Diagnoses))
+	for i, d := range record.Diagnoses {
+		conditions = append(conditions, &Condition{
+			ResourceType:       "Condition",
+			ID:                 fmt.Sprintf("diagnosis-%d-%d", record.ID, i+1),
+			Meta:               meta(record.UpdatedAt),
+			VerificationStatus: concept(SystemCondVerify, "confirmed", "Confirmed"),
+			Category:           []CodeableConcept{*concept(SystemCondCategory, "encounter-diagnosis", "Encounter Diagnosis")},
+			Code: &CodeableConcept{
+				Coding: []Coding{{System: codeSystemURI(d.System), Code: d.Code, Display: d.Display}},
+				Text:   d.Display,
+			},
+			Subject:      subject,
+			RecordedDate: dateTime(record.CreatedAt),
+		})
+	}
+	return conditions
+}
+
+func codeSystemURI(system models.CodeSystem) string {
+	switch system {
+	case models.CodeSystemICD10CM:
+		return SystemICD10CM
+	case models.CodeSystemSNOMED:
+		return SystemSNOMED
+	}
+	return ""
+}
+
+// NewMedicationStatement maps a medication order
+func NewMedicationStatement(mo *models.MedicationOrder, subject Reference) *MedicationStatement {
+	resource := &MedicationStatement{
+		ResourceType:              "MedicationStatement",
+		ID:                        strconv.FormatUint(uint64(mo.ID), 10),
+		Meta:                      meta(mo.UpdatedAt),
+		Status:                    "active",
+		MedicationCodeableConcept: CodeableConcept{Text: mo.Drug},
+		Subject:                   subject,
+	}
+	if mo.Status == models.MedicationStatusDiscontinued {
+		resource.Status = "stopped"
+	}
+	if start, end := dateTime(mo.StartDate), dateTimePtr(mo.StopDate); start != "" || end != "" {
+		resource.EffectivePeriod = &Period{Start: start, End: end}
+	}
+	if mo.Prescriber.Name != "" {
+		resource.InformationSource = &Reference{Display: mo.Prescriber.Name}
+	}
+
+	dosage := Dosage{Text: joinNonEmpty(" ", mo.Dose, string(mo.Route), mo.Frequency)}
+	if mo.Route != "" {
+		dosage.Route = &CodeableConcept{Text: string(mo.Route)}
+	}
+	if dosage.Text != "" {
+		resource.Dosage = []Dosage{dosage}
+	}
+	return resource
+}
+
+var interpretationCodes = map[models.Interpretation]Coding{
+	models.InterpretationNormal:       {System: SystemInterpretation, Code: "N", Display: "Normal"},
+	models.InterpretationLow:          {System: SystemInterpretation, Code: "L", Display: "Low"},
+	models.InterpretationHigh:         {System: SystemInterpretation, Code: "H", Display: "High"},
+	models.InterpretationAbnormal:     {System: SystemInterpretation, Code: "A", Display: "Abnormal"},
+	models.InterpretationCriticalLow:  {System: SystemInterpretation, Code: "LL", Display: "Critical low"},
+	models.InterpretationCriticalHigh: {System: SystemInterpretation, Code: "HH", Display: "Critical high"},
+	models.InterpretationCritical:     {System: SystemInterpretation, Code: "AA", Display: "Critical abnormal"},
+}
+
+// NewObservation maps a vital sign or lab result
+func NewObservation(o *models.Observation, subject Reference) *Observation {
+	resource := &Observation{
+		ResourceType:      "Observation",
+		ID:                strconv.FormatUint(uint64(o.ID), 10),
+		Meta:              meta(o.UpdatedAt),
+		Status:            "final",
+		Code:              CodeableConcept{Coding: []Coding{{System: SystemLOINC, Code: o.Code, Display: o.Display}}, Text: o.Display},
+		Subject:           subject,
+		EffectiveDateTime: dateTime(o.EffectiveAt),
+		ValueString:       o.ValueString,
+	}
+
+	switch o.Category {
+	case models.CategoryVitalSigns:
+		resource.Category = []CodeableConcept{*concept(SystemObsCategory, "vital-signs", "Vital Signs")}
+	case models.CategoryLaboratory:
+		resource.Category = []CodeableConcept{*concept(SystemObsCategory, "laboratory", "Laboratory")}
+	}
+	if o.ValueQuantity != nil {
+		resource.ValueQuantity = quantity(o.ValueQuantity, o.Unit)
+	}
+	if coding, ok := interpretationCodes[o.Interpretation]; ok {
+		resource.Interpretation = []CodeableConcept{{Coding: []Coding{coding}}}
+	}
+	if o.ReferenceLow != nil || o.ReferenceHigh != nil {
+		resource.ReferenceRange = []ObservationReferenceRange{{
+			Low:  quantity(o.ReferenceLow, o.Unit),
+			High: quantity(o.ReferenceHigh, o.Unit),
+		}}
+	}
+	if o.Comment != "" {
+		resource.Note = []Annotation{{Text: o.Comment}}
+	}
+	if o.Recorder.Name != "" {
+		resource.Performer = []Reference{{Display: o.Recorder.Name}}
+	}
+	return resource
+}
+
+// NewAllergyIntolerance maps an allergy
+func NewAllergyIntolerance(a *models.Allergy, subject Reference) *AllergyIntolerance {
+	resource := &AllergyIntolerance{
+		ResourceType:       "AllergyIntolerance",
+		ID:                 strconv.FormatUint(uint64(a.ID), 10),
+		Meta:               meta(a.UpdatedAt),
+		VerificationStatus: concept(SystemAllergyVerify, "confirmed", "Confirmed"),
+		Patient:            subject,
+		RecordedDate:       dateTime(a.CreatedAt),
+	}
+	if a.Status != "" {
+		resource.ClinicalStatus = concept(SystemAllergyStatus, string(a.Status), "")
+	}
+	if a.Substance != "" {
+		resource.Code = &CodeableConcept{Text: a.Substance}
+	}
+
+	severity := ""
+	switch a.Severity {
+	case models.AllergySeverityMild, models.AllergySeverityModerate:
+		severity = string(a.Severity)
+		resource.Criticality = "low"
+	case models.AllergySeveritySevere, models.AllergySeverityLifeThreatening:
+		severity = "severe"
+		resource.Criticality = "high"
+	}
+	if a.Reaction != "" {
+		resource.Reaction = []AllergyReaction{{
+			Manifestation: []CodeableConcept{{Text: a.Reaction}},
+			Severity:      severity,
+		}}
+	}
+	return resource
+}
+
+// sensitivityLabels are the security labels that tell a receiving system how
+// to protect a classified note
+var sensitivityLabels = map[models.SensitivityLevel]Coding{
+	models.SensitivityRestricted:     {System: SystemConfidential, Code: "R", Display: "restricted"},
+	models.SensitivityVeryRestricted: {System: SystemConfidential, Code: "V", Display: "very restricted"},
+	models.SensitivitySubstanceUse:   {System: SystemActCode, Code: "ETH", Display: "substance abuse information sensitivity"},
+	models.SensitivityMentalHealth:   {System: SystemActCode, Code: "PSY", Display: "psychiatry disorder information sensitivity"},
+	models.SensitivityReproductive:   {System: SystemActCode, Code: "SEX", Display: "sexuality and reproductive health information sensitivity"},
+}
+
+// NewDocumentReference maps a medical record to a clinical note whose text is
+// carried inline
+func NewDocumentReference(record *models.MedicalRecord, subject Reference) *DocumentReference {
+	date := dateTime(record.CreatedAt)
+	if signed := dateTimePtr(record.SignedAt); signed != "" {
+		date = signed
+	}
+
+	resource := &DocumentReference{
+		ResourceType: "DocumentReference",
+		ID:           strconv.FormatUint(uint64(record.ID), 10),
+		Meta:         meta(record.UpdatedAt),
+		Status:       "current",
+		Type:         concept(SystemLOINC, "11506-3", "Progress note"),
+		Category:     []CodeableConcept{*concept(SystemDocCategory, "clinical-note", "Clinical Note")},
+		Subject:      subject,
+		Date:         date,
+		Content: []DocumentReferenceContent{{Attachment: Attachment{
+			ContentType: "text/plain; charset=utf-8",
+			Data:        base64.StdEncoding.EncodeToString([]byte(record.Narrative())),
+			Title:       "Clinical note",
+			Creation:    dateTime(record.CreatedAt),
+		}}},
+	}
+
+	switch record.Status {
+	case models.RecordStatusSigned:
+		resource.DocStatus = "final"
+	case models.RecordStatusDraft, models.RecordStatusPendingCosign:
+		resource.DocStatus = "preliminary"
+	}
+	if record.Doctor.Name != "" {
+		resource.Author = []Reference{{Display: record.Doctor.Name}}
+	}
+	if label, ok := sensitivityLabels[record.Sensitivity]; ok {
+		resource.SecurityLabel = []CodeableConcept{{Coding: []Coding{label}}}
+	}
+	return resource
+}
+
+var auditEventTypes = map[models.AuditAction]Coding{
+	models.ActionLogin:        {System: SystemDICOM, Code: "110114", Display: "User Authentication"},
+	models.ActionLogout:       {System: SystemDICOM, Code: "110114", Display: "User Authentication"},
+	models.ActionUnauthorized: {System: SystemDICOM, Code: "110113", Display: "Security Alert"},
+	models.ActionDownload:     {System: SystemDICOM, Code: "110106", Display: "Export"},
+	models.ActionExport:       {System: SystemDICOM, Code: "110106", Display: "Export"},
+}
+
+var auditEventSubtypes = map[models.AuditAction]Coding{
+	models.ActionLogin:  {System: SystemDICOM, Code: "110122", Display: "Login"},
+	models.ActionLogout: {System: SystemDICOM, Code: "110123", Display: "Logout"},
+}
+
+var auditEventActions = map[models.AuditAction]string{
+	models.ActionCreate:          "C",
+	models.ActionView:            "R",
+	models.ActionDownload:        "R",
+	models.ActionExport:          "R",
+	models.ActionEmergencyAccess: "R",
+	models.ActionUpdate:          "U",
+	models.ActionDelete:          "D",
+}
+
+var purposeCodes = map[models.PurposeOfUse]Coding{
+	models.PurposeTreatment:      {System: SystemActReason, Code: "TREAT", Display: "treatment"},
+	models.PurposePayment:        {System: SystemActReason, Code: "HPAYMT", Display: "healthcare payment"},
+	models.PurposeOperations:     {System: SystemActReason, Code: "HOPERAT", Display: "healthcare operations"},
+	models.PurposeResearch:       {System: SystemActReason, Code: "HRESCH", Display: "healthcare research"},
+	models.PurposeLegal:          {System: SystemActReason, Code: "HLEGAL", Display: "legal"},
+	models.PurposePatientRequest: {System: SystemActReason, Code: "PATRQT", Display: "patient requested"},
+}
+
+// NewAuditEvent maps an audit log entry. Accesses to patient data are Patient
+// Record events; the patient is referenced while their chart still exists.
+func NewAuditEvent(log *models.AuditLog) *AuditEvent {
+	event := &AuditEvent{
+		ResourceType: "AuditEvent",
+		ID:           strconv.FormatUint(uint64(log.ID), 10),
+		Type:         Coding{System: SystemDICOM, Code: "110110", Display: "Patient Record"},
+		Action:       auditEventActions[log.Action],
+		Recorded:     dateTime(log.Timestamp),
+		Outcome:      "0",
+		Source:       AuditEventSource{Observer: Reference{Display: "HealthSecure"}},
+	}
+	if eventType, ok := auditEventTypes[log.Action]; ok {
+		event.Type = eventType
+	}
+	if subtype, ok := auditEventSubtypes[log.Action]; ok {
+		event.Subtype = []Coding{subtype}
+	}
+	if !log.Success || log.Action == models.ActionUnauthorized {
+		event.Outcome = "4"
+		event.OutcomeDesc = log.ErrorMessage
+	}
+
+	if purpose, ok := purposeCodes[log.Purpose]; ok {
+		event.PurposeOfEvent = append(event.PurposeOfEvent, CodeableConcept{Coding: []Coding{purpose}})
+	}
+	if log.IsEmergencyAccess() {
+		event.PurposeOfEvent = append(event.PurposeOfEvent, *concept(SystemActReason, "ETREAT", "Emergency Treatment"))
+	}
+
+	agent := AuditEventAgent{AltID: strconv.FormatUint(uint64(log.UserID), 10), Requestor: true}
+	if log.User.Name != "" {
+		agent.Who = &Reference{Display: log.User.Name}
+	}
+	if log.IPAddress != "" {
+		agent.Network = &AuditEventAgentNetwork{Address: log.IPAddress, Type: "2"}
+	}
+	event.Agent = []AuditEventAgent{agent}
+
+	if log.Patient != nil && log.Patient.PublicID != "" {
+		event.Entity = append(event.Entity, AuditEventEntity{
+			What: &Reference{Reference: "Patient/" + PatientID(log.Patient.PublicID)},
+			Type: &Coding{System: "http://terminology.hl7.org/CodeSystem/audit-entity-type", Code: "1", Display: "Person"},
+		})
+	}
+
+	entity := AuditEventEntity{Description: log.Resource}
+	if label, ok := sensitivityLabels[models.SensitivityLevel(log.Sensitivity)]; ok {
+		entity.SecurityLabel = []Coding{label}
+	}
+	for _, detail := range []AuditEventEntityDetail{
+		{Type: "disclosed_fields", ValueString: log.DisclosedFields},
+		{Type: "recipient", ValueString: log.Recipient},
+		{Type: "reason", ValueString: log.Reason},
+	} {
+		if detail.ValueString != "" {
+			entity.Detail = append(entity.Detail, detail)
+		}
+	}
+	event.Entity = append(event.Entity, entity)
+
+	return event
+}
+
+func concept(system, code, display string) *CodeableConcept {
+	return &CodeableConcept{Coding: []Coding{{System: system, Code: code, Display: display}}}
+}
+
+func quantity(value *float64, unit string) *Quantity {
+	if value == nil {
+		return nil
+	}
+	q := &Quantity{Value: value, Unit: unit}
+	if unit != "" {
+		q.System = SystemUCUM
+		q.Code = unit
+	}
+	return q
+}
+
+func meta(updatedAt time.Time) *Meta {
+	if updatedAt.IsZero() {
+		return nil
+	}
+	return &Meta{LastUpdated: dateTime(updatedAt)}
+}
+
+func withSecurity(m *Meta, label Coding) *Meta {
+	if m == nil {
+		m = &Meta{}
+	}
+	m.Security = append(m.Security, label)
+	return m
+}
+
+// dateTime formats a FHIR dateTime. Zero times, which is what fields withheld
+// by the field policy decode to, are left out.
+func dateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func dateTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return dateTime(*t)
+}
+
+func date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var kept []string
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return strings.Join(kept, sep)
+}
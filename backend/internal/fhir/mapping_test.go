@@ -0,0 +1,158 @@
+package fhir
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatientID(t *testing.T) {
+	assert.Equal(t, "pt-abc234", PatientID("pt_abc234"))
+	assert.Equal(t, "pt_abc234", PatientPublicID("pt-abc234"))
+	assert.Equal(t, "", PatientPublicID("42"))
+}
+
+func TestNewPatient(t *testing.T) {
+	patient := &models.Patient{
+		PublicID:     "pt_abc234",
+		FirstName:    "John",
+		LastName:     "Doe",
+		DateOfBirth:  time.Date(1980, 5, 15, 0, 0, 0, 0, time.UTC),
+		SSN:          "123-45-6789",
+		Phone:        "555-0101",
+		Confidential: true,
+		Identifiers: []models.PatientIdentifier{
+			{Type: models.IdentifierMRN, System: "urn:healthsecure:mrn:main", Value: "MRN0000001"},
+		},
+	}
+
+	resource := NewPatient(patient)
+	assert.Equal(t, "pt-abc234", resource.ID)
+	assert.Equal(t, "1980-05-15", resource.BirthDate)
+	assert.Equal(t, []HumanName{{Use: "official", Family: "Doe", Given: []string{"John"}}}, resource.Name)
+	require.Len(t, resource.Identifier, 1)
+	assert.Equal(t, "MR", resource.Identifier[0].Type.Coding[0].Code)
+	assert.Equal(t, "R", resource.Meta.Security[0].Code)
+
+	data, err := json.Marshal(resource)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "6789", "SSNs are never exported")
+	assert.NotContains(t, string(data), "address", "empty elements are left out")
+}
+
+func TestNewObservation(t *testing.T) {
+	value, low, high := 0.0, 36.1, 37.8
+	observation := &models.Observation{
+		ID:             7,
+		Category:       models.CategoryVitalSigns,
+		Code:           "8310-5",
+		Display:        "Body temperature",
+		ValueQuantity:  &value,
+		Unit:           "Cel",
+		ReferenceLow:   &low,
+		ReferenceHigh:  &high,
+		Interpretation: models.InterpretationCriticalLow,
+	}
+	subject := Reference{Reference: "Patient/pt-abc234"}
+
+	resource := NewObservation(observation, subject)
+	assert.Equal(t, "7", resource.ID)
+	assert.Equal(t, "vital-signs", resource.Category[0].Coding[0].Code)
+	require.NotNil(t, resource.ValueQuantity)
+	assert.Equal(t, 0.0, *resource.ValueQuantity.Value, "a measured zero is kept")
+	assert.Equal(t, SystemUCUM, resource.ValueQuantity.System)
+	assert.Equal(t, "LL", resource.Interpretation[0].Coding[0].Code)
+	assert.Equal(t, 36.1, *resource.ReferenceRange[0].Low.Value)
+	assert.Equal(t, subject, resource.Subject)
+	assert.Empty(t, resource.EffectiveDateTime, "withheld times are left out")
+}
+
+func TestNewDocumentReference(t *testing.T) {
+	signedAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	record := &models.MedicalRecord{
+		ID:          12,
+		Diagnosis:   "Depression",
+		Status:      models.RecordStatusSigned,
+		SignedAt:    &signedAt,
+		Sensitivity: models.SensitivityMentalHealth,
+		Doctor:      models.User{Name: "Dr. Sarah Smith"},
+		Diagnoses:   []models.RecordDiagnosis{{System: models.CodeSystemICD10CM, Code: "F32.9", Display: "Major depressive disorder"}},
+	}
+	subject := Reference{Reference: "urn:uuid:1"}
+
+	document := NewDocumentReference(record, subject)
+	assert.Equal(t, "final", document.DocStatus)
+	assert.Equal(t, "2026-03-01T09:30:00Z", document.Date)
+	assert.Equal(t, "PSY", document.SecurityLabel[0].Coding[0].Code)
+	text, err := base64.StdEncoding.DecodeString(document.Content[0].Attachment.Data)
+	require.NoError(t, err)
+	assert.Equal(t, record.Narrative(), string(text))
+
+	conditions := NewDiagnosisConditions(record, subject)
+	require.Len(t, conditions, 1)
+	assert.Equal(t, "diagnosis-12-1", conditions[0].ID)
+	assert.Equal(t, SystemICD10CM, conditions[0].Code.Coding[0].System)
+	assert.Equal(t, "encounter-diagnosis", conditions[0].Category[0].Coding[0].Code)
+}
+
+func TestNewMedicationStatement(t *testing.T) {
+	stop := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	order := &models.MedicationOrder{
+		ID:        3,
+		Drug:      "Metformin",
+		Dose:      "500 mg",
+		Route:     models.RouteOral,
+		Frequency: "twice daily",
+		StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		StopDate:  &stop,
+		Status:    models.MedicationStatusDiscontinued,
+	}
+
+	statement := NewMedicationStatement(order, Reference{Reference: "urn:uuid:1"})
+	assert.Equal(t, "stopped", statement.Status)
+	assert.Equal(t, "500 mg oral twice daily", statement.Dosage[0].Text)
+	assert.Equal(t, "2026-02-01T00:00:00Z", statement.EffectivePeriod.End)
+	assert.Nil(t, statement.InformationSource)
+}
+
+func TestNewAuditEvent(t *testing.T) {
+	log := &models.AuditLog{
+		ID:              9,
+		UserID:          4,
+		Action:          models.ActionView,
+		Resource:        "patient",
+		IPAddress:       "10.0.0.5",
+		EmergencyUse:    true,
+		DisclosedFields: "first_name,last_name",
+		Purpose:         models.PurposeTreatment,
+		Success:         true,
+		Timestamp:       time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+		User:            models.User{Name: "Dr. Smith"},
+		Patient:         &models.Patient{PublicID: "pt_abc234"},
+	}
+
+	event := NewAuditEvent(log)
+	assert.Equal(t, "9", event.ID)
+	assert.Equal(t, "R", event.Action)
+	assert.Equal(t, "0", event.Outcome)
+	assert.Equal(t, "110110", event.Type.Code)
+	require.Len(t, event.PurposeOfEvent, 2)
+	assert.Equal(t, "TREAT", event.PurposeOfEvent[0].Coding[0].Code)
+	assert.Equal(t, "ETREAT", event.PurposeOfEvent[1].Coding[0].Code)
+	assert.Equal(t, "4", event.Agent[0].AltID)
+	assert.Equal(t, "Dr. Smith", event.Agent[0].Who.Display)
+	require.Len(t, event.Entity, 2)
+	assert.Equal(t, "Patient/pt-abc234", event.Entity[0].What.Reference)
+	assert.Equal(t, []AuditEventEntityDetail{{Type: "disclosed_fields", ValueString: "first_name,last_name"}}, event.Entity[1].Detail)
+
+	denied := NewAuditEvent(&models.AuditLog{Action: models.ActionUnauthorized, ErrorMessage: "insufficient_role"})
+	assert.Equal(t, "4", denied.Outcome)
+	assert.Equal(t, "110113", denied.Type.Code)
+	assert.Len(t, denied.Entity, 1, "no patient entity without a patient")
+}
@@ -0,0 +1,10 @@
+package fhir
+
+// NewOperationOutcome reports an error with an issue type such as invalid,
+// not-found or forbidden
+func NewOperationOutcome(code, diagnostics string) *OperationOutcome {
+	return &OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []OperationOutcomeIssue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
@@ -0,0 +1,192 @@
+package fhir
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+)
+
+// ParsePatient reads the demographics of a Patient sent by a client. The
+// official name, or else the first, gives the patient's names. Identifiers
+// are not read: MRNs are issued here and external identifiers are managed
+// separately.
+func ParsePatient(p *Patient) (*models.Patient, error) {
+	if p.ResourceType != "Patient" {
+		return nil, fmt.Errorf("resource must be a Patient")
+	}
+
+	name := officialName(p.Name)
+	if name == nil || name.Family == "" || len(name.Given) == 0 {
+		return nil, fmt.Errorf("patient needs a family and a given name")
+	}
+	if p.BirthDate == "" {
+		return nil, fmt.Errorf("patient needs a birthDate")
+	}
+	birthDate, err := time.Parse("2006-01-02", p.BirthDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid birthDate %q", p.BirthDate)
+	}
+
+	patient := &models.Patient{
+		FirstName:   strings.Join(name.Given, " "),
+		LastName:    name.Family,
+		DateOfBirth: birthDate,
+		Phone:       phone(p.Telecom),
+	}
+	if len(p.Address) > 0 {
+		patient.Address = addressText(p.Address[0])
+	}
+	if len(p.Contact) > 0 {
+		contact := p.Contact[0]
+		contactName := ""
+		if contact.Name != nil {
+			contactName = contact.Name.Text
+			if contactName == "" {
+				contactName = joinNonEmpty(" ", strings.Join(contact.Name.Given, " "), contact.Name.Family)
+			}
+		}
+		patient.EmergencyContact = joinNonEmpty(" ", contactName, phone(contact.Telecom))
+	}
+	if p.Meta != nil {
+		for _, label := range p.Meta.Security {
+			if label.System == SystemConfidential && (label.Code == "R" || label.Code == "V") {
+				patient.Confidential = true
+			}
+		}
+	}
+	return patient, nil
+}
+
+func officialName(names []HumanName) *HumanName {
+	for i := range names {
+		if names[i].Use == "official" {
+			return &names[i]
+		}
+	}
+	if len(names) > 0 {
+		return &names[0]
+	}
+	return nil
+}
+
+func phone(telecom []ContactPoint) string {
+	for _, contact := range telecom {
+		if contact.System == "phone" {
+			return contact.Value
+		}
+	}
+	return ""
+}
+
+func addressText(a Address) string {
+	if a.Text != "" {
+		return a.Text
+	}
+	return joinNonEmpty(", ", strings.Join(a.Line, ", "), a.City, joinNonEmpty(" ", a.State, a.PostalCode), a.Country)
+}
+
+// recordableStatuses are the Observation statuses of complete results
+var recordableStatuses = map[string]bool{"final": true, "amended": true, "corrected": true}
+
+// ParseObservation reads a vital sign or lab result sent by a client. The
+// code must be LOINC and the category vital-signs or laboratory; the subject
+// is left to the caller.
+func ParseObservation(o *Observation) (*models.Observation, error) {
+	if o.ResourceType != "Observation" {
+		return nil, fmt.Errorf("resource must be an Observation")
+	}
+	if !recordableStatuses[o.Status] {
+		return nil, fmt.Errorf("only final observations can be recorded")
+	}
+
+	observation := &models.Observation{ValueString: o.ValueString}
+	for _, category := range o.Category {
+		for _, coding := range category.Coding {
+			if coding.System != SystemObsCategory {
+				continue
+			}
+			switch coding.Code {
+			case "vital-signs":
+				observation.Category = models.CategoryVitalSigns
+			case "laboratory":
+				observation.Category = models.CategoryLaboratory
+			}
+		}
+	}
+	if observation.Category == "" {
+		return nil, fmt.Errorf("observation category must be vital-signs or laboratory")
+	}
+
+	for _, coding := range o.Code.Coding {
+		if coding.System == SystemLOINC {
+			observation.Code = coding.Code
+			observation.Display = coding.Display
+			break
+		}
+	}
+	if observation.Code == "" {
+		return nil, fmt.Errorf("observation code needs a LOINC coding")
+	}
+	if observation.Display == "" {
+		observation.Display = o.Code.Text
+	}
+
+	if o.ValueQuantity != nil {
+		observation.ValueQuantity = o.ValueQuantity.Value
+		observation.Unit = quantityUnit(o.ValueQuantity)
+	}
+	if len(o.ReferenceRange) > 0 {
+		if low := o.ReferenceRange[0].Low; low != nil {
+			observation.ReferenceLow = low.Value
+		}
+		if high := o.ReferenceRange[0].High; high != nil {
+			observation.ReferenceHigh = high.Value
+		}
+	}
+	for _, interpretation := range o.Interpretation {
+		for _, coding := range interpretation.Coding {
+			if coding.System != SystemInterpretation {
+				continue
+			}
+			value, ok := interpretationFromCode(coding.Code)
+			if !ok {
+				return nil, fmt.Errorf("unsupported interpretation %q", coding.Code)
+			}
+			observation.Interpretation = value
+		}
+	}
+	if o.EffectiveDateTime != "" {
+		effective, err := time.Parse(time.RFC3339Nano, o.EffectiveDateTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid effectiveDateTime %q", o.EffectiveDateTime)
+		}
+		observation.EffectiveAt = effective
+	}
+
+	notes := make([]string, 0, len(o.Note))
+	for _, note := range o.Note {
+		notes = append(notes, note.Text)
+	}
+	observation.Comment = joinNonEmpty("\n", notes...)
+
+	return observation, nil
+}
+
+// quantityUnit prefers the UCUM code, which is what units are stored as
+func quantityUnit(q *Quantity) string {
+	if q.System == SystemUCUM && q.Code != "" {
+		return q.Code
+	}
+	return q.Unit
+}
+
+func interpretationFromCode(code string) (models.Interpretation, bool) {
+	for interpretation, coding := range interpretationCodes {
+		if coding.Code == code {
+			return interpretation, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,101 @@
+package fhir
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePatient(t *testing.T) {
+	resource := &Patient{
+		ResourceType: "Patient",
+		Meta:         &Meta{Security: []Coding{{System: SystemConfidential, Code: "R"}}},
+		Name: []HumanName{
+			{Use: "nickname", Given: []string{"Johnny"}},
+			{Use: "official", Family: "Doe", Given: []string{"John", "Michael"}},
+		},
+		Telecom:   []ContactPoint{{System: "email", Value: "john@example.org"}, {System: "phone", Value: "555-0101"}},
+		BirthDate: "1980-05-15",
+		Address:   []Address{{Line: []string{"1 Main St"}, City: "Springfield", State: "IL", PostalCode: "62701"}},
+		Contact: []PatientContact{{
+			Name:    &HumanName{Family: "Doe", Given: []string{"Jane"}},
+			Telecom: []ContactPoint{{System: "phone", Value: "555-0102"}},
+		}},
+	}
+
+	patient, err := ParsePatient(resource)
+	require.NoError(t, err)
+	assert.Equal(t, "John Michael", patient.FirstName)
+	assert.Equal(t, "Doe", patient.LastName)
+	assert.Equal(t, time.Date(1980, 5, 15, 0, 0, 0, 0, time.UTC), patient.DateOfBirth)
+	assert.Equal(t, "555-0101", patient.Phone)
+	assert.Equal(t, "1 Main St, Springfield, IL 62701", patient.Address)
+	assert.Equal(t, "Jane Doe 555-0102", patient.EmergencyContact)
+	assert.True(t, patient.Confidential)
+
+	_, err = ParsePatient(&Patient{ResourceType: "Patient", Name: []HumanName{{Family: "Doe"}}, BirthDate: "1980-05-15"})
+	assert.Error(t, err, "a given name is required")
+	_, err = ParsePatient(&Patient{ResourceType: "Patient", Name: []HumanName{{Family: "Doe", Given: []string{"John"}}}, BirthDate: "15/05/1980"})
+	assert.Error(t, err)
+}
+
+func TestParseObservationRoundTrip(t *testing.T) {
+	value, low, high := 39.2, 36.1, 37.8
+	original := &models.Observation{
+		Category:       models.CategoryVitalSigns,
+		Code:           "8310-5",
+		Display:        "Body temperature",
+		ValueQuantity:  &value,
+		Unit:           "Cel",
+		ReferenceLow:   &low,
+		ReferenceHigh:  &high,
+		Interpretation: models.InterpretationHigh,
+		Comment:        "Taken orally",
+		EffectiveAt:    time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+
+	parsed, err := ParseObservation(NewObservation(original, Reference{Reference: "Patient/pt-abc234"}))
+	require.NoError(t, err)
+	assert.Equal(t, original.Category, parsed.Category)
+	assert.Equal(t, original.Code, parsed.Code)
+	assert.Equal(t, original.Display, parsed.Display)
+	assert.Equal(t, value, *parsed.ValueQuantity)
+	assert.Equal(t, "Cel", parsed.Unit)
+	assert.Equal(t, low, *parsed.ReferenceLow)
+	assert.Equal(t, high, *parsed.ReferenceHigh)
+	assert.Equal(t, models.InterpretationHigh, parsed.Interpretation)
+	assert.Equal(t, "Taken orally", parsed.Comment)
+	assert.True(t, original.EffectiveAt.Equal(parsed.EffectiveAt))
+}
+
+func TestParseObservationRejects(t *testing.T) {
+	valid := func() *Observation {
+		return &Observation{
+			ResourceType: "Observation",
+			Status:       "final",
+			Category:     []CodeableConcept{*concept(SystemObsCategory, "laboratory", "Laboratory")},
+			Code:         CodeableConcept{Coding: []Coding{{System: SystemLOINC, Code: "2345-7"}}},
+		}
+	}
+	_, err := ParseObservation(valid())
+	require.NoError(t, err)
+
+	preliminary := valid()
+	preliminary.Status = "preliminary"
+	_, err = ParseObservation(preliminary)
+	assert.Error(t, err)
+
+	imaging := valid()
+	imaging.Category = []CodeableConcept{*concept(SystemObsCategory, "imaging", "Imaging")}
+	_, err = ParseObservation(imaging)
+	assert.Error(t, err)
+
+	uncoded := valid()
+	uncoded.Code = CodeableConcept{Coding: []Coding{{System: SystemSNOMED, Code: "271649006"}}}
+	_, err = ParseObservation(uncoded)
+	assert.Error(t, err)
+}
@@ -0,0 +1,289 @@
+// Package fhir maps the chart models to HL7 FHIR R4 resources. Only the
+// elements the models can fill are declared; everything else is left out of
+// the JSON.
+package fhir
+
+// Code systems and identifier systems used in the mapped resources
+const (
+	SystemLOINC          = "http://loinc.org"
+	SystemSNOMED         = "http://snomed.info/sct"
+	SystemICD10CM        = "http://hl7.org/fhir/sid/icd-10-cm"
+	SystemUCUM           = "http://unitsofmeasure.org"
+	SystemIdentifierType = "http://terminology.hl7.org/CodeSystem/v2-0203"
+	SystemConfidential   = "http://terminology.hl7.org/CodeSystem/v3-Confidentiality"
+	SystemActCode        = "http://terminology.hl7.org/CodeSystem/v3-ActCode"
+	SystemInterpretation = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+	SystemObsCategory    = "http://terminology.hl7.org/CodeSystem/observation-category"
+	SystemCondCategory   = "http://terminology.hl7.org/CodeSystem/condition-category"
+	SystemCondClinical   = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	SystemCondVerify     = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+	SystemAllergyStatus  = "http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical"
+	SystemAllergyVerify  = "http://terminology.hl7.org/CodeSystem/allergyintolerance-verification"
+	SystemDocCategory    = "http://hl7.org/fhir/us/core/CodeSystem/us-core-documentreference-category"
+	SystemDICOM          = "http://dicom.nema.org/resources/ontology/DCM"
+	SystemActReason      = "http://terminology.hl7.org/CodeSystem/v3-ActReason"
+	SystemNetworkType    = "http://terminology.hl7.org/CodeSystem/network-type"
+)
+
+type Meta struct {
+	LastUpdated string   `json:"lastUpdated,omitempty"`
+	Security    []Coding `json:"security,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Identifier struct {
+	Use    string           `json:"use,omitempty"`
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value,omitempty"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+type Address struct {
+	Text       string   `json:"text,omitempty"`
+	Line       []string `json:"line,omitempty"`
+	City       string   `json:"city,omitempty"`
+	State      string   `json:"state,omitempty"`
+	PostalCode string   `json:"postalCode,omitempty"`
+	Country    string   `json:"country,omitempty"`
+}
+
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Quantity holds its value by pointer so that a measured zero is kept
+type Quantity struct {
+	Value  *float64 `json:"value,omitempty"`
+	Unit   string   `json:"unit,omitempty"`
+	System string   `json:"system,omitempty"`
+	Code   string   `json:"code,omitempty"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+type Attachment struct {
+	ContentType string `json:"contentType,omitempty"`
+	Data        string `json:"data,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Creation    string `json:"creation,omitempty"`
+}
+
+type PatientContact struct {
+	Name    *HumanName     `json:"name,omitempty"`
+	Telecom []ContactPoint `json:"telecom,omitempty"`
+}
+
+type Patient struct {
+	ResourceType string           `json:"resourceType"`
+	ID           string           `json:"id,omitempty"`
+	Meta         *Meta            `json:"meta,omitempty"`
+	Identifier   []Identifier     `json:"identifier,omitempty"`
+	Name         []HumanName      `json:"name,omitempty"`
+	Telecom      []ContactPoint   `json:"telecom,omitempty"`
+	BirthDate    string           `json:"birthDate,omitempty"`
+	Address      []Address        `json:"address,omitempty"`
+	Contact      []PatientContact `json:"contact,omitempty"`
+}
+
+type Condition struct {
+	ResourceType       string            `json:"resourceType"`
+	ID                 string            `json:"id,omitempty"`
+	Meta               *Meta             `json:"meta,omitempty"`
+	ClinicalStatus     *CodeableConcept  `json:"clinicalStatus,omitempty"`
+	VerificationStatus *CodeableConcept  `json:"verificationStatus,omitempty"`
+	Category           []CodeableConcept `json:"category,omitempty"`
+	Code               *CodeableConcept  `json:"code,omitempty"`
+	Subject            Reference         `json:"subject"`
+	OnsetDateTime      string            `json:"onsetDateTime,omitempty"`
+	AbatementDateTime  string            `json:"abatementDateTime,omitempty"`
+	RecordedDate       string            `json:"recordedDate,omitempty"`
+}
+
+type Dosage struct {
+	Text  string           `json:"text,omitempty"`
+	Route *CodeableConcept `json:"route,omitempty"`
+}
+
+type MedicationStatement struct {
+	ResourceType              string          `json:"resourceType"`
+	ID                        string          `json:"id,omitempty"`
+	Meta                      *Meta           `json:"meta,omitempty"`
+	Status                    string          `json:"status"`
+	MedicationCodeableConcept CodeableConcept `json:"medicationCodeableConcept"`
+	Subject                   Reference       `json:"subject"`
+	EffectivePeriod           *Period         `json:"effectivePeriod,omitempty"`
+	InformationSource         *Reference      `json:"informationSource,omitempty"`
+	Dosage                    []Dosage        `json:"dosage,omitempty"`
+}
+
+type ObservationReferenceRange struct {
+	Low  *Quantity `json:"low,omitempty"`
+	High *Quantity `json:"high,omitempty"`
+}
+
+type Observation struct {
+	ResourceType      string                      `json:"resourceType"`
+	ID                string                      `json:"id,omitempty"`
+	Meta              *Meta                       `json:"meta,omitempty"`
+	Status            string                      `json:"status"`
+	Category          []CodeableConcept           `json:"category,omitempty"`
+	Code              CodeableConcept             `json:"code"`
+	Subject           Reference                   `json:"subject"`
+	EffectiveDateTime string                      `json:"effectiveDateTime,omitempty"`
+	Performer         []Reference                 `json:"performer,omitempty"`
+	ValueQuantity     *Quantity                   `json:"valueQuantity,omitempty"`
+	ValueString       string                      `json:"valueString,omitempty"`
+	Interpretation    []CodeableConcept           `json:"interpretation,omitempty"`
+	Note              []Annotation                `json:"note,omitempty"`
+	ReferenceRange    []ObservationReferenceRange `json:"referenceRange,omitempty"`
+}
+
+type AllergyReaction struct {
+	Manifestation []CodeableConcept `json:"manifestation"`
+	Severity      string            `json:"severity,omitempty"`
+}
+
+type AllergyIntolerance struct {
+	ResourceType       string            `json:"resourceType"`
+	ID                 string            `json:"id,omitempty"`
+	Meta               *Meta             `json:"meta,omitempty"`
+	ClinicalStatus     *CodeableConcept  `json:"clinicalStatus,omitempty"`
+	VerificationStatus *CodeableConcept  `json:"verificationStatus,omitempty"`
+	Criticality        string            `json:"criticality,omitempty"`
+	Code               *CodeableConcept  `json:"code,omitempty"`
+	Patient            Reference         `json:"patient"`
+	RecordedDate       string            `json:"recordedDate,omitempty"`
+	Reaction           []AllergyReaction `json:"reaction,omitempty"`
+}
+
+type DocumentReferenceContent struct {
+	Attachment Attachment `json:"attachment"`
+}
+
+type DocumentReference struct {
+	ResourceType  string                     `json:"resourceType"`
+	ID            string                     `json:"id,omitempty"`
+	Meta          *Meta                      `json:"meta,omitempty"`
+	Status        string                     `json:"status"`
+	DocStatus     string                     `json:"docStatus,omitempty"`
+	Type          *CodeableConcept           `json:"type,omitempty"`
+	Category      []CodeableConcept          `json:"category,omitempty"`
+	Subject       Reference                  `json:"subject"`
+	Date          string                     `json:"date,omitempty"`
+	Author        []Reference                `json:"author,omitempty"`
+	SecurityLabel []CodeableConcept          `json:"securityLabel,omitempty"`
+	Content       []DocumentReferenceContent `json:"content"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleEntrySearch struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+type BundleEntry struct {
+	FullURL  string             `json:"fullUrl,omitempty"`
+	Resource interface{}        `json:"resource"`
+	Search   *BundleEntrySearch `json:"search,omitempty"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	ID           string        `json:"id,omitempty"`
+	Meta         *Meta         `json:"meta,omitempty"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp,omitempty"`
+	Total        *int64        `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type AuditEventAgentNetwork struct {
+	Address string `json:"address,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+type AuditEventAgent struct {
+	Who       *Reference              `json:"who,omitempty"`
+	AltID     string                  `json:"altId,omitempty"`
+	Requestor bool                    `json:"requestor"`
+	Network   *AuditEventAgentNetwork `json:"network,omitempty"`
+}
+
+type AuditEventSource struct {
+	Observer Reference `json:"observer"`
+}
+
+type AuditEventEntityDetail struct {
+	Type        string `json:"type"`
+	ValueString string `json:"valueString"`
+}
+
+type AuditEventEntity struct {
+	What          *Reference               `json:"what,omitempty"`
+	Type          *Coding                  `json:"type,omitempty"`
+	SecurityLabel []Coding                 `json:"securityLabel,omitempty"`
+	Description   string                   `json:"description,omitempty"`
+	Detail        []AuditEventEntityDetail `json:"detail,omitempty"`
+}
+
+type AuditEvent struct {
+	ResourceType   string             `json:"resourceType"`
+	ID             string             `json:"id,omitempty"`
+	Meta           *Meta              `json:"meta,omitempty"`
+	Type           Coding             `json:"type"`
+	Subtype        []Coding           `json:"subtype,omitempty"`
+	Action         string             `json:"action,omitempty"`
+	Recorded       string             `json:"recorded"`
+	Outcome        string             `json:"outcome,omitempty"`
+	OutcomeDesc    string             `json:"outcomeDesc,omitempty"`
+	PurposeOfEvent []CodeableConcept  `json:"purposeOfEvent,omitempty"`
+	Agent          []AuditEventAgent  `json:"agent"`
+	Source         AuditEventSource   `json:"source"`
+	Entity         []AuditEventEntity `json:"entity,omitempty"`
+}
+
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
@@ -0,0 +1,112 @@
+package fhir
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateRange is the interval a date search matches, from Since up to but not
+// including Before. Either end may be open.
+type DateRange struct {
+	Since  *time.Time
+	Before *time.Time
+}
+
+// ParseDateParam reads the values of a date search parameter. Each value is a
+// date or dateTime with an optional eq, ge, gt, le or lt prefix, and matches
+// the whole period its precision names: "2026-03" is all of March. Repeated
+// values must all match, so ge and lt together give a range. Dates without a
+// time zone are in the server's zone.
+func ParseDateParam(values []string) (DateRange, error) {
+	var r DateRange
+	for _, value := range values {
+		prefix := "eq"
+		if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+			prefix, value = value[:2], value[2:]
+		}
+
+		start, end, err := parsePeriod(value)
+		if err != nil {
+			return DateRange{}, err
+		}
+
+		switch prefix {
+		case "eq":
+			r.Since = later(r.Since, start)
+			r.Before = earlier(r.Before, end)
+		case "ge":
+			r.Since = later(r.Since, start)
+		case "gt":
+			r.Since = later(r.Since, end)
+		case "le":
+			r.Before = earlier(r.Before, end)
+		case "lt":
+			r.Before = earlier(r.Before, start)
+		default:
+			return DateRange{}, fmt.Errorf("unsupported date prefix %q", prefix)
+		}
+	}
+	return r, nil
+}
+
+// parsePeriod returns the start and exclusive end of the period a date or
+// dateTime names
+func parsePeriod(value string) (time.Time, time.Time, error) {
+	switch len(value) {
+	case 4:
+		if t, err := time.ParseInLocation("2006", value, time.Local); err == nil {
+			return t, t.AddDate(1, 0, 0), nil
+		}
+	case 7:
+		if t, err := time.ParseInLocation("2006-01", value, time.Local); err == nil {
+			return t, t.AddDate(0, 1, 0), nil
+		}
+	case 10:
+		if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+			return t, t.AddDate(0, 0, 1), nil
+		}
+	default:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, t.Add(time.Second), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func later(current *time.Time, t time.Time) *time.Time {
+	if current != nil && current.After(t) {
+		return current
+	}
+	return &t
+}
+
+func earlier(current *time.Time, t time.Time) *time.Time {
+	if current != nil && current.Before(t) {
+		return current
+	}
+	return &t
+}
+
+// ParseToken splits a token search value into its system and code. A value
+// without a bar has no system.
+func ParseToken(value string) (system, code string) {
+	if i := strings.Index(value, "|"); i >= 0 {
+		return value[:i], value[i+1:]
+	}
+	return "", value
+}
+
+// ParseReference returns the id a reference search value names, accepting a
+// bare id, "Type/id" or an absolute URL. It returns "" for references to
+// another resource type.
+func ParseReference(value, resourceType string) string {
+	parts := strings.Split(strings.TrimSpace(value), "/")
+	switch {
+	case len(parts) == 1:
+		return parts[0]
+	case parts[len(parts)-2] == resourceType:
+		return parts[len(parts)-1]
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package fhir
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDateParam(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+
+	r, err := ParseDateParam([]string{"2026-03"})
+	require.NoError(t, err)
+	assert.Equal(t, day(2026, 3, 1), *r.Since)
+	assert.Equal(t, day(2026, 4, 1), *r.Before)
+
+	r, err = ParseDateParam([]string{"ge1980", "lt1990-06-15"})
+	require.NoError(t, err)
+	assert.Equal(t, day(1980, 1, 1), *r.Since)
+	assert.Equal(t, day(1990, 6, 15), *r.Before)
+
+	r, err = ParseDateParam([]string{"gt2026-03-01", "le2026-03-31"})
+	require.NoError(t, err)
+	assert.Equal(t, day(2026, 3, 2), *r.Since)
+	assert.Equal(t, day(2026, 4, 1), *r.Before)
+
+	r, err = ParseDateParam([]string{"ge2026-03-01T09:30:00Z"})
+	require.NoError(t, err)
+	assert.True(t, r.Since.Equal(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)))
+	assert.Nil(t, r.Before)
+
+	r, err = ParseDateParam(nil)
+	require.NoError(t, err)
+	assert.Nil(t, r.Since)
+	assert.Nil(t, r.Before)
+
+	_, err = ParseDateParam([]string{"sa2026"})
+	assert.Error(t, err)
+	_, err = ParseDateParam([]string{"March"})
+	assert.Error(t, err)
+}
+
+func TestParseToken(t *testing.T) {
+	system, code := ParseToken("http://loinc.org|8310-5")
+	assert.Equal(t, "http://loinc.org", system)
+	assert.Equal(t, "8310-5", code)
+
+	system, code = ParseToken("active")
+	assert.Equal(t, "", system)
+	assert.Equal(t, "active", code)
+}
+
+func TestParseReference(t *testing.T) {
+	assert.Equal(t, "pt-abc234", ParseReference("pt-abc234", "Patient"))
+	assert.Equal(t, "pt-abc234", ParseReference("Patient/pt-abc234", "Patient"))
+	assert.Equal(t, "pt-abc234", ParseReference("https://ehr.example.org/fhir/R4/Patient/pt-abc234", "Patient"))
+	assert.Equal(t, "", ParseReference("Group/7", "Patient"))
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AmendmentHandler struct {
+	amendmentService *services.AmendmentService
+	jwtService       *auth.JWTService
+}
+
+func NewAmendmentHandler(amendmentService *services.AmendmentService, jwtService *auth.JWTService) *AmendmentHandler {
+	return &AmendmentHandler{
+		amendmentService: amendmentService,
+		jwtService:       jwtService,
+	}
+}
+
+// SubmitAmendment logs a patient's request to amend a medical record
+func (h *AmendmentHandler) SubmitAmendment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	var req services.SubmitAmendmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	amendment, err := h.amendmentService.SubmitAmendment(uint(recordID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Amendment request submitted successfully",
+		"amendment": amendment,
+	})
+}
+
+// GetRecordAmendments lists the amendment requests made against a record
+func (h *AmendmentHandler) GetRecordAmendments(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	amendments, err := h.amendmentService.GetRecordAmendments(uint(recordID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"amendments": amendments})
+}
+
+// GetAmendments lists amendment requests with their deadlines
+func (h *AmendmentHandler) GetAmendments(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var query services.AmendmentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set default pagination
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	amendments, total, err := h.amendmentService.GetAmendments(&query, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"amendments": amendments,
+		"pagination": gin.H{
+			"current_page": query.Page,
+			"limit":        query.Limit,
+			"total":        total,
+			"total_pages":  (total + int64(query.Limit) - 1) / int64(query.Limit),
+		},
+	})
+}
+
+// GetAmendment returns a single amendment request
+func (h *AmendmentHandler) GetAmendment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	amendmentIDStr := c.Param("id")
+	amendmentID, err := strconv.ParseUint(amendmentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amendment request ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	amendment, err := h.amendmentService.GetAmendment(uint(amendmentID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"amendment": amendment})
+}
+
+// StartReview moves a submitted request under review
+func (h *AmendmentHandler) StartReview(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	amendmentIDStr := c.Param("id")
+	amendmentID, err := strconv.ParseUint(amendmentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amendment request ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	amendment, err := h.amendmentService.StartReview(uint(amendmentID), userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Amendment request under review",
+		"amendment": amendment,
+	})
+}
+
+// ExtendDeadline extends the response deadline by 30 days
+func (h *AmendmentHandler) ExtendDeadline(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	amendmentIDStr := c.Param("id")
+	amendmentID, err := strconv.ParseUint(amendmentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amendment request ID"})
+		return
+	}
+
+	var req services.ExtendAmendmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	amendment, err := h.amendmentService.ExtendDeadline(uint(amendmentID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Amendment deadline extended",
+		"amendment": amendment,
+	})
+}
+
+// AcceptAmendment accepts a request and appends it to the record as an addendum
+func (h *AmendmentHandler) AcceptAmendment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	amendmentIDStr := c.Param("id")
+	amendmentID, err := strconv.ParseUint(amendmentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amendment request ID"})
+		return
+	}
+
+	// The body is optional: without it the requested change is appended as is
+	var req services.AcceptAmendmentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	amendment, err := h.amendmentService.AcceptAmendment(uint(amendmentID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Amendment request accepted",
+		"amendment": amendment,
+	})
+}
+
+// DenyAmendment denies a request with the basis for denial
+func (h *AmendmentHandler) DenyAmendment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	amendmentIDStr := c.Param("id")
+	amendmentID, err := strconv.ParseUint(amendmentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amendment request ID"})
+		return
+	}
+
+	var req services.DenyAmendmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	amendment, err := h.amendmentService.DenyAmendment(uint(amendmentID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Amendment request denied",
+		"amendment": amendment,
+	})
+}
+
+// FileDisagreement records the patient's statement of disagreement with a denial
+func (h *AmendmentHandler) FileDisagreement(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	amendmentIDStr := c.Param("id")
+	amendmentID, err := strconv.ParseUint(amendmentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amendment request ID"})
+		return
+	}
+
+	var req services.AmendmentDisagreementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	amendment, err := h.amendmentService.FileDisagreement(uint(amendmentID), &req, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Statement of disagreement filed",
+		"amendment": amendment,
+	})
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for the form fields and part headers around the file
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	attachmentService *services.AttachmentService
+	jwtService        *auth.JWTService
+}
+
+func NewAttachmentHandler(attachmentService *services.AttachmentService, jwtService *auth.JWTService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		jwtService:        jwtService,
+	}
+}
+
+// UploadAttachment files a document on a patient's chart from a multipart
+// form with a "file" part
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	maxSize := h.attachmentService.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+
+	var req services.UploadAttachmentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file exceeds the %d MB limit", maxSize>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+		return
+	}
+	if header.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file exceeds the %d MB limit", maxSize>>20)})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	req.Filename = header.Filename
+	req.Data, err = io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	attachment, err := h.attachmentService.UploadAttachment(uint(patientID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Attachment uploaded successfully",
+		"attachment": attachment,
+	})
+}
+
+// GetAttachments lists the documents on a patient's chart
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var query services.AttachmentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	attachments, err := h.attachmentService.GetAttachments(uint(patientID), &query, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), getFieldsParam(c))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attachments": attachments})
+}
+
+// DownloadAttachment sends the decrypted document
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, attachmentID, ok := parseClinicalListIDs(c, "attachmentId", "attachment")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	content, err := h.attachmentService.DownloadAttachment(patientID, attachmentID, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		if err.Error() == "attachment not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": content.Attachment.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, content.Attachment.ContentType, content.Data)
+}
+
+// DeleteAttachment removes a document filed in error
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, attachmentID, ok := parseClinicalListIDs(c, "attachmentId", "attachment")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.attachmentService.DeleteAttachment(patientID, attachmentID, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -114,6 +115,50 @@ func (h *AuditHandler) GetPatientAuditHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"audit_history": logs})
 }
 
+// GetDisclosureReport returns a patient's accounting of disclosures as JSON
+// or, with format=pdf, as a printable PDF
+func (h *AuditHandler) GetDisclosureReport(c *gin.Context) {
+	requestedByUserID := c.GetUint("user_id")
+	requestedByRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var query services.DisclosureReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	report, err := h.auditService.GetDisclosureReport(uint(patientID), &query, requestedByUserID, requestedByRole, ipAddress, userAgent)
+	if err != nil {
+		switch err.Error() {
+		case "patient not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "insufficient permissions to run an accounting of disclosures":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if c.Query("format") == "pdf" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="disclosures-patient-%d.pdf"`, report.PatientID))
+		c.Data(http.StatusOK, "application/pdf", report.PDF())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
 // GetSecurityEvents retrieves security events
 func (h *AuditHandler) GetSecurityEvents(c *gin.Context) {
 	page, limit := getPaginationParams(c)
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"healthsecure/internal/auth"
 	"healthsecure/internal/models"
@@ -263,4 +264,22 @@ func getPaginationParams(c *gin.Context) (page int, limit int) {
 	}
 
 	return page, limit
+}
+
+// Helper function to get the requested field subset from a comma-separated
+// fields parameter
+func getFieldsParam(c *gin.Context) []string {
+	var fields []string
+	for _, field := range strings.Split(c.Query("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Helper function to get the purpose of use resolved by the auth middleware
+// from the X-Purpose-Of-Use header or the login token
+func getPurposeOfUse(c *gin.Context) models.PurposeOfUse {
+	return models.PurposeOfUse(c.GetString("purpose_of_use"))
 }
\ No newline at end of file
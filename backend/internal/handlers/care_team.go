@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CareTeamHandler struct {
+	careTeamService *services.CareTeamService
+	jwtService      *auth.JWTService
+}
+
+func NewCareTeamHandler(careTeamService *services.CareTeamService, jwtService *auth.JWTService) *CareTeamHandler {
+	return &CareTeamHandler{
+		careTeamService: careTeamService,
+		jwtService:      jwtService,
+	}
+}
+
+// GetCareTeam lists the active care team of a patient
+func (h *CareTeamHandler) GetCareTeam(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	members, err := h.careTeamService.GetCareTeam(uint(patientID), userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"care_team": members})
+}
+
+// AddMember assigns a clinician to a patient's care team
+func (h *CareTeamHandler) AddMember(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.AddCareTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	member, err := h.careTeamService.AddMember(uint(patientID), &req, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Care team member added successfully",
+		"member":  member,
+	})
+}
+
+// RemoveMember ends a clinician's care team membership
+func (h *CareTeamHandler) RemoveMember(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	memberIDStr := c.Param("userId")
+	memberID, err := strconv.ParseUint(memberIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.careTeamService.RemoveMember(uint(patientID), uint(memberID), userID, userRole, ipAddress, userAgent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Care team member removed successfully"})
+}
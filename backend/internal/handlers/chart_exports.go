@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChartExportHandler struct {
+	chartExportService *services.ChartExportService
+	jwtService         *auth.JWTService
+}
+
+func NewChartExportHandler(chartExportService *services.ChartExportService, jwtService *auth.JWTService) *ChartExportHandler {
+	return &ChartExportHandler{
+		chartExportService: chartExportService,
+		jwtService:         jwtService,
+	}
+}
+
+// ExportChart downloads a copy of a patient's chart as a FHIR bundle or a
+// C-CDA document
+func (h *ChartExportHandler) ExportChart(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.ExportChartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	export, err := h.chartExportService.ExportChart(uint(patientID), &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		switch {
+		case err.Error() == "patient not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err.Error() == "invalid export format", err.Error() == "recipient is required",
+			err.Error() == "a purpose of use is required to export a chart":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "failed to"):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.Filename}))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, export.ContentType, export.Body)
+}
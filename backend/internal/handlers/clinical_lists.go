@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/cds"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClinicalListHandler struct {
+	clinicalListService *services.ClinicalListService
+	jwtService          *auth.JWTService
+}
+
+func NewClinicalListHandler(clinicalListService *services.ClinicalListService, jwtService *auth.JWTService) *ClinicalListHandler {
+	return &ClinicalListHandler{
+		clinicalListService: clinicalListService,
+		jwtService:          jwtService,
+	}
+}
+
+// GetMedications lists a patient's medication orders
+func (h *ClinicalListHandler) GetMedications(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	encounterID, err := getEncounterIDParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid encounter ID"})
+		return
+	}
+
+	medications, err := h.clinicalListService.GetMedications(uint(patientID), &services.ClinicalListQuery{Status: c.Query("status"), EncounterID: encounterID}, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"medications": medications})
+}
+
+// CreateMedication adds an entry to a patient's medication list
+func (h *ClinicalListHandler) CreateMedication(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.CreateMedicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	medication, alerts, err := h.clinicalListService.CreateMedication(uint(patientID), &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err == cds.ErrOverrideRequired {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "alerts": alerts})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Medication order created successfully",
+		"medication": medication,
+		"alerts":     alerts,
+	})
+}
+
+// UpdateMedication changes an entry on a patient's medication list
+func (h *ClinicalListHandler) UpdateMedication(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, itemID, ok := parseClinicalListIDs(c, "medicationId", "Medication order")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateMedicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	medication, alerts, err := h.clinicalListService.UpdateMedication(patientID, itemID, &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err == cds.ErrOverrideRequired {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "alerts": alerts})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Medication order updated successfully",
+		"medication": medication,
+		"alerts":     alerts,
+	})
+}
+
+// DeleteMedication removes an entry entered in error from a patient's medication list
+func (h *ClinicalListHandler) DeleteMedication(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, itemID, ok := parseClinicalListIDs(c, "medicationId", "Medication order")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	if err := h.clinicalListService.DeleteMedication(patientID, itemID, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Medication order deleted successfully"})
+}
+
+// GetAllergies lists a patient's allergies
+func (h *ClinicalListHandler) GetAllergies(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	allergies, err := h.clinicalListService.GetAllergies(uint(patientID), &services.ClinicalListQuery{Status: c.Query("status")}, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"allergies": allergies})
+}
+
+// CreateAllergy adds an entry to a patient's allergy list
+func (h *ClinicalListHandler) CreateAllergy(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.CreateAllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	allergy, err := h.clinicalListService.CreateAllergy(uint(patientID), &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Allergy created successfully",
+		"allergy": allergy,
+	})
+}
+
+// UpdateAllergy changes an entry on a patient's allergy list
+func (h *ClinicalListHandler) UpdateAllergy(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, itemID, ok := parseClinicalListIDs(c, "allergyId", "Allergy")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateAllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	allergy, err := h.clinicalListService.UpdateAllergy(patientID, itemID, &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Allergy updated successfully",
+		"allergy": allergy,
+	})
+}
+
+// DeleteAllergy removes an entry entered in error from a patient's allergy list
+func (h *ClinicalListHandler) DeleteAllergy(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, itemID, ok := parseClinicalListIDs(c, "allergyId", "Allergy")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	if err := h.clinicalListService.DeleteAllergy(patientID, itemID, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Allergy deleted successfully"})
+}
+
+// GetProblems lists a patient's problem list
+func (h *ClinicalListHandler) GetProblems(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	problems, err := h.clinicalListService.GetProblems(uint(patientID), &services.ClinicalListQuery{Status: c.Query("status")}, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"problems": problems})
+}
+
+// CreateProblem adds an entry to a patient's problem list
+func (h *ClinicalListHandler) CreateProblem(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.CreateProblemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	problem, err := h.clinicalListService.CreateProblem(uint(patientID), &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Problem created successfully",
+		"problem": problem,
+	})
+}
+
+// UpdateProblem changes an entry on a patient's problem list
+func (h *ClinicalListHandler) UpdateProblem(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, itemID, ok := parseClinicalListIDs(c, "problemId", "Problem")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateProblemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	problem, err := h.clinicalListService.UpdateProblem(patientID, itemID, &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Problem updated successfully",
+		"problem": problem,
+	})
+}
+
+// DeleteProblem removes an entry entered in error from a patient's problem list
+func (h *ClinicalListHandler) DeleteProblem(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, itemID, ok := parseClinicalListIDs(c, "problemId", "Problem")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	if err := h.clinicalListService.DeleteProblem(patientID, itemID, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Problem deleted successfully"})
+}
+
+// parseClinicalListIDs reads the patient ID and the list entry ID named by
+// param, writing a 400 response if either is malformed
+func parseClinicalListIDs(c *gin.Context, param, label string) (uint, uint, bool) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return 0, 0, false
+	}
+
+	itemID, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + label + " ID"})
+		return 0, 0, false
+	}
+
+	return uint(patientID), uint(itemID), true
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConsentHandler struct {
+	consentService *services.ConsentService
+	jwtService     *auth.JWTService
+}
+
+func NewConsentHandler(consentService *services.ConsentService, jwtService *auth.JWTService) *ConsentHandler {
+	return &ConsentHandler{
+		consentService: consentService,
+		jwtService:     jwtService,
+	}
+}
+
+// RecordConsent records a patient's consent to disclose a sensitive category
+func (h *ConsentHandler) RecordConsent(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.CreateConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	consent, err := h.consentService.RecordConsent(uint(patientID), &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Consent recorded successfully",
+		"consent": consent,
+	})
+}
+
+// GetPatientConsents lists the consents recorded for a patient
+func (h *ConsentHandler) GetPatientConsents(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	consents, err := h.consentService.GetPatientConsents(uint(patientID), userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"consents": consents})
+}
+
+// RevokeConsent withdraws a patient's consent
+func (h *ConsentHandler) RevokeConsent(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	consentIDStr := c.Param("id")
+	consentID, err := strconv.ParseUint(consentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid consent ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.consentService.RevokeConsent(uint(consentID), userID, userRole, ipAddress, userAgent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Consent revoked successfully"})
+}
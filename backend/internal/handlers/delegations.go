@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DelegationHandler struct {
+	delegationService *services.DelegationService
+	jwtService        *auth.JWTService
+}
+
+func NewDelegationHandler(delegationService *services.DelegationService, jwtService *auth.JWTService) *DelegationHandler {
+	return &DelegationHandler{
+		delegationService: delegationService,
+		jwtService:        jwtService,
+	}
+}
+
+// CreateDelegation lends the current doctor's patient access to a colleague
+func (h *DelegationHandler) CreateDelegation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var req services.CreateDelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	grant, err := h.delegationService.CreateDelegation(&req, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Delegation granted successfully"
+	if grant.IsPending() {
+		message = "Delegation submitted for admin approval"
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    message,
+		"delegation": grant,
+	})
+}
+
+// GetMyDelegations lists grants the current user has given or received
+func (h *DelegationHandler) GetMyDelegations(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	page, limit := getPaginationParams(c)
+
+	grants, total, err := h.delegationService.GetUserDelegations(userID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delegations": grants,
+		"pagination": gin.H{
+			"current_page": page,
+			"limit":        limit,
+			"total":        total,
+			"total_pages":  (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetDelegations lists all grants for admin review
+func (h *DelegationHandler) GetDelegations(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var query services.DelegationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set default pagination
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	grants, total, err := h.delegationService.GetDelegations(&query, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delegations": grants,
+		"pagination": gin.H{
+			"current_page": query.Page,
+			"limit":        query.Limit,
+			"total":        total,
+			"total_pages":  (total + int64(query.Limit) - 1) / int64(query.Limit),
+		},
+	})
+}
+
+// ApproveDelegation activates a pending grant
+func (h *DelegationHandler) ApproveDelegation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	delegationIDStr := c.Param("id")
+	delegationID, err := strconv.ParseUint(delegationIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delegation ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.delegationService.ApproveDelegation(uint(delegationID), userID, userRole, ipAddress, userAgent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delegation approved successfully"})
+}
+
+// RejectDelegation declines a pending grant
+func (h *DelegationHandler) RejectDelegation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	delegationIDStr := c.Param("id")
+	delegationID, err := strconv.ParseUint(delegationIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delegation ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.delegationService.RejectDelegation(uint(delegationID), userID, userRole, ipAddress, userAgent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delegation rejected successfully"})
+}
+
+// RevokeDelegation ends a grant early
+func (h *DelegationHandler) RevokeDelegation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	delegationIDStr := c.Param("id")
+	delegationID, err := strconv.ParseUint(delegationIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delegation ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.delegationService.RevokeDelegation(uint(delegationID), userID, userRole, ipAddress, userAgent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delegation revoked successfully"})
+}
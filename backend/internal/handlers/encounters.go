@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EncounterHandler struct {
+	encounterService *services.EncounterService
+	jwtService       *auth.JWTService
+}
+
+func NewEncounterHandler(encounterService *services.EncounterService, jwtService *auth.JWTService) *EncounterHandler {
+	return &EncounterHandler{
+		encounterService: encounterService,
+		jwtService:       jwtService,
+	}
+}
+
+// CreateEncounter opens a visit or admission for a patient
+func (h *EncounterHandler) CreateEncounter(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.CreateEncounterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	encounter, err := h.encounterService.CreateEncounter(uint(patientID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Encounter created successfully",
+		"encounter": encounter,
+	})
+}
+
+// GetPatientEncounters lists a patient's encounters
+func (h *EncounterHandler) GetPatientEncounters(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var query services.EncounterQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set default pagination
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	encounters, total, err := h.encounterService.GetPatientEncounters(uint(patientID), &query, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"encounters": encounters,
+		"pagination": gin.H{
+			"current_page": query.Page,
+			"limit":        query.Limit,
+			"total":        total,
+			"total_pages":  (total + int64(query.Limit) - 1) / int64(query.Limit),
+		},
+	})
+}
+
+// GetEncounter returns a single encounter
+func (h *EncounterHandler) GetEncounter(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	encounterID, ok := parseEncounterID(c)
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	encounter, err := h.encounterService.GetEncounter(encounterID, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		if err.Error() == "encounter not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"encounter": encounter})
+}
+
+// UpdateEncounter transfers an active encounter or changes its attending
+func (h *EncounterHandler) UpdateEncounter(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	encounterID, ok := parseEncounterID(c)
+	if !ok {
+		return
+	}
+
+	var req services.UpdateEncounterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	encounter, err := h.encounterService.UpdateEncounter(encounterID, &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Encounter updated successfully",
+		"encounter": encounter,
+	})
+}
+
+// DischargeEncounter ends an active encounter
+func (h *EncounterHandler) DischargeEncounter(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	encounterID, ok := parseEncounterID(c)
+	if !ok {
+		return
+	}
+
+	var req services.DischargeEncounterRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	encounter, err := h.encounterService.DischargeEncounter(encounterID, &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Encounter discharged successfully",
+		"encounter": encounter,
+	})
+}
+
+// GetCensus lists currently admitted patients by facility and department
+func (h *EncounterHandler) GetCensus(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var query services.CensusQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	units, err := h.encounterService.GetCensus(&query, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	total := 0
+	for _, unit := range units {
+		total += unit.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"units": units,
+		"total": total,
+	})
+}
+
+func parseEncounterID(c *gin.Context) (uint, bool) {
+	encounterID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid encounter ID"})
+		return 0, false
+	}
+	return uint(encounterID), true
+}
+
+// getEncounterIDParam reads the optional encounter_id filter used by the
+// patient chart views
+func getEncounterIDParam(c *gin.Context) (*uint, error) {
+	value := c.Query("encounter_id")
+	if value == "" {
+		return nil, nil
+	}
+
+	encounterID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	id := uint(encounterID)
+	return &id, nil
+}
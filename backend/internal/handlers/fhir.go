@@ -0,0 +1,594 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthsecure/internal/fhir"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fhirBasePath is where the FHIR API is mounted
+const fhirBasePath = "/fhir/R4"
+
+const fhirContentType = "application/fhir+json; charset=utf-8"
+
+// FHIRHandler serves the FHIR R4 API. Responses are FHIR resources, and
+// errors are OperationOutcomes rather than the native {"error": ...} body.
+type FHIRHandler struct {
+	fhirService *services.FHIRService
+	capability  *fhir.CapabilityStatement
+}
+
+func NewFHIRHandler(fhirService *services.FHIRService) *FHIRHandler {
+	return &FHIRHandler{
+		fhirService: fhirService,
+		capability:  fhir.NewCapabilityStatement(time.Now()),
+	}
+}
+
+// GetCapabilityStatement describes the resources and searches the server
+// supports
+func (h *FHIRHandler) GetCapabilityStatement(c *gin.Context) {
+	writeFHIR(c, http.StatusOK, h.capability)
+}
+
+// ReadPatient returns a patient by their FHIR id
+func (h *FHIRHandler) ReadPatient(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	patient, err := h.fhirService.ReadPatient(c.Param("id"), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	writeFHIR(c, http.StatusOK, patient)
+}
+
+// SearchPatients searches patients by name, birthdate, identifier and
+// _lastUpdated
+func (h *FHIRHandler) SearchPatients(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	page, count, err := getFHIRPaging(c)
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	query := services.PatientSearchQuery{
+		FirstName: c.Query("given"),
+		LastName:  c.Query("family"),
+		Name:      c.Query("name"),
+		Page:      page,
+		Limit:     count,
+	}
+	born, err := fhir.ParseDateParam(c.QueryArray("birthdate"))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+	query.BornSince, query.BornBefore = born.Since, born.Before
+	updated, err := fhir.ParseDateParam(c.QueryArray("_lastUpdated"))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+	query.UpdatedSince, query.UpdatedBefore = updated.Since, updated.Before
+
+	// An identifier without a system is one of our MRNs
+	if identifier := c.Query("identifier"); identifier != "" {
+		system, value := fhir.ParseToken(identifier)
+		if system == "" {
+			query.Identifier = &services.PatientLookupQuery{MRN: value}
+		} else {
+			query.Identifier = &services.PatientLookupQuery{System: system, Value: value}
+		}
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	patients, total, err := h.fhirService.SearchPatients(&query, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	bundle := newFHIRSearchSet(c, total, page, count)
+	for _, patient := range patients {
+		bundle.AddMatch(fhirURL(c, "Patient", patient.ID), patient)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// CreatePatient registers a patient from a Patient resource
+func (h *FHIRHandler) CreatePatient(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var resource fhir.Patient
+	if !bindFHIR(c, &resource) {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	patient, err := h.fhirService.CreatePatient(&resource, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	c.Header("Location", fhirURL(c, "Patient", patient.ID))
+	writeFHIR(c, http.StatusCreated, patient)
+}
+
+// UpdatePatient replaces a patient's demographics with a Patient resource
+func (h *FHIRHandler) UpdatePatient(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var resource fhir.Patient
+	if !bindFHIR(c, &resource) {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	patient, err := h.fhirService.UpdatePatient(c.Param("id"), &resource, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	writeFHIR(c, http.StatusOK, patient)
+}
+
+// ReadCondition returns an entry on a problem list
+func (h *FHIRHandler) ReadCondition(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	condition, err := h.fhirService.ReadCondition(c.Param("id"), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	writeFHIR(c, http.StatusOK, condition)
+}
+
+// SearchConditions returns a patient's problem list, filtered by
+// clinical-status and _lastUpdated
+func (h *FHIRHandler) SearchConditions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patient, ok := getFHIRPatientParam(c)
+	if !ok {
+		return
+	}
+	query, ok := getFHIRListQuery(c)
+	if !ok {
+		return
+	}
+	if status := c.Query("clinical-status"); status != "" {
+		_, query.Status = fhir.ParseToken(status)
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	conditions, err := h.fhirService.SearchConditions(patient, query, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	bundle := newFHIRSearchSet(c, int64(len(conditions)), 1, len(conditions))
+	for _, condition := range conditions {
+		bundle.AddMatch(fhirURL(c, "Condition", condition.ID), condition)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// ReadMedicationStatement returns a medication order
+func (h *FHIRHandler) ReadMedicationStatement(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	id, ok := getFHIRNumericID(c, "medication statement")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	statement, err := h.fhirService.ReadMedicationStatement(id, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	writeFHIR(c, http.StatusOK, statement)
+}
+
+// SearchMedicationStatements returns a patient's medication orders, filtered
+// by status and _lastUpdated
+func (h *FHIRHandler) SearchMedicationStatements(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patient, ok := getFHIRPatientParam(c)
+	if !ok {
+		return
+	}
+	query, ok := getFHIRListQuery(c)
+	if !ok {
+		return
+	}
+	switch _, status := fhir.ParseToken(c.Query("status")); status {
+	case "":
+	case "active":
+		query.Status = string(models.MedicationStatusActive)
+	case "stopped":
+		query.Status = string(models.MedicationStatusDiscontinued)
+	default:
+		writeFHIRError(c, fmt.Errorf("status must be active or stopped"))
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	statements, err := h.fhirService.SearchMedicationStatements(patient, query, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	bundle := newFHIRSearchSet(c, int64(len(statements)), 1, len(statements))
+	for _, statement := range statements {
+		bundle.AddMatch(fhirURL(c, "MedicationStatement", statement.ID), statement)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// ReadObservation returns a vital sign or lab result
+func (h *FHIRHandler) ReadObservation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	id, ok := getFHIRNumericID(c, "observation")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	observation, err := h.fhirService.ReadObservation(id, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	writeFHIR(c, http.StatusOK, observation)
+}
+
+// SearchObservations returns a page of a patient's observations, filtered
+// by category, code, date and _lastUpdated
+func (h *FHIRHandler) SearchObservations(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patient, ok := getFHIRPatientParam(c)
+	if !ok {
+		return
+	}
+	page, count, err := getFHIRPaging(c)
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	query := services.ObservationQuery{Page: page, Limit: count}
+	if category := c.Query("category"); category != "" {
+		_, query.Category = fhir.ParseToken(category)
+	}
+	if code := c.Query("code"); code != "" {
+		system, value := fhir.ParseToken(code)
+		if system != "" && system != fhir.SystemLOINC {
+			writeFHIRError(c, fmt.Errorf("observation codes are LOINC"))
+			return
+		}
+		query.Code = value
+	}
+	effective, err := fhir.ParseDateParam(c.QueryArray("date"))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+	query.From, query.Before = effective.Since, effective.Before
+	updated, err := fhir.ParseDateParam(c.QueryArray("_lastUpdated"))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+	query.UpdatedSince, query.UpdatedBefore = updated.Since, updated.Before
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	observations, total, err := h.fhirService.SearchObservations(patient, &query, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	bundle := newFHIRSearchSet(c, total, page, count)
+	for _, observation := range observations {
+		bundle.AddMatch(fhirURL(c, "Observation", observation.ID), observation)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// CreateObservation records a vital sign or lab result from an Observation
+// resource
+func (h *FHIRHandler) CreateObservation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var resource fhir.Observation
+	if !bindFHIR(c, &resource) {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	observation, err := h.fhirService.CreateObservation(&resource, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	c.Header("Location", fhirURL(c, "Observation", observation.ID))
+	writeFHIR(c, http.StatusCreated, observation)
+}
+
+// ReadAuditEvent returns an audit log entry
+func (h *FHIRHandler) ReadAuditEvent(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	id, ok := getFHIRNumericID(c, "audit event")
+	if !ok {
+		return
+	}
+
+	event, err := h.fhirService.ReadAuditEvent(id, userID, userRole)
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	writeFHIR(c, http.StatusOK, event)
+}
+
+// SearchAuditEvents returns a page of audit log entries, filtered by
+// patient, date and _lastUpdated
+func (h *FHIRHandler) SearchAuditEvents(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	page, count, err := getFHIRPaging(c)
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	// Audit events never change, so date and _lastUpdated both filter on
+	// when the event was recorded
+	recorded, err := fhir.ParseDateParam(append(c.QueryArray("date"), c.QueryArray("_lastUpdated")...))
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+	query := services.AuditLogQuery{StartTime: recorded.Since, Before: recorded.Before, Page: page, Limit: count}
+
+	patient := ""
+	if value := c.Query("patient"); value != "" {
+		if patient = fhir.ParseReference(value, "Patient"); patient == "" {
+			writeFHIRError(c, fmt.Errorf("patient must reference a Patient"))
+			return
+		}
+	}
+
+	events, total, err := h.fhirService.SearchAuditEvents(patient, &query, userID, userRole)
+	if err != nil {
+		writeFHIRError(c, err)
+		return
+	}
+
+	bundle := newFHIRSearchSet(c, total, page, count)
+	for _, event := range events {
+		bundle.AddMatch(fhirURL(c, "AuditEvent", event.ID), event)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// writeFHIR sends a FHIR resource as application/fhir+json
+func writeFHIR(c *gin.Context, status int, resource interface{}) {
+	body, err := json.Marshal(resource)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body, _ = json.Marshal(fhir.NewOperationOutcome("exception", "failed to encode resource"))
+	}
+	c.Data(status, fhirContentType, body)
+}
+
+// writeFHIRError reports a service error as an OperationOutcome, with the
+// status the native API would give it
+func writeFHIRError(c *gin.Context, err error) {
+	message := err.Error()
+	switch {
+	case strings.HasSuffix(message, "not found"):
+		writeFHIR(c, http.StatusNotFound, fhir.NewOperationOutcome("not-found", message))
+	case strings.HasPrefix(message, "insufficient permissions"), strings.HasPrefix(message, "access denied"),
+		strings.HasPrefix(message, "a stated reason is required"), strings.HasPrefix(message, "patient consent required"):
+		writeFHIR(c, http.StatusForbidden, fhir.NewOperationOutcome("forbidden", message))
+	case strings.HasPrefix(message, "failed to"):
+		writeFHIR(c, http.StatusInternalServerError, fhir.NewOperationOutcome("exception", message))
+	default:
+		writeFHIR(c, http.StatusBadRequest, fhir.NewOperationOutcome("invalid", message))
+	}
+}
+
+// bindFHIR decodes a resource from the request body, reporting malformed
+// JSON as an OperationOutcome
+func bindFHIR(c *gin.Context, resource interface{}) bool {
+	if err := json.NewDecoder(c.Request.Body).Decode(resource); err != nil {
+		writeFHIRError(c, fmt.Errorf("invalid resource: %v", err))
+		return false
+	}
+	return true
+}
+
+// getFHIRPaging reads the page and _count search parameters. Pages hold 50
+// resources unless asked otherwise, and at most 200.
+func getFHIRPaging(c *gin.Context) (page int, count int, err error) {
+	page, count = 1, 50
+	if value := c.Query("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil || page <= 0 {
+			return 0, 0, fmt.Errorf("invalid page %q", value)
+		}
+	}
+	if value := c.Query("_count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil || count <= 0 {
+			return 0, 0, fmt.Errorf("invalid _count %q", value)
+		}
+		if count > 200 {
+			count = 200
+		}
+	}
+	return page, count, nil
+}
+
+// getFHIRPatientParam reads the patient a chart search is within
+func getFHIRPatientParam(c *gin.Context) (string, bool) {
+	value := c.Query("patient")
+	if value == "" {
+		writeFHIRError(c, fmt.Errorf("patient is required"))
+		return "", false
+	}
+	patient := fhir.ParseReference(value, "Patient")
+	if patient == "" {
+		writeFHIRError(c, fmt.Errorf("patient must reference a Patient"))
+		return "", false
+	}
+	return patient, true
+}
+
+// getFHIRListQuery reads the _lastUpdated filter of a clinical list search
+func getFHIRListQuery(c *gin.Context) (*services.ClinicalListQuery, bool) {
+	updated, err := fhir.ParseDateParam(c.QueryArray("_lastUpdated"))
+	if err != nil {
+		writeFHIRError(c, err)
+		return nil, false
+	}
+	return &services.ClinicalListQuery{UpdatedSince: updated.Since, UpdatedBefore: updated.Before}, true
+}
+
+// getFHIRNumericID reads the id of a resource keyed by a database ID. Any
+// other id cannot name one, so it is not found.
+func getFHIRNumericID(c *gin.Context, resource string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeFHIRError(c, fmt.Errorf("%s not found", resource))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// fhirBaseURL is the absolute URL of the FHIR API as the client reached it
+func fhirBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + fhirBasePath
+}
+
+func fhirURL(c *gin.Context, resourceType, id string) string {
+	return fhirBaseURL(c) + "/" + resourceType + "/" + id
+}
+
+// newFHIRSearchSet starts a search result bundle linking to this page and,
+// when there are more matches, the next
+func newFHIRSearchSet(c *gin.Context, total int64, page, count int) *fhir.Bundle {
+	bundle := fhir.NewSearchSet(total, time.Now())
+
+	pageURL := func(page int) string {
+		query := c.Request.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		return fhirBaseURL(c) + strings.TrimPrefix(c.Request.URL.Path, fhirBasePath) + "?" + query.Encode()
+	}
+	bundle.AddLink("self", pageURL(page))
+	if int64(page)*int64(count) < total {
+		bundle.AddLink("next", pageURL(page+1))
+	}
+	return bundle
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"healthsecure/internal/auth"
 	"healthsecure/internal/models"
@@ -45,8 +46,9 @@ func (h *MedicalRecordHandler) CreateMedicalRecord(c *gin.Context) {
 
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
 
-	record, err := h.recordService.CreateMedicalRecord(&req, userID, userRole, ipAddress, userAgent)
+	record, err := h.recordService.CreateMedicalRecord(&req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -75,8 +77,15 @@ func (h *MedicalRecordHandler) GetMedicalRecord(c *gin.Context) {
 
 	// Check for emergency access
 	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
 
-	record, err := h.recordService.GetMedicalRecord(uint(recordID), userID, userRole, ipAddress, userAgent, emergencyAccess)
+	fields := getFieldsParam(c)
+
+	record, err := h.recordService.GetMedicalRecord(uint(recordID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err == models.ErrRecordTampered {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -103,8 +112,21 @@ func (h *MedicalRecordHandler) GetPatientMedicalRecords(c *gin.Context) {
 
 	// Check for emergency access
 	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
 
-	records, total, err := h.recordService.GetPatientMedicalRecords(uint(patientID), userID, userRole, ipAddress, userAgent, emergencyAccess, page, limit)
+	encounterID, err := getEncounterIDParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid encounter ID"})
+		return
+	}
+
+	records, total, err := h.recordService.GetPatientMedicalRecords(uint(patientID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields, encounterID, page, limit)
+	if err == models.ErrRecordTampered {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -142,7 +164,11 @@ func (h *MedicalRecordHandler) UpdateMedicalRecord(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	record, err := h.recordService.UpdateMedicalRecord(uint(recordID), &req, userID, userRole, ipAddress, userAgent)
+	record, err := h.recordService.UpdateMedicalRecord(uint(recordID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err == models.ErrRecordLocked {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -152,4 +178,115 @@ func (h *MedicalRecordHandler) UpdateMedicalRecord(c *gin.Context) {
 		"message": "Medical record updated successfully",
 		"record":  record,
 	})
+}
+
+// GetMedicalRecordRevisions lists the revisions of a medical record
+func (h *MedicalRecordHandler) GetMedicalRecordRevisions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	page, limit := getPaginationParams(c)
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	revisions, total, err := h.recordService.GetMedicalRecordRevisions(uint(recordID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), page, limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"pagination": gin.H{
+			"current_page": page,
+			"limit":        limit,
+			"total":        total,
+			"total_pages":  (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetMedicalRecordAsOf returns the medical record as it stood at the time given by the at parameter
+func (h *MedicalRecordHandler) GetMedicalRecordAsOf(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 timestamp"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	record, revision, err := h.recordService.GetMedicalRecordAsOf(uint(recordID), at, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"record":   record,
+		"revision": revision,
+	})
+}
+
+// DiffMedicalRecordRevisions compares the revisions given by the from and to parameters
+func (h *MedicalRecordHandler) DiffMedicalRecordRevisions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	from, fromErr := strconv.Atoi(c.Query("from"))
+	to, toErr := strconv.Atoi(c.Query("to"))
+	if fromErr != nil || toErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to revision versions are required"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	diff, err := h.recordService.DiffMedicalRecordRevisions(uint(recordID), from, to, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
 }
\ No newline at end of file
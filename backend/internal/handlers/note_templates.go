@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NoteTemplateHandler struct {
+	noteTemplateService *services.NoteTemplateService
+	jwtService          *auth.JWTService
+}
+
+func NewNoteTemplateHandler(noteTemplateService *services.NoteTemplateService, jwtService *auth.JWTService) *NoteTemplateHandler {
+	return &NoteTemplateHandler{
+		noteTemplateService: noteTemplateService,
+		jwtService:          jwtService,
+	}
+}
+
+// GetTemplates lists the note templates available for new notes
+func (h *NoteTemplateHandler) GetTemplates(c *gin.Context) {
+	var query services.NoteTemplateQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	templates, err := h.noteTemplateService.GetTemplates(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// GetTemplate returns one template version
+func (h *NoteTemplateHandler) GetTemplate(c *gin.Context) {
+	templateIDStr := c.Param("id")
+	templateID, err := strconv.ParseUint(templateIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	template, err := h.noteTemplateService.GetTemplate(uint(templateID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
+// CreateTemplate publishes the first version of a template
+func (h *NoteTemplateHandler) CreateTemplate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var req services.NoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	template, err := h.noteTemplateService.CreateTemplate(&req, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Note template created successfully",
+		"template": template,
+	})
+}
+
+// PublishVersion publishes a new version of an existing template
+func (h *NoteTemplateHandler) PublishVersion(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var req services.NoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	template, err := h.noteTemplateService.PublishVersion(c.Param("key"), &req, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		if err.Error() == "note template not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Note template version published successfully",
+		"template": template,
+	})
+}
+
+// RetireTemplate stops a template being offered for new notes
+func (h *NoteTemplateHandler) RetireTemplate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.noteTemplateService.RetireTemplate(c.Param("key"), userID, userRole, ipAddress, userAgent); err != nil {
+		if err.Error() == "note template not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Note template retired successfully"})
+}
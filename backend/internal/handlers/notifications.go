@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+	jwtService          *auth.JWTService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService, jwtService *auth.JWTService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		jwtService:          jwtService,
+	}
+}
+
+// GetNotifications lists the caller's notifications
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var query services.NotificationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set default pagination
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	notifications, total, err := h.notificationService.GetNotifications(&query, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"pagination": gin.H{
+			"current_page": query.Page,
+			"limit":        query.Limit,
+			"total":        total,
+			"total_pages":  (total + int64(query.Limit) - 1) / int64(query.Limit),
+		},
+	})
+}
+
+// MarkNotificationRead marks one of the caller's notifications as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	notificationIDStr := c.Param("id")
+	notificationID, err := strconv.ParseUint(notificationIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	notification, err := h.notificationService.MarkRead(uint(notificationID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notification": notification})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ObservationHandler struct {
+	observationService *services.ObservationService
+	jwtService         *auth.JWTService
+}
+
+func NewObservationHandler(observationService *services.ObservationService, jwtService *auth.JWTService) *ObservationHandler {
+	return &ObservationHandler{
+		observationService: observationService,
+		jwtService:         jwtService,
+	}
+}
+
+// RecordObservation records a vital sign or lab result for a patient
+func (h *ObservationHandler) RecordObservation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.RecordObservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	observation, err := h.observationService.RecordObservation(uint(patientID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Observation recorded successfully",
+		"observation": observation,
+	})
+}
+
+// GetObservations lists a patient's observations
+func (h *ObservationHandler) GetObservations(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var query services.ObservationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set default pagination
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 || query.Limit > 200 {
+		query.Limit = 50
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	observations, total, err := h.observationService.GetObservations(uint(patientID), &query, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"observations": observations,
+		"pagination": gin.H{
+			"current_page": query.Page,
+			"limit":        query.Limit,
+			"total":        total,
+			"total_pages":  (total + int64(query.Limit) - 1) / int64(query.Limit),
+		},
+	})
+}
+
+// GetObservationTrend returns one code's results over a time window
+func (h *ObservationHandler) GetObservationTrend(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var query services.TrendQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	trend, err := h.observationService.GetObservationTrend(uint(patientID), &query, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trend": trend})
+}
+
+// DeleteObservation removes an observation entered in error
+func (h *ObservationHandler) DeleteObservation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, observationID, ok := parseClinicalListIDs(c, "observationId", "observation")
+	if !ok {
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.observationService.DeleteObservation(patientID, observationID, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Observation deleted successfully"})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PatientIdentifierHandler struct {
+	identifierService *services.PatientIdentifierService
+	jwtService        *auth.JWTService
+}
+
+func NewPatientIdentifierHandler(identifierService *services.PatientIdentifierService, jwtService *auth.JWTService) *PatientIdentifierHandler {
+	return &PatientIdentifierHandler{
+		identifierService: identifierService,
+		jwtService:        jwtService,
+	}
+}
+
+// AddIdentifier records an external identifier for a patient
+func (h *PatientIdentifierHandler) AddIdentifier(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.AddIdentifierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	identifier, err := h.identifierService.AddIdentifier(uint(patientID), &req, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		if err.Error() == "identifier is already assigned to a patient" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Identifier added successfully",
+		"identifier": identifier,
+	})
+}
+
+// AssignMRN issues a patient an MRN at another facility
+func (h *PatientIdentifierHandler) AssignMRN(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.AssignMRNRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	identifier, err := h.identifierService.AssignMRN(uint(patientID), &req, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "MRN assigned successfully",
+		"identifier": identifier,
+	})
+}
+
+// DeleteIdentifier removes an external identifier entered in error
+func (h *PatientIdentifierHandler) DeleteIdentifier(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	identifierIDStr := c.Param("identifierId")
+	identifierID, err := strconv.ParseUint(identifierIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identifier ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.identifierService.DeleteIdentifier(uint(patientID), uint(identifierID), userID, userRole, ipAddress, userAgent); err != nil {
+		if err.Error() == "identifier not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identifier removed successfully"})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PatientProxyHandler struct {
+	proxyService *services.PatientProxyService
+	jwtService   *auth.JWTService
+}
+
+func NewPatientProxyHandler(proxyService *services.PatientProxyService, jwtService *auth.JWTService) *PatientProxyHandler {
+	return &PatientProxyHandler{
+		proxyService: proxyService,
+		jwtService:   jwtService,
+	}
+}
+
+// GetProxies lists the portal accounts that can view a patient's chart
+func (h *PatientProxyHandler) GetProxies(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	proxies, err := h.proxyService.GetProxies(uint(patientID), userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		if err.Error() == "patient not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"proxies": proxies})
+}
+
+// CreateProxy gives a portal account access to a patient's chart
+func (h *PatientProxyHandler) CreateProxy(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	var req services.CreateProxyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	proxy, err := h.proxyService.CreateProxy(uint(patientID), &req, userID, userRole, ipAddress, userAgent)
+	if err != nil {
+		switch err.Error() {
+		case "patient not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "portal account is already a proxy for this patient":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Proxy added successfully",
+		"proxy":   proxy,
+	})
+}
+
+// RevokeProxy ends a portal account's access to a patient's chart
+func (h *PatientProxyHandler) RevokeProxy(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	proxyIDStr := c.Param("proxyId")
+	proxyID, err := strconv.ParseUint(proxyIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proxy ID"})
+		return
+	}
+
+	var req services.RevokeProxyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.proxyService.RevokeProxy(uint(patientID), uint(proxyID), &req, userID, userRole, ipAddress, userAgent); err != nil {
+		if err.Error() == "proxy not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Proxy revoked successfully"})
+}
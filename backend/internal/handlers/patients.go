@@ -38,16 +38,20 @@ func (h *PatientHandler) CreatePatient(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	patient, err := h.patientService.CreatePatient(&req, userID, userRole, ipAddress, userAgent)
+	patient, duplicates, err := h.patientService.CreatePatient(&req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"message": "Patient created successfully",
 		"patient": patient,
-	})
+	}
+	if len(duplicates) > 0 {
+		response["possible_duplicates"] = duplicates
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // GetPatient retrieves a patient by ID
@@ -68,7 +72,11 @@ func (h *PatientHandler) GetPatient(c *gin.Context) {
 	// Check for emergency access
 	emergencyAccess := h.checkEmergencyAccess(c, userID, uint(patientID))
 
-	patient, err := h.patientService.GetPatient(uint(patientID), userID, userRole, ipAddress, userAgent, emergencyAccess)
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	patient, err := h.patientService.GetPatient(uint(patientID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -99,7 +107,9 @@ func (h *PatientHandler) GetPatients(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	patients, total, err := h.patientService.GetPatients(&query, userID, userRole, ipAddress, userAgent)
+	fields := getFieldsParam(c)
+
+	patients, total, err := h.patientService.GetPatients(&query, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c), fields)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -136,8 +146,9 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
 
-	patient, err := h.patientService.UpdatePatient(uint(patientID), &req, userID, userRole, ipAddress, userAgent)
+	patient, err := h.patientService.UpdatePatient(uint(patientID), &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -172,6 +183,29 @@ func (h *PatientHandler) DeletePatient(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Patient deleted successfully"})
 }
 
+// RestorePatient undoes a soft delete (admin only)
+func (h *PatientHandler) RestorePatient(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if err := h.patientService.RestorePatient(uint(patientID), userID, userRole, ipAddress, userAgent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Patient restored successfully"})
+}
+
 // SearchPatients searches patients by name
 func (h *PatientHandler) SearchPatients(c *gin.Context) {
 	userID := c.GetUint("user_id")
@@ -192,7 +226,9 @@ func (h *PatientHandler) SearchPatients(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	patients, err := h.patientService.SearchPatientsByName(name, userID, userRole, ipAddress, userAgent, limit)
+	fields := getFieldsParam(c)
+
+	patients, err := h.patientService.SearchPatientsByName(name, userID, userRole, ipAddress, userAgent, limit, getPurposeOfUse(c), fields)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -204,6 +240,46 @@ func (h *PatientHandler) SearchPatients(c *gin.Context) {
 	})
 }
 
+// LookupPatient retrieves a patient by MRN or by an external identifier
+func (h *PatientHandler) LookupPatient(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var query services.PatientLookupQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	purpose := getPurposeOfUse(c)
+
+	patientID, err := h.patientService.FindPatientByIdentifier(&query, userID, userRole, ipAddress, userAgent, purpose)
+	if err != nil {
+		switch {
+		case err.Error() == "patient not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err.Error() == "insufficient permissions to access patient data":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	emergencyAccess := h.checkEmergencyAccess(c, userID, patientID)
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	patient, err := h.patientService.GetPatient(patientID, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose, getFieldsParam(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"patient": patient})
+}
+
 // GetPatientWithRecords retrieves a patient with their medical records
 func (h *PatientHandler) GetPatientWithRecords(c *gin.Context) {
 	userID := c.GetUint("user_id")
@@ -222,7 +298,11 @@ func (h *PatientHandler) GetPatientWithRecords(c *gin.Context) {
 	// Check for emergency access
 	emergencyAccess := h.checkEmergencyAccess(c, userID, uint(patientID))
 
-	patient, err := h.patientService.GetPatientWithMedicalRecords(uint(patientID), userID, userRole, ipAddress, userAgent, emergencyAccess)
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	patient, err := h.patientService.GetPatientWithMedicalRecords(uint(patientID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -260,4 +340,115 @@ func (h *PatientHandler) checkEmergencyAccess(c *gin.Context, userID, patientID
 	// For now, we'll just check if the header exists
 	// In a real implementation, you would validate the token against the database
 	return true
+}
+
+// GetPatientRevisions lists the revisions of a patient's demographics
+func (h *PatientHandler) GetPatientRevisions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	page, limit := getPaginationParams(c)
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := h.checkEmergencyAccess(c, userID, uint(patientID))
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	revisions, total, err := h.patientService.GetPatientRevisions(uint(patientID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), page, limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"pagination": gin.H{
+			"current_page": page,
+			"limit":        limit,
+			"total":        total,
+			"total_pages":  (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetPatientAsOf returns the patient's demographics as it stood at the time given by the at parameter
+func (h *PatientHandler) GetPatientAsOf(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 timestamp"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := h.checkEmergencyAccess(c, userID, uint(patientID))
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	fields := getFieldsParam(c)
+
+	patient, revision, err := h.patientService.GetPatientAsOf(uint(patientID), at, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c), fields)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"patient":   patient,
+		"revision": revision,
+	})
+}
+
+// DiffPatientRevisions compares the revisions given by the from and to parameters
+func (h *PatientHandler) DiffPatientRevisions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientIDStr := c.Param("id")
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	from, fromErr := strconv.Atoi(c.Query("from"))
+	to, toErr := strconv.Atoi(c.Query("to"))
+	if fromErr != nil || toErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to revision versions are required"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := h.checkEmergencyAccess(c, userID, uint(patientID))
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	diff, err := h.patientService.DiffPatientRevisions(uint(patientID), from, to, userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
 }
\ No newline at end of file
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PortalHandler struct {
+	portalService *services.PortalService
+	jwtService    *auth.JWTService
+}
+
+func NewPortalHandler(portalService *services.PortalService, jwtService *auth.JWTService) *PortalHandler {
+	return &PortalHandler{
+		portalService: portalService,
+		jwtService:    jwtService,
+	}
+}
+
+// GetMyPatient returns the demographics of the signed-in patient or of a
+// patient they are a proxy for
+func (h *PortalHandler) GetMyPatient(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, ok := portalPatientParam(c)
+	if !ok {
+		return
+	}
+
+	patient, err := h.portalService.GetMyPatient(userID, userRole, patientID, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		portalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"patient": patient})
+}
+
+// GetMyRecords lists signed medical records
+func (h *PortalHandler) GetMyRecords(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, ok := portalPatientParam(c)
+	if !ok {
+		return
+	}
+	page, limit := getPaginationParams(c)
+
+	records, total, err := h.portalService.GetMyRecords(userID, userRole, patientID, c.ClientIP(), c.GetHeader("User-Agent"), page, limit)
+	if err != nil {
+		portalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records":    records,
+		"pagination": portalPagination(page, limit, total),
+	})
+}
+
+// GetMyMedications lists medications
+func (h *PortalHandler) GetMyMedications(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, ok := portalPatientParam(c)
+	if !ok {
+		return
+	}
+
+	medications, err := h.portalService.GetMyMedications(userID, userRole, patientID, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		portalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"medications": medications})
+}
+
+// GetMyResults lists vital signs and lab results
+func (h *PortalHandler) GetMyResults(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	patientID, ok := portalPatientParam(c)
+	if !ok {
+		return
+	}
+	page, limit := getPaginationParams(c)
+
+	results, total, err := h.portalService.GetMyResults(userID, userRole, patientID, c.ClientIP(), c.GetHeader("User-Agent"), page, limit)
+	if err != nil {
+		portalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":    results,
+		"pagination": portalPagination(page, limit, total),
+	})
+}
+
+// GetMyAccessLog lists who accessed the signed-in patient's chart. With
+// format=csv the whole log is downloaded as a CSV file.
+func (h *PortalHandler) GetMyAccessLog(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	if c.Query("format") == "csv" {
+		entries, _, err := h.portalService.GetMyAccessLog(userID, userRole, ipAddress, userAgent, 1, 0)
+		if err != nil {
+			portalError(c, err)
+			return
+		}
+		h.portalService.LogAccessLogDownload(userID, userRole, ipAddress, userAgent)
+
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="access-log.csv"`)
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{"timestamp", "user_name", "user_role", "action", "resource", "purpose", "emergency_use", "disclosed_fields"})
+		for _, entry := range entries {
+			w.Write([]string{
+				entry.Timestamp.UTC().Format(time.RFC3339),
+				entry.UserName,
+				string(entry.UserRole),
+				string(entry.Action),
+				entry.Resource,
+				string(entry.Purpose),
+				strconv.FormatBool(entry.EmergencyUse),
+				strings.Join(entry.DisclosedFields, " "),
+			})
+		}
+		w.Flush()
+		return
+	}
+
+	page, limit := getPaginationParams(c)
+	entries, total, err := h.portalService.GetMyAccessLog(userID, userRole, ipAddress, userAgent, page, limit)
+	if err != nil {
+		portalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_log": entries,
+		"pagination": portalPagination(page, limit, total),
+	})
+}
+
+// GetMyDependents lists the patients the signed-in account is a proxy for
+func (h *PortalHandler) GetMyDependents(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	dependents, err := h.portalService.GetMyDependents(userID, userRole, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		portalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dependents": dependents})
+}
+
+// portalPatientParam returns the dependent patient named in the URL, or zero
+// on routes for the account's own chart
+func portalPatientParam(c *gin.Context) (uint, bool) {
+	patientIDStr := c.Param("id")
+	if patientIDStr == "" {
+		return 0, true
+	}
+	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return 0, false
+	}
+	return uint(patientID), true
+}
+
+func portalPagination(page, limit int, total int64) gin.H {
+	return gin.H{
+		"current_page": page,
+		"limit":        limit,
+		"total":        total,
+		"total_pages":  (total + int64(limit) - 1) / int64(limit),
+	}
+}
+
+func portalError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "patient not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "only patient portal accounts can use the portal",
+		strings.HasPrefix(err.Error(), "proxy access does not include"):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
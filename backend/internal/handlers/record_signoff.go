@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecordSignoffHandler struct {
+	signoffService *services.RecordSignoffService
+	jwtService     *auth.JWTService
+}
+
+func NewRecordSignoffHandler(signoffService *services.RecordSignoffService, jwtService *auth.JWTService) *RecordSignoffHandler {
+	return &RecordSignoffHandler{
+		signoffService: signoffService,
+		jwtService:     jwtService,
+	}
+}
+
+// SignMedicalRecord signs and locks a draft record
+func (h *RecordSignoffHandler) SignMedicalRecord(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	// The body is optional: only residents need to name a cosigner
+	var req services.SignRecordRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	record, err := h.signoffService.SignMedicalRecord(uint(recordID), &req, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err == models.ErrRecordLocked {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Medical record signed successfully",
+		"record":  record,
+	})
+}
+
+// CosignMedicalRecord records an attending's cosignature on a resident's record
+func (h *RecordSignoffHandler) CosignMedicalRecord(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	record, err := h.signoffService.CosignMedicalRecord(uint(recordID), userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Medical record cosigned successfully",
+		"record":  record,
+	})
+}
+
+// GetAddenda lists the addenda of a medical record
+func (h *RecordSignoffHandler) GetAddenda(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Check for emergency access
+	emergencyAccess := c.GetHeader("X-Emergency-Access-Token") != ""
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	addenda, err := h.signoffService.GetAddenda(uint(recordID), userID, userRole, ipAddress, userAgent, emergencyAccess, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addenda": addenda})
+}
+
+// AddAddendum appends a correction to a signed medical record
+func (h *RecordSignoffHandler) AddAddendum(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	recordIDStr := c.Param("id")
+	recordID, err := strconv.ParseUint(recordIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	var req services.CreateAddendumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	accessReason := c.GetHeader("X-Access-Reason")
+
+	addendum, err := h.signoffService.AddAddendum(uint(recordID), &req, userID, userRole, ipAddress, userAgent, accessReason, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Addendum added successfully",
+		"addendum": addendum,
+	})
+}
+
+// GetWorkQueue lists the caller's overdue drafts and pending cosignatures
+func (h *RecordSignoffHandler) GetWorkQueue(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	queue, err := h.signoffService.GetWorkQueue(userID, userRole)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"work_queue": queue})
+}
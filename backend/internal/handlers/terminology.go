@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"healthsecure/internal/auth"
+	"healthsecure/internal/models"
+	"healthsecure/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TerminologyHandler struct {
+	terminologyService *services.TerminologyService
+	jwtService         *auth.JWTService
+}
+
+func NewTerminologyHandler(terminologyService *services.TerminologyService, jwtService *auth.JWTService) *TerminologyHandler {
+	return &TerminologyHandler{
+		terminologyService: terminologyService,
+		jwtService:         jwtService,
+	}
+}
+
+// LookupCode returns a code with its display and ancestors
+func (h *TerminologyHandler) LookupCode(c *gin.Context) {
+	concept, err := h.terminologyService.Lookup(models.CodeSystem(c.Param("system")), c.Param("code"))
+	if err != nil {
+		if err.Error() == "code not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"concept": concept})
+}
+
+// SearchCodes finds codes by code prefix or description
+func (h *TerminologyHandler) SearchCodes(c *gin.Context) {
+	var query services.TerminologySearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if query.Limit <= 0 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	matches, err := h.terminologyService.Search(&query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": matches})
+}
+
+// GetDiagnosisReport counts coded diagnoses grouped by code hierarchy
+func (h *TerminologyHandler) GetDiagnosisReport(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	userRole := models.UserRole(c.GetString("user_role"))
+
+	var query services.DiagnosisReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	report, err := h.terminologyService.GetDiagnosisReport(&query, userID, userRole, ipAddress, userAgent, getPurposeOfUse(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
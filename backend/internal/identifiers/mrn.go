@@ -0,0 +1,211 @@
+// Package identifiers formats and checks the medical record numbers issued
+// to patients. MRNs are built from a configurable format holding the
+// facility code, a zero-padded per-facility sequence number and a check
+// digit, so that a mistyped MRN is caught before it reaches the wrong chart.
+package identifiers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Check digit algorithms
+const (
+	CheckLuhn  = "luhn"
+	CheckMod11 = "mod11"
+	CheckNone  = "none"
+)
+
+const maxSequenceWidth = 18
+
+var (
+	facilityPattern = regexp.MustCompile(`^[A-Z0-9]{1,16}$`)
+	tokenPattern    = regexp.MustCompile(`\{[^}]*\}`)
+	literalPattern  = regexp.MustCompile(`^[A-Z0-9-]*$`)
+)
+
+// MRNGenerator formats and validates MRNs for one configured format
+type MRNGenerator struct {
+	format          string
+	checkDigit      string
+	defaultFacility string
+	sequenceWidth   int
+	pattern         *regexp.Regexp
+}
+
+// NewMRNGenerator parses a format such as "{facility}-{seq:7}{check}".
+// {seq:N} is the sequence number padded to N digits and is required;
+// {facility} and {check} are optional, but {check} must be present exactly
+// when a check digit algorithm is chosen. Other characters must be upper case
+// letters, digits or hyphens.
+func NewMRNGenerator(format, checkDigit, defaultFacility string) (*MRNGenerator, error) {
+	switch checkDigit {
+	case CheckLuhn, CheckMod11, CheckNone:
+	default:
+		return nil, fmt.Errorf("unknown MRN check digit algorithm %q", checkDigit)
+	}
+	if !ValidFacility(defaultFacility) {
+		return nil, fmt.Errorf("MRN facility %q must be 1-16 upper case letters or digits", defaultFacility)
+	}
+
+	g := &MRNGenerator{format: format, checkDigit: checkDigit, defaultFacility: defaultFacility}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	seen := make(map[string]bool)
+	rest := format
+	for {
+		loc := tokenPattern.FindStringIndex(rest)
+		literal := rest
+		if loc != nil {
+			literal = rest[:loc[0]]
+		}
+		if !literalPattern.MatchString(literal) {
+			return nil, fmt.Errorf("MRN format %q may only contain upper case letters, digits and hyphens outside tokens", format)
+		}
+		pattern.WriteString(regexp.QuoteMeta(literal))
+		if loc == nil {
+			break
+		}
+
+		token := rest[loc[0]+1 : loc[1]-1]
+		name := token
+		if strings.HasPrefix(token, "seq:") {
+			name = "seq"
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("MRN format %q repeats {%s}", format, name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "facility":
+			pattern.WriteString(`(?P<facility>[A-Z0-9]{1,16})`)
+		case "seq":
+			width, err := strconv.Atoi(strings.TrimPrefix(token, "seq:"))
+			if err != nil || width < 1 || width > maxSequenceWidth {
+				return nil, fmt.Errorf("MRN format %q needs a sequence width from 1 to %d", format, maxSequenceWidth)
+			}
+			g.sequenceWidth = width
+			fmt.Fprintf(&pattern, `(?P<seq>[0-9]{%d})`, width)
+		case "check":
+			if !seen["seq"] {
+				return nil, fmt.Errorf("MRN format %q must place {check} after {seq:N}", format)
+			}
+			pattern.WriteString(`(?P<check>[0-9X])`)
+		default:
+			return nil, fmt.Errorf("MRN format %q has unknown token {%s}", format, token)
+		}
+		rest = rest[loc[1]:]
+	}
+	pattern.WriteString("$")
+
+	if !seen["seq"] {
+		return nil, fmt.Errorf("MRN format %q needs a {seq:N} token", format)
+	}
+	if seen["check"] != (checkDigit != CheckNone) {
+		return nil, fmt.Errorf("MRN format %q must include {check} exactly when a check digit algorithm is set", format)
+	}
+
+	g.pattern = regexp.MustCompile(pattern.String())
+	return g, nil
+}
+
+// ValidFacility reports whether a facility code may appear in an MRN
+func ValidFacility(facility string) bool {
+	return facilityPattern.MatchString(facility)
+}
+
+// DefaultFacility is the facility new patients are registered at
+func (g *MRNGenerator) DefaultFacility() string {
+	return g.defaultFacility
+}
+
+// Format builds the MRN for a facility's sequence number
+func (g *MRNGenerator) Format(facility string, sequence uint64) (string, error) {
+	if !ValidFacility(facility) {
+		return "", fmt.Errorf("invalid facility code %q", facility)
+	}
+	digits := fmt.Sprintf("%0*d", g.sequenceWidth, sequence)
+	if len(digits) > g.sequenceWidth {
+		return "", fmt.Errorf("MRN sequence for facility %s is exhausted", facility)
+	}
+
+	check := ""
+	if g.checkDigit != CheckNone {
+		check = checkDigit(g.checkDigit, digits)
+	}
+
+	mrn := strings.ReplaceAll(g.format, "{facility}", facility)
+	mrn = strings.ReplaceAll(mrn, fmt.Sprintf("{seq:%d}", g.sequenceWidth), digits)
+	mrn = strings.ReplaceAll(mrn, "{check}", check)
+	return mrn, nil
+}
+
+// Parse checks an MRN against the format and its check digit. It returns the
+// facility the MRN names, or an empty string when the format has none.
+func (g *MRNGenerator) Parse(mrn string) (string, error) {
+	match := g.pattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(mrn)))
+	if match == nil {
+		return "", fmt.Errorf("MRN does not match the expected format")
+	}
+
+	var facility, digits, check string
+	for i, name := range g.pattern.SubexpNames() {
+		switch name {
+		case "facility":
+			facility = match[i]
+		case "seq":
+			digits = match[i]
+		case "check":
+			check = match[i]
+		}
+	}
+
+	if g.checkDigit != CheckNone && checkDigit(g.checkDigit, digits) != check {
+		return "", fmt.Errorf("MRN check digit is wrong")
+	}
+	return facility, nil
+}
+
+func checkDigit(algorithm, digits string) string {
+	switch algorithm {
+	case CheckLuhn:
+		return strconv.Itoa(luhn(digits))
+	case CheckMod11:
+		if check := mod11(digits); check != 10 {
+			return strconv.Itoa(check)
+		}
+		return "X"
+	}
+	return ""
+}
+
+// luhn returns the Luhn mod 10 check digit
+func luhn(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
+
+// mod11 returns the ISO 7064 MOD 11-2 check character, with 10 written as X
+func mod11(digits string) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		sum = (sum + int(digits[i]-'0')) * 2 % 11
+	}
+	return (12 - sum%11) % 11
+}
@@ -0,0 +1,84 @@
+package identifiers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMRNGeneratorFormat(t *testing.T) {
+	g, err := NewMRNGenerator("{facility}-{seq:7}{check}", CheckLuhn, "MAIN")
+	require.NoError(t, err)
+
+	mrn, err := g.Format("MAIN", 42)
+	require.NoError(t, err)
+	assert.Equal(t, "MAIN-00000422", mrn)
+
+	facility, err := g.Parse("main-00000422")
+	require.NoError(t, err)
+	assert.Equal(t, "MAIN", facility)
+}
+
+func TestMRNGeneratorRejectsTypos(t *testing.T) {
+	g, err := NewMRNGenerator("{facility}-{seq:7}{check}", CheckLuhn, "MAIN")
+	require.NoError(t, err)
+
+	_, err = g.Parse("MAIN-00000423")
+	assert.Error(t, err, "wrong check digit")
+	_, err = g.Parse("MAIN-00000242")
+	assert.Error(t, err, "transposed digits")
+	_, err = g.Parse("MAIN-0000422")
+	assert.Error(t, err, "short sequence")
+}
+
+func TestMRNGeneratorMod11(t *testing.T) {
+	g, err := NewMRNGenerator("H{seq:15}{check}", CheckMod11, "MAIN")
+	require.NoError(t, err)
+
+	// The ISO 7064 MOD 11-2 example used by ORCID, 0000-0002-1694-233X
+	mrn, err := g.Format("MAIN", 21694233)
+	require.NoError(t, err)
+	assert.Equal(t, "H000000021694233X", mrn)
+
+	facility, err := g.Parse(mrn)
+	require.NoError(t, err)
+	assert.Empty(t, facility)
+}
+
+func TestLuhn(t *testing.T) {
+	assert.Equal(t, 3, luhn("7992739871"))
+	assert.Equal(t, 0, luhn("0000000"))
+}
+
+func TestMRNGeneratorSequenceExhausted(t *testing.T) {
+	g, err := NewMRNGenerator("{seq:3}", CheckNone, "MAIN")
+	require.NoError(t, err)
+
+	_, err = g.Format("MAIN", 999)
+	assert.NoError(t, err)
+	_, err = g.Format("MAIN", 1000)
+	assert.Error(t, err)
+}
+
+func TestNewMRNGeneratorRejectsBadFormats(t *testing.T) {
+	for _, tc := range []struct {
+		format, check string
+	}{
+		{"{facility}-{check}", CheckLuhn},
+		{"{seq:7}", CheckLuhn},
+		{"{seq:7}{check}", CheckNone},
+		{"{check}{seq:7}", CheckLuhn},
+		{"{seq:0}", CheckNone},
+		{"{seq:7}{seq:7}", CheckNone},
+		{"mrn-{seq:7}", CheckNone},
+		{"{site}{seq:7}", CheckNone},
+		{"{seq:7}", "crc"},
+	} {
+		_, err := NewMRNGenerator(tc.format, tc.check, "MAIN")
+		assert.Error(t, err, tc.format)
+	}
+
+	_, err := NewMRNGenerator("{seq:7}", CheckNone, "main")
+	assert.Error(t, err, "lower case facility")
+}
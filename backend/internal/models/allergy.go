@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AllergySeverity string
+
+const (
+	AllergySeverityMild            AllergySeverity = "mild"
+	AllergySeverityModerate        AllergySeverity = "moderate"
+	AllergySeveritySevere          AllergySeverity = "severe"
+	AllergySeverityLifeThreatening AllergySeverity = "life_threatening"
+)
+
+func IsValidAllergySeverity(severity AllergySeverity) bool {
+	switch severity {
+	case AllergySeverityMild, AllergySeverityModerate, AllergySeveritySevere, AllergySeverityLifeThreatening:
+		return true
+	}
+	return false
+}
+
+type AllergyStatus string
+
+const (
+	AllergyStatusActive   AllergyStatus = "active"
+	AllergyStatusInactive AllergyStatus = "inactive"
+)
+
+// Allergy records a substance the patient reacts to. Deleting an allergy
+// marks it entered in error; an allergy that no longer applies is set
+// inactive instead.
+type Allergy struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	PatientID  uint            `json:"patient_id" gorm:"not null;index"`
+	Substance  string          `json:"substance" gorm:"type:text;not null;serializer:encrypted"`
+	Reaction   string          `json:"reaction" gorm:"type:text;serializer:encrypted"`
+	Severity   AllergySeverity `json:"severity" gorm:"type:enum('mild','moderate','severe','life_threatening');not null"`
+	Status     AllergyStatus   `json:"status" gorm:"type:enum('active','inactive');default:'active';index"`
+	RecordedBy uint            `json:"recorded_by" gorm:"not null"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt  `json:"-" gorm:"index"`
+}
+
+func (a *Allergy) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	if a.UpdatedAt.IsZero() {
+		a.UpdatedAt = time.Now()
+	}
+	if a.Status == "" {
+		a.Status = AllergyStatusActive
+	}
+	return
+}
+
+func (a *Allergy) BeforeUpdate(tx *gorm.DB) (err error) {
+	a.UpdatedAt = time.Now()
+	return
+}
+
+func (a *Allergy) IsActive() bool {
+	return a.Status == AllergyStatusActive
+}
+
+func (a *Allergy) TableName() string {
+	return "allergies"
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AmendmentStatus string
+
+const (
+	AmendmentStatusSubmitted   AmendmentStatus = "submitted"
+	AmendmentStatusUnderReview AmendmentStatus = "under_review"
+	AmendmentStatusAccepted    AmendmentStatus = "accepted"
+	AmendmentStatusDenied      AmendmentStatus = "denied"
+)
+
+// HIPAA (45 CFR 164.526) requires a decision within 60 days of receiving the
+// request, extendable once by up to 30 days with notice to the patient.
+const (
+	AmendmentResponseWindow = 60 * 24 * time.Hour
+	AmendmentExtension      = 30 * 24 * time.Hour
+)
+
+// AmendmentRequest is a patient's request to amend a medical record. An
+// accepted request appends an addendum to the record; a denied one keeps the
+// basis for denial and any statement of disagreement the patient files.
+type AmendmentRequest struct {
+	ID                    uint            `json:"id" gorm:"primaryKey"`
+	RecordID              uint            `json:"record_id" gorm:"not null;index"`
+	PatientID             uint            `json:"patient_id" gorm:"not null;index"`
+	RequestedChange       string          `json:"requested_change" gorm:"type:text;not null;serializer:encrypted"`
+	Justification         string          `json:"justification" gorm:"type:text;serializer:encrypted"`
+	Status                AmendmentStatus `json:"status" gorm:"type:enum('submitted','under_review','accepted','denied');default:'submitted';index"`
+	ReceivedAt            time.Time       `json:"received_at" gorm:"not null"`
+	DueAt                 time.Time       `json:"due_at" gorm:"not null;index"`
+	ExtensionReason       string          `json:"extension_reason,omitempty" gorm:"type:text"`
+	SubmittedBy           uint            `json:"submitted_by" gorm:"not null"`
+	ReviewerID            *uint           `json:"reviewer_id,omitempty"`
+	ReviewStartedAt       *time.Time      `json:"review_started_at,omitempty"`
+	DecidedBy             *uint           `json:"decided_by,omitempty"`
+	DecidedAt             *time.Time      `json:"decided_at,omitempty"`
+	DenialReason          string          `json:"denial_reason,omitempty" gorm:"type:text;serializer:encrypted"`
+	DisagreementStatement string          `json:"disagreement_statement,omitempty" gorm:"type:text;serializer:encrypted"`
+	DisagreementFiledAt   *time.Time      `json:"disagreement_filed_at,omitempty"`
+	AddendumID            *uint           `json:"addendum_id,omitempty"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+}
+
+func (ar *AmendmentRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	if ar.CreatedAt.IsZero() {
+		ar.CreatedAt = time.Now()
+	}
+	if ar.UpdatedAt.IsZero() {
+		ar.UpdatedAt = time.Now()
+	}
+	if ar.ReceivedAt.IsZero() {
+		ar.ReceivedAt = ar.CreatedAt
+	}
+	if ar.DueAt.IsZero() {
+		ar.DueAt = ar.ReceivedAt.Add(AmendmentResponseWindow)
+	}
+	if ar.Status == "" {
+		ar.Status = AmendmentStatusSubmitted
+	}
+	return
+}
+
+func (ar *AmendmentRequest) BeforeUpdate(tx *gorm.DB) (err error) {
+	ar.UpdatedAt = time.Now()
+	return
+}
+
+func (ar *AmendmentRequest) IsOpen() bool {
+	return ar.Status == AmendmentStatusSubmitted || ar.Status == AmendmentStatusUnderReview
+}
+
+func (ar *AmendmentRequest) IsExtended() bool {
+	return ar.ExtensionReason != ""
+}
+
+// IsOverdue reports whether an open request has passed its response deadline
+func (ar *AmendmentRequest) IsOverdue(now time.Time) bool {
+	return ar.IsOpen() && now.After(ar.DueAt)
+}
+
+func (ar *AmendmentRequest) StartReview(reviewerID uint) error {
+	if ar.Status != AmendmentStatusSubmitted {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	ar.Status = AmendmentStatusUnderReview
+	ar.ReviewerID = &reviewerID
+	ar.ReviewStartedAt = &now
+	return nil
+}
+
+// Extend pushes the deadline back once, before it has passed
+func (ar *AmendmentRequest) Extend(reason string, now time.Time) error {
+	if !ar.IsOpen() || ar.IsExtended() || now.After(ar.DueAt) || reason == "" {
+		return gorm.ErrInvalidValue
+	}
+
+	ar.DueAt = ar.DueAt.Add(AmendmentExtension)
+	ar.ExtensionReason = reason
+	return nil
+}
+
+func (ar *AmendmentRequest) Accept(decidedBy, addendumID uint) error {
+	if ar.Status != AmendmentStatusUnderReview {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	ar.Status = AmendmentStatusAccepted
+	ar.DecidedBy = &decidedBy
+	ar.DecidedAt = &now
+	ar.AddendumID = &addendumID
+	return nil
+}
+
+func (ar *AmendmentRequest) Deny(decidedBy uint, reason string) error {
+	if ar.Status != AmendmentStatusUnderReview || reason == "" {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	ar.Status = AmendmentStatusDenied
+	ar.DecidedBy = &decidedBy
+	ar.DecidedAt = &now
+	ar.DenialReason = reason
+	return nil
+}
+
+// FileDisagreement records the patient's statement disagreeing with a denial.
+// Only one statement is kept.
+func (ar *AmendmentRequest) FileDisagreement(statement string) error {
+	if ar.Status != AmendmentStatusDenied || ar.DisagreementStatement != "" || statement == "" {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	ar.DisagreementStatement = statement
+	ar.DisagreementFiledAt = &now
+	return nil
+}
+
+func (ar *AmendmentRequest) TableName() string {
+	return "amendment_requests"
+}
+
+type AmendmentRequestFilter struct {
+	RecordID  *uint
+	DoctorID  *uint
+	Status    *AmendmentStatus
+	OverdueAt *time.Time
+	Limit     int
+	Offset    int
+}
+
+func (f *AmendmentRequestFilter) Apply(db *gorm.DB) *gorm.DB {
+	query := db
+
+	if f.RecordID != nil {
+		query = query.Where("record_id = ?", *f.RecordID)
+	}
+	if f.DoctorID != nil {
+		query = query.Where("record_id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&MedicalRecord{}).Select("id").Where("doctor_id = ?", *f.DoctorID))
+	}
+	if f.Status != nil {
+		query = query.Where("status = ?", *f.Status)
+	}
+	if f.OverdueAt != nil {
+		query = query.Where("status IN ? AND due_at < ?",
+			[]AmendmentStatus{AmendmentStatusSubmitted, AmendmentStatusUnderReview}, *f.OverdueAt)
+	}
+
+	query = query.Order("created_at DESC")
+
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		query = query.Offset(f.Offset)
+	}
+
+	return query
+}
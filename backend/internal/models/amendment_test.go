@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmendmentRequest(t *testing.T) {
+	received := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	newRequest := func() *AmendmentRequest {
+		request := &AmendmentRequest{RecordID: 1, PatientID: 1, RequestedChange: "Allergy is to penicillin, not amoxicillin", ReceivedAt: received}
+		request.BeforeCreate(nil)
+		return request
+	}
+
+	t.Run("SixtyDayDeadline", func(t *testing.T) {
+		request := newRequest()
+
+		assert.Equal(t, AmendmentStatusSubmitted, request.Status)
+		assert.Equal(t, received.Add(60*24*time.Hour), request.DueAt)
+		assert.False(t, request.IsOverdue(received.Add(59*24*time.Hour)))
+		assert.True(t, request.IsOverdue(received.Add(61*24*time.Hour)))
+	})
+
+	t.Run("SingleExtensionBeforeDeadline", func(t *testing.T) {
+		request := newRequest()
+
+		assert.Error(t, request.Extend("Records archived off site", received.Add(61*24*time.Hour)))
+		assert.NoError(t, request.Extend("Records archived off site", received.Add(30*24*time.Hour)))
+		assert.Equal(t, received.Add(90*24*time.Hour), request.DueAt)
+		assert.Error(t, request.Extend("Second extension", received.Add(31*24*time.Hour)))
+	})
+
+	t.Run("AcceptAfterReview", func(t *testing.T) {
+		request := newRequest()
+
+		assert.Error(t, request.Accept(2, 7))
+		assert.NoError(t, request.StartReview(2))
+		assert.NoError(t, request.Accept(2, 7))
+		assert.Equal(t, AmendmentStatusAccepted, request.Status)
+		assert.Equal(t, uint(7), *request.AddendumID)
+		assert.False(t, request.IsOverdue(received.Add(365*24*time.Hour)))
+	})
+
+	t.Run("DenyWithDisagreement", func(t *testing.T) {
+		request := newRequest()
+		assert.NoError(t, request.StartReview(2))
+
+		assert.Error(t, request.FileDisagreement("I was never prescribed amoxicillin"))
+		assert.Error(t, request.Deny(2, ""))
+		assert.NoError(t, request.Deny(2, "Record is accurate and complete"))
+		assert.Equal(t, AmendmentStatusDenied, request.Status)
+
+		assert.NoError(t, request.FileDisagreement("I was never prescribed amoxicillin"))
+		assert.NotNil(t, request.DisagreementFiledAt)
+		assert.Error(t, request.FileDisagreement("A second statement"))
+	})
+}
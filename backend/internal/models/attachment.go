@@ -0,0 +1,108 @@
+package models
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentCategory string
+
+const (
+	AttachmentReferralLetter AttachmentCategory = "referral_letter"
+	AttachmentImagingReport  AttachmentCategory = "imaging_report"
+	AttachmentLabReport      AttachmentCategory = "lab_report"
+	AttachmentConsentForm    AttachmentCategory = "consent_form"
+	AttachmentOther          AttachmentCategory = "other"
+)
+
+func IsValidAttachmentCategory(category AttachmentCategory) bool {
+	switch category {
+	case AttachmentReferralLetter, AttachmentImagingReport, AttachmentLabReport, AttachmentConsentForm, AttachmentOther:
+		return true
+	}
+	return false
+}
+
+// Attachment is a scanned or exported document filed on a patient's chart,
+// optionally against one medical record. The blob is kept encrypted in the
+// attachment store under StorageKey; only metadata lives in the database.
+// Record attachments are disclosed under the record's own access rules.
+type Attachment struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	PatientID   uint               `json:"patient_id" gorm:"not null;index"`
+	RecordID    *uint              `json:"record_id,omitempty" gorm:"index"`
+	Category    AttachmentCategory `json:"category" gorm:"type:enum('referral_letter','imaging_report','lab_report','consent_form','other');default:'other'"`
+	Filename    string             `json:"filename" gorm:"type:text;not null;serializer:encrypted"`
+	ContentType string             `json:"content_type" gorm:"size:100;not null"`
+	Size        int64              `json:"size" gorm:"not null"`
+	SHA256      string             `json:"sha256" gorm:"column:sha256;size:64;not null"`
+	StorageKey  string             `json:"-" gorm:"size:255;not null;uniqueIndex"`
+	UploadedBy  uint               `json:"uploaded_by" gorm:"not null"`
+	CreatedAt   time.Time          `json:"created_at"`
+	DeletedAt   gorm.DeletedAt     `json:"-" gorm:"index"`
+
+	Uploader User `json:"uploader,omitempty" gorm:"foreignKey:UploadedBy"`
+}
+
+func (a *Attachment) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	if a.Category == "" {
+		a.Category = AttachmentOther
+	}
+	return
+}
+
+func (a *Attachment) TableName() string {
+	return "attachments"
+}
+
+// attachmentSignatures are the document formats accepted for upload, by the
+// magic bytes they start with. The declared content type is not trusted.
+var attachmentSignatures = []struct {
+	contentType string
+	magic       []byte
+}{
+	{"application/pdf", []byte("%PDF-")},
+	{"image/png", []byte("\x89PNG\r\n\x1a\n")},
+	{"image/jpeg", []byte("\xff\xd8\xff")},
+	{"image/tiff", []byte("II*\x00")},
+	{"image/tiff", []byte("MM\x00*")},
+}
+
+// DetectAttachmentType returns the content type of an accepted document
+// format, or an empty string when the data is not one
+func DetectAttachmentType(data []byte) string {
+	for _, signature := range attachmentSignatures {
+		if bytes.HasPrefix(data, signature.magic) {
+			return signature.contentType
+		}
+	}
+	return ""
+}
+
+// CleanAttachmentFilename keeps the base name of an uploaded file without
+// control characters, so it is safe to echo in a Content-Disposition header
+func CleanAttachmentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[len(runes)-255:])
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectAttachmentType(t *testing.T) {
+	assert.Equal(t, "application/pdf", DetectAttachmentType([]byte("%PDF-1.7\n...")))
+	assert.Equal(t, "image/png", DetectAttachmentType([]byte("\x89PNG\r\n\x1a\n\x00\x00")))
+	assert.Equal(t, "image/jpeg", DetectAttachmentType([]byte("\xff\xd8\xff\xe0JFIF")))
+	assert.Equal(t, "image/tiff", DetectAttachmentType([]byte("II*\x00\x08\x00")))
+	assert.Equal(t, "image/tiff", DetectAttachmentType([]byte("MM\x00*\x00\x08")))
+
+	assert.Empty(t, DetectAttachmentType([]byte("<html><script>")))
+	assert.Empty(t, DetectAttachmentType([]byte("MZ\x90\x00")), "executables are rejected")
+	assert.Empty(t, DetectAttachmentType(nil))
+}
+
+func TestCleanAttachmentFilename(t *testing.T) {
+	assert.Equal(t, "referral.pdf", CleanAttachmentFilename("referral.pdf"))
+	assert.Equal(t, "scan.tiff", CleanAttachmentFilename(`C:\Users\front desk\scan.tiff`))
+	assert.Equal(t, "passwd", CleanAttachmentFilename("../../etc/passwd"))
+	assert.Equal(t, "ab.pdf", CleanAttachmentFilename("a\r\n\"b.pdf"))
+	assert.Equal(t, "attachment", CleanAttachmentFilename(""))
+	assert.Equal(t, "attachment", CleanAttachmentFilename("/"))
+}
+
+func TestAttachmentsFollowRecordSanitization(t *testing.T) {
+	attachments := []Attachment{{ID: 1, Filename: "echo-report.pdf"}}
+
+	routine := &MedicalRecord{Severity: SeverityLow, Sensitivity: SensitivityNormal, Attachments: attachments}
+	critical := &MedicalRecord{Severity: SeverityCritical, Sensitivity: SensitivityNormal, Attachments: attachments}
+	sensitive := &MedicalRecord{Severity: SeverityLow, Sensitivity: SensitivityMentalHealth, Attachments: attachments}
+
+	assert.Len(t, routine.SanitizeForRole(RoleDoctor).Attachments, 1)
+	assert.Len(t, critical.SanitizeForRole(RoleDoctor).Attachments, 1)
+	assert.Len(t, routine.SanitizeForRole(RoleNurse).Attachments, 1)
+
+	assert.Empty(t, critical.SanitizeForRole(RoleNurse).Attachments)
+	assert.Empty(t, sensitive.SanitizeForRole(RoleNurse).Attachments)
+	assert.Empty(t, routine.SanitizeForRole(RoleBilling).Attachments, "billing never sees clinical documents")
+	assert.Nil(t, routine.SanitizeForRole(RoleFrontDesk))
+
+	assert.Len(t, routine.Attachments, 1, "the original record is not changed")
+}
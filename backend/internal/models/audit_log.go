@@ -18,25 +18,44 @@ const (
 	ActionEmergencyRequest AuditAction = "EMERGENCY_REQUEST"
 	ActionEmergencyAccess  AuditAction = "EMERGENCY_ACCESS"
 	ActionUnauthorized     AuditAction = "UNAUTHORIZED_ACCESS"
+	ActionAlertOverride    AuditAction = "ALERT_OVERRIDE"
+	ActionDownload         AuditAction = "DOWNLOAD"
+	ActionExport           AuditAction = "EXPORT"
+)
+
+// DisclosureCategory groups the entries of an accounting of disclosures
+type DisclosureCategory string
+
+const (
+	DisclosureEmergency  DisclosureCategory = "emergency_access"
+	DisclosureExport     DisclosureCategory = "export"
+	DisclosureExternal   DisclosureCategory = "external"
+	DisclosureRoutineTPO DisclosureCategory = "treatment_payment_operations"
 )
 
 type AuditLog struct {
-	ID           uint        `json:"id" gorm:"primaryKey"`
-	UserID       uint        `json:"user_id" gorm:"not null;index"`
-	PatientID    *uint       `json:"patient_id,omitempty" gorm:"index"`
-	RecordID     *uint       `json:"record_id,omitempty" gorm:"index"`
-	Action       AuditAction `json:"action" gorm:"not null;index"`
-	Resource     string      `json:"resource" gorm:"not null"`
-	IPAddress    string      `json:"ip_address" gorm:"not null"`
-	UserAgent    string      `json:"user_agent" gorm:"type:text"`
-	EmergencyUse bool        `json:"emergency_use" gorm:"default:false;index"`
-	Reason       string      `json:"reason,omitempty" gorm:"type:text"`
-	Success      bool        `json:"success" gorm:"default:true;index"`
-	ErrorMessage string      `json:"error_message,omitempty"`
-	Timestamp    time.Time   `json:"timestamp" gorm:"autoCreateTime;index"`
-
-	User    User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Patient *Patient `json:"patient,omitempty" gorm:"foreignKey:PatientID"`
+	ID              uint         `json:"id" gorm:"primaryKey"`
+	UserID          uint         `json:"user_id" gorm:"not null;index"`
+	PatientID       *uint        `json:"patient_id,omitempty" gorm:"index"`
+	RecordID        *uint        `json:"record_id,omitempty" gorm:"index"`
+	Action          AuditAction  `json:"action" gorm:"not null;index"`
+	Resource        string       `json:"resource" gorm:"not null"`
+	IPAddress       string       `json:"ip_address" gorm:"not null"`
+	UserAgent       string       `json:"user_agent" gorm:"type:text"`
+	EmergencyUse    bool         `json:"emergency_use" gorm:"default:false;index"`
+	SensitiveAccess bool         `json:"sensitive_access" gorm:"default:false;index"`
+	Sensitivity     string       `json:"sensitivity,omitempty"`
+	DisclosedFields string       `json:"disclosed_fields,omitempty" gorm:"type:text"`
+	Recipient       string       `json:"recipient,omitempty" gorm:"size:255"`
+	Purpose         PurposeOfUse `json:"purpose,omitempty" gorm:"size:32;index"`
+	Reason          string       `json:"reason,omitempty" gorm:"type:text"`
+	Success         bool         `json:"success" gorm:"default:true;index"`
+	ErrorMessage    string       `json:"error_message,omitempty"`
+	Timestamp       time.Time    `json:"timestamp" gorm:"autoCreateTime;index"`
+
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	// No foreign key: audit rows must outlive purged patients
+	Patient *Patient `json:"patient,omitempty" gorm:"foreignKey:PatientID;constraint:-"`
 }
 
 func (al *AuditLog) BeforeCreate(tx *gorm.DB) (err error) {
@@ -56,19 +75,25 @@ func (al *AuditLog) IsEmergencyAccess() bool {
 	return al.EmergencyUse || al.Action == ActionEmergencyAccess || al.Action == ActionEmergencyRequest
 }
 
+// IsSensitiveAccess reports whether the entry touched a record with a privacy
+// classification other than normal.
+func (al *AuditLog) IsSensitiveAccess() bool {
+	return al.SensitiveAccess
+}
+
 func (al *AuditLog) IsPatientDataAccess() bool {
 	return al.PatientID != nil && (al.Action == ActionView || al.Action == ActionUpdate)
 }
 
 func (al *AuditLog) RequiresNotification() bool {
-	return al.IsSecurityEvent() || (al.IsEmergencyAccess() && al.Success)
+	return al.IsSecurityEvent() || (al.IsEmergencyAccess() && al.Success) || al.IsSensitiveAccess()
 }
 
 func (al *AuditLog) GetSeverity() string {
 	if !al.Success {
 		return "HIGH"
 	}
-	if al.IsEmergencyAccess() {
+	if al.IsEmergencyAccess() || al.IsSensitiveAccess() {
 		return "MEDIUM"
 	}
 	if al.Action == ActionUnauthorized {
@@ -77,6 +102,35 @@ func (al *AuditLog) GetSeverity() string {
 	return "LOW"
 }
 
+// DisclosureCategory reports whether the entry belongs in the patient's
+// accounting of disclosures, and under which category. Only successful reads
+// and exports count. Disclosures to the patient or their representative are
+// exempt, and treatment, payment and operations are only listed when
+// includeTPO is set. Break-glass access, exports and research or legal
+// disclosures are always listed, whatever purpose was declared.
+func (al *AuditLog) DisclosureCategory(includeTPO bool) (DisclosureCategory, bool) {
+	if al.PatientID == nil || !al.Success || al.Purpose == PurposePatientRequest {
+		return "", false
+	}
+	switch al.Action {
+	case ActionView, ActionDownload, ActionExport, ActionEmergencyAccess:
+	default:
+		return "", false
+	}
+
+	switch {
+	case al.EmergencyUse || al.Action == ActionEmergencyAccess:
+		return DisclosureEmergency, true
+	case al.Action == ActionDownload || al.Action == ActionExport:
+		return DisclosureExport, true
+	case al.Purpose == PurposeResearch || al.Purpose == PurposeLegal:
+		return DisclosureExternal, true
+	case includeTPO:
+		return DisclosureRoutineTPO, true
+	}
+	return "", false
+}
+
 func (al *AuditLog) TableName() string {
 	return "audit_logs"
 }
@@ -84,12 +138,19 @@ func (al *AuditLog) TableName() string {
 type AuditLogFilter struct {
 	UserID      *uint
 	PatientID   *uint
+	// PatientIDs matches any of several charts, such as a chart and the
+	// charts merged into it
+	PatientIDs  []uint
 	Action      *AuditAction
 	Success     *bool
 	Emergency   *bool
+	Sensitive   *bool
+	Purpose     *PurposeOfUse
 	StartTime   *time.Time
 	EndTime     *time.Time
 	IPAddress   string
+	ID          *uint
+	Before      *time.Time
 	Limit       int
 	Offset      int
 }
@@ -103,6 +164,9 @@ func (f *AuditLogFilter) Apply(db *gorm.DB) *gorm.DB {
 	if f.PatientID != nil {
 		query = query.Where("patient_id = ?", *f.PatientID)
 	}
+	if len(f.PatientIDs) > 0 {
+		query = query.Where("patient_id IN ?", f.PatientIDs)
+	}
 	if f.Action != nil {
 		query = query.Where("action = ?", *f.Action)
 	}
@@ -112,6 +176,12 @@ func (f *AuditLogFilter) Apply(db *gorm.DB) *gorm.DB {
 	if f.Emergency != nil {
 		query = query.Where("emergency_use = ?", *f.Emergency)
 	}
+	if f.Sensitive != nil {
+		query = query.Where("sensitive_access = ?", *f.Sensitive)
+	}
+	if f.Purpose != nil {
+		query = query.Where("purpose = ?", *f.Purpose)
+	}
 	if f.StartTime != nil {
 		query = query.Where("timestamp >= ?", *f.StartTime)
 	}
@@ -121,6 +191,12 @@ func (f *AuditLogFilter) Apply(db *gorm.DB) *gorm.DB {
 	if f.IPAddress != "" {
 		query = query.Where("ip_address = ?", f.IPAddress)
 	}
+	if f.ID != nil {
+		query = query.Where("id = ?", *f.ID)
+	}
+	if f.Before != nil {
+		query = query.Where("timestamp < ?", *f.Before)
+	}
 
 	query = query.Order("timestamp DESC")
 
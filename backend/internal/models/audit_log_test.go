@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogDisclosureCategory(t *testing.T) {
+	patientID := uint(3)
+	entry := func(action AuditAction, purpose PurposeOfUse, emergency bool) *AuditLog {
+		return &AuditLog{PatientID: &patientID, Action: action, Purpose: purpose, EmergencyUse: emergency, Success: true}
+	}
+
+	t.Run("RoutineAccessOnlyWhenTPOIncluded", func(t *testing.T) {
+		_, listed := entry(ActionView, PurposeTreatment, false).DisclosureCategory(false)
+		assert.False(t, listed)
+
+		category, listed := entry(ActionView, PurposePayment, false).DisclosureCategory(true)
+		assert.True(t, listed)
+		assert.Equal(t, DisclosureRoutineTPO, category)
+	})
+
+	t.Run("BreakGlassExportsAndExternalAlwaysListed", func(t *testing.T) {
+		category, _ := entry(ActionView, PurposeTreatment, true).DisclosureCategory(false)
+		assert.Equal(t, DisclosureEmergency, category)
+
+		category, _ = entry(ActionExport, PurposeTreatment, false).DisclosureCategory(false)
+		assert.Equal(t, DisclosureExport, category)
+
+		category, _ = entry(ActionView, PurposeResearch, false).DisclosureCategory(false)
+		assert.Equal(t, DisclosureExternal, category)
+	})
+
+	t.Run("ExemptOrNotADisclosure", func(t *testing.T) {
+		_, listed := entry(ActionView, PurposePatientRequest, false).DisclosureCategory(true)
+		assert.False(t, listed, "disclosures to the patient are exempt")
+
+		_, listed = entry(ActionUpdate, PurposeLegal, false).DisclosureCategory(true)
+		assert.False(t, listed, "writes disclose nothing")
+
+		failed := entry(ActionView, PurposeLegal, false)
+		failed.Success = false
+		_, listed = failed.DisclosureCategory(true)
+		assert.False(t, listed)
+	})
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CareTeamRelationship string
+
+const (
+	CareTeamAttending    CareTeamRelationship = "attending"
+	CareTeamPrimaryNurse CareTeamRelationship = "primary_nurse"
+	CareTeamConsultant   CareTeamRelationship = "consultant"
+	CareTeamMemberRole   CareTeamRelationship = "member"
+)
+
+func IsValidCareTeamRelationship(relationship CareTeamRelationship) bool {
+	switch relationship {
+	case CareTeamAttending, CareTeamPrimaryNurse, CareTeamConsultant, CareTeamMemberRole:
+		return true
+	}
+	return false
+}
+
+// CareTeamMember assigns a clinician to a patient's treating team. Members
+// see confidential charts unmasked and without stating a reason.
+type CareTeamMember struct {
+	ID           uint                 `json:"id" gorm:"primaryKey"`
+	PatientID    uint                 `json:"patient_id" gorm:"not null;uniqueIndex:idx_care_team_patient_user"`
+	UserID       uint                 `json:"user_id" gorm:"not null;uniqueIndex:idx_care_team_patient_user;index"`
+	Relationship CareTeamRelationship `json:"relationship" gorm:"not null;default:'member'"`
+	AddedBy      uint                 `json:"added_by" gorm:"not null"`
+	EndedAt      *time.Time           `json:"ended_at,omitempty" gorm:"index"`
+	CreatedAt    time.Time            `json:"created_at"`
+
+	Patient Patient `json:"patient,omitempty" gorm:"foreignKey:PatientID"`
+	User    User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+func (m *CareTeamMember) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	if m.Relationship == "" {
+		m.Relationship = CareTeamMemberRole
+	}
+	return
+}
+
+func (m *CareTeamMember) IsActive() bool {
+	return m.EndedAt == nil
+}
+
+func (m *CareTeamMember) End() error {
+	if !m.IsActive() {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	m.EndedAt = &now
+	return nil
+}
+
+func (m *CareTeamMember) TableName() string {
+	return "care_team_members"
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMedicationOrder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("ActiveBetweenStartAndStop", func(t *testing.T) {
+		stop := start.Add(30 * 24 * time.Hour)
+		order := &MedicationOrder{Drug: "Amoxicillin", Route: RouteOral, StartDate: start, StopDate: &stop}
+		order.BeforeCreate(nil)
+
+		assert.Equal(t, MedicationStatusActive, order.Status)
+		assert.False(t, order.IsActive(start.Add(-time.Hour)))
+		assert.True(t, order.IsActive(start.Add(time.Hour)))
+		assert.False(t, order.IsActive(stop))
+	})
+
+	t.Run("Discontinue", func(t *testing.T) {
+		order := &MedicationOrder{Drug: "Lisinopril", Route: RouteOral, StartDate: start}
+		order.BeforeCreate(nil)
+		at := start.Add(10 * 24 * time.Hour)
+
+		assert.NoError(t, order.Discontinue(at))
+		assert.Equal(t, MedicationStatusDiscontinued, order.Status)
+		assert.Equal(t, at, *order.StopDate)
+		assert.False(t, order.IsActive(start.Add(time.Hour)))
+		assert.Error(t, order.Discontinue(at))
+	})
+
+	t.Run("StartDefaultsToCreation", func(t *testing.T) {
+		order := &MedicationOrder{Drug: "Metformin", Route: RouteOral}
+		order.BeforeCreate(nil)
+
+		assert.Equal(t, order.CreatedAt, order.StartDate)
+	})
+
+	t.Run("ValidRoutes", func(t *testing.T) {
+		assert.True(t, IsValidMedicationRoute(RouteIntravenous))
+		assert.False(t, IsValidMedicationRoute("by mouth"))
+	})
+}
+
+func TestAllergy(t *testing.T) {
+	allergy := &Allergy{Substance: "Penicillin", Severity: AllergySeveritySevere}
+	allergy.BeforeCreate(nil)
+
+	assert.True(t, allergy.IsActive())
+	assert.True(t, IsValidAllergySeverity(AllergySeverityLifeThreatening))
+	assert.False(t, IsValidAllergySeverity("fatal"))
+}
+
+func TestProblemStatus(t *testing.T) {
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	problem := &Problem{Condition: "Community-acquired pneumonia"}
+	problem.BeforeCreate(nil)
+	assert.Equal(t, ProblemStatusActive, problem.Status)
+
+	assert.NoError(t, problem.SetStatus(ProblemStatusResolved, at))
+	assert.Equal(t, at, *problem.ResolvedDate)
+
+	assert.NoError(t, problem.SetStatus(ProblemStatusResolved, at.Add(time.Hour)))
+	assert.Equal(t, at, *problem.ResolvedDate)
+
+	assert.NoError(t, problem.SetStatus(ProblemStatusActive, at))
+	assert.Nil(t, problem.ResolvedDate)
+
+	assert.Error(t, problem.SetStatus("chronic", at))
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PatientConsent records a patient's written consent to disclose records of a
+// sensitive category. A nil GranteeUserID covers every clinician treating the
+// patient; otherwise only the named user is covered.
+type PatientConsent struct {
+	ID            uint             `json:"id" gorm:"primaryKey"`
+	PatientID     uint             `json:"patient_id" gorm:"not null;index"`
+	Category      SensitivityLevel `json:"category" gorm:"not null;index"`
+	GranteeUserID *uint            `json:"grantee_user_id,omitempty" gorm:"index"`
+	Purpose       string           `json:"purpose" gorm:"type:text;not null"`
+	// PatientAttestation references the patient's signed consent form
+	PatientAttestation string     `json:"patient_attestation,omitempty" gorm:"type:text"`
+	RecordedBy         uint       `json:"recorded_by" gorm:"not null"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy          *uint      `json:"revoked_by,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+
+	Patient     Patient `json:"patient,omitempty" gorm:"foreignKey:PatientID"`
+	GranteeUser *User   `json:"grantee_user,omitempty" gorm:"foreignKey:GranteeUserID"`
+}
+
+func (pc *PatientConsent) BeforeCreate(tx *gorm.DB) (err error) {
+	if pc.CreatedAt.IsZero() {
+		pc.CreatedAt = time.Now()
+	}
+	return
+}
+
+func (pc *PatientConsent) IsRevoked() bool {
+	return pc.RevokedAt != nil
+}
+
+func (pc *PatientConsent) IsExpired() bool {
+	return pc.ExpiresAt != nil && pc.ExpiresAt.Before(time.Now())
+}
+
+func (pc *PatientConsent) IsActive() bool {
+	return !pc.IsRevoked() && !pc.IsExpired()
+}
+
+// Covers reports whether the consent authorises disclosure to the given user.
+func (pc *PatientConsent) Covers(userID uint) bool {
+	return pc.IsActive() && (pc.GranteeUserID == nil || *pc.GranteeUserID == userID)
+}
+
+func (pc *PatientConsent) Revoke(revokedByUserID uint) error {
+	if pc.IsRevoked() {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	pc.RevokedAt = &now
+	pc.RevokedBy = &revokedByUserID
+	return nil
+}
+
+func (pc *PatientConsent) TableName() string {
+	return "patient_consents"
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DelegationStatus string
+
+const (
+	DelegationStatusPending  DelegationStatus = "pending"
+	DelegationStatusActive   DelegationStatus = "active"
+	DelegationStatusRejected DelegationStatus = "rejected"
+	DelegationStatusRevoked  DelegationStatus = "revoked"
+	DelegationStatusExpired  DelegationStatus = "expired"
+)
+
+// AccessDelegation lends a doctor's patient access to a covering colleague
+// for a fixed window. A grant without listed patients covers the delegator's
+// whole care-team panel. Grants created while approval is required stay
+// pending until an admin reviews them.
+type AccessDelegation struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	DelegatorID uint             `json:"delegator_id" gorm:"not null;index"`
+	DelegateID  uint             `json:"delegate_id" gorm:"not null;index"`
+	Reason      string           `json:"reason" gorm:"type:text;not null"`
+	Status      DelegationStatus `json:"status" gorm:"default:'pending';index"`
+	StartsAt    time.Time        `json:"starts_at" gorm:"not null"`
+	ExpiresAt   time.Time        `json:"expires_at" gorm:"not null;index"`
+	ReviewedBy  *uint            `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time       `json:"reviewed_at,omitempty"`
+	RevokedBy   *uint            `json:"revoked_by,omitempty"`
+	RevokedAt   *time.Time       `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+
+	Patients  []AccessDelegationPatient `json:"patients,omitempty" gorm:"foreignKey:DelegationID"`
+	Delegator User                      `json:"delegator,omitempty" gorm:"foreignKey:DelegatorID"`
+	Delegate  User                      `json:"delegate,omitempty" gorm:"foreignKey:DelegateID"`
+}
+
+// AccessDelegationPatient narrows a grant to a single patient of the
+// delegator's panel.
+type AccessDelegationPatient struct {
+	ID           uint `json:"id" gorm:"primaryKey"`
+	DelegationID uint `json:"delegation_id" gorm:"not null;index"`
+	PatientID    uint `json:"patient_id" gorm:"not null;index"`
+}
+
+func (ad *AccessDelegation) BeforeCreate(tx *gorm.DB) (err error) {
+	if ad.CreatedAt.IsZero() {
+		ad.CreatedAt = time.Now()
+	}
+	if ad.StartsAt.IsZero() {
+		ad.StartsAt = ad.CreatedAt
+	}
+	if ad.Status == "" {
+		ad.Status = DelegationStatusPending
+	}
+	return
+}
+
+func (ad *AccessDelegation) IsPending() bool {
+	return ad.Status == DelegationStatusPending
+}
+
+func (ad *AccessDelegation) IsExpired() bool {
+	return ad.ExpiresAt.Before(time.Now())
+}
+
+// IsActive reports whether the grant is approved and inside its window.
+func (ad *AccessDelegation) IsActive() bool {
+	now := time.Now()
+	return ad.Status == DelegationStatusActive &&
+		!ad.StartsAt.After(now) &&
+		ad.ExpiresAt.After(now)
+}
+
+// IsPanelWide reports whether the grant covers every patient on the
+// delegator's panel rather than a listed subset.
+func (ad *AccessDelegation) IsPanelWide() bool {
+	return len(ad.Patients) == 0
+}
+
+func (ad *AccessDelegation) ListsPatient(patientID uint) bool {
+	for _, patient := range ad.Patients {
+		if patient.PatientID == patientID {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditReason identifies the grant in audit entries for access made through it.
+func (ad *AccessDelegation) AuditReason() string {
+	return fmt.Sprintf("delegated_access:grant_%d", ad.ID)
+}
+
+func (ad *AccessDelegation) Approve(reviewedByUserID uint) error {
+	if !ad.IsPending() || ad.IsExpired() {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	ad.Status = DelegationStatusActive
+	ad.ReviewedBy = &reviewedByUserID
+	ad.ReviewedAt = &now
+	return nil
+}
+
+func (ad *AccessDelegation) Reject(reviewedByUserID uint) error {
+	if !ad.IsPending() {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	ad.Status = DelegationStatusRejected
+	ad.ReviewedBy = &reviewedByUserID
+	ad.ReviewedAt = &now
+	return nil
+}
+
+func (ad *AccessDelegation) Revoke(revokedByUserID uint) error {
+	if ad.Status != DelegationStatusPending && ad.Status != DelegationStatusActive {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	ad.Status = DelegationStatusRevoked
+	ad.RevokedBy = &revokedByUserID
+	ad.RevokedAt = &now
+	return nil
+}
+
+func (ad *AccessDelegation) TableName() string {
+	return "access_delegations"
+}
+
+func (adp *AccessDelegationPatient) TableName() string {
+	return "access_delegation_patients"
+}
+
+type AccessDelegationFilter struct {
+	DelegatorID *uint
+	DelegateID  *uint
+	Status      *DelegationStatus
+	Limit       int
+	Offset      int
+}
+
+func (f *AccessDelegationFilter) Apply(db *gorm.DB) *gorm.DB {
+	query := db
+
+	if f.DelegatorID != nil {
+		query = query.Where("delegator_id = ?", *f.DelegatorID)
+	}
+	if f.DelegateID != nil {
+		query = query.Where("delegate_id = ?", *f.DelegateID)
+	}
+	if f.Status != nil {
+		query = query.Where("status = ?", *f.Status)
+	}
+
+	query = query.Order("created_at DESC")
+
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		query = query.Offset(f.Offset)
+	}
+
+	return query
+}
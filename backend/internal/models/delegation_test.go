@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessDelegation(t *testing.T) {
+	t.Run("ActiveOnlyInsideApprovedWindow", func(t *testing.T) {
+		now := time.Now()
+		grant := &AccessDelegation{
+			Status:    DelegationStatusActive,
+			StartsAt:  now.Add(-time.Hour),
+			ExpiresAt: now.Add(time.Hour),
+		}
+		assert.True(t, grant.IsActive())
+
+		future := &AccessDelegation{
+			Status:    DelegationStatusActive,
+			StartsAt:  now.Add(time.Hour),
+			ExpiresAt: now.Add(2 * time.Hour),
+		}
+		assert.False(t, future.IsActive())
+
+		pending := &AccessDelegation{
+			Status:    DelegationStatusPending,
+			StartsAt:  now.Add(-time.Hour),
+			ExpiresAt: now.Add(time.Hour),
+		}
+		assert.False(t, pending.IsActive())
+	})
+
+	t.Run("PanelWideUnlessPatientsListed", func(t *testing.T) {
+		panel := &AccessDelegation{}
+		assert.True(t, panel.IsPanelWide())
+
+		scoped := &AccessDelegation{Patients: []AccessDelegationPatient{{PatientID: 3}}}
+		assert.False(t, scoped.IsPanelWide())
+		assert.True(t, scoped.ListsPatient(3))
+		assert.False(t, scoped.ListsPatient(4))
+	})
+
+	t.Run("ReviewLifecycle", func(t *testing.T) {
+		grant := &AccessDelegation{Status: DelegationStatusPending, ExpiresAt: time.Now().Add(time.Hour)}
+		assert.NoError(t, grant.Approve(1))
+		assert.Equal(t, DelegationStatusActive, grant.Status)
+		assert.Error(t, grant.Reject(1))
+
+		assert.NoError(t, grant.Revoke(2))
+		assert.Equal(t, DelegationStatusRevoked, grant.Status)
+		assert.Error(t, grant.Revoke(2))
+
+		stale := &AccessDelegation{Status: DelegationStatusPending, ExpiresAt: time.Now().Add(-time.Minute)}
+		assert.Error(t, stale.Approve(1))
+	})
+}
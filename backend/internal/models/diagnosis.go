@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CodeSystem names a terminology that diagnoses may be coded in
+type CodeSystem string
+
+const (
+	CodeSystemICD10CM CodeSystem = "icd10cm"
+	CodeSystemSNOMED  CodeSystem = "snomed"
+)
+
+func IsValidCodeSystem(system CodeSystem) bool {
+	switch system {
+	case CodeSystemICD10CM, CodeSystemSNOMED:
+		return true
+	}
+	return false
+}
+
+// Name is the code system's published name, for display in exported documents
+func (s CodeSystem) Name() string {
+	switch s {
+	case CodeSystemICD10CM:
+		return "ICD-10-CM"
+	case CodeSystemSNOMED:
+		return "SNOMED CT"
+	}
+	return string(s)
+}
+
+// RecordDiagnosis is one coded diagnosis on a medical record, kept alongside
+// the narrative diagnosis. Codes are stored in clear so that reports can
+// group by them; the narrative stays encrypted. Only the coded content is
+// serialized, so that revisions and sign-off hashes do not change when the
+// set is rewritten unchanged.
+type RecordDiagnosis struct {
+	ID        uint       `json:"-" gorm:"primaryKey"`
+	RecordID  uint       `json:"-" gorm:"not null;index"`
+	PatientID uint       `json:"-" gorm:"not null;index"`
+	System    CodeSystem `json:"system" gorm:"column:code_system;type:enum('icd10cm','snomed');not null;index:idx_diagnosis_code,priority:1"`
+	Code      string     `json:"code" gorm:"size:20;not null;index:idx_diagnosis_code,priority:2"`
+	Display   string     `json:"display" gorm:"size:255;not null"`
+	Primary   bool       `json:"primary" gorm:"column:is_primary;default:false"`
+	Position  int        `json:"-" gorm:"not null;default:0"`
+	CreatedAt time.Time  `json:"-"`
+}
+
+func (d *RecordDiagnosis) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	return
+}
+
+func (d *RecordDiagnosis) TableName() string {
+	return "record_diagnoses"
+}
+
+// PreloadDiagnoses loads a record's coded diagnoses in the order they were
+// entered
+func PreloadDiagnoses(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
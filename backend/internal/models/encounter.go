@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EncounterClass string
+
+const (
+	EncounterOutpatient EncounterClass = "outpatient"
+	EncounterInpatient  EncounterClass = "inpatient"
+	EncounterEmergency  EncounterClass = "emergency"
+)
+
+func IsValidEncounterClass(class EncounterClass) bool {
+	switch class {
+	case EncounterOutpatient, EncounterInpatient, EncounterEmergency:
+		return true
+	}
+	return false
+}
+
+type EncounterStatus string
+
+const (
+	EncounterActive     EncounterStatus = "active"
+	EncounterDischarged EncounterStatus = "discharged"
+)
+
+// Encounter is a visit, admission or ED stay. Medical records, medication
+// orders and observations are filed under the encounter they belong to.
+// Active inpatient encounters make up the census of their department.
+type Encounter struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	PatientID   uint            `json:"patient_id" gorm:"not null;index"`
+	Class       EncounterClass  `json:"class" gorm:"type:enum('outpatient','inpatient','emergency');not null"`
+	Status      EncounterStatus `json:"status" gorm:"type:enum('active','discharged');default:'active';index:idx_encounter_census,priority:1"`
+	AdmitAt     time.Time       `json:"admit_at" gorm:"not null;index"`
+	DischargeAt *time.Time      `json:"discharge_at,omitempty"`
+	Facility    string          `json:"facility" gorm:"size:100;not null;index:idx_encounter_census,priority:2"`
+	Department  string          `json:"department" gorm:"size:100;not null;index:idx_encounter_census,priority:3"`
+	Location    string          `json:"location,omitempty" gorm:"size:50"`
+	AttendingID uint            `json:"attending_id" gorm:"not null;index"`
+	Reason      string          `json:"reason,omitempty" gorm:"type:text;serializer:encrypted"`
+	CreatedBy   uint            `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+
+	Attending User `json:"attending,omitempty" gorm:"foreignKey:AttendingID"`
+}
+
+func (e *Encounter) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if e.UpdatedAt.IsZero() {
+		e.UpdatedAt = time.Now()
+	}
+	if e.AdmitAt.IsZero() {
+		e.AdmitAt = e.CreatedAt
+	}
+	if e.Status == "" {
+		e.Status = EncounterActive
+	}
+	return
+}
+
+func (e *Encounter) BeforeUpdate(tx *gorm.DB) (err error) {
+	e.UpdatedAt = time.Now()
+	return
+}
+
+func (e *Encounter) IsActive() bool {
+	return e.Status == EncounterActive
+}
+
+// Discharge ends the encounter at the given time, which may not precede the
+// admission
+func (e *Encounter) Discharge(at time.Time) error {
+	if !e.IsActive() || at.Before(e.AdmitAt) {
+		return gorm.ErrInvalidValue
+	}
+
+	e.Status = EncounterDischarged
+	e.DischargeAt = &at
+	return nil
+}
+
+// LengthOfStay is the time from admission to discharge, or to now while the
+// encounter is active
+func (e *Encounter) LengthOfStay(now time.Time) time.Duration {
+	if e.DischargeAt != nil {
+		return e.DischargeAt.Sub(e.AdmitAt)
+	}
+	return now.Sub(e.AdmitAt)
+}
+
+func (e *Encounter) TableName() string {
+	return "encounters"
+}
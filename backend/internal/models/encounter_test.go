@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncounterLifecycle(t *testing.T) {
+	admit := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+
+	t.Run("Defaults", func(t *testing.T) {
+		encounter := &Encounter{Class: EncounterInpatient}
+		encounter.BeforeCreate(nil)
+
+		assert.True(t, encounter.IsActive())
+		assert.Equal(t, encounter.CreatedAt, encounter.AdmitAt)
+	})
+
+	t.Run("Discharge", func(t *testing.T) {
+		encounter := &Encounter{Class: EncounterInpatient, AdmitAt: admit}
+		encounter.BeforeCreate(nil)
+
+		assert.Equal(t, 26*time.Hour, encounter.LengthOfStay(admit.Add(26*time.Hour)))
+
+		discharge := admit.Add(72 * time.Hour)
+		assert.NoError(t, encounter.Discharge(discharge))
+		assert.Equal(t, EncounterDischarged, encounter.Status)
+		assert.Equal(t, 72*time.Hour, encounter.LengthOfStay(discharge.Add(time.Hour)))
+
+		assert.Error(t, encounter.Discharge(discharge), "discharged twice")
+	})
+
+	t.Run("DischargeBeforeAdmission", func(t *testing.T) {
+		encounter := &Encounter{Class: EncounterEmergency, AdmitAt: admit}
+		encounter.BeforeCreate(nil)
+
+		assert.Error(t, encounter.Discharge(admit.Add(-time.Minute)))
+		assert.True(t, encounter.IsActive())
+	})
+}
+
+func TestEncounterClass(t *testing.T) {
+	assert.True(t, IsValidEncounterClass(EncounterOutpatient))
+	assert.True(t, IsValidEncounterClass(EncounterEmergency))
+	assert.False(t, IsValidEncounterClass("ambulatory"))
+}
@@ -0,0 +1,343 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"unicode"
+)
+
+// FieldRule controls how a single field is disclosed to a role.
+type FieldRule string
+
+const (
+	FieldVisible FieldRule = "visible"
+	FieldLast4   FieldRule = "last4"
+)
+
+const (
+	ResourcePatient       = "patient"
+	ResourceMedicalRecord = "medical_record"
+	ResourceUser          = "user"
+	ResourceMedication    = "medication"
+	ResourceAllergy       = "allergy"
+	ResourceProblem       = "problem"
+	ResourceObservation   = "observation"
+	ResourceEncounter     = "encounter"
+	ResourceAttachment    = "attachment"
+)
+
+// Projection is a model reduced to the fields a role may see, keyed by JSON
+// field name.
+type Projection map[string]interface{}
+
+// FieldPolicy lists, per resource and role, the JSON fields that may be
+// disclosed. Fields missing from a role's list are never returned and roles
+// missing from a resource see nothing of it.
+type FieldPolicy map[string]map[UserRole]map[string]FieldRule
+
+// nestedResources maps relation fields to the resource whose rules project them
+var nestedResources = map[string]string{
+	"medical_records": ResourceMedicalRecord,
+	"patient":         ResourcePatient,
+	"doctor":          ResourceUser,
+	"prescriber":      ResourceUser,
+	"recorder":        ResourceUser,
+	"attending":       ResourceUser,
+	"uploader":        ResourceUser,
+}
+
+// DefaultFieldPolicy is the minimum-necessary baseline. Front desk staff see
+// demographics only, billing sees coded clinical fields and the problem list
+// without narrative notes or medications, and only doctors see the full SSN.
+// Patients see their own chart as a patient would read it, without internal
+// flags, sign-off bookkeeping or staff comments.
+func DefaultFieldPolicy() FieldPolicy {
+	demographics := []string{"id", "public_id", "first_name", "last_name", "date_of_birth", "phone",
+		"address", "emergency_contact", "confidential", "identifiers", "created_at", "updated_at"}
+	clinical := []string{"id", "patient_id", "doctor_id", "encounter_id", "diagnosis", "diagnoses", "treatment", "notes",
+		"template_id", "sections", "medications", "severity", "sensitivity", "created_at", "updated_at", "patient", "doctor"}
+	coded := []string{"id", "patient_id", "doctor_id", "encounter_id", "diagnosis", "diagnoses", "template_id", "severity",
+		"created_at", "updated_at", "doctor"}
+	signature := []string{"status", "signed_by", "signed_at", "content_hash",
+		"cosigner_id", "cosigned_by", "cosigned_at"}
+	staff := []string{"id", "name", "role", "resident"}
+	medications := []string{"id", "patient_id", "encounter_id", "drug", "dose", "route", "frequency", "start_date",
+		"stop_date", "status", "prescriber_id", "created_at", "updated_at", "prescriber"}
+	allergies := []string{"id", "patient_id", "substance", "reaction", "severity", "status",
+		"recorded_by", "created_at", "updated_at"}
+	problems := []string{"id", "patient_id", "condition", "onset_date", "status", "resolved_date",
+		"recorded_by", "created_at", "updated_at"}
+	codedProblems := []string{"id", "patient_id", "condition", "onset_date", "status", "resolved_date"}
+	observations := []string{"id", "patient_id", "encounter_id", "category", "code", "display",
+		"value_quantity", "value_string", "unit", "reference_low", "reference_high", "critical_low",
+		"critical_high", "interpretation", "effective_at", "comment", "recorded_by",
+		"ordering_doctor_id", "created_at", "updated_at", "recorder"}
+	visits := []string{"id", "patient_id", "class", "status", "admit_at", "discharge_at", "facility",
+		"department", "location", "attending_id", "created_by", "created_at", "updated_at", "attending"}
+	documents := []string{"id", "patient_id", "record_id", "category", "filename", "content_type", "size",
+		"sha256", "uploaded_by", "created_at", "uploader"}
+
+	selfDemographics := []string{"id", "public_id", "first_name", "last_name", "date_of_birth", "phone",
+		"address", "emergency_contact", "identifiers", "created_at", "updated_at"}
+	selfRecords := []string{"id", "encounter_id", "diagnosis", "diagnoses", "treatment", "notes", "template_id",
+		"sections", "medications", "status", "signed_at", "created_at", "updated_at", "doctor"}
+	selfMedications := []string{"id", "drug", "dose", "route", "frequency", "start_date", "stop_date", "status",
+		"created_at", "prescriber"}
+	selfResults := []string{"id", "encounter_id", "category", "code", "display", "value_quantity", "value_string",
+		"unit", "reference_low", "reference_high", "interpretation", "effective_at"}
+
+	nursePatient := fieldRules(demographics, "ssn", "medical_records")
+	nursePatient["ssn"] = FieldLast4
+	billingPatient := fieldRules(demographics, "ssn", "medical_records")
+	billingPatient["ssn"] = FieldLast4
+	selfPatient := fieldRules(selfDemographics, "ssn")
+	selfPatient["ssn"] = FieldLast4
+
+	return FieldPolicy{
+		ResourcePatient: {
+			RoleDoctor:    fieldRules(demographics, "ssn", "employee_user_id", "medical_records"),
+			RoleNurse:     nursePatient,
+			RoleBilling:   billingPatient,
+			RoleFrontDesk: fieldRules(demographics),
+			RolePatient:   selfPatient,
+		},
+		ResourceMedicalRecord: {
+			RoleDoctor:  fieldRules(clinical, signature...),
+			RoleNurse:   fieldRules(clinical, signature...),
+			RoleBilling: fieldRules(coded, signature...),
+			RolePatient: fieldRules(selfRecords),
+		},
+		ResourceMedication: {
+			RoleDoctor:  fieldRules(medications),
+			RoleNurse:   fieldRules(medications),
+			RolePatient: fieldRules(selfMedications),
+		},
+		ResourceAllergy: {
+			RoleDoctor: fieldRules(allergies),
+			RoleNurse:  fieldRules(allergies),
+		},
+		ResourceProblem: {
+			RoleDoctor:  fieldRules(problems),
+			RoleNurse:   fieldRules(problems),
+			RoleBilling: fieldRules(codedProblems),
+		},
+		ResourceObservation: {
+			RoleDoctor:  fieldRules(observations),
+			RoleNurse:   fieldRules(observations),
+			RolePatient: fieldRules(selfResults),
+		},
+		ResourceEncounter: {
+			RoleDoctor:    fieldRules(visits, "reason"),
+			RoleNurse:     fieldRules(visits, "reason"),
+			RoleBilling:   fieldRules(visits),
+			RoleFrontDesk: fieldRules(visits),
+		},
+		ResourceAttachment: {
+			RoleDoctor: fieldRules(documents),
+			RoleNurse:  fieldRules(documents),
+		},
+		ResourceUser: {
+			RoleDoctor:    fieldRules(staff),
+			RoleNurse:     fieldRules(staff),
+			RoleBilling:   fieldRules(staff),
+			RoleFrontDesk: fieldRules(staff),
+			RolePatient:   fieldRules([]string{"name", "role"}),
+		},
+	}
+}
+
+func fieldRules(fields []string, extra ...string) map[string]FieldRule {
+	rules := make(map[string]FieldRule, len(fields)+len(extra))
+	for _, field := range fields {
+		rules[field] = FieldVisible
+	}
+	for _, field := range extra {
+		rules[field] = FieldVisible
+	}
+	return rules
+}
+
+// LoadFieldPolicy reads a JSON policy file and overlays it on the defaults.
+// Each resource and role present in the file replaces the default rules for
+// that pair. An empty path returns the defaults.
+func LoadFieldPolicy(path string) (FieldPolicy, error) {
+	policy := DefaultFieldPolicy()
+	if path == "" {
+		return policy, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read field policy: %w", err)
+	}
+
+	var overrides FieldPolicy
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse field policy: %w", err)
+	}
+
+	for resource, roles := range overrides {
+		if policy[resource] == nil {
+			policy[resource] = make(map[UserRole]map[string]FieldRule)
+		}
+		for role, rules := range roles {
+			for field, rule := range rules {
+				if rule != FieldVisible && rule != FieldLast4 {
+					return nil, fmt.Errorf("invalid rule %q for %s.%s", rule, resource, field)
+				}
+			}
+			policy[resource][role] = rules
+		}
+	}
+
+	return policy, nil
+}
+
+// Project renders value through the role's rules for resource. When requested
+// is non-empty only those top-level fields are kept; the id is always kept if
+// the role may see it. It also returns the sorted list of fields that were
+// disclosed with a value, nested fields prefixed by their relation name.
+func (fp FieldPolicy) Project(resource string, role UserRole, value interface{}, requested []string) (Projection, []string) {
+	raw, err := toFieldMap(value)
+	if err != nil {
+		return Projection{}, nil
+	}
+
+	disclosed := make(map[string]bool)
+	projection := fp.project(resource, role, raw, requested, "", disclosed)
+
+	return projection, sortedFields(disclosed)
+}
+
+// Allows reports whether the role may see the field of resource in any form
+func (fp FieldPolicy) Allows(resource string, role UserRole, field string) bool {
+	_, allowed := fp[resource][role][field]
+	return allowed
+}
+
+// ProjectAll renders a slice of values and returns the union of disclosed fields.
+func (fp FieldPolicy) ProjectAll(resource string, role UserRole, values interface{}, requested []string) ([]Projection, []string) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, nil
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil
+	}
+
+	disclosed := make(map[string]bool)
+	projections := make([]Projection, 0, len(items))
+	for _, item := range items {
+		projections = append(projections, fp.project(resource, role, item, requested, "", disclosed))
+	}
+
+	return projections, sortedFields(disclosed)
+}
+
+func (fp FieldPolicy) project(resource string, role UserRole, raw map[string]interface{}, requested []string, prefix string, disclosed map[string]bool) Projection {
+	rules := fp[resource][role]
+
+	var wanted map[string]bool
+	if len(requested) > 0 {
+		wanted = make(map[string]bool, len(requested))
+		for _, field := range requested {
+			wanted[field] = true
+		}
+	}
+
+	projection := make(Projection)
+	for field, value := range raw {
+		rule, allowed := rules[field]
+		if !allowed {
+			continue
+		}
+		if wanted != nil && !wanted[field] && field != "id" {
+			continue
+		}
+
+		if nested, isNested := nestedResources[field]; isNested {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				projection[field] = fp.project(nested, role, v, nil, prefix+field+".", disclosed)
+			case []interface{}:
+				items := make([]Projection, 0, len(v))
+				for _, item := range v {
+					if m, ok := item.(map[string]interface{}); ok {
+						items = append(items, fp.project(nested, role, m, nil, prefix+field+".", disclosed))
+					}
+				}
+				projection[field] = items
+			}
+			continue
+		}
+
+		if s, ok := value.(string); ok && rule == FieldLast4 {
+			value = MaskLast4(s)
+		}
+		projection[field] = value
+
+		if !isEmptyFieldValue(value) {
+			disclosed[prefix+field] = true
+		}
+	}
+
+	return projection
+}
+
+// MaskLast4 replaces every letter and digit except the last four, keeping
+// separators so formats such as SSNs stay recognisable.
+func MaskLast4(value string) string {
+	runes := []rune(value)
+	kept := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			continue
+		}
+		if kept < 4 {
+			kept++
+			continue
+		}
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+func toFieldMap(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func isEmptyFieldValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == "" || v == "0001-01-01T00:00:00Z"
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	}
+	return false
+}
+
+func sortedFields(set map[string]bool) []string {
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
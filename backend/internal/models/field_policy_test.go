@@ -0,0 +1,102 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldPolicyProjection(t *testing.T) {
+	policy := DefaultFieldPolicy()
+	patient := &Patient{
+		ID:        3,
+		FirstName: "John",
+		LastName:  "Doe",
+		SSN:       "123-45-6789",
+		Phone:     "+1-555-0123",
+	}
+
+	t.Run("DoctorSeesFullSSN", func(t *testing.T) {
+		projection, _ := policy.Project(ResourcePatient, RoleDoctor, patient, nil)
+		assert.Equal(t, "123-45-6789", projection["ssn"])
+	})
+
+	t.Run("NurseSeesLast4", func(t *testing.T) {
+		projection, disclosed := policy.Project(ResourcePatient, RoleNurse, patient, nil)
+		assert.Equal(t, "***-**-6789", projection["ssn"])
+		assert.Contains(t, disclosed, "ssn")
+	})
+
+	t.Run("FrontDeskSeesNoSSN", func(t *testing.T) {
+		projection, disclosed := policy.Project(ResourcePatient, RoleFrontDesk, patient, nil)
+		assert.NotContains(t, projection, "ssn")
+		assert.NotContains(t, disclosed, "ssn")
+		assert.Equal(t, "John", projection["first_name"])
+	})
+
+	t.Run("RequestedFieldsKeepID", func(t *testing.T) {
+		projection, disclosed := policy.Project(ResourcePatient, RoleDoctor, patient, []string{"phone", "notes"})
+		assert.Len(t, projection, 2)
+		assert.Equal(t, "+1-555-0123", projection["phone"])
+		assert.Equal(t, []string{"id", "phone"}, disclosed)
+	})
+
+	t.Run("BillingRecordHasNoNotes", func(t *testing.T) {
+		record := &MedicalRecord{ID: 5, Diagnosis: "Hypertension", Notes: "Responding well", Doctor: User{ID: 2, Name: "Dr. Smith", Email: "smith@example.com"}}
+		projection, disclosed := policy.Project(ResourceMedicalRecord, RoleBilling, record, nil)
+
+		assert.Equal(t, "Hypertension", projection["diagnosis"])
+		assert.NotContains(t, projection, "notes")
+		assert.NotContains(t, projection["doctor"], "email")
+		assert.Contains(t, disclosed, "doctor.name")
+	})
+
+	t.Run("PatientRecordHasNoStaffBookkeeping", func(t *testing.T) {
+		record := &MedicalRecord{ID: 5, Diagnosis: "Hypertension", Severity: SeverityHigh, ContentHash: "abc", Doctor: User{ID: 2, Name: "Dr. Smith", Email: "smith@example.com", Role: RoleDoctor}}
+		projection, _ := policy.Project(ResourceMedicalRecord, RolePatient, record, nil)
+
+		assert.Equal(t, "Hypertension", projection["diagnosis"])
+		assert.NotContains(t, projection, "severity")
+		assert.NotContains(t, projection, "content_hash")
+		assert.Equal(t, Projection{"name": "Dr. Smith", "role": "doctor"}, projection["doctor"])
+
+		own, _ := policy.Project(ResourcePatient, RolePatient, &Patient{ID: 3, SSN: "123-45-6789", Confidential: true}, nil)
+		assert.Equal(t, "***-**-6789", own["ssn"])
+		assert.NotContains(t, own, "confidential")
+	})
+
+	t.Run("UnknownRoleSeesNothing", func(t *testing.T) {
+		projection, disclosed := policy.Project(ResourcePatient, RoleAdmin, patient, nil)
+		assert.Empty(t, projection)
+		assert.Empty(t, disclosed)
+	})
+}
+
+func TestLoadFieldPolicy(t *testing.T) {
+	t.Run("OverridesRole", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"patient":{"nurse":{"id":"visible","last_name":"visible"}}}`), 0600))
+
+		policy, err := LoadFieldPolicy(path)
+		require.NoError(t, err)
+		assert.Len(t, policy[ResourcePatient][RoleNurse], 2)
+		assert.Equal(t, DefaultFieldPolicy()[ResourcePatient][RoleDoctor], policy[ResourcePatient][RoleDoctor])
+	})
+
+	t.Run("RejectsUnknownRule", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"patient":{"nurse":{"ssn":"hidden"}}}`), 0600))
+
+		_, err := LoadFieldPolicy(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestMaskLast4(t *testing.T) {
+	assert.Equal(t, "***-**-6789", MaskLast4("123-45-6789"))
+	assert.Equal(t, "***-**-6789", MaskLast4(MaskLast4("123-45-6789")))
+	assert.Equal(t, "12", MaskLast4("12"))
+}
@@ -1,6 +1,12 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,20 +21,74 @@ const (
 	SeverityCritical SeverityLevel = "critical"
 )
 
+// SensitivityLevel is the privacy classification of a record. It is
+// independent of Severity, which only describes clinical urgency.
+type SensitivityLevel string
+
+const (
+	SensitivityNormal         SensitivityLevel = "normal"
+	SensitivityRestricted     SensitivityLevel = "restricted"
+	SensitivityVeryRestricted SensitivityLevel = "very_restricted"
+	SensitivitySubstanceUse   SensitivityLevel = "substance_use"
+	SensitivityMentalHealth   SensitivityLevel = "mental_health"
+	SensitivityReproductive   SensitivityLevel = "reproductive"
+)
+
+// RecordStatus tracks a record through sign-off. Only drafts may be edited.
+type RecordStatus string
+
+const (
+	RecordStatusDraft         RecordStatus = "draft"
+	RecordStatusPendingCosign RecordStatus = "pending_cosign"
+	RecordStatusSigned        RecordStatus = "signed"
+)
+
+// ErrRecordLocked is returned when a signed record would be changed
+var ErrRecordLocked = errors.New("medical record is signed and locked; add an addendum instead")
+
+// ErrRecordTampered is returned when a signed record no longer matches the
+// hash taken when it was signed
+var ErrRecordTampered = errors.New("medical record content does not match its signature")
+
+const sensitiveRecordPlaceholder = "[RESTRICTED - Sensitive Record]"
+
+func IsValidSensitivity(level SensitivityLevel) bool {
+	switch level {
+	case SensitivityNormal, SensitivityRestricted, SensitivityVeryRestricted,
+		SensitivitySubstanceUse, SensitivityMentalHealth, SensitivityReproductive:
+		return true
+	}
+	return false
+}
+
 type MedicalRecord struct {
-	ID          uint          `json:"id" gorm:"primaryKey"`
-	PatientID   uint          `json:"patient_id" gorm:"not null;index"`
-	DoctorID    uint          `json:"doctor_id" gorm:"not null;index"`
-	Diagnosis   string        `json:"diagnosis" gorm:"type:text"`
-	Treatment   string        `json:"treatment" gorm:"type:text"`
-	Notes       string        `json:"notes" gorm:"type:text"`
-	Medications string        `json:"medications" gorm:"type:text"`
-	Severity    SeverityLevel `json:"severity" gorm:"type:enum('low','medium','high','critical')"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	PatientID   uint             `json:"patient_id" gorm:"not null;index"`
+	DoctorID    uint             `json:"doctor_id" gorm:"not null;index"`
+	EncounterID *uint            `json:"encounter_id,omitempty" gorm:"index"`
+	Diagnosis   string           `json:"diagnosis" gorm:"type:text;serializer:encrypted"`
+	Treatment   string           `json:"treatment" gorm:"type:text;serializer:encrypted"`
+	Notes       string           `json:"notes" gorm:"type:text;serializer:encrypted"`
+	TemplateID  *uint            `json:"template_id,omitempty" gorm:"index"`
+	Sections    NoteContent      `json:"sections,omitempty" gorm:"type:text;serializer:encryptedjson"`
+	Medications string           `json:"medications" gorm:"type:text;serializer:encrypted"`
+	Severity    SeverityLevel    `json:"severity" gorm:"type:enum('low','medium','high','critical')"`
+	Sensitivity SensitivityLevel `json:"sensitivity" gorm:"type:enum('normal','restricted','very_restricted','substance_use','mental_health','reproductive');default:'normal';index"`
+	Status      RecordStatus     `json:"status" gorm:"type:enum('draft','pending_cosign','signed');default:'draft';index"`
+	SignedBy    *uint            `json:"signed_by,omitempty"`
+	SignedAt    *time.Time       `json:"signed_at,omitempty"`
+	ContentHash string           `json:"content_hash,omitempty" gorm:"size:64"`
+	CosignerID  *uint            `json:"cosigner_id,omitempty" gorm:"index"`
+	CosignedBy  *uint            `json:"cosigned_by,omitempty"`
+	CosignedAt  *time.Time       `json:"cosigned_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt   `json:"-" gorm:"index"`
 
-	Patient Patient `json:"patient,omitempty" gorm:"foreignKey:PatientID"`
-	Doctor  User    `json:"doctor,omitempty" gorm:"foreignKey:DoctorID"`
+	Diagnoses   []RecordDiagnosis `json:"diagnoses,omitempty" gorm:"foreignKey:RecordID"`
+	Attachments []Attachment      `json:"attachments,omitempty" gorm:"foreignKey:RecordID"`
+	Patient     Patient           `json:"patient,omitempty" gorm:"foreignKey:PatientID"`
+	Doctor      User              `json:"doctor,omitempty" gorm:"foreignKey:DoctorID"`
 }
 
 func (mr *MedicalRecord) BeforeCreate(tx *gorm.DB) (err error) {
@@ -38,6 +98,12 @@ func (mr *MedicalRecord) BeforeCreate(tx *gorm.DB) (err error) {
 	if mr.UpdatedAt.IsZero() {
 		mr.UpdatedAt = time.Now()
 	}
+	if mr.Sensitivity == "" {
+		mr.Sensitivity = SensitivityNormal
+	}
+	if mr.Status == "" {
+		mr.Status = RecordStatusDraft
+	}
 	return
 }
 
@@ -54,13 +120,200 @@ func (mr *MedicalRecord) IsHighSeverity() bool {
 	return mr.Severity == SeverityHigh || mr.Severity == SeverityCritical
 }
 
+// IsSensitive reports whether the record carries any privacy classification
+// beyond normal.
+func (mr *MedicalRecord) IsSensitive() bool {
+	return mr.Sensitivity != "" && mr.Sensitivity != SensitivityNormal
+}
+
+// RequiresConsent reports whether the patient must have consented before the
+// record is disclosed to anyone (42 CFR Part 2).
+func (mr *MedicalRecord) RequiresConsent() bool {
+	return mr.Sensitivity == SensitivitySubstanceUse
+}
+
+// IsWithheldFrom reports whether a very restricted record is hidden from a
+// doctor other than its author.
+func (mr *MedicalRecord) IsWithheldFrom(userID uint) bool {
+	return mr.Sensitivity == SensitivityVeryRestricted && mr.DoctorID != userID
+}
+
+// IsExcludedFromDefaultExport reports whether the record must be left out of
+// exports unless sensitive categories are requested explicitly.
+func (mr *MedicalRecord) IsExcludedFromDefaultExport() bool {
+	return mr.IsSensitive()
+}
+
+// Narrative renders the record's clinical text as plain text, one titled
+// paragraph per field, for exports that carry the note as a document.
+// Templated notes are already rendered into Notes.
+func (mr *MedicalRecord) Narrative() string {
+	var parts []string
+	for _, field := range []struct{ title, text string }{
+		{"Diagnosis", mr.Diagnosis},
+		{"Treatment", mr.Treatment},
+		{"Medications", mr.Medications},
+		{"Notes", mr.Notes},
+	} {
+		if text := strings.TrimSpace(field.text); text != "" {
+			parts = append(parts, field.title+":\n"+text)
+		}
+	}
+
+	if len(mr.Diagnoses) > 0 {
+		coded := make([]string, len(mr.Diagnoses))
+		for i, d := range mr.Diagnoses {
+			coded[i] = fmt.Sprintf("- %s %s %s", d.System.Name(), d.Code, d.Display)
+		}
+		parts = append(parts, "Coded diagnoses:\n"+strings.Join(coded, "\n"))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// IsLocked reports whether the record has been signed. Locked records are
+// corrected only through addenda.
+func (mr *MedicalRecord) IsLocked() bool {
+	return mr.Status == RecordStatusPendingCosign || mr.Status == RecordStatusSigned
+}
+
+// ComputeContentHash returns the SHA-256 of the record's clinical content as
+// captured in revisions, bound to its patient and author.
+func (mr *MedicalRecord) ComputeContentHash() (string, error) {
+	raw, err := toFieldMap(mr)
+	if err != nil {
+		return "", err
+	}
+	content := map[string]interface{}{
+		"id":         mr.ID,
+		"patient_id": mr.PatientID,
+		"doctor_id":  mr.DoctorID,
+	}
+	for _, field := range revisionFields[ResourceMedicalRecord] {
+		// Fields the record has no value for, such as coded diagnoses on
+		// records signed before coding, are left out so older hashes verify
+		if value, ok := raw[field]; ok {
+			content[field] = value
+		}
+	}
+
+	// encoding/json sorts map keys, so the encoding is canonical
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyContentHash reports whether a signed record still matches the hash
+// taken when it was signed.
+func (mr *MedicalRecord) VerifyContentHash() bool {
+	if mr.ContentHash == "" {
+		return false
+	}
+	hash, err := mr.ComputeContentHash()
+	return err == nil && hash == mr.ContentHash
+}
+
+// CheckIntegrity returns ErrRecordTampered for a signed record whose content
+// has changed since it was signed. Drafts have no hash to check.
+func (mr *MedicalRecord) CheckIntegrity() error {
+	if mr.IsLocked() && !mr.VerifyContentHash() {
+		return ErrRecordTampered
+	}
+	return nil
+}
+
+// MoveToPatient files the record under another chart, as when duplicate
+// charts are merged. The patient is part of the signed content, so a signed
+// record's hash is checked against its current chart and then taken again
+// for the new one.
+func (mr *MedicalRecord) MoveToPatient(patientID uint) error {
+	if !mr.IsLocked() || mr.ContentHash == "" {
+		mr.PatientID = patientID
+		return nil
+	}
+	if !mr.VerifyContentHash() {
+		return ErrRecordTampered
+	}
+
+	mr.PatientID = patientID
+	hash, err := mr.ComputeContentHash()
+	if err != nil {
+		return err
+	}
+	mr.ContentHash = hash
+	return nil
+}
+
+// Sign locks a draft on behalf of its author. A resident's signature leaves
+// the record waiting for the named attending to cosign.
+func (mr *MedicalRecord) Sign(signer *User, cosignerID *uint) error {
+	if mr.Status != RecordStatusDraft {
+		return fmt.Errorf("only draft records can be signed")
+	}
+	if signer.ID != mr.DoctorID {
+		return fmt.Errorf("only the authoring doctor can sign this record")
+	}
+
+	status := RecordStatusSigned
+	if signer.IsResident() {
+		if cosignerID == nil || *cosignerID == signer.ID {
+			return fmt.Errorf("a resident's record must name an attending physician to cosign")
+		}
+		status = RecordStatusPendingCosign
+		mr.CosignerID = cosignerID
+	} else {
+		mr.CosignerID = nil
+	}
+
+	hash, err := mr.ComputeContentHash()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	mr.Status = status
+	mr.SignedBy = &signer.ID
+	mr.SignedAt = &now
+	mr.ContentHash = hash
+	return nil
+}
+
+// Cosign completes sign-off of a resident's record by the attending it names
+func (mr *MedicalRecord) Cosign(attending *User) error {
+	if mr.Status != RecordStatusPendingCosign {
+		return fmt.Errorf("record is not awaiting cosignature")
+	}
+	if !attending.IsAttending() {
+		return fmt.Errorf("only an attending physician can cosign")
+	}
+	if mr.CosignerID == nil || *mr.CosignerID != attending.ID {
+		return fmt.Errorf("record is awaiting cosignature by another physician")
+	}
+	if !mr.VerifyContentHash() {
+		return ErrRecordTampered
+	}
+
+	now := time.Now()
+	mr.Status = RecordStatusSigned
+	mr.CosignedBy = &attending.ID
+	mr.CosignedAt = &now
+	return nil
+}
+
 func (mr *MedicalRecord) CanBeAccessedByRole(role UserRole, userID uint) bool {
 	switch role {
 	case RoleDoctor:
-		return true
+		return !mr.IsWithheldFrom(userID)
 	case RoleNurse:
-		return mr.Severity != SeverityCritical
-	case RoleAdmin:
+		// Restricted records are the one classification nurses may open,
+		// redacted by SanitizeForRole
+		return mr.Severity != SeverityCritical && (!mr.IsSensitive() || mr.Sensitivity == SensitivityRestricted)
+	case RoleBilling:
+		return !mr.IsSensitive()
+	case RoleAdmin, RoleFrontDesk:
 		return false
 	default:
 		return false
@@ -76,14 +329,71 @@ func (mr *MedicalRecord) SanitizeForRole(role UserRole) *MedicalRecord {
 			sanitized.Diagnosis = "[RESTRICTED - Doctor Only]"
 			sanitized.Treatment = "[RESTRICTED - Doctor Only]"
 			sanitized.Medications = "[RESTRICTED - Doctor Only]"
+			sanitized.Sections = nil
+			sanitized.Diagnoses = nil
+			sanitized.Attachments = nil
 		}
-	case RoleAdmin:
+		sanitized.redactSensitive(role)
+	case RoleBilling:
+		sanitized.redactSensitive(role)
+		sanitized.Treatment = ""
+		sanitized.Notes = ""
+		sanitized.Sections = nil
+		sanitized.Medications = ""
+		sanitized.Attachments = nil
+	case RoleAdmin, RoleFrontDesk:
 		return nil
 	}
 
 	return &sanitized
 }
 
+// redactSensitive applies the rule for the record's sensitivity category to a
+// copy shown to a nurse or billing staff. Doctors see every category in full.
+func (mr *MedicalRecord) redactSensitive(role UserRole) {
+	switch mr.Sensitivity {
+	case SensitivityRestricted:
+		// Nurses keep what they need to give care; the narrative stays with
+		// doctors. Billing keeps the codes for claims.
+		if role == RoleNurse {
+			mr.Notes = sensitiveRecordPlaceholder
+			mr.Sections = nil
+			mr.Attachments = nil
+		} else {
+			mr.Diagnosis = sensitiveRecordPlaceholder
+		}
+	case SensitivityMentalHealth:
+		// Billing keeps the codes for claims, but no clinical content
+		// reaches anyone but doctors
+		mr.redactContent()
+		if role != RoleBilling {
+			mr.Diagnoses = nil
+		}
+	case SensitivityReproductive:
+		// The codes alone reveal the care, so nobody but doctors gets them
+		mr.redactContent()
+		mr.Diagnoses = nil
+	case SensitivitySubstanceUse:
+		// Disclosed only under the patient's consent, and then to doctors
+		mr.redactContent()
+		mr.Diagnoses = nil
+	case SensitivityVeryRestricted:
+		// Withheld outright from everyone but the author; this covers a copy
+		// that reaches another role regardless
+		mr.redactContent()
+		mr.Diagnoses = nil
+	}
+}
+
+func (mr *MedicalRecord) redactContent() {
+	mr.Diagnosis = sensitiveRecordPlaceholder
+	mr.Treatment = sensitiveRecordPlaceholder
+	mr.Notes = sensitiveRecordPlaceholder
+	mr.Medications = sensitiveRecordPlaceholder
+	mr.Sections = nil
+	mr.Attachments = nil
+}
+
 func (mr *MedicalRecord) TableName() string {
 	return "medical_records"
-}
\ No newline at end of file
+}
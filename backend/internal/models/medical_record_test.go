@@ -0,0 +1,234 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMedicalRecordSensitivity(t *testing.T) {
+	t.Run("NurseCannotOpenSensitiveRecords", func(t *testing.T) {
+		normal := &MedicalRecord{Severity: SeverityLow, Sensitivity: SensitivityNormal}
+		mental := &MedicalRecord{Severity: SeverityLow, Sensitivity: SensitivityMentalHealth}
+
+		assert.True(t, normal.CanBeAccessedByRole(RoleNurse, 2))
+		assert.False(t, mental.CanBeAccessedByRole(RoleNurse, 2))
+		assert.True(t, mental.CanBeAccessedByRole(RoleDoctor, 3))
+	})
+
+	t.Run("VeryRestrictedOnlyForAuthor", func(t *testing.T) {
+		record := &MedicalRecord{DoctorID: 1, Sensitivity: SensitivityVeryRestricted}
+
+		assert.True(t, record.CanBeAccessedByRole(RoleDoctor, 1))
+		assert.False(t, record.CanBeAccessedByRole(RoleDoctor, 2))
+		assert.True(t, record.IsWithheldFrom(2))
+	})
+
+	t.Run("SanitizeRedactsSensitiveForNurse", func(t *testing.T) {
+		record := &MedicalRecord{
+			Diagnosis:   "Alcohol use disorder",
+			Notes:       "Enrolled in program",
+			Severity:    SeverityMedium,
+			Sensitivity: SensitivitySubstanceUse,
+			Diagnoses:   []RecordDiagnosis{{System: CodeSystemICD10CM, Code: "F10.20", Primary: true}},
+		}
+
+		sanitized := record.SanitizeForRole(RoleNurse)
+		assert.Equal(t, sensitiveRecordPlaceholder, sanitized.Diagnosis)
+		assert.Equal(t, sensitiveRecordPlaceholder, sanitized.Notes)
+		assert.Empty(t, sanitized.Diagnoses)
+		assert.Empty(t, record.SanitizeForRole(RoleBilling).Diagnoses)
+
+		unchanged := record.SanitizeForRole(RoleDoctor)
+		assert.Equal(t, "Alcohol use disorder", unchanged.Diagnosis)
+		assert.Len(t, unchanged.Diagnoses, 1)
+	})
+
+	t.Run("EachCategoryHasItsOwnRule", func(t *testing.T) {
+		codes := []RecordDiagnosis{{System: CodeSystemICD10CM, Code: "Z00.00", Primary: true}}
+		record := func(sensitivity SensitivityLevel) *MedicalRecord {
+			return &MedicalRecord{
+				Diagnosis:   "Diagnosis",
+				Treatment:   "Treatment",
+				Notes:       "Notes",
+				Severity:    SeverityLow,
+				Sensitivity: sensitivity,
+				Diagnoses:   codes,
+			}
+		}
+
+		restricted := record(SensitivityRestricted)
+		assert.True(t, restricted.CanBeAccessedByRole(RoleNurse, 2))
+		nurseCopy := restricted.SanitizeForRole(RoleNurse)
+		assert.Equal(t, "Diagnosis", nurseCopy.Diagnosis)
+		assert.Equal(t, "Treatment", nurseCopy.Treatment)
+		assert.Equal(t, sensitiveRecordPlaceholder, nurseCopy.Notes)
+		assert.Len(t, nurseCopy.Diagnoses, 1)
+		assert.Len(t, restricted.SanitizeForRole(RoleBilling).Diagnoses, 1)
+
+		mental := record(SensitivityMentalHealth)
+		assert.False(t, mental.CanBeAccessedByRole(RoleNurse, 2))
+		assert.Equal(t, sensitiveRecordPlaceholder, mental.SanitizeForRole(RoleNurse).Treatment)
+		assert.Empty(t, mental.SanitizeForRole(RoleNurse).Diagnoses)
+		assert.Len(t, mental.SanitizeForRole(RoleBilling).Diagnoses, 1)
+
+		reproductive := record(SensitivityReproductive)
+		assert.False(t, reproductive.CanBeAccessedByRole(RoleNurse, 2))
+		assert.Empty(t, reproductive.SanitizeForRole(RoleNurse).Diagnoses)
+		assert.Empty(t, reproductive.SanitizeForRole(RoleBilling).Diagnoses)
+		assert.Equal(t, sensitiveRecordPlaceholder, reproductive.SanitizeForRole(RoleBilling).Diagnosis)
+
+		for _, sensitivity := range []SensitivityLevel{SensitivityRestricted, SensitivityMentalHealth, SensitivityReproductive} {
+			doctorCopy := record(sensitivity).SanitizeForRole(RoleDoctor)
+			assert.Equal(t, "Notes", doctorCopy.Notes)
+			assert.Len(t, doctorCopy.Diagnoses, 1)
+		}
+	})
+
+	t.Run("SanitizeRestrictsCriticalForNurse", func(t *testing.T) {
+		record := &MedicalRecord{
+			Diagnosis:   "Acute myocardial infarction",
+			Treatment:   "Emergency PCI",
+			Severity:    SeverityCritical,
+			Sensitivity: SensitivityNormal,
+			Sections:    NoteContent{"assessment": "STEMI", "plan": "Cath lab"},
+			Diagnoses:   []RecordDiagnosis{{System: CodeSystemICD10CM, Code: "I21.9", Primary: true}},
+		}
+
+		sanitized := record.SanitizeForRole(RoleNurse)
+		assert.Equal(t, "[RESTRICTED - Doctor Only]", sanitized.Diagnosis)
+		assert.Equal(t, "[RESTRICTED - Doctor Only]", sanitized.Treatment)
+		assert.Empty(t, sanitized.Sections)
+		assert.Empty(t, sanitized.Diagnoses)
+
+		assert.Len(t, record.SanitizeForRole(RoleDoctor).Sections, 2)
+	})
+
+	t.Run("ConsentAndExport", func(t *testing.T) {
+		substance := &MedicalRecord{Sensitivity: SensitivitySubstanceUse}
+		normal := &MedicalRecord{Sensitivity: SensitivityNormal}
+
+		assert.True(t, substance.RequiresConsent())
+		assert.False(t, normal.RequiresConsent())
+		assert.True(t, substance.IsExcludedFromDefaultExport())
+		assert.False(t, normal.IsExcludedFromDefaultExport())
+	})
+}
+
+func TestMedicalRecordSignoff(t *testing.T) {
+	attending := &User{ID: 1, Role: RoleDoctor}
+	resident := &User{ID: 2, Role: RoleDoctor, Resident: true}
+	newDraft := func(authorID uint) *MedicalRecord {
+		return &MedicalRecord{ID: 10, PatientID: 5, DoctorID: authorID, Diagnosis: "Hypertension",
+			Severity: SeverityMedium, Status: RecordStatusDraft}
+	}
+
+	t.Run("AttendingSignatureLocks", func(t *testing.T) {
+		record := newDraft(attending.ID)
+
+		assert.False(t, record.IsLocked())
+		assert.NoError(t, record.Sign(attending, nil))
+		assert.Equal(t, RecordStatusSigned, record.Status)
+		assert.True(t, record.IsLocked())
+		assert.True(t, record.VerifyContentHash())
+		assert.Error(t, record.Sign(attending, nil))
+
+		record.Diagnosis = "Hypotension"
+		assert.False(t, record.VerifyContentHash())
+	})
+
+	t.Run("CodedDiagnosesAreSigned", func(t *testing.T) {
+		record := newDraft(attending.ID)
+		unsigned := newDraft(attending.ID)
+		record.Diagnoses = []RecordDiagnosis{{System: CodeSystemICD10CM, Code: "I10", Display: "Essential (primary) hypertension", Primary: true}}
+
+		assert.NoError(t, record.Sign(attending, nil))
+		uncoded, err := unsigned.ComputeContentHash()
+		assert.NoError(t, err)
+		assert.NotEqual(t, uncoded, record.ContentHash)
+
+		record.Diagnoses[0].Code = "I15.0"
+		assert.False(t, record.VerifyContentHash())
+	})
+
+	t.Run("OnlyAuthorSigns", func(t *testing.T) {
+		record := newDraft(attending.ID)
+
+		assert.Error(t, record.Sign(&User{ID: 3, Role: RoleDoctor}, nil))
+		assert.Equal(t, RecordStatusDraft, record.Status)
+	})
+
+	t.Run("ResidentNeedsNamedAttending", func(t *testing.T) {
+		record := newDraft(resident.ID)
+
+		assert.Error(t, record.Sign(resident, nil))
+		assert.NoError(t, record.Sign(resident, &attending.ID))
+		assert.Equal(t, RecordStatusPendingCosign, record.Status)
+		assert.True(t, record.IsLocked())
+
+		assert.Error(t, record.Cosign(resident))
+		assert.Error(t, record.Cosign(&User{ID: 3, Role: RoleDoctor}))
+		assert.NoError(t, record.Cosign(attending))
+		assert.Equal(t, RecordStatusSigned, record.Status)
+		assert.Equal(t, attending.ID, *record.CosignedBy)
+	})
+
+	t.Run("AddendumHash", func(t *testing.T) {
+		addendum := &RecordAddendum{RecordID: 10, AuthorID: 1, Content: "Dose corrected to 20mg"}
+		assert.NoError(t, addendum.BeforeCreate(nil))
+		assert.True(t, addendum.VerifyContentHash())
+
+		addendum.Content = "Dose corrected to 40mg"
+		assert.False(t, addendum.VerifyContentHash())
+		assert.ErrorIs(t, addendum.BeforeUpdate(nil), ErrAddendumImmutable)
+	})
+
+	t.Run("MergedRecordCanBeCosigned", func(t *testing.T) {
+		record := newDraft(resident.ID)
+		assert.NoError(t, record.Sign(resident, &attending.ID))
+		signedHash := record.ContentHash
+
+		moved := *record
+		moved.PatientID = 8
+		assert.Error(t, moved.Cosign(attending), "moving the row alone breaks the signature")
+
+		assert.NoError(t, record.MoveToPatient(8))
+		assert.Equal(t, uint(8), record.PatientID)
+		assert.NotEqual(t, signedHash, record.ContentHash)
+		assert.NoError(t, record.Cosign(attending))
+		assert.Equal(t, RecordStatusSigned, record.Status)
+	})
+
+	t.Run("TamperedRecordIsNotReanchored", func(t *testing.T) {
+		record := newDraft(attending.ID)
+		assert.NoError(t, record.Sign(attending, nil))
+
+		record.Diagnosis = "Hypotension"
+		assert.ErrorIs(t, record.MoveToPatient(8), ErrRecordTampered)
+		assert.Equal(t, uint(5), record.PatientID)
+	})
+
+	t.Run("IntegrityCheckedOnSignedRecords", func(t *testing.T) {
+		record := newDraft(attending.ID)
+		record.Diagnosis = "Edited before signing"
+		assert.NoError(t, record.CheckIntegrity())
+
+		assert.NoError(t, record.Sign(attending, nil))
+		assert.NoError(t, record.CheckIntegrity())
+
+		record.PatientID = 8
+		assert.ErrorIs(t, record.CheckIntegrity(), ErrRecordTampered)
+	})
+}
+
+func TestMedicalRecordNarrative(t *testing.T) {
+	record := &MedicalRecord{
+		Diagnosis: "Type 2 diabetes",
+		Notes:     "Review in three months\n",
+		Diagnoses: []RecordDiagnosis{{System: CodeSystemICD10CM, Code: "E11.9", Display: "Type 2 diabetes mellitus without complications"}},
+	}
+
+	assert.Equal(t, "Diagnosis:\nType 2 diabetes\n\nNotes:\nReview in three months\n\n"+
+		"Coded diagnoses:\n- ICD-10-CM E11.9 Type 2 diabetes mellitus without complications", record.Narrative())
+	assert.Equal(t, "", (&MedicalRecord{}).Narrative())
+}
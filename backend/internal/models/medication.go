@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MedicationRoute string
+
+const (
+	RouteOral          MedicationRoute = "oral"
+	RouteIntravenous   MedicationRoute = "intravenous"
+	RouteIntramuscular MedicationRoute = "intramuscular"
+	RouteSubcutaneous  MedicationRoute = "subcutaneous"
+	RouteSublingual    MedicationRoute = "sublingual"
+	RouteTopical       MedicationRoute = "topical"
+	RouteTransdermal   MedicationRoute = "transdermal"
+	RouteInhaled       MedicationRoute = "inhaled"
+	RouteNasal         MedicationRoute = "nasal"
+	RouteOphthalmic    MedicationRoute = "ophthalmic"
+	RouteOtic          MedicationRoute = "otic"
+	RouteRectal        MedicationRoute = "rectal"
+	RouteOther         MedicationRoute = "other"
+)
+
+func IsValidMedicationRoute(route MedicationRoute) bool {
+	switch route {
+	case RouteOral, RouteIntravenous, RouteIntramuscular, RouteSubcutaneous, RouteSublingual,
+		RouteTopical, RouteTransdermal, RouteInhaled, RouteNasal, RouteOphthalmic, RouteOtic,
+		RouteRectal, RouteOther:
+		return true
+	}
+	return false
+}
+
+type MedicationStatus string
+
+const (
+	MedicationStatusActive       MedicationStatus = "active"
+	MedicationStatusDiscontinued MedicationStatus = "discontinued"
+)
+
+// MedicationOrder is a structured prescription on a patient's medication
+// list. It replaces the free-text MedicalRecord.Medications, which is kept
+// readable for records written before orders existed. Deleting an order
+// marks it entered in error.
+type MedicationOrder struct {
+	ID           uint             `json:"id" gorm:"primaryKey"`
+	PatientID    uint             `json:"patient_id" gorm:"not null;index"`
+	EncounterID  *uint            `json:"encounter_id,omitempty" gorm:"index"`
+	Drug         string           `json:"drug" gorm:"type:text;not null;serializer:encrypted"`
+	Dose         string           `json:"dose" gorm:"size:100;not null"`
+	Route        MedicationRoute  `json:"route" gorm:"size:32;not null"`
+	Frequency    string           `json:"frequency" gorm:"size:100;not null"`
+	StartDate    time.Time        `json:"start_date" gorm:"not null"`
+	StopDate     *time.Time       `json:"stop_date,omitempty"`
+	Status       MedicationStatus `json:"status" gorm:"type:enum('active','discontinued');default:'active';index"`
+	PrescriberID uint             `json:"prescriber_id" gorm:"not null;index"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt   `json:"-" gorm:"index"`
+
+	Prescriber User `json:"prescriber,omitempty" gorm:"foreignKey:PrescriberID"`
+}
+
+func (mo *MedicationOrder) BeforeCreate(tx *gorm.DB) (err error) {
+	if mo.CreatedAt.IsZero() {
+		mo.CreatedAt = time.Now()
+	}
+	if mo.UpdatedAt.IsZero() {
+		mo.UpdatedAt = time.Now()
+	}
+	if mo.StartDate.IsZero() {
+		mo.StartDate = mo.CreatedAt
+	}
+	if mo.Status == "" {
+		mo.Status = MedicationStatusActive
+	}
+	return
+}
+
+func (mo *MedicationOrder) BeforeUpdate(tx *gorm.DB) (err error) {
+	mo.UpdatedAt = time.Now()
+	return
+}
+
+// IsActive reports whether the patient should be taking the medication now
+func (mo *MedicationOrder) IsActive(now time.Time) bool {
+	return mo.Status == MedicationStatusActive &&
+		!mo.StartDate.After(now) &&
+		(mo.StopDate == nil || mo.StopDate.After(now))
+}
+
+func (mo *MedicationOrder) Discontinue(at time.Time) error {
+	if mo.Status != MedicationStatusActive {
+		return gorm.ErrInvalidValue
+	}
+
+	mo.Status = MedicationStatusDiscontinued
+	if mo.StopDate == nil || mo.StopDate.After(at) {
+		mo.StopDate = &at
+	}
+	return nil
+}
+
+func (mo *MedicationOrder) TableName() string {
+	return "medication_orders"
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NoteSectionType is the kind of value a template section holds
+type NoteSectionType string
+
+const (
+	SectionText    NoteSectionType = "text"
+	SectionNumber  NoteSectionType = "number"
+	SectionDate    NoteSectionType = "date"
+	SectionChoice  NoteSectionType = "choice"
+	SectionBoolean NoteSectionType = "boolean"
+)
+
+const (
+	maxTemplateSections = 50
+	maxSectionText      = 20000
+)
+
+var templateKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+func IsValidSectionType(sectionType NoteSectionType) bool {
+	switch sectionType {
+	case SectionText, SectionNumber, SectionDate, SectionChoice, SectionBoolean:
+		return true
+	}
+	return false
+}
+
+// NoteSection is one field of a note template
+type NoteSection struct {
+	Key      string          `json:"key"`
+	Title    string          `json:"title"`
+	Type     NoteSectionType `json:"type"`
+	Required bool            `json:"required,omitempty"`
+	Options  []string        `json:"options,omitempty"`
+	Unit     string          `json:"unit,omitempty"`
+	Help     string          `json:"help,omitempty"`
+}
+
+// NoteContent holds a templated note's values keyed by section key
+type NoteContent map[string]interface{}
+
+// NoteTemplate is one version of an admin-managed note structure such as SOAP
+// or a discharge summary. Versions are immutable: changing a template
+// publishes a new version under the same key, and notes keep pointing at the
+// version they were written against.
+type NoteTemplate struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	Key         string        `json:"key" gorm:"column:template_key;size:64;not null;uniqueIndex:idx_note_template_version"`
+	Version     int           `json:"version" gorm:"not null;uniqueIndex:idx_note_template_version"`
+	Name        string        `json:"name" gorm:"size:100;not null"`
+	Description string        `json:"description,omitempty" gorm:"type:text"`
+	Sections    []NoteSection `json:"sections" gorm:"type:text;serializer:json;not null"`
+	CreatedBy   uint          `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time     `json:"created_at"`
+	RetiredAt   *time.Time    `json:"retired_at,omitempty"`
+}
+
+func (t *NoteTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	if t.Version == 0 {
+		t.Version = 1
+	}
+	return
+}
+
+func (t *NoteTemplate) TableName() string {
+	return "note_templates"
+}
+
+func (t *NoteTemplate) IsRetired() bool {
+	return t.RetiredAt != nil
+}
+
+// Validate checks the template definition
+func (t *NoteTemplate) Validate() error {
+	if !templateKeyPattern.MatchString(t.Key) {
+		return fmt.Errorf("template key must be lower case letters, digits and underscores")
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("template name is required")
+	}
+	if len(t.Sections) == 0 {
+		return fmt.Errorf("a template needs at least one section")
+	}
+	if len(t.Sections) > maxTemplateSections {
+		return fmt.Errorf("a template may have at most %d sections", maxTemplateSections)
+	}
+
+	seen := make(map[string]bool, len(t.Sections))
+	for _, section := range t.Sections {
+		if !templateKeyPattern.MatchString(section.Key) {
+			return fmt.Errorf("section key %q must be lower case letters, digits and underscores", section.Key)
+		}
+		if seen[section.Key] {
+			return fmt.Errorf("section %q is listed twice", section.Key)
+		}
+		seen[section.Key] = true
+
+		if strings.TrimSpace(section.Title) == "" {
+			return fmt.Errorf("section %q needs a title", section.Key)
+		}
+		if !IsValidSectionType(section.Type) {
+			return fmt.Errorf("section %q has invalid type %q", section.Key, section.Type)
+		}
+
+		if section.Type == SectionChoice {
+			if len(section.Options) < 2 {
+				return fmt.Errorf("choice section %q needs at least two options", section.Key)
+			}
+			options := make(map[string]bool, len(section.Options))
+			for _, option := range section.Options {
+				if strings.TrimSpace(option) == "" || options[option] {
+					return fmt.Errorf("choice section %q has an empty or repeated option", section.Key)
+				}
+				options[option] = true
+			}
+		} else if len(section.Options) > 0 {
+			return fmt.Errorf("only choice sections have options")
+		}
+		if section.Unit != "" && section.Type != SectionNumber {
+			return fmt.Errorf("only number sections have a unit")
+		}
+	}
+	return nil
+}
+
+// CheckContent validates note values against the sections and returns them
+// normalized: text trimmed, empty optional sections dropped.
+func (t *NoteTemplate) CheckContent(content NoteContent) (NoteContent, error) {
+	sections := make(map[string]NoteSection, len(t.Sections))
+	for _, section := range t.Sections {
+		sections[section.Key] = section
+	}
+	for key := range content {
+		if _, ok := sections[key]; !ok {
+			return nil, fmt.Errorf("template %s has no section %q", t.Key, key)
+		}
+	}
+
+	checked := make(NoteContent, len(content))
+	for _, section := range t.Sections {
+		value, present := content[section.Key]
+		if text, ok := value.(string); ok {
+			value = strings.TrimSpace(text)
+			present = value != ""
+		}
+		if !present || value == nil {
+			if section.Required {
+				return nil, fmt.Errorf("section %q is required", section.Title)
+			}
+			continue
+		}
+
+		switch section.Type {
+		case SectionText:
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("section %q must be text", section.Title)
+			}
+			if len([]rune(text)) > maxSectionText {
+				return nil, fmt.Errorf("section %q is longer than %d characters", section.Title, maxSectionText)
+			}
+		case SectionNumber:
+			if _, ok := value.(float64); !ok {
+				return nil, fmt.Errorf("section %q must be a number", section.Title)
+			}
+		case SectionDate:
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("section %q must be a date", section.Title)
+			}
+			if _, err := time.Parse("2006-01-02", text); err != nil {
+				return nil, fmt.Errorf("section %q must be a date in YYYY-MM-DD form", section.Title)
+			}
+		case SectionChoice:
+			text, ok := value.(string)
+			if !ok || !containsString(section.Options, text) {
+				return nil, fmt.Errorf("section %q must be one of %s", section.Title, strings.Join(section.Options, ", "))
+			}
+		case SectionBoolean:
+			if _, ok := value.(bool); !ok {
+				return nil, fmt.Errorf("section %q must be true or false", section.Title)
+			}
+		}
+		checked[section.Key] = value
+	}
+	return checked, nil
+}
+
+// Render writes checked content as a narrative in section order. Text
+// sections start on their own line under the title; other values follow it.
+func (t *NoteTemplate) Render(content NoteContent) string {
+	parts := make([]string, 0, len(t.Sections))
+	for _, section := range t.Sections {
+		value, ok := content[section.Key]
+		if !ok {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			if section.Type == SectionText {
+				parts = append(parts, section.Title+":\n"+v)
+			} else {
+				parts = append(parts, section.Title+": "+v)
+			}
+		case float64:
+			text := strconv.FormatFloat(v, 'f', -1, 64)
+			if section.Unit != "" {
+				text += " " + section.Unit
+			}
+			parts = append(parts, section.Title+": "+text)
+		case bool:
+			text := "No"
+			if v {
+				text = "Yes"
+			}
+			parts = append(parts, section.Title+": "+text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
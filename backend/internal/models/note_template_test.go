@@ -0,0 +1,111 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSOAPTemplate() *NoteTemplate {
+	return &NoteTemplate{
+		Key:  "soap",
+		Name: "SOAP Note",
+		Sections: []NoteSection{
+			{Key: "subjective", Title: "Subjective", Type: SectionText, Required: true},
+			{Key: "pain_score", Title: "Pain Score", Type: SectionNumber, Unit: "/10"},
+			{Key: "onset", Title: "Onset", Type: SectionDate},
+			{Key: "condition", Title: "Condition", Type: SectionChoice, Options: []string{"Improved", "Stable"}},
+			{Key: "follow_up", Title: "Follow-up Needed", Type: SectionBoolean},
+		},
+	}
+}
+
+func TestNoteTemplateValidate(t *testing.T) {
+	assert.NoError(t, testSOAPTemplate().Validate())
+
+	badKey := testSOAPTemplate()
+	badKey.Key = "SOAP Note"
+	assert.Error(t, badKey.Validate())
+
+	duplicate := testSOAPTemplate()
+	duplicate.Sections = append(duplicate.Sections, NoteSection{Key: "onset", Title: "Onset again", Type: SectionText})
+	assert.Error(t, duplicate.Validate())
+
+	oneOption := testSOAPTemplate()
+	oneOption.Sections[3].Options = []string{"Improved"}
+	assert.Error(t, oneOption.Validate())
+
+	textUnit := testSOAPTemplate()
+	textUnit.Sections[0].Unit = "mg"
+	assert.Error(t, textUnit.Validate())
+
+	badType := testSOAPTemplate()
+	badType.Sections[0].Type = "rich_text"
+	assert.Error(t, badType.Validate())
+}
+
+func TestNoteTemplateCheckContent(t *testing.T) {
+	template := testSOAPTemplate()
+
+	checked, err := template.CheckContent(NoteContent{
+		"subjective": "  Back pain for two weeks.  ",
+		"pain_score": float64(6),
+		"onset":      "2024-01-02",
+		"condition":  "Stable",
+		"follow_up":  true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Back pain for two weeks.", checked["subjective"])
+
+	checked, err = template.CheckContent(NoteContent{"subjective": "Pain", "onset": "", "condition": nil})
+	require.NoError(t, err)
+	assert.Len(t, checked, 1, "empty optional sections are dropped")
+
+	_, err = template.CheckContent(NoteContent{"pain_score": float64(2)})
+	assert.Error(t, err, "required section missing")
+
+	_, err = template.CheckContent(NoteContent{"subjective": "   "})
+	assert.Error(t, err, "blank text does not satisfy a required section")
+
+	_, err = template.CheckContent(NoteContent{"subjective": "Pain", "history": "none"})
+	assert.Error(t, err, "unknown section")
+
+	_, err = template.CheckContent(NoteContent{"subjective": "Pain", "pain_score": "six"})
+	assert.Error(t, err)
+
+	_, err = template.CheckContent(NoteContent{"subjective": "Pain", "onset": "02/01/2024"})
+	assert.Error(t, err)
+
+	_, err = template.CheckContent(NoteContent{"subjective": "Pain", "condition": "Worse"})
+	assert.Error(t, err)
+
+	_, err = template.CheckContent(NoteContent{"subjective": "Pain", "follow_up": "yes"})
+	assert.Error(t, err)
+}
+
+func TestNoteTemplateRender(t *testing.T) {
+	template := testSOAPTemplate()
+
+	content, err := template.CheckContent(NoteContent{
+		"follow_up":  false,
+		"subjective": "Back pain.",
+		"pain_score": 6.5,
+		"condition":  "Improved",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Subjective:\nBack pain.\n\nPain Score: 6.5 /10\n\nCondition: Improved\n\nFollow-up Needed: No", template.Render(content))
+}
+
+func TestNoteSectionsFollowRecordSanitization(t *testing.T) {
+	sections := NoteContent{"subjective": "Back pain."}
+
+	routine := &MedicalRecord{Severity: SeverityLow, Sensitivity: SensitivityNormal, Sections: sections}
+	sensitive := &MedicalRecord{Severity: SeverityLow, Sensitivity: SensitivityMentalHealth, Sections: sections}
+
+	assert.Len(t, routine.SanitizeForRole(RoleDoctor).Sections, 1)
+	assert.Len(t, routine.SanitizeForRole(RoleNurse).Sections, 1)
+	assert.Nil(t, sensitive.SanitizeForRole(RoleNurse).Sections)
+	assert.Nil(t, routine.SanitizeForRole(RoleBilling).Sections)
+}
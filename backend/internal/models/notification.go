@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationSeverity string
+
+const (
+	NotificationLow      NotificationSeverity = "low"
+	NotificationMedium   NotificationSeverity = "medium"
+	NotificationHigh     NotificationSeverity = "high"
+	NotificationCritical NotificationSeverity = "critical"
+)
+
+type NotificationType string
+
+const (
+	NotificationAbnormalResult NotificationType = "abnormal_result"
+)
+
+// Notification is an item in a user's inbox. The message names what needs
+// attention without clinical values; the resource points at the detail,
+// which is read through the usual access checks.
+type Notification struct {
+	ID        uint                 `json:"id" gorm:"primaryKey"`
+	UserID    uint                 `json:"user_id" gorm:"not null;index"`
+	Type      NotificationType     `json:"type" gorm:"size:32;not null"`
+	Severity  NotificationSeverity `json:"severity" gorm:"type:enum('low','medium','high','critical');default:'medium';index"`
+	PatientID *uint                `json:"patient_id,omitempty" gorm:"index"`
+	Resource  string               `json:"resource" gorm:"size:255"`
+	Message   string               `json:"message" gorm:"size:255;not null"`
+	ReadAt    *time.Time           `json:"read_at,omitempty"`
+	CreatedAt time.Time            `json:"created_at" gorm:"index"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) (err error) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	if n.Severity == "" {
+		n.Severity = NotificationMedium
+	}
+	return
+}
+
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+func (n *Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ObservationCategory string
+
+const (
+	CategoryVitalSigns ObservationCategory = "vital_signs"
+	CategoryLaboratory ObservationCategory = "laboratory"
+)
+
+// Interpretation is the abnormal flag of an observation
+type Interpretation string
+
+const (
+	InterpretationNormal       Interpretation = "normal"
+	InterpretationLow          Interpretation = "low"
+	InterpretationHigh         Interpretation = "high"
+	InterpretationAbnormal     Interpretation = "abnormal"
+	InterpretationCriticalLow  Interpretation = "critical_low"
+	InterpretationCriticalHigh Interpretation = "critical_high"
+	InterpretationCritical     Interpretation = "critical"
+)
+
+func IsValidInterpretation(interpretation Interpretation) bool {
+	switch interpretation {
+	case InterpretationNormal, InterpretationLow, InterpretationHigh, InterpretationAbnormal,
+		InterpretationCriticalLow, InterpretationCriticalHigh, InterpretationCritical:
+		return true
+	}
+	return false
+}
+
+// loincCode matches the LOINC code format: up to five digits, a hyphen and a
+// check digit
+var loincCode = regexp.MustCompile(`^[0-9]{1,5}-[0-9]$`)
+
+func IsValidLOINCCode(code string) bool {
+	return loincCode.MatchString(code)
+}
+
+// VitalSign describes a vital sign with its LOINC code, UCUM unit and adult
+// reference and critical limits
+type VitalSign struct {
+	Display       string
+	Unit          string
+	ReferenceLow  *float64
+	ReferenceHigh *float64
+	CriticalLow   *float64
+	CriticalHigh  *float64
+}
+
+func vitalLimit(value float64) *float64 {
+	return &value
+}
+
+// VitalSigns are the vital signs recorded at the bedside, keyed by LOINC
+// code. Observations of these codes must use the listed unit and default to
+// the listed limits.
+var VitalSigns = map[string]VitalSign{
+	"8480-6":  {Display: "Systolic blood pressure", Unit: "mm[Hg]", ReferenceLow: vitalLimit(90), ReferenceHigh: vitalLimit(139), CriticalLow: vitalLimit(70), CriticalHigh: vitalLimit(180)},
+	"8462-4":  {Display: "Diastolic blood pressure", Unit: "mm[Hg]", ReferenceLow: vitalLimit(60), ReferenceHigh: vitalLimit(89), CriticalLow: vitalLimit(40), CriticalHigh: vitalLimit(120)},
+	"8867-4":  {Display: "Heart rate", Unit: "/min", ReferenceLow: vitalLimit(60), ReferenceHigh: vitalLimit(100), CriticalLow: vitalLimit(40), CriticalHigh: vitalLimit(130)},
+	"9279-1":  {Display: "Respiratory rate", Unit: "/min", ReferenceLow: vitalLimit(12), ReferenceHigh: vitalLimit(20), CriticalLow: vitalLimit(8), CriticalHigh: vitalLimit(30)},
+	"59408-5": {Display: "Oxygen saturation by pulse oximetry", Unit: "%", ReferenceLow: vitalLimit(95), ReferenceHigh: vitalLimit(100), CriticalLow: vitalLimit(88)},
+	"8310-5":  {Display: "Body temperature", Unit: "Cel", ReferenceLow: vitalLimit(36.1), ReferenceHigh: vitalLimit(37.8), CriticalLow: vitalLimit(35), CriticalHigh: vitalLimit(40)},
+	"29463-7": {Display: "Body weight", Unit: "kg"},
+}
+
+// Observation is a single vital sign or lab result. Numeric results with
+// reference limits are flagged when created; other results carry the flag
+// reported by the lab. Deleting an observation marks it entered in error.
+type Observation struct {
+	ID               uint                `json:"id" gorm:"primaryKey"`
+	PatientID        uint                `json:"patient_id" gorm:"not null;index:idx_observation_trend,priority:1"`
+	EncounterID      *uint               `json:"encounter_id,omitempty" gorm:"index"`
+	Category         ObservationCategory `json:"category" gorm:"type:enum('vital_signs','laboratory');not null"`
+	Code             string              `json:"code" gorm:"size:16;not null;index:idx_observation_trend,priority:2"`
+	Display          string              `json:"display" gorm:"size:255;not null"`
+	ValueQuantity    *float64            `json:"value_quantity,omitempty"`
+	ValueString      string              `json:"value_string,omitempty" gorm:"type:text;serializer:encrypted"`
+	Unit             string              `json:"unit,omitempty" gorm:"size:32"`
+	ReferenceLow     *float64            `json:"reference_low,omitempty"`
+	ReferenceHigh    *float64            `json:"reference_high,omitempty"`
+	CriticalLow      *float64            `json:"critical_low,omitempty"`
+	CriticalHigh     *float64            `json:"critical_high,omitempty"`
+	Interpretation   Interpretation      `json:"interpretation" gorm:"type:enum('normal','low','high','abnormal','critical_low','critical_high','critical');default:'normal';index"`
+	EffectiveAt      time.Time           `json:"effective_at" gorm:"not null;index:idx_observation_trend,priority:3"`
+	Comment          string              `json:"comment,omitempty" gorm:"type:text;serializer:encrypted"`
+	RecordedBy       uint                `json:"recorded_by" gorm:"not null"`
+	OrderingDoctorID *uint               `json:"ordering_doctor_id,omitempty" gorm:"index"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt      `json:"-" gorm:"index"`
+
+	Recorder User `json:"recorder,omitempty" gorm:"foreignKey:RecordedBy"`
+}
+
+func (o *Observation) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = time.Now()
+	}
+	if o.UpdatedAt.IsZero() {
+		o.UpdatedAt = time.Now()
+	}
+	if o.EffectiveAt.IsZero() {
+		o.EffectiveAt = o.CreatedAt
+	}
+	if interpretation, ok := o.Interpret(); ok {
+		o.Interpretation = interpretation
+	}
+	if o.Interpretation == "" {
+		o.Interpretation = InterpretationNormal
+	}
+	return
+}
+
+func (o *Observation) BeforeUpdate(tx *gorm.DB) (err error) {
+	o.UpdatedAt = time.Now()
+	return
+}
+
+// Interpret flags a numeric value against the critical and reference
+// limits. It reports false when there is no value or no limit to compare.
+func (o *Observation) Interpret() (Interpretation, bool) {
+	if o.ValueQuantity == nil {
+		return "", false
+	}
+	value := *o.ValueQuantity
+
+	switch {
+	case o.CriticalLow != nil && value < *o.CriticalLow:
+		return InterpretationCriticalLow, true
+	case o.CriticalHigh != nil && value > *o.CriticalHigh:
+		return InterpretationCriticalHigh, true
+	case o.ReferenceLow != nil && value < *o.ReferenceLow:
+		return InterpretationLow, true
+	case o.ReferenceHigh != nil && value > *o.ReferenceHigh:
+		return InterpretationHigh, true
+	case o.ReferenceLow != nil || o.ReferenceHigh != nil || o.CriticalLow != nil || o.CriticalHigh != nil:
+		return InterpretationNormal, true
+	}
+	return "", false
+}
+
+func (o *Observation) IsAbnormal() bool {
+	return o.Interpretation != "" && o.Interpretation != InterpretationNormal
+}
+
+func (o *Observation) IsCritical() bool {
+	switch o.Interpretation {
+	case InterpretationCriticalLow, InterpretationCriticalHigh, InterpretationCritical:
+		return true
+	}
+	return false
+}
+
+// ApplyVitalSign fills the display name, unit and limits of a known vital
+// sign where the caller left them out. It reports false when the caller gave
+// a different unit, since mixing units would corrupt trends.
+func (o *Observation) ApplyVitalSign() bool {
+	vital, known := VitalSigns[o.Code]
+	if !known {
+		return true
+	}
+	if o.Unit != "" && o.Unit != vital.Unit {
+		return false
+	}
+
+	o.Unit = vital.Unit
+	if o.Display == "" {
+		o.Display = vital.Display
+	}
+	if o.ReferenceLow == nil && o.ReferenceHigh == nil {
+		o.ReferenceLow = vital.ReferenceLow
+		o.ReferenceHigh = vital.ReferenceHigh
+	}
+	if o.CriticalLow == nil && o.CriticalHigh == nil {
+		o.CriticalLow = vital.CriticalLow
+		o.CriticalHigh = vital.CriticalHigh
+	}
+	return true
+}
+
+func (o *Observation) TableName() string {
+	return "observations"
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObservationInterpretation(t *testing.T) {
+	newVital := func(code string, value float64) *Observation {
+		observation := &Observation{Category: CategoryVitalSigns, Code: code, ValueQuantity: &value}
+		assert.True(t, observation.ApplyVitalSign())
+		observation.BeforeCreate(nil)
+		return observation
+	}
+
+	t.Run("VitalSignDefaults", func(t *testing.T) {
+		observation := newVital("8867-4", 72)
+
+		assert.Equal(t, "Heart rate", observation.Display)
+		assert.Equal(t, "/min", observation.Unit)
+		assert.Equal(t, InterpretationNormal, observation.Interpretation)
+		assert.False(t, observation.IsAbnormal())
+	})
+
+	t.Run("AbnormalAndCritical", func(t *testing.T) {
+		high := newVital("8480-6", 150)
+		assert.Equal(t, InterpretationHigh, high.Interpretation)
+		assert.True(t, high.IsAbnormal())
+		assert.False(t, high.IsCritical())
+
+		critical := newVital("59408-5", 82)
+		assert.Equal(t, InterpretationCriticalLow, critical.Interpretation)
+		assert.True(t, critical.IsCritical())
+	})
+
+	t.Run("WrongUnitRejected", func(t *testing.T) {
+		value := 180.0
+		observation := &Observation{Code: "29463-7", ValueQuantity: &value, Unit: "[lb_av]"}
+
+		assert.False(t, observation.ApplyVitalSign())
+	})
+
+	t.Run("LabFlagWithoutLimits", func(t *testing.T) {
+		observation := &Observation{Category: CategoryLaboratory, Code: "5196-1", ValueString: "Reactive", Interpretation: InterpretationAbnormal}
+		observation.BeforeCreate(nil)
+
+		assert.Equal(t, InterpretationAbnormal, observation.Interpretation)
+	})
+
+	t.Run("ComputedFlagWins", func(t *testing.T) {
+		value, low, high := 5.9, 3.5, 5.1
+		observation := &Observation{Category: CategoryLaboratory, Code: "2823-3", ValueQuantity: &value,
+			ReferenceLow: &low, ReferenceHigh: &high, Interpretation: InterpretationNormal}
+		observation.BeforeCreate(nil)
+
+		assert.Equal(t, InterpretationHigh, observation.Interpretation)
+	})
+}
+
+func TestLOINCCode(t *testing.T) {
+	assert.True(t, IsValidLOINCCode("2823-3"))
+	assert.True(t, IsValidLOINCCode("59408-5"))
+	assert.False(t, IsValidLOINCCode("2823"))
+	assert.False(t, IsValidLOINCCode("ABC-1"))
+}
@@ -1,26 +1,141 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
 	"time"
+	"unicode"
+
+	"healthsecure/internal/encryption"
 
 	"gorm.io/gorm"
 )
 
+// Blind index domains. Each identifier is indexed under its own domain so that
+// equal values in different fields never share an index.
+const (
+	blindIndexSSN         = "patient.ssn"
+	blindIndexPhone       = "patient.phone"
+	blindIndexDemographic = "patient.dob_name"
+)
+
+// PatientPublicIDPrefix marks the opaque patient IDs accepted in API URLs
+const PatientPublicIDPrefix = "pt_"
+
+var publicIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
 type Patient struct {
 	ID               uint            `json:"id" gorm:"primaryKey"`
+	PublicID         string          `json:"public_id" gorm:"size:32;uniqueIndex"`
 	FirstName        string          `json:"first_name" gorm:"not null"`
 	LastName         string          `json:"last_name" gorm:"not null"`
 	DateOfBirth      time.Time       `json:"date_of_birth"`
-	SSN              string          `json:"ssn,omitempty" gorm:"unique;column:ssn"`
-	Phone            string          `json:"phone"`
+	SSN              string          `json:"ssn,omitempty" gorm:"column:ssn;size:255;serializer:encrypted"`
+	Phone            string          `json:"phone" gorm:"size:255;serializer:encrypted"`
 	Address          string          `json:"address"`
 	EmergencyContact string          `json:"emergency_contact"`
+	Confidential     bool            `json:"confidential" gorm:"default:false;index"`
+	EmployeeUserID   *uint           `json:"employee_user_id,omitempty" gorm:"index"`
+	SSNIndex         *string         `json:"-" gorm:"column:ssn_index;size:64;uniqueIndex"`
+	PhoneIndex       string          `json:"-" gorm:"size:64;index"`
+	DemographicIndex string          `json:"-" gorm:"size:64;index"`
+	MergedIntoID     *uint           `json:"merged_into_id,omitempty" gorm:"index"`
 	MedicalRecords   []MedicalRecord `json:"medical_records,omitempty"`
 	CreatedAt        time.Time       `json:"created_at"`
 	UpdatedAt        time.Time       `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt  `json:"-" gorm:"index"`
+
+	EmployeeUser *User               `json:"-" gorm:"foreignKey:EmployeeUserID"`
+	Identifiers  []PatientIdentifier `json:"identifiers,omitempty"`
+}
+
+// BeforeSave keeps the blind indexes in step with the encrypted identifiers.
+// Updates must select the index columns alongside the identifiers they cover.
+func (p *Patient) BeforeSave(tx *gorm.DB) (err error) {
+	return p.ComputeBlindIndexes()
+}
+
+// ComputeBlindIndexes derives the exact-match search indexes for SSN, phone
+// and date of birth plus name.
+func (p *Patient) ComputeBlindIndexes() error {
+	ssnIndex, err := PatientSSNIndex(p.SSN)
+	if err != nil {
+		return err
+	}
+	phoneIndex, err := PatientPhoneIndex(p.Phone)
+	if err != nil {
+		return err
+	}
+	demographicIndex, err := PatientDemographicIndex(p.DateOfBirth, p.FirstName, p.LastName)
+	if err != nil {
+		return err
+	}
+
+	// A NULL index keeps patients without an SSN out of the unique constraint
+	p.SSNIndex = nil
+	if ssnIndex != "" {
+		p.SSNIndex = &ssnIndex
+	}
+	p.PhoneIndex = phoneIndex
+	p.DemographicIndex = demographicIndex
+	return nil
+}
+
+// PatientSSNIndex returns the blind index for an SSN, ignoring formatting
+func PatientSSNIndex(ssn string) (string, error) {
+	return encryption.BlindIndex(blindIndexSSN, digitsOnly(ssn))
+}
+
+// PatientPhoneIndex returns the blind index for a phone number, ignoring
+// formatting
+func PatientPhoneIndex(phone string) (string, error) {
+	return encryption.BlindIndex(blindIndexPhone, digitsOnly(phone))
+}
+
+// PatientDemographicIndex returns the blind index for a date of birth and
+// full name. Names are compared case-insensitively; all three parts are
+// required.
+func PatientDemographicIndex(dateOfBirth time.Time, firstName, lastName string) (string, error) {
+	firstName = strings.ToLower(strings.TrimSpace(firstName))
+	lastName = strings.ToLower(strings.TrimSpace(lastName))
+	if dateOfBirth.IsZero() || firstName == "" || lastName == "" {
+		return "", nil
+	}
+	return encryption.BlindIndex(blindIndexDemographic, dateOfBirth.Format("2006-01-02")+"|"+firstName+"|"+lastName)
+}
+
+func digitsOnly(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// NewPatientPublicID returns a random opaque ID that reveals nothing about
+// how many patients exist or when the chart was created
+func NewPatientPublicID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return PatientPublicIDPrefix + strings.ToLower(publicIDEncoding.EncodeToString(b)), nil
+}
+
+// IsPatientPublicID reports whether a URL parameter is an opaque patient ID
+// rather than a numeric one
+func IsPatientPublicID(value string) bool {
+	return strings.HasPrefix(value, PatientPublicIDPrefix)
 }
 
 func (p *Patient) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.PublicID == "" {
+		if p.PublicID, err = NewPatientPublicID(); err != nil {
+			return err
+		}
+	}
 	if p.CreatedAt.IsZero() {
 		p.CreatedAt = time.Now()
 	}
@@ -51,12 +166,58 @@ func (p *Patient) GetAge() int {
 	return years
 }
 
+// IsDeleted reports whether the patient has been soft deleted
+func (p *Patient) IsDeleted() bool {
+	return p.DeletedAt.Valid
+}
+
+// IsMerged reports whether the chart was merged into another as a duplicate
+func (p *Patient) IsMerged() bool {
+	return p.MergedIntoID != nil
+}
+
+// PurgeEligibleAt returns when a soft-deleted patient may be purged
+func (p *Patient) PurgeEligibleAt(retention time.Duration) time.Time {
+	return p.DeletedAt.Time.Add(retention)
+}
+
+// CanPurge reports whether the patient is soft deleted and past retention
+func (p *Patient) CanPurge(retention time.Duration, now time.Time) bool {
+	return p.IsDeleted() && !now.Before(p.PurgeEligibleAt(retention))
+}
+
+// IsEmployee reports whether the patient is linked to a staff user account.
+func (p *Patient) IsEmployee() bool {
+	return p.EmployeeUserID != nil
+}
+
+// IsConfidential reports whether the chart needs heightened monitoring.
+// Employee-patients are always treated as confidential.
+func (p *Patient) IsConfidential() bool {
+	return p.Confidential || p.IsEmployee()
+}
+
+// MaskConfidential returns a copy that only reveals that a confidential
+// patient exists, for callers outside the care team.
+func (p *Patient) MaskConfidential() *Patient {
+	return &Patient{
+		ID:           p.ID,
+		PublicID:     p.PublicID,
+		FirstName:    "[CONFIDENTIAL]",
+		LastName:     "[CONFIDENTIAL]",
+		Confidential: true,
+	}
+}
+
 func (p *Patient) SanitizeForRole(role UserRole) *Patient {
 	sanitized := *p
 
 	switch role {
-	case RoleNurse:
+	case RoleNurse, RoleBilling:
+		sanitized.SSN = MaskLast4(p.SSN)
+	case RoleFrontDesk:
 		sanitized.SSN = ""
+		sanitized.MedicalRecords = nil
 	case RoleAdmin:
 		return nil
 	}
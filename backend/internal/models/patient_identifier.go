@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"healthsecure/internal/encryption"
+
+	"gorm.io/gorm"
+)
+
+const blindIndexIdentifier = "patient.identifier"
+
+// MRNSystemPrefix namespaces the MRNs this system issues, one system per
+// facility
+const MRNSystemPrefix = "urn:healthsecure:mrn:"
+
+type IdentifierType string
+
+const (
+	IdentifierMRN             IdentifierType = "mrn"
+	IdentifierExternalMRN     IdentifierType = "external_mrn"
+	IdentifierInsuranceMember IdentifierType = "insurance_member"
+	IdentifierOther           IdentifierType = "other"
+)
+
+// IsValid reports whether the identifier type is known
+func (t IdentifierType) IsValid() bool {
+	switch t {
+	case IdentifierMRN, IdentifierExternalMRN, IdentifierInsuranceMember, IdentifierOther:
+		return true
+	}
+	return false
+}
+
+// PatientIdentifier is an identifier a patient is known by: an MRN issued by
+// one of our facilities, another hospital's MRN or an insurance member ID.
+// The system, usually a URI, names the issuer and is unique together with
+// the value.
+type PatientIdentifier struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	PatientID  uint           `json:"patient_id" gorm:"not null;index"`
+	Type       IdentifierType `json:"type" gorm:"size:32;not null"`
+	System     string         `json:"system" gorm:"column:identifier_system;size:255;not null;uniqueIndex:idx_identifier_value"`
+	Value      string         `json:"value" gorm:"size:255;not null;serializer:encrypted"`
+	ValueIndex string         `json:"-" gorm:"size:64;not null;uniqueIndex:idx_identifier_value"`
+	CreatedBy  uint           `json:"created_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// BeforeSave keeps the blind index in step with the encrypted value
+func (pi *PatientIdentifier) BeforeSave(tx *gorm.DB) (err error) {
+	pi.ValueIndex, err = PatientIdentifierIndex(pi.System, pi.Value)
+	return err
+}
+
+func (pi *PatientIdentifier) BeforeCreate(tx *gorm.DB) (err error) {
+	if pi.CreatedAt.IsZero() {
+		pi.CreatedAt = time.Now()
+	}
+	return
+}
+
+func (pi *PatientIdentifier) TableName() string {
+	return "patient_identifiers"
+}
+
+// PatientIdentifierIndex returns the blind index for an identifier value
+// within its system. Values are compared ignoring case and surrounding
+// spaces.
+func PatientIdentifierIndex(system, value string) (string, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return "", nil
+	}
+	return encryption.BlindIndex(blindIndexIdentifier, strings.TrimSpace(system)+"|"+value)
+}
+
+// MRNSystem returns the identifier system for MRNs issued by a facility
+func MRNSystem(facility string) string {
+	return MRNSystemPrefix + strings.ToLower(facility)
+}
+
+// MRNSequence holds the next MRN sequence number for a facility
+type MRNSequence struct {
+	Facility  string `json:"facility" gorm:"primaryKey;size:16"`
+	NextValue uint64 `json:"next_value" gorm:"not null"`
+}
+
+func (ms *MRNSequence) TableName() string {
+	return "mrn_sequences"
+}
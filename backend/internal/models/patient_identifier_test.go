@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"healthsecure/internal/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatientIdentifierIndex(t *testing.T) {
+	previous := encryption.Default()
+	encryption.SetDefault(encryption.NewStaticKeyring("default", map[int][]byte{1: make([]byte, 32)}, 1, make([]byte, 32)))
+	defer encryption.SetDefault(previous)
+
+	index, err := PatientIdentifierIndex("urn:oid:2.16.840.1.113883.4.1", " abc123 ")
+	require.NoError(t, err)
+	assert.NotEmpty(t, index)
+	assert.Equal(t, index, mustIndex(t, func(v string) (string, error) {
+		return PatientIdentifierIndex("urn:oid:2.16.840.1.113883.4.1", v)
+	}, "ABC123"))
+
+	other, _ := PatientIdentifierIndex("https://payer.example/members", "ABC123")
+	assert.NotEqual(t, index, other, "the same value in another system is a different identifier")
+
+	empty, _ := PatientIdentifierIndex("https://payer.example/members", "  ")
+	assert.Empty(t, empty)
+}
+
+func TestPatientPublicID(t *testing.T) {
+	a, err := NewPatientPublicID()
+	require.NoError(t, err)
+	b, _ := NewPatientPublicID()
+
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, 29)
+	assert.True(t, IsPatientPublicID(a))
+	assert.Regexp(t, `^pt_[a-z2-7]{26}$`, a)
+	assert.False(t, IsPatientPublicID("42"))
+}
+
+func TestMRNSystem(t *testing.T) {
+	assert.Equal(t, "urn:healthsecure:mrn:main", MRNSystem("MAIN"))
+	assert.True(t, IdentifierInsuranceMember.IsValid())
+	assert.False(t, IdentifierType("ssn").IsValid())
+}
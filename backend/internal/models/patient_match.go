@@ -0,0 +1,249 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Agreement levels reported for each compared field
+const (
+	MatchExact      = "exact"
+	MatchSimilar    = "similar"
+	MatchTransposed = "transposed"
+	MatchDisagree   = "disagree"
+)
+
+// Field weights for duplicate scoring. Agreement on a field adds its weight,
+// partial agreement part of it, and clear disagreement on a name or date of
+// birth subtracts, so that twins and parent and child sharing a name, phone
+// and address are not reported as one person.
+const (
+	weightLastName      = 25
+	weightFirstName     = 20
+	weightDateOfBirth   = 30
+	weightPhone         = 15
+	weightAddress       = 10
+	penaltyFirstName    = 20
+	penaltyDateOfBirth  = 15
+	similarNameScore    = 0.92
+	differentNameScore  = 0.75
+	similarAddressScore = 0.6
+)
+
+// PatientMatch scores how likely two charts belong to the same person, from
+// 0 to 100, with the agreement level of each field that was compared
+type PatientMatch struct {
+	PatientID uint              `json:"patient_id"`
+	Score     int               `json:"score"`
+	Fields    map[string]string `json:"fields"`
+}
+
+// MatchPatients compares the demographics of two patients. Fields missing on
+// either side count neither for nor against a match.
+func MatchPatients(a, b *Patient) PatientMatch {
+	match := PatientMatch{PatientID: b.ID, Fields: make(map[string]string)}
+	score := 0
+
+	lastA, lastB := normalizeName(a.LastName), normalizeName(b.LastName)
+	if lastA != "" && lastB != "" {
+		switch similarity := jaroWinkler(lastA, lastB); {
+		case lastA == lastB:
+			score += weightLastName
+			match.Fields["last_name"] = MatchExact
+		case similarity >= similarNameScore:
+			score += weightLastName * 3 / 4
+			match.Fields["last_name"] = MatchSimilar
+		default:
+			match.Fields["last_name"] = MatchDisagree
+		}
+	}
+
+	firstA, firstB := normalizeName(a.FirstName), normalizeName(b.FirstName)
+	if firstA != "" && firstB != "" {
+		switch similarity := jaroWinkler(firstA, firstB); {
+		case firstA == firstB:
+			score += weightFirstName
+			match.Fields["first_name"] = MatchExact
+		case similarity >= similarNameScore:
+			score += weightFirstName * 3 / 4
+			match.Fields["first_name"] = MatchSimilar
+		case similarity < differentNameScore:
+			score -= penaltyFirstName
+			match.Fields["first_name"] = MatchDisagree
+		default:
+			match.Fields["first_name"] = MatchDisagree
+		}
+	}
+
+	if !a.DateOfBirth.IsZero() && !b.DateOfBirth.IsZero() {
+		yearA, monthA, dayA := a.DateOfBirth.Date()
+		yearB, monthB, dayB := b.DateOfBirth.Date()
+		same := 0
+		if yearA == yearB {
+			same++
+		}
+		if monthA == monthB {
+			same++
+		}
+		if dayA == dayB {
+			same++
+		}
+		switch {
+		case same == 3:
+			score += weightDateOfBirth
+			match.Fields["date_of_birth"] = MatchExact
+		case yearA == yearB && int(monthA) == dayB && dayA == int(monthB):
+			score += weightDateOfBirth * 2 / 3
+			match.Fields["date_of_birth"] = MatchTransposed
+		case same == 2:
+			score += weightDateOfBirth * 2 / 5
+			match.Fields["date_of_birth"] = MatchSimilar
+		default:
+			score -= penaltyDateOfBirth
+			match.Fields["date_of_birth"] = MatchDisagree
+		}
+	}
+
+	phoneA, phoneB := lastDigits(a.Phone, 10), lastDigits(b.Phone, 10)
+	if phoneA != "" && phoneB != "" {
+		if phoneA == phoneB {
+			score += weightPhone
+			match.Fields["phone"] = MatchExact
+		} else {
+			match.Fields["phone"] = MatchDisagree
+		}
+	}
+
+	addressA, addressB := addressTokens(a.Address), addressTokens(b.Address)
+	if len(addressA) > 0 && len(addressB) > 0 {
+		switch overlap := jaccard(addressA, addressB); {
+		case overlap == 1:
+			score += weightAddress
+			match.Fields["address"] = MatchExact
+		case overlap >= similarAddressScore:
+			score += weightAddress * 3 / 5
+			match.Fields["address"] = MatchSimilar
+		default:
+			match.Fields["address"] = MatchDisagree
+		}
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	match.Score = score
+	return match
+}
+
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func lastDigits(value string, n int) string {
+	digits := digitsOnly(value)
+	if len(digits) > n {
+		digits = digits[len(digits)-n:]
+	}
+	return digits
+}
+
+// addressAbbreviations folds common street suffixes so that "Main Street"
+// and "Main St" agree
+var addressAbbreviations = map[string]string{
+	"street": "st", "avenue": "ave", "road": "rd", "drive": "dr", "lane": "ln",
+	"boulevard": "blvd", "court": "ct", "place": "pl", "apartment": "apt",
+	"suite": "ste", "north": "n", "south": "s", "east": "e", "west": "w",
+}
+
+func addressTokens(address string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, token := range strings.FieldsFunc(strings.ToLower(address), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if short, ok := addressAbbreviations[token]; ok {
+			token = short
+		}
+		tokens[token] = true
+	}
+	return tokens
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+	for token := range a {
+		if b[token] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings, from 0 for
+// nothing in common to 1 for equal strings
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := len(ra)
+	if len(rb) > window {
+		window = len(rb)
+	}
+	window = window/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		start, end := i-window, i+window+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(rb) {
+			end = len(rb)
+		}
+		for j := start; j < end; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < 4 && prefix < len(ra) && prefix < len(rb) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func matchTestPatient() *Patient {
+	return &Patient{
+		ID:          1,
+		FirstName:   "Katherine",
+		LastName:    "Johnson",
+		DateOfBirth: time.Date(1985, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Phone:       "+1 (555) 010-0123",
+		Address:     "123 Main Street, Anytown",
+	}
+}
+
+func TestMatchPatientsSamePerson(t *testing.T) {
+	a := matchTestPatient()
+	b := &Patient{
+		ID:          2,
+		FirstName:   "KATHERINE",
+		LastName:    "Jonson",
+		DateOfBirth: a.DateOfBirth,
+		Phone:       "555-010-0123",
+		Address:     "123 Main St. Anytown",
+	}
+
+	match := MatchPatients(a, b)
+	assert.Equal(t, uint(2), match.PatientID)
+	assert.GreaterOrEqual(t, match.Score, 85)
+	assert.Equal(t, MatchExact, match.Fields["first_name"])
+	assert.Equal(t, MatchSimilar, match.Fields["last_name"])
+	assert.Equal(t, MatchExact, match.Fields["phone"])
+	assert.Equal(t, MatchExact, match.Fields["address"])
+}
+
+func TestMatchPatientsIdentical(t *testing.T) {
+	a := matchTestPatient()
+	assert.Equal(t, 100, MatchPatients(a, a).Score)
+}
+
+func TestMatchPatientsTransposedBirthDate(t *testing.T) {
+	a := matchTestPatient()
+	b := matchTestPatient()
+	b.DateOfBirth = time.Date(1985, time.April, 3, 0, 0, 0, 0, time.UTC)
+	b.Phone, b.Address = "", ""
+
+	match := MatchPatients(a, b)
+	assert.Equal(t, MatchTransposed, match.Fields["date_of_birth"])
+	assert.GreaterOrEqual(t, match.Score, 60)
+	assert.NotContains(t, match.Fields, "phone", "missing fields are not compared")
+}
+
+func TestMatchPatientsTwinsAreNotDuplicates(t *testing.T) {
+	a := matchTestPatient()
+	b := matchTestPatient()
+	b.FirstName = "Robert"
+
+	match := MatchPatients(a, b)
+	assert.Equal(t, MatchDisagree, match.Fields["first_name"])
+	assert.Less(t, match.Score, 70)
+}
+
+func TestMatchPatientsParentAndChild(t *testing.T) {
+	a := matchTestPatient()
+	b := matchTestPatient()
+	b.DateOfBirth = time.Date(1958, time.October, 21, 0, 0, 0, 0, time.UTC)
+
+	match := MatchPatients(a, b)
+	assert.Equal(t, MatchDisagree, match.Fields["date_of_birth"])
+	assert.Less(t, match.Score, 70)
+}
+
+func TestMatchPatientsUnrelated(t *testing.T) {
+	b := &Patient{
+		FirstName:   "Luis",
+		LastName:    "Garcia",
+		DateOfBirth: time.Date(1990, time.July, 22, 0, 0, 0, 0, time.UTC),
+		Phone:       "+1-555-0999",
+	}
+	assert.Equal(t, 0, MatchPatients(matchTestPatient(), b).Score)
+}
+
+func TestJaroWinkler(t *testing.T) {
+	assert.InDelta(t, 0.961, jaroWinkler("martha", "marhta"), 0.001)
+	assert.InDelta(t, 0.840, jaroWinkler("dwayne", "duane"), 0.001)
+	assert.Equal(t, 1.0, jaroWinkler("smith", "smith"))
+	assert.Equal(t, 0.0, jaroWinkler("abc", "xyz"))
+}
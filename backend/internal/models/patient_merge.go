@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MergeStatus string
+
+const (
+	MergeStatusMerged MergeStatus = "merged"
+	MergeStatusUndone MergeStatus = "undone"
+)
+
+// PatientMerge folds a duplicate chart into the surviving chart. The
+// duplicate is soft deleted and points at the survivor; every row moved to
+// the survivor is listed in PatientMergeRow so that the merge can be undone
+// exactly.
+type PatientMerge struct {
+	ID         uint        `json:"id" gorm:"primaryKey"`
+	SurvivorID uint        `json:"survivor_id" gorm:"not null;index"`
+	MergedID   uint        `json:"merged_id" gorm:"not null;index"`
+	Score      int         `json:"score"`
+	Reason     string      `json:"reason" gorm:"type:text;not null"`
+	Status     MergeStatus `json:"status" gorm:"default:'merged';index"`
+	RowsMoved  int         `json:"rows_moved"`
+	MergedBy   uint        `json:"merged_by" gorm:"not null;index"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UndoneBy   *uint       `json:"undone_by,omitempty"`
+	UndoneAt   *time.Time  `json:"undone_at,omitempty"`
+	UndoReason string      `json:"undo_reason,omitempty" gorm:"type:text"`
+}
+
+func (pm *PatientMerge) BeforeCreate(tx *gorm.DB) (err error) {
+	if pm.CreatedAt.IsZero() {
+		pm.CreatedAt = time.Now()
+	}
+	if pm.Status == "" {
+		pm.Status = MergeStatusMerged
+	}
+	return
+}
+
+func (pm *PatientMerge) TableName() string {
+	return "patient_merges"
+}
+
+func (pm *PatientMerge) IsUndone() bool {
+	return pm.Status == MergeStatusUndone
+}
+
+// Undo records that the merge has been reversed
+func (pm *PatientMerge) Undo(undoneByUserID uint, reason string) error {
+	if pm.IsUndone() {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	pm.Status = MergeStatusUndone
+	pm.UndoneBy = &undoneByUserID
+	pm.UndoneAt = &now
+	pm.UndoReason = reason
+	return nil
+}
+
+// PatientMergeRow is one row moved from the duplicate to the survivor
+type PatientMergeRow struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	MergeID uint   `json:"merge_id" gorm:"not null;index:idx_merge_row"`
+	Table   string `json:"table" gorm:"column:source_table;size:64;not null;index:idx_merge_row"`
+	RowID   uint   `json:"row_id" gorm:"not null"`
+}
+
+func (r *PatientMergeRow) TableName() string {
+	return "patient_merge_rows"
+}
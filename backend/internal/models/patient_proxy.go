@@ -0,0 +1,166 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ProxyRelationship string
+
+const (
+	ProxyParent          ProxyRelationship = "parent"
+	ProxyGuardian        ProxyRelationship = "guardian"
+	ProxyCaregiver       ProxyRelationship = "caregiver"
+	ProxyHealthcareAgent ProxyRelationship = "healthcare_agent"
+)
+
+// IsValid reports whether the relationship is known
+func (r ProxyRelationship) IsValid() bool {
+	switch r {
+	case ProxyParent, ProxyGuardian, ProxyCaregiver, ProxyHealthcareAgent:
+		return true
+	}
+	return false
+}
+
+// ForMinors reports whether the relationship is one to a minor, whose
+// access narrows and then ends as the patient grows up
+func (r ProxyRelationship) ForMinors() bool {
+	return r == ProxyParent || r == ProxyGuardian
+}
+
+// ProxyScope is how much of a patient's portal a proxy sees. A full proxy
+// sees everything the patient would except the access log; a limited proxy
+// sees demographics only. Medication orders are not limited because they
+// carry no sensitivity of their own, and a prescription can reveal what an
+// adolescent shared in confidence.
+type ProxyScope string
+
+const (
+	ProxyScopeNone    ProxyScope = ""
+	ProxyScopeLimited ProxyScope = "limited"
+	ProxyScopeFull    ProxyScope = "full"
+)
+
+// Portal sections a proxy scope may cover
+const (
+	PortalSectionDemographics = "demographics"
+	PortalSectionRecords      = "records"
+	PortalSectionMedications  = "medications"
+	PortalSectionResults      = "results"
+)
+
+func (s ProxyScope) IsValid() bool {
+	return s == ProxyScopeLimited || s == ProxyScopeFull
+}
+
+// Allows reports whether the scope covers a portal section
+func (s ProxyScope) Allows(section string) bool {
+	switch s {
+	case ProxyScopeFull:
+		return section == PortalSectionDemographics || section == PortalSectionRecords ||
+			section == PortalSectionMedications || section == PortalSectionResults
+	case ProxyScopeLimited:
+		return section == PortalSectionDemographics
+	}
+	return false
+}
+
+// narrower returns the narrower of two scopes
+func (s ProxyScope) narrower(other ProxyScope) ProxyScope {
+	if s == ProxyScopeNone || other == ProxyScopeNone {
+		return ProxyScopeNone
+	}
+	if s == ProxyScopeLimited || other == ProxyScopeLimited {
+		return ProxyScopeLimited
+	}
+	return ProxyScopeFull
+}
+
+// ProxyAgeRules are the ages at which parent and guardian access narrows to
+// limited and then ends
+type ProxyAgeRules struct {
+	RestrictedAge int
+	AdultAge      int
+}
+
+// PatientProxy lets a portal account view another patient's chart, such as
+// a parent for a child or a caregiver for an elderly patient. The grant runs
+// from StartsAt until EndsAt, if set, or until it is revoked.
+type PatientProxy struct {
+	ID           uint              `json:"id" gorm:"primaryKey"`
+	ProxyUserID  uint              `json:"proxy_user_id" gorm:"not null;index"`
+	PatientID    uint              `json:"patient_id" gorm:"not null;index"`
+	Relationship ProxyRelationship `json:"relationship" gorm:"type:enum('parent','guardian','caregiver','healthcare_agent');not null"`
+	Scope        ProxyScope        `json:"scope" gorm:"type:enum('limited','full');not null"`
+	StartsAt     time.Time         `json:"starts_at" gorm:"not null"`
+	EndsAt       *time.Time        `json:"ends_at,omitempty" gorm:"index"`
+	CreatedBy    uint              `json:"created_by" gorm:"not null"`
+	RevokedBy    *uint             `json:"revoked_by,omitempty"`
+	RevokedAt    *time.Time        `json:"revoked_at,omitempty"`
+	RevokeReason string            `json:"revoke_reason,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time         `json:"created_at"`
+
+	// EffectiveScope is the scope after age rules, filled in when listed
+	EffectiveScope ProxyScope `json:"effective_scope" gorm:"-"`
+
+	ProxyUser User `json:"proxy_user,omitempty" gorm:"foreignKey:ProxyUserID"`
+}
+
+func (pp *PatientProxy) BeforeCreate(tx *gorm.DB) (err error) {
+	if pp.CreatedAt.IsZero() {
+		pp.CreatedAt = time.Now()
+	}
+	if pp.StartsAt.IsZero() {
+		pp.StartsAt = pp.CreatedAt
+	}
+	return
+}
+
+func (pp *PatientProxy) TableName() string {
+	return "patient_proxies"
+}
+
+// IsActiveAt reports whether the grant is unrevoked and inside its window
+func (pp *PatientProxy) IsActiveAt(t time.Time) bool {
+	return pp.RevokedAt == nil &&
+		!pp.StartsAt.After(t) &&
+		(pp.EndsAt == nil || pp.EndsAt.After(t))
+}
+
+// EffectiveScopeAt returns the scope the proxy has at time t for a patient
+// born on dateOfBirth. Parents and guardians drop to limited access at the
+// restricted age and lose access at the adult age.
+func (pp *PatientProxy) EffectiveScopeAt(t, dateOfBirth time.Time, rules ProxyAgeRules) ProxyScope {
+	if !pp.IsActiveAt(t) {
+		return ProxyScopeNone
+	}
+	if !pp.Relationship.ForMinors() {
+		return pp.Scope
+	}
+
+	age := AgeOn(dateOfBirth, t)
+	switch {
+	case age >= rules.AdultAge:
+		return ProxyScopeNone
+	case age >= rules.RestrictedAge:
+		return pp.Scope.narrower(ProxyScopeLimited)
+	}
+	return pp.Scope
+}
+
+// AuditReason identifies the grant in audit entries for access made through it
+func (pp *PatientProxy) AuditReason() string {
+	return fmt.Sprintf("proxy_access:%s:grant_%d", pp.Relationship, pp.ID)
+}
+
+// AgeOn returns a person's age in whole years on day t
+func AgeOn(dateOfBirth, t time.Time) int {
+	age := t.Year() - dateOfBirth.Year()
+	if t.Month() < dateOfBirth.Month() || (t.Month() == dateOfBirth.Month() && t.Day() < dateOfBirth.Day()) {
+		age--
+	}
+	return age
+}
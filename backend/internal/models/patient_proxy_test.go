@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatientProxy(t *testing.T) {
+	rules := ProxyAgeRules{RestrictedAge: 13, AdultAge: 18}
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	bornAt := func(years int) time.Time { return now.AddDate(-years, 0, 0) }
+
+	t.Run("ParentAccessNarrowsWithAge", func(t *testing.T) {
+		proxy := &PatientProxy{Relationship: ProxyParent, Scope: ProxyScopeFull, StartsAt: now.AddDate(-1, 0, 0)}
+
+		assert.Equal(t, ProxyScopeFull, proxy.EffectiveScopeAt(now, bornAt(12), rules))
+		assert.Equal(t, ProxyScopeLimited, proxy.EffectiveScopeAt(now, bornAt(13), rules))
+		assert.Equal(t, ProxyScopeNone, proxy.EffectiveScopeAt(now, bornAt(18), rules))
+		assert.Equal(t, ProxyScopeLimited, proxy.EffectiveScopeAt(now, bornAt(18).AddDate(0, 0, 1), rules), "the day before the eighteenth birthday")
+	})
+
+	t.Run("CaregiverHasNoAgeRules", func(t *testing.T) {
+		proxy := &PatientProxy{Relationship: ProxyCaregiver, Scope: ProxyScopeFull, StartsAt: now.AddDate(-1, 0, 0)}
+		assert.Equal(t, ProxyScopeFull, proxy.EffectiveScopeAt(now, bornAt(84), rules))
+	})
+
+	t.Run("InactiveOutsideWindowOrRevoked", func(t *testing.T) {
+		ended := now.Add(-time.Hour)
+		expired := &PatientProxy{Relationship: ProxyCaregiver, Scope: ProxyScopeFull, StartsAt: now.AddDate(-1, 0, 0), EndsAt: &ended}
+		assert.Equal(t, ProxyScopeNone, expired.EffectiveScopeAt(now, bornAt(80), rules))
+
+		future := &PatientProxy{Relationship: ProxyCaregiver, Scope: ProxyScopeFull, StartsAt: now.Add(time.Hour)}
+		assert.False(t, future.IsActiveAt(now))
+
+		revoked := &PatientProxy{Relationship: ProxyCaregiver, Scope: ProxyScopeFull, StartsAt: now.AddDate(-1, 0, 0), RevokedAt: &ended}
+		assert.False(t, revoked.IsActiveAt(now))
+	})
+
+	t.Run("LimitedScopeSections", func(t *testing.T) {
+		assert.True(t, ProxyScopeLimited.Allows(PortalSectionDemographics))
+		assert.False(t, ProxyScopeLimited.Allows(PortalSectionMedications), "orders may reveal confidential care")
+		assert.False(t, ProxyScopeLimited.Allows(PortalSectionRecords))
+		assert.True(t, ProxyScopeFull.Allows(PortalSectionResults))
+		assert.False(t, ProxyScopeNone.Allows(PortalSectionDemographics))
+	})
+}
+
+func TestAgeOn(t *testing.T) {
+	dob := time.Date(2010, 3, 15, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 15, AgeOn(dob, time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 16, AgeOn(dob, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)))
+}
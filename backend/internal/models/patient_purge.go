@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PurgeStatus string
+
+const (
+	PurgeStatusPending   PurgeStatus = "pending"
+	PurgeStatusCompleted PurgeStatus = "completed"
+	PurgeStatusRejected  PurgeStatus = "rejected"
+	PurgeStatusCancelled PurgeStatus = "cancelled"
+)
+
+// PatientPurgeRequest asks for a soft-deleted patient to be removed for good.
+// A second admin must approve it, and only once the retention period since
+// deletion has passed. The request outlives the patient it names.
+type PatientPurgeRequest struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	PatientID   uint        `json:"patient_id" gorm:"not null;index"`
+	Reason      string      `json:"reason" gorm:"type:text;not null"`
+	Status      PurgeStatus `json:"status" gorm:"default:'pending';index"`
+	RequestedBy uint        `json:"requested_by" gorm:"not null;index"`
+	ReviewedBy  *uint       `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time  `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+
+	Requester User  `json:"requester,omitempty" gorm:"foreignKey:RequestedBy"`
+	Reviewer  *User `json:"reviewer,omitempty" gorm:"foreignKey:ReviewedBy"`
+}
+
+func (pr *PatientPurgeRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	if pr.CreatedAt.IsZero() {
+		pr.CreatedAt = time.Now()
+	}
+	if pr.Status == "" {
+		pr.Status = PurgeStatusPending
+	}
+	return
+}
+
+func (pr *PatientPurgeRequest) IsPending() bool {
+	return pr.Status == PurgeStatusPending
+}
+
+// Approve records the second admin's sign-off. The requester cannot approve
+// their own request.
+func (pr *PatientPurgeRequest) Approve(reviewedByUserID uint) error {
+	if !pr.IsPending() || reviewedByUserID == pr.RequestedBy {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	pr.Status = PurgeStatusCompleted
+	pr.ReviewedBy = &reviewedByUserID
+	pr.ReviewedAt = &now
+	return nil
+}
+
+func (pr *PatientPurgeRequest) Reject(reviewedByUserID uint) error {
+	if !pr.IsPending() {
+		return gorm.ErrInvalidValue
+	}
+
+	now := time.Now()
+	pr.Status = PurgeStatusRejected
+	pr.ReviewedBy = &reviewedByUserID
+	pr.ReviewedAt = &now
+	return nil
+}
+
+func (pr *PatientPurgeRequest) TableName() string {
+	return "patient_purge_requests"
+}
+
+type PatientPurgeRequestFilter struct {
+	PatientID *uint
+	Status    *PurgeStatus
+	Limit     int
+	Offset    int
+}
+
+func (f *PatientPurgeRequestFilter) Apply(db *gorm.DB) *gorm.DB {
+	query := db
+
+	if f.PatientID != nil {
+		query = query.Where("patient_id = ?", *f.PatientID)
+	}
+	if f.Status != nil {
+		query = query.Where("status = ?", *f.Status)
+	}
+
+	query = query.Order("created_at DESC")
+
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		query = query.Offset(f.Offset)
+	}
+
+	return query
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPatientPurge(t *testing.T) {
+	retention := 24 * time.Hour
+
+	t.Run("OnlyDeletedPatientsPastRetention", func(t *testing.T) {
+		now := time.Now()
+		active := &Patient{}
+		recent := &Patient{DeletedAt: gorm.DeletedAt{Time: now.Add(-time.Hour), Valid: true}}
+		old := &Patient{DeletedAt: gorm.DeletedAt{Time: now.Add(-48 * time.Hour), Valid: true}}
+
+		assert.False(t, active.CanPurge(retention, now))
+		assert.False(t, recent.CanPurge(retention, now))
+		assert.True(t, old.CanPurge(retention, now))
+		assert.Equal(t, recent.DeletedAt.Time.Add(retention), recent.PurgeEligibleAt(retention))
+	})
+
+	t.Run("RequesterCannotApprove", func(t *testing.T) {
+		request := &PatientPurgeRequest{Status: PurgeStatusPending, RequestedBy: 1}
+
+		assert.Error(t, request.Approve(1))
+		assert.True(t, request.IsPending())
+
+		assert.NoError(t, request.Approve(2))
+		assert.Equal(t, PurgeStatusCompleted, request.Status)
+		assert.Equal(t, uint(2), *request.ReviewedBy)
+	})
+
+	t.Run("OnlyPendingRequestsCanBeReviewed", func(t *testing.T) {
+		request := &PatientPurgeRequest{Status: PurgeStatusCancelled, RequestedBy: 1}
+
+		assert.Error(t, request.Approve(2))
+		assert.Error(t, request.Reject(2))
+	})
+}
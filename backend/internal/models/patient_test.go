@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/internal/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatientConfidentiality(t *testing.T) {
+	t.Run("EmployeeIsConfidential", func(t *testing.T) {
+		staffID := uint(7)
+		regular := &Patient{}
+		vip := &Patient{Confidential: true}
+		employee := &Patient{EmployeeUserID: &staffID}
+
+		assert.False(t, regular.IsConfidential())
+		assert.True(t, vip.IsConfidential())
+		assert.True(t, employee.IsConfidential())
+		assert.True(t, employee.IsEmployee())
+	})
+
+	t.Run("MaskConfidentialHidesDemographics", func(t *testing.T) {
+		patient := &Patient{
+			ID:        4,
+			FirstName: "Jane",
+			LastName:  "Smith",
+			SSN:       "123-45-6789",
+			Phone:     "+1-555-0123",
+		}
+
+		masked := patient.MaskConfidential()
+		assert.Equal(t, uint(4), masked.ID)
+		assert.Equal(t, "[CONFIDENTIAL]", masked.FirstName)
+		assert.Empty(t, masked.SSN)
+		assert.Empty(t, masked.Phone)
+		assert.True(t, masked.Confidential)
+	})
+}
+
+func TestPatientBlindIndexes(t *testing.T) {
+	previous := encryption.Default()
+	encryption.SetDefault(encryption.NewStaticKeyring("default", map[int][]byte{1: make([]byte, 32)}, 1, make([]byte, 32)))
+	defer encryption.SetDefault(previous)
+
+	t.Run("IgnoresFormatting", func(t *testing.T) {
+		formatted, err := PatientSSNIndex("123-45-6789")
+		require.NoError(t, err)
+		plain, _ := PatientSSNIndex("123456789")
+		assert.Equal(t, formatted, plain)
+
+		phone, _ := PatientPhoneIndex("+1 (555) 012-3456")
+		assert.Equal(t, phone, mustIndex(t, PatientPhoneIndex, "15550123456"))
+	})
+
+	t.Run("SameDigitsDifferentFields", func(t *testing.T) {
+		ssn, _ := PatientSSNIndex("5550123")
+		phone, _ := PatientPhoneIndex("5550123")
+		assert.NotEqual(t, ssn, phone)
+	})
+
+	t.Run("DemographicIndexNeedsAllParts", func(t *testing.T) {
+		dob := time.Date(1980, 5, 15, 0, 0, 0, 0, time.UTC)
+
+		full, err := PatientDemographicIndex(dob, " Jane ", "SMITH")
+		require.NoError(t, err)
+		assert.NotEmpty(t, full)
+		same, _ := PatientDemographicIndex(dob, "jane", "smith")
+		assert.Equal(t, full, same)
+
+		missing, _ := PatientDemographicIndex(dob, "Jane", "")
+		assert.Empty(t, missing)
+		missing, _ = PatientDemographicIndex(time.Time{}, "Jane", "Smith")
+		assert.Empty(t, missing)
+	})
+
+	t.Run("ComputeBlindIndexes", func(t *testing.T) {
+		patient := &Patient{FirstName: "Jane", LastName: "Smith", DateOfBirth: time.Date(1980, 5, 15, 0, 0, 0, 0, time.UTC), SSN: "123-45-6789"}
+		require.NoError(t, patient.ComputeBlindIndexes())
+		require.NotNil(t, patient.SSNIndex)
+		assert.Empty(t, patient.PhoneIndex)
+		assert.NotEmpty(t, patient.DemographicIndex)
+
+		patient.SSN = ""
+		require.NoError(t, patient.ComputeBlindIndexes())
+		assert.Nil(t, patient.SSNIndex)
+	})
+}
+
+func mustIndex(t *testing.T, index func(string) (string, error), value string) string {
+	result, err := index(value)
+	require.NoError(t, err)
+	return result
+}
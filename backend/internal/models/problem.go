@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ProblemStatus string
+
+const (
+	ProblemStatusActive   ProblemStatus = "active"
+	ProblemStatusInactive ProblemStatus = "inactive"
+	ProblemStatusResolved ProblemStatus = "resolved"
+)
+
+func IsValidProblemStatus(status ProblemStatus) bool {
+	switch status {
+	case ProblemStatusActive, ProblemStatusInactive, ProblemStatusResolved:
+		return true
+	}
+	return false
+}
+
+// Problem is an entry on the patient's problem list. Deleting a problem marks
+// it entered in error; a condition that has cleared up is resolved instead.
+type Problem struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	PatientID    uint           `json:"patient_id" gorm:"not null;index"`
+	Condition    string         `json:"condition" gorm:"column:condition_text;type:text;not null;serializer:encrypted"`
+	OnsetDate    *time.Time     `json:"onset_date,omitempty"`
+	Status       ProblemStatus  `json:"status" gorm:"type:enum('active','inactive','resolved');default:'active';index"`
+	ResolvedDate *time.Time     `json:"resolved_date,omitempty"`
+	RecordedBy   uint           `json:"recorded_by" gorm:"not null"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (p *Problem) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now()
+	}
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now()
+	}
+	if p.Status == "" {
+		p.Status = ProblemStatusActive
+	}
+	return
+}
+
+func (p *Problem) BeforeUpdate(tx *gorm.DB) (err error) {
+	p.UpdatedAt = time.Now()
+	return
+}
+
+// SetStatus moves the problem to status, stamping or clearing the resolution
+// date to match
+func (p *Problem) SetStatus(status ProblemStatus, at time.Time) error {
+	if !IsValidProblemStatus(status) {
+		return gorm.ErrInvalidValue
+	}
+
+	p.Status = status
+	if status == ProblemStatusResolved {
+		if p.ResolvedDate == nil {
+			p.ResolvedDate = &at
+		}
+	} else {
+		p.ResolvedDate = nil
+	}
+	return nil
+}
+
+func (p *Problem) TableName() string {
+	return "problems"
+}
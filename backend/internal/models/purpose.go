@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PurposeOfUse is the reason a user declares for accessing patient data, as
+// required for HIPAA disclosure accounting.
+type PurposeOfUse string
+
+const (
+	PurposeTreatment      PurposeOfUse = "treatment"
+	PurposePayment        PurposeOfUse = "payment"
+	PurposeOperations     PurposeOfUse = "operations"
+	PurposeResearch       PurposeOfUse = "research"
+	PurposeLegal          PurposeOfUse = "legal"
+	PurposePatientRequest PurposeOfUse = "patient_request"
+)
+
+// purposeFields narrows the field policy for purposes that justify less than
+// the full chart. Purposes and resources missing here are not narrowed; a
+// resource listed with no fields is withheld entirely.
+var purposeFields = map[PurposeOfUse]map[string][]string{
+	PurposePayment: {
+		ResourcePatient: {"id", "first_name", "last_name", "date_of_birth", "ssn", "address",
+			"created_at", "updated_at", "medical_records"},
+		ResourceMedicalRecord: {"id", "patient_id", "doctor_id", "encounter_id", "diagnosis", "diagnoses", "severity",
+			"created_at", "updated_at", "doctor"},
+		ResourceProblem:     {"id", "patient_id", "condition", "onset_date", "status", "resolved_date"},
+		ResourceMedication:  {},
+		ResourceAllergy:     {},
+		ResourceObservation: {},
+		ResourceEncounter: {"id", "patient_id", "class", "status", "admit_at", "discharge_at", "facility",
+			"department", "attending_id"},
+		ResourceAttachment: {},
+	},
+	PurposeOperations: {
+		ResourcePatient: {"id", "first_name", "last_name", "date_of_birth", "confidential",
+			"created_at", "updated_at", "medical_records"},
+		ResourceMedicalRecord: {"id", "patient_id", "doctor_id", "encounter_id", "diagnosis", "diagnoses", "severity",
+			"sensitivity", "created_at", "updated_at", "doctor"},
+		ResourceProblem:     {"id", "patient_id", "condition", "status"},
+		ResourceMedication:  {},
+		ResourceAllergy:     {},
+		ResourceObservation: {"id", "patient_id", "category", "code", "display", "interpretation", "effective_at"},
+		ResourceEncounter: {"id", "patient_id", "class", "status", "admit_at", "discharge_at", "facility",
+			"department", "location", "attending_id"},
+		ResourceAttachment: {"id", "patient_id", "record_id", "category", "content_type", "size", "created_at"},
+	},
+	PurposeResearch: {
+		ResourcePatient:       {"id", "date_of_birth", "medical_records"},
+		ResourceMedicalRecord: {"id", "patient_id", "diagnosis", "diagnoses", "treatment", "medications", "severity", "created_at"},
+		ResourceMedication:    {"id", "patient_id", "drug", "dose", "route", "frequency", "start_date", "stop_date", "status"},
+		ResourceAllergy:       {"id", "patient_id", "substance", "reaction", "severity", "status"},
+		ResourceProblem:       {"id", "patient_id", "condition", "onset_date", "status", "resolved_date"},
+		ResourceObservation: {"id", "patient_id", "category", "code", "display", "value_quantity", "value_string",
+			"unit", "reference_low", "reference_high", "interpretation", "effective_at"},
+		ResourceEncounter:  {"id", "patient_id", "class", "admit_at", "discharge_at", "department"},
+		ResourceAttachment: {},
+		ResourceUser:       {},
+	},
+}
+
+func (p PurposeOfUse) IsValid() bool {
+	switch p {
+	case PurposeTreatment, PurposePayment, PurposeOperations, PurposeResearch, PurposeLegal, PurposePatientRequest:
+		return true
+	}
+	return false
+}
+
+// ParsePurposeOfUse normalises a declared purpose, accepting hyphenated
+// spellings such as "patient-request". An empty value means no purpose was
+// declared and is not an error.
+func ParsePurposeOfUse(value string) (PurposeOfUse, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	purpose := PurposeOfUse(strings.ReplaceAll(strings.ToLower(value), "-", "_"))
+	if !purpose.IsValid() {
+		return "", fmt.Errorf("invalid purpose of use %q", value)
+	}
+	return purpose, nil
+}
+
+// ForPurpose returns the policy narrowed to the fields the purpose justifies.
+// Role rules are never widened by a purpose.
+func (fp FieldPolicy) ForPurpose(purpose PurposeOfUse) FieldPolicy {
+	restrictions, restricted := purposeFields[purpose]
+	if !restricted {
+		return fp
+	}
+
+	narrowed := make(FieldPolicy, len(fp))
+	for resource, roles := range fp {
+		allowed, limitsResource := restrictions[resource]
+		if !limitsResource {
+			narrowed[resource] = roles
+			continue
+		}
+
+		keep := make(map[string]bool, len(allowed))
+		for _, field := range allowed {
+			keep[field] = true
+		}
+
+		narrowed[resource] = make(map[UserRole]map[string]FieldRule, len(roles))
+		for role, rules := range roles {
+			kept := make(map[string]FieldRule)
+			for field, rule := range rules {
+				if keep[field] {
+					kept[field] = rule
+				}
+			}
+			narrowed[resource][role] = kept
+		}
+	}
+
+	return narrowed
+}
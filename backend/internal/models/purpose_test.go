@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePurposeOfUse(t *testing.T) {
+	purpose, err := ParsePurposeOfUse("Patient-Request")
+	assert.NoError(t, err)
+	assert.Equal(t, PurposePatientRequest, purpose)
+
+	purpose, err = ParsePurposeOfUse("")
+	assert.NoError(t, err)
+	assert.Empty(t, purpose)
+
+	_, err = ParsePurposeOfUse("curiosity")
+	assert.Error(t, err)
+}
+
+func TestFieldPolicyForPurpose(t *testing.T) {
+	policy := DefaultFieldPolicy()
+	record := &MedicalRecord{ID: 5, PatientID: 3, Diagnosis: "Hypertension", Treatment: "ACE inhibitor", Notes: "Responding well",
+		Doctor: User{ID: 2, Name: "Dr. Smith"}}
+
+	t.Run("TreatmentIsUnrestricted", func(t *testing.T) {
+		projection, _ := policy.ForPurpose(PurposeTreatment).Project(ResourceMedicalRecord, RoleDoctor, record, nil)
+		assert.Equal(t, "Responding well", projection["notes"])
+	})
+
+	t.Run("PaymentDropsNarrative", func(t *testing.T) {
+		projection, _ := policy.ForPurpose(PurposePayment).Project(ResourceMedicalRecord, RoleDoctor, record, nil)
+		assert.Equal(t, "Hypertension", projection["diagnosis"])
+		assert.NotContains(t, projection, "treatment")
+		assert.NotContains(t, projection, "notes")
+	})
+
+	t.Run("ResearchDropsIdentifiers", func(t *testing.T) {
+		patient := &Patient{ID: 3, FirstName: "John", LastName: "Doe", SSN: "123-45-6789"}
+		projection, disclosed := policy.ForPurpose(PurposeResearch).Project(ResourcePatient, RoleDoctor, patient, nil)
+		assert.NotContains(t, projection, "first_name")
+		assert.NotContains(t, projection, "ssn")
+		assert.Equal(t, []string{"id"}, disclosed)
+
+		recordProjection, _ := policy.ForPurpose(PurposeResearch).Project(ResourceMedicalRecord, RoleDoctor, record, nil)
+		assert.Empty(t, recordProjection["doctor"])
+	})
+
+	t.Run("PurposeNeverWidensRole", func(t *testing.T) {
+		projection, _ := policy.ForPurpose(PurposePayment).Project(ResourceMedicalRecord, RoleBilling, record, nil)
+		assert.NotContains(t, projection, "treatment")
+		assert.Len(t, DefaultFieldPolicy()[ResourceMedicalRecord][RoleDoctor], len(policy[ResourceMedicalRecord][RoleDoctor]))
+	})
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAddendumImmutable is returned when something tries to change a stored
+// addendum.
+var ErrAddendumImmutable = errors.New("addenda are immutable")
+
+// RecordAddendum corrects or supplements a signed medical record without
+// changing it. Addenda are signed by their author when written and can
+// never be edited.
+type RecordAddendum struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	RecordID    uint      `json:"record_id" gorm:"not null;index"`
+	AuthorID    uint      `json:"author_id" gorm:"not null;index"`
+	Content     string    `json:"content" gorm:"type:text;not null;serializer:encrypted"`
+	Reason      string    `json:"reason" gorm:"type:text"`
+	ContentHash string    `json:"content_hash" gorm:"size:64;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	Author User `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+}
+
+func (a *RecordAddendum) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	// The column holds whole seconds, so hash what will be read back
+	a.CreatedAt = a.CreatedAt.Truncate(time.Second)
+	a.ContentHash, err = a.ComputeContentHash()
+	return
+}
+
+func (a *RecordAddendum) BeforeUpdate(tx *gorm.DB) (err error) {
+	return ErrAddendumImmutable
+}
+
+// ComputeContentHash returns the SHA-256 of the addendum bound to its record,
+// author and time of writing.
+func (a *RecordAddendum) ComputeContentHash() (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"record_id":  a.RecordID,
+		"author_id":  a.AuthorID,
+		"content":    a.Content,
+		"reason":     a.Reason,
+		"created_at": a.CreatedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyContentHash reports whether the addendum is unchanged since it was
+// written.
+func (a *RecordAddendum) VerifyContentHash() bool {
+	hash, err := a.ComputeContentHash()
+	return err == nil && hash == a.ContentHash
+}
+
+func (a *RecordAddendum) TableName() string {
+	return "medical_record_addenda"
+}
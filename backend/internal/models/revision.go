@@ -0,0 +1,177 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrRevisionImmutable is returned when something tries to change a stored
+// revision.
+var ErrRevisionImmutable = errors.New("revisions are immutable")
+
+// revisionFields lists, per resource, the JSON fields captured in each
+// revision. Relations and bookkeeping columns are not versioned.
+var revisionFields = map[string][]string{
+	ResourcePatient: {"first_name", "last_name", "date_of_birth", "ssn", "phone", "address",
+		"emergency_contact", "confidential", "employee_user_id"},
+	ResourceMedicalRecord: {"diagnosis", "diagnoses", "treatment", "notes", "template_id", "sections", "medications",
+		"severity", "sensitivity"},
+}
+
+// Revision is an immutable snapshot of a patient or medical record written
+// every time it is created or changed. The snapshot holds PHI and is stored
+// encrypted.
+type Revision struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ResourceType  string    `json:"resource_type" gorm:"size:32;not null;uniqueIndex:idx_revision_version"`
+	ResourceID    uint      `json:"resource_id" gorm:"not null;uniqueIndex:idx_revision_version"`
+	Version       int       `json:"version" gorm:"not null;uniqueIndex:idx_revision_version"`
+	Snapshot      string    `json:"-" gorm:"type:mediumtext;not null;serializer:encrypted"`
+	ChangedFields string    `json:"-" gorm:"type:text"`
+	AuthorID      uint      `json:"author_id" gorm:"not null;index"`
+	Reason        string    `json:"reason" gorm:"type:text"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+
+	Author User `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+}
+
+// FieldChange is one field that differs between two revisions
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// NewRevision snapshots value as the revision following previous, which is
+// nil for the first revision of a resource.
+func NewRevision(resource string, resourceID uint, value interface{}, previous *Revision, authorID uint, reason string) (*Revision, error) {
+	fields, ok := revisionFields[resource]
+	if !ok {
+		return nil, fmt.Errorf("resource %s is not versioned", resource)
+	}
+
+	raw, err := toFieldMap(value)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		snapshot[field] = raw[field]
+	}
+
+	version := 1
+	changed := fields
+	if previous != nil {
+		prior, err := previous.Fields()
+		if err != nil {
+			return nil, err
+		}
+		version = previous.Version + 1
+		changed = nil
+		for _, field := range fields {
+			if !reflect.DeepEqual(prior[field], snapshot[field]) {
+				changed = append(changed, field)
+			}
+		}
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revision{
+		ResourceType:  resource,
+		ResourceID:    resourceID,
+		Version:       version,
+		Snapshot:      string(data),
+		ChangedFields: strings.Join(changed, ","),
+		AuthorID:      authorID,
+		Reason:        reason,
+	}, nil
+}
+
+func (r *Revision) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	return
+}
+
+func (r *Revision) BeforeUpdate(tx *gorm.DB) (err error) {
+	return ErrRevisionImmutable
+}
+
+// Fields decodes the snapshot
+func (r *Revision) Fields() (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(r.Snapshot), &fields); err != nil {
+		return nil, fmt.Errorf("invalid revision snapshot: %w", err)
+	}
+	return fields, nil
+}
+
+// ApplyTo overwrites the versioned fields of target, a *Patient or
+// *MedicalRecord, with the values from this revision. Fields hidden from JSON
+// are reset, so the result is only fit for display.
+func (r *Revision) ApplyTo(target interface{}) error {
+	merged, err := toFieldMap(target)
+	if err != nil {
+		return err
+	}
+	snapshot, err := r.Fields()
+	if err != nil {
+		return err
+	}
+	for field, value := range snapshot {
+		merged[field] = value
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+
+	// Decode into a zero value so that null snapshot values clear the field
+	restored := reflect.New(reflect.TypeOf(target).Elem())
+	if err := json.Unmarshal(data, restored.Interface()); err != nil {
+		return fmt.Errorf("invalid revision snapshot: %w", err)
+	}
+	reflect.ValueOf(target).Elem().Set(restored.Elem())
+	return nil
+}
+
+func (r *Revision) ChangedFieldList() []string {
+	if r.ChangedFields == "" {
+		return []string{}
+	}
+	return strings.Split(r.ChangedFields, ",")
+}
+
+// DiffProjections compares two projected versions of a resource field by
+// field. Fields the caller may not see are absent from both projections and
+// so never appear in the diff.
+func DiffProjections(resource string, from, to Projection) []FieldChange {
+	changes := []FieldChange{}
+	for _, field := range revisionFields[resource] {
+		before, inFrom := from[field]
+		after, inTo := to[field]
+		if !inFrom && !inTo {
+			continue
+		}
+		if !reflect.DeepEqual(before, after) {
+			changes = append(changes, FieldChange{Field: field, From: before, To: after})
+		}
+	}
+	return changes
+}
+
+func (r *Revision) TableName() string {
+	return "revisions"
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevision(t *testing.T) {
+	record := &MedicalRecord{
+		ID:          1,
+		PatientID:   1,
+		Diagnosis:   "Hypertension",
+		Treatment:   "ACE inhibitor therapy",
+		Notes:       "Follow up in two weeks",
+		Severity:    SeverityMedium,
+		Sensitivity: SensitivityNormal,
+	}
+
+	t.Run("VersionsAndChangedFields", func(t *testing.T) {
+		first, err := NewRevision(ResourceMedicalRecord, record.ID, record, nil, 2, "medical_record_created")
+		require.NoError(t, err)
+		assert.Equal(t, 1, first.Version)
+		assert.Contains(t, first.ChangedFieldList(), "diagnosis")
+
+		updated := *record
+		updated.Treatment = "ACE inhibitor therapy, dose increased"
+		second, err := NewRevision(ResourceMedicalRecord, record.ID, &updated, first, 2, "dose change")
+		require.NoError(t, err)
+		assert.Equal(t, 2, second.Version)
+		assert.Equal(t, []string{"treatment"}, second.ChangedFieldList())
+
+		unchanged, err := NewRevision(ResourceMedicalRecord, record.ID, &updated, second, 2, "no-op")
+		require.NoError(t, err)
+		assert.Equal(t, []string{}, unchanged.ChangedFieldList())
+	})
+
+	t.Run("UnversionedResource", func(t *testing.T) {
+		_, err := NewRevision("user", 1, &User{}, nil, 1, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("ApplyToRestoresSnapshot", func(t *testing.T) {
+		revision, err := NewRevision(ResourceMedicalRecord, record.ID, &MedicalRecord{Diagnosis: "Hypertension"}, nil, 2, "")
+		require.NoError(t, err)
+
+		current := *record
+		require.NoError(t, revision.ApplyTo(&current))
+		assert.Equal(t, "Hypertension", current.Diagnosis)
+		assert.Empty(t, current.Treatment)
+		assert.Empty(t, current.Notes)
+		assert.Equal(t, record.ID, current.ID)
+	})
+
+	t.Run("DiffProjections", func(t *testing.T) {
+		from := Projection{"id": 1, "diagnosis": "Hypertension", "treatment": "ACE inhibitor therapy"}
+		to := Projection{"id": 1, "diagnosis": "Hypertension", "treatment": "Beta blocker"}
+
+		changes := DiffProjections(ResourceMedicalRecord, from, to)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "treatment", changes[0].Field)
+		assert.Equal(t, "ACE inhibitor therapy", changes[0].From)
+		assert.Equal(t, "Beta blocker", changes[0].To)
+	})
+
+	t.Run("Immutable", func(t *testing.T) {
+		revision := &Revision{}
+		assert.ErrorIs(t, revision.BeforeUpdate(nil), ErrRevisionImmutable)
+	})
+}
@@ -9,18 +9,27 @@ import (
 type UserRole string
 
 const (
-	RoleDoctor UserRole = "doctor"
-	RoleNurse  UserRole = "nurse"
-	RoleAdmin  UserRole = "admin"
+	RoleDoctor    UserRole = "doctor"
+	RoleNurse     UserRole = "nurse"
+	RoleAdmin     UserRole = "admin"
+	RoleFrontDesk UserRole = "front_desk"
+	RoleBilling   UserRole = "billing"
+	RolePatient   UserRole = "patient"
 )
 
+// StaffRoles are the workforce roles. Patient portal accounts hold none of
+// them and never reach staff endpoints.
+var StaffRoles = []UserRole{RoleDoctor, RoleNurse, RoleAdmin, RoleFrontDesk, RoleBilling}
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Email     string    `json:"email" gorm:"unique;not null;index"`
 	Password  string    `json:"-" gorm:"not null"`
-	Role      UserRole  `json:"role" gorm:"not null;type:enum('doctor','nurse','admin')"`
+	Role      UserRole  `json:"role" gorm:"not null;type:enum('doctor','nurse','admin','front_desk','billing','patient')"`
 	Name      string    `json:"name" gorm:"not null"`
 	Active    bool      `json:"active" gorm:"default:true"`
+	Resident  bool      `json:"resident" gorm:"default:false"`
+	PatientID *uint     `json:"patient_id,omitempty" gorm:"index"`
 	LastLogin time.Time `json:"last_login"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -45,6 +54,17 @@ func (u *User) IsDoctor() bool {
 	return u.Role == RoleDoctor
 }
 
+// IsResident reports whether the user is a doctor in training whose records
+// need an attending's cosignature.
+func (u *User) IsResident() bool {
+	return u.Role == RoleDoctor && u.Resident
+}
+
+// IsAttending reports whether the user is a fully licensed doctor
+func (u *User) IsAttending() bool {
+	return u.Role == RoleDoctor && !u.Resident
+}
+
 func (u *User) IsNurse() bool {
 	return u.Role == RoleNurse
 }
@@ -53,6 +73,19 @@ func (u *User) IsAdmin() bool {
 	return u.Role == RoleAdmin
 }
 
+func (u *User) IsFrontDesk() bool {
+	return u.Role == RoleFrontDesk
+}
+
+func (u *User) IsBilling() bool {
+	return u.Role == RoleBilling
+}
+
+// IsPatient reports whether the account is a patient portal identity
+func (u *User) IsPatient() bool {
+	return u.Role == RolePatient
+}
+
 func (u *User) CanAccessPatientData() bool {
 	return u.Role == RoleDoctor || u.Role == RoleNurse
 }
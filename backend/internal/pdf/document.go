@@ -0,0 +1,272 @@
+// Package pdf writes simple printable PDF documents: headings, wrapped text
+// and fixed-width tables on US Letter pages, using only the standard PDF
+// fonts. It is meant for reports, not for general layout.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pageWidth  = 612.0
+	pageHeight = 792.0
+	margin     = 54.0
+
+	headingSize = 14.0
+	textSize    = 10.0
+	tableSize   = 8.0
+
+	// Courier glyphs are 600 units wide, so table columns line up
+	courierWidth = 0.6
+	// A conservative average Helvetica glyph width for wrapping text
+	helveticaWidth = 0.55
+)
+
+// textWidth is how many characters of body text fit across the page
+var textWidth = charsAcross(textSize, helveticaWidth)
+
+// Font resource names, in the order the fonts are written
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	fontMono    = "F3"
+)
+
+type line struct {
+	font string
+	size float64
+	text string
+}
+
+// Document is a PDF being built up line by line. Lines flow onto a new page
+// when the current one is full.
+type Document struct {
+	title string
+	pages [][]line
+	y     float64
+}
+
+// New starts a document with the given title, which is also printed in each
+// page footer
+func New(title string) *Document {
+	d := &Document{title: title}
+	d.newPage()
+	return d
+}
+
+// Heading adds a bold heading
+func (d *Document) Heading(text string) {
+	d.add(line{font: fontBold, size: headingSize, text: text})
+}
+
+// Text adds a paragraph, wrapped to the page width
+func (d *Document) Text(text string) {
+	for _, wrapped := range wrap(text, textWidth) {
+		d.add(line{font: fontRegular, size: textSize, text: wrapped})
+	}
+}
+
+// Blank adds an empty line
+func (d *Document) Blank() {
+	d.add(line{font: fontRegular, size: textSize})
+}
+
+// Table adds rows in columns of the given widths, in characters. Cells too
+// long for their column wrap onto further lines of the same row; nothing is
+// cut off. The header row is repeated at the top of each new page.
+func (d *Document) Table(headers []string, widths []int, rows [][]string) {
+	header := tableLines(headers, widths)
+	rule := strings.Repeat("-", sum(widths)+len(widths)-1)
+	writeHeader := func() {
+		for _, text := range header {
+			d.add(line{font: fontMono, size: tableSize, text: text})
+		}
+		d.add(line{font: fontMono, size: tableSize, text: rule})
+	}
+
+	writeHeader()
+	for _, row := range rows {
+		lines := tableLines(row, widths)
+		if d.y-float64(len(lines))*leading(tableSize) < margin+leading(textSize) {
+			d.newPage()
+			writeHeader()
+		}
+		for _, text := range lines {
+			d.add(line{font: fontMono, size: tableSize, text: text})
+		}
+	}
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-5 are the catalog, the page tree, the info dictionary and
+	// the fonts; each page then takes two objects, the page and its content
+	const firstPage = 7
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object(fmt.Sprintf("<< /Title (%s) /Producer (HealthSecure) >>", escape(d.title)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		content := d.content(page, i+1)
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s 4 0 R /%s 5 0 R /%s 6 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, fontRegular, fontBold, fontMono, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// content renders the text of one page with its footer
+func (d *Document) content(page []line, number int) string {
+	var b strings.Builder
+	y := pageHeight - margin
+	for _, l := range page {
+		y -= leading(l.size)
+		if l.text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "BT /%s %.0f Tf %.0f %.1f Td (%s) Tj ET\n", l.font, l.size, margin, y, escape(l.text))
+	}
+	footer := fmt.Sprintf("%s - page %d of %d", d.title, number, len(d.pages))
+	fmt.Fprintf(&b, "BT /%s %.0f Tf %.0f %.0f Td (%s) Tj ET", fontRegular, tableSize, margin, margin/2, escape(footer))
+	return b.String()
+}
+
+func (d *Document) add(l line) {
+	if d.y-leading(l.size) < margin {
+		d.newPage()
+	}
+	d.y -= leading(l.size)
+	d.pages[len(d.pages)-1] = append(d.pages[len(d.pages)-1], l)
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, nil)
+	d.y = pageHeight - margin
+}
+
+// charsAcross is how many glyphs of the given size and average width fit
+// between the margins
+func charsAcross(size, glyphWidth float64) int {
+	return int((pageWidth - 2*margin) / (size * glyphWidth))
+}
+
+func leading(size float64) float64 {
+	return size * 1.25
+}
+
+// tableLines lays a row out in fixed-width columns, wrapping long cells
+func tableLines(cells []string, widths []int) []string {
+	wrapped := make([][]string, len(widths))
+	height := 1
+	for i, width := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		wrapped[i] = wrap(cell, width)
+		if len(wrapped[i]) > height {
+			height = len(wrapped[i])
+		}
+	}
+
+	lines := make([]string, height)
+	for row := range lines {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			text := ""
+			if row < len(wrapped[i]) {
+				text = wrapped[i][row]
+			}
+			parts[i] = text + strings.Repeat(" ", width-len([]rune(text)))
+		}
+		lines[row] = strings.TrimRight(strings.Join(parts, " "), " ")
+	}
+	return lines
+}
+
+// wrap breaks text into lines of at most width characters, at spaces where
+// possible
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		current := []rune{}
+		for _, word := range strings.Fields(paragraph) {
+			runes := []rune(word)
+			for len(runes) > width {
+				if len(current) > 0 {
+					lines = append(lines, string(current))
+					current = current[:0]
+				}
+				lines = append(lines, string(runes[:width]))
+				runes = runes[width:]
+			}
+			if len(current) > 0 && len(current)+1+len(runes) > width {
+				lines = append(lines, string(current))
+				current = current[:0]
+			}
+			if len(current) > 0 {
+				current = append(current, ' ')
+			}
+			current = append(current, runes...)
+		}
+		if len(current) > 0 || len(lines) == 0 {
+			lines = append(lines, string(current))
+		}
+	}
+	return lines
+}
+
+// escape encodes text as a PDF string in WinAnsiEncoding. Characters outside
+// Latin-1 are replaced with a question mark.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
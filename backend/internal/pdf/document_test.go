@@ -0,0 +1,62 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentBytes(t *testing.T) {
+	doc := New("Report (draft)")
+	doc.Heading("Accounting of Disclosures")
+	doc.Text("Patient: Zoë Doe")
+	out := doc.Bytes()
+
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, string(out), `/Title (Report \(draft\))`)
+	assert.Contains(t, string(out), `(Patient: Zo\353 Doe)`)
+
+	// Every xref entry points at the start of its object
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	require.NotNil(t, match)
+	xref, _ := strconv.Atoi(string(match[1]))
+	require.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n")))
+
+	entries := strings.Split(string(out[xref:]), "\n")[3:]
+	for i := 1; ; i++ {
+		entry := entries[i-1]
+		if !strings.HasSuffix(entry, " n ") {
+			break
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		assert.True(t, bytes.HasPrefix(out[offset:], []byte(fmt.Sprintf("%d 0 obj", i))), "object %d", i)
+	}
+}
+
+func TestDocumentTableFlowsOntoNewPages(t *testing.T) {
+	doc := New("Report")
+	rows := make([][]string, 200)
+	for i := range rows {
+		rows[i] = []string{strconv.Itoa(i), "a description long enough to wrap onto a second line of its cell"}
+	}
+	doc.Table([]string{"#", "Description"}, []int{4, 40}, rows)
+
+	assert.Greater(t, len(doc.pages), 1)
+	for _, page := range doc.pages[1:] {
+		assert.Equal(t, "#    Description", page[0].text, "each page repeats the header")
+	}
+	assert.Contains(t, string(doc.Bytes()), fmt.Sprintf("page 1 of %d", len(doc.pages)))
+}
+
+func TestWrap(t *testing.T) {
+	assert.Equal(t, []string{"one two", "three"}, wrap("one two three", 8))
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, wrap("abcdefghij", 4))
+	assert.Equal(t, []string{""}, wrap("", 10))
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AmendmentService tracks patients' requests to amend their medical records
+// through review to acceptance or denial.
+type AmendmentService struct {
+	db      *gorm.DB
+	audit   *AuditService
+	records *MedicalRecordService
+}
+
+type SubmitAmendmentRequest struct {
+	RequestedChange string     `json:"requested_change" binding:"required"`
+	Justification   string     `json:"justification"`
+	ReceivedAt      *time.Time `json:"received_at,omitempty"`
+}
+
+type ExtendAmendmentRequest struct {
+	Reason string `json:"reason" binding:"required,min=10"`
+}
+
+type AcceptAmendmentRequest struct {
+	Content string `json:"content,omitempty"`
+}
+
+type DenyAmendmentRequest struct {
+	Reason string `json:"reason" binding:"required,min=10"`
+}
+
+type AmendmentDisagreementRequest struct {
+	Statement string `json:"statement" binding:"required"`
+}
+
+type AmendmentQuery struct {
+	Status  string `form:"status"`
+	Overdue bool   `form:"overdue"`
+	Page    int    `form:"page,default=1"`
+	Limit   int    `form:"limit,default=20"`
+}
+
+// AmendmentSummary describes a request for deadline tracking without its
+// clinical content
+type AmendmentSummary struct {
+	ID         uint                   `json:"id"`
+	RecordID   uint                   `json:"record_id"`
+	PatientID  uint                   `json:"patient_id"`
+	Status     models.AmendmentStatus `json:"status"`
+	ReceivedAt time.Time              `json:"received_at"`
+	DueAt      time.Time              `json:"due_at"`
+	Extended   bool                   `json:"extended"`
+	Overdue    bool                   `json:"overdue"`
+	DecidedAt  *time.Time             `json:"decided_at,omitempty"`
+}
+
+func NewAmendmentService(db *gorm.DB, audit *AuditService, records *MedicalRecordService) *AmendmentService {
+	return &AmendmentService{
+		db:      db,
+		audit:   audit,
+		records: records,
+	}
+}
+
+// SubmitAmendment logs a patient's amendment request against a record. The
+// 60-day deadline runs from when the request was received, which may be
+// earlier than when it is entered.
+func (s *AmendmentService) SubmitAmendment(recordID uint, req *SubmitAmendmentRequest, submittedByUserID uint, submittedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.AmendmentRequest, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if submittedByRole != models.RoleDoctor && submittedByRole != models.RoleNurse {
+		audit.LogUnauthorizedAccess(submittedByUserID, fmt.Sprintf("medical_record:%d:amendment", recordID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to submit amendment request")
+	}
+
+	// The request is logged without reading the record, so staff who may not
+	// see a classified record can still file it
+	var record models.MedicalRecord
+	if err := s.db.Where("id = ?", recordID).First(&record).Error; err != nil {
+		return nil, fmt.Errorf("medical record not found")
+	}
+
+	now := time.Now()
+	amendment := models.AmendmentRequest{
+		RecordID:        record.ID,
+		PatientID:       record.PatientID,
+		RequestedChange: req.RequestedChange,
+		Justification:   req.Justification,
+		SubmittedBy:     submittedByUserID,
+	}
+	if req.ReceivedAt != nil {
+		if req.ReceivedAt.After(now) {
+			return nil, fmt.Errorf("received_at cannot be in the future")
+		}
+		amendment.ReceivedAt = *req.ReceivedAt
+	}
+
+	if err := s.db.Create(&amendment).Error; err != nil {
+		return nil, fmt.Errorf("failed to submit amendment request: %w", err)
+	}
+
+	s.logTransition(&record, &amendment, submittedByUserID, models.ActionCreate, ipAddress, userAgent, "submitted", purpose)
+
+	return &amendment, nil
+}
+
+// GetAmendment returns a request in full to users who may read its record
+func (s *AmendmentService) GetAmendment(amendmentID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*models.AmendmentRequest, error) {
+	amendment, err := s.loadAmendment(amendmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.records.authorizeRecordAccess(amendment.RecordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logTransition(record, amendment, requestedByUserID, models.ActionView, ipAddress, userAgent, "viewed", purpose)
+
+	return amendment, nil
+}
+
+// GetRecordAmendments lists every request made against a record
+func (s *AmendmentService) GetRecordAmendments(recordID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]models.AmendmentRequest, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	record, err := s.records.authorizeRecordAccess(recordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.AmendmentRequestFilter{RecordID: &record.ID}
+
+	var amendments []models.AmendmentRequest
+	if err := filter.Apply(s.db).Find(&amendments).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve amendment requests: %w", err)
+	}
+
+	reason := historyReason(s.records.disclosureReason(record.PatientID, requestedByUserID, emergencyAccess), "amendments_listed")
+	audit.LogMedicalRecordDisclosure(record, requestedByUserID, ipAddress, userAgent, emergencyAccess, reason, []string{"amendment_requests"})
+
+	return amendments, nil
+}
+
+// GetAmendments lists requests for deadline tracking. Doctors see requests
+// against their own records; admins see all of them.
+func (s *AmendmentService) GetAmendments(query *AmendmentQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]AmendmentSummary, int64, error) {
+	filter := &models.AmendmentRequestFilter{}
+	switch requestedByRole {
+	case models.RoleAdmin:
+	case models.RoleDoctor:
+		filter.DoctorID = &requestedByUserID
+	default:
+		s.audit.LogUnauthorizedAccess(requestedByUserID, "amendment_requests", ipAddress, userAgent, "insufficient_role")
+		return nil, 0, fmt.Errorf("insufficient permissions to list amendment requests")
+	}
+
+	if query.Status != "" {
+		status := models.AmendmentStatus(query.Status)
+		filter.Status = &status
+	}
+	now := time.Now()
+	if query.Overdue {
+		filter.OverdueAt = &now
+	}
+
+	var total int64
+	filter.Apply(s.db.Model(&models.AmendmentRequest{})).Count(&total)
+
+	filter.Limit = query.Limit
+	filter.Offset = (query.Page - 1) * query.Limit
+
+	var amendments []models.AmendmentRequest
+	if err := filter.Apply(s.db).Find(&amendments).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve amendment requests: %w", err)
+	}
+
+	summaries := make([]AmendmentSummary, 0, len(amendments))
+	for _, amendment := range amendments {
+		summaries = append(summaries, AmendmentSummary{
+			ID:         amendment.ID,
+			RecordID:   amendment.RecordID,
+			PatientID:  amendment.PatientID,
+			Status:     amendment.Status,
+			ReceivedAt: amendment.ReceivedAt,
+			DueAt:      amendment.DueAt,
+			Extended:   amendment.IsExtended(),
+			Overdue:    amendment.IsOverdue(now),
+			DecidedAt:  amendment.DecidedAt,
+		})
+	}
+
+	return summaries, total, nil
+}
+
+// StartReview moves a submitted request under review by the record's author
+func (s *AmendmentService) StartReview(amendmentID uint, reviewerID uint, reviewerRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.AmendmentRequest, error) {
+	amendment, record, reason, err := s.authorizeDecision(amendmentID, reviewerID, reviewerRole, ipAddress, userAgent, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := amendment.StartReview(reviewerID); err != nil {
+		return nil, fmt.Errorf("amendment request is not awaiting review")
+	}
+	if err := s.db.Save(amendment).Error; err != nil {
+		return nil, fmt.Errorf("failed to update amendment request: %w", err)
+	}
+
+	s.logTransition(record, amendment, reviewerID, models.ActionUpdate, ipAddress, userAgent, "under_review"+reason, purpose)
+
+	return amendment, nil
+}
+
+// ExtendDeadline uses the one 30-day extension the rule allows
+func (s *AmendmentService) ExtendDeadline(amendmentID uint, req *ExtendAmendmentRequest, extendedByUserID uint, extendedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.AmendmentRequest, error) {
+	amendment, record, reason, err := s.authorizeDecision(amendmentID, extendedByUserID, extendedByRole, ipAddress, userAgent, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := amendment.Extend(req.Reason, time.Now()); err != nil {
+		return nil, fmt.Errorf("deadline can be extended only once, before it passes, while the request is open")
+	}
+	if err := s.db.Save(amendment).Error; err != nil {
+		return nil, fmt.Errorf("failed to update amendment request: %w", err)
+	}
+
+	s.logTransition(record, amendment, extendedByUserID, models.ActionUpdate, ipAddress, userAgent, "deadline_extended"+reason, purpose)
+
+	return amendment, nil
+}
+
+// AcceptAmendment appends the amendment to the record as an addendum. The
+// addendum holds the requested change unless the reviewer words it.
+func (s *AmendmentService) AcceptAmendment(amendmentID uint, req *AcceptAmendmentRequest, decidedByUserID uint, decidedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.AmendmentRequest, error) {
+	amendment, record, reason, err := s.authorizeDecision(amendmentID, decidedByUserID, decidedByRole, ipAddress, userAgent, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if amendment.Status != models.AmendmentStatusUnderReview {
+		return nil, fmt.Errorf("amendment request is not under review")
+	}
+	// Addenda correct signed records; drafts are edited directly
+	if !record.IsLocked() {
+		return nil, fmt.Errorf("record is not signed; amend the draft directly")
+	}
+
+	content := req.Content
+	if content == "" {
+		content = amendment.RequestedChange
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the request before adding the addendum, so that concurrent
+		// accepts cannot both append one
+		result := tx.Model(&models.AmendmentRequest{}).
+			Where("id = ? AND status = ?", amendment.ID, models.AmendmentStatusUnderReview).
+			Update("status", models.AmendmentStatusAccepted)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrInvalidValue
+		}
+
+		addendum := models.RecordAddendum{
+			RecordID: record.ID,
+			AuthorID: decidedByUserID,
+			Content:  content,
+			Reason:   fmt.Sprintf("Patient amendment request %d", amendment.ID),
+		}
+		if err := tx.Create(&addendum).Error; err != nil {
+			return err
+		}
+		if err := amendment.Accept(decidedByUserID, addendum.ID); err != nil {
+			return err
+		}
+		return tx.Save(amendment).Error
+	})
+	if err == gorm.ErrInvalidValue {
+		return nil, fmt.Errorf("amendment request is not under review")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept amendment request: %w", err)
+	}
+
+	s.logTransition(record, amendment, decidedByUserID, models.ActionUpdate, ipAddress, userAgent,
+		fmt.Sprintf("accepted:addendum_%d%s", *amendment.AddendumID, reason), purpose)
+
+	return amendment, nil
+}
+
+// DenyAmendment closes the request with the basis for denial
+func (s *AmendmentService) DenyAmendment(amendmentID uint, req *DenyAmendmentRequest, decidedByUserID uint, decidedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.AmendmentRequest, error) {
+	amendment, record, reason, err := s.authorizeDecision(amendmentID, decidedByUserID, decidedByRole, ipAddress, userAgent, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := amendment.Deny(decidedByUserID, req.Reason); err != nil {
+		return nil, fmt.Errorf("amendment request is not under review")
+	}
+	if err := s.db.Save(amendment).Error; err != nil {
+		return nil, fmt.Errorf("failed to update amendment request: %w", err)
+	}
+
+	s.logTransition(record, amendment, decidedByUserID, models.ActionUpdate, ipAddress, userAgent, "denied"+reason, purpose)
+
+	return amendment, nil
+}
+
+// FileDisagreement records the patient's statement of disagreement with a
+// denial, as entered by staff on the patient's behalf
+func (s *AmendmentService) FileDisagreement(amendmentID uint, req *AmendmentDisagreementRequest, filedByUserID uint, filedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*models.AmendmentRequest, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if filedByRole != models.RoleDoctor && filedByRole != models.RoleNurse {
+		audit.LogUnauthorizedAccess(filedByUserID, fmt.Sprintf("amendment_request:%d", amendmentID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to file a statement of disagreement")
+	}
+
+	amendment, err := s.loadAmendment(amendmentID)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.records.authorizeRecordAccess(amendment.RecordID, filedByUserID, filedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := amendment.FileDisagreement(req.Statement); err != nil {
+		return nil, fmt.Errorf("a statement of disagreement can be filed once, against a denied request")
+	}
+	if err := s.db.Save(amendment).Error; err != nil {
+		return nil, fmt.Errorf("failed to update amendment request: %w", err)
+	}
+
+	s.logTransition(record, amendment, filedByUserID, models.ActionUpdate, ipAddress, userAgent, "disagreement_filed", purpose)
+
+	return amendment, nil
+}
+
+// authorizeDecision allows the record's author, or a colleague covering for
+// them through a delegation grant, to review and decide a request. The
+// returned suffix names the grant for the audit trail.
+func (s *AmendmentService) authorizeDecision(amendmentID uint, userID uint, role models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.AmendmentRequest, *models.MedicalRecord, string, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if role != models.RoleDoctor {
+		audit.LogUnauthorizedAccess(userID, fmt.Sprintf("amendment_request:%d", amendmentID), ipAddress, userAgent, "insufficient_role")
+		return nil, nil, "", fmt.Errorf("insufficient permissions to review amendment request")
+	}
+
+	amendment, err := s.loadAmendment(amendmentID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	record, err := s.loadRecord(amendment.RecordID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if record.DoctorID == userID {
+		return amendment, record, "", nil
+	}
+	grant := s.records.careTeam.DelegationFrom(record.DoctorID, userID, record.PatientID)
+	if grant == nil {
+		audit.LogUnauthorizedAccess(userID, fmt.Sprintf("amendment_request:%d", amendmentID), ipAddress, userAgent, "not_creating_doctor")
+		return nil, nil, "", fmt.Errorf("only the record's author can review this amendment request")
+	}
+	return amendment, record, ":" + grant.AuditReason(), nil
+}
+
+func (s *AmendmentService) loadAmendment(amendmentID uint) (*models.AmendmentRequest, error) {
+	var amendment models.AmendmentRequest
+	if err := s.db.Where("id = ?", amendmentID).First(&amendment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("amendment request not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve amendment request: %w", err)
+	}
+	return &amendment, nil
+}
+
+func (s *AmendmentService) loadRecord(recordID uint) (*models.MedicalRecord, error) {
+	var record models.MedicalRecord
+	if err := s.db.Where("id = ?", recordID).First(&record).Error; err != nil {
+		return nil, fmt.Errorf("medical record not found")
+	}
+	return &record, nil
+}
+
+// logTransition audits a step in a request's lifecycle against its record
+func (s *AmendmentService) logTransition(record *models.MedicalRecord, amendment *models.AmendmentRequest, userID uint, action models.AuditAction, ipAddress, userAgent, event string, purpose models.PurposeOfUse) {
+	reason := fmt.Sprintf("amendment_request_%d:%s", amendment.ID, event)
+	s.records.logRecordAccess(record, userID, action, ipAddress, userAgent, false, reason, purpose)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAmendmentService(t *testing.T) *AmendmentService {
+	consents, records := newConsentService(t)
+	return NewAmendmentService(consents.db, records.audit, records)
+}
+
+func TestAmendmentDeadlines(t *testing.T) {
+	amendments := newAmendmentService(t)
+	db := amendments.db
+
+	author := createUser(t, db, models.RoleDoctor)
+	nurse := createUser(t, db, models.RoleNurse)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, author)
+	record := createRecord(t, db, patient, author, models.SensitivityNormal, models.SeverityLow)
+
+	submit := func(receivedAt time.Time) *models.AmendmentRequest {
+		amendment, err := amendments.SubmitAmendment(record.ID, &SubmitAmendmentRequest{RequestedChange: "Allergy is to penicillin, not amoxicillin", ReceivedAt: &receivedAt}, nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		require.NoError(t, err)
+		return amendment
+	}
+
+	t.Run("DueSixtyDaysAfterReceipt", func(t *testing.T) {
+		receivedAt := time.Now().Add(-10 * 24 * time.Hour)
+		amendment := submit(receivedAt)
+		assert.WithinDuration(t, receivedAt.Add(models.AmendmentResponseWindow), amendment.DueAt, time.Second)
+	})
+
+	t.Run("ReceiptCannotBeInFuture", func(t *testing.T) {
+		future := time.Now().Add(time.Hour)
+		_, err := amendments.SubmitAmendment(record.ID, &SubmitAmendmentRequest{RequestedChange: "Change", ReceivedAt: &future}, nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("ExtendedOnce", func(t *testing.T) {
+		amendment := submit(time.Now())
+		due := amendment.DueAt
+
+		extended, err := amendments.ExtendDeadline(amendment.ID, &ExtendAmendmentRequest{Reason: "Awaiting outside records"}, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		require.NoError(t, err)
+		assert.WithinDuration(t, due.Add(models.AmendmentExtension), extended.DueAt, time.Second)
+
+		_, err = amendments.ExtendDeadline(amendment.ID, &ExtendAmendmentRequest{Reason: "Still awaiting records"}, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("NotExtendedAfterDeadline", func(t *testing.T) {
+		amendment := submit(time.Now().Add(-61 * 24 * time.Hour))
+		_, err := amendments.ExtendDeadline(amendment.ID, &ExtendAmendmentRequest{Reason: "Awaiting outside records"}, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		assert.Error(t, err)
+
+		overdue, _, err := amendments.GetAmendments(&AmendmentQuery{Overdue: true, Page: 1, Limit: 20}, author.ID, models.RoleDoctor, testIP, testUserAgent)
+		require.NoError(t, err)
+		require.Len(t, overdue, 1)
+		assert.Equal(t, amendment.ID, overdue[0].ID)
+		assert.True(t, overdue[0].Overdue)
+	})
+
+	t.Run("OnlyAuthorDecides", func(t *testing.T) {
+		amendment := submit(time.Now())
+		colleague := createUser(t, db, models.RoleDoctor)
+
+		_, err := amendments.StartReview(amendment.ID, colleague.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		assert.Error(t, err)
+		assert.Equal(t, "not_creating_doctor", lastAuditEntry(t, db, colleague.ID).ErrorMessage)
+	})
+}
+
+func TestAmendmentDecisions(t *testing.T) {
+	amendments := newAmendmentService(t)
+	db := amendments.db
+
+	author := createUser(t, db, models.RoleDoctor)
+	nurse := createUser(t, db, models.RoleNurse)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, author)
+	signed := createRecord(t, db, patient, author, models.SensitivityNormal, models.SeverityLow)
+
+	underReview := func(recordID uint) *models.AmendmentRequest {
+		amendment, err := amendments.SubmitAmendment(recordID, &SubmitAmendmentRequest{RequestedChange: "Correct the date of onset"}, nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		require.NoError(t, err)
+		_, err = amendments.StartReview(amendment.ID, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		require.NoError(t, err)
+		return amendment
+	}
+
+	t.Run("AcceptedOnceAsAddendum", func(t *testing.T) {
+		amendment := underReview(signed.ID)
+
+		accepted, err := amendments.AcceptAmendment(amendment.ID, &AcceptAmendmentRequest{}, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		require.NoError(t, err)
+		require.NotNil(t, accepted.AddendumID)
+
+		_, err = amendments.AcceptAmendment(amendment.ID, &AcceptAmendmentRequest{}, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		assert.Error(t, err)
+
+		var addenda int64
+		db.Model(&models.RecordAddendum{}).Where("record_id = ?", signed.ID).Count(&addenda)
+		assert.Equal(t, int64(1), addenda)
+	})
+
+	t.Run("DraftsAreNotAmended", func(t *testing.T) {
+		draft := &models.MedicalRecord{PatientID: patient.ID, DoctorID: author.ID, Diagnosis: "Draft", Treatment: "Draft", Severity: models.SeverityLow}
+		require.NoError(t, db.Create(draft).Error)
+		amendment := underReview(draft.ID)
+
+		_, err := amendments.AcceptAmendment(amendment.ID, &AcceptAmendmentRequest{}, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("DisagreementNeedsRecordAccess", func(t *testing.T) {
+		amendment := underReview(signed.ID)
+		_, err := amendments.DenyAmendment(amendment.ID, &DenyAmendmentRequest{Reason: "The record is accurate as written"}, author.ID, models.RoleDoctor, testIP, testUserAgent, "")
+		require.NoError(t, err)
+
+		confidential := createPatient(t, db, true)
+		other := createRecord(t, db, confidential, author, models.SensitivityNormal, models.SeverityLow)
+		hidden, err := amendments.SubmitAmendment(other.ID, &SubmitAmendmentRequest{RequestedChange: "Change"}, nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		require.NoError(t, err)
+		_, err = amendments.FileDisagreement(hidden.ID, &AmendmentDisagreementRequest{Statement: "I disagree"}, nurse.ID, models.RoleNurse, testIP, testUserAgent, false, "", "")
+		assert.Error(t, err)
+
+		statement := &AmendmentDisagreementRequest{Statement: "I disagree with the denial"}
+		filed, err := amendments.FileDisagreement(amendment.ID, statement, nurse.ID, models.RoleNurse, testIP, testUserAgent, false, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, statement.Statement, filed.DisagreementStatement)
+
+		_, err = amendments.FileDisagreement(amendment.ID, statement, nurse.ID, models.RoleNurse, testIP, testUserAgent, false, "", "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"healthsecure/internal/encryption"
+	"healthsecure/internal/models"
+	"healthsecure/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// AttachmentService files scanned and exported documents on patient charts.
+// Blobs are encrypted with the tenant data key before they reach the store.
+// Attachments filed against a medical record are disclosed only to users
+// who may read that record, after SanitizeForRole; every download is audited.
+type AttachmentService struct {
+	db          *gorm.DB
+	audit       *AuditService
+	careTeam    *CareTeamService
+	consents    *ConsentService
+	records     *MedicalRecordService
+	fieldPolicy models.FieldPolicy
+	store       storage.Store
+	maxSize     int64
+}
+
+type UploadAttachmentRequest struct {
+	RecordID *uint                     `form:"record_id"`
+	Category models.AttachmentCategory `form:"category"`
+	Filename string                    `form:"-"`
+	Data     []byte                    `form:"-"`
+}
+
+type AttachmentQuery struct {
+	RecordID *uint  `form:"record_id"`
+	Category string `form:"category"`
+}
+
+// AttachmentContent is a decrypted attachment ready to send
+type AttachmentContent struct {
+	Attachment *models.Attachment
+	Data       []byte
+}
+
+func NewAttachmentService(db *gorm.DB, audit *AuditService, careTeam *CareTeamService, consents *ConsentService, records *MedicalRecordService, fieldPolicy models.FieldPolicy, store storage.Store, maxSize int64) *AttachmentService {
+	return &AttachmentService{
+		db:          db,
+		audit:       audit,
+		careTeam:    careTeam,
+		consents:    consents,
+		records:     records,
+		fieldPolicy: fieldPolicy,
+		store:       store,
+		maxSize:     maxSize,
+	}
+}
+
+// MaxSize is the largest upload accepted, in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// UploadAttachment validates, encrypts and stores a document. Doctors and
+// nurses may file documents on a chart, or on a record they can fully read.
+func (s *AttachmentService) UploadAttachment(patientID uint, req *UploadAttachmentRequest, uploadedByUserID uint, uploadedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.Attachment, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if uploadedByRole != models.RoleDoctor && uploadedByRole != models.RoleNurse {
+		audit.LogUnauthorizedAccess(uploadedByUserID, fmt.Sprintf("attachments:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to upload attachments")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	if req.Category == "" {
+		req.Category = models.AttachmentOther
+	}
+	if !models.IsValidAttachmentCategory(req.Category) {
+		return nil, fmt.Errorf("invalid attachment category")
+	}
+	if len(req.Data) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if int64(len(req.Data)) > s.maxSize {
+		return nil, fmt.Errorf("file exceeds the %d MB limit", s.maxSize>>20)
+	}
+	contentType := models.DetectAttachmentType(req.Data)
+	if contentType == "" {
+		return nil, fmt.Errorf("unsupported file type; upload a PDF, PNG, JPEG or TIFF document")
+	}
+
+	if req.RecordID != nil {
+		record, err := s.records.authorizeRecordAccess(*req.RecordID, uploadedByUserID, uploadedByRole, ipAddress, userAgent, false, "", purpose)
+		if err != nil {
+			return nil, err
+		}
+		if record.PatientID != patientID {
+			return nil, fmt.Errorf("medical record does not belong to this patient")
+		}
+		if !attachmentsVisible(record, uploadedByRole) {
+			audit.LogUnauthorizedAccess(uploadedByUserID, fmt.Sprintf("medical_record:%d", record.ID), ipAddress, userAgent, "role_access_denied")
+			return nil, fmt.Errorf("access denied to medical record")
+		}
+	}
+
+	key, err := newStorageKey(patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	keyring := encryption.Default()
+	if keyring == nil {
+		return nil, fmt.Errorf("attachment encryption is not configured")
+	}
+	blob, err := keyring.EncryptBlob(key, req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt attachment: %w", err)
+	}
+
+	ctx := context.Background()
+	if err := s.store.Put(ctx, key, blob); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	sum := sha256.Sum256(req.Data)
+	attachment := models.Attachment{
+		PatientID:   patientID,
+		RecordID:    req.RecordID,
+		Category:    req.Category,
+		Filename:    models.CleanAttachmentFilename(req.Filename),
+		ContentType: contentType,
+		Size:        int64(len(req.Data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		StorageKey:  key,
+		UploadedBy:  uploadedByUserID,
+	}
+	if err := s.db.Create(&attachment).Error; err != nil {
+		// Do not leave an orphaned blob behind
+		_ = s.store.Delete(ctx, key)
+		return nil, fmt.Errorf("failed to save attachment: %w", err)
+	}
+
+	audit.LogPatientDataAccess(uploadedByUserID, patientID, fmt.Sprintf("attachment:%d", attachment.ID),
+		models.ActionCreate, ipAddress, userAgent, false, "attachment_uploaded", nil)
+
+	return &attachment, nil
+}
+
+// GetAttachments lists the attachments on a chart that the caller may see.
+// Record attachments follow the same disclosure rules as the record list.
+func (s *AttachmentService) GetAttachments(patientID uint, query *AttachmentQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) ([]models.Projection, error) {
+	if err := s.authorizeRead(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	db := s.db.Where("patient_id = ? AND record_id IS NULL", patientID)
+	if query.Category != "" {
+		db = db.Where("category = ?", query.Category)
+	}
+	var attachments []models.Attachment
+	if query.RecordID == nil {
+		if err := db.Preload("Uploader").Order("created_at DESC").Find(&attachments).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve attachments: %w", err)
+		}
+	}
+
+	// Load the records that have attachments and keep what survives the
+	// record rules
+	recordQuery := s.db.Where("patient_id = ? AND id IN (?)", patientID,
+		s.db.Model(&models.Attachment{}).Select("record_id").Where("patient_id = ? AND record_id IS NOT NULL", patientID))
+	if query.RecordID != nil {
+		recordQuery = recordQuery.Where("id = ?", *query.RecordID)
+	}
+	var records []models.MedicalRecord
+	if err := recordQuery.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		if query.Category != "" {
+			db = db.Where("category = ?", query.Category)
+		}
+		return db.Preload("Uploader").Order("created_at DESC")
+	}).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve attachments: %w", err)
+	}
+
+	for _, record := range s.consents.FilterDisclosable(records, requestedByUserID) {
+		if !record.CanBeAccessedByRole(requestedByRole, requestedByUserID) && !emergencyAccess {
+			continue
+		}
+		if sanitized := record.SanitizeForRole(requestedByRole); sanitized != nil {
+			attachments = append(attachments, sanitized.Attachments...)
+		}
+	}
+
+	policy := s.fieldPolicy.ForPurpose(purpose)
+	projections, disclosed := policy.ProjectAll(models.ResourceAttachment, requestedByRole, attachments, fields)
+	if projections == nil {
+		projections = []models.Projection{}
+	}
+
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(requestedByUserID, patientID, fmt.Sprintf("attachments:patient_%d", patientID),
+		models.ActionView, ipAddress, userAgent, emergencyAccess, s.disclosureReason(patientID, requestedByUserID, emergencyAccess), disclosed)
+
+	return projections, nil
+}
+
+// DownloadAttachment decrypts an attachment for the caller after applying
+// the chart rules and, for record attachments, the record's own rules
+func (s *AttachmentService) DownloadAttachment(patientID, attachmentID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*AttachmentContent, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if err := s.authorizeRead(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	var attachment models.Attachment
+	if err := s.db.Where("id = ? AND patient_id = ?", attachmentID, patientID).First(&attachment).Error; err != nil {
+		return nil, fmt.Errorf("attachment not found")
+	}
+
+	var record *models.MedicalRecord
+	if attachment.RecordID != nil {
+		var err error
+		record, err = s.records.authorizeRecordAccess(*attachment.RecordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+		if err != nil {
+			return nil, err
+		}
+		if !attachmentsVisible(record, requestedByRole) {
+			audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("attachment:%d", attachment.ID), ipAddress, userAgent, "role_access_denied")
+			return nil, fmt.Errorf("access denied to attachment")
+		}
+	}
+
+	keyring := encryption.Default()
+	if keyring == nil {
+		return nil, fmt.Errorf("attachment encryption is not configured")
+	}
+	blob, err := s.store.Get(context.Background(), attachment.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	data, err := keyring.DecryptBlob(attachment.StorageKey, blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt attachment: %w", err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != attachment.SHA256 {
+		return nil, fmt.Errorf("attachment failed its integrity check")
+	}
+
+	reason := fmt.Sprintf("attachment_downloaded:%d", attachment.ID)
+	if delegated := s.disclosureReason(patientID, requestedByUserID, emergencyAccess); delegated != "" {
+		reason = reason + ":" + delegated
+	}
+	if record != nil && record.IsSensitive() {
+		audit.LogSensitiveRecordAccess(requestedByUserID, patientID, record.ID, record.Sensitivity, models.ActionDownload, ipAddress, userAgent, emergencyAccess, reason)
+	} else {
+		audit.LogPatientDataAccess(requestedByUserID, patientID, fmt.Sprintf("attachment:%d", attachment.ID),
+			models.ActionDownload, ipAddress, userAgent, emergencyAccess, reason, []string{"content"})
+	}
+
+	return &AttachmentContent{Attachment: &attachment, Data: data}, nil
+}
+
+// DeleteAttachment marks an attachment filed in error. The blob is kept for
+// the retention period and removed when the chart is purged.
+func (s *AttachmentService) DeleteAttachment(patientID, attachmentID uint, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) error {
+	audit := s.audit.WithPurpose(purpose)
+
+	var attachment models.Attachment
+	if err := s.db.Where("id = ? AND patient_id = ?", attachmentID, patientID).First(&attachment).Error; err != nil {
+		return fmt.Errorf("attachment not found")
+	}
+
+	// Doctors may remove any attachment; nurses only their own uploads
+	if deletedByRole != models.RoleDoctor && !(deletedByRole == models.RoleNurse && attachment.UploadedBy == deletedByUserID) {
+		audit.LogUnauthorizedAccess(deletedByUserID, fmt.Sprintf("attachment:%d", attachmentID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to delete attachment")
+	}
+
+	if err := s.db.Delete(&attachment).Error; err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	audit.LogPatientDataAccess(deletedByUserID, patientID, fmt.Sprintf("attachment:%d", attachmentID),
+		models.ActionDelete, ipAddress, userAgent, false, "entered_in_error", nil)
+
+	return nil
+}
+
+// authorizeRead admits roles the field policy shows attachments to, then
+// applies the confidential-chart rules
+func (s *AttachmentService) authorizeRead(patientID, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) error {
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	if len(policy[models.ResourceAttachment][requestedByRole]) == 0 {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("attachments:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to access attachments")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return fmt.Errorf("patient not found")
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	return s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose)
+}
+
+func (s *AttachmentService) disclosureReason(patientID, requestedByUserID uint, emergencyAccess bool) string {
+	if emergencyAccess {
+		return "emergency_access"
+	}
+	return s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
+}
+
+// attachmentsVisible reports whether SanitizeForRole leaves a record's
+// attachments in place for role
+func attachmentsVisible(record *models.MedicalRecord, role models.UserRole) bool {
+	probe := *record
+	probe.Attachments = []models.Attachment{{}}
+	sanitized := probe.SanitizeForRole(role)
+	return sanitized != nil && len(sanitized.Attachments) > 0
+}
+
+// newStorageKey names a blob by patient and a random id, so that keys reveal
+// nothing about the document
+func newStorageKey(patientID uint) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("patients/%d/%s", patientID, hex.EncodeToString(id)), nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"healthsecure/internal/encryption"
+	"healthsecure/internal/models"
+	"healthsecure/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRekeyedAttachmentsStayReadable(t *testing.T) {
+	consents, records := newConsentService(t)
+	db := consents.db
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	attachments := NewAttachmentService(db, records.audit, records.careTeam, consents, records, models.DefaultFieldPolicy(), store, 1<<20)
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, doctor)
+
+	data := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{7}, 64)...)
+	attachment, err := attachments.UploadAttachment(patient.ID, &UploadAttachmentRequest{Filename: "scan.png", Data: data}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "")
+	require.NoError(t, err)
+
+	// Rotate to a new active key while the old one can still decrypt
+	original := encryption.Default()
+	t.Cleanup(func() { encryption.SetDefault(original) })
+	oldKey, newKey := make([]byte, 32), bytes.Repeat([]byte{1}, 32)
+	rotated := encryption.NewStaticKeyring("default", map[int][]byte{1: oldKey, 2: newKey}, 2, make([]byte, 32))
+	encryption.SetDefault(rotated)
+
+	rekeyed, err := rotated.RekeyBlobs(db, store, encryption.EncryptedColumn{Table: "attachments", Column: "storage_key"}, encryption.RekeyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rekeyed)
+
+	t.Run("NoLongerNeedsOldKey", func(t *testing.T) {
+		blob, err := store.Get(context.Background(), attachment.StorageKey)
+		require.NoError(t, err)
+		assert.False(t, rotated.BlobNeedsRekey(blob))
+
+		retired := encryption.NewStaticKeyring("default", map[int][]byte{2: newKey}, 2, make([]byte, 32))
+		plaintext, err := retired.DecryptBlob(attachment.StorageKey, blob)
+		require.NoError(t, err)
+		assert.Equal(t, data, plaintext)
+	})
+
+	t.Run("DownloadsAfterRekey", func(t *testing.T) {
+		content, err := attachments.DownloadAttachment(patient.ID, attachment.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, data, content.Data)
+	})
+
+	t.Run("SecondRunIsNoOp", func(t *testing.T) {
+		rekeyed, err := rotated.RekeyBlobs(db, store, encryption.EncryptedColumn{Table: "attachments", Column: "storage_key"}, encryption.RekeyOptions{})
+		require.NoError(t, err)
+		assert.Zero(t, rekeyed)
+	})
+}
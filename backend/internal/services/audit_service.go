@@ -1,7 +1,9 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"healthsecure/internal/database"
@@ -11,7 +13,11 @@ import (
 )
 
 type AuditService struct {
-	db *gorm.DB
+	db      *gorm.DB
+	purpose models.PurposeOfUse
+	// includeTPO lists treatment, payment and operations accesses in
+	// accountings of disclosures
+	includeTPO bool
 }
 
 type AuditLogQuery struct {
@@ -20,16 +26,33 @@ type AuditLogQuery struct {
 	Action      *models.AuditAction  `form:"action"`
 	Success     *bool                `form:"success"`
 	Emergency   *bool                `form:"emergency"`
+	Sensitive   *bool                `form:"sensitive"`
+	Purpose     *models.PurposeOfUse `form:"purpose"`
 	StartTime   *time.Time           `form:"start_time"`
 	EndTime     *time.Time           `form:"end_time"`
 	IPAddress   string               `form:"ip_address"`
 	Page        int                  `form:"page,default=1"`
 	Limit       int                  `form:"limit,default=50"`
+
+	// Filters used by the FHIR API. Before excludes its end.
+	ID     *uint      `form:"-"`
+	Before *time.Time `form:"-"`
+}
+
+func NewAuditService(db *gorm.DB, includeTPO bool) *AuditService {
+	return &AuditService{
+		db:         db,
+		includeTPO: includeTPO,
+	}
 }
 
-func NewAuditService(db *gorm.DB) *AuditService {
+// WithPurpose returns an audit service that stamps every entry it writes with
+// the purpose of use declared for the current request
+func (s *AuditService) WithPurpose(purpose models.PurposeOfUse) *AuditService {
 	return &AuditService{
-		db: db,
+		db:         s.db,
+		purpose:    purpose,
+		includeTPO: s.includeTPO,
 	}
 }
 
@@ -42,6 +65,7 @@ func (s *AuditService) LogUserAction(userID uint, action models.AuditAction, res
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
 		EmergencyUse: false,
+		Purpose:      s.purpose,
 		Reason:       reason,
 		Success:      success,
 		Timestamp:    time.Now(),
@@ -64,6 +88,7 @@ func (s *AuditService) LogPatientAccess(userID, patientID uint, action models.Au
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
 		EmergencyUse: emergencyUse,
+		Purpose:      s.purpose,
 		Reason:       reason,
 		Success:      true,
 		Timestamp:    time.Now(),
@@ -81,6 +106,36 @@ func (s *AuditService) LogPatientAccess(userID, patientID uint, action models.Au
 	return nil
 }
 
+// LogPatientDataAccess logs access to a patient's structured clinical data,
+// such as the medication list, under a resource of its own. Reads pass the
+// fields actually disclosed.
+func (s *AuditService) LogPatientDataAccess(userID, patientID uint, resource string, action models.AuditAction, ipAddress, userAgent string, emergencyUse bool, reason string, disclosedFields []string) error {
+	auditLog := &models.AuditLog{
+		UserID:          userID,
+		PatientID:       &patientID,
+		Action:          action,
+		Resource:        resource,
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		EmergencyUse:    emergencyUse,
+		DisclosedFields: strings.Join(disclosedFields, ","),
+		Purpose:         s.purpose,
+		Reason:          reason,
+		Success:         true,
+		Timestamp:       time.Now(),
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		return fmt.Errorf("failed to log patient data access: %w", err)
+	}
+
+	if emergencyUse {
+		s.createSecurityEvent(userID, ipAddress, auditLog)
+	}
+
+	return nil
+}
+
 // LogMedicalRecordAccess logs access to medical records
 func (s *AuditService) LogMedicalRecordAccess(userID, patientID, recordID uint, action models.AuditAction, ipAddress, userAgent string, emergencyUse bool, reason string) error {
 	auditLog := &models.AuditLog{
@@ -92,6 +147,7 @@ func (s *AuditService) LogMedicalRecordAccess(userID, patientID, recordID uint,
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
 		EmergencyUse: emergencyUse,
+		Purpose:      s.purpose,
 		Reason:       reason,
 		Success:      true,
 		Timestamp:    time.Now(),
@@ -109,6 +165,188 @@ func (s *AuditService) LogMedicalRecordAccess(userID, patientID, recordID uint,
 	return nil
 }
 
+// LogSensitiveRecordAccess logs access to a medical record carrying a privacy
+// classification. Every such access is flagged and raises a security event so
+// the privacy office can review it.
+func (s *AuditService) LogSensitiveRecordAccess(userID, patientID, recordID uint, sensitivity models.SensitivityLevel, action models.AuditAction, ipAddress, userAgent string, emergencyUse bool, reason string) error {
+	auditLog := &models.AuditLog{
+		UserID:          userID,
+		PatientID:       &patientID,
+		RecordID:        &recordID,
+		Action:          action,
+		Resource:        fmt.Sprintf("medical_record:%d", recordID),
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		EmergencyUse:    emergencyUse,
+		SensitiveAccess: true,
+		Sensitivity:     string(sensitivity),
+		Purpose:         s.purpose,
+		Reason:          reason,
+		Success:         true,
+		Timestamp:       time.Now(),
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		return fmt.Errorf("failed to log sensitive record access: %w", err)
+	}
+
+	s.createSecurityEventForSensitiveAccess(userID, ipAddress, auditLog)
+
+	return nil
+}
+
+// LogConfidentialPatientAccess logs access to a confidential or employee
+// patient's chart and always raises a security event for the privacy office,
+// whether or not break-glass was used.
+func (s *AuditService) LogConfidentialPatientAccess(userID uint, patient *models.Patient, action models.AuditAction, ipAddress, userAgent, reason string, onCareTeam bool) error {
+	auditLog := &models.AuditLog{
+		UserID:          userID,
+		PatientID:       &patient.ID,
+		Action:          action,
+		Resource:        fmt.Sprintf("patient:%d", patient.ID),
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		SensitiveAccess: true,
+		Sensitivity:     "confidential_patient",
+		Purpose:         s.purpose,
+		Reason:          reason,
+		Success:         true,
+		Timestamp:       time.Now(),
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		return fmt.Errorf("failed to log confidential patient access: %w", err)
+	}
+
+	s.createSecurityEventForConfidentialAccess(userID, ipAddress, auditLog, patient, onCareTeam)
+
+	return nil
+}
+
+// LogPatientDisclosure logs a read of patient data together with the fields
+// actually disclosed to the caller
+func (s *AuditService) LogPatientDisclosure(userID, patientID uint, ipAddress, userAgent string, emergencyUse bool, reason string, disclosedFields []string) error {
+	auditLog := &models.AuditLog{
+		UserID:          userID,
+		PatientID:       &patientID,
+		Action:          models.ActionView,
+		Resource:        fmt.Sprintf("patient:%d", patientID),
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		EmergencyUse:    emergencyUse,
+		DisclosedFields: strings.Join(disclosedFields, ","),
+		Purpose:         s.purpose,
+		Reason:          reason,
+		Success:         true,
+		Timestamp:       time.Now(),
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		return fmt.Errorf("failed to log patient disclosure: %w", err)
+	}
+
+	if emergencyUse {
+		s.createSecurityEvent(userID, ipAddress, auditLog)
+	}
+
+	return nil
+}
+
+// LogMedicalRecordDisclosure logs a read of a medical record together with the
+// fields actually disclosed. Classified records go to the sensitive audit trail.
+func (s *AuditService) LogMedicalRecordDisclosure(record *models.MedicalRecord, userID uint, ipAddress, userAgent string, emergencyUse bool, reason string, disclosedFields []string) error {
+	auditLog := &models.AuditLog{
+		UserID:          userID,
+		PatientID:       &record.PatientID,
+		RecordID:        &record.ID,
+		Action:          models.ActionView,
+		Resource:        fmt.Sprintf("medical_record:%d", record.ID),
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		EmergencyUse:    emergencyUse,
+		SensitiveAccess: record.IsSensitive(),
+		DisclosedFields: strings.Join(disclosedFields, ","),
+		Purpose:         s.purpose,
+		Reason:          reason,
+		Success:         true,
+		Timestamp:       time.Now(),
+	}
+	if record.IsSensitive() {
+		auditLog.Sensitivity = string(record.Sensitivity)
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		return fmt.Errorf("failed to log medical record disclosure: %w", err)
+	}
+
+	if record.IsSensitive() {
+		s.createSecurityEventForSensitiveAccess(userID, ipAddress, auditLog)
+	} else if emergencyUse {
+		s.createSecurityEvent(userID, ipAddress, auditLog)
+	}
+
+	return nil
+}
+
+// LogListDisclosure logs a list or search read together with the union of
+// fields disclosed across the returned items. Each returned patient gets an
+// entry of their own, so the read shows in their accounting of disclosures;
+// a read that returned nobody is logged once without a patient.
+func (s *AuditService) LogListDisclosure(userID uint, resource, ipAddress, userAgent, reason string, patientIDs []uint, disclosedFields []string) error {
+	newEntry := func(patientID *uint) models.AuditLog {
+		return models.AuditLog{
+			UserID:          userID,
+			PatientID:       patientID,
+			Action:          models.ActionView,
+			Resource:        resource,
+			IPAddress:       ipAddress,
+			UserAgent:       userAgent,
+			DisclosedFields: strings.Join(disclosedFields, ","),
+			Purpose:         s.purpose,
+			Reason:          reason,
+			Success:         true,
+			Timestamp:       time.Now(),
+		}
+	}
+
+	if len(patientIDs) == 0 {
+		entry := newEntry(nil)
+		return s.db.Create(&entry).Error
+	}
+
+	entries := make([]models.AuditLog, len(patientIDs))
+	for i := range patientIDs {
+		entries[i] = newEntry(&patientIDs[i])
+	}
+	return s.db.Create(&entries).Error
+}
+
+// LogChartExport logs a copy of a patient's chart leaving the system, with the
+// outside party it is for and the fields it disclosed. Exports always appear
+// in the patient's accounting of disclosures.
+func (s *AuditService) LogChartExport(userID, patientID uint, format, recipient, ipAddress, userAgent, reason string, disclosedFields []string) error {
+	auditLog := &models.AuditLog{
+		UserID:          userID,
+		PatientID:       &patientID,
+		Action:          models.ActionExport,
+		Resource:        fmt.Sprintf("chart_export:patient_%d:%s", patientID, format),
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		DisclosedFields: strings.Join(disclosedFields, ","),
+		Recipient:       recipient,
+		Purpose:         s.purpose,
+		Reason:          reason,
+		Success:         true,
+		Timestamp:       time.Now(),
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		return fmt.Errorf("failed to log chart export: %w", err)
+	}
+
+	return nil
+}
+
 // LogEmergencyAccess logs emergency access requests and usage
 func (s *AuditService) LogEmergencyAccess(userID, patientID uint, action models.AuditAction, ipAddress, userAgent, reason string, success bool) error {
 	auditLog := &models.AuditLog{
@@ -119,6 +357,7 @@ func (s *AuditService) LogEmergencyAccess(userID, patientID uint, action models.
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
 		EmergencyUse: true,
+		Purpose:      s.purpose,
 		Reason:       reason,
 		Success:      success,
 		Timestamp:    time.Now(),
@@ -147,6 +386,7 @@ func (s *AuditService) LogFailedLogin(email, ipAddress, userAgent, reason string
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
 		EmergencyUse: false,
+		Purpose:      s.purpose,
 		Success:      false,
 		ErrorMessage: reason,
 		Timestamp:    time.Now(),
@@ -171,6 +411,7 @@ func (s *AuditService) LogUnauthorizedAccess(userID uint, resource, ipAddress, u
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
 		EmergencyUse: false,
+		Purpose:      s.purpose,
 		Success:      false,
 		ErrorMessage: reason,
 		Timestamp:    time.Now(),
@@ -186,6 +427,32 @@ func (s *AuditService) LogUnauthorizedAccess(userID uint, resource, ipAddress, u
 	return nil
 }
 
+// LogUnauthorizedPatientAccess logs a refused action on a known patient's
+// chart, so the refusal also shows in the patient's audit history
+func (s *AuditService) LogUnauthorizedPatientAccess(userID, patientID uint, resource, ipAddress, userAgent, reason string) error {
+	auditLog := &models.AuditLog{
+		UserID:       userID,
+		PatientID:    &patientID,
+		Action:       models.ActionUnauthorized,
+		Resource:     resource,
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+		EmergencyUse: false,
+		Purpose:      s.purpose,
+		Success:      false,
+		ErrorMessage: reason,
+		Timestamp:    time.Now(),
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		return fmt.Errorf("failed to log unauthorized access: %w", err)
+	}
+
+	s.createSecurityEvent(userID, ipAddress, auditLog)
+
+	return nil
+}
+
 // GetAuditLogs retrieves audit logs with filtering and pagination
 func (s *AuditService) GetAuditLogs(query *AuditLogQuery, requestedByRole models.UserRole, requestedByUserID uint) ([]models.AuditLog, int64, error) {
 	// Only admins and medical staff can view audit logs
@@ -199,13 +466,27 @@ func (s *AuditService) GetAuditLogs(query *AuditLogQuery, requestedByRole models
 		Action:    query.Action,
 		Success:   query.Success,
 		Emergency: query.Emergency,
+		Sensitive: query.Sensitive,
+		Purpose:   query.Purpose,
 		StartTime: query.StartTime,
 		EndTime:   query.EndTime,
 		IPAddress: query.IPAddress,
+		ID:        query.ID,
+		Before:    query.Before,
 		Limit:     query.Limit,
 		Offset:    (query.Page - 1) * query.Limit,
 	}
 
+	// A chart's trail includes the charts merged into it
+	if query.PatientID != nil {
+		ids, err := mergedChartIDs(s.db, *query.PatientID)
+		if err != nil {
+			return nil, 0, err
+		}
+		filter.PatientID = nil
+		filter.PatientIDs = ids
+	}
+
 	// Non-admins can only see their own audit logs or patient-related logs they have access to
 	if requestedByRole != models.RoleAdmin {
 		filter.UserID = &requestedByUserID
@@ -264,8 +545,13 @@ func (s *AuditService) GetPatientAuditHistory(patientID uint, requestedByRole mo
 		return nil, fmt.Errorf("insufficient permissions to view patient audit history")
 	}
 
+	ids, err := mergedChartIDs(s.db, patientID)
+	if err != nil {
+		return nil, err
+	}
+
 	var auditLogs []models.AuditLog
-	query := s.db.Where("patient_id = ?", patientID).
+	query := s.db.Where("patient_id IN ?", ids).
 		Order("timestamp DESC").
 		Limit(limit).
 		Preload("User").
@@ -376,6 +662,18 @@ func (s *AuditService) GetAuditStatistics(startTime, endTime time.Time, requeste
 		Scan(&actionStats)
 	stats["actions_by_type"] = actionStats
 
+	// Patient data accesses by declared purpose of use
+	var purposeStats []struct {
+		Purpose models.PurposeOfUse `json:"purpose"`
+		Count   int64               `json:"count"`
+	}
+	s.db.Model(&models.AuditLog{}).
+		Select("purpose, COUNT(*) as count").
+		Where("timestamp BETWEEN ? AND ? AND patient_id IS NOT NULL", startTime, endTime).
+		Group("purpose").
+		Scan(&purposeStats)
+	stats["accesses_by_purpose"] = purposeStats
+
 	return stats, nil
 }
 
@@ -433,4 +731,78 @@ func (s *AuditService) createSecurityEventForFailedLogin(email, ipAddress, reaso
 	}
 
 	s.db.Create(securityEvent)
+}
+
+// createSecurityEventForSensitiveAccess creates a security event for access to
+// a sensitive record
+func (s *AuditService) createSecurityEventForSensitiveAccess(userID uint, ipAddress string, auditLog *models.AuditLog) {
+	severity := database.SecuritySeverityLow
+	if auditLog.EmergencyUse || models.SensitivityLevel(auditLog.Sensitivity) == models.SensitivitySubstanceUse {
+		severity = database.SecuritySeverityMedium
+	}
+
+	securityEvent := &database.SecurityEvent{
+		EventType:   database.SecurityEventSensitiveAccess,
+		Severity:    severity,
+		UserID:      &userID,
+		IPAddress:   ipAddress,
+		Description: fmt.Sprintf("%s access to %s record %s", auditLog.Action, auditLog.Sensitivity, auditLog.Resource),
+		Details: securityEventDetails(map[string]interface{}{
+			"audit_log_id": auditLog.ID,
+			"resource":     auditLog.Resource,
+			"sensitivity":  auditLog.Sensitivity,
+			"reason":       auditLog.Reason,
+		}),
+		Resolved: false,
+	}
+
+	s.db.Create(securityEvent)
+}
+
+// createSecurityEventForConfidentialAccess creates a security event for access
+// to a confidential patient's chart
+func (s *AuditService) createSecurityEventForConfidentialAccess(userID uint, ipAddress string, auditLog *models.AuditLog, patient *models.Patient, onCareTeam bool) {
+	severity := database.SecuritySeverityLow
+	description := fmt.Sprintf("Confidential chart %s opened by care team member", auditLog.Resource)
+
+	if !onCareTeam {
+		severity = database.SecuritySeverityHigh
+		description = fmt.Sprintf("Confidential chart %s opened outside the care team", auditLog.Resource)
+	}
+	if patient.EmployeeUserID != nil {
+		severity = database.SecuritySeverityHigh
+		if *patient.EmployeeUserID == userID {
+			description = fmt.Sprintf("Employee opened own chart %s", auditLog.Resource)
+		} else {
+			description = fmt.Sprintf("Colleague lookup of employee chart %s", auditLog.Resource)
+		}
+	}
+
+	securityEvent := &database.SecurityEvent{
+		EventType:   database.SecurityEventConfidentialAccess,
+		Severity:    severity,
+		UserID:      &userID,
+		IPAddress:   ipAddress,
+		Description: description,
+		Details: securityEventDetails(map[string]interface{}{
+			"audit_log_id":     auditLog.ID,
+			"resource":         auditLog.Resource,
+			"on_care_team":     onCareTeam,
+			"employee_patient": patient.IsEmployee(),
+			"reason":           auditLog.Reason,
+		}),
+		Resolved: false,
+	}
+
+	s.db.Create(securityEvent)
+}
+
+// securityEventDetails encodes free-form details for the JSON details column.
+// Reasons are user supplied, so they must be escaped rather than formatted in.
+func securityEventDetails(details map[string]interface{}) string {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
 }
\ No newline at end of file
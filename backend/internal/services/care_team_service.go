@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CareTeamService struct {
+	db    *gorm.DB
+	audit *AuditService
+}
+
+type AddCareTeamMemberRequest struct {
+	UserID       uint                        `json:"user_id" binding:"required"`
+	Relationship models.CareTeamRelationship `json:"relationship"`
+}
+
+func NewCareTeamService(db *gorm.DB, audit *AuditService) *CareTeamService {
+	return &CareTeamService{
+		db:    db,
+		audit: audit,
+	}
+}
+
+// AddMember assigns a clinician to a patient's care team. Membership opens
+// confidential charts, so only admins and doctors already responsible for
+// the patient may add members, and nobody may add themselves.
+func (s *CareTeamService) AddMember(patientID uint, req *AddCareTeamMemberRequest, addedByUserID uint, addedByRole models.UserRole, ipAddress, userAgent string) (*models.CareTeamMember, error) {
+	if err := s.authorizeManage(patientID, addedByUserID, addedByRole, ipAddress, userAgent); err != nil {
+		return nil, err
+	}
+	if req.UserID == addedByUserID {
+		s.audit.LogUnauthorizedAccess(addedByUserID, fmt.Sprintf("care_team:patient_%d", patientID), ipAddress, userAgent, "care_team_self_add")
+		return nil, fmt.Errorf("you cannot add yourself to a care team")
+	}
+
+	if req.Relationship != "" && !models.IsValidCareTeamRelationship(req.Relationship) {
+		return nil, fmt.Errorf("invalid care team relationship")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ? AND active = ?", req.UserID, true).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found or inactive")
+	}
+	if !user.CanAccessPatientData() {
+		return nil, fmt.Errorf("only medical staff can join a care team")
+	}
+
+	// Reactivate an ended membership instead of duplicating it
+	var member models.CareTeamMember
+	err := s.db.Where("patient_id = ? AND user_id = ?", patientID, req.UserID).First(&member).Error
+	switch {
+	case err == nil:
+		if member.IsActive() {
+			return nil, fmt.Errorf("user is already on the care team")
+		}
+		member.EndedAt = nil
+		member.AddedBy = addedByUserID
+		if req.Relationship != "" {
+			member.Relationship = req.Relationship
+		}
+		if err := s.db.Save(&member).Error; err != nil {
+			return nil, fmt.Errorf("failed to add care team member: %w", err)
+		}
+	case err == gorm.ErrRecordNotFound:
+		member = models.CareTeamMember{
+			PatientID:    patientID,
+			UserID:       req.UserID,
+			Relationship: req.Relationship,
+			AddedBy:      addedByUserID,
+		}
+		if err := s.db.Create(&member).Error; err != nil {
+			return nil, fmt.Errorf("failed to add care team member: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to retrieve care team: %w", err)
+	}
+
+	s.audit.LogPatientAccess(addedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, fmt.Sprintf("care_team_added:user_%d", req.UserID))
+
+	return &member, nil
+}
+
+// RemoveMember ends a clinician's care team membership
+func (s *CareTeamService) RemoveMember(patientID, userID uint, removedByUserID uint, removedByRole models.UserRole, ipAddress, userAgent string) error {
+	if err := s.authorizeManage(patientID, removedByUserID, removedByRole, ipAddress, userAgent); err != nil {
+		return err
+	}
+
+	var member models.CareTeamMember
+	if err := s.db.Where("patient_id = ? AND user_id = ?", patientID, userID).First(&member).Error; err != nil {
+		return fmt.Errorf("care team member not found")
+	}
+
+	if err := member.End(); err != nil {
+		return fmt.Errorf("care team membership already ended")
+	}
+
+	if err := s.db.Save(&member).Error; err != nil {
+		return fmt.Errorf("failed to remove care team member: %w", err)
+	}
+
+	s.audit.LogPatientAccess(removedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, fmt.Sprintf("care_team_removed:user_%d", userID))
+
+	return nil
+}
+
+// authorizeManage lets admins manage any care team, and doctors the care
+// team of a patient they attend on an active encounter or already belong to
+func (s *CareTeamService) authorizeManage(patientID, userID uint, role models.UserRole, ipAddress, userAgent string) error {
+	if role == models.RoleAdmin {
+		return nil
+	}
+	if role != models.RoleDoctor {
+		s.audit.LogUnauthorizedAccess(userID, fmt.Sprintf("care_team:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to manage care team")
+	}
+
+	if !s.isResponsibleFor(patientID, userID) {
+		s.audit.LogUnauthorizedAccess(userID, fmt.Sprintf("care_team:patient_%d", patientID), ipAddress, userAgent, "not_on_care_team")
+		return fmt.Errorf("insufficient permissions to manage this patient's care team")
+	}
+	return nil
+}
+
+// GetCareTeam lists the active care team of a patient
+func (s *CareTeamService) GetCareTeam(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.CareTeamMember, error) {
+	if requestedByRole != models.RoleDoctor && requestedByRole != models.RoleNurse {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("care_team:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to view care team")
+	}
+
+	var members []models.CareTeamMember
+	if err := s.db.Where("patient_id = ? AND ended_at IS NULL", patientID).
+		Preload("User").Order("created_at ASC").Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve care team: %w", err)
+	}
+
+	for i := range members {
+		members[i].User.Password = ""
+	}
+
+	return members, nil
+}
+
+// IsOnCareTeam checks if a user treats a patient directly or covers for a
+// colleague who does through an active delegation grant
+func (s *CareTeamService) IsOnCareTeam(patientID, userID uint) bool {
+	return s.isDirectCareTeam(patientID, userID) || s.DelegationFor(patientID, userID) != nil
+}
+
+// CareTeamPatientIDs returns the set of patients whose care team includes the
+// user, directly or through an active delegation grant
+func (s *CareTeamService) CareTeamPatientIDs(userID uint) map[uint]bool {
+	patientIDs := s.directCareTeamPatientIDs(userID)
+
+	for _, grant := range s.activeDelegations(userID) {
+		for id := range s.directCareTeamPatientIDs(grant.DelegatorID) {
+			if grant.IsPanelWide() || grant.ListsPatient(id) {
+				patientIDs[id] = true
+			}
+		}
+	}
+	return patientIDs
+}
+
+// DelegationFor returns an active grant through which the user reaches the
+// patient, or nil. Direct care team membership is not considered.
+func (s *CareTeamService) DelegationFor(patientID, userID uint) *models.AccessDelegation {
+	for _, grant := range s.activeDelegations(userID) {
+		if s.delegationCovers(&grant, patientID) {
+			return &grant
+		}
+	}
+	return nil
+}
+
+// DelegationFrom returns an active grant from a specific delegator covering
+// the patient, or nil
+func (s *CareTeamService) DelegationFrom(delegatorID, delegateID, patientID uint) *models.AccessDelegation {
+	for _, grant := range s.activeDelegations(delegateID) {
+		if grant.DelegatorID == delegatorID && s.delegationCovers(&grant, patientID) {
+			return &grant
+		}
+	}
+	return nil
+}
+
+// DelegatedAccessReason returns the audit reason for a user reaching a patient
+// only through a delegation grant, or an empty string
+func (s *CareTeamService) DelegatedAccessReason(patientID, userID uint) string {
+	if s.isDirectCareTeam(patientID, userID) {
+		return ""
+	}
+	if grant := s.DelegationFor(patientID, userID); grant != nil {
+		return grant.AuditReason()
+	}
+	return ""
+}
+
+// isResponsibleFor checks if a user attends the patient on an active
+// encounter or is an active member of the care team. Delegation grants do
+// not count.
+func (s *CareTeamService) isResponsibleFor(patientID, userID uint) bool {
+	var attending int64
+	s.db.Model(&models.Encounter{}).
+		Where("patient_id = ? AND attending_id = ? AND status = ?", patientID, userID, models.EncounterActive).
+		Count(&attending)
+	return attending > 0 || s.isDirectCareTeam(patientID, userID)
+}
+
+// isDirectCareTeam checks if a user is an active member of a patient's care
+// team. Authoring a record does not count: any doctor can write one.
+func (s *CareTeamService) isDirectCareTeam(patientID, userID uint) bool {
+	var count int64
+	s.db.Model(&models.CareTeamMember{}).
+		Where("patient_id = ? AND user_id = ? AND ended_at IS NULL", patientID, userID).
+		Count(&count)
+	return count > 0
+}
+
+func (s *CareTeamService) directCareTeamPatientIDs(userID uint) map[uint]bool {
+	var assigned []uint
+	s.db.Model(&models.CareTeamMember{}).
+		Where("user_id = ? AND ended_at IS NULL", userID).
+		Pluck("patient_id", &assigned)
+
+	patientIDs := make(map[uint]bool, len(assigned))
+	for _, id := range assigned {
+		patientIDs[id] = true
+	}
+	return patientIDs
+}
+
+func (s *CareTeamService) activeDelegations(delegateID uint) []models.AccessDelegation {
+	now := time.Now()
+
+	var grants []models.AccessDelegation
+	s.db.Where("delegate_id = ? AND status = ? AND starts_at <= ? AND expires_at > ?",
+		delegateID, models.DelegationStatusActive, now, now).
+		Preload("Patients").Find(&grants)
+	return grants
+}
+
+// delegationCovers checks a grant against the delegator's current panel, so a
+// delegate loses access as soon as the delegator leaves the care team
+func (s *CareTeamService) delegationCovers(grant *models.AccessDelegation, patientID uint) bool {
+	if !grant.IsPanelWide() && !grant.ListsPatient(patientID) {
+		return false
+	}
+	return s.isDirectCareTeam(patientID, grant.DelegatorID)
+}
+
+// AuthorizeConfidentialAccess enforces the stated-reason requirement for
+// confidential charts and records every access to one. Non-confidential
+// patients pass through untouched.
+func (s *CareTeamService) AuthorizeConfidentialAccess(patient *models.Patient, userID uint, action models.AuditAction, ipAddress, userAgent, accessReason string, emergencyAccess bool, purpose models.PurposeOfUse) error {
+	audit := s.audit.WithPurpose(purpose)
+
+	if !patient.IsConfidential() {
+		return nil
+	}
+
+	reason := strings.TrimSpace(accessReason)
+	if reason == "" && emergencyAccess {
+		reason = "emergency_access"
+	}
+
+	onCareTeam := s.isDirectCareTeam(patient.ID, userID)
+	if !onCareTeam {
+		if grant := s.DelegationFor(patient.ID, userID); grant != nil {
+			onCareTeam = true
+			if reason == "" {
+				reason = grant.AuditReason()
+			} else {
+				reason = fmt.Sprintf("%s (%s)", reason, grant.AuditReason())
+			}
+		}
+	}
+
+	if !onCareTeam && reason == "" {
+		audit.LogUnauthorizedAccess(userID, fmt.Sprintf("patient:%d", patient.ID), ipAddress, userAgent, "confidential_reason_required")
+		return fmt.Errorf("a stated reason is required to open this confidential chart")
+	}
+
+	audit.LogConfidentialPatientAccess(userID, patient, action, ipAddress, userAgent, reason, onCareTeam)
+
+	return nil
+}
+
+// MaskForSearch masks confidential patients outside the user's care team in a
+// result list and records every confidential patient that is revealed.
+// Employee-patients are recorded even when masked so colleagues' lookups are
+// always flagged.
+func (s *CareTeamService) MaskForSearch(patients []models.Patient, userID uint, ipAddress, userAgent, context string, purpose models.PurposeOfUse) []models.Patient {
+	audit := s.audit.WithPurpose(purpose)
+
+	var careTeam map[uint]bool
+
+	masked := make([]models.Patient, 0, len(patients))
+	for _, patient := range patients {
+		if !patient.IsConfidential() {
+			masked = append(masked, patient)
+			continue
+		}
+
+		if careTeam == nil {
+			careTeam = s.CareTeamPatientIDs(userID)
+		}
+
+		onCareTeam := careTeam[patient.ID]
+		if onCareTeam || patient.IsEmployee() {
+			audit.LogConfidentialPatientAccess(userID, &patient, models.ActionView, ipAddress, userAgent, context, onCareTeam)
+		}
+
+		if onCareTeam {
+			masked = append(masked, patient)
+		} else {
+			masked = append(masked, *patient.MaskConfidential())
+		}
+	}
+
+	return masked
+}
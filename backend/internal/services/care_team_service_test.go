@@ -0,0 +1,140 @@
+package services
+
+import (
+	"testing"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddCareTeamMember(t *testing.T) {
+	db := newTestDB(t)
+	careTeam := NewCareTeamService(db, NewAuditService(db, false))
+
+	member := createUser(t, db, models.RoleDoctor)
+	colleague := createUser(t, db, models.RoleDoctor)
+	nurse := createUser(t, db, models.RoleNurse)
+	patient := createPatient(t, db, true)
+	addToCareTeam(t, db, patient, member)
+
+	t.Run("MemberAddsColleague", func(t *testing.T) {
+		_, err := careTeam.AddMember(patient.ID, &AddCareTeamMemberRequest{UserID: nurse.ID}, member.ID, models.RoleDoctor, testIP, testUserAgent)
+		require.NoError(t, err)
+		assert.True(t, careTeam.IsOnCareTeam(patient.ID, nurse.ID))
+	})
+
+	t.Run("NoSelfAdd", func(t *testing.T) {
+		admin := createUser(t, db, models.RoleAdmin)
+		_, err := careTeam.AddMember(patient.ID, &AddCareTeamMemberRequest{UserID: admin.ID}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+		assert.Error(t, err)
+		assert.Equal(t, "care_team_self_add", lastAuditEntry(t, db, admin.ID).ErrorMessage)
+	})
+
+	t.Run("OutsiderCannotManage", func(t *testing.T) {
+		_, err := careTeam.AddMember(patient.ID, &AddCareTeamMemberRequest{UserID: member.ID}, colleague.ID, models.RoleDoctor, testIP, testUserAgent)
+		assert.Error(t, err)
+		assert.Equal(t, "not_on_care_team", lastAuditEntry(t, db, colleague.ID).ErrorMessage)
+	})
+
+	t.Run("NursesCannotManage", func(t *testing.T) {
+		err := careTeam.RemoveMember(patient.ID, member.ID, nurse.ID, models.RoleNurse, testIP, testUserAgent)
+		assert.Error(t, err)
+		assert.True(t, careTeam.IsOnCareTeam(patient.ID, member.ID))
+	})
+
+	t.Run("AuthoringRecordDoesNotGrantMembership", func(t *testing.T) {
+		createRecord(t, db, patient, colleague, models.SensitivityNormal, models.SeverityLow)
+		assert.False(t, careTeam.IsOnCareTeam(patient.ID, colleague.ID))
+		assert.False(t, careTeam.CareTeamPatientIDs(colleague.ID)[patient.ID])
+	})
+}
+
+func TestAuthorizeConfidentialAccess(t *testing.T) {
+	db := newTestDB(t)
+	careTeam := NewCareTeamService(db, NewAuditService(db, false))
+
+	member := createUser(t, db, models.RoleDoctor)
+	outsider := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, true)
+	addToCareTeam(t, db, patient, member)
+
+	t.Run("OutsiderNeedsReason", func(t *testing.T) {
+		err := careTeam.AuthorizeConfidentialAccess(patient, outsider.ID, models.ActionView, testIP, testUserAgent, "", false, "")
+		assert.Error(t, err)
+
+		entry := lastAuditEntry(t, db, outsider.ID)
+		assert.Equal(t, models.ActionUnauthorized, entry.Action)
+		assert.Equal(t, "confidential_reason_required", entry.ErrorMessage)
+	})
+
+	t.Run("LogsTheActionTaken", func(t *testing.T) {
+		for _, action := range []models.AuditAction{models.ActionView, models.ActionCreate, models.ActionUpdate, models.ActionDelete} {
+			require.NoError(t, careTeam.AuthorizeConfidentialAccess(patient, outsider.ID, action, testIP, testUserAgent, "Covering the ward", false, ""))
+
+			entry := lastAuditEntry(t, db, outsider.ID)
+			assert.Equal(t, action, entry.Action)
+			assert.Equal(t, "Covering the ward", entry.Reason)
+			assert.True(t, entry.SensitiveAccess)
+		}
+	})
+
+	t.Run("CareTeamNeedsNoReason", func(t *testing.T) {
+		require.NoError(t, careTeam.AuthorizeConfidentialAccess(patient, member.ID, models.ActionView, testIP, testUserAgent, "", false, ""))
+		assert.Equal(t, "confidential_patient", lastAuditEntry(t, db, member.ID).Sensitivity)
+	})
+
+	t.Run("MaskedInSearchOutsideCareTeam", func(t *testing.T) {
+		open := createPatient(t, db, false)
+		patients := []models.Patient{*patient, *open}
+
+		masked := careTeam.MaskForSearch(patients, outsider.ID, testIP, testUserAgent, "patient_search", "")
+		assert.NotEqual(t, patient.FirstName, masked[0].FirstName)
+		assert.Equal(t, open.FirstName, masked[1].FirstName)
+
+		revealed := careTeam.MaskForSearch(patients, member.ID, testIP, testUserAgent, "patient_search", "")
+		assert.Equal(t, patient.FirstName, revealed[0].FirstName)
+	})
+}
+
+func TestConfidentialChartWrites(t *testing.T) {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	careTeam := NewCareTeamService(db, audit)
+	consents := NewConsentService(db, audit, careTeam)
+	patients := NewPatientService(db, audit, consents, careTeam, models.DefaultFieldPolicy(), &configs.Config{}, nil)
+	records := NewMedicalRecordService(db, audit, consents, careTeam, models.DefaultFieldPolicy(), nil)
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, true)
+	phone := "555-0100"
+
+	t.Run("PatientUpdateNeedsReason", func(t *testing.T) {
+		_, err := patients.UpdatePatient(patient.ID, &UpdatePatientRequest{Phone: &phone}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.Error(t, err)
+
+		_, err = patients.UpdatePatient(patient.ID, &UpdatePatientRequest{Phone: &phone}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "Updating contact details", "")
+		require.NoError(t, err)
+
+		var confidential models.AuditLog
+		require.NoError(t, db.Where("user_id = ? AND sensitivity = ?", doctor.ID, "confidential_patient").Last(&confidential).Error)
+		assert.Equal(t, models.ActionUpdate, confidential.Action)
+	})
+
+	t.Run("RecordCreateLogsCreate", func(t *testing.T) {
+		req := &CreateMedicalRecordRequest{PatientID: patient.ID, Diagnosis: "Migraine", Treatment: "Rest", Severity: models.SeverityLow}
+
+		_, err := records.CreateMedicalRecord(req, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.Error(t, err)
+
+		_, err = records.CreateMedicalRecord(req, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "Clinic visit", "")
+		require.NoError(t, err)
+
+		var confidential models.AuditLog
+		require.NoError(t, db.Where("user_id = ? AND sensitivity = ?", doctor.ID, "confidential_patient").Last(&confidential).Error)
+		assert.Equal(t, models.ActionCreate, confidential.Action)
+		assert.Equal(t, "Clinic visit", confidential.Reason)
+	})
+}
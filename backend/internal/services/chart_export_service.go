@@ -0,0 +1,290 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/ccda"
+	"healthsecure/internal/fhir"
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExportFormat names a format a chart can be exported in
+type ExportFormat string
+
+const (
+	ExportFormatFHIR ExportFormat = "fhir"
+	ExportFormatCCDA ExportFormat = "ccda"
+)
+
+// ChartExportService produces copies of a patient's chart for the patient or
+// another provider. Every copy is logged as a disclosure to its recipient.
+type ChartExportService struct {
+	db          *gorm.DB
+	audit       *AuditService
+	consents    *ConsentService
+	careTeam    *CareTeamService
+	fieldPolicy models.FieldPolicy
+}
+
+// ExportChartRequest names the format and who the copy is for. Sensitive
+// records are left out unless asked for, and even then only those the caller
+// may read are included.
+type ExportChartRequest struct {
+	Format           ExportFormat `json:"format" binding:"required"`
+	Recipient        string       `json:"recipient" binding:"required"`
+	IncludeSensitive bool         `json:"include_sensitive"`
+}
+
+// ChartExport is a rendered chart copy
+type ChartExport struct {
+	Filename    string
+	ContentType string
+	Body        []byte
+}
+
+func NewChartExportService(db *gorm.DB, audit *AuditService, consents *ConsentService, careTeam *CareTeamService, fieldPolicy models.FieldPolicy) *ChartExportService {
+	return &ChartExportService{
+		db:          db,
+		audit:       audit,
+		consents:    consents,
+		careTeam:    careTeam,
+		fieldPolicy: fieldPolicy,
+	}
+}
+
+// ExportChart renders the patient's chart: demographics, problems, coded
+// diagnoses, allergies, medications, results and signed notes. The copy holds
+// exactly what the caller could read for the declared purpose, so a purpose
+// is required.
+func (s *ChartExportService) ExportChart(patientID uint, req *ExportChartRequest, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent, accessReason string, purpose models.PurposeOfUse) (*ChartExport, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	if requestedByRole != models.RoleDoctor && requestedByRole != models.RoleNurse {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("chart_export:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to export charts")
+	}
+	if purpose == "" {
+		return nil, fmt.Errorf("a purpose of use is required to export a chart")
+	}
+	if req.Format != ExportFormatFHIR && req.Format != ExportFormatCCDA {
+		return nil, fmt.Errorf("invalid export format")
+	}
+	recipient := strings.TrimSpace(req.Recipient)
+	if recipient == "" {
+		return nil, fmt.Errorf("recipient is required")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).Preload("Identifiers").First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	if err := s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, false, purpose); err != nil {
+		return nil, err
+	}
+
+	chart, disclosed, err := s.loadChart(&patient, req.IncludeSensitive, requestedByUserID, requestedByRole, ipAddress, userAgent, policy, audit)
+	if err != nil {
+		return nil, err
+	}
+
+	var requester models.User
+	s.db.Select("id, name").Where("id = ?", requestedByUserID).First(&requester)
+	chart.Author = requester.Name
+	chart.Recipient = recipient
+	chart.CreatedAt = time.Now()
+
+	export := &ChartExport{}
+	switch req.Format {
+	case ExportFormatFHIR:
+		export.Filename = fmt.Sprintf("chart-%s.json", patient.PublicID)
+		export.ContentType = "application/fhir+json"
+		export.Body, err = json.MarshalIndent(chartBundle(chart), "", "  ")
+	case ExportFormatCCDA:
+		export.Filename = fmt.Sprintf("chart-%s.xml", patient.PublicID)
+		export.ContentType = "application/xml"
+		export.Body, err = ccda.Render(chart)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart export: %w", err)
+	}
+
+	reason := s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
+	audit.LogChartExport(requestedByUserID, patientID, string(req.Format), recipient, ipAddress, userAgent, reason, disclosed)
+
+	return export, nil
+}
+
+// loadChart gathers the chart through the caller's role and field rules and
+// returns it with the fields disclosed, prefixed by the list they came from.
+// Lists the policy withholds from the caller, or that could reveal a withheld
+// record, are left nil.
+func (s *ChartExportService) loadChart(patient *models.Patient, includeSensitive bool, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, policy models.FieldPolicy, audit *AuditService) (*ccda.Chart, []string, error) {
+	chart := &ccda.Chart{Patient: &models.Patient{}}
+	var disclosed []string
+
+	sanitized := patient.SanitizeForRole(requestedByRole)
+	projection, fields := policy.Project(models.ResourcePatient, requestedByRole, sanitized, nil)
+	if err := decodeProjection(projection, chart.Patient); err != nil {
+		return nil, nil, err
+	}
+	disclosed = append(disclosed, prefixFields("patient", fields)...)
+
+	var problems []models.Problem
+	if err := s.db.Where("patient_id = ?", patient.ID).Order("created_at").Find(&problems).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve problems: %w", err)
+	}
+	var allergies []models.Allergy
+	if err := s.db.Where("patient_id = ?", patient.ID).Order("created_at").Find(&allergies).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve allergies: %w", err)
+	}
+	var medications []models.MedicationOrder
+	if err := s.db.Where("patient_id = ?", patient.ID).Preload("Prescriber").Order("start_date").Find(&medications).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve medications: %w", err)
+	}
+	var observations []models.Observation
+	if err := s.db.Where("patient_id = ?", patient.ID).Preload("Recorder").Order("effective_at").Find(&observations).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve observations: %w", err)
+	}
+
+	// Only signed notes are part of the chart. Withheld and unconsented
+	// records are dropped first, then the role and export rules apply.
+	var records []models.MedicalRecord
+	if err := s.db.Where("patient_id = ?", patient.ID).
+		Preload("Doctor").Preload("Diagnoses", models.PreloadDiagnoses).
+		Order("created_at").Find(&records).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve medical records: %w", err)
+	}
+	disclosable := make(map[uint]bool, len(records))
+	for _, record := range s.consents.FilterDisclosable(records, requestedByUserID) {
+		disclosable[record.ID] = true
+	}
+
+	// The structured lists carry no sensitivity of their own. Entries filed
+	// under an encounter with a withheld record are withheld with it, and
+	// once anything is withheld, entries that cannot be tied to an encounter
+	// are too.
+	var exported []models.MedicalRecord
+	withheld := false
+	withheldEncounters := map[uint]bool{}
+	for _, record := range records {
+		if !disclosable[record.ID] || (record.IsExcludedFromDefaultExport() && (!includeSensitive || !record.CanBeAccessedByRole(requestedByRole, requestedByUserID))) {
+			withheld = true
+			if record.EncounterID != nil {
+				withheldEncounters[*record.EncounterID] = true
+			}
+			continue
+		}
+		if record.Status != models.RecordStatusSigned || !record.CanBeAccessedByRole(requestedByRole, requestedByUserID) {
+			continue
+		}
+		if err := record.CheckIntegrity(); err != nil {
+			return nil, nil, fmt.Errorf("medical record %d: %w", record.ID, err)
+		}
+		if record.IsSensitive() {
+			audit.LogSensitiveRecordAccess(requestedByUserID, patient.ID, record.ID, record.Sensitivity, models.ActionView, ipAddress, userAgent, false, "chart_export")
+		}
+		exported = append(exported, *record.SanitizeForRole(requestedByRole))
+	}
+	if withheld {
+		keptMedications := make([]models.MedicationOrder, 0, len(medications))
+		for _, order := range medications {
+			if order.EncounterID != nil && !withheldEncounters[*order.EncounterID] {
+				keptMedications = append(keptMedications, order)
+			}
+		}
+		keptObservations := make([]models.Observation, 0, len(observations))
+		for _, observation := range observations {
+			if observation.EncounterID != nil && !withheldEncounters[*observation.EncounterID] {
+				keptObservations = append(keptObservations, observation)
+			}
+		}
+		medications, observations = keptMedications, keptObservations
+	}
+
+	for _, list := range []struct {
+		name     string
+		resource string
+		entries  interface{}
+		out      interface{}
+		withheld bool
+	}{
+		{"problems", models.ResourceProblem, problems, &chart.Problems, withheld},
+		{"allergies", models.ResourceAllergy, allergies, &chart.Allergies, withheld},
+		{"medications", models.ResourceMedication, medications, &chart.Medications, false},
+		{"observations", models.ResourceObservation, observations, &chart.Observations, false},
+		{"medical_records", models.ResourceMedicalRecord, exported, &chart.Records, false},
+	} {
+		if list.withheld || len(policy[list.resource][requestedByRole]) == 0 {
+			continue
+		}
+		projections, fields := policy.ProjectAll(list.resource, requestedByRole, list.entries, nil)
+		if err := decodeProjection(projections, list.out); err != nil {
+			return nil, nil, err
+		}
+		disclosed = append(disclosed, prefixFields(list.name, fields)...)
+	}
+
+	return chart, disclosed, nil
+}
+
+// decodeProjection reads a projection back into its model, so that exports
+// and FHIR resources are built only from the fields the projection kept
+func decodeProjection(projection interface{}, out interface{}) error {
+	data, err := json.Marshal(projection)
+	if err != nil {
+		return fmt.Errorf("failed to decode projection: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode projection: %w", err)
+	}
+	return nil
+}
+
+func prefixFields(prefix string, fields []string) []string {
+	prefixed := make([]string, len(fields))
+	for i, field := range fields {
+		prefixed[i] = prefix + "." + field
+	}
+	return prefixed
+}
+
+// chartBundle maps a chart to a FHIR collection bundle. Entries reference the
+// patient by its fullUrl.
+func chartBundle(chart *ccda.Chart) *fhir.Bundle {
+	bundle := fhir.NewCollection(chart.CreatedAt)
+
+	patientURL := fhir.NewEntryURL()
+	bundle.Add(patientURL, fhir.NewPatient(chart.Patient))
+	subject := fhir.Reference{Reference: patientURL}
+
+	for i := range chart.Problems {
+		bundle.Add(fhir.NewEntryURL(), fhir.NewProblemCondition(&chart.Problems[i], subject))
+	}
+	for i := range chart.Records {
+		for _, condition := range fhir.NewDiagnosisConditions(&chart.Records[i], subject) {
+			bundle.Add(fhir.NewEntryURL(), condition)
+		}
+	}
+	for i := range chart.Allergies {
+		bundle.Add(fhir.NewEntryURL(), fhir.NewAllergyIntolerance(&chart.Allergies[i], subject))
+	}
+	for i := range chart.Medications {
+		bundle.Add(fhir.NewEntryURL(), fhir.NewMedicationStatement(&chart.Medications[i], subject))
+	}
+	for i := range chart.Observations {
+		bundle.Add(fhir.NewEntryURL(), fhir.NewObservation(&chart.Observations[i], subject))
+	}
+	for i := range chart.Records {
+		bundle.Add(fhir.NewEntryURL(), fhir.NewDocumentReference(&chart.Records[i], subject))
+	}
+
+	return bundle
+}
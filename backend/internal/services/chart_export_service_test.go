@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChartExportWithholding(t *testing.T) {
+	consents, records := newConsentService(t)
+	db := consents.db
+	exports := NewChartExportService(db, records.audit, consents, records.careTeam, models.DefaultFieldPolicy())
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, doctor)
+	visit := createEncounter(t, db, patient, doctor)
+	createRecord(t, db, patient, doctor, models.SensitivityNormal, models.SeverityLow)
+	require.NoError(t, db.Create(&models.Problem{PatientID: patient.ID, Condition: "Hypertension", RecordedBy: doctor.ID}).Error)
+
+	prescribe := func(drug string, encounterID *uint) {
+		order := &models.MedicationOrder{PatientID: patient.ID, EncounterID: encounterID, Drug: drug, Dose: "10 mg", Route: models.RouteOral, Frequency: "daily", StartDate: time.Now(), PrescriberID: doctor.ID}
+		require.NoError(t, db.Create(order).Error)
+	}
+	prescribe("Lisinopril", &visit.ID)
+	prescribe("Metformin", nil)
+
+	export := func(includeSensitive bool) string {
+		req := &ExportChartRequest{Format: ExportFormatFHIR, Recipient: "Dr. Smith, Cardiology Associates", IncludeSensitive: includeSensitive}
+		chart, err := exports.ExportChart(patient.ID, req, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", models.PurposeTreatment)
+		require.NoError(t, err)
+		return string(chart.Body)
+	}
+
+	t.Run("FullChartWhenNothingWithheld", func(t *testing.T) {
+		body := export(false)
+		assert.Contains(t, body, "Hypertension")
+		assert.Contains(t, body, "Lisinopril")
+		assert.Contains(t, body, "Metformin")
+	})
+
+	therapy := createEncounter(t, db, patient, doctor)
+	sensitive := createRecord(t, db, patient, doctor, models.SensitivityMentalHealth, models.SeverityLow)
+	require.NoError(t, db.Model(sensitive).UpdateColumn("encounter_id", therapy.ID).Error)
+	prescribe("Sertraline", &therapy.ID)
+
+	t.Run("ListsThatCouldRevealWithheldRecordsAreLeftOut", func(t *testing.T) {
+		body := export(false)
+		assert.NotContains(t, body, "Sertraline")
+		assert.NotContains(t, body, "Metformin", "orders outside an encounter cannot be ruled out")
+		assert.NotContains(t, body, "Hypertension")
+		assert.Contains(t, body, "Lisinopril")
+
+		entry := lastAuditEntry(t, db, doctor.ID)
+		assert.Equal(t, models.ActionExport, entry.Action)
+		assert.NotContains(t, entry.DisclosedFields, "problems.")
+	})
+
+	t.Run("IncludedWhenAskedFor", func(t *testing.T) {
+		body := export(true)
+		assert.Contains(t, body, "Sertraline")
+		assert.Contains(t, body, "Metformin")
+		assert.Contains(t, body, "Hypertension")
+	})
+}
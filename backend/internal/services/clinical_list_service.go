@@ -0,0 +1,586 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/cds"
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ClinicalListService manages a patient's structured medication list,
+// allergies and problem list. Reads follow the same role, field policy,
+// confidentiality and audit rules as medical records; only doctors write.
+// Medication orders are checked against the patient's allergies and active
+// medications before they are saved.
+type ClinicalListService struct {
+	db          *gorm.DB
+	audit       *AuditService
+	careTeam    *CareTeamService
+	fieldPolicy models.FieldPolicy
+	checker     *cds.Checker
+}
+
+type CreateMedicationRequest struct {
+	Drug      string                 `json:"drug" binding:"required"`
+	Dose      string                 `json:"dose" binding:"required"`
+	Route     models.MedicationRoute `json:"route" binding:"required"`
+	Frequency string                 `json:"frequency" binding:"required"`
+	StartDate *time.Time             `json:"start_date,omitempty"`
+	StopDate  *time.Time             `json:"stop_date,omitempty"`
+
+	EncounterID *uint `json:"encounter_id,omitempty"`
+
+	// Required to prescribe despite severe alerts
+	OverrideReason string `json:"override_reason,omitempty"`
+}
+
+type UpdateMedicationRequest struct {
+	Dose        *string                 `json:"dose,omitempty"`
+	Route       *models.MedicationRoute `json:"route,omitempty"`
+	Frequency   *string                 `json:"frequency,omitempty"`
+	StopDate    *time.Time              `json:"stop_date,omitempty"`
+	Discontinue bool                    `json:"discontinue,omitempty"`
+
+	// Required to keep the order despite severe alerts
+	OverrideReason string `json:"override_reason,omitempty"`
+}
+
+type CreateAllergyRequest struct {
+	Substance string                 `json:"substance" binding:"required"`
+	Reaction  string                 `json:"reaction"`
+	Severity  models.AllergySeverity `json:"severity" binding:"required"`
+}
+
+type UpdateAllergyRequest struct {
+	Reaction *string                 `json:"reaction,omitempty"`
+	Severity *models.AllergySeverity `json:"severity,omitempty"`
+	Status   *models.AllergyStatus   `json:"status,omitempty"`
+}
+
+type CreateProblemRequest struct {
+	Condition string               `json:"condition" binding:"required"`
+	OnsetDate *time.Time           `json:"onset_date,omitempty"`
+	Status    models.ProblemStatus `json:"status"`
+}
+
+type UpdateProblemRequest struct {
+	Condition *string               `json:"condition,omitempty"`
+	OnsetDate *time.Time            `json:"onset_date,omitempty"`
+	Status    *models.ProblemStatus `json:"status,omitempty"`
+}
+
+// ClinicalListQuery filters a clinical list. The FHIR API also reads single
+// entries by ID and filters by last update, from UpdatedSince up to but not
+// including UpdatedBefore.
+type ClinicalListQuery struct {
+	Status        string
+	EncounterID   *uint
+	ID            uint
+	UpdatedSince  *time.Time
+	UpdatedBefore *time.Time
+}
+
+func NewClinicalListService(db *gorm.DB, audit *AuditService, careTeam *CareTeamService, fieldPolicy models.FieldPolicy, checker *cds.Checker) *ClinicalListService {
+	return &ClinicalListService{
+		db:          db,
+		audit:       audit,
+		careTeam:    careTeam,
+		fieldPolicy: fieldPolicy,
+		checker:     checker,
+	}
+}
+
+// GetMedications lists a patient's medication orders, newest first
+func (s *ClinicalListService) GetMedications(patientID uint, filter *ClinicalListQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) ([]models.Projection, error) {
+	if err := s.authorizeRead(models.ResourceMedication, "medications", patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	query := filter.apply(s.db.Where("patient_id = ?", patientID))
+
+	var orders []models.MedicationOrder
+	if err := query.Preload("Prescriber").Order("start_date DESC").Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve medications: %w", err)
+	}
+
+	return s.projectList(models.ResourceMedication, "medications", orders, patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, purpose, fields), nil
+}
+
+// CreateMedication adds an order prescribed by the calling doctor. Alerts
+// raised by the interaction and allergy check are returned with the order;
+// severe alerts without an override reason return cds.ErrOverrideRequired
+// and save nothing.
+func (s *ClinicalListService) CreateMedication(patientID uint, req *CreateMedicationRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) (*models.MedicationOrder, []cds.Alert, error) {
+	if err := s.authorizeWrite("medications", patientID, createdByUserID, createdByRole, models.ActionCreate, ipAddress, userAgent, accessReason, purpose); err != nil {
+		return nil, nil, err
+	}
+
+	if !models.IsValidMedicationRoute(req.Route) {
+		return nil, nil, fmt.Errorf("invalid medication route")
+	}
+	if err := checkEncounter(s.db, patientID, req.EncounterID); err != nil {
+		return nil, nil, err
+	}
+
+	order := models.MedicationOrder{
+		PatientID:    patientID,
+		EncounterID:  req.EncounterID,
+		Drug:         req.Drug,
+		Dose:         req.Dose,
+		Route:        req.Route,
+		Frequency:    req.Frequency,
+		StopDate:     req.StopDate,
+		PrescriberID: createdByUserID,
+	}
+	if req.StartDate != nil {
+		order.StartDate = *req.StartDate
+	}
+	if order.StopDate != nil && req.StartDate != nil && !order.StopDate.After(order.StartDate) {
+		return nil, nil, fmt.Errorf("stop date must be after start date")
+	}
+
+	alerts, err := s.checkMedication(patientID, order.Drug, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.logAlerts(patientID, alerts, createdByUserID, ipAddress, userAgent, purpose)
+	overrideReason := strings.TrimSpace(req.OverrideReason)
+	if cds.RequiresOverride(alerts) && overrideReason == "" {
+		return nil, alerts, cds.ErrOverrideRequired
+	}
+
+	if err := s.db.Create(&order).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to create medication order: %w", err)
+	}
+
+	s.logWrite(fmt.Sprintf("medication:%d", order.ID), patientID, createdByUserID, models.ActionCreate, ipAddress, userAgent, "medication_ordered", purpose)
+	s.logOverride(&order, alerts, overrideReason, createdByUserID, ipAddress, userAgent, purpose)
+
+	return &order, alerts, nil
+}
+
+// UpdateMedication changes the dose, route, frequency or stop date of an
+// order, or discontinues it. A different drug is a new order. Changes to an
+// active order are checked again like a new prescription.
+func (s *ClinicalListService) UpdateMedication(patientID, orderID uint, req *UpdateMedicationRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) (*models.MedicationOrder, []cds.Alert, error) {
+	if err := s.authorizeWrite("medications", patientID, updatedByUserID, updatedByRole, models.ActionUpdate, ipAddress, userAgent, accessReason, purpose); err != nil {
+		return nil, nil, err
+	}
+
+	var order models.MedicationOrder
+	if err := s.db.Where("id = ? AND patient_id = ?", orderID, patientID).First(&order).Error; err != nil {
+		return nil, nil, fmt.Errorf("medication order not found")
+	}
+
+	updated := make([]string, 0, 6)
+	if req.Dose != nil {
+		order.Dose = *req.Dose
+		updated = append(updated, "dose")
+	}
+	if req.Route != nil {
+		if !models.IsValidMedicationRoute(*req.Route) {
+			return nil, nil, fmt.Errorf("invalid medication route")
+		}
+		order.Route = *req.Route
+		updated = append(updated, "route")
+	}
+	if req.Frequency != nil {
+		order.Frequency = *req.Frequency
+		updated = append(updated, "frequency")
+	}
+	if req.StopDate != nil {
+		if !req.StopDate.After(order.StartDate) {
+			return nil, nil, fmt.Errorf("stop date must be after start date")
+		}
+		order.StopDate = req.StopDate
+		updated = append(updated, "stop_date")
+	}
+	reason := "medication_updated"
+	if req.Discontinue {
+		if err := order.Discontinue(time.Now()); err != nil {
+			return nil, nil, fmt.Errorf("medication order is already discontinued")
+		}
+		updated = append(updated, "status", "stop_date")
+		reason = "medication_discontinued"
+	}
+
+	var alerts []cds.Alert
+	overrideReason := strings.TrimSpace(req.OverrideReason)
+	if len(updated) > 0 && !req.Discontinue && order.Status == models.MedicationStatusActive {
+		var err error
+		alerts, err = s.checkMedication(patientID, order.Drug, order.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.logAlerts(patientID, alerts, updatedByUserID, ipAddress, userAgent, purpose)
+		if cds.RequiresOverride(alerts) && overrideReason == "" {
+			return nil, alerts, cds.ErrOverrideRequired
+		}
+	}
+
+	if len(updated) > 0 {
+		order.UpdatedAt = time.Now()
+		updated = append(updated, "updated_at")
+		if err := s.db.Model(&order).Select(updated).Updates(&order).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to update medication order: %w", err)
+		}
+	}
+
+	s.logWrite(fmt.Sprintf("medication:%d", order.ID), patientID, updatedByUserID, models.ActionUpdate, ipAddress, userAgent, reason, purpose)
+	s.logOverride(&order, alerts, overrideReason, updatedByUserID, ipAddress, userAgent, purpose)
+
+	return &order, alerts, nil
+}
+
+// DeleteMedication removes an order entered in error
+func (s *ClinicalListService) DeleteMedication(patientID, orderID uint, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) error {
+	return s.deleteEntry(&models.MedicationOrder{}, "medications", "medication", patientID, orderID, deletedByUserID, deletedByRole, ipAddress, userAgent, accessReason, purpose)
+}
+
+// GetAllergies lists a patient's allergies, newest first
+func (s *ClinicalListService) GetAllergies(patientID uint, filter *ClinicalListQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) ([]models.Projection, error) {
+	if err := s.authorizeRead(models.ResourceAllergy, "allergies", patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	query := filter.apply(s.db.Where("patient_id = ?", patientID))
+
+	var allergies []models.Allergy
+	if err := query.Order("created_at DESC").Find(&allergies).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve allergies: %w", err)
+	}
+
+	return s.projectList(models.ResourceAllergy, "allergies", allergies, patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, purpose, fields), nil
+}
+
+// CreateAllergy records an allergy
+func (s *ClinicalListService) CreateAllergy(patientID uint, req *CreateAllergyRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) (*models.Allergy, error) {
+	if err := s.authorizeWrite("allergies", patientID, createdByUserID, createdByRole, models.ActionCreate, ipAddress, userAgent, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	if !models.IsValidAllergySeverity(req.Severity) {
+		return nil, fmt.Errorf("invalid allergy severity")
+	}
+
+	allergy := models.Allergy{
+		PatientID:  patientID,
+		Substance:  req.Substance,
+		Reaction:   req.Reaction,
+		Severity:   req.Severity,
+		RecordedBy: createdByUserID,
+	}
+	if err := s.db.Create(&allergy).Error; err != nil {
+		return nil, fmt.Errorf("failed to record allergy: %w", err)
+	}
+
+	s.logWrite(fmt.Sprintf("allergy:%d", allergy.ID), patientID, createdByUserID, models.ActionCreate, ipAddress, userAgent, "allergy_recorded", purpose)
+
+	return &allergy, nil
+}
+
+// UpdateAllergy changes the reaction, severity or status of an allergy
+func (s *ClinicalListService) UpdateAllergy(patientID, allergyID uint, req *UpdateAllergyRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) (*models.Allergy, error) {
+	if err := s.authorizeWrite("allergies", patientID, updatedByUserID, updatedByRole, models.ActionUpdate, ipAddress, userAgent, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	var allergy models.Allergy
+	if err := s.db.Where("id = ? AND patient_id = ?", allergyID, patientID).First(&allergy).Error; err != nil {
+		return nil, fmt.Errorf("allergy not found")
+	}
+
+	updated := make([]string, 0, 4)
+	if req.Reaction != nil {
+		allergy.Reaction = *req.Reaction
+		updated = append(updated, "reaction")
+	}
+	if req.Severity != nil {
+		if !models.IsValidAllergySeverity(*req.Severity) {
+			return nil, fmt.Errorf("invalid allergy severity")
+		}
+		allergy.Severity = *req.Severity
+		updated = append(updated, "severity")
+	}
+	if req.Status != nil {
+		if *req.Status != models.AllergyStatusActive && *req.Status != models.AllergyStatusInactive {
+			return nil, fmt.Errorf("invalid allergy status")
+		}
+		allergy.Status = *req.Status
+		updated = append(updated, "status")
+	}
+
+	if len(updated) > 0 {
+		allergy.UpdatedAt = time.Now()
+		updated = append(updated, "updated_at")
+		if err := s.db.Model(&allergy).Select(updated).Updates(&allergy).Error; err != nil {
+			return nil, fmt.Errorf("failed to update allergy: %w", err)
+		}
+	}
+
+	s.logWrite(fmt.Sprintf("allergy:%d", allergy.ID), patientID, updatedByUserID, models.ActionUpdate, ipAddress, userAgent, "allergy_updated", purpose)
+
+	return &allergy, nil
+}
+
+// DeleteAllergy removes an allergy entered in error
+func (s *ClinicalListService) DeleteAllergy(patientID, allergyID uint, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) error {
+	return s.deleteEntry(&models.Allergy{}, "allergies", "allergy", patientID, allergyID, deletedByUserID, deletedByRole, ipAddress, userAgent, accessReason, purpose)
+}
+
+// GetProblems lists a patient's problem list, newest first
+func (s *ClinicalListService) GetProblems(patientID uint, filter *ClinicalListQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) ([]models.Projection, error) {
+	if err := s.authorizeRead(models.ResourceProblem, "problems", patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	query := filter.apply(s.db.Where("patient_id = ?", patientID))
+
+	var problems []models.Problem
+	if err := query.Order("created_at DESC").Find(&problems).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve problems: %w", err)
+	}
+
+	return s.projectList(models.ResourceProblem, "problems", problems, patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, purpose, fields), nil
+}
+
+// CreateProblem adds a condition to the problem list
+func (s *ClinicalListService) CreateProblem(patientID uint, req *CreateProblemRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) (*models.Problem, error) {
+	if err := s.authorizeWrite("problems", patientID, createdByUserID, createdByRole, models.ActionCreate, ipAddress, userAgent, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	problem := models.Problem{
+		PatientID:  patientID,
+		Condition:  req.Condition,
+		OnsetDate:  req.OnsetDate,
+		RecordedBy: createdByUserID,
+	}
+	if req.Status != "" {
+		if err := problem.SetStatus(req.Status, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid problem status")
+		}
+	}
+
+	if err := s.db.Create(&problem).Error; err != nil {
+		return nil, fmt.Errorf("failed to add problem: %w", err)
+	}
+
+	s.logWrite(fmt.Sprintf("problem:%d", problem.ID), patientID, createdByUserID, models.ActionCreate, ipAddress, userAgent, "problem_added", purpose)
+
+	return &problem, nil
+}
+
+// UpdateProblem changes a problem's wording, onset or status
+func (s *ClinicalListService) UpdateProblem(patientID, problemID uint, req *UpdateProblemRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) (*models.Problem, error) {
+	if err := s.authorizeWrite("problems", patientID, updatedByUserID, updatedByRole, models.ActionUpdate, ipAddress, userAgent, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	var problem models.Problem
+	if err := s.db.Where("id = ? AND patient_id = ?", problemID, patientID).First(&problem).Error; err != nil {
+		return nil, fmt.Errorf("problem not found")
+	}
+
+	updated := make([]string, 0, 5)
+	if req.Condition != nil {
+		problem.Condition = *req.Condition
+		updated = append(updated, "condition_text")
+	}
+	if req.OnsetDate != nil {
+		problem.OnsetDate = req.OnsetDate
+		updated = append(updated, "onset_date")
+	}
+	if req.Status != nil {
+		if err := problem.SetStatus(*req.Status, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid problem status")
+		}
+		updated = append(updated, "status", "resolved_date")
+	}
+
+	if len(updated) > 0 {
+		problem.UpdatedAt = time.Now()
+		updated = append(updated, "updated_at")
+		if err := s.db.Model(&problem).Select(updated).Updates(&problem).Error; err != nil {
+			return nil, fmt.Errorf("failed to update problem: %w", err)
+		}
+	}
+
+	s.logWrite(fmt.Sprintf("problem:%d", problem.ID), patientID, updatedByUserID, models.ActionUpdate, ipAddress, userAgent, "problem_updated", purpose)
+
+	return &problem, nil
+}
+
+// DeleteProblem removes a problem entered in error
+func (s *ClinicalListService) DeleteProblem(patientID, problemID uint, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) error {
+	return s.deleteEntry(&models.Problem{}, "problems", "problem", patientID, problemID, deletedByUserID, deletedByRole, ipAddress, userAgent, accessReason, purpose)
+}
+
+func (q *ClinicalListQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.EncounterID != nil {
+		db = db.Where("encounter_id = ?", *q.EncounterID)
+	}
+	if q.ID != 0 {
+		db = db.Where("id = ?", q.ID)
+	}
+	if q.UpdatedSince != nil {
+		db = db.Where("updated_at >= ?", *q.UpdatedSince)
+	}
+	if q.UpdatedBefore != nil {
+		db = db.Where("updated_at < ?", *q.UpdatedBefore)
+	}
+	return db
+}
+
+// authorizeRead admits roles the field policy shows the list to, then applies
+// the confidential-chart rules
+func (s *ClinicalListService) authorizeRead(resource, list string, patientID, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) error {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	if len(policy[resource][requestedByRole]) == 0 {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("%s:patient_%d", list, patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to access %s", list)
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return fmt.Errorf("patient not found")
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	return s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose)
+}
+
+// authorizeWrite admits doctors to change the lists of an existing patient,
+// under the same confidential-chart rules as reads
+func (s *ClinicalListService) authorizeWrite(list string, patientID, userID uint, role models.UserRole, action models.AuditAction, ipAddress, userAgent, accessReason string, purpose models.PurposeOfUse) error {
+	if role != models.RoleDoctor {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(userID, fmt.Sprintf("%s:patient_%d", list, patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to change %s", list)
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return fmt.Errorf("patient not found")
+	}
+	return s.careTeam.AuthorizeConfidentialAccess(&patient, userID, action, ipAddress, userAgent, accessReason, false, purpose)
+}
+
+// projectList renders entries through the caller's field rules and logs the
+// fields disclosed
+func (s *ClinicalListService) projectList(resource, list string, entries interface{}, patientID, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, purpose models.PurposeOfUse, fields []string) []models.Projection {
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	projections, disclosed := policy.ProjectAll(resource, requestedByRole, entries, fields)
+	if projections == nil {
+		projections = []models.Projection{}
+	}
+
+	reason := ""
+	if emergencyAccess {
+		reason = "emergency_access"
+	} else {
+		reason = s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
+	}
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(requestedByUserID, patientID, fmt.Sprintf("%s:patient_%d", list, patientID),
+		models.ActionView, ipAddress, userAgent, emergencyAccess, reason, disclosed)
+
+	return projections
+}
+
+func (s *ClinicalListService) deleteEntry(entry interface{}, list, resource string, patientID, entryID, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string, accessReason string, purpose models.PurposeOfUse) error {
+	if err := s.authorizeWrite(list, patientID, deletedByUserID, deletedByRole, models.ActionDelete, ipAddress, userAgent, accessReason, purpose); err != nil {
+		return err
+	}
+
+	result := s.db.Where("id = ? AND patient_id = ?", entryID, patientID).Delete(entry)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete %s: %w", resource, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%s not found", resource)
+	}
+
+	s.logWrite(fmt.Sprintf("%s:%d", resource, entryID), patientID, deletedByUserID, models.ActionDelete, ipAddress, userAgent, "entered_in_error", purpose)
+
+	return nil
+}
+
+// checkMedication runs the interaction and allergy check for drug against the
+// patient's other active orders and allergies
+func (s *ClinicalListService) checkMedication(patientID uint, drug string, excludeOrderID uint) ([]cds.Alert, error) {
+	var orders []models.MedicationOrder
+	if err := s.db.Where("patient_id = ? AND status = ? AND id <> ?", patientID, models.MedicationStatusActive, excludeOrderID).
+		Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to check medications: %w", err)
+	}
+
+	var allergies []models.Allergy
+	if err := s.db.Where("patient_id = ? AND status = ?", patientID, models.AllergyStatusActive).
+		Find(&allergies).Error; err != nil {
+		return nil, fmt.Errorf("failed to check allergies: %w", err)
+	}
+
+	return s.checker.Check(drug, orders, allergies, time.Now()), nil
+}
+
+// logAlerts records the allergies and active orders that alerts disclose to
+// the prescriber, whether or not the order then goes ahead
+func (s *ClinicalListService) logAlerts(patientID uint, alerts []cds.Alert, userID uint, ipAddress, userAgent string, purpose models.PurposeOfUse) {
+	if len(alerts) == 0 {
+		return
+	}
+
+	entries := make([]string, 0, len(alerts))
+	fields := map[string]bool{}
+	for _, alert := range alerts {
+		switch alert.Type {
+		case cds.AlertAllergy:
+			entries = append(entries, fmt.Sprintf("allergy_%d", alert.ConflictID))
+			fields["allergy.substance"], fields["allergy.reaction"] = true, true
+		case cds.AlertInteraction:
+			entries = append(entries, fmt.Sprintf("medication_%d", alert.ConflictID))
+			fields["medication.drug"] = true
+		}
+	}
+	disclosed := make([]string, 0, len(fields))
+	for _, field := range []string{"allergy.reaction", "allergy.substance", "medication.drug"} {
+		if fields[field] {
+			disclosed = append(disclosed, field)
+		}
+	}
+
+	reason := historyReason(s.careTeam.DelegatedAccessReason(patientID, userID), "alerts:"+strings.Join(entries, ","))
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(userID, patientID, fmt.Sprintf("medication_alerts:patient_%d", patientID),
+		models.ActionView, ipAddress, userAgent, false, reason, disclosed)
+}
+
+// logOverride records a prescriber going ahead despite severe alerts
+func (s *ClinicalListService) logOverride(order *models.MedicationOrder, alerts []cds.Alert, overrideReason string, userID uint, ipAddress, userAgent string, purpose models.PurposeOfUse) {
+	if !cds.RequiresOverride(alerts) {
+		return
+	}
+
+	overridden := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.RequiresOverride() {
+			overridden = append(overridden, alert.String())
+		}
+	}
+
+	reason := fmt.Sprintf("overrode %s: %s", strings.Join(overridden, "; "), overrideReason)
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(userID, order.PatientID, fmt.Sprintf("medication:%d", order.ID),
+		models.ActionAlertOverride, ipAddress, userAgent, false, reason, nil)
+}
+
+func (s *ClinicalListService) logWrite(resource string, patientID, userID uint, action models.AuditAction, ipAddress, userAgent, reason string, purpose models.PurposeOfUse) {
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(userID, patientID, resource, action, ipAddress, userAgent, false, reason, nil)
+}
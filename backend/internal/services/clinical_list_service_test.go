@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"healthsecure/internal/cds"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClinicalListService(t *testing.T) *ClinicalListService {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	return NewClinicalListService(db, audit, NewCareTeamService(db, audit), models.DefaultFieldPolicy(), cds.NewChecker(cds.DefaultDataset()))
+}
+
+func TestConfidentialListWrites(t *testing.T) {
+	lists := newClinicalListService(t)
+	db := lists.db
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, true)
+
+	confidentialEntry := func() models.AuditLog {
+		var entry models.AuditLog
+		require.NoError(t, db.Where("user_id = ? AND sensitivity = ?", doctor.ID, "confidential_patient").Last(&entry).Error)
+		return entry
+	}
+
+	problem := &CreateProblemRequest{Condition: "Hypertension"}
+	_, err := lists.CreateProblem(patient.ID, problem, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+	assert.Error(t, err)
+
+	created, err := lists.CreateProblem(patient.ID, problem, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "Clinic follow-up", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.ActionCreate, confidentialEntry().Action)
+
+	condition := "Essential hypertension"
+	_, err = lists.UpdateProblem(patient.ID, created.ID, &UpdateProblemRequest{Condition: &condition}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+	assert.Error(t, err)
+	_, err = lists.UpdateProblem(patient.ID, created.ID, &UpdateProblemRequest{Condition: &condition}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "Clinic follow-up", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.ActionUpdate, confidentialEntry().Action)
+
+	assert.Error(t, lists.DeleteProblem(patient.ID, created.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", ""))
+	require.NoError(t, lists.DeleteProblem(patient.ID, created.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "Entered in error", ""))
+	assert.Equal(t, models.ActionDelete, confidentialEntry().Action)
+}
+
+func TestMedicationAlertsAreAudited(t *testing.T) {
+	lists := newClinicalListService(t)
+	db := lists.db
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+
+	allergy, err := lists.CreateAllergy(patient.ID, &CreateAllergyRequest{Substance: "Penicillin", Reaction: "Hives", Severity: models.AllergySeverityModerate}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+	require.NoError(t, err)
+
+	alertEntry := func() models.AuditLog {
+		var entry models.AuditLog
+		require.NoError(t, db.Where("user_id = ? AND resource = ?", doctor.ID, fmt.Sprintf("medication_alerts:patient_%d", patient.ID)).Last(&entry).Error)
+		return entry
+	}
+
+	warfarin, _, err := lists.CreateMedication(patient.ID, &CreateMedicationRequest{Drug: "Warfarin", Dose: "5 mg", Route: models.RouteOral, Frequency: "daily"}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+	require.NoError(t, err)
+
+	t.Run("LoggedWhenOrderIsRefused", func(t *testing.T) {
+		_, alerts, err := lists.CreateMedication(patient.ID, &CreateMedicationRequest{Drug: "Ibuprofen", Dose: "400 mg", Route: models.RouteOral, Frequency: "tid"}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.Equal(t, cds.ErrOverrideRequired, err)
+		require.NotEmpty(t, alerts)
+
+		entry := alertEntry()
+		assert.Contains(t, entry.Reason, fmt.Sprintf("medication_%d", warfarin.ID))
+		assert.Equal(t, "medication.drug", entry.DisclosedFields)
+	})
+
+	t.Run("AllergyAlertDisclosesAllergy", func(t *testing.T) {
+		_, alerts, err := lists.CreateMedication(patient.ID, &CreateMedicationRequest{Drug: "Cephalexin", Dose: "500 mg", Route: models.RouteOral, Frequency: "qid"}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		require.NoError(t, err)
+		require.NotEmpty(t, alerts)
+
+		entry := alertEntry()
+		assert.Contains(t, entry.Reason, fmt.Sprintf("allergy_%d", allergy.ID))
+		assert.Equal(t, "allergy.reaction,allergy.substance", entry.DisclosedFields)
+	})
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ConsentService struct {
+	db       *gorm.DB
+	audit    *AuditService
+	careTeam *CareTeamService
+}
+
+// CreateConsentRequest describes a consent to record. PatientAttestation
+// references the patient's signed consent form and is required unless the
+// recording doctor attends the patient or is on the care team.
+type CreateConsentRequest struct {
+	Category           models.SensitivityLevel `json:"category" binding:"required"`
+	GranteeUserID      *uint                   `json:"grantee_user_id,omitempty"`
+	Purpose            string                  `json:"purpose" binding:"required"`
+	PatientAttestation string                  `json:"patient_attestation,omitempty"`
+	ExpiresAt          *time.Time              `json:"expires_at,omitempty"`
+}
+
+func NewConsentService(db *gorm.DB, audit *AuditService, careTeam *CareTeamService) *ConsentService {
+	return &ConsentService{
+		db:       db,
+		audit:    audit,
+		careTeam: careTeam,
+	}
+}
+
+// RecordConsent stores a patient's consent to disclose a sensitive category.
+// A doctor may not name themselves as the grantee, and substance use consents
+// must name one.
+func (s *ConsentService) RecordConsent(patientID uint, req *CreateConsentRequest, recordedByUserID uint, recordedByRole models.UserRole, ipAddress, userAgent, accessReason string, purpose models.PurposeOfUse) (*models.PatientConsent, error) {
+	audit := s.audit.WithPurpose(purpose)
+	resource := fmt.Sprintf("consent:patient_%d", patientID)
+
+	if recordedByRole != models.RoleDoctor {
+		audit.LogUnauthorizedAccess(recordedByUserID, resource, ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to record consent")
+	}
+
+	if !models.IsValidSensitivity(req.Category) || req.Category == models.SensitivityNormal {
+		return nil, fmt.Errorf("invalid consent category")
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("consent expiry must be in the future")
+	}
+
+	// Substance use disclosures must name their recipient
+	if req.GranteeUserID == nil && req.Category == models.SensitivitySubstanceUse {
+		return nil, fmt.Errorf("substance use consent must name a grantee")
+	}
+
+	if req.GranteeUserID != nil && *req.GranteeUserID == recordedByUserID {
+		audit.LogUnauthorizedPatientAccess(recordedByUserID, patientID, resource, ipAddress, userAgent, "self_grant")
+		return nil, fmt.Errorf("cannot record a consent granted to yourself")
+	}
+
+	// Verify patient exists
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	if req.GranteeUserID != nil {
+		var grantee models.User
+		if err := s.db.Where("id = ? AND active = ?", *req.GranteeUserID, true).First(&grantee).Error; err != nil {
+			return nil, fmt.Errorf("grantee not found")
+		}
+	}
+
+	attestation := strings.TrimSpace(req.PatientAttestation)
+	if attestation == "" && !s.careTeam.isResponsibleFor(patientID, recordedByUserID) {
+		audit.LogUnauthorizedPatientAccess(recordedByUserID, patientID, resource, ipAddress, userAgent, "not_on_care_team")
+		return nil, fmt.Errorf("a patient attestation is required to record consent for a patient you do not treat")
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	if err := s.careTeam.AuthorizeConfidentialAccess(&patient, recordedByUserID, models.ActionCreate, ipAddress, userAgent, accessReason, false, purpose); err != nil {
+		return nil, err
+	}
+
+	consent := models.PatientConsent{
+		PatientID:          patientID,
+		Category:           req.Category,
+		GranteeUserID:      req.GranteeUserID,
+		Purpose:            req.Purpose,
+		PatientAttestation: attestation,
+		RecordedBy:         recordedByUserID,
+		ExpiresAt:          req.ExpiresAt,
+	}
+
+	if err := s.db.Create(&consent).Error; err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+
+	audit.LogPatientAccess(recordedByUserID, patientID, models.ActionCreate, ipAddress, userAgent, false, fmt.Sprintf("consent_recorded:%s", consent.Category))
+
+	return &consent, nil
+}
+
+// RevokeConsent withdraws a previously recorded consent. Only the doctor who
+// recorded it or a doctor responsible for the patient may revoke it.
+func (s *ConsentService) RevokeConsent(consentID uint, revokedByUserID uint, revokedByRole models.UserRole, ipAddress, userAgent string) error {
+	var consent models.PatientConsent
+	if err := s.db.Where("id = ?", consentID).First(&consent).Error; err != nil {
+		return fmt.Errorf("consent not found")
+	}
+	resource := fmt.Sprintf("consent:%d", consentID)
+
+	if revokedByRole != models.RoleDoctor {
+		s.audit.LogUnauthorizedPatientAccess(revokedByUserID, consent.PatientID, resource, ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to revoke consent")
+	}
+
+	if consent.RecordedBy != revokedByUserID && !s.careTeam.isResponsibleFor(consent.PatientID, revokedByUserID) {
+		s.audit.LogUnauthorizedPatientAccess(revokedByUserID, consent.PatientID, resource, ipAddress, userAgent, "not_on_care_team")
+		return fmt.Errorf("insufficient permissions to revoke this consent")
+	}
+
+	if err := consent.Revoke(revokedByUserID); err != nil {
+		return fmt.Errorf("cannot revoke consent: %w", err)
+	}
+
+	if err := s.db.Save(&consent).Error; err != nil {
+		return fmt.Errorf("failed to revoke consent: %w", err)
+	}
+
+	s.audit.LogPatientAccess(revokedByUserID, consent.PatientID, models.ActionUpdate, ipAddress, userAgent, false, fmt.Sprintf("consent_revoked:%s", consent.Category))
+
+	return nil
+}
+
+// GetPatientConsents lists all consents recorded for a patient
+func (s *ConsentService) GetPatientConsents(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.PatientConsent, error) {
+	if requestedByRole != models.RoleDoctor && requestedByRole != models.RoleNurse {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("consents:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to view consents")
+	}
+
+	var consents []models.PatientConsent
+	if err := s.db.Where("patient_id = ?", patientID).
+		Preload("GranteeUser").
+		Order("created_at DESC").Find(&consents).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve consents: %w", err)
+	}
+
+	for i := range consents {
+		if consents[i].GranteeUser != nil {
+			consents[i].GranteeUser.Password = ""
+		}
+	}
+
+	return consents, nil
+}
+
+// HasActiveConsent checks if a patient has consented to disclose a category to a user
+func (s *ConsentService) HasActiveConsent(patientID, userID uint, category models.SensitivityLevel) bool {
+	var consents []models.PatientConsent
+	s.db.Where("patient_id = ? AND category = ? AND revoked_at IS NULL", patientID, category).
+		Find(&consents)
+
+	for _, consent := range consents {
+		if consent.Covers(userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDisclosable drops records the user may not see at all: very restricted
+// records authored by another doctor and consent-gated records the patient has
+// not released to this user. Remaining records still need SanitizeForRole.
+func (s *ConsentService) FilterDisclosable(records []models.MedicalRecord, userID uint) []models.MedicalRecord {
+	var disclosable []models.MedicalRecord
+	consentCache := make(map[uint]bool)
+
+	for _, record := range records {
+		if record.IsWithheldFrom(userID) {
+			continue
+		}
+		if record.RequiresConsent() {
+			consented, checked := consentCache[record.PatientID]
+			if !checked {
+				consented = s.HasActiveConsent(record.PatientID, userID, record.Sensitivity)
+				consentCache[record.PatientID] = consented
+			}
+			if !consented {
+				continue
+			}
+		}
+		disclosable = append(disclosable, record)
+	}
+
+	return disclosable
+}
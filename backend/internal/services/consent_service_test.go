@@ -0,0 +1,201 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConsentService(t *testing.T) (*ConsentService, *MedicalRecordService) {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	careTeam := NewCareTeamService(db, audit)
+	consents := NewConsentService(db, audit, careTeam)
+	return consents, NewMedicalRecordService(db, audit, consents, careTeam, models.DefaultFieldPolicy(), nil)
+}
+
+func TestRecordConsent(t *testing.T) {
+	consents, _ := newConsentService(t)
+	db := consents.db
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	colleague := createUser(t, db, models.RoleDoctor)
+	outsider := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, doctor)
+
+	request := func(category models.SensitivityLevel, grantee *uint) *CreateConsentRequest {
+		return &CreateConsentRequest{Category: category, GranteeUserID: grantee, Purpose: "Coordination of care"}
+	}
+
+	t.Run("CareTeamDoctorRecordsConsent", func(t *testing.T) {
+		consent, err := consents.RecordConsent(patient.ID, request(models.SensitivitySubstanceUse, &colleague.ID), doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, doctor.ID, consent.RecordedBy)
+		assert.True(t, consents.HasActiveConsent(patient.ID, colleague.ID, models.SensitivitySubstanceUse))
+	})
+
+	t.Run("RefusesSelfGrant", func(t *testing.T) {
+		_, err := consents.RecordConsent(patient.ID, request(models.SensitivitySubstanceUse, &doctor.ID), doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.Error(t, err)
+
+		entry := lastAuditEntry(t, db, doctor.ID)
+		assert.Equal(t, models.ActionUnauthorized, entry.Action)
+		assert.Equal(t, "self_grant", entry.ErrorMessage)
+		require.NotNil(t, entry.PatientID)
+		assert.Equal(t, patient.ID, *entry.PatientID)
+	})
+
+	t.Run("RefusesUnknownGrantee", func(t *testing.T) {
+		unknown := uint(9999)
+		_, err := consents.RecordConsent(patient.ID, request(models.SensitivityMentalHealth, &unknown), doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.EqualError(t, err, "grantee not found")
+	})
+
+	t.Run("SubstanceUseMustNameGrantee", func(t *testing.T) {
+		_, err := consents.RecordConsent(patient.ID, request(models.SensitivitySubstanceUse, nil), doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.Error(t, err)
+
+		consent, err := consents.RecordConsent(patient.ID, request(models.SensitivityMentalHealth, nil), doctor.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		require.NoError(t, err)
+		assert.Nil(t, consent.GranteeUserID)
+	})
+
+	t.Run("OutsiderNeedsPatientAttestation", func(t *testing.T) {
+		_, err := consents.RecordConsent(patient.ID, request(models.SensitivityReproductive, &colleague.ID), outsider.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.Error(t, err)
+		assert.Equal(t, "not_on_care_team", lastAuditEntry(t, db, outsider.ID).ErrorMessage)
+
+		req := request(models.SensitivityReproductive, &colleague.ID)
+		req.PatientAttestation = "Signed form, document 8812"
+		consent, err := consents.RecordConsent(patient.ID, req, outsider.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "Signed form, document 8812", consent.PatientAttestation)
+	})
+
+	t.Run("AttendingDoctorRecordsConsent", func(t *testing.T) {
+		attending := createUser(t, db, models.RoleDoctor)
+		require.NoError(t, db.Create(&models.Encounter{PatientID: patient.ID, Class: models.EncounterOutpatient, Status: models.EncounterActive, AdmitAt: time.Now(), Facility: "Main", Department: "Clinic", AttendingID: attending.ID, CreatedBy: attending.ID}).Error)
+
+		_, err := consents.RecordConsent(patient.ID, request(models.SensitivityRestricted, &colleague.ID), attending.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ConfidentialChartNeedsReason", func(t *testing.T) {
+		confidential := createPatient(t, db, true)
+		req := request(models.SensitivityMentalHealth, &colleague.ID)
+		req.PatientAttestation = "Signed form, document 9001"
+
+		_, err := consents.RecordConsent(confidential.ID, req, outsider.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		assert.Error(t, err)
+
+		_, err = consents.RecordConsent(confidential.ID, req, outsider.ID, models.RoleDoctor, testIP, testUserAgent, "Referral for counselling", "")
+		require.NoError(t, err)
+	})
+
+	t.Run("NursesCannotRecordConsent", func(t *testing.T) {
+		nurse := createUser(t, db, models.RoleNurse)
+		_, err := consents.RecordConsent(patient.ID, request(models.SensitivityMentalHealth, &colleague.ID), nurse.ID, models.RoleNurse, testIP, testUserAgent, "", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestRevokeConsent(t *testing.T) {
+	consents, _ := newConsentService(t)
+	db := consents.db
+
+	recorder := createUser(t, db, models.RoleDoctor)
+	member := createUser(t, db, models.RoleDoctor)
+	outsider := createUser(t, db, models.RoleDoctor)
+	grantee := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, recorder)
+
+	record := func() *models.PatientConsent {
+		consent, err := consents.RecordConsent(patient.ID, &CreateConsentRequest{Category: models.SensitivitySubstanceUse, GranteeUserID: &grantee.ID, Purpose: "Care"}, recorder.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		require.NoError(t, err)
+		return consent
+	}
+
+	t.Run("OutsiderCannotRevoke", func(t *testing.T) {
+		consent := record()
+
+		err := consents.RevokeConsent(consent.ID, outsider.ID, models.RoleDoctor, testIP, testUserAgent)
+		assert.Error(t, err)
+		assert.True(t, consents.HasActiveConsent(patient.ID, grantee.ID, models.SensitivitySubstanceUse))
+
+		entry := lastAuditEntry(t, db, outsider.ID)
+		assert.Equal(t, models.ActionUnauthorized, entry.Action)
+		require.NotNil(t, entry.PatientID)
+		assert.Equal(t, patient.ID, *entry.PatientID)
+	})
+
+	t.Run("RecorderRevokes", func(t *testing.T) {
+		consent := record()
+		require.NoError(t, consents.RevokeConsent(consent.ID, recorder.ID, models.RoleDoctor, testIP, testUserAgent))
+		assert.Error(t, consents.RevokeConsent(consent.ID, recorder.ID, models.RoleDoctor, testIP, testUserAgent), "already revoked")
+	})
+
+	t.Run("CareTeamRevokes", func(t *testing.T) {
+		consent := record()
+		addToCareTeam(t, db, patient, member)
+
+		require.NoError(t, consents.RevokeConsent(consent.ID, member.ID, models.RoleDoctor, testIP, testUserAgent))
+
+		entry := lastAuditEntry(t, db, member.ID)
+		assert.Equal(t, models.ActionUpdate, entry.Action)
+		require.NotNil(t, entry.PatientID)
+		assert.Equal(t, patient.ID, *entry.PatientID)
+	})
+}
+
+func TestConsentGatesSubstanceUseRecords(t *testing.T) {
+	consents, records := newConsentService(t)
+	db := consents.db
+
+	author := createUser(t, db, models.RoleDoctor)
+	grantee := createUser(t, db, models.RoleDoctor)
+	other := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, author)
+
+	createRecord(t, db, patient, author, models.SensitivityNormal, models.SeverityLow)
+	substance := createRecord(t, db, patient, author, models.SensitivitySubstanceUse, models.SeverityMedium)
+
+	list := func(userID uint) ([]models.Projection, int64) {
+		projections, total, err := records.GetPatientMedicalRecords(patient.ID, userID, models.RoleDoctor, testIP, testUserAgent, false, "", "", nil, nil, 1, 20)
+		require.NoError(t, err)
+		return projections, total
+	}
+
+	t.Run("WithheldWithoutConsent", func(t *testing.T) {
+		projections, total := list(grantee.ID)
+		assert.Len(t, projections, 1)
+		assert.Equal(t, int64(1), total, "the total must not reveal withheld records")
+
+		_, err := records.GetMedicalRecord(substance.ID, grantee.ID, models.RoleDoctor, testIP, testUserAgent, false, "", "", nil)
+		assert.Error(t, err)
+		assert.Equal(t, "consent_required", lastAuditEntry(t, db, grantee.ID).ErrorMessage)
+	})
+
+	t.Run("EmergencyAccessDoesNotBypassConsent", func(t *testing.T) {
+		_, err := records.GetMedicalRecord(substance.ID, grantee.ID, models.RoleDoctor, testIP, testUserAgent, true, "", "", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("DisclosedToGranteeOnly", func(t *testing.T) {
+		_, err := consents.RecordConsent(patient.ID, &CreateConsentRequest{Category: models.SensitivitySubstanceUse, GranteeUserID: &grantee.ID, Purpose: "Care"}, author.ID, models.RoleDoctor, testIP, testUserAgent, "", "")
+		require.NoError(t, err)
+
+		projections, total := list(grantee.ID)
+		assert.Len(t, projections, 2)
+		assert.Equal(t, int64(2), total)
+
+		_, total = list(other.ID)
+		assert.Equal(t, int64(1), total)
+	})
+}
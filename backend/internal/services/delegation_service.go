@@ -0,0 +1,235 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type DelegationService struct {
+	db       *gorm.DB
+	audit    *AuditService
+	careTeam *CareTeamService
+	config   *configs.Config
+}
+
+type CreateDelegationRequest struct {
+	DelegateID uint       `json:"delegate_id" binding:"required"`
+	PatientIDs []uint     `json:"patient_ids,omitempty"`
+	Reason     string     `json:"reason" binding:"required,min=10"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at" binding:"required"`
+}
+
+type DelegationQuery struct {
+	Status string `form:"status"`
+	Page   int    `form:"page,default=1"`
+	Limit  int    `form:"limit,default=20"`
+}
+
+func NewDelegationService(db *gorm.DB, audit *AuditService, careTeam *CareTeamService, config *configs.Config) *DelegationService {
+	return &DelegationService{
+		db:       db,
+		audit:    audit,
+		careTeam: careTeam,
+		config:   config,
+	}
+}
+
+// CreateDelegation lends the doctor's patient access to a colleague for a fixed
+// window. The grant is pending until an admin approves it when approval is
+// required by configuration.
+func (s *DelegationService) CreateDelegation(req *CreateDelegationRequest, delegatorID uint, delegatorRole models.UserRole, ipAddress, userAgent string) (*models.AccessDelegation, error) {
+	if delegatorRole != models.RoleDoctor {
+		s.audit.LogUnauthorizedAccess(delegatorID, "access_delegations", ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("only doctors can delegate patient access")
+	}
+
+	if req.DelegateID == delegatorID {
+		return nil, fmt.Errorf("cannot delegate access to yourself")
+	}
+
+	var delegate models.User
+	if err := s.db.Where("id = ? AND active = ?", req.DelegateID, true).First(&delegate).Error; err != nil {
+		return nil, fmt.Errorf("delegate not found or inactive")
+	}
+	if !delegate.CanAccessPatientData() {
+		return nil, fmt.Errorf("access can only be delegated to medical staff")
+	}
+
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if !req.ExpiresAt.After(startsAt) || !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("delegation must end after it starts and in the future")
+	}
+	if req.ExpiresAt.Sub(startsAt) > s.config.Delegation.MaxDuration {
+		return nil, fmt.Errorf("delegation cannot exceed %s", s.config.Delegation.MaxDuration)
+	}
+
+	// A delegator can only lend access to patients they treat themselves
+	patients := make([]models.AccessDelegationPatient, 0, len(req.PatientIDs))
+	for _, patientID := range req.PatientIDs {
+		if !s.careTeam.isDirectCareTeam(patientID, delegatorID) {
+			s.audit.LogUnauthorizedAccess(delegatorID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "delegation_outside_panel")
+			return nil, fmt.Errorf("patient %d is not on your care team", patientID)
+		}
+		patients = append(patients, models.AccessDelegationPatient{PatientID: patientID})
+	}
+
+	status := models.DelegationStatusActive
+	if s.config.Delegation.RequireApproval {
+		status = models.DelegationStatusPending
+	}
+
+	grant := models.AccessDelegation{
+		DelegatorID: delegatorID,
+		DelegateID:  req.DelegateID,
+		Reason:      req.Reason,
+		Status:      status,
+		StartsAt:    startsAt,
+		ExpiresAt:   req.ExpiresAt,
+		Patients:    patients,
+	}
+
+	if err := s.db.Create(&grant).Error; err != nil {
+		return nil, fmt.Errorf("failed to create delegation: %w", err)
+	}
+
+	s.audit.LogUserAction(delegatorID, models.ActionCreate, fmt.Sprintf("access_delegation:%d", grant.ID), ipAddress, userAgent, true,
+		fmt.Sprintf("delegation_%s:user_%d", grant.Status, grant.DelegateID))
+
+	return &grant, nil
+}
+
+// ApproveDelegation activates a pending grant
+func (s *DelegationService) ApproveDelegation(delegationID uint, reviewedByUserID uint, reviewedByRole models.UserRole, ipAddress, userAgent string) error {
+	return s.review(delegationID, true, reviewedByUserID, reviewedByRole, ipAddress, userAgent)
+}
+
+// RejectDelegation declines a pending grant
+func (s *DelegationService) RejectDelegation(delegationID uint, reviewedByUserID uint, reviewedByRole models.UserRole, ipAddress, userAgent string) error {
+	return s.review(delegationID, false, reviewedByUserID, reviewedByRole, ipAddress, userAgent)
+}
+
+// RevokeDelegation ends a grant early. The delegator, the delegate and admins
+// may revoke it.
+func (s *DelegationService) RevokeDelegation(delegationID uint, revokedByUserID uint, revokedByRole models.UserRole, ipAddress, userAgent string) error {
+	var grant models.AccessDelegation
+	if err := s.db.Where("id = ?", delegationID).First(&grant).Error; err != nil {
+		return fmt.Errorf("delegation not found")
+	}
+
+	if revokedByRole != models.RoleAdmin && revokedByUserID != grant.DelegatorID && revokedByUserID != grant.DelegateID {
+		s.audit.LogUnauthorizedAccess(revokedByUserID, fmt.Sprintf("access_delegation:%d", delegationID), ipAddress, userAgent, "not_delegation_party")
+		return fmt.Errorf("insufficient permissions to revoke delegation")
+	}
+
+	if err := grant.Revoke(revokedByUserID); err != nil {
+		return fmt.Errorf("delegation is no longer active")
+	}
+
+	if err := s.db.Save(&grant).Error; err != nil {
+		return fmt.Errorf("failed to revoke delegation: %w", err)
+	}
+
+	s.audit.LogUserAction(revokedByUserID, models.ActionUpdate, fmt.Sprintf("access_delegation:%d", grant.ID), ipAddress, userAgent, true, "delegation_revoked")
+
+	return nil
+}
+
+// GetUserDelegations lists grants the user has given or received
+func (s *DelegationService) GetUserDelegations(userID uint, page, limit int) ([]models.AccessDelegation, int64, error) {
+	var grants []models.AccessDelegation
+	var total int64
+
+	query := s.db.Model(&models.AccessDelegation{}).
+		Where("delegator_id = ? OR delegate_id = ?", userID, userID)
+
+	query.Count(&total)
+
+	offset := (page - 1) * limit
+	if err := query.Preload("Patients").Preload("Delegator").Preload("Delegate").
+		Order("created_at DESC").Offset(offset).Limit(limit).Find(&grants).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve delegations: %w", err)
+	}
+
+	sanitizeDelegationUsers(grants)
+
+	return grants, total, nil
+}
+
+// GetDelegations lists all grants for admin review
+func (s *DelegationService) GetDelegations(query *DelegationQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.AccessDelegation, int64, error) {
+	if requestedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, "access_delegations", ipAddress, userAgent, "insufficient_role")
+		return nil, 0, fmt.Errorf("insufficient permissions to review delegations")
+	}
+
+	filter := &models.AccessDelegationFilter{}
+	if query.Status != "" {
+		status := models.DelegationStatus(query.Status)
+		filter.Status = &status
+	}
+
+	var total int64
+	filter.Apply(s.db.Model(&models.AccessDelegation{})).Count(&total)
+
+	filter.Limit = query.Limit
+	filter.Offset = (query.Page - 1) * query.Limit
+
+	var grants []models.AccessDelegation
+	if err := filter.Apply(s.db).Preload("Patients").Preload("Delegator").Preload("Delegate").
+		Find(&grants).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve delegations: %w", err)
+	}
+
+	sanitizeDelegationUsers(grants)
+
+	return grants, total, nil
+}
+
+func (s *DelegationService) review(delegationID uint, approve bool, reviewedByUserID uint, reviewedByRole models.UserRole, ipAddress, userAgent string) error {
+	if reviewedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(reviewedByUserID, fmt.Sprintf("access_delegation:%d", delegationID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to review delegation")
+	}
+
+	var grant models.AccessDelegation
+	if err := s.db.Where("id = ?", delegationID).First(&grant).Error; err != nil {
+		return fmt.Errorf("delegation not found")
+	}
+
+	var err error
+	outcome := "delegation_approved"
+	if approve {
+		err = grant.Approve(reviewedByUserID)
+	} else {
+		outcome = "delegation_rejected"
+		err = grant.Reject(reviewedByUserID)
+	}
+	if err != nil {
+		return fmt.Errorf("delegation is not awaiting review")
+	}
+
+	if err := s.db.Save(&grant).Error; err != nil {
+		return fmt.Errorf("failed to review delegation: %w", err)
+	}
+
+	s.audit.LogUserAction(reviewedByUserID, models.ActionUpdate, fmt.Sprintf("access_delegation:%d", grant.ID), ipAddress, userAgent, true, outcome)
+
+	return nil
+}
+
+func sanitizeDelegationUsers(grants []models.AccessDelegation) {
+	for i := range grants {
+		grants[i].Delegator.Password = ""
+		grants[i].Delegate.Password = ""
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDelegationService(t *testing.T, requireApproval bool) (*DelegationService, *CareTeamService) {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	careTeam := NewCareTeamService(db, audit)
+	config := &configs.Config{Delegation: configs.DelegationConfig{RequireApproval: requireApproval, MaxDuration: 14 * 24 * time.Hour}}
+	return NewDelegationService(db, audit, careTeam, config), careTeam
+}
+
+func TestDelegationWindow(t *testing.T) {
+	delegations, careTeam := newDelegationService(t, false)
+	db := delegations.db
+
+	delegator := createUser(t, db, models.RoleDoctor)
+	delegate := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, true)
+	addToCareTeam(t, db, patient, delegator)
+
+	t.Run("ActiveGrantCoversPanel", func(t *testing.T) {
+		grant, err := delegations.CreateDelegation(&CreateDelegationRequest{DelegateID: delegate.ID, Reason: "Covering the weekend", ExpiresAt: time.Now().Add(time.Hour)}, delegator.ID, models.RoleDoctor, testIP, testUserAgent)
+		require.NoError(t, err)
+		assert.Equal(t, models.DelegationStatusActive, grant.Status)
+		assert.True(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+
+		require.NoError(t, delegations.RevokeDelegation(grant.ID, delegator.ID, models.RoleDoctor, testIP, testUserAgent))
+		assert.False(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+	})
+
+	t.Run("ExpiredGrantGivesNoAccess", func(t *testing.T) {
+		expired := models.AccessDelegation{
+			DelegatorID: delegator.ID,
+			DelegateID:  delegate.ID,
+			Reason:      "Covering last week",
+			Status:      models.DelegationStatusActive,
+			StartsAt:    time.Now().Add(-48 * time.Hour),
+			ExpiresAt:   time.Now().Add(-time.Minute),
+		}
+		require.NoError(t, db.Create(&expired).Error)
+
+		assert.False(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+		assert.Nil(t, careTeam.DelegationFor(patient.ID, delegate.ID))
+	})
+
+	t.Run("FutureGrantNotYetActive", func(t *testing.T) {
+		startsAt := time.Now().Add(24 * time.Hour)
+		_, err := delegations.CreateDelegation(&CreateDelegationRequest{DelegateID: delegate.ID, Reason: "Covering next week", StartsAt: &startsAt, ExpiresAt: startsAt.Add(time.Hour)}, delegator.ID, models.RoleDoctor, testIP, testUserAgent)
+		require.NoError(t, err)
+		assert.False(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+	})
+
+	t.Run("RefusesInvalidWindows", func(t *testing.T) {
+		_, err := delegations.CreateDelegation(&CreateDelegationRequest{DelegateID: delegate.ID, Reason: "Covering in the past", ExpiresAt: time.Now().Add(-time.Hour)}, delegator.ID, models.RoleDoctor, testIP, testUserAgent)
+		assert.Error(t, err)
+
+		_, err = delegations.CreateDelegation(&CreateDelegationRequest{DelegateID: delegate.ID, Reason: "Covering for a month", ExpiresAt: time.Now().Add(30 * 24 * time.Hour)}, delegator.ID, models.RoleDoctor, testIP, testUserAgent)
+		assert.Error(t, err)
+	})
+
+	t.Run("EndsWhenDelegatorLeavesCareTeam", func(t *testing.T) {
+		_, err := delegations.CreateDelegation(&CreateDelegationRequest{DelegateID: delegate.ID, PatientIDs: []uint{patient.ID}, Reason: "Covering this patient", ExpiresAt: time.Now().Add(time.Hour)}, delegator.ID, models.RoleDoctor, testIP, testUserAgent)
+		require.NoError(t, err)
+		assert.True(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+
+		require.NoError(t, db.Model(&models.CareTeamMember{}).Where("patient_id = ? AND user_id = ?", patient.ID, delegator.ID).Update("ended_at", time.Now()).Error)
+		assert.False(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+	})
+
+	t.Run("CannotDelegateOutsidePanel", func(t *testing.T) {
+		other := createPatient(t, db, false)
+		_, err := delegations.CreateDelegation(&CreateDelegationRequest{DelegateID: delegate.ID, PatientIDs: []uint{other.ID}, Reason: "Covering another patient", ExpiresAt: time.Now().Add(time.Hour)}, delegator.ID, models.RoleDoctor, testIP, testUserAgent)
+		assert.Error(t, err)
+		assert.Equal(t, "delegation_outside_panel", lastAuditEntry(t, db, delegator.ID).ErrorMessage)
+	})
+}
+
+func TestDelegationApproval(t *testing.T) {
+	delegations, careTeam := newDelegationService(t, true)
+	db := delegations.db
+
+	delegator := createUser(t, db, models.RoleDoctor)
+	delegate := createUser(t, db, models.RoleDoctor)
+	admin := createUser(t, db, models.RoleAdmin)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, delegator)
+
+	grant, err := delegations.CreateDelegation(&CreateDelegationRequest{DelegateID: delegate.ID, Reason: "Covering the weekend", ExpiresAt: time.Now().Add(time.Hour)}, delegator.ID, models.RoleDoctor, testIP, testUserAgent)
+	require.NoError(t, err)
+	assert.Equal(t, models.DelegationStatusPending, grant.Status)
+	assert.False(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+
+	assert.Error(t, delegations.ApproveDelegation(grant.ID, delegator.ID, models.RoleDoctor, testIP, testUserAgent))
+
+	require.NoError(t, delegations.ApproveDelegation(grant.ID, admin.ID, models.RoleAdmin, testIP, testUserAgent))
+	assert.True(t, careTeam.IsOnCareTeam(patient.ID, delegate.ID))
+	assert.Error(t, delegations.RejectDelegation(grant.ID, admin.ID, models.RoleAdmin, testIP, testUserAgent))
+}
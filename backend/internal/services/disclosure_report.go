@@ -0,0 +1,189 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+	"healthsecure/internal/pdf"
+)
+
+// accountingYears is how far back a patient may ask for an accounting of
+// disclosures under HIPAA 164.528
+const accountingYears = 6
+
+// DisclosureReportQuery is the period an accounting covers, as calendar dates.
+// Both ends are inclusive; the default is the last six years.
+type DisclosureReportQuery struct {
+	From *time.Time `form:"from" time_format:"2006-01-02"`
+	To   *time.Time `form:"to" time_format:"2006-01-02"`
+}
+
+// DisclosureReport is a patient's accounting of disclosures
+type DisclosureReport struct {
+	PatientID   uint              `json:"patient_id"`
+	PatientName string            `json:"patient_name"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	IncludesTPO bool              `json:"includes_tpo"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	GeneratedBy string            `json:"generated_by"`
+	Disclosures []DisclosureEntry `json:"disclosures"`
+}
+
+// DisclosureEntry is one disclosure: when, to whom, why and what. Exports also
+// name the outside party they were sent to.
+type DisclosureEntry struct {
+	Date            time.Time                 `json:"date"`
+	UserName        string                    `json:"user_name"`
+	UserRole        models.UserRole           `json:"user_role"`
+	Recipient       string                    `json:"recipient,omitempty"`
+	Category        models.DisclosureCategory `json:"category"`
+	Purpose         models.PurposeOfUse       `json:"purpose,omitempty"`
+	Action          models.AuditAction        `json:"action"`
+	Description     string                    `json:"description"`
+	DisclosedFields []string                  `json:"disclosed_fields,omitempty"`
+}
+
+// GetDisclosureReport compiles a patient's accounting of disclosures from the
+// audit trail. Only admins, acting for the privacy office, can run it. The
+// run is audited as a disclosure to the patient, so it never lists itself.
+func (s *AuditService) GetDisclosureReport(patientID uint, query *DisclosureReportQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) (*DisclosureReport, error) {
+	if requestedByRole != models.RoleAdmin {
+		s.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("disclosure_report:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to run an accounting of disclosures")
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if query.To != nil {
+		to = *query.To
+	}
+	from := to.AddDate(-accountingYears, 0, 0)
+	if query.From != nil {
+		from = *query.From
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("accounting period must start before it ends")
+	}
+	if from.Before(to.AddDate(-accountingYears, 0, 0)) {
+		return nil, fmt.Errorf("accounting period cannot exceed %d years", accountingYears)
+	}
+
+	// Audit rows outlive deleted charts, so a deleted patient can still ask
+	var patient models.Patient
+	if err := s.db.Unscoped().Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	var requester models.User
+	s.db.Select("id, name").Where("id = ?", requestedByUserID).First(&requester)
+
+	// Accesses to duplicate charts merged into this one are the patient's too
+	chartIDs, err := mergedChartIDs(s.db, patient.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []models.AuditLog
+	if err := s.db.Where("patient_id IN ? AND success = ? AND timestamp >= ? AND timestamp < ?", chartIDs, true, from, to.AddDate(0, 0, 1)).
+		Where("action IN ?", []models.AuditAction{models.ActionView, models.ActionDownload, models.ActionExport, models.ActionEmergencyAccess}).
+		Preload("User").Order("timestamp ASC").Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve audit logs: %w", err)
+	}
+
+	report := &DisclosureReport{
+		PatientID:   patient.ID,
+		PatientName: patient.GetFullName(),
+		From:        from,
+		To:          to,
+		IncludesTPO: s.includeTPO,
+		GeneratedAt: now,
+		GeneratedBy: requester.Name,
+		Disclosures: []DisclosureEntry{},
+	}
+	for _, log := range logs {
+		category, listed := log.DisclosureCategory(s.includeTPO)
+		if !listed {
+			continue
+		}
+		entry := DisclosureEntry{
+			Date:        log.Timestamp,
+			UserName:    log.User.Name,
+			UserRole:    log.User.Role,
+			Recipient:   log.Recipient,
+			Category:    category,
+			Purpose:     log.Purpose,
+			Action:      log.Action,
+			Description: log.Resource,
+		}
+		if log.DisclosedFields != "" {
+			entry.DisclosedFields = strings.Split(log.DisclosedFields, ",")
+		}
+		report.Disclosures = append(report.Disclosures, entry)
+	}
+
+	s.WithPurpose(models.PurposePatientRequest).LogPatientAccess(requestedByUserID, patientID, models.ActionView, ipAddress, userAgent, false, "accounting_of_disclosures")
+
+	return report, nil
+}
+
+// PDF renders the report for printing and mailing to the patient
+func (r *DisclosureReport) PDF() []byte {
+	doc := pdf.New("Accounting of Disclosures")
+	doc.Heading("Accounting of Disclosures")
+	doc.Text(fmt.Sprintf("Patient: %s (ID %d)", r.PatientName, r.PatientID))
+	doc.Text(fmt.Sprintf("Period: %s to %s", r.From.Format("January 2, 2006"), r.To.Format("January 2, 2006")))
+	doc.Text(fmt.Sprintf("Prepared %s by %s", r.GeneratedAt.Format("January 2, 2006 15:04 MST"), r.GeneratedBy))
+	doc.Blank()
+	if r.IncludesTPO {
+		doc.Text("This accounting lists every access to your health information, including those for treatment, payment and health care operations.")
+	} else {
+		doc.Text("This accounting lists emergency access to your health information, copies exported from our systems and disclosures for research or legal purposes. Access for your treatment, for payment and for our health care operations is not listed, nor is access by you or your personal representative.")
+	}
+	doc.Blank()
+
+	if len(r.Disclosures) == 0 {
+		doc.Text("No disclosures were made during this period.")
+		return doc.Bytes()
+	}
+
+	rows := make([][]string, len(r.Disclosures))
+	for i, d := range r.Disclosures {
+		purpose := string(d.Purpose)
+		if purpose == "" {
+			purpose = "not declared"
+		}
+		// Exports name the outside party as well as the user who sent them
+		recipient := d.UserName
+		if d.Recipient != "" {
+			recipient = d.Recipient + ", via " + d.UserName
+		}
+		rows[i] = []string{
+			d.Date.Format("2006-01-02 15:04"),
+			recipient,
+			string(d.UserRole),
+			disclosureCategoryLabel(d.Category),
+			strings.ReplaceAll(purpose, "_", " "),
+			d.Description,
+		}
+	}
+	doc.Table([]string{"Date", "Recipient", "Role", "Type", "Purpose", "Information"}, []int{16, 20, 10, 16, 12, 25}, rows)
+
+	return doc.Bytes()
+}
+
+func disclosureCategoryLabel(category models.DisclosureCategory) string {
+	switch category {
+	case models.DisclosureEmergency:
+		return "Emergency access"
+	case models.DisclosureExport:
+		return "Export"
+	case models.DisclosureExternal:
+		return "External"
+	case models.DisclosureRoutineTPO:
+		return "Routine"
+	}
+	return string(category)
+}
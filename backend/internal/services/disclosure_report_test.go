@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisclosureReportListsSearches(t *testing.T) {
+	db := newTestDB(t)
+	audit := NewAuditService(db, true)
+	careTeam := NewCareTeamService(db, audit)
+	patients := NewPatientService(db, audit, NewConsentService(db, audit, careTeam), careTeam, models.DefaultFieldPolicy(), &configs.Config{}, nil)
+
+	admin := createUser(t, db, models.RoleAdmin)
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	other := &models.Patient{FirstName: "John", LastName: "Smith"}
+	require.NoError(t, db.Create(other).Error)
+
+	list := func(query *PatientSearchQuery, purpose models.PurposeOfUse) {
+		query.Page, query.Limit = 1, 20
+		_, _, err := patients.GetPatients(query, doctor.ID, models.RoleDoctor, testIP, testUserAgent, purpose, nil)
+		require.NoError(t, err)
+	}
+	list(&PatientSearchQuery{}, models.PurposeTreatment)
+	list(&PatientSearchQuery{}, models.PurposeResearch)
+	list(&PatientSearchQuery{LastName: "Smith"}, models.PurposeResearch)
+
+	report, err := audit.GetDisclosureReport(patient.ID, &DisclosureReportQuery{}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+	require.NoError(t, err)
+
+	var categories []models.DisclosureCategory
+	for _, disclosure := range report.Disclosures {
+		if disclosure.Description == "patients_list" {
+			categories = append(categories, disclosure.Category)
+			assert.Equal(t, doctor.Name, disclosure.UserName)
+			assert.NotEmpty(t, disclosure.DisclosedFields)
+		}
+	}
+	assert.Equal(t, []models.DisclosureCategory{models.DisclosureRoutineTPO, models.DisclosureExternal}, categories)
+
+	t.Run("EachReturnedPatient", func(t *testing.T) {
+		report, err := audit.GetDisclosureReport(other.ID, &DisclosureReportQuery{}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+		require.NoError(t, err)
+
+		var lists int
+		for _, disclosure := range report.Disclosures {
+			if disclosure.Description == "patients_list" {
+				lists++
+			}
+		}
+		assert.Equal(t, 3, lists)
+	})
+}
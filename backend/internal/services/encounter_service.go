@@ -0,0 +1,436 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// EncounterService manages visits, admissions and ED stays, and the census
+// of currently admitted patients. Clinical staff and front desk open and
+// transfer encounters; only clinical staff discharge them.
+type EncounterService struct {
+	db          *gorm.DB
+	audit       *AuditService
+	careTeam    *CareTeamService
+	fieldPolicy models.FieldPolicy
+}
+
+type CreateEncounterRequest struct {
+	Class       models.EncounterClass `json:"class" binding:"required"`
+	AdmitAt     *time.Time            `json:"admit_at,omitempty"`
+	Facility    string                `json:"facility" binding:"required"`
+	Department  string                `json:"department" binding:"required"`
+	Location    string                `json:"location"`
+	AttendingID uint                  `json:"attending_id" binding:"required"`
+	Reason      string                `json:"reason"`
+}
+
+type UpdateEncounterRequest struct {
+	Facility    *string `json:"facility,omitempty"`
+	Department  *string `json:"department,omitempty"`
+	Location    *string `json:"location,omitempty"`
+	AttendingID *uint   `json:"attending_id,omitempty"`
+	Reason      *string `json:"reason,omitempty"`
+}
+
+type DischargeEncounterRequest struct {
+	DischargeAt *time.Time `json:"discharge_at,omitempty"`
+}
+
+type EncounterQuery struct {
+	Status string `form:"status"`
+	Class  string `form:"class"`
+	Page   int    `form:"page,default=1"`
+	Limit  int    `form:"limit,default=20"`
+}
+
+type CensusQuery struct {
+	Facility   string `form:"facility"`
+	Department string `form:"department"`
+	Class      string `form:"class"`
+}
+
+// CensusEntry is one admitted patient, shown with the fields of the patient
+// the caller may see
+type CensusEntry struct {
+	EncounterID uint                  `json:"encounter_id"`
+	Class       models.EncounterClass `json:"class"`
+	Location    string                `json:"location,omitempty"`
+	AdmitAt     time.Time             `json:"admit_at"`
+	AttendingID uint                  `json:"attending_id"`
+	Patient     models.Projection     `json:"patient"`
+}
+
+// CensusUnit lists the patients admitted to one department of a facility
+type CensusUnit struct {
+	Facility   string        `json:"facility"`
+	Department string        `json:"department"`
+	Count      int           `json:"count"`
+	Patients   []CensusEntry `json:"patients"`
+}
+
+// censusPatientFields are the patient fields a census shows at most
+var censusPatientFields = []string{"id", "first_name", "last_name", "date_of_birth", "confidential"}
+
+func NewEncounterService(db *gorm.DB, audit *AuditService, careTeam *CareTeamService, fieldPolicy models.FieldPolicy) *EncounterService {
+	return &EncounterService{
+		db:          db,
+		audit:       audit,
+		careTeam:    careTeam,
+		fieldPolicy: fieldPolicy,
+	}
+}
+
+// CreateEncounter opens a visit or admission for a patient
+func (s *EncounterService) CreateEncounter(patientID uint, req *CreateEncounterRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.Encounter, error) {
+	if !s.canManageEncounters(createdByRole) {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(createdByUserID, fmt.Sprintf("encounters:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to create encounters")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	if !models.IsValidEncounterClass(req.Class) {
+		return nil, fmt.Errorf("invalid encounter class")
+	}
+	if req.AdmitAt != nil && req.AdmitAt.After(time.Now().Add(5*time.Minute)) {
+		return nil, fmt.Errorf("admit time cannot be in the future")
+	}
+	if err := s.validateAttending(req.AttendingID); err != nil {
+		return nil, err
+	}
+
+	encounter := models.Encounter{
+		PatientID:   patientID,
+		Class:       req.Class,
+		Facility:    strings.TrimSpace(req.Facility),
+		Department:  strings.TrimSpace(req.Department),
+		Location:    strings.TrimSpace(req.Location),
+		AttendingID: req.AttendingID,
+		Reason:      req.Reason,
+		CreatedBy:   createdByUserID,
+	}
+	if req.AdmitAt != nil {
+		encounter.AdmitAt = *req.AdmitAt
+	}
+
+	if err := s.db.Create(&encounter).Error; err != nil {
+		return nil, fmt.Errorf("failed to create encounter: %w", err)
+	}
+
+	s.logWrite(&encounter, createdByUserID, models.ActionCreate, ipAddress, userAgent, fmt.Sprintf("encounter_opened:%s", encounter.Class), purpose)
+
+	return &encounter, nil
+}
+
+// GetPatientEncounters lists a patient's encounters, most recent first
+func (s *EncounterService) GetPatientEncounters(patientID uint, query *EncounterQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) ([]models.Projection, int64, error) {
+	if err := s.authorizeRead(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, 0, err
+	}
+
+	db := s.db.Model(&models.Encounter{}).Where("patient_id = ?", patientID)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Class != "" {
+		db = db.Where("class = ?", query.Class)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count encounters: %w", err)
+	}
+
+	var encounters []models.Encounter
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Preload("Attending").Order("admit_at DESC").Offset(offset).Limit(query.Limit).Find(&encounters).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve encounters: %w", err)
+	}
+
+	policy := s.fieldPolicy.ForPurpose(purpose)
+	projections, disclosed := policy.ProjectAll(models.ResourceEncounter, requestedByRole, encounters, fields)
+	if projections == nil {
+		projections = []models.Projection{}
+	}
+	s.logRead(patientID, fmt.Sprintf("encounters:patient_%d", patientID), requestedByUserID, ipAddress, userAgent, emergencyAccess, purpose, disclosed)
+
+	return projections, total, nil
+}
+
+// GetEncounter returns a single encounter
+func (s *EncounterService) GetEncounter(encounterID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) (models.Projection, error) {
+	var encounter models.Encounter
+	if err := s.db.Preload("Attending").Where("id = ?", encounterID).First(&encounter).Error; err != nil {
+		return nil, fmt.Errorf("encounter not found")
+	}
+
+	if err := s.authorizeRead(encounter.PatientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	policy := s.fieldPolicy.ForPurpose(purpose)
+	projection, disclosed := policy.Project(models.ResourceEncounter, requestedByRole, &encounter, fields)
+	s.logRead(encounter.PatientID, fmt.Sprintf("encounter:%d", encounter.ID), requestedByUserID, ipAddress, userAgent, emergencyAccess, purpose, disclosed)
+
+	return projection, nil
+}
+
+// UpdateEncounter transfers an active encounter to another facility,
+// department or bed, or hands it to another attending
+func (s *EncounterService) UpdateEncounter(encounterID uint, req *UpdateEncounterRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.Encounter, error) {
+	if !s.canManageEncounters(updatedByRole) {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("encounter:%d", encounterID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to update encounters")
+	}
+
+	var encounter models.Encounter
+	if err := s.db.Where("id = ?", encounterID).First(&encounter).Error; err != nil {
+		return nil, fmt.Errorf("encounter not found")
+	}
+	if !encounter.IsActive() {
+		return nil, fmt.Errorf("encounter has been discharged")
+	}
+
+	updated := make([]string, 0, 6)
+	if req.Facility != nil {
+		encounter.Facility = strings.TrimSpace(*req.Facility)
+		updated = append(updated, "facility")
+	}
+	if req.Department != nil {
+		encounter.Department = strings.TrimSpace(*req.Department)
+		updated = append(updated, "department")
+	}
+	if req.Location != nil {
+		encounter.Location = strings.TrimSpace(*req.Location)
+		updated = append(updated, "location")
+	}
+	if req.AttendingID != nil {
+		if err := s.validateAttending(*req.AttendingID); err != nil {
+			return nil, err
+		}
+		encounter.AttendingID = *req.AttendingID
+		updated = append(updated, "attending_id")
+	}
+	if req.Reason != nil {
+		encounter.Reason = *req.Reason
+		updated = append(updated, "reason")
+	}
+	if encounter.Facility == "" || encounter.Department == "" {
+		return nil, fmt.Errorf("facility and department are required")
+	}
+
+	if len(updated) > 0 {
+		encounter.UpdatedAt = time.Now()
+		updated = append(updated, "updated_at")
+		if err := s.db.Model(&encounter).Select(updated).Updates(&encounter).Error; err != nil {
+			return nil, fmt.Errorf("failed to update encounter: %w", err)
+		}
+	}
+
+	s.logWrite(&encounter, updatedByUserID, models.ActionUpdate, ipAddress, userAgent, "encounter_updated", purpose)
+
+	return &encounter, nil
+}
+
+// DischargeEncounter ends an active encounter, removing it from the census
+func (s *EncounterService) DischargeEncounter(encounterID uint, req *DischargeEncounterRequest, dischargedByUserID uint, dischargedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.Encounter, error) {
+	if dischargedByRole != models.RoleDoctor && dischargedByRole != models.RoleNurse {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(dischargedByUserID, fmt.Sprintf("encounter:%d", encounterID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to discharge encounters")
+	}
+
+	var encounter models.Encounter
+	if err := s.db.Where("id = ?", encounterID).First(&encounter).Error; err != nil {
+		return nil, fmt.Errorf("encounter not found")
+	}
+
+	at := time.Now()
+	if req.DischargeAt != nil {
+		if req.DischargeAt.After(at.Add(5 * time.Minute)) {
+			return nil, fmt.Errorf("discharge time cannot be in the future")
+		}
+		at = *req.DischargeAt
+	}
+	if err := encounter.Discharge(at); err != nil {
+		return nil, fmt.Errorf("encounter cannot be discharged: it is not active or the time precedes admission")
+	}
+
+	// Guard on status so a concurrent discharge is not overwritten
+	result := s.db.Model(&encounter).Where("status = ?", models.EncounterActive).
+		Select("status", "discharge_at", "updated_at").Updates(&encounter)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to discharge encounter: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("encounter has been discharged")
+	}
+
+	s.logWrite(&encounter, dischargedByUserID, models.ActionUpdate, ipAddress, userAgent, "encounter_discharged", purpose)
+
+	return &encounter, nil
+}
+
+// GetCensus lists currently admitted patients grouped by facility and
+// department. Only inpatient encounters are counted unless another class is
+// requested. Confidential patients outside the caller's care team are
+// masked.
+func (s *EncounterService) GetCensus(query *CensusQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) ([]CensusUnit, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	if !s.canManageEncounters(requestedByRole) {
+		audit.LogUnauthorizedAccess(requestedByUserID, "census", ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to view the census")
+	}
+
+	class := models.EncounterClass(query.Class)
+	if class == "" {
+		class = models.EncounterInpatient
+	}
+	if !models.IsValidEncounterClass(class) {
+		return nil, fmt.Errorf("invalid encounter class")
+	}
+
+	db := s.db.Where("status = ? AND class = ?", models.EncounterActive, class)
+	if query.Facility != "" {
+		db = db.Where("facility = ?", query.Facility)
+	}
+	if query.Department != "" {
+		db = db.Where("department = ?", query.Department)
+	}
+
+	var encounters []models.Encounter
+	if err := db.Order("facility, department, location, admit_at").Find(&encounters).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve census: %w", err)
+	}
+
+	patientIDs := make([]uint, 0, len(encounters))
+	for _, encounter := range encounters {
+		patientIDs = append(patientIDs, encounter.PatientID)
+	}
+	var patients []models.Patient
+	if len(patientIDs) > 0 {
+		if err := s.db.Where("id IN ?", patientIDs).Find(&patients).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve census patients: %w", err)
+		}
+	}
+	patients = s.careTeam.MaskForSearch(patients, requestedByUserID, ipAddress, userAgent, "census", purpose)
+
+	projected := make(map[uint]models.Projection, len(patients))
+	disclosedSet := make(map[string]bool)
+	for i := range patients {
+		projection, disclosed := policy.Project(models.ResourcePatient, requestedByRole, &patients[i], censusPatientFields)
+		projected[patients[i].ID] = projection
+		for _, field := range disclosed {
+			disclosedSet[field] = true
+		}
+	}
+
+	units := make([]CensusUnit, 0)
+	for _, encounter := range encounters {
+		patient, found := projected[encounter.PatientID]
+		if !found {
+			// Deleted patients drop off the census
+			continue
+		}
+		if len(units) == 0 || units[len(units)-1].Facility != encounter.Facility || units[len(units)-1].Department != encounter.Department {
+			units = append(units, CensusUnit{Facility: encounter.Facility, Department: encounter.Department, Patients: []CensusEntry{}})
+		}
+		unit := &units[len(units)-1]
+		unit.Patients = append(unit.Patients, CensusEntry{
+			EncounterID: encounter.ID,
+			Class:       encounter.Class,
+			Location:    encounter.Location,
+			AdmitAt:     encounter.AdmitAt,
+			AttendingID: encounter.AttendingID,
+			Patient:     patient,
+		})
+		unit.Count++
+	}
+
+	disclosed := make([]string, 0, len(disclosedSet))
+	for _, field := range censusPatientFields {
+		if disclosedSet[field] {
+			disclosed = append(disclosed, field)
+		}
+	}
+	audit.LogListDisclosure(requestedByUserID, "census", ipAddress, userAgent, fmt.Sprintf("returned_%d_patients", len(projected)), patientIDsOf(patients), disclosed)
+
+	return units, nil
+}
+
+func (s *EncounterService) canManageEncounters(role models.UserRole) bool {
+	return role == models.RoleDoctor || role == models.RoleNurse || role == models.RoleFrontDesk
+}
+
+// validateAttending requires the attending physician to be an active doctor
+// who is not a resident
+func (s *EncounterService) validateAttending(userID uint) error {
+	var attending models.User
+	if err := s.db.Where("id = ? AND active = ?", userID, true).First(&attending).Error; err != nil {
+		return fmt.Errorf("attending physician not found")
+	}
+	if !attending.IsAttending() {
+		return fmt.Errorf("attending physician must be a doctor who is not a resident")
+	}
+	return nil
+}
+
+func (s *EncounterService) authorizeRead(patientID, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) error {
+	policy := s.fieldPolicy.ForPurpose(purpose)
+	if len(policy[models.ResourceEncounter][requestedByRole]) == 0 {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("encounters:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to access encounters")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return fmt.Errorf("patient not found")
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	return s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose)
+}
+
+func (s *EncounterService) logRead(patientID uint, resource string, requestedByUserID uint, ipAddress, userAgent string, emergencyAccess bool, purpose models.PurposeOfUse, disclosed []string) {
+	reason := ""
+	if emergencyAccess {
+		reason = "emergency_access"
+	} else {
+		reason = s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
+	}
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(requestedByUserID, patientID, resource,
+		models.ActionView, ipAddress, userAgent, emergencyAccess, reason, disclosed)
+}
+
+func (s *EncounterService) logWrite(encounter *models.Encounter, userID uint, action models.AuditAction, ipAddress, userAgent, reason string, purpose models.PurposeOfUse) {
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(userID, encounter.PatientID, fmt.Sprintf("encounter:%d", encounter.ID),
+		action, ipAddress, userAgent, false, reason, nil)
+}
+
+// checkEncounter verifies that an encounter clinical data is filed under
+// belongs to the same patient. Late results may still be filed under a
+// discharged encounter.
+func checkEncounter(db *gorm.DB, patientID uint, encounterID *uint) error {
+	if encounterID == nil {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Encounter{}).Where("id = ? AND patient_id = ?", *encounterID, patientID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check encounter: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("encounter not found for this patient")
+	}
+	return nil
+}
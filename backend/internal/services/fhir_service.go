@@ -0,0 +1,426 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"healthsecure/internal/fhir"
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// FHIRService serves the FHIR R4 API. Every read and write goes through the
+// services behind the native API, so role checks, field rules, confidential
+// chart rules and audit logging are the same; the projections they return
+// are mapped to FHIR resources. Patients are known only by their public IDs.
+type FHIRService struct {
+	db            *gorm.DB
+	audit         *AuditService
+	patients      *PatientService
+	clinicalLists *ClinicalListService
+	observations  *ObservationService
+}
+
+func NewFHIRService(db *gorm.DB, audit *AuditService, patients *PatientService, clinicalLists *ClinicalListService, observations *ObservationService) *FHIRService {
+	return &FHIRService{
+		db:            db,
+		audit:         audit,
+		patients:      patients,
+		clinicalLists: clinicalLists,
+		observations:  observations,
+	}
+}
+
+// ReadPatient returns a patient's demographics
+func (s *FHIRService) ReadPatient(id string, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*fhir.Patient, error) {
+	patientID, publicID, err := s.resolvePatient(id)
+	if err != nil {
+		return nil, err
+	}
+
+	projection, err := s.patients.GetPatient(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var patient models.Patient
+	if err := decodeProjection(projection, &patient); err != nil {
+		return nil, err
+	}
+	patient.PublicID = publicID
+	return fhir.NewPatient(&patient), nil
+}
+
+// SearchPatients returns a page of matching patients and the number of
+// matches
+func (s *FHIRService) SearchPatients(query *PatientSearchQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) ([]*fhir.Patient, int64, error) {
+	projections, total, err := s.patients.GetPatients(query, requestedByUserID, requestedByRole, ipAddress, userAgent, purpose, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var patients []models.Patient
+	if err := decodeProjection(projections, &patients); err != nil {
+		return nil, 0, err
+	}
+	ids := make([]uint, len(patients))
+	for i, patient := range patients {
+		ids[i] = patient.ID
+	}
+	publicIDs, err := s.publicIDs(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resources := make([]*fhir.Patient, len(patients))
+	for i := range patients {
+		patients[i].PublicID = publicIDs[patients[i].ID]
+		resources[i] = fhir.NewPatient(&patients[i])
+	}
+	return resources, total, nil
+}
+
+// CreatePatient registers a patient and issues their MRN
+func (s *FHIRService) CreatePatient(resource *fhir.Patient, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*fhir.Patient, error) {
+	demographics, err := fhir.ParsePatient(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &CreatePatientRequest{
+		FirstName:        demographics.FirstName,
+		LastName:         demographics.LastName,
+		DateOfBirth:      demographics.DateOfBirth,
+		Phone:            demographics.Phone,
+		Address:          demographics.Address,
+		EmergencyContact: demographics.EmergencyContact,
+		Confidential:     demographics.Confidential,
+	}
+	patient, _, err := s.patients.CreatePatient(req, createdByUserID, createdByRole, ipAddress, userAgent, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	return fhir.NewPatient(patient.SanitizeForRole(createdByRole)), nil
+}
+
+// UpdatePatient replaces a patient's demographics with those of resource.
+// Elements left out are cleared. The confidentiality flag is only passed on
+// when it changes, since only doctors may change it.
+func (s *FHIRService) UpdatePatient(id string, resource *fhir.Patient, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent, accessReason string, purpose models.PurposeOfUse) (*fhir.Patient, error) {
+	if resource.ID != "" && resource.ID != id {
+		return nil, fmt.Errorf("resource id does not match the URL")
+	}
+	patientID, publicID, err := s.resolvePatient(id)
+	if err != nil {
+		return nil, err
+	}
+	demographics, err := fhir.ParsePatient(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var current models.Patient
+	if err := s.db.Select("id, confidential").Where("id = ?", patientID).First(&current).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	req := &UpdatePatientRequest{
+		FirstName:        &demographics.FirstName,
+		LastName:         &demographics.LastName,
+		DateOfBirth:      &demographics.DateOfBirth,
+		Phone:            &demographics.Phone,
+		Address:          &demographics.Address,
+		EmergencyContact: &demographics.EmergencyContact,
+	}
+	if demographics.Confidential != current.Confidential {
+		req.Confidential = &demographics.Confidential
+	}
+
+	projection, err := s.patients.UpdatePatient(patientID, req, updatedByUserID, updatedByRole, ipAddress, userAgent, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	var patient models.Patient
+	if err := decodeProjection(projection, &patient); err != nil {
+		return nil, err
+	}
+	patient.PublicID = publicID
+	return fhir.NewPatient(&patient), nil
+}
+
+// ReadCondition returns an entry on a problem list. Condition ids are
+// "problem-" followed by the problem's ID.
+func (s *FHIRService) ReadCondition(id string, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*fhir.Condition, error) {
+	problemID, err := strconv.ParseUint(strings.TrimPrefix(id, "problem-"), 10, 32)
+	if err != nil || !strings.HasPrefix(id, "problem-") {
+		return nil, fmt.Errorf("condition not found")
+	}
+	patientID, err := s.entryPatient(&models.Problem{}, uint(problemID), "condition")
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, err := s.searchConditions(patientID, &ClinicalListQuery{ID: uint(problemID)}, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("condition not found")
+	}
+	return conditions[0], nil
+}
+
+// SearchConditions returns the entries on a patient's problem list
+func (s *FHIRService) SearchConditions(patient string, query *ClinicalListQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]*fhir.Condition, error) {
+	patientID, _, err := s.resolvePatient(patient)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchConditions(patientID, query, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+}
+
+func (s *FHIRService) searchConditions(patientID uint, query *ClinicalListQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]*fhir.Condition, error) {
+	projections, err := s.clinicalLists.GetProblems(patientID, query, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose, nil)
+	if err != nil {
+		return nil, err
+	}
+	var problems []models.Problem
+	if err := decodeProjection(projections, &problems); err != nil {
+		return nil, err
+	}
+	subject, err := s.subject(patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := make([]*fhir.Condition, len(problems))
+	for i := range problems {
+		conditions[i] = fhir.NewProblemCondition(&problems[i], subject)
+	}
+	return conditions, nil
+}
+
+// ReadMedicationStatement returns a medication order
+func (s *FHIRService) ReadMedicationStatement(id uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*fhir.MedicationStatement, error) {
+	patientID, err := s.entryPatient(&models.MedicationOrder{}, id, "medication statement")
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := s.searchMedicationStatements(patientID, &ClinicalListQuery{ID: id}, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("medication statement not found")
+	}
+	return statements[0], nil
+}
+
+// SearchMedicationStatements returns a patient's medication orders
+func (s *FHIRService) SearchMedicationStatements(patient string, query *ClinicalListQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]*fhir.MedicationStatement, error) {
+	patientID, _, err := s.resolvePatient(patient)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchMedicationStatements(patientID, query, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+}
+
+func (s *FHIRService) searchMedicationStatements(patientID uint, query *ClinicalListQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]*fhir.MedicationStatement, error) {
+	projections, err := s.clinicalLists.GetMedications(patientID, query, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose, nil)
+	if err != nil {
+		return nil, err
+	}
+	var orders []models.MedicationOrder
+	if err := decodeProjection(projections, &orders); err != nil {
+		return nil, err
+	}
+	subject, err := s.subject(patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := make([]*fhir.MedicationStatement, len(orders))
+	for i := range orders {
+		statements[i] = fhir.NewMedicationStatement(&orders[i], subject)
+	}
+	return statements, nil
+}
+
+// ReadObservation returns a vital sign or lab result
+func (s *FHIRService) ReadObservation(id uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*fhir.Observation, error) {
+	patientID, err := s.entryPatient(&models.Observation{}, id, "observation")
+	if err != nil {
+		return nil, err
+	}
+
+	observations, _, err := s.searchObservations(patientID, &ObservationQuery{ID: id, Page: 1, Limit: 1}, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(observations) == 0 {
+		return nil, fmt.Errorf("observation not found")
+	}
+	return observations[0], nil
+}
+
+// SearchObservations returns a page of a patient's observations, most recent
+// first, and the number of matches
+func (s *FHIRService) SearchObservations(patient string, query *ObservationQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]*fhir.Observation, int64, error) {
+	patientID, _, err := s.resolvePatient(patient)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.searchObservations(patientID, query, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+}
+
+func (s *FHIRService) searchObservations(patientID uint, query *ObservationQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]*fhir.Observation, int64, error) {
+	projections, total, err := s.observations.GetObservations(patientID, query, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	var observations []models.Observation
+	if err := decodeProjection(projections, &observations); err != nil {
+		return nil, 0, err
+	}
+	subject, err := s.subject(patientID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resources := make([]*fhir.Observation, len(observations))
+	for i := range observations {
+		resources[i] = fhir.NewObservation(&observations[i], subject)
+	}
+	return resources, total, nil
+}
+
+// CreateObservation records a vital sign or lab result for the patient named
+// as its subject
+func (s *FHIRService) CreateObservation(resource *fhir.Observation, recordedByUserID uint, recordedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*fhir.Observation, error) {
+	parsed, err := fhir.ParseObservation(resource)
+	if err != nil {
+		return nil, err
+	}
+	patient := fhir.ParseReference(resource.Subject.Reference, "Patient")
+	if patient == "" {
+		return nil, fmt.Errorf("observation subject must be a Patient")
+	}
+	patientID, _, err := s.resolvePatient(patient)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &RecordObservationRequest{
+		Category:       parsed.Category,
+		Code:           parsed.Code,
+		Display:        parsed.Display,
+		ValueQuantity:  parsed.ValueQuantity,
+		ValueString:    parsed.ValueString,
+		Unit:           parsed.Unit,
+		ReferenceLow:   parsed.ReferenceLow,
+		ReferenceHigh:  parsed.ReferenceHigh,
+		Interpretation: parsed.Interpretation,
+		Comment:        parsed.Comment,
+	}
+	if !parsed.EffectiveAt.IsZero() {
+		req.EffectiveAt = &parsed.EffectiveAt
+	}
+
+	observation, err := s.observations.RecordObservation(patientID, req, recordedByUserID, recordedByRole, ipAddress, userAgent, purpose)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := s.subject(patientID)
+	if err != nil {
+		return nil, err
+	}
+	return fhir.NewObservation(observation, subject), nil
+}
+
+// ReadAuditEvent returns an audit log entry the caller may see
+func (s *FHIRService) ReadAuditEvent(id uint, requestedByUserID uint, requestedByRole models.UserRole) (*fhir.AuditEvent, error) {
+	events, _, err := s.SearchAuditEvents("", &AuditLogQuery{ID: &id, Page: 1, Limit: 1}, requestedByUserID, requestedByRole)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("audit event not found")
+	}
+	return events[0], nil
+}
+
+// SearchAuditEvents returns a page of audit log entries, newest first, and
+// the number of matches. Admins see every entry, medical staff their own.
+func (s *FHIRService) SearchAuditEvents(patient string, query *AuditLogQuery, requestedByUserID uint, requestedByRole models.UserRole) ([]*fhir.AuditEvent, int64, error) {
+	if patient != "" {
+		patientID, _, err := s.resolvePatient(patient)
+		if err != nil {
+			return nil, 0, err
+		}
+		query.PatientID = &patientID
+	}
+
+	logs, total, err := s.audit.GetAuditLogs(query, requestedByRole, requestedByUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := make([]*fhir.AuditEvent, len(logs))
+	for i := range logs {
+		events[i] = fhir.NewAuditEvent(&logs[i])
+	}
+	return events, total, nil
+}
+
+// resolvePatient maps a FHIR patient id to the patient's database and public
+// IDs
+func (s *FHIRService) resolvePatient(id string) (uint, string, error) {
+	publicID := fhir.PatientPublicID(id)
+	if publicID == "" {
+		return 0, "", fmt.Errorf("patient not found")
+	}
+	patientID, err := s.patients.ResolvePatientPublicID(publicID)
+	if err != nil {
+		return 0, "", err
+	}
+	return patientID, publicID, nil
+}
+
+// entryPatient returns the patient an entry on one of the clinical lists
+// belongs to
+func (s *FHIRService) entryPatient(model interface{}, id uint, resource string) (uint, error) {
+	var patientIDs []uint
+	if err := s.db.Model(model).Where("id = ?", id).Pluck("patient_id", &patientIDs).Error; err != nil || len(patientIDs) == 0 {
+		return 0, fmt.Errorf("%s not found", resource)
+	}
+	return patientIDs[0], nil
+}
+
+// subject references a patient by their FHIR id
+func (s *FHIRService) subject(patientID uint) (fhir.Reference, error) {
+	publicIDs, err := s.publicIDs([]uint{patientID})
+	if err != nil {
+		return fhir.Reference{}, err
+	}
+	return fhir.Reference{Reference: "Patient/" + fhir.PatientID(publicIDs[patientID])}, nil
+}
+
+func (s *FHIRService) publicIDs(patientIDs []uint) (map[uint]string, error) {
+	var patients []models.Patient
+	if len(patientIDs) > 0 {
+		if err := s.db.Select("id, public_id").Where("id IN ?", patientIDs).Find(&patients).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve patient IDs: %w", err)
+		}
+	}
+
+	publicIDs := make(map[uint]string, len(patients))
+	for _, patient := range patients {
+		publicIDs[patient.ID] = patient.PublicID
+	}
+	return publicIDs, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"healthsecure/configs"
+	"healthsecure/internal/cds"
+	"healthsecure/internal/fhir"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFHIRService(t *testing.T) *FHIRService {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	careTeam := NewCareTeamService(db, audit)
+	patients := NewPatientService(db, audit, NewConsentService(db, audit, careTeam), careTeam, models.DefaultFieldPolicy(), &configs.Config{}, nil)
+	lists := NewClinicalListService(db, audit, careTeam, models.DefaultFieldPolicy(), cds.NewChecker(cds.DefaultDataset()))
+	observations := NewObservationService(db, audit, careTeam, models.DefaultFieldPolicy(), nil)
+	return NewFHIRService(db, audit, patients, lists, observations)
+}
+
+func TestFHIRReads(t *testing.T) {
+	service := newFHIRService(t)
+	db := service.db
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	outsider := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, true)
+	addToCareTeam(t, db, patient, doctor)
+	problem := &models.Problem{PatientID: patient.ID, Condition: "Hypertension", RecordedBy: doctor.ID}
+	require.NoError(t, db.Create(problem).Error)
+
+	id := fhir.PatientID(patient.PublicID)
+	conditionID := fmt.Sprintf("problem-%d", problem.ID)
+
+	t.Run("PatientByPublicID", func(t *testing.T) {
+		resource, err := service.ReadPatient(id, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", models.PurposeTreatment)
+		require.NoError(t, err)
+		assert.Equal(t, id, resource.ID)
+		require.NotEmpty(t, resource.Name)
+		assert.Equal(t, "Doe", resource.Name[0].Family)
+		assert.Equal(t, models.ActionView, lastAuditEntry(t, db, doctor.ID).Action)
+
+		_, err = service.ReadPatient(fmt.Sprint(patient.ID), doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", models.PurposeTreatment)
+		assert.EqualError(t, err, "patient not found")
+	})
+
+	t.Run("ConditionSubjectIsPatient", func(t *testing.T) {
+		condition, err := service.ReadCondition(conditionID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", models.PurposeTreatment)
+		require.NoError(t, err)
+		assert.Equal(t, "Patient/"+id, condition.Subject.Reference)
+
+		_, err = service.ReadCondition(fmt.Sprint(problem.ID), doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", models.PurposeTreatment)
+		assert.EqualError(t, err, "condition not found")
+	})
+
+	t.Run("ConfidentialChartNeedsReason", func(t *testing.T) {
+		_, err := service.ReadPatient(id, outsider.ID, models.RoleDoctor, testIP, testUserAgent, false, "", models.PurposeTreatment)
+		assert.Error(t, err)
+		_, err = service.ReadCondition(conditionID, outsider.ID, models.RoleDoctor, testIP, testUserAgent, false, "", models.PurposeTreatment)
+		assert.Error(t, err)
+
+		_, err = service.ReadPatient(id, outsider.ID, models.RoleDoctor, testIP, testUserAgent, false, "Covering for the attending", models.PurposeTreatment)
+		assert.NoError(t, err)
+	})
+}
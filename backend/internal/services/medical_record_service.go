@@ -5,13 +5,18 @@ import (
 	"time"
 
 	"healthsecure/internal/models"
+	"healthsecure/internal/terminology"
 
 	"gorm.io/gorm"
 )
 
 type MedicalRecordService struct {
-	db    *gorm.DB
-	audit *AuditService
+	db       *gorm.DB
+	audit    *AuditService
+	consents    *ConsentService
+	careTeam    *CareTeamService
+	fieldPolicy models.FieldPolicy
+	terms       *terminology.Terminology
 }
 
 type CreateMedicalRecordRequest struct {
@@ -21,106 +26,184 @@ type CreateMedicalRecordRequest struct {
 	Notes       string                  `json:"notes"`
 	Medications string                  `json:"medications"`
 	Severity    models.SeverityLevel    `json:"severity" binding:"required"`
+	Sensitivity models.SensitivityLevel `json:"sensitivity"`
+	EncounterID *uint                   `json:"encounter_id,omitempty"`
+	Diagnoses   []DiagnosisInput        `json:"diagnoses,omitempty"`
+	Template    string                  `json:"template,omitempty"`
+	Sections    models.NoteContent      `json:"sections,omitempty"`
 }
 
 type UpdateMedicalRecordRequest struct {
-	Diagnosis   *string                 `json:"diagnosis,omitempty"`
-	Treatment   *string                 `json:"treatment,omitempty"`
-	Notes       *string                 `json:"notes,omitempty"`
-	Medications *string                 `json:"medications,omitempty"`
-	Severity    *models.SeverityLevel   `json:"severity,omitempty"`
+	Diagnosis   *string                  `json:"diagnosis,omitempty"`
+	Treatment   *string                  `json:"treatment,omitempty"`
+	Notes       *string                  `json:"notes,omitempty"`
+	Medications *string                  `json:"medications,omitempty"`
+	Severity    *models.SeverityLevel    `json:"severity,omitempty"`
+	Sensitivity *models.SensitivityLevel `json:"sensitivity,omitempty"`
+	Diagnoses   *[]DiagnosisInput        `json:"diagnoses,omitempty"`
+	Sections    *models.NoteContent      `json:"sections,omitempty"`
+	Reason      string                   `json:"reason,omitempty"`
 }
 
-func NewMedicalRecordService(db *gorm.DB, audit *AuditService) *MedicalRecordService {
+func NewMedicalRecordService(db *gorm.DB, audit *AuditService, consents *ConsentService, careTeam *CareTeamService, fieldPolicy models.FieldPolicy, terms *terminology.Terminology) *MedicalRecordService {
 	return &MedicalRecordService{
-		db:    db,
-		audit: audit,
+		db:          db,
+		audit:       audit,
+		consents:    consents,
+		careTeam:    careTeam,
+		fieldPolicy: fieldPolicy,
+		terms:       terms,
 	}
 }
 
 // CreateMedicalRecord creates a new medical record
-func (s *MedicalRecordService) CreateMedicalRecord(req *CreateMedicalRecordRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string) (*models.MedicalRecord, error) {
+func (s *MedicalRecordService) CreateMedicalRecord(req *CreateMedicalRecordRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent, accessReason string, purpose models.PurposeOfUse) (*models.MedicalRecord, error) {
+	audit := s.audit.WithPurpose(purpose)
+
 	// Only doctors can create medical records
 	if createdByRole != models.RoleDoctor {
-		s.audit.LogUnauthorizedAccess(createdByUserID, fmt.Sprintf("medical_record:create:patient_%d", req.PatientID), ipAddress, userAgent, "insufficient_role")
+		audit.LogUnauthorizedAccess(createdByUserID, fmt.Sprintf("medical_record:create:patient_%d", req.PatientID), ipAddress, userAgent, "insufficient_role")
 		return nil, fmt.Errorf("insufficient permissions to create medical record")
 	}
 
+	if req.Sensitivity == "" {
+		req.Sensitivity = models.SensitivityNormal
+	}
+	if !models.IsValidSensitivity(req.Sensitivity) {
+		return nil, fmt.Errorf("invalid sensitivity classification")
+	}
+
 	// Verify patient exists
 	var patient models.Patient
 	if err := s.db.Where("id = ?", req.PatientID).First(&patient).Error; err != nil {
 		return nil, fmt.Errorf("patient not found")
 	}
 
+	// Writing to a confidential chart needs the same stated reason as reading it
+	if err := s.careTeam.AuthorizeConfidentialAccess(&patient, createdByUserID, models.ActionCreate, ipAddress, userAgent, accessReason, false, purpose); err != nil {
+		return nil, err
+	}
+
+	if err := checkEncounter(s.db, req.PatientID, req.EncounterID); err != nil {
+		return nil, err
+	}
+
+	diagnoses, err := codeDiagnoses(s.terms, req.PatientID, req.Diagnoses)
+	if err != nil {
+		return nil, err
+	}
+
+	// A templated note keeps its sections and a narrative rendered from them
+	var templateID *uint
+	var sections models.NoteContent
+	notes := req.Notes
+	if req.Template != "" {
+		if req.Notes != "" {
+			return nil, fmt.Errorf("notes are rendered from the template sections")
+		}
+		template, err := currentTemplate(s.db, req.Template)
+		if err != nil {
+			return nil, err
+		}
+		sections, err = template.CheckContent(req.Sections)
+		if err != nil {
+			return nil, err
+		}
+		templateID = &template.ID
+		notes = template.Render(sections)
+	} else if len(req.Sections) > 0 {
+		return nil, fmt.Errorf("sections require a template")
+	}
+
 	// Create medical record
 	record := models.MedicalRecord{
 		PatientID:   req.PatientID,
 		DoctorID:    createdByUserID,
+		EncounterID: req.EncounterID,
 		Diagnosis:   req.Diagnosis,
 		Treatment:   req.Treatment,
-		Notes:       req.Notes,
+		Notes:       notes,
+		TemplateID:  templateID,
+		Sections:    sections,
 		Medications: req.Medications,
 		Severity:    req.Severity,
+		Sensitivity: req.Sensitivity,
+		Diagnoses:   diagnoses,
 	}
 
-	if err := s.db.Create(&record).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, models.ResourceMedicalRecord, record.ID, &record, createdByUserID, "medical_record_created")
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create medical record: %w", err)
 	}
 
 	// Log medical record creation
-	s.audit.LogMedicalRecordAccess(createdByUserID, req.PatientID, record.ID, models.ActionCreate, ipAddress, userAgent, false, "medical_record_created")
+	s.logRecordAccess(&record, createdByUserID, models.ActionCreate, ipAddress, userAgent, false, "medical_record_created", purpose)
 
 	return &record, nil
 }
 
 // GetMedicalRecord retrieves a medical record by ID
-func (s *MedicalRecordService) GetMedicalRecord(recordID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool) (*models.MedicalRecord, error) {
-	if !s.canAccessMedicalRecords(requestedByRole) {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "insufficient_role")
-		return nil, fmt.Errorf("insufficient permissions to access medical records")
-	}
+func (s *MedicalRecordService) GetMedicalRecord(recordID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) (models.Projection, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
 
-	var record models.MedicalRecord
-	if err := s.db.Where("id = ?", recordID).Preload("Patient").Preload("Doctor").First(&record).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("medical record not found")
-		}
-		return nil, fmt.Errorf("failed to retrieve medical record: %w", err)
+	record, err := s.authorizeRecordAccess(recordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check role-based access
-	if !record.CanBeAccessedByRole(requestedByRole, requestedByUserID) && !emergencyAccess {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "role_access_denied")
+	// Sanitize based on role
+	sanitized := record.SanitizeForRole(requestedByRole)
+	if sanitized == nil {
 		return nil, fmt.Errorf("access denied to medical record")
 	}
 
-	// Log medical record access
+	projection, disclosed := policy.Project(models.ResourceMedicalRecord, requestedByRole, sanitized, fields)
+
+	// Log medical record access with the fields actually disclosed
 	reason := ""
 	if emergencyAccess {
 		reason = "emergency_access"
+	} else {
+		reason = s.careTeam.DelegatedAccessReason(record.PatientID, requestedByUserID)
 	}
-	s.audit.LogMedicalRecordAccess(requestedByUserID, record.PatientID, recordID, models.ActionView, ipAddress, userAgent, emergencyAccess, reason)
+	audit.LogMedicalRecordDisclosure(record, requestedByUserID, ipAddress, userAgent, emergencyAccess, reason, disclosed)
 
-	// Sanitize based on role
-	sanitized := record.SanitizeForRole(requestedByRole)
-	if sanitized == nil {
-		return nil, fmt.Errorf("access denied to medical record")
-	}
-
-	return sanitized, nil
+	return projection, nil
 }
 
 // GetPatientMedicalRecords retrieves all medical records for a patient
-func (s *MedicalRecordService) GetPatientMedicalRecords(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, page, limit int) ([]models.MedicalRecord, int64, error) {
+func (s *MedicalRecordService) GetPatientMedicalRecords(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string, encounterID *uint, page, limit int) ([]models.Projection, int64, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
 	if !s.canAccessMedicalRecords(requestedByRole) {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_records:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_records:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
 		return nil, 0, fmt.Errorf("insufficient permissions to access medical records")
 	}
 
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, 0, fmt.Errorf("patient not found")
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	if err := s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose); err != nil {
+		return nil, 0, err
+	}
+
 	var records []models.MedicalRecord
 	var total int64
 
 	query := s.db.Where("patient_id = ?", patientID)
+	if encounterID != nil {
+		query = query.Where("encounter_id = ?", *encounterID)
+	}
 
 	// Apply role-based filtering
 	if requestedByRole == models.RoleNurse && !emergencyAccess {
@@ -128,16 +211,23 @@ func (s *MedicalRecordService) GetPatientMedicalRecords(patientID uint, requeste
 		query = query.Where("severity != ?", models.SeverityCritical)
 	}
 
-	query.Model(&models.MedicalRecord{}).Count(&total)
-
-	offset := (page - 1) * limit
-	if err := query.Offset(offset).Limit(limit).
-		Preload("Patient").Preload("Doctor").
+	// Withholding depends on consents and designations, so records are
+	// filtered before paging and the total only counts what the caller can
+	// see. Otherwise the total would reveal how many records are withheld.
+	if err := query.Preload("Patient").Preload("Doctor").Preload("Diagnoses", models.PreloadDiagnoses).
 		Order("created_at DESC").Find(&records).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to retrieve medical records: %w", err)
 	}
+	for i := range records {
+		if err := records[i].CheckIntegrity(); err != nil {
+			audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d", records[i].ID), ipAddress, userAgent, "integrity_check_failed")
+			return nil, 0, err
+		}
+	}
+
+	// Drop records withheld from this user, then sanitize based on role
+	records = s.consents.FilterDisclosable(records, requestedByUserID)
 
-	// Sanitize records based on role
 	var sanitizedRecords []models.MedicalRecord
 	for _, record := range records {
 		if sanitized := record.SanitizeForRole(requestedByRole); sanitized != nil {
@@ -145,20 +235,37 @@ func (s *MedicalRecordService) GetPatientMedicalRecords(patientID uint, requeste
 		}
 	}
 
-	// Log access
+	total = int64(len(sanitizedRecords))
+	offset := (page - 1) * limit
+	if offset > len(sanitizedRecords) {
+		offset = len(sanitizedRecords)
+	}
+	end := offset + limit
+	if end > len(sanitizedRecords) {
+		end = len(sanitizedRecords)
+	}
+	sanitizedRecords = sanitizedRecords[offset:end]
+
+	projections, disclosed := policy.ProjectAll(models.ResourceMedicalRecord, requestedByRole, sanitizedRecords, fields)
+
+	// Log access with the fields actually disclosed
 	reason := ""
 	if emergencyAccess {
 		reason = "emergency_access"
+	} else {
+		reason = s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
 	}
-	s.audit.LogPatientAccess(requestedByUserID, patientID, models.ActionView, ipAddress, userAgent, emergencyAccess, reason)
+	audit.LogPatientDisclosure(requestedByUserID, patientID, ipAddress, userAgent, emergencyAccess, reason, disclosed)
 
-	return sanitizedRecords, total, nil
+	return projections, total, nil
 }
 
 // UpdateMedicalRecord updates a medical record
-func (s *MedicalRecordService) UpdateMedicalRecord(recordID uint, req *UpdateMedicalRecordRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent string) (*models.MedicalRecord, error) {
+func (s *MedicalRecordService) UpdateMedicalRecord(recordID uint, req *UpdateMedicalRecordRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.MedicalRecord, error) {
+	audit := s.audit.WithPurpose(purpose)
+
 	var record models.MedicalRecord
-	if err := s.db.Where("id = ?", recordID).First(&record).Error; err != nil {
+	if err := s.db.Where("id = ?", recordID).Preload("Diagnoses", models.PreloadDiagnoses).First(&record).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("medical record not found")
 		}
@@ -167,52 +274,333 @@ func (s *MedicalRecordService) UpdateMedicalRecord(recordID uint, req *UpdateMed
 
 	// Check permissions - only doctors can update, and typically only the creating doctor
 	if updatedByRole != models.RoleDoctor {
-		s.audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "insufficient_role")
+		audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "insufficient_role")
 		return nil, fmt.Errorf("insufficient permissions to update medical record")
 	}
 
-	// Additional check: only the creating doctor or any doctor with emergency access can update
+	// Additional check: only the creating doctor, or a colleague covering for them
+	// through a delegation grant, can update
+	reason := "medical_record_updated"
 	if record.DoctorID != updatedByUserID {
-		s.audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "not_creating_doctor")
-		return nil, fmt.Errorf("only the creating doctor can update this medical record")
+		grant := s.careTeam.DelegationFrom(record.DoctorID, updatedByUserID, record.PatientID)
+		if grant == nil {
+			audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "not_creating_doctor")
+			return nil, fmt.Errorf("only the creating doctor can update this medical record")
+		}
+		reason = fmt.Sprintf("medical_record_updated:%s", grant.AuditReason())
+	}
+
+	// Signed records are corrected only through addenda
+	if record.IsLocked() {
+		audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "record_locked")
+		return nil, models.ErrRecordLocked
 	}
 
-	// Build update map
-	updates := make(map[string]interface{})
+	// Apply changes to the model rather than a map so that clinical text is
+	// written through the encrypted serializer
+	original := record
+	updated := make([]string, 0, 9)
 	
 	if req.Diagnosis != nil {
-		updates["diagnosis"] = *req.Diagnosis
+		record.Diagnosis = *req.Diagnosis
+		updated = append(updated, "diagnosis")
 	}
 	if req.Treatment != nil {
-		updates["treatment"] = *req.Treatment
+		record.Treatment = *req.Treatment
+		updated = append(updated, "treatment")
 	}
 	if req.Notes != nil {
-		updates["notes"] = *req.Notes
+		if record.TemplateID != nil {
+			return nil, fmt.Errorf("notes are rendered from the template sections")
+		}
+		record.Notes = *req.Notes
+		updated = append(updated, "notes")
+	}
+	if req.Sections != nil {
+		if record.TemplateID == nil {
+			return nil, fmt.Errorf("sections require a template")
+		}
+		// Edits are checked against the version the note was written with
+		var template models.NoteTemplate
+		if err := s.db.Where("id = ?", *record.TemplateID).First(&template).Error; err != nil {
+			return nil, fmt.Errorf("note template not found")
+		}
+		sections, err := template.CheckContent(*req.Sections)
+		if err != nil {
+			return nil, err
+		}
+		record.Sections = sections
+		record.Notes = template.Render(sections)
+		updated = append(updated, "sections", "notes")
 	}
 	if req.Medications != nil {
-		updates["medications"] = *req.Medications
+		record.Medications = *req.Medications
+		updated = append(updated, "medications")
 	}
 	if req.Severity != nil {
-		updates["severity"] = *req.Severity
+		record.Severity = *req.Severity
+		updated = append(updated, "severity")
+	}
+	if req.Sensitivity != nil {
+		if !models.IsValidSensitivity(*req.Sensitivity) {
+			return nil, fmt.Errorf("invalid sensitivity classification")
+		}
+		record.Sensitivity = *req.Sensitivity
+		updated = append(updated, "sensitivity")
+	}
+	recode := req.Diagnoses != nil
+	if recode {
+		diagnoses, err := codeDiagnoses(s.terms, record.PatientID, *req.Diagnoses)
+		if err != nil {
+			return nil, err
+		}
+		record.Diagnoses = diagnoses
 	}
 
-	if len(updates) > 0 {
-		updates["updated_at"] = time.Now()
-		if err := s.db.Model(&record).Updates(updates).Error; err != nil {
+	if len(updated) > 0 || recode {
+		record.UpdatedAt = time.Now()
+		updated = append(updated, "updated_at")
+
+		revisionReason := req.Reason
+		if revisionReason == "" {
+			revisionReason = reason
+		}
+
+		// Every change is kept as an immutable revision
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := ensureBaselineRevision(tx, models.ResourceMedicalRecord, record.ID, &original, original.UpdatedAt, original.DoctorID); err != nil {
+				return err
+			}
+			// Guard against the record being signed since it was read
+			result := tx.Model(&record).Where("status = ?", models.RecordStatusDraft).Select(updated).Omit("Diagnoses").Updates(&record)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return models.ErrRecordLocked
+			}
+			// Coded diagnoses are replaced as a set
+			if recode {
+				if err := tx.Where("record_id = ?", record.ID).Delete(&models.RecordDiagnosis{}).Error; err != nil {
+					return err
+				}
+				for i := range record.Diagnoses {
+					record.Diagnoses[i].RecordID = record.ID
+				}
+				if len(record.Diagnoses) > 0 {
+					if err := tx.Create(&record.Diagnoses).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return recordRevision(tx, models.ResourceMedicalRecord, record.ID, &record, updatedByUserID, revisionReason)
+		})
+		if err == models.ErrRecordLocked {
+			return nil, err
+		}
+		if err != nil {
 			return nil, fmt.Errorf("failed to update medical record: %w", err)
 		}
 	}
 
-	// Log update
-	s.audit.LogMedicalRecordAccess(updatedByUserID, record.PatientID, recordID, models.ActionUpdate, ipAddress, userAgent, false, "medical_record_updated")
-
 	// Reload record
-	s.db.Where("id = ?", recordID).Preload("Patient").Preload("Doctor").First(&record)
+	s.db.Where("id = ?", recordID).Preload("Patient").Preload("Doctor").Preload("Diagnoses", models.PreloadDiagnoses).First(&record)
+
+	// Log update against the record's current classification
+	s.logRecordAccess(&record, updatedByUserID, models.ActionUpdate, ipAddress, userAgent, false, reason, purpose)
+
+	return &record, nil
+}
+
+// GetMedicalRecordRevisions lists a record's revisions, newest first
+func (s *MedicalRecordService) GetMedicalRecordRevisions(recordID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, page, limit int) ([]RevisionSummary, int64, error) {
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	record, err := s.authorizeRecordAccess(recordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	revisions, total, err := listRevisions(s.db, policy, requestedByRole, models.ResourceMedicalRecord, recordID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.logRecordAccess(record, requestedByUserID, models.ActionView, ipAddress, userAgent, emergencyAccess,
+		historyReason(s.disclosureReason(record.PatientID, requestedByUserID, emergencyAccess), "revisions_listed"), purpose)
+
+	return revisions, total, nil
+}
+
+// GetMedicalRecordAsOf returns the record as it stood at the given time
+func (s *MedicalRecordService) GetMedicalRecordAsOf(recordID uint, at time.Time, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) (models.Projection, *RevisionSummary, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	record, err := s.authorizeRecordAccess(recordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revision, err := revisionAsOf(s.db, models.ResourceMedicalRecord, recordID, at)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projection, disclosed, err := s.projectRevision(record, revision, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, policy, fields, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reason := historyReason(s.disclosureReason(record.PatientID, requestedByUserID, emergencyAccess), fmt.Sprintf("revision_%d", revision.Version))
+	audit.LogMedicalRecordDisclosure(record, requestedByUserID, ipAddress, userAgent, emergencyAccess, reason, disclosed)
+
+	summary := summarizeRevision(revision, policy, requestedByRole)
+	return projection, &summary, nil
+}
+
+// DiffMedicalRecordRevisions compares two revisions of a record field by field
+func (s *MedicalRecordService) DiffMedicalRecordRevisions(recordID uint, fromVersion, toVersion int, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*RevisionDiff, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	record, err := s.authorizeRecordAccess(recordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := revisionVersion(s.db, models.ResourceMedicalRecord, recordID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := revisionVersion(s.db, models.ResourceMedicalRecord, recordID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	fromProjection, _, err := s.projectRevision(record, from, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, policy, nil, purpose)
+	if err != nil {
+		return nil, err
+	}
+	toProjection, _, err := s.projectRevision(record, to, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, policy, nil, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := models.DiffProjections(models.ResourceMedicalRecord, fromProjection, toProjection)
+
+	reason := historyReason(s.disclosureReason(record.PatientID, requestedByUserID, emergencyAccess), fmt.Sprintf("revision_diff_%d_%d", fromVersion, toVersion))
+	audit.LogMedicalRecordDisclosure(record, requestedByUserID, ipAddress, userAgent, emergencyAccess, reason, changedFieldNames(changes))
+
+	return &RevisionDiff{
+		From:    summarizeRevision(from, policy, requestedByRole),
+		To:      summarizeRevision(to, policy, requestedByRole),
+		Changes: changes,
+	}, nil
+}
+
+// projectRevision renders a historical version of the record for the caller,
+// re-checking access against the classification it had at the time
+func (s *MedicalRecordService) projectRevision(record *models.MedicalRecord, revision *models.Revision, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, policy models.FieldPolicy, fields []string, purpose models.PurposeOfUse) (models.Projection, []string, error) {
+	historical := *record
+	if err := revision.ApplyTo(&historical); err != nil {
+		return nil, nil, err
+	}
+	historical.ID = record.ID
+	historical.PatientID = record.PatientID
+	historical.DoctorID = record.DoctorID
+
+	if err := s.checkRecordDisclosure(&historical, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, purpose); err != nil {
+		return nil, nil, err
+	}
+
+	sanitized := historical.SanitizeForRole(requestedByRole)
+	if sanitized == nil {
+		return nil, nil, fmt.Errorf("access denied to medical record")
+	}
+
+	projection, disclosed := policy.Project(models.ResourceMedicalRecord, requestedByRole, sanitized, fields)
+	return projection, disclosed, nil
+}
+
+// disclosureReason records why access outside the usual rules was allowed
+func (s *MedicalRecordService) disclosureReason(patientID, requestedByUserID uint, emergencyAccess bool) string {
+	if emergencyAccess {
+		return "emergency_access"
+	}
+	return s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
+}
+
+// authorizeRecordAccess loads a record and runs every check that guards
+// reading it: role, severity and classification, patient consent and the
+// confidential-chart rules.
+func (s *MedicalRecordService) authorizeRecordAccess(recordID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*models.MedicalRecord, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if !s.canAccessMedicalRecords(requestedByRole) {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to access medical records")
+	}
+
+	var record models.MedicalRecord
+	if err := s.db.Where("id = ?", recordID).Preload("Patient").Preload("Doctor").Preload("Diagnoses", models.PreloadDiagnoses).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("medical record not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve medical record: %w", err)
+	}
+
+	// A signed record that no longer matches its hash is never served
+	if err := record.CheckIntegrity(); err != nil {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d", recordID), ipAddress, userAgent, "integrity_check_failed")
+		return nil, err
+	}
+
+	if err := s.checkRecordDisclosure(&record, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, purpose); err != nil {
+		return nil, err
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	if err := s.careTeam.AuthorizeConfidentialAccess(&record.Patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose); err != nil {
+		return nil, err
+	}
 
 	return &record, nil
 }
 
+// checkRecordDisclosure applies the per-record role and consent rules. It is
+// run against historical versions too, whose classification may differ.
+func (s *MedicalRecordService) checkRecordDisclosure(record *models.MedicalRecord, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, purpose models.PurposeOfUse) error {
+	audit := s.audit.WithPurpose(purpose)
+
+	// Check role-based access
+	if !record.CanBeAccessedByRole(requestedByRole, requestedByUserID) && !emergencyAccess {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d", record.ID), ipAddress, userAgent, "role_access_denied")
+		return fmt.Errorf("access denied to medical record")
+	}
+
+	// Substance-use records are never disclosed without patient consent, even
+	// under emergency access
+	if record.RequiresConsent() && !s.consents.HasActiveConsent(record.PatientID, requestedByUserID, record.Sensitivity) {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d", record.ID), ipAddress, userAgent, "consent_required")
+		return fmt.Errorf("patient consent required to access this medical record")
+	}
+
+	return nil
+}
+
 // Helper methods
 func (s *MedicalRecordService) canAccessMedicalRecords(role models.UserRole) bool {
-	return role == models.RoleDoctor || role == models.RoleNurse
+	return role == models.RoleDoctor || role == models.RoleNurse || role == models.RoleBilling
+}
+
+// logRecordAccess sends access to classified records to the sensitive audit trail
+func (s *MedicalRecordService) logRecordAccess(record *models.MedicalRecord, userID uint, action models.AuditAction, ipAddress, userAgent string, emergencyUse bool, reason string, purpose models.PurposeOfUse) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if record.IsSensitive() {
+		audit.LogSensitiveRecordAccess(userID, record.PatientID, record.ID, record.Sensitivity, action, ipAddress, userAgent, emergencyUse, reason)
+		return
+	}
+	audit.LogMedicalRecordAccess(userID, record.PatientID, record.ID, action, ipAddress, userAgent, emergencyUse, reason)
 }
\ No newline at end of file
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurposeOfUseLimits(t *testing.T) {
+	consents, records := newConsentService(t)
+	db := consents.db
+	attachments := NewAttachmentService(db, records.audit, records.careTeam, consents, records, models.DefaultFieldPolicy(), nil, 0)
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, doctor)
+	record := createRecord(t, db, patient, doctor, models.SensitivityNormal, models.SeverityLow)
+
+	get := func(purpose models.PurposeOfUse) models.Projection {
+		projection, err := records.GetMedicalRecord(record.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", purpose, nil)
+		require.NoError(t, err)
+		return projection
+	}
+
+	t.Run("TreatmentSeesFullRecord", func(t *testing.T) {
+		projection := get(models.PurposeTreatment)
+		assert.Contains(t, projection, "notes")
+		assert.Contains(t, projection, "treatment")
+	})
+
+	t.Run("PaymentSeesBillingFields", func(t *testing.T) {
+		projection := get(models.PurposePayment)
+		assert.Contains(t, projection, "diagnosis")
+		assert.NotContains(t, projection, "treatment")
+		assert.NotContains(t, projection, "notes")
+	})
+
+	t.Run("ResearchSeesNoAuthor", func(t *testing.T) {
+		projection := get(models.PurposeResearch)
+		assert.Contains(t, projection, "treatment")
+		assert.NotContains(t, projection, "doctor_id")
+		assert.NotContains(t, projection, "notes")
+	})
+
+	t.Run("PurposeIsAudited", func(t *testing.T) {
+		get(models.PurposeOperations)
+		entry := lastAuditEntry(t, db, doctor.ID)
+		assert.Equal(t, models.PurposeOperations, entry.Purpose)
+		assert.NotContains(t, entry.DisclosedFields, "notes")
+	})
+
+	t.Run("AttachmentsWithheldForPaymentAndResearch", func(t *testing.T) {
+		for _, purpose := range []models.PurposeOfUse{models.PurposePayment, models.PurposeResearch} {
+			_, err := attachments.GetAttachments(patient.ID, &AttachmentQuery{}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", purpose, nil)
+			assert.Error(t, err, purpose)
+		}
+
+		_, err := attachments.GetAttachments(patient.ID, &AttachmentQuery{}, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", models.PurposeTreatment, nil)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCriticalRecordsForNurses(t *testing.T) {
+	_, records := newConsentService(t)
+	db := records.db
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	nurse := createUser(t, db, models.RoleNurse)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, doctor)
+	addToCareTeam(t, db, patient, nurse)
+
+	createRecord(t, db, patient, doctor, models.SensitivityNormal, models.SeverityLow)
+	critical := &models.MedicalRecord{
+		PatientID: patient.ID,
+		DoctorID:  doctor.ID,
+		Diagnosis: "Acute myocardial infarction",
+		Treatment: "Emergency PCI",
+		Severity:  models.SeverityCritical,
+		Sections:  models.NoteContent{"assessment": "STEMI", "plan": "Cath lab"},
+	}
+	require.NoError(t, db.Create(critical).Error)
+	require.NoError(t, critical.Sign(doctor, nil))
+	require.NoError(t, db.Save(critical).Error)
+
+	t.Run("HiddenWithoutEmergencyAccess", func(t *testing.T) {
+		_, err := records.GetMedicalRecord(critical.ID, nurse.ID, models.RoleNurse, testIP, testUserAgent, false, "", "", nil)
+		assert.Error(t, err)
+
+		projections, total, err := records.GetPatientMedicalRecords(patient.ID, nurse.ID, models.RoleNurse, testIP, testUserAgent, false, "", "", nil, nil, 1, 20)
+		require.NoError(t, err)
+		assert.Len(t, projections, 1)
+		assert.Equal(t, int64(1), total)
+	})
+
+	t.Run("RedactedUnderEmergencyAccess", func(t *testing.T) {
+		projection, err := records.GetMedicalRecord(critical.ID, nurse.ID, models.RoleNurse, testIP, testUserAgent, true, "", "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "[RESTRICTED - Doctor Only]", projection["diagnosis"])
+		assert.Empty(t, projection["sections"])
+		assert.True(t, lastAuditEntry(t, db, nurse.ID).EmergencyUse)
+	})
+
+	t.Run("DoctorSeesSections", func(t *testing.T) {
+		projection, err := records.GetMedicalRecord(critical.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", "", nil)
+		require.NoError(t, err)
+		assert.Len(t, projection["sections"], 2)
+	})
+}
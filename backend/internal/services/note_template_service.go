@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// NoteTemplateService manages the note templates admins publish for
+// structured clinical notes. A template key names a series of immutable
+// versions; new notes use the newest version of a template that is not
+// retired.
+type NoteTemplateService struct {
+	db    *gorm.DB
+	audit *AuditService
+}
+
+// NoteTemplateRequest defines a template or a new version of one. The key is
+// taken from the path when publishing a new version.
+type NoteTemplateRequest struct {
+	Key         string               `json:"key"`
+	Name        string               `json:"name" binding:"required"`
+	Description string               `json:"description"`
+	Sections    []models.NoteSection `json:"sections" binding:"required"`
+}
+
+type NoteTemplateQuery struct {
+	Key            string `form:"key"`
+	IncludeRetired bool   `form:"include_retired"`
+}
+
+func NewNoteTemplateService(db *gorm.DB, audit *AuditService) *NoteTemplateService {
+	return &NoteTemplateService{
+		db:    db,
+		audit: audit,
+	}
+}
+
+// CreateTemplate publishes the first version of a new template
+func (s *NoteTemplateService) CreateTemplate(req *NoteTemplateRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string) (*models.NoteTemplate, error) {
+	if createdByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(createdByUserID, "note_template:create", ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to manage note templates")
+	}
+
+	var count int64
+	s.db.Model(&models.NoteTemplate{}).Where("template_key = ?", req.Key).Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("template %s already exists; publish a new version instead", req.Key)
+	}
+
+	return s.publish(req.Key, 1, req, createdByUserID, ipAddress, userAgent)
+}
+
+// PublishVersion adds a new version of an existing template. Notes written
+// against earlier versions keep them.
+func (s *NoteTemplateService) PublishVersion(key string, req *NoteTemplateRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string) (*models.NoteTemplate, error) {
+	if createdByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(createdByUserID, fmt.Sprintf("note_template:%s", key), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to manage note templates")
+	}
+
+	var latest models.NoteTemplate
+	if err := s.db.Where("template_key = ?", key).Order("version DESC").First(&latest).Error; err != nil {
+		return nil, fmt.Errorf("note template not found")
+	}
+	if latest.IsRetired() {
+		return nil, fmt.Errorf("template %s is retired", key)
+	}
+
+	return s.publish(key, latest.Version+1, req, createdByUserID, ipAddress, userAgent)
+}
+
+// RetireTemplate stops a template from being used for new notes. Existing
+// notes still refer to their version.
+func (s *NoteTemplateService) RetireTemplate(key string, retiredByUserID uint, retiredByRole models.UserRole, ipAddress, userAgent string) error {
+	if retiredByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(retiredByUserID, fmt.Sprintf("note_template:%s", key), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to manage note templates")
+	}
+
+	result := s.db.Model(&models.NoteTemplate{}).Where("template_key = ? AND retired_at IS NULL", key).Update("retired_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to retire note template: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("note template not found")
+	}
+
+	s.audit.LogUserAction(retiredByUserID, models.ActionUpdate, fmt.Sprintf("note_template:%s", key), ipAddress, userAgent, true, "note_template_retired")
+
+	return nil
+}
+
+// GetTemplates lists the newest version of each template, or every version
+// of one key
+func (s *NoteTemplateService) GetTemplates(query *NoteTemplateQuery) ([]models.NoteTemplate, error) {
+	db := s.db.Model(&models.NoteTemplate{})
+	if query.Key != "" {
+		db = db.Where("template_key = ?", query.Key)
+	} else {
+		db = db.Where("version = (?)", s.db.Model(&models.NoteTemplate{}).Select("MAX(version)").
+			Where("template_key = note_templates.template_key"))
+	}
+	if !query.IncludeRetired {
+		db = db.Where("retired_at IS NULL")
+	}
+
+	var templates []models.NoteTemplate
+	if err := db.Order("template_key, version DESC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve note templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate returns one template version by id, retired or not, so that a
+// note can be shown with the version it was written against
+func (s *NoteTemplateService) GetTemplate(templateID uint) (*models.NoteTemplate, error) {
+	var template models.NoteTemplate
+	if err := s.db.Where("id = ?", templateID).First(&template).Error; err != nil {
+		return nil, fmt.Errorf("note template not found")
+	}
+	return &template, nil
+}
+
+func (s *NoteTemplateService) publish(key string, version int, req *NoteTemplateRequest, createdByUserID uint, ipAddress, userAgent string) (*models.NoteTemplate, error) {
+	template := models.NoteTemplate{
+		Key:         key,
+		Version:     version,
+		Name:        req.Name,
+		Description: req.Description,
+		Sections:    req.Sections,
+		CreatedBy:   createdByUserID,
+	}
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The unique key and version index rejects a concurrent publish
+	if err := s.db.Create(&template).Error; err != nil {
+		return nil, fmt.Errorf("failed to save note template: %w", err)
+	}
+
+	s.audit.LogUserAction(createdByUserID, models.ActionCreate, fmt.Sprintf("note_template:%s:v%d", key, version), ipAddress, userAgent, true, "note_template_published")
+
+	return &template, nil
+}
+
+// currentTemplate returns the newest version of a template for a new note
+func currentTemplate(db *gorm.DB, key string) (*models.NoteTemplate, error) {
+	var template models.NoteTemplate
+	if err := db.Where("template_key = ?", key).Order("version DESC").First(&template).Error; err != nil {
+		return nil, fmt.Errorf("note template %s not found", key)
+	}
+	if template.IsRetired() {
+		return nil, fmt.Errorf("note template %s is retired", key)
+	}
+	return &template, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// NotificationService keeps each user's inbox of items needing attention
+type NotificationService struct {
+	db *gorm.DB
+}
+
+type NotificationQuery struct {
+	Unread bool `form:"unread"`
+	Page   int  `form:"page,default=1"`
+	Limit  int  `form:"limit,default=20"`
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// Notify adds a notification inside tx, so it is only delivered if the
+// change that raised it is saved
+func (s *NotificationService) Notify(tx *gorm.DB, notification *models.Notification) error {
+	if err := tx.Create(notification).Error; err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+// GetNotifications lists the caller's own notifications, newest first
+func (s *NotificationService) GetNotifications(query *NotificationQuery, userID uint) ([]models.Notification, int64, error) {
+	db := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if query.Unread {
+		db = db.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	var notifications []models.Notification
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Order("created_at DESC").Offset(offset).Limit(query.Limit).Find(&notifications).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve notifications: %w", err)
+	}
+
+	return notifications, total, nil
+}
+
+// MarkRead marks one of the caller's notifications as read, keeping the
+// time it was first read
+func (s *NotificationService) MarkRead(notificationID, userID uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		return nil, fmt.Errorf("notification not found")
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := s.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to mark notification read: %w", err)
+		}
+	}
+
+	return &notification, nil
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxTrendObservations bounds the observations read for one trend query
+const maxTrendObservations = 5000
+
+// ObservationService records vital signs and lab results. Doctors and nurses
+// record them; reads follow the same field policy, confidentiality and audit
+// rules as the other clinical lists. Abnormal results notify the ordering
+// doctor.
+type ObservationService struct {
+	db            *gorm.DB
+	audit         *AuditService
+	careTeam      *CareTeamService
+	fieldPolicy   models.FieldPolicy
+	notifications *NotificationService
+}
+
+type RecordObservationRequest struct {
+	EncounterID      *uint                      `json:"encounter_id,omitempty"`
+	Category         models.ObservationCategory `json:"category" binding:"required"`
+	Code             string                     `json:"code" binding:"required"`
+	Display          string                     `json:"display"`
+	ValueQuantity    *float64                   `json:"value_quantity,omitempty"`
+	ValueString      string                     `json:"value_string,omitempty"`
+	Unit             string                     `json:"unit,omitempty"`
+	ReferenceLow     *float64                   `json:"reference_low,omitempty"`
+	ReferenceHigh    *float64                   `json:"reference_high,omitempty"`
+	CriticalLow      *float64                   `json:"critical_low,omitempty"`
+	CriticalHigh     *float64                   `json:"critical_high,omitempty"`
+	Interpretation   models.Interpretation      `json:"interpretation,omitempty"`
+	EffectiveAt      *time.Time                 `json:"effective_at,omitempty"`
+	Comment          string                     `json:"comment,omitempty"`
+	OrderingDoctorID *uint                      `json:"ordering_doctor_id,omitempty"`
+}
+
+type ObservationQuery struct {
+	Code        string     `form:"code"`
+	Category    string     `form:"category"`
+	EncounterID *uint      `form:"encounter_id"`
+	From        *time.Time `form:"from"`
+	To          *time.Time `form:"to"`
+	Abnormal    bool       `form:"abnormal"`
+	Page        int        `form:"page,default=1"`
+	Limit       int        `form:"limit,default=50"`
+
+	// Filters used by the FHIR API. Before and UpdatedBefore exclude their
+	// end.
+	ID            uint       `form:"-"`
+	Before        *time.Time `form:"-"`
+	UpdatedSince  *time.Time `form:"-"`
+	UpdatedBefore *time.Time `form:"-"`
+}
+
+type TrendQuery struct {
+	Code     string     `form:"code" binding:"required"`
+	From     *time.Time `form:"from"`
+	To       *time.Time `form:"to"`
+	Interval string     `form:"interval"`
+}
+
+// TrendPoint summarizes the numeric results in one interval, or a single
+// result when no interval is requested
+type TrendPoint struct {
+	At       time.Time `json:"at"`
+	Count    int       `json:"count"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	Mean     float64   `json:"mean"`
+	Abnormal int       `json:"abnormal"`
+}
+
+type ObservationTrend struct {
+	Code     string       `json:"code"`
+	Display  string       `json:"display"`
+	Unit     string       `json:"unit"`
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Interval string       `json:"interval,omitempty"`
+	Points   []TrendPoint `json:"points"`
+}
+
+func NewObservationService(db *gorm.DB, audit *AuditService, careTeam *CareTeamService, fieldPolicy models.FieldPolicy, notifications *NotificationService) *ObservationService {
+	return &ObservationService{
+		db:            db,
+		audit:         audit,
+		careTeam:      careTeam,
+		fieldPolicy:   fieldPolicy,
+		notifications: notifications,
+	}
+}
+
+// RecordObservation stores a vital sign or lab result. Known vital signs get
+// their unit and limits filled in; numeric results are flagged against their
+// limits. An abnormal result notifies the ordering doctor, who defaults to
+// the caller when a doctor records it, or else the attending of the
+// encounter. A critical result with nobody to notify is refused.
+func (s *ObservationService) RecordObservation(patientID uint, req *RecordObservationRequest, recordedByUserID uint, recordedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.Observation, error) {
+	if recordedByRole != models.RoleDoctor && recordedByRole != models.RoleNurse {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(recordedByUserID, fmt.Sprintf("observations:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to record observations")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	if req.Category != models.CategoryVitalSigns && req.Category != models.CategoryLaboratory {
+		return nil, fmt.Errorf("invalid observation category")
+	}
+	if !models.IsValidLOINCCode(req.Code) {
+		return nil, fmt.Errorf("invalid LOINC code")
+	}
+	if req.ValueQuantity == nil && strings.TrimSpace(req.ValueString) == "" {
+		return nil, fmt.Errorf("observation needs a value")
+	}
+	if req.Interpretation != "" && !models.IsValidInterpretation(req.Interpretation) {
+		return nil, fmt.Errorf("invalid interpretation")
+	}
+	if req.EffectiveAt != nil && req.EffectiveAt.After(time.Now().Add(5*time.Minute)) {
+		return nil, fmt.Errorf("effective time cannot be in the future")
+	}
+	if err := checkEncounter(s.db, patientID, req.EncounterID); err != nil {
+		return nil, err
+	}
+
+	observation := models.Observation{
+		PatientID:        patientID,
+		EncounterID:      req.EncounterID,
+		Category:         req.Category,
+		Code:             req.Code,
+		Display:          strings.TrimSpace(req.Display),
+		ValueQuantity:    req.ValueQuantity,
+		ValueString:      req.ValueString,
+		Unit:             req.Unit,
+		ReferenceLow:     req.ReferenceLow,
+		ReferenceHigh:    req.ReferenceHigh,
+		CriticalLow:      req.CriticalLow,
+		CriticalHigh:     req.CriticalHigh,
+		Interpretation:   req.Interpretation,
+		Comment:          req.Comment,
+		RecordedBy:       recordedByUserID,
+		OrderingDoctorID: req.OrderingDoctorID,
+	}
+	if req.EffectiveAt != nil {
+		observation.EffectiveAt = *req.EffectiveAt
+	}
+	if !observation.ApplyVitalSign() {
+		return nil, fmt.Errorf("unit for %s must be %s", observation.Code, models.VitalSigns[observation.Code].Unit)
+	}
+	if observation.Display == "" {
+		return nil, fmt.Errorf("display name is required for %s", observation.Code)
+	}
+	// Flagged now rather than on save, since the flag decides who is notified
+	if interpretation, ok := observation.Interpret(); ok {
+		observation.Interpretation = interpretation
+	}
+
+	if observation.OrderingDoctorID == nil && recordedByRole == models.RoleDoctor {
+		observation.OrderingDoctorID = &recordedByUserID
+	}
+	if observation.OrderingDoctorID != nil {
+		var doctor models.User
+		if err := s.db.Where("id = ? AND role = ? AND active = ?", *observation.OrderingDoctorID, models.RoleDoctor, true).
+			First(&doctor).Error; err != nil {
+			return nil, fmt.Errorf("ordering doctor not found")
+		}
+	}
+
+	recipient := observation.OrderingDoctorID
+	if recipient == nil && observation.IsAbnormal() && observation.EncounterID != nil {
+		var encounter models.Encounter
+		if err := s.db.Select("id, attending_id").Where("id = ?", *observation.EncounterID).First(&encounter).Error; err != nil {
+			return nil, fmt.Errorf("encounter not found")
+		}
+		var attending models.User
+		if err := s.db.Where("id = ? AND role = ? AND active = ?", encounter.AttendingID, models.RoleDoctor, true).
+			First(&attending).Error; err == nil {
+			recipient = &attending.ID
+		}
+	}
+	if recipient == nil && observation.IsCritical() {
+		return nil, fmt.Errorf("a critical result needs an ordering doctor or an encounter with an active attending to notify")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&observation).Error; err != nil {
+			return fmt.Errorf("failed to record observation: %w", err)
+		}
+		if !observation.IsAbnormal() || recipient == nil {
+			return nil
+		}
+		return s.notifications.Notify(tx, abnormalResultNotification(&observation, *recipient))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(recordedByUserID, patientID, fmt.Sprintf("observation:%d", observation.ID),
+		models.ActionCreate, ipAddress, userAgent, false, fmt.Sprintf("observation_recorded:%s", observation.Code), nil)
+
+	return &observation, nil
+}
+
+// abnormalResultNotification tells the doctor responsible for a result that
+// it needs review. Critical results are raised as critical, other abnormal
+// results as high.
+func abnormalResultNotification(observation *models.Observation, recipientID uint) *models.Notification {
+	severity := models.NotificationHigh
+	label := "Abnormal"
+	if observation.IsCritical() {
+		severity = models.NotificationCritical
+		label = "Critical"
+	}
+
+	patientID := observation.PatientID
+	return &models.Notification{
+		UserID:    recipientID,
+		Type:      models.NotificationAbnormalResult,
+		Severity:  severity,
+		PatientID: &patientID,
+		Resource:  fmt.Sprintf("observation:%d", observation.ID),
+		Message:   fmt.Sprintf("%s result: %s (%s)", label, observation.Display, observation.Code),
+	}
+}
+
+// GetObservations lists a patient's observations, most recent first
+func (s *ObservationService) GetObservations(patientID uint, query *ObservationQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) ([]models.Projection, int64, error) {
+	if err := s.authorizeRead(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, 0, err
+	}
+
+	db := s.db.Model(&models.Observation{}).Where("patient_id = ?", patientID)
+	if query.Code != "" {
+		db = db.Where("code = ?", query.Code)
+	}
+	if query.Category != "" {
+		db = db.Where("category = ?", query.Category)
+	}
+	if query.EncounterID != nil {
+		db = db.Where("encounter_id = ?", *query.EncounterID)
+	}
+	if query.From != nil {
+		db = db.Where("effective_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("effective_at <= ?", *query.To)
+	}
+	if query.Abnormal {
+		db = db.Where("interpretation <> ?", models.InterpretationNormal)
+	}
+	if query.ID != 0 {
+		db = db.Where("id = ?", query.ID)
+	}
+	if query.Before != nil {
+		db = db.Where("effective_at < ?", *query.Before)
+	}
+	if query.UpdatedSince != nil {
+		db = db.Where("updated_at >= ?", *query.UpdatedSince)
+	}
+	if query.UpdatedBefore != nil {
+		db = db.Where("updated_at < ?", *query.UpdatedBefore)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count observations: %w", err)
+	}
+
+	var observations []models.Observation
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Preload("Recorder").Order("effective_at DESC").Offset(offset).Limit(query.Limit).Find(&observations).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve observations: %w", err)
+	}
+
+	policy := s.fieldPolicy.ForPurpose(purpose)
+	projections, disclosed := policy.ProjectAll(models.ResourceObservation, requestedByRole, observations, fields)
+	if projections == nil {
+		projections = []models.Projection{}
+	}
+	s.logRead(patientID, requestedByUserID, ipAddress, userAgent, emergencyAccess, purpose, disclosed)
+
+	return projections, total, nil
+}
+
+// GetObservationTrend returns the numeric results for one code over a time
+// window, defaulting to the last 30 days. An interval of hour, day or week
+// summarizes the results per interval.
+func (s *ObservationService) GetObservationTrend(patientID uint, query *TrendQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*ObservationTrend, error) {
+	if err := s.authorizeRead(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, err
+	}
+
+	policy := s.fieldPolicy.ForPurpose(purpose)
+	if !policy.Allows(models.ResourceObservation, requestedByRole, "value_quantity") {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("observations:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to access observation values")
+	}
+
+	trend := &ObservationTrend{Code: query.Code, Interval: query.Interval, To: time.Now()}
+	if query.To != nil {
+		trend.To = *query.To
+	}
+	trend.From = trend.To.AddDate(0, 0, -30)
+	if query.From != nil {
+		trend.From = *query.From
+	}
+	if !trend.From.Before(trend.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	var bucket func(time.Time) time.Time
+	switch query.Interval {
+	case "":
+	case "hour":
+		bucket = func(t time.Time) time.Time { return t.Truncate(time.Hour) }
+	case "day":
+		bucket = func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()) }
+	case "week":
+		bucket = func(t time.Time) time.Time {
+			day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+			return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		}
+	default:
+		return nil, fmt.Errorf("interval must be hour, day or week")
+	}
+
+	var observations []models.Observation
+	if err := s.db.Where("patient_id = ? AND code = ? AND effective_at BETWEEN ? AND ? AND value_quantity IS NOT NULL",
+		patientID, query.Code, trend.From, trend.To).
+		Order("effective_at ASC").Limit(maxTrendObservations).Find(&observations).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve observations: %w", err)
+	}
+
+	trend.Points = buildTrend(observations, bucket)
+	if len(observations) > 0 {
+		latest := observations[len(observations)-1]
+		trend.Display = latest.Display
+		trend.Unit = latest.Unit
+	}
+
+	s.logRead(patientID, requestedByUserID, ipAddress, userAgent, emergencyAccess, purpose,
+		[]string{"code", "effective_at", "value_quantity", "unit", "interpretation"})
+
+	return trend, nil
+}
+
+// buildTrend turns observations ordered by time into trend points, one per
+// observation or one per bucket when bucket is set
+func buildTrend(observations []models.Observation, bucket func(time.Time) time.Time) []TrendPoint {
+	points := make([]TrendPoint, 0)
+	for _, observation := range observations {
+		value := *observation.ValueQuantity
+		at := observation.EffectiveAt
+		if bucket != nil {
+			at = bucket(at)
+		}
+
+		if bucket == nil || len(points) == 0 || !points[len(points)-1].At.Equal(at) {
+			points = append(points, TrendPoint{At: at, Min: value, Max: value})
+		}
+		point := &points[len(points)-1]
+		point.Mean = (point.Mean*float64(point.Count) + value) / float64(point.Count+1)
+		point.Count++
+		point.Min = math.Min(point.Min, value)
+		point.Max = math.Max(point.Max, value)
+		if observation.IsAbnormal() {
+			point.Abnormal++
+		}
+	}
+	return points
+}
+
+// DeleteObservation removes an observation entered in error. Doctors and the
+// nurse who recorded it may delete it.
+func (s *ObservationService) DeleteObservation(patientID, observationID, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) error {
+	var observation models.Observation
+	if err := s.db.Where("id = ? AND patient_id = ?", observationID, patientID).First(&observation).Error; err != nil {
+		return fmt.Errorf("observation not found")
+	}
+
+	if deletedByRole != models.RoleDoctor && !(deletedByRole == models.RoleNurse && observation.RecordedBy == deletedByUserID) {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(deletedByUserID, fmt.Sprintf("observation:%d", observationID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to delete observation")
+	}
+
+	if err := s.db.Delete(&observation).Error; err != nil {
+		return fmt.Errorf("failed to delete observation: %w", err)
+	}
+
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(deletedByUserID, patientID, fmt.Sprintf("observation:%d", observationID),
+		models.ActionDelete, ipAddress, userAgent, false, "entered_in_error", nil)
+
+	return nil
+}
+
+func (s *ObservationService) authorizeRead(patientID, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) error {
+	policy := s.fieldPolicy.ForPurpose(purpose)
+	if len(policy[models.ResourceObservation][requestedByRole]) == 0 {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("observations:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to access observations")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return fmt.Errorf("patient not found")
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	return s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose)
+}
+
+func (s *ObservationService) logRead(patientID, requestedByUserID uint, ipAddress, userAgent string, emergencyAccess bool, purpose models.PurposeOfUse, disclosed []string) {
+	reason := ""
+	if emergencyAccess {
+		reason = "emergency_access"
+	} else {
+		reason = s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
+	}
+	s.audit.WithPurpose(purpose).LogPatientDataAccess(requestedByUserID, patientID, fmt.Sprintf("observations:patient_%d", patientID),
+		models.ActionView, ipAddress, userAgent, emergencyAccess, reason, disclosed)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbnormalResultNotifications(t *testing.T) {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	observations := NewObservationService(db, audit, NewCareTeamService(db, audit), models.DefaultFieldPolicy(), NewNotificationService(db))
+
+	nurse := createUser(t, db, models.RoleNurse)
+	attending := createUser(t, db, models.RoleDoctor)
+	orderer := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	encounter := createEncounter(t, db, patient, attending)
+
+	heartRate := func(value float64, encounterID, orderingDoctorID *uint) *RecordObservationRequest {
+		return &RecordObservationRequest{EncounterID: encounterID, Category: models.CategoryVitalSigns, Code: "8867-4", ValueQuantity: &value, OrderingDoctorID: orderingDoctorID}
+	}
+	notified := func(userID uint) []models.Notification {
+		var notifications []models.Notification
+		require.NoError(t, db.Where("user_id = ? AND patient_id = ?", userID, patient.ID).Order("id").Find(&notifications).Error)
+		return notifications
+	}
+
+	t.Run("OrderingDoctorNotified", func(t *testing.T) {
+		_, err := observations.RecordObservation(patient.ID, heartRate(115, &encounter.ID, &orderer.ID), nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		require.NoError(t, err)
+
+		require.Len(t, notified(orderer.ID), 1)
+		assert.Equal(t, models.NotificationHigh, notified(orderer.ID)[0].Severity)
+		assert.Empty(t, notified(attending.ID))
+	})
+
+	t.Run("AttendingNotifiedWithoutOrderingDoctor", func(t *testing.T) {
+		_, err := observations.RecordObservation(patient.ID, heartRate(140, &encounter.ID, nil), nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		require.NoError(t, err)
+
+		notifications := notified(attending.ID)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, models.NotificationCritical, notifications[0].Severity)
+	})
+
+	t.Run("NormalResultNotifiesNobody", func(t *testing.T) {
+		_, err := observations.RecordObservation(patient.ID, heartRate(72, &encounter.ID, nil), nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		require.NoError(t, err)
+		assert.Len(t, notified(attending.ID), 1)
+	})
+
+	t.Run("UnroutedCriticalResultRefused", func(t *testing.T) {
+		_, err := observations.RecordObservation(patient.ID, heartRate(35, nil, nil), nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		assert.Error(t, err)
+
+		var count int64
+		db.Model(&models.Observation{}).Where("patient_id = ? AND value_quantity = ?", patient.ID, 35).Count(&count)
+		assert.Zero(t, count)
+
+		// An abnormal but not critical result may still be filed unrouted
+		_, err = observations.RecordObservation(patient.ID, heartRate(105, nil, nil), nurse.ID, models.RoleNurse, testIP, testUserAgent, "")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"healthsecure/internal/identifiers"
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PatientIdentifierService struct {
+	db    *gorm.DB
+	audit *AuditService
+	mrns  *identifiers.MRNGenerator
+}
+
+type AddIdentifierRequest struct {
+	Type   models.IdentifierType `json:"type" binding:"required"`
+	System string                `json:"system" binding:"required"`
+	Value  string                `json:"value" binding:"required"`
+}
+
+type AssignMRNRequest struct {
+	Facility string `json:"facility" binding:"required"`
+}
+
+func NewPatientIdentifierService(db *gorm.DB, audit *AuditService, mrns *identifiers.MRNGenerator) *PatientIdentifierService {
+	return &PatientIdentifierService{
+		db:    db,
+		audit: audit,
+		mrns:  mrns,
+	}
+}
+
+// AddIdentifier records an identifier issued elsewhere, such as another
+// hospital's MRN or an insurance member ID. MRNs in our own systems are only
+// issued by AssignMRN.
+func (s *PatientIdentifierService) AddIdentifier(patientID uint, req *AddIdentifierRequest, addedByUserID uint, addedByRole models.UserRole, ipAddress, userAgent string) (*models.PatientIdentifier, error) {
+	if !s.canManageIdentifiers(addedByRole) {
+		s.audit.LogUnauthorizedAccess(addedByUserID, fmt.Sprintf("identifiers:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to manage patient identifiers")
+	}
+
+	if !req.Type.IsValid() {
+		return nil, fmt.Errorf("invalid identifier type")
+	}
+	system := strings.TrimSpace(req.System)
+	value := strings.TrimSpace(req.Value)
+	if req.Type == models.IdentifierMRN || strings.HasPrefix(system, models.MRNSystemPrefix) {
+		return nil, fmt.Errorf("MRNs are issued by the system and cannot be added by hand")
+	}
+	if system == "" || value == "" {
+		return nil, fmt.Errorf("identifier system and value are required")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	if err := s.checkUnused(system, value); err != nil {
+		return nil, err
+	}
+
+	identifier := models.PatientIdentifier{
+		PatientID: patientID,
+		Type:      req.Type,
+		System:    system,
+		Value:     value,
+		CreatedBy: addedByUserID,
+	}
+	if err := s.db.Create(&identifier).Error; err != nil {
+		return nil, fmt.Errorf("failed to add identifier: %w", err)
+	}
+
+	s.audit.LogPatientAccess(addedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, fmt.Sprintf("identifier_added:%s", identifier.Type))
+
+	return &identifier, nil
+}
+
+// AssignMRN issues the patient an MRN at another facility. A patient holds at
+// most one MRN per facility, except where charts were merged.
+func (s *PatientIdentifierService) AssignMRN(patientID uint, req *AssignMRNRequest, assignedByUserID uint, assignedByRole models.UserRole, ipAddress, userAgent string) (*models.PatientIdentifier, error) {
+	if !s.canManageIdentifiers(assignedByRole) {
+		s.audit.LogUnauthorizedAccess(assignedByUserID, fmt.Sprintf("identifiers:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to manage patient identifiers")
+	}
+
+	facility := strings.ToUpper(strings.TrimSpace(req.Facility))
+	if !identifiers.ValidFacility(facility) {
+		return nil, fmt.Errorf("facility must be 1-16 letters or digits")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	var identifier *models.PatientIdentifier
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.PatientIdentifier{}).
+			Where("patient_id = ? AND type = ? AND identifier_system = ?", patientID, models.IdentifierMRN, models.MRNSystem(facility)).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("patient already has an MRN at facility %s", facility)
+		}
+
+		var err error
+		identifier, err = assignMRN(tx, s.mrns, patientID, facility, assignedByUserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogPatientAccess(assignedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, fmt.Sprintf("mrn_assigned:%s", facility))
+
+	return identifier, nil
+}
+
+// DeleteIdentifier removes an identifier entered in error. MRNs stay with the
+// patient for good so that they are never reissued or left pointing nowhere.
+func (s *PatientIdentifierService) DeleteIdentifier(patientID, identifierID uint, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string) error {
+	if !s.canManageIdentifiers(deletedByRole) {
+		s.audit.LogUnauthorizedAccess(deletedByUserID, fmt.Sprintf("identifiers:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to manage patient identifiers")
+	}
+
+	var identifier models.PatientIdentifier
+	if err := s.db.Where("id = ? AND patient_id = ?", identifierID, patientID).First(&identifier).Error; err != nil {
+		return fmt.Errorf("identifier not found")
+	}
+	if identifier.Type == models.IdentifierMRN {
+		return fmt.Errorf("MRNs cannot be deleted")
+	}
+
+	if err := s.db.Delete(&identifier).Error; err != nil {
+		return fmt.Errorf("failed to delete identifier: %w", err)
+	}
+
+	s.audit.LogPatientAccess(deletedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, fmt.Sprintf("identifier_removed:%s", identifier.Type))
+
+	return nil
+}
+
+// checkUnused refuses an identifier already held by any patient, since the
+// identifier would then find the wrong chart
+func (s *PatientIdentifierService) checkUnused(system, value string) error {
+	index, err := models.PatientIdentifierIndex(system, value)
+	if err != nil {
+		return fmt.Errorf("failed to index identifier: %w", err)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.PatientIdentifier{}).Where("identifier_system = ? AND value_index = ?", system, index).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check identifier: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("identifier is already assigned to a patient")
+	}
+	return nil
+}
+
+func (s *PatientIdentifierService) canManageIdentifiers(role models.UserRole) bool {
+	return role == models.RoleDoctor || role == models.RoleNurse || role == models.RoleFrontDesk
+}
+
+// assignMRN issues the next MRN at a facility. The facility's sequence row is
+// locked for the rest of the transaction so that concurrent registrations
+// never share a number.
+func assignMRN(tx *gorm.DB, mrns *identifiers.MRNGenerator, patientID uint, facility string, createdByUserID uint) (*models.PatientIdentifier, error) {
+	sequence := models.MRNSequence{Facility: facility, NextValue: 1}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error; err != nil {
+		return nil, fmt.Errorf("failed to start MRN sequence: %w", err)
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("facility = ?", facility).First(&sequence).Error; err != nil {
+		return nil, fmt.Errorf("failed to read MRN sequence: %w", err)
+	}
+
+	mrn, err := mrns.Format(facility, sequence.NextValue)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&sequence).Update("next_value", sequence.NextValue+1).Error; err != nil {
+		return nil, fmt.Errorf("failed to advance MRN sequence: %w", err)
+	}
+
+	identifier := models.PatientIdentifier{
+		PatientID: patientID,
+		Type:      models.IdentifierMRN,
+		System:    models.MRNSystem(facility),
+		Value:     mrn,
+		CreatedBy: createdByUserID,
+	}
+	if err := tx.Create(&identifier).Error; err != nil {
+		return nil, fmt.Errorf("failed to record MRN: %w", err)
+	}
+	return &identifier, nil
+}
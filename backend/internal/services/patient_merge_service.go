@@ -0,0 +1,435 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxDuplicateCandidates bounds the charts scored for one duplicate check
+	maxDuplicateCandidates = 500
+	mergeBatchSize         = 1000
+)
+
+// mergedTables lists every table holding rows of a patient's chart. A merge
+// moves their rows to the surviving chart; undo moves the same rows back.
+// Audit logs are not moved: they keep the chart that was accessed, and reads
+// of the survivor's trail include its merged charts through mergedChartIDs.
+var mergedTables = []struct {
+	table string
+	model interface{}
+}{
+	{"patient_identifiers", &models.PatientIdentifier{}},
+	{"medical_records", &models.MedicalRecord{}},
+	{"record_diagnoses", &models.RecordDiagnosis{}},
+	{"attachments", &models.Attachment{}},
+	{"amendment_requests", &models.AmendmentRequest{}},
+	{"medication_orders", &models.MedicationOrder{}},
+	{"allergies", &models.Allergy{}},
+	{"problems", &models.Problem{}},
+	{"observations", &models.Observation{}},
+	{"encounters", &models.Encounter{}},
+	{"notifications", &models.Notification{}},
+	{"patient_consents", &models.PatientConsent{}},
+	{"care_team_members", &models.CareTeamMember{}},
+	{"patient_proxies", &models.PatientProxy{}},
+	{"access_delegation_patients", &models.AccessDelegationPatient{}},
+	{"emergency_access", &models.EmergencyAccess{}},
+	// Portal accounts follow the chart so the patient keeps seeing all of it
+	{"users", &models.User{}},
+}
+
+type PatientMergeService struct {
+	db     *gorm.DB
+	audit  *AuditService
+	config *configs.Config
+}
+
+type MergePatientsRequest struct {
+	SurvivorID uint   `json:"survivor_id" binding:"required"`
+	MergedID   uint   `json:"merged_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required,min=10"`
+}
+
+type UndoMergeRequest struct {
+	Reason string `json:"reason" binding:"required,min=10"`
+}
+
+type PatientMergeQuery struct {
+	PatientID uint   `form:"patient_id"`
+	Status    string `form:"status"`
+	Page      int    `form:"page,default=1"`
+	Limit     int    `form:"limit,default=20"`
+}
+
+func NewPatientMergeService(db *gorm.DB, audit *AuditService, config *configs.Config) *PatientMergeService {
+	return &PatientMergeService{
+		db:     db,
+		audit:  audit,
+		config: config,
+	}
+}
+
+// FindDuplicates lists the charts likely to belong to the same person as a
+// patient, best match first
+func (s *PatientMergeService) FindDuplicates(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.PatientMatch, error) {
+	if requestedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to review duplicate patients")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	matches, err := findDuplicatePatients(s.db, &patient, s.config.MPI.MatchThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogPatientAccess(requestedByUserID, patientID, models.ActionView, ipAddress, userAgent, false, "duplicate_check")
+
+	return matches, nil
+}
+
+// MergePatients moves everything on the duplicate chart to the surviving
+// chart and soft deletes the duplicate (admin only). Demographics are not
+// copied; correct the survivor separately if the duplicate held better data.
+func (s *PatientMergeService) MergePatients(req *MergePatientsRequest, mergedByUserID uint, mergedByRole models.UserRole, ipAddress, userAgent string) (*models.PatientMerge, error) {
+	if mergedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(mergedByUserID, fmt.Sprintf("patient:%d", req.MergedID), ipAddress, userAgent, "insufficient_role_for_merge")
+		return nil, fmt.Errorf("insufficient permissions to merge patients")
+	}
+	if req.SurvivorID == req.MergedID {
+		return nil, fmt.Errorf("a patient cannot be merged into itself")
+	}
+
+	var merge models.PatientMerge
+	var reanchored []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var survivor, duplicate models.Patient
+		if err := tx.Where("id = ?", req.SurvivorID).First(&survivor).Error; err != nil {
+			return fmt.Errorf("surviving patient not found")
+		}
+		if err := tx.Where("id = ?", req.MergedID).First(&duplicate).Error; err != nil {
+			return fmt.Errorf("duplicate patient not found")
+		}
+
+		// A merge must not weaken the protection of the duplicate's data
+		if duplicate.Confidential && !survivor.Confidential {
+			return fmt.Errorf("the duplicate chart is confidential; mark the surviving chart confidential first")
+		}
+		if duplicate.EmployeeUserID != nil && (survivor.EmployeeUserID == nil || *survivor.EmployeeUserID != *duplicate.EmployeeUserID) {
+			return fmt.Errorf("the duplicate chart belongs to a staff member; link the surviving chart to the same user first")
+		}
+
+		merge = models.PatientMerge{
+			SurvivorID: survivor.ID,
+			MergedID:   duplicate.ID,
+			Score:      models.MatchPatients(&survivor, &duplicate).Score,
+			Reason:     req.Reason,
+			MergedBy:   mergedByUserID,
+		}
+		if err := tx.Create(&merge).Error; err != nil {
+			return err
+		}
+
+		for _, t := range mergedTables {
+			var ids []uint
+			query := tx.Unscoped().Model(t.model).Where("patient_id = ?", duplicate.ID)
+			// A member already on the survivor's care team keeps that entry;
+			// the duplicate's entry stays with the duplicate
+			if t.table == "care_team_members" {
+				query = query.Where("user_id NOT IN (?)", tx.Model(&models.CareTeamMember{}).Select("user_id").Where("patient_id = ?", survivor.ID))
+			}
+			if err := query.Pluck("id", &ids).Error; err != nil {
+				return fmt.Errorf("failed to read %s: %w", t.table, err)
+			}
+
+			for start := 0; start < len(ids); start += mergeBatchSize {
+				end := start + mergeBatchSize
+				if end > len(ids) {
+					end = len(ids)
+				}
+				batch := ids[start:end]
+
+				rows := make([]models.PatientMergeRow, len(batch))
+				for i, id := range batch {
+					rows[i] = models.PatientMergeRow{MergeID: merge.ID, Table: t.table, RowID: id}
+				}
+				if err := tx.Create(&rows).Error; err != nil {
+					return err
+				}
+				if t.table == "medical_records" {
+					moved, err := moveSignedRecords(tx, batch, duplicate.ID, survivor.ID)
+					if err != nil {
+						return err
+					}
+					reanchored = append(reanchored, moved...)
+				}
+				if err := tx.Unscoped().Model(t.model).Where("id IN ?", batch).UpdateColumn("patient_id", survivor.ID).Error; err != nil {
+					return fmt.Errorf("failed to move %s: %w", t.table, err)
+				}
+			}
+			merge.RowsMoved += len(ids)
+		}
+
+		if err := tx.Model(&merge).UpdateColumn("rows_moved", merge.RowsMoved).Error; err != nil {
+			return err
+		}
+		return tx.Model(&duplicate).UpdateColumns(map[string]interface{}{
+			"merged_into_id": survivor.ID,
+			"deleted_at":     time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge patients: %w", err)
+	}
+
+	// Logged after the move so that these entries stay with their own chart
+	s.audit.LogPatientAccess(mergedByUserID, merge.MergedID, models.ActionUpdate, ipAddress, userAgent, false,
+		fmt.Sprintf("patient_merged:merge_%d:into_patient_%d", merge.ID, merge.SurvivorID))
+	s.audit.LogPatientAccess(mergedByUserID, merge.SurvivorID, models.ActionUpdate, ipAddress, userAgent, false,
+		fmt.Sprintf("patient_merge_survivor:merge_%d:from_patient_%d:rows_%d", merge.ID, merge.MergedID, merge.RowsMoved))
+	s.logReanchored(reanchored, &merge, merge.MergedID, merge.SurvivorID, mergedByUserID, ipAddress, userAgent)
+
+	return &merge, nil
+}
+
+// UndoMerge moves the rows taken from the duplicate back to it and restores
+// the duplicate chart (admin only). Data added to the survivor since the
+// merge stays with the survivor.
+func (s *PatientMergeService) UndoMerge(mergeID uint, req *UndoMergeRequest, undoneByUserID uint, undoneByRole models.UserRole, ipAddress, userAgent string) (*models.PatientMerge, error) {
+	if undoneByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(undoneByUserID, fmt.Sprintf("patient_merge:%d", mergeID), ipAddress, userAgent, "insufficient_role_for_merge")
+		return nil, fmt.Errorf("insufficient permissions to undo patient merge")
+	}
+
+	var merge models.PatientMerge
+	var reanchored []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", mergeID).First(&merge).Error; err != nil {
+			return fmt.Errorf("patient merge not found")
+		}
+		if err := merge.Undo(undoneByUserID, req.Reason); err != nil {
+			return fmt.Errorf("patient merge has already been undone")
+		}
+
+		var survivor models.Patient
+		if err := tx.Unscoped().Where("id = ?", merge.SurvivorID).First(&survivor).Error; err != nil {
+			return fmt.Errorf("surviving patient no longer exists")
+		}
+		// Merges are undone newest first: rows may have moved on with the survivor
+		if survivor.IsMerged() {
+			return fmt.Errorf("the surviving patient was itself merged into patient %d; undo that merge first", *survivor.MergedIntoID)
+		}
+
+		var duplicate models.Patient
+		if err := tx.Unscoped().Where("id = ? AND merged_into_id = ?", merge.MergedID, merge.SurvivorID).First(&duplicate).Error; err != nil {
+			return fmt.Errorf("merged patient no longer exists")
+		}
+
+		for _, t := range mergedTables {
+			var ids []uint
+			if err := tx.Model(&models.PatientMergeRow{}).Where("merge_id = ? AND source_table = ?", merge.ID, t.table).
+				Pluck("row_id", &ids).Error; err != nil {
+				return err
+			}
+			for start := 0; start < len(ids); start += mergeBatchSize {
+				end := start + mergeBatchSize
+				if end > len(ids) {
+					end = len(ids)
+				}
+				if t.table == "medical_records" {
+					moved, err := moveSignedRecords(tx, ids[start:end], merge.SurvivorID, merge.MergedID)
+					if err != nil {
+						return err
+					}
+					reanchored = append(reanchored, moved...)
+				}
+				if err := tx.Unscoped().Model(t.model).Where("id IN ? AND patient_id = ?", ids[start:end], merge.SurvivorID).
+					UpdateColumn("patient_id", merge.MergedID).Error; err != nil {
+					return fmt.Errorf("failed to move %s back: %w", t.table, err)
+				}
+			}
+		}
+
+		if err := tx.Unscoped().Model(&duplicate).UpdateColumns(map[string]interface{}{
+			"merged_into_id": nil,
+			"deleted_at":     nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Save(&merge).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to undo patient merge: %w", err)
+	}
+
+	s.audit.LogPatientAccess(undoneByUserID, merge.MergedID, models.ActionUpdate, ipAddress, userAgent, false,
+		fmt.Sprintf("patient_merge_undone:merge_%d:from_patient_%d", merge.ID, merge.SurvivorID))
+	s.audit.LogPatientAccess(undoneByUserID, merge.SurvivorID, models.ActionUpdate, ipAddress, userAgent, false,
+		fmt.Sprintf("patient_merge_undone:merge_%d:to_patient_%d", merge.ID, merge.MergedID))
+	s.logReanchored(reanchored, &merge, merge.SurvivorID, merge.MergedID, undoneByUserID, ipAddress, userAgent)
+
+	return &merge, nil
+}
+
+// moveSignedRecords files the signed records among ids that are still on one
+// chart under another, re-anchoring their content hashes, and returns their
+// ids. A record whose content no longer matches its signature stops the
+// merge.
+func moveSignedRecords(tx *gorm.DB, ids []uint, fromPatientID, toPatientID uint) ([]uint, error) {
+	var records []models.MedicalRecord
+	if err := tx.Unscoped().Where("id IN ? AND patient_id = ? AND status IN ?", ids, fromPatientID,
+		[]models.RecordStatus{models.RecordStatusPendingCosign, models.RecordStatusSigned}).
+		Preload("Diagnoses", models.PreloadDiagnoses).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read signed medical records: %w", err)
+	}
+
+	moved := make([]uint, 0, len(records))
+	for i := range records {
+		record := &records[i]
+		if err := record.MoveToPatient(toPatientID); err != nil {
+			return nil, fmt.Errorf("medical record %d: %w", record.ID, err)
+		}
+		if err := tx.Unscoped().Model(record).UpdateColumns(map[string]interface{}{
+			"patient_id":   record.PatientID,
+			"content_hash": record.ContentHash,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to move medical record %d: %w", record.ID, err)
+		}
+		moved = append(moved, record.ID)
+	}
+	return moved, nil
+}
+
+// logReanchored audits each signed record whose hash was taken again for the
+// chart it moved to
+func (s *PatientMergeService) logReanchored(recordIDs []uint, merge *models.PatientMerge, fromPatientID, toPatientID, userID uint, ipAddress, userAgent string) {
+	for _, recordID := range recordIDs {
+		s.audit.LogMedicalRecordAccess(userID, toPatientID, recordID, models.ActionUpdate, ipAddress, userAgent, false,
+			fmt.Sprintf("signature_reanchored:merge_%d:from_patient_%d", merge.ID, fromPatientID))
+	}
+}
+
+// GetMerges lists merges, newest first
+func (s *PatientMergeService) GetMerges(query *PatientMergeQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.PatientMerge, int64, error) {
+	if requestedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, "patient_merges", ipAddress, userAgent, "insufficient_role")
+		return nil, 0, fmt.Errorf("insufficient permissions to review patient merges")
+	}
+
+	db := s.db.Model(&models.PatientMerge{})
+	if query.PatientID != 0 {
+		db = db.Where("survivor_id = ? OR merged_id = ?", query.PatientID, query.PatientID)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	var total int64
+	db.Count(&total)
+
+	var merges []models.PatientMerge
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Order("created_at DESC").Offset(offset).Limit(query.Limit).Find(&merges).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve patient merges: %w", err)
+	}
+
+	return merges, total, nil
+}
+
+// findDuplicatePatients scores the live charts sharing a date of birth,
+// phone or name with the patient and returns those at or above the
+// threshold, best first. Candidates are narrowed in SQL first so that only a
+// handful of charts are scored. A missing date of birth matches nothing.
+func findDuplicatePatients(db *gorm.DB, patient *models.Patient, threshold int) ([]models.PatientMatch, error) {
+	blocks := db.Session(&gorm.Session{NewDB: true})
+	blocked := false
+	if !patient.DateOfBirth.IsZero() {
+		blocks = blocks.Or("date_of_birth = ?", patient.DateOfBirth)
+		blocked = true
+	}
+
+	lastName := strings.TrimSpace(patient.LastName)
+	if lastName != "" {
+		blocks = blocks.Or("last_name = ?", lastName)
+		blocked = true
+		// Misspelled surnames with a birth date in the same year
+		if prefix := []rune(lastName); len(prefix) >= 2 && !patient.DateOfBirth.IsZero() {
+			year := patient.DateOfBirth.Year()
+			blocks = blocks.Or("last_name LIKE ? AND date_of_birth BETWEEN ? AND ?",
+				escapeLike(string(prefix[:2]))+"%",
+				time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC))
+		}
+	}
+
+	phoneIndex, err := models.PatientPhoneIndex(patient.Phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index phone: %w", err)
+	}
+	if phoneIndex != "" {
+		blocks = blocks.Or("phone_index = ?", phoneIndex)
+		blocked = true
+	}
+	if !blocked {
+		return []models.PatientMatch{}, nil
+	}
+
+	query := db.Where(blocks)
+	if patient.ID != 0 {
+		query = query.Where("id <> ?", patient.ID)
+	}
+
+	var candidates []models.Patient
+	if err := query.Limit(maxDuplicateCandidates).Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to search for duplicate patients: %w", err)
+	}
+
+	matches := make([]models.PatientMatch, 0)
+	for i := range candidates {
+		if match := models.MatchPatients(patient, &candidates[i]); match.Score >= threshold {
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	return matches, nil
+}
+
+// mergedChartIDs returns a chart and every chart merged into it, directly or
+// through earlier merges
+func mergedChartIDs(db *gorm.DB, patientID uint) ([]uint, error) {
+	ids := []uint{patientID}
+	seen := map[uint]bool{patientID: true}
+	for frontier := ids; len(frontier) > 0; {
+		var merged []uint
+		if err := db.Unscoped().Model(&models.Patient{}).Where("merged_into_id IN ?", frontier).Pluck("id", &merged).Error; err != nil {
+			return nil, fmt.Errorf("failed to resolve merged charts: %w", err)
+		}
+		frontier = frontier[:0:0]
+		for _, id := range merged {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMergeService(t *testing.T) (*PatientMergeService, *MedicalRecordService) {
+	_, records := newConsentService(t)
+	config := &configs.Config{MPI: configs.MPIConfig{MatchThreshold: 70}}
+	return NewPatientMergeService(records.db, records.audit, config), records
+}
+
+func TestMergeAndUndo(t *testing.T) {
+	merges, records := newMergeService(t)
+	db := merges.db
+
+	admin := createUser(t, db, models.RoleAdmin)
+	doctor := createUser(t, db, models.RoleDoctor)
+	survivor := createPatient(t, db, false)
+	duplicate := createPatient(t, db, false)
+	addToCareTeam(t, db, duplicate, doctor)
+	record := createRecord(t, db, duplicate, doctor, models.SensitivityNormal, models.SeverityLow)
+
+	listFor := func(patientID uint) []models.Projection {
+		projections, _, err := records.GetPatientMedicalRecords(patientID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", "", nil, nil, 1, 20)
+		require.NoError(t, err)
+		return projections
+	}
+
+	merge, err := merges.MergePatients(&MergePatientsRequest{SurvivorID: survivor.ID, MergedID: duplicate.ID, Reason: "Same person registered twice"}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+	require.NoError(t, err)
+
+	t.Run("MovesChartToSurvivor", func(t *testing.T) {
+		assert.Len(t, listFor(survivor.ID), 1)
+		assert.True(t, records.careTeam.IsOnCareTeam(survivor.ID, doctor.ID))
+
+		var merged models.Patient
+		require.NoError(t, db.Unscoped().First(&merged, duplicate.ID).Error)
+		require.NotNil(t, merged.MergedIntoID)
+		assert.Equal(t, survivor.ID, *merged.MergedIntoID)
+	})
+
+	t.Run("SignedRecordReanchored", func(t *testing.T) {
+		_, err := records.GetMedicalRecord(record.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", "", nil)
+		require.NoError(t, err)
+
+		var reanchored int64
+		db.Model(&models.AuditLog{}).Where("record_id = ? AND reason LIKE ?", record.ID, "signature_reanchored:%").Count(&reanchored)
+		assert.Equal(t, int64(1), reanchored)
+	})
+
+	t.Run("OnlyAdminsMerge", func(t *testing.T) {
+		other := createPatient(t, db, false)
+		_, err := merges.MergePatients(&MergePatientsRequest{SurvivorID: survivor.ID, MergedID: other.ID, Reason: "Same person registered twice"}, doctor.ID, models.RoleDoctor, testIP, testUserAgent)
+		assert.Error(t, err)
+	})
+
+	t.Run("UndoRestoresDuplicate", func(t *testing.T) {
+		_, err := merges.UndoMerge(merge.ID, &UndoMergeRequest{Reason: "Different people after all"}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+		require.NoError(t, err)
+
+		assert.Empty(t, listFor(survivor.ID))
+		assert.Len(t, listFor(duplicate.ID), 1)
+		assert.False(t, records.careTeam.IsOnCareTeam(survivor.ID, doctor.ID))
+
+		_, err = merges.UndoMerge(merge.ID, &UndoMergeRequest{Reason: "Different people after all"}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+		assert.Error(t, err)
+	})
+
+	t.Run("ConfidentialDuplicateNeedsConfidentialSurvivor", func(t *testing.T) {
+		confidential := createPatient(t, db, true)
+		_, err := merges.MergePatients(&MergePatientsRequest{SurvivorID: survivor.ID, MergedID: confidential.ID, Reason: "Same person registered twice"}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+		assert.ErrorContains(t, err, "mark the surviving chart confidential")
+	})
+}
+
+func TestTamperedRecordIsNotServed(t *testing.T) {
+	_, records := newConsentService(t)
+	db := records.db
+
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, doctor)
+	record := createRecord(t, db, patient, doctor, models.SensitivityNormal, models.SeverityLow)
+
+	require.NoError(t, db.Model(&models.MedicalRecord{}).Where("id = ?", record.ID).UpdateColumn("content_hash", "0000").Error)
+
+	_, err := records.GetMedicalRecord(record.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", "", nil)
+	assert.True(t, errors.Is(err, models.ErrRecordTampered))
+	assert.Equal(t, "integrity_check_failed", lastAuditEntry(t, db, doctor.ID).ErrorMessage)
+
+	_, _, err = records.GetPatientMedicalRecords(patient.ID, doctor.ID, models.RoleDoctor, testIP, testUserAgent, false, "", "", nil, nil, 1, 20)
+	assert.True(t, errors.Is(err, models.ErrRecordTampered))
+}
+
+func TestFindDuplicates(t *testing.T) {
+	merges, _ := newMergeService(t)
+	db := merges.db
+
+	admin := createUser(t, db, models.RoleAdmin)
+	patient := createPatient(t, db, false)
+	twin := createPatient(t, db, false)
+
+	t.Run("MatchesSameDemographics", func(t *testing.T) {
+		matches, err := merges.FindDuplicates(patient.ID, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+		require.NoError(t, err)
+		require.NotEmpty(t, matches)
+		assert.Equal(t, twin.ID, matches[0].PatientID)
+	})
+
+	t.Run("MissingBirthDateIsNotAMatch", func(t *testing.T) {
+		first := &models.Patient{FirstName: "Alex", LastName: "Unknown"}
+		second := &models.Patient{FirstName: "Alex", LastName: "Unknownson"}
+		require.NoError(t, db.Create(first).Error)
+		require.NoError(t, db.Create(second).Error)
+		require.True(t, first.DateOfBirth.Equal(time.Time{}))
+
+		matches, err := merges.FindDuplicates(first.ID, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+		require.NoError(t, err)
+		for _, match := range matches {
+			assert.NotEqual(t, second.ID, match.PatientID)
+		}
+	})
+}
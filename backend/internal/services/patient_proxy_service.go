@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PatientProxyService struct {
+	db    *gorm.DB
+	audit *AuditService
+	rules models.ProxyAgeRules
+}
+
+type CreateProxyRequest struct {
+	ProxyUserID  uint                     `json:"proxy_user_id" binding:"required"`
+	Relationship models.ProxyRelationship `json:"relationship" binding:"required"`
+	Scope        models.ProxyScope        `json:"scope" binding:"required"`
+	StartsAt     *time.Time               `json:"starts_at,omitempty"`
+	EndsAt       *time.Time               `json:"ends_at,omitempty"`
+}
+
+type RevokeProxyRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func NewPatientProxyService(db *gorm.DB, audit *AuditService, rules models.ProxyAgeRules) *PatientProxyService {
+	return &PatientProxyService{
+		db:    db,
+		audit: audit,
+		rules: rules,
+	}
+}
+
+// CreateProxy lets a portal account view a patient's chart. Parents and
+// guardians can only be added for minors.
+func (s *PatientProxyService) CreateProxy(patientID uint, req *CreateProxyRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string) (*models.PatientProxy, error) {
+	if !s.canManageProxies(createdByRole) {
+		s.audit.LogUnauthorizedAccess(createdByUserID, fmt.Sprintf("proxies:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to manage patient proxies")
+	}
+
+	if !req.Relationship.IsValid() {
+		return nil, fmt.Errorf("invalid proxy relationship")
+	}
+	if !req.Scope.IsValid() {
+		return nil, fmt.Errorf("invalid proxy scope")
+	}
+
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(startsAt) {
+		return nil, fmt.Errorf("proxy end date must be after its start date")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+	if req.Relationship.ForMinors() && models.AgeOn(patient.DateOfBirth, now) >= s.rules.AdultAge {
+		return nil, fmt.Errorf("%s proxies can only be added for minors", req.Relationship)
+	}
+
+	var proxyUser models.User
+	if err := s.db.Where("id = ? AND role = ? AND active = ?", req.ProxyUserID, models.RolePatient, true).First(&proxyUser).Error; err != nil {
+		return nil, fmt.Errorf("proxy must be an active patient portal account")
+	}
+	if proxyUser.PatientID != nil && *proxyUser.PatientID == patientID {
+		return nil, fmt.Errorf("patients already have access to their own chart")
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.PatientProxy{}).
+		Where("proxy_user_id = ? AND patient_id = ? AND revoked_at IS NULL AND (ends_at IS NULL OR ends_at > ?)", req.ProxyUserID, patientID, now).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check proxies: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("portal account is already a proxy for this patient")
+	}
+
+	proxy := models.PatientProxy{
+		ProxyUserID:  req.ProxyUserID,
+		PatientID:    patientID,
+		Relationship: req.Relationship,
+		Scope:        req.Scope,
+		StartsAt:     startsAt,
+		EndsAt:       req.EndsAt,
+		CreatedBy:    createdByUserID,
+	}
+	if err := s.db.Create(&proxy).Error; err != nil {
+		return nil, fmt.Errorf("failed to create proxy: %w", err)
+	}
+	proxy.EffectiveScope = proxy.EffectiveScopeAt(now, patient.DateOfBirth, s.rules)
+
+	s.audit.LogPatientAccess(createdByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false,
+		fmt.Sprintf("proxy_added:%s:user_%d", proxy.Relationship, proxy.ProxyUserID))
+
+	return &proxy, nil
+}
+
+// GetProxies lists a patient's proxies, including ended and revoked ones,
+// with the scope each has today
+func (s *PatientProxyService) GetProxies(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.PatientProxy, error) {
+	if !s.canManageProxies(requestedByRole) {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("proxies:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to manage patient proxies")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	var proxies []models.PatientProxy
+	if err := s.db.Where("patient_id = ?", patientID).
+		Preload("ProxyUser", func(db *gorm.DB) *gorm.DB { return db.Select("id, name, email, role") }).
+		Order("created_at DESC").Find(&proxies).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve proxies: %w", err)
+	}
+
+	now := time.Now()
+	for i := range proxies {
+		proxies[i].EffectiveScope = proxies[i].EffectiveScopeAt(now, patient.DateOfBirth, s.rules)
+	}
+
+	s.audit.LogPatientAccess(requestedByUserID, patientID, models.ActionView, ipAddress, userAgent, false, "proxies_viewed")
+
+	return proxies, nil
+}
+
+// RevokeProxy ends a proxy's access immediately
+func (s *PatientProxyService) RevokeProxy(patientID, proxyID uint, req *RevokeProxyRequest, revokedByUserID uint, revokedByRole models.UserRole, ipAddress, userAgent string) error {
+	if !s.canManageProxies(revokedByRole) {
+		s.audit.LogUnauthorizedAccess(revokedByUserID, fmt.Sprintf("proxies:patient_%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to manage patient proxies")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return fmt.Errorf("a reason is required to revoke a proxy")
+	}
+
+	var proxy models.PatientProxy
+	if err := s.db.Where("id = ? AND patient_id = ?", proxyID, patientID).First(&proxy).Error; err != nil {
+		return fmt.Errorf("proxy not found")
+	}
+	if proxy.RevokedAt != nil {
+		return fmt.Errorf("proxy is already revoked")
+	}
+
+	now := time.Now()
+	proxy.RevokedAt = &now
+	proxy.RevokedBy = &revokedByUserID
+	proxy.RevokeReason = reason
+	if err := s.db.Save(&proxy).Error; err != nil {
+		return fmt.Errorf("failed to revoke proxy: %w", err)
+	}
+
+	s.audit.LogPatientAccess(revokedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false,
+		fmt.Sprintf("proxy_revoked:grant_%d", proxy.ID))
+
+	return nil
+}
+
+func (s *PatientProxyService) canManageProxies(role models.UserRole) bool {
+	return role == models.RoleDoctor || role == models.RoleNurse || role == models.RoleFrontDesk
+}
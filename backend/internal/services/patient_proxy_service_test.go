@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var testProxyRules = models.ProxyAgeRules{RestrictedAge: 13, AdultAge: 18}
+
+func newPatientProxyService(t *testing.T) (*PatientProxyService, *PortalService) {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	return NewPatientProxyService(db, audit, testProxyRules), NewPortalService(db, audit, models.DefaultFieldPolicy(), testProxyRules)
+}
+
+// createDependent registers a patient who turned the given age a month ago
+func createDependent(t *testing.T, db *gorm.DB, age int) *models.Patient {
+	t.Helper()
+
+	patient := &models.Patient{
+		FirstName:   "Sam",
+		LastName:    "Doe",
+		DateOfBirth: time.Now().AddDate(-age, -1, 0),
+	}
+	require.NoError(t, db.Create(patient).Error)
+	return patient
+}
+
+func TestProxyAgeRules(t *testing.T) {
+	proxies, portal := newPatientProxyService(t)
+	db := proxies.db
+
+	clerk := createUser(t, db, models.RoleFrontDesk)
+	parent := createUser(t, db, models.RolePatient)
+
+	addProxy := func(patient *models.Patient, relationship models.ProxyRelationship) (*models.PatientProxy, error) {
+		req := &CreateProxyRequest{ProxyUserID: parent.ID, Relationship: relationship, Scope: models.ProxyScopeFull}
+		return proxies.CreateProxy(patient.ID, req, clerk.ID, models.RoleFrontDesk, testIP, testUserAgent)
+	}
+
+	t.Run("ParentsOnlyForMinors", func(t *testing.T) {
+		adult := createDependent(t, db, 30)
+		_, err := addProxy(adult, models.ProxyParent)
+		assert.ErrorContains(t, err, "only be added for minors")
+
+		caregiver, err := addProxy(adult, models.ProxyCaregiver)
+		require.NoError(t, err)
+		assert.Equal(t, models.ProxyScopeFull, caregiver.EffectiveScope)
+	})
+
+	t.Run("ChildFullAccess", func(t *testing.T) {
+		child := createDependent(t, db, 8)
+		proxy, err := addProxy(child, models.ProxyParent)
+		require.NoError(t, err)
+		assert.Equal(t, models.ProxyScopeFull, proxy.EffectiveScope)
+
+		_, err = portal.GetMyMedications(parent.ID, models.RolePatient, child.ID, testIP, testUserAgent)
+		assert.NoError(t, err)
+		assert.Equal(t, proxy.AuditReason(), lastAuditEntry(t, db, parent.ID).Reason)
+	})
+
+	t.Run("AdolescentLimitedToDemographics", func(t *testing.T) {
+		teen := createDependent(t, db, 15)
+		proxy, err := addProxy(teen, models.ProxyParent)
+		require.NoError(t, err)
+		assert.Equal(t, models.ProxyScopeLimited, proxy.EffectiveScope)
+
+		_, err = portal.GetMyPatient(parent.ID, models.RolePatient, teen.ID, testIP, testUserAgent)
+		assert.NoError(t, err)
+
+		for _, read := range []func() error{
+			func() error {
+				_, err := portal.GetMyMedications(parent.ID, models.RolePatient, teen.ID, testIP, testUserAgent)
+				return err
+			},
+			func() error {
+				_, _, err := portal.GetMyRecords(parent.ID, models.RolePatient, teen.ID, testIP, testUserAgent, 1, 20)
+				return err
+			},
+		} {
+			assert.Error(t, read())
+			assert.Equal(t, "proxy_scope_limited", lastAuditEntry(t, db, parent.ID).ErrorMessage)
+		}
+	})
+
+	t.Run("AccessEndsAtAdulthood", func(t *testing.T) {
+		minor := createDependent(t, db, 17)
+		_, err := addProxy(minor, models.ProxyGuardian)
+		require.NoError(t, err)
+
+		require.NoError(t, db.Model(minor).UpdateColumn("date_of_birth", time.Now().AddDate(-18, 0, -1)).Error)
+
+		dependents, err := portal.GetMyDependents(parent.ID, models.RolePatient, testIP, testUserAgent)
+		require.NoError(t, err)
+		for _, dependent := range dependents {
+			assert.NotEqual(t, minor.ID, dependent.PatientID)
+		}
+
+		_, err = portal.GetMyPatient(parent.ID, models.RolePatient, minor.ID, testIP, testUserAgent)
+		assert.EqualError(t, err, "patient not found")
+		assert.Equal(t, "no_active_proxy", lastAuditEntry(t, db, parent.ID).ErrorMessage)
+	})
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+	"healthsecure/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+type PatientPurgeService struct {
+	db     *gorm.DB
+	audit  *AuditService
+	store  storage.Store
+	config *configs.Config
+}
+
+type CreatePurgeRequest struct {
+	Reason string `json:"reason" binding:"required,min=10"`
+}
+
+type PurgeRequestQuery struct {
+	Status string `form:"status"`
+	Page   int    `form:"page,default=1"`
+	Limit  int    `form:"limit,default=20"`
+}
+
+// DeletedPatient identifies a soft-deleted chart without disclosing any of its
+// contents to the reviewing admin.
+type DeletedPatient struct {
+	ID              uint      `json:"id"`
+	DeletedAt       time.Time `json:"deleted_at"`
+	PurgeEligibleAt time.Time `json:"purge_eligible_at"`
+}
+
+func NewPatientPurgeService(db *gorm.DB, audit *AuditService, store storage.Store, config *configs.Config) *PatientPurgeService {
+	return &PatientPurgeService{
+		db:     db,
+		audit:  audit,
+		store:  store,
+		config: config,
+	}
+}
+
+// GetDeletedPatients lists soft-deleted patients with the date each may be purged
+func (s *PatientPurgeService) GetDeletedPatients(page, limit int, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]DeletedPatient, int64, error) {
+	if requestedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, "patients", ipAddress, userAgent, "insufficient_role")
+		return nil, 0, fmt.Errorf("insufficient permissions to review deleted patients")
+	}
+
+	var total int64
+	query := s.db.Unscoped().Model(&models.Patient{}).Where("deleted_at IS NOT NULL")
+	query.Count(&total)
+
+	var patients []models.Patient
+	offset := (page - 1) * limit
+	if err := query.Select("id", "deleted_at").Order("deleted_at").
+		Offset(offset).Limit(limit).Find(&patients).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve deleted patients: %w", err)
+	}
+
+	deleted := make([]DeletedPatient, 0, len(patients))
+	for _, patient := range patients {
+		deleted = append(deleted, DeletedPatient{
+			ID:              patient.ID,
+			DeletedAt:       patient.DeletedAt.Time,
+			PurgeEligibleAt: patient.PurgeEligibleAt(s.config.Retention.PatientPurgeAfter),
+		})
+	}
+
+	return deleted, total, nil
+}
+
+// RequestPurge asks for a soft-deleted patient past the retention period to
+// be purged. Another admin must approve the request.
+func (s *PatientPurgeService) RequestPurge(patientID uint, req *CreatePurgeRequest, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) (*models.PatientPurgeRequest, error) {
+	if requestedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role_for_purge")
+		return nil, fmt.Errorf("insufficient permissions to purge patient")
+	}
+
+	if _, err := s.purgeablePatient(s.db, patientID); err != nil {
+		return nil, err
+	}
+
+	var pending int64
+	s.db.Model(&models.PatientPurgeRequest{}).
+		Where("patient_id = ? AND status = ?", patientID, models.PurgeStatusPending).
+		Count(&pending)
+	if pending > 0 {
+		return nil, fmt.Errorf("a purge request for this patient is already pending")
+	}
+
+	request := models.PatientPurgeRequest{
+		PatientID:   patientID,
+		Reason:      req.Reason,
+		RequestedBy: requestedByUserID,
+	}
+	if err := s.db.Create(&request).Error; err != nil {
+		return nil, fmt.Errorf("failed to create purge request: %w", err)
+	}
+
+	s.audit.LogUserAction(requestedByUserID, models.ActionCreate, fmt.Sprintf("patient_purge_request:%d", request.ID), ipAddress, userAgent, true,
+		fmt.Sprintf("purge_requested:patient_%d", patientID))
+
+	return &request, nil
+}
+
+// ApprovePurge records the second admin's approval and permanently deletes
+// the patient with their records, addenda, amendment requests, revisions,
+// consents, care team, access grants and merge history.
+// Audit logs are never deleted.
+func (s *PatientPurgeService) ApprovePurge(requestID uint, reviewedByUserID uint, reviewedByRole models.UserRole, ipAddress, userAgent string) error {
+	if reviewedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(reviewedByUserID, fmt.Sprintf("patient_purge_request:%d", requestID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to review purge request")
+	}
+
+	var request models.PatientPurgeRequest
+	if err := s.db.Where("id = ?", requestID).First(&request).Error; err != nil {
+		return fmt.Errorf("purge request not found")
+	}
+
+	if request.RequestedBy == reviewedByUserID {
+		s.audit.LogUnauthorizedAccess(reviewedByUserID, fmt.Sprintf("patient_purge_request:%d", requestID), ipAddress, userAgent, "purge_self_approval")
+		return fmt.Errorf("a purge must be approved by a different admin")
+	}
+	if err := request.Approve(reviewedByUserID); err != nil {
+		return fmt.Errorf("purge request is not awaiting review")
+	}
+
+	var blobKeys []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		patient, err := s.purgeablePatient(tx, request.PatientID)
+		if err != nil {
+			return err
+		}
+
+		// Attachment blobs are removed once the rows are gone
+		if err := tx.Unscoped().Model(&models.Attachment{}).Where("patient_id = ?", patient.ID).
+			Pluck("storage_key", &blobKeys).Error; err != nil {
+			return fmt.Errorf("failed to list attachments: %w", err)
+		}
+
+		// Revisions hold copies of the purged PHI
+		if err := tx.Where("resource_type = ? AND resource_id IN (?)", models.ResourceMedicalRecord,
+			tx.Unscoped().Model(&models.MedicalRecord{}).Select("id").Where("patient_id = ?", patient.ID)).
+			Delete(&models.Revision{}).Error; err != nil {
+			return fmt.Errorf("failed to purge record revisions: %w", err)
+		}
+		if err := tx.Where("resource_type = ? AND resource_id = ?", models.ResourcePatient, patient.ID).
+			Delete(&models.Revision{}).Error; err != nil {
+			return fmt.Errorf("failed to purge patient revisions: %w", err)
+		}
+		if err := tx.Where("record_id IN (?)",
+			tx.Unscoped().Model(&models.MedicalRecord{}).Select("id").Where("patient_id = ?", patient.ID)).
+			Delete(&models.RecordAddendum{}).Error; err != nil {
+			return fmt.Errorf("failed to purge record addenda: %w", err)
+		}
+
+		// Only undone merges can remain; their history names the chart
+		merges := tx.Model(&models.PatientMerge{}).Select("id").Where("survivor_id = ? OR merged_id = ?", patient.ID, patient.ID)
+		if err := tx.Where("merge_id IN (?)", merges).Delete(&models.PatientMergeRow{}).Error; err != nil {
+			return fmt.Errorf("failed to purge merge rows: %w", err)
+		}
+		if err := tx.Where("survivor_id = ? OR merged_id = ?", patient.ID, patient.ID).Delete(&models.PatientMerge{}).Error; err != nil {
+			return fmt.Errorf("failed to purge merge history: %w", err)
+		}
+
+		dependents := []interface{}{
+			&models.AmendmentRequest{},
+			&models.RecordDiagnosis{},
+			&models.Attachment{},
+			&models.MedicalRecord{},
+			&models.MedicationOrder{},
+			&models.Allergy{},
+			&models.Problem{},
+			&models.Observation{},
+			&models.Notification{},
+			&models.PatientConsent{},
+			&models.CareTeamMember{},
+			&models.AccessDelegationPatient{},
+			&models.EmergencyAccess{},
+			&models.Encounter{},
+			&models.PatientIdentifier{},
+			&models.PatientProxy{},
+		}
+		for _, dependent := range dependents {
+			if err := tx.Unscoped().Where("patient_id = ?", patient.ID).Delete(dependent).Error; err != nil {
+				return fmt.Errorf("failed to purge %T: %w", dependent, err)
+			}
+		}
+		// Portal accounts are kept for their login history but lose the chart
+		if err := tx.Model(&models.User{}).Where("patient_id = ?", patient.ID).
+			Updates(map[string]interface{}{"patient_id": nil, "active": false}).Error; err != nil {
+			return fmt.Errorf("failed to unlink portal accounts: %w", err)
+		}
+		if err := tx.Unscoped().Delete(patient).Error; err != nil {
+			return fmt.Errorf("failed to purge patient: %w", err)
+		}
+
+		return tx.Save(&request).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// The rows are already gone, so a blob that fails to delete is reported
+	// in the audit trail for manual removal rather than failing the purge
+	failed := 0
+	for _, key := range blobKeys {
+		if err := s.store.Delete(context.Background(), key); err != nil {
+			failed++
+		}
+	}
+
+	reason := fmt.Sprintf("patient_purged:request_%d:requested_by_%d", request.ID, request.RequestedBy)
+	if failed > 0 {
+		reason = fmt.Sprintf("%s:attachment_blobs_not_deleted_%d", reason, failed)
+	}
+	s.audit.LogPatientAccess(reviewedByUserID, request.PatientID, models.ActionDelete, ipAddress, userAgent, false, reason)
+
+	return nil
+}
+
+// RejectPurge declines a pending purge request
+func (s *PatientPurgeService) RejectPurge(requestID uint, reviewedByUserID uint, reviewedByRole models.UserRole, ipAddress, userAgent string) error {
+	if reviewedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(reviewedByUserID, fmt.Sprintf("patient_purge_request:%d", requestID), ipAddress, userAgent, "insufficient_role")
+		return fmt.Errorf("insufficient permissions to review purge request")
+	}
+
+	var request models.PatientPurgeRequest
+	if err := s.db.Where("id = ?", requestID).First(&request).Error; err != nil {
+		return fmt.Errorf("purge request not found")
+	}
+
+	if err := request.Reject(reviewedByUserID); err != nil {
+		return fmt.Errorf("purge request is not awaiting review")
+	}
+
+	if err := s.db.Save(&request).Error; err != nil {
+		return fmt.Errorf("failed to review purge request: %w", err)
+	}
+
+	s.audit.LogUserAction(reviewedByUserID, models.ActionUpdate, fmt.Sprintf("patient_purge_request:%d", request.ID), ipAddress, userAgent, true, "purge_rejected")
+
+	return nil
+}
+
+// GetPurgeRequests lists purge requests for admin review
+func (s *PatientPurgeService) GetPurgeRequests(query *PurgeRequestQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.PatientPurgeRequest, int64, error) {
+	if requestedByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(requestedByUserID, "patient_purge_requests", ipAddress, userAgent, "insufficient_role")
+		return nil, 0, fmt.Errorf("insufficient permissions to review purge requests")
+	}
+
+	filter := &models.PatientPurgeRequestFilter{}
+	if query.Status != "" {
+		status := models.PurgeStatus(query.Status)
+		filter.Status = &status
+	}
+
+	var total int64
+	filter.Apply(s.db.Model(&models.PatientPurgeRequest{})).Count(&total)
+
+	filter.Limit = query.Limit
+	filter.Offset = (query.Page - 1) * query.Limit
+
+	var requests []models.PatientPurgeRequest
+	if err := filter.Apply(s.db).Preload("Requester").Preload("Reviewer").
+		Find(&requests).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve purge requests: %w", err)
+	}
+
+	for i := range requests {
+		requests[i].Requester.Password = ""
+		if requests[i].Reviewer != nil {
+			requests[i].Reviewer.Password = ""
+		}
+	}
+
+	return requests, total, nil
+}
+
+// purgeablePatient loads a soft-deleted patient whose retention period has
+// ended and that no merge can still be undone into or out of
+func (s *PatientPurgeService) purgeablePatient(db *gorm.DB, patientID uint) (*models.Patient, error) {
+	var patient models.Patient
+	if err := db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", patientID).First(&patient).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("only deleted patients can be purged")
+		}
+		return nil, fmt.Errorf("failed to retrieve patient: %w", err)
+	}
+
+	retention := s.config.Retention.PatientPurgeAfter
+	if !patient.CanPurge(retention, time.Now()) {
+		return nil, fmt.Errorf("patient cannot be purged before %s", patient.PurgeEligibleAt(retention).Format("2006-01-02"))
+	}
+
+	var merge models.PatientMerge
+	err := db.Where("(survivor_id = ? OR merged_id = ?) AND status = ?", patientID, patientID, models.MergeStatusMerged).
+		First(&merge).Error
+	if err == nil {
+		return nil, fmt.Errorf("patient cannot be purged while patient merge %d can be undone", merge.ID)
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to check patient merges: %w", err)
+	}
+
+	return &patient, nil
+}
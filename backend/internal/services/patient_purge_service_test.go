@@ -0,0 +1,132 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newPurgeService(t *testing.T, retention time.Duration) (*PatientPurgeService, *PatientMergeService) {
+	db := newTestDB(t)
+	audit := NewAuditService(db, false)
+	config := &configs.Config{Retention: configs.RetentionConfig{PatientPurgeAfter: retention}}
+	return NewPatientPurgeService(db, audit, nil, config), NewPatientMergeService(db, audit, config)
+}
+
+// deletePatient soft deletes a patient as of the given time
+func deletePatient(t *testing.T, db *gorm.DB, patient *models.Patient, at time.Time) {
+	t.Helper()
+	require.NoError(t, db.Model(patient).UpdateColumn("deleted_at", at).Error)
+}
+
+func TestPurgeApproval(t *testing.T) {
+	purges, _ := newPurgeService(t, 0)
+	db := purges.db
+
+	requester := createUser(t, db, models.RoleAdmin)
+	reviewer := createUser(t, db, models.RoleAdmin)
+	doctor := createUser(t, db, models.RoleDoctor)
+	patient := createPatient(t, db, false)
+	addToCareTeam(t, db, patient, doctor)
+	createRecord(t, db, patient, doctor, models.SensitivityNormal, models.SeverityLow)
+
+	reason := &CreatePurgeRequest{Reason: "Retention period has ended"}
+
+	t.Run("OnlyDeletedPatients", func(t *testing.T) {
+		_, err := purges.RequestPurge(patient.ID, reason, requester.ID, models.RoleAdmin, testIP, testUserAgent)
+		assert.EqualError(t, err, "only deleted patients can be purged")
+	})
+
+	deletePatient(t, db, patient, time.Now().Add(-time.Minute))
+
+	t.Run("OnlyAdmins", func(t *testing.T) {
+		_, err := purges.RequestPurge(patient.ID, reason, doctor.ID, models.RoleDoctor, testIP, testUserAgent)
+		assert.Error(t, err)
+	})
+
+	request, err := purges.RequestPurge(patient.ID, reason, requester.ID, models.RoleAdmin, testIP, testUserAgent)
+	require.NoError(t, err)
+
+	t.Run("OnePendingRequest", func(t *testing.T) {
+		_, err := purges.RequestPurge(patient.ID, reason, reviewer.ID, models.RoleAdmin, testIP, testUserAgent)
+		assert.Error(t, err)
+	})
+
+	t.Run("RequesterCannotApprove", func(t *testing.T) {
+		err := purges.ApprovePurge(request.ID, requester.ID, models.RoleAdmin, testIP, testUserAgent)
+		assert.Error(t, err)
+		assert.Equal(t, "purge_self_approval", lastAuditEntry(t, db, requester.ID).ErrorMessage)
+	})
+
+	t.Run("SecondAdminPurges", func(t *testing.T) {
+		require.NoError(t, purges.ApprovePurge(request.ID, reviewer.ID, models.RoleAdmin, testIP, testUserAgent))
+
+		var count int64
+		db.Unscoped().Model(&models.Patient{}).Where("id = ?", patient.ID).Count(&count)
+		assert.Zero(t, count)
+		db.Unscoped().Model(&models.MedicalRecord{}).Where("patient_id = ?", patient.ID).Count(&count)
+		assert.Zero(t, count)
+		db.Model(&models.CareTeamMember{}).Where("patient_id = ?", patient.ID).Count(&count)
+		assert.Zero(t, count)
+
+		// The audit trail outlives the chart
+		db.Model(&models.AuditLog{}).Where("patient_id = ?", patient.ID).Count(&count)
+		assert.NotZero(t, count)
+	})
+}
+
+func TestPurgeRetention(t *testing.T) {
+	purges, _ := newPurgeService(t, 30*24*time.Hour)
+	db := purges.db
+
+	admin := createUser(t, db, models.RoleAdmin)
+	patient := createPatient(t, db, false)
+	deletePatient(t, db, patient, time.Now().Add(-24*time.Hour))
+
+	_, err := purges.RequestPurge(patient.ID, &CreatePurgeRequest{Reason: "Retention period has ended"}, admin.ID, models.RoleAdmin, testIP, testUserAgent)
+	assert.ErrorContains(t, err, "patient cannot be purged before")
+}
+
+func TestPurgeAcrossMerges(t *testing.T) {
+	purges, merges := newPurgeService(t, 0)
+	db := purges.db
+
+	requester := createUser(t, db, models.RoleAdmin)
+	reviewer := createUser(t, db, models.RoleAdmin)
+	survivor := createPatient(t, db, false)
+	duplicate := createPatient(t, db, false)
+	createRecord(t, db, duplicate, createUser(t, db, models.RoleDoctor), models.SensitivityNormal, models.SeverityLow)
+
+	merge, err := merges.MergePatients(&MergePatientsRequest{SurvivorID: survivor.ID, MergedID: duplicate.ID, Reason: "Same person registered twice"}, requester.ID, models.RoleAdmin, testIP, testUserAgent)
+	require.NoError(t, err)
+	require.NotZero(t, merge.RowsMoved)
+
+	reason := &CreatePurgeRequest{Reason: "Retention period has ended"}
+
+	t.Run("BlockedWhileMergeCanBeUndone", func(t *testing.T) {
+		_, err := purges.RequestPurge(duplicate.ID, reason, requester.ID, models.RoleAdmin, testIP, testUserAgent)
+		assert.ErrorContains(t, err, "can be undone")
+	})
+
+	t.Run("PurgesMergeHistoryOnceUndone", func(t *testing.T) {
+		_, err := merges.UndoMerge(merge.ID, &UndoMergeRequest{Reason: "Different people after all"}, requester.ID, models.RoleAdmin, testIP, testUserAgent)
+		require.NoError(t, err)
+		deletePatient(t, db, duplicate, time.Now().Add(-time.Minute))
+
+		request, err := purges.RequestPurge(duplicate.ID, reason, requester.ID, models.RoleAdmin, testIP, testUserAgent)
+		require.NoError(t, err)
+		require.NoError(t, purges.ApprovePurge(request.ID, reviewer.ID, models.RoleAdmin, testIP, testUserAgent))
+
+		var count int64
+		db.Model(&models.PatientMerge{}).Where("id = ?", merge.ID).Count(&count)
+		assert.Zero(t, count)
+		db.Model(&models.PatientMergeRow{}).Where("merge_id = ?", merge.ID).Count(&count)
+		assert.Zero(t, count)
+	})
+}
@@ -2,27 +2,37 @@ package services
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"healthsecure/configs"
 	"healthsecure/internal/database"
+	"healthsecure/internal/identifiers"
 	"healthsecure/internal/models"
 
 	"gorm.io/gorm"
 )
 
 type PatientService struct {
-	db    *gorm.DB
-	audit *AuditService
+	db          *gorm.DB
+	audit       *AuditService
+	consents    *ConsentService
+	careTeam    *CareTeamService
+	fieldPolicy models.FieldPolicy
+	config      *configs.Config
+	mrns        *identifiers.MRNGenerator
 }
 
 type CreatePatientRequest struct {
 	FirstName        string    `json:"first_name" binding:"required"`
 	LastName         string    `json:"last_name" binding:"required"`
 	DateOfBirth      time.Time `json:"date_of_birth" binding:"required"`
-	SSN              string    `json:"ssn" binding:"required"`
+	SSN              string    `json:"ssn"`
 	Phone            string    `json:"phone"`
 	Address          string    `json:"address"`
 	EmergencyContact string    `json:"emergency_contact"`
+	Confidential     bool      `json:"confidential"`
+	EmployeeUserID   *uint     `json:"employee_user_id,omitempty"`
 }
 
 type UpdatePatientRequest struct {
@@ -32,6 +42,18 @@ type UpdatePatientRequest struct {
 	Phone            *string    `json:"phone,omitempty"`
 	Address          *string    `json:"address,omitempty"`
 	EmergencyContact *string    `json:"emergency_contact,omitempty"`
+	Confidential     *bool      `json:"confidential,omitempty"`
+	EmployeeUserID   *uint      `json:"employee_user_id,omitempty"`
+	Reason           string     `json:"reason,omitempty"`
+}
+
+// PatientLookupQuery finds a patient by one of their identifiers: either a
+// system and value, or one of our MRNs
+type PatientLookupQuery struct {
+	System   string `form:"system"`
+	Value    string `form:"value"`
+	MRN      string `form:"mrn"`
+	Facility string `form:"facility"`
 }
 
 type PatientSearchQuery struct {
@@ -42,27 +64,64 @@ type PatientSearchQuery struct {
 	DateOfBirth time.Time `form:"date_of_birth"`
 	Page        int       `form:"page,default=1"`
 	Limit       int       `form:"limit,default=20"`
+
+	// Filters used by the FHIR API. Name matches either name; ranges
+	// include their start and exclude their end.
+	Name          string              `form:"-"`
+	Identifier    *PatientLookupQuery `form:"-"`
+	BornSince     *time.Time          `form:"-"`
+	BornBefore    *time.Time          `form:"-"`
+	UpdatedSince  *time.Time          `form:"-"`
+	UpdatedBefore *time.Time          `form:"-"`
 }
 
-func NewPatientService(db *gorm.DB, audit *AuditService) *PatientService {
+func NewPatientService(db *gorm.DB, audit *AuditService, consents *ConsentService, careTeam *CareTeamService, fieldPolicy models.FieldPolicy, config *configs.Config, mrns *identifiers.MRNGenerator) *PatientService {
 	return &PatientService{
-		db:    db,
-		audit: audit,
+		db:          db,
+		audit:       audit,
+		consents:    consents,
+		careTeam:    careTeam,
+		fieldPolicy: fieldPolicy,
+		config:      config,
+		mrns:        mrns,
 	}
 }
 
-// CreatePatient creates a new patient record
-func (s *PatientService) CreatePatient(req *CreatePatientRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string) (*models.Patient, error) {
+// CreatePatient creates a new patient record and issues its MRN at the default
+// facility. Existing charts that are likely the same person are returned with
+// it so that registration can merge them rather than leave a duplicate.
+func (s *PatientService) CreatePatient(req *CreatePatientRequest, createdByUserID uint, createdByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.Patient, []models.PatientMatch, error) {
+	audit := s.audit.WithPurpose(purpose)
+
 	// Only doctors and admins can create patients
 	if createdByRole != models.RoleDoctor && createdByRole != models.RoleAdmin {
-		s.audit.LogUnauthorizedAccess(createdByUserID, "patients", ipAddress, userAgent, "insufficient_role_for_creation")
-		return nil, fmt.Errorf("insufficient permissions to create patient")
+		audit.LogUnauthorizedAccess(createdByUserID, "patients", ipAddress, userAgent, "insufficient_role_for_creation")
+		return nil, nil, fmt.Errorf("insufficient permissions to create patient")
 	}
 
-	// Check if patient with SSN already exists
+	// Check if patient with SSN already exists. The SSN is encrypted, so the
+	// lookup goes through its blind index.
+	ssnIndex, err := models.PatientSSNIndex(req.SSN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to index SSN: %w", err)
+	}
 	var existingPatient models.Patient
-	if err := s.db.Where("ssn = ?", req.SSN).First(&existingPatient).Error; err == nil {
-		return nil, fmt.Errorf("patient with SSN already exists")
+	if ssnIndex != "" {
+		if err := s.db.Unscoped().Where("ssn_index = ?", ssnIndex).First(&existingPatient).Error; err == nil {
+			if existingPatient.IsMerged() {
+				return nil, nil, fmt.Errorf("patient with SSN was merged into patient %d", *existingPatient.MergedIntoID)
+			}
+			if existingPatient.IsDeleted() {
+				return nil, nil, fmt.Errorf("patient with SSN was deleted and must be restored instead")
+			}
+			return nil, nil, fmt.Errorf("patient with SSN already exists")
+		}
+	}
+
+	if req.EmployeeUserID != nil {
+		if err := s.validateEmployeeUser(*req.EmployeeUserID); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Create patient
@@ -74,56 +133,152 @@ func (s *PatientService) CreatePatient(req *CreatePatientRequest, createdByUserI
 		Phone:            req.Phone,
 		Address:          req.Address,
 		EmergencyContact: req.EmergencyContact,
+		Confidential:     req.Confidential,
+		EmployeeUserID:   req.EmployeeUserID,
+	}
+
+	// Many patients have no SSN, so likely duplicates are found by matching
+	// demographics. They are reported, not refused: the match may be wrong.
+	duplicates, err := findDuplicatePatients(s.db, &patient, s.config.MPI.MatchThreshold)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := s.db.Create(&patient).Error; err != nil {
-		return nil, fmt.Errorf("failed to create patient: %w", err)
+	var mrn *models.PatientIdentifier
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&patient).Error; err != nil {
+			return err
+		}
+		if err := recordRevision(tx, models.ResourcePatient, patient.ID, &patient, createdByUserID, "patient_created"); err != nil {
+			return err
+		}
+		var err error
+		mrn, err = assignMRN(tx, s.mrns, patient.ID, s.mrns.DefaultFacility(), createdByUserID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create patient: %w", err)
 	}
+	patient.Identifiers = []models.PatientIdentifier{*mrn}
 
-	// Log patient creation
-	s.audit.LogPatientAccess(createdByUserID, patient.ID, models.ActionCreate, ipAddress, userAgent, false, "patient_created")
+	// Log patient creation, noting the charts it may duplicate
+	reason := "patient_created"
+	if len(duplicates) > 0 {
+		ids := make([]string, len(duplicates))
+		for i, match := range duplicates {
+			ids[i] = fmt.Sprintf("%d", match.PatientID)
+		}
+		reason = fmt.Sprintf("patient_created:possible_duplicates_%s", strings.Join(ids, "_"))
+	}
+	audit.LogPatientAccess(createdByUserID, patient.ID, models.ActionCreate, ipAddress, userAgent, false, reason)
 
-	return &patient, nil
+	return &patient, duplicates, nil
 }
 
-// GetPatient retrieves a patient by ID with role-based data filtering
-func (s *PatientService) GetPatient(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool) (*models.Patient, error) {
-	// Check if user has permission to access patient data
-	if !s.canAccessPatientData(requestedByRole) {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role")
-		return nil, fmt.Errorf("insufficient permissions to access patient data")
+// GetPatient retrieves a patient by ID projected to the fields the role may see
+func (s *PatientService) GetPatient(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) (models.Projection, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	patient, err := s.authorizePatientAccess(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("patient_id = ?", patient.ID).Order("id").Find(&patient.Identifiers).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve patient identifiers: %w", err)
 	}
 
-	var patient models.Patient
-	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("patient not found")
-		}
-		return nil, fmt.Errorf("failed to retrieve patient: %w", err)
+	// Apply role-based filtering
+	sanitizedPatient := patient.SanitizeForRole(requestedByRole)
+	if sanitizedPatient == nil {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "role_based_filtering_denied")
+		return nil, fmt.Errorf("access denied to patient data")
 	}
 
-	// Log patient access
+	projection, disclosed := policy.Project(models.ResourcePatient, requestedByRole, sanitizedPatient, fields)
+
+	// Log patient access with the fields actually disclosed
 	reason := ""
 	if emergencyAccess {
 		reason = "emergency_access"
+	} else {
+		reason = s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
 	}
-	s.audit.LogPatientAccess(requestedByUserID, patientID, models.ActionView, ipAddress, userAgent, emergencyAccess, reason)
+	audit.LogPatientDisclosure(requestedByUserID, patientID, ipAddress, userAgent, emergencyAccess, reason, disclosed)
 
-	// Apply role-based filtering
-	sanitizedPatient := patient.SanitizeForRole(requestedByRole)
-	if sanitizedPatient == nil {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "role_based_filtering_denied")
-		return nil, fmt.Errorf("access denied to patient data")
+	return projection, nil
+}
+
+// FindPatientByIdentifier returns the ID of the patient holding an
+// identifier. MRNs are checked against their format and check digit first so
+// that a mistyped MRN is reported rather than silently finding nothing.
+func (s *PatientService) FindPatientByIdentifier(query *PatientLookupQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (uint, error) {
+	if !s.canAccessPatientData(requestedByRole) {
+		s.audit.WithPurpose(purpose).LogUnauthorizedAccess(requestedByUserID, "patients_lookup", ipAddress, userAgent, "insufficient_role")
+		return 0, fmt.Errorf("insufficient permissions to access patient data")
+	}
+
+	system, index, err := s.identifierIndex(query)
+	if err != nil {
+		return 0, err
+	}
+	var identifier models.PatientIdentifier
+	if err := s.db.Where("identifier_system = ? AND value_index = ?", system, index).First(&identifier).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, fmt.Errorf("patient not found")
+		}
+		return 0, fmt.Errorf("failed to look up identifier: %w", err)
 	}
 
-	return sanitizedPatient, nil
+	return identifier.PatientID, nil
+}
+
+// identifierIndex returns the system and blind index of the identifier a
+// lookup names
+func (s *PatientService) identifierIndex(query *PatientLookupQuery) (string, string, error) {
+	system, value := strings.TrimSpace(query.System), strings.TrimSpace(query.Value)
+	if query.MRN != "" {
+		facility, err := s.mrns.Parse(query.MRN)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid MRN: %w", err)
+		}
+		if facility == "" {
+			facility = strings.ToUpper(strings.TrimSpace(query.Facility))
+		}
+		if facility == "" {
+			facility = s.mrns.DefaultFacility()
+		}
+		system, value = models.MRNSystem(facility), query.MRN
+	}
+	if system == "" || value == "" {
+		return "", "", fmt.Errorf("an MRN or an identifier system and value are required")
+	}
+
+	index, err := models.PatientIdentifierIndex(system, value)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to index identifier: %w", err)
+	}
+	return system, index, nil
+}
+
+// ResolvePatientPublicID maps a public patient ID to the database ID. Deleted
+// patients resolve too, so that admin routes can restore them.
+func (s *PatientService) ResolvePatientPublicID(publicID string) (uint, error) {
+	var patient models.Patient
+	if err := s.db.Unscoped().Select("id").Where("public_id = ?", publicID).First(&patient).Error; err != nil {
+		return 0, fmt.Errorf("patient not found")
+	}
+	return patient.ID, nil
 }
 
 // GetPatients retrieves patients with filtering, pagination, and role-based access control
-func (s *PatientService) GetPatients(query *PatientSearchQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string) ([]models.Patient, int64, error) {
+func (s *PatientService) GetPatients(query *PatientSearchQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse, fields []string) ([]models.Projection, int64, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
 	// Check if user has permission to access patient data
 	if !s.canAccessPatientData(requestedByRole) {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, "patients", ipAddress, userAgent, "insufficient_role")
+		audit.LogUnauthorizedAccess(requestedByUserID, "patients", ipAddress, userAgent, "insufficient_role")
 		return nil, 0, fmt.Errorf("insufficient permissions to access patient data")
 	}
 
@@ -133,25 +288,68 @@ func (s *PatientService) GetPatients(query *PatientSearchQuery, requestedByUserI
 	// Build query
 	dbQuery := s.db.Model(&models.Patient{})
 
-	// Apply search filters
-	if query.FirstName != "" {
-		dbQuery = dbQuery.Where("first_name LIKE ?", "%"+query.FirstName+"%")
+	// Apply search filters. Date of birth with a full name is an exact match
+	// through the demographic index; otherwise names match partially.
+	demographicIndex, err := models.PatientDemographicIndex(query.DateOfBirth, query.FirstName, query.LastName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to index search: %w", err)
 	}
-	if query.LastName != "" {
-		dbQuery = dbQuery.Where("last_name LIKE ?", "%"+query.LastName+"%")
+	if demographicIndex != "" {
+		dbQuery = dbQuery.Where("demographic_index = ?", demographicIndex)
+	} else {
+		if query.FirstName != "" {
+			dbQuery = dbQuery.Where("first_name LIKE ?", "%"+query.FirstName+"%")
+		}
+		if query.LastName != "" {
+			dbQuery = dbQuery.Where("last_name LIKE ?", "%"+query.LastName+"%")
+		}
+		if !query.DateOfBirth.IsZero() {
+			dbQuery = dbQuery.Where("date_of_birth = ?", query.DateOfBirth)
+		}
 	}
-	if query.Phone != "" {
-		dbQuery = dbQuery.Where("phone LIKE ?", "%"+query.Phone+"%")
+
+	if query.Name != "" {
+		dbQuery = dbQuery.Where("first_name LIKE ? OR last_name LIKE ?", "%"+query.Name+"%", "%"+query.Name+"%")
+	}
+	if query.BornSince != nil {
+		dbQuery = dbQuery.Where("date_of_birth >= ?", *query.BornSince)
+	}
+	if query.BornBefore != nil {
+		dbQuery = dbQuery.Where("date_of_birth < ?", *query.BornBefore)
+	}
+	if query.UpdatedSince != nil {
+		dbQuery = dbQuery.Where("updated_at >= ?", *query.UpdatedSince)
+	}
+	if query.UpdatedBefore != nil {
+		dbQuery = dbQuery.Where("updated_at < ?", *query.UpdatedBefore)
+	}
+	if query.Identifier != nil {
+		system, index, err := s.identifierIndex(query.Identifier)
+		if err != nil {
+			return nil, 0, err
+		}
+		dbQuery = dbQuery.Where("id IN (?)", s.db.Model(&models.PatientIdentifier{}).Select("patient_id").
+			Where("identifier_system = ? AND value_index = ?", system, index))
 	}
-	if !query.DateOfBirth.IsZero() {
-		dbQuery = dbQuery.Where("date_of_birth = ?", query.DateOfBirth)
+
+	// Phone numbers are encrypted, so only exact matches are possible
+	if query.Phone != "" {
+		phoneIndex, err := models.PatientPhoneIndex(query.Phone)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to index search: %w", err)
+		}
+		dbQuery = dbQuery.Where("phone_index = ?", phoneIndex)
 	}
 
 	// Only doctors can search by SSN
 	if query.SSN != "" && requestedByRole == models.RoleDoctor {
-		dbQuery = dbQuery.Where("ssn = ?", query.SSN)
+		ssnIndex, err := models.PatientSSNIndex(query.SSN)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to index search: %w", err)
+		}
+		dbQuery = dbQuery.Where("ssn_index = ?", ssnIndex)
 	} else if query.SSN != "" {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, "patients", ipAddress, userAgent, "ssn_search_denied")
+		audit.LogUnauthorizedAccess(requestedByUserID, "patients", ipAddress, userAgent, "ssn_search_denied")
 		return nil, 0, fmt.Errorf("insufficient permissions to search by SSN")
 	}
 
@@ -173,17 +371,25 @@ func (s *PatientService) GetPatients(query *PatientSearchQuery, requestedByUserI
 		}
 	}
 
+	// Mask confidential patients outside the caller's care team
+	filteredPatients = s.careTeam.MaskForSearch(filteredPatients, requestedByUserID, ipAddress, userAgent, "patients_list", purpose)
+
+	projections, disclosed := policy.ProjectAll(models.ResourcePatient, requestedByRole, filteredPatients, fields)
+
 	// Log patients list access
-	s.audit.LogUserAction(requestedByUserID, models.ActionView, "patients_list", ipAddress, userAgent, true, fmt.Sprintf("returned_%d_patients", len(filteredPatients)))
+	audit.LogListDisclosure(requestedByUserID, "patients_list", ipAddress, userAgent, fmt.Sprintf("returned_%d_patients", len(filteredPatients)), patientIDsOf(filteredPatients), disclosed)
 
-	return filteredPatients, total, nil
+	return projections, total, nil
 }
 
 // UpdatePatient updates patient information with role-based access control
-func (s *PatientService) UpdatePatient(patientID uint, req *UpdatePatientRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent string) (*models.Patient, error) {
+func (s *PatientService) UpdatePatient(patientID uint, req *UpdatePatientRequest, updatedByUserID uint, updatedByRole models.UserRole, ipAddress, userAgent, accessReason string, purpose models.PurposeOfUse) (models.Projection, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
 	// Only doctors and nurses can update patients (different permissions)
 	if !s.canUpdatePatientData(updatedByRole) {
-		s.audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role_for_update")
+		audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role_for_update")
 		return nil, fmt.Errorf("insufficient permissions to update patient")
 	}
 
@@ -195,52 +401,104 @@ func (s *PatientService) UpdatePatient(patientID uint, req *UpdatePatientRequest
 		return nil, fmt.Errorf("failed to retrieve patient: %w", err)
 	}
 
-	// Build update map
-	updates := make(map[string]interface{})
+	// Confidential charts need a stated reason to change, as to read
+	if err := s.careTeam.AuthorizeConfidentialAccess(&patient, updatedByUserID, models.ActionUpdate, ipAddress, userAgent, accessReason, false, purpose); err != nil {
+		return nil, err
+	}
+
+	// Apply changes to the model rather than a map so that the phone number is
+	// encrypted and the blind indexes are recomputed
+	original := patient
+	updated := make([]string, 0, 8)
 	
 	if req.FirstName != nil {
-		updates["first_name"] = *req.FirstName
+		patient.FirstName = *req.FirstName
+		updated = append(updated, "first_name")
 	}
 	if req.LastName != nil {
-		updates["last_name"] = *req.LastName
+		patient.LastName = *req.LastName
+		updated = append(updated, "last_name")
 	}
 	if req.DateOfBirth != nil {
-		updates["date_of_birth"] = *req.DateOfBirth
+		patient.DateOfBirth = *req.DateOfBirth
+		updated = append(updated, "date_of_birth")
 	}
 	if req.Phone != nil {
-		updates["phone"] = *req.Phone
+		patient.Phone = *req.Phone
+		updated = append(updated, "phone")
 	}
 	if req.Address != nil {
-		updates["address"] = *req.Address
+		patient.Address = *req.Address
+		updated = append(updated, "address")
 	}
 	if req.EmergencyContact != nil {
-		updates["emergency_contact"] = *req.EmergencyContact
+		patient.EmergencyContact = *req.EmergencyContact
+		updated = append(updated, "emergency_contact")
+	}
+
+	// Only doctors may change the confidentiality flags
+	if (req.Confidential != nil || req.EmployeeUserID != nil) && updatedByRole != models.RoleDoctor {
+		audit.LogUnauthorizedAccess(updatedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "confidential_flag_change_denied")
+		return nil, fmt.Errorf("insufficient permissions to change patient confidentiality")
+	}
+	if req.Confidential != nil {
+		patient.Confidential = *req.Confidential
+		updated = append(updated, "confidential")
+	}
+	if req.EmployeeUserID != nil {
+		if err := s.validateEmployeeUser(*req.EmployeeUserID); err != nil {
+			return nil, err
+		}
+		patient.EmployeeUserID = req.EmployeeUserID
+		updated = append(updated, "employee_user_id")
 	}
 
 	// Apply updates if any
-	if len(updates) > 0 {
-		updates["updated_at"] = time.Now()
-		if err := s.db.Model(&patient).Updates(updates).Error; err != nil {
+	if len(updated) > 0 {
+		patient.UpdatedAt = time.Now()
+		updated = append(updated, "updated_at", "phone_index", "demographic_index")
+
+		revisionReason := req.Reason
+		if revisionReason == "" {
+			revisionReason = "patient_updated"
+		}
+
+		// Every change is kept as an immutable revision
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := ensureBaselineRevision(tx, models.ResourcePatient, patient.ID, &original, original.UpdatedAt, updatedByUserID); err != nil {
+				return err
+			}
+			if err := tx.Model(&patient).Select(updated).Updates(&patient).Error; err != nil {
+				return err
+			}
+			return recordRevision(tx, models.ResourcePatient, patient.ID, &patient, updatedByUserID, revisionReason)
+		})
+		if err != nil {
 			return nil, fmt.Errorf("failed to update patient: %w", err)
 		}
 	}
 
 	// Log patient update
-	s.audit.LogPatientAccess(updatedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, "patient_updated")
+	audit.LogPatientAccess(updatedByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, "patient_updated")
 
 	// Reload patient data
 	s.db.Where("id = ?", patientID).First(&patient)
 
-	// Apply role-based filtering before returning
+	// Apply role-based filtering and the field policy before returning
 	sanitizedPatient := patient.SanitizeForRole(updatedByRole)
 	if sanitizedPatient == nil {
 		return nil, fmt.Errorf("access denied to updated patient data")
 	}
 
-	return sanitizedPatient, nil
+	projection, disclosed := policy.Project(models.ResourcePatient, updatedByRole, sanitizedPatient, nil)
+	audit.LogPatientDisclosure(updatedByUserID, patientID, ipAddress, userAgent, false, s.careTeam.DelegatedAccessReason(patientID, updatedByUserID), disclosed)
+
+	return projection, nil
 }
 
-// DeletePatient soft deletes a patient (admin only)
+// DeletePatient soft deletes a patient and their medical records (admin only).
+// The chart stays in the database, hidden from every query, until it is
+// restored or purged.
 func (s *PatientService) DeletePatient(patientID uint, deletedByUserID uint, deletedByRole models.UserRole, ipAddress, userAgent string) error {
 	// Only admins can delete patients
 	if deletedByRole != models.RoleAdmin {
@@ -256,8 +514,17 @@ func (s *PatientService) DeletePatient(patientID uint, deletedByUserID uint, del
 		return fmt.Errorf("failed to retrieve patient: %w", err)
 	}
 
-	// Soft delete the patient
-	if err := s.db.Delete(&patient).Error; err != nil {
+	// Records share the patient's deletion time so that a restore brings back
+	// exactly the records removed with the patient
+	deletedAt := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.MedicalRecord{}).Where("patient_id = ?", patientID).
+			UpdateColumn("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&patient).UpdateColumn("deleted_at", deletedAt).Error
+	})
+	if err != nil {
 		return fmt.Errorf("failed to delete patient: %w", err)
 	}
 
@@ -267,20 +534,66 @@ func (s *PatientService) DeletePatient(patientID uint, deletedByUserID uint, del
 	return nil
 }
 
+// RestorePatient undoes a soft delete along with the medical records deleted
+// with the patient, and cancels any pending purge request (admin only)
+func (s *PatientService) RestorePatient(patientID uint, restoredByUserID uint, restoredByRole models.UserRole, ipAddress, userAgent string) error {
+	if restoredByRole != models.RoleAdmin {
+		s.audit.LogUnauthorizedAccess(restoredByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role_for_restore")
+		return fmt.Errorf("insufficient permissions to restore patient")
+	}
+
+	var patient models.Patient
+	if err := s.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", patientID).First(&patient).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("deleted patient not found")
+		}
+		return fmt.Errorf("failed to retrieve patient: %w", err)
+	}
+	if patient.IsMerged() {
+		return fmt.Errorf("patient was merged into patient %d; undo the merge instead", *patient.MergedIntoID)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.MedicalRecord{}).
+			Where("patient_id = ? AND deleted_at = ?", patientID, patient.DeletedAt.Time).
+			UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PatientPurgeRequest{}).
+			Where("patient_id = ? AND status = ?", patientID, models.PurgeStatusPending).
+			Update("status", models.PurgeStatusCancelled).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&patient).UpdateColumn("deleted_at", nil).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore patient: %w", err)
+	}
+
+	s.audit.LogPatientAccess(restoredByUserID, patientID, models.ActionUpdate, ipAddress, userAgent, false, "patient_restored")
+
+	return nil
+}
+
 // GetPatientWithMedicalRecords retrieves a patient with their medical records
-func (s *PatientService) GetPatientWithMedicalRecords(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool) (*models.Patient, error) {
+func (s *PatientService) GetPatientWithMedicalRecords(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) (models.Projection, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
 	// Check permissions
 	if !s.canAccessPatientData(requestedByRole) {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role")
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role")
 		return nil, fmt.Errorf("insufficient permissions to access patient data")
 	}
 
 	var patient models.Patient
-	query := s.db.Where("id = ?", patientID).Preload("MedicalRecords")
+	query := s.db.Where("id = ?", patientID).Preload("Identifiers")
 
 	// Nurses can't see critical medical records unless emergency access
 	if requestedByRole == models.RoleNurse && !emergencyAccess {
 		query = query.Preload("MedicalRecords", "severity != ?", models.SeverityCritical)
+	} else {
+		query = query.Preload("MedicalRecords")
 	}
 
 	if err := query.First(&patient).Error; err != nil {
@@ -290,36 +603,54 @@ func (s *PatientService) GetPatientWithMedicalRecords(patientID uint, requestedB
 		return nil, fmt.Errorf("failed to retrieve patient with records: %w", err)
 	}
 
-	// Log access to patient and medical records
+	// Confidential charts need a stated reason and are always reported
+	if err := s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose); err != nil {
+		return nil, err
+	}
+
 	reason := ""
 	if emergencyAccess {
 		reason = "emergency_access"
+	} else {
+		reason = s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
 	}
-	s.audit.LogPatientAccess(requestedByUserID, patientID, models.ActionView, ipAddress, userAgent, emergencyAccess, reason)
 
-	// Sanitize medical records based on role
+	// Drop withheld records, then sanitize the rest based on role
 	var sanitizedRecords []models.MedicalRecord
-	for _, record := range patient.MedicalRecords {
-		if sanitized := record.SanitizeForRole(requestedByRole); sanitized != nil {
-			sanitizedRecords = append(sanitizedRecords, *sanitized)
+	for _, record := range s.consents.FilterDisclosable(patient.MedicalRecords, requestedByUserID) {
+		sanitized := record.SanitizeForRole(requestedByRole)
+		if sanitized == nil {
+			continue
 		}
+		if record.IsSensitive() {
+			audit.LogSensitiveRecordAccess(requestedByUserID, patientID, record.ID, record.Sensitivity, models.ActionView, ipAddress, userAgent, emergencyAccess, reason)
+		}
+		sanitizedRecords = append(sanitizedRecords, *sanitized)
 	}
 	patient.MedicalRecords = sanitizedRecords
 
 	// Apply role-based filtering to patient data
 	sanitizedPatient := patient.SanitizeForRole(requestedByRole)
 	if sanitizedPatient == nil {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "role_based_filtering_denied")
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "role_based_filtering_denied")
 		return nil, fmt.Errorf("access denied to patient data")
 	}
 
-	return sanitizedPatient, nil
+	projection, disclosed := policy.Project(models.ResourcePatient, requestedByRole, sanitizedPatient, fields)
+
+	// Log access to patient and medical records with the fields actually disclosed
+	audit.LogPatientDisclosure(requestedByUserID, patientID, ipAddress, userAgent, emergencyAccess, reason, disclosed)
+
+	return projection, nil
 }
 
 // SearchPatientsByName searches patients by name with fuzzy matching
-func (s *PatientService) SearchPatientsByName(name string, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, limit int) ([]models.Patient, error) {
+func (s *PatientService) SearchPatientsByName(name string, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, limit int, purpose models.PurposeOfUse, fields []string) ([]models.Projection, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
 	if !s.canAccessPatientData(requestedByRole) {
-		s.audit.LogUnauthorizedAccess(requestedByUserID, "patients_search", ipAddress, userAgent, "insufficient_role")
+		audit.LogUnauthorizedAccess(requestedByUserID, "patients_search", ipAddress, userAgent, "insufficient_role")
 		return nil, fmt.Errorf("insufficient permissions to search patients")
 	}
 
@@ -342,10 +673,15 @@ func (s *PatientService) SearchPatientsByName(name string, requestedByUserID uin
 		}
 	}
 
+	// Mask confidential patients outside the caller's care team
+	filteredPatients = s.careTeam.MaskForSearch(filteredPatients, requestedByUserID, ipAddress, userAgent, "patients_search", purpose)
+
+	projections, disclosed := policy.ProjectAll(models.ResourcePatient, requestedByRole, filteredPatients, fields)
+
 	// Log search
-	s.audit.LogUserAction(requestedByUserID, models.ActionView, "patients_search", ipAddress, userAgent, true, fmt.Sprintf("searched_name:%s", name))
+	audit.LogListDisclosure(requestedByUserID, "patients_search", ipAddress, userAgent, fmt.Sprintf("searched_name:%s", name), patientIDsOf(filteredPatients), disclosed)
 
-	return filteredPatients, nil
+	return projections, nil
 }
 
 // GetPatientStatistics returns patient statistics (admin only)
@@ -394,9 +730,162 @@ func (s *PatientService) GetPatientStatistics(requestedByRole models.UserRole) (
 	return stats, nil
 }
 
+// validateEmployeeUser checks that an employee-patient link points at a real user
+func (s *PatientService) validateEmployeeUser(userID uint) error {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return fmt.Errorf("employee user not found")
+	}
+	return nil
+}
+
+// GetPatientRevisions lists a patient's demographic revisions, newest first
+func (s *PatientService) GetPatientRevisions(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, page, limit int) ([]RevisionSummary, int64, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	if _, err := s.authorizePatientAccess(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose); err != nil {
+		return nil, 0, err
+	}
+
+	revisions, total, err := listRevisions(s.db, policy, requestedByRole, models.ResourcePatient, patientID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	audit.LogPatientAccess(requestedByUserID, patientID, models.ActionView, ipAddress, userAgent, emergencyAccess,
+		historyReason(s.disclosureReason(patientID, requestedByUserID, emergencyAccess), "revisions_listed"))
+
+	return revisions, total, nil
+}
+
+// GetPatientAsOf returns the patient's demographics as they stood at the
+// given time
+func (s *PatientService) GetPatientAsOf(patientID uint, at time.Time, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse, fields []string) (models.Projection, *RevisionSummary, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	patient, err := s.authorizePatientAccess(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revision, err := revisionAsOf(s.db, models.ResourcePatient, patientID, at)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projection, disclosed, err := s.projectRevision(patient, revision, requestedByRole, policy, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reason := historyReason(s.disclosureReason(patientID, requestedByUserID, emergencyAccess), fmt.Sprintf("revision_%d", revision.Version))
+	audit.LogPatientDisclosure(requestedByUserID, patientID, ipAddress, userAgent, emergencyAccess, reason, disclosed)
+
+	summary := summarizeRevision(revision, policy, requestedByRole)
+	return projection, &summary, nil
+}
+
+// DiffPatientRevisions compares two demographic revisions field by field
+func (s *PatientService) DiffPatientRevisions(patientID uint, fromVersion, toVersion int, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*RevisionDiff, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.fieldPolicy.ForPurpose(purpose)
+
+	patient, err := s.authorizePatientAccess(patientID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := revisionVersion(s.db, models.ResourcePatient, patientID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := revisionVersion(s.db, models.ResourcePatient, patientID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	fromProjection, _, err := s.projectRevision(patient, from, requestedByRole, policy, nil)
+	if err != nil {
+		return nil, err
+	}
+	toProjection, _, err := s.projectRevision(patient, to, requestedByRole, policy, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := models.DiffProjections(models.ResourcePatient, fromProjection, toProjection)
+
+	reason := historyReason(s.disclosureReason(patientID, requestedByUserID, emergencyAccess), fmt.Sprintf("revision_diff_%d_%d", fromVersion, toVersion))
+	audit.LogPatientDisclosure(requestedByUserID, patientID, ipAddress, userAgent, emergencyAccess, reason, changedFieldNames(changes))
+
+	return &RevisionDiff{
+		From:    summarizeRevision(from, policy, requestedByRole),
+		To:      summarizeRevision(to, policy, requestedByRole),
+		Changes: changes,
+	}, nil
+}
+
+// authorizePatientAccess loads a patient the caller may read, applying the
+// role and confidential-chart rules
+func (s *PatientService) authorizePatientAccess(patientID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) (*models.Patient, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	// Check if user has permission to access patient data
+	if !s.canAccessPatientData(requestedByRole) {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("patient:%d", patientID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to access patient data")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("patient not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve patient: %w", err)
+	}
+
+	// Confidential charts need a stated reason and are always reported
+	if err := s.careTeam.AuthorizeConfidentialAccess(&patient, requestedByUserID, models.ActionView, ipAddress, userAgent, accessReason, emergencyAccess, purpose); err != nil {
+		return nil, err
+	}
+
+	return &patient, nil
+}
+
+// projectRevision renders a historical version of the patient for the caller
+func (s *PatientService) projectRevision(patient *models.Patient, revision *models.Revision, requestedByRole models.UserRole, policy models.FieldPolicy, fields []string) (models.Projection, []string, error) {
+	historical := *patient
+	if err := revision.ApplyTo(&historical); err != nil {
+		return nil, nil, err
+	}
+	historical.ID = patient.ID
+
+	sanitized := historical.SanitizeForRole(requestedByRole)
+	if sanitized == nil {
+		return nil, nil, fmt.Errorf("access denied to patient data")
+	}
+
+	projection, disclosed := policy.Project(models.ResourcePatient, requestedByRole, sanitized, fields)
+	return projection, disclosed, nil
+}
+
+// disclosureReason records why access outside the usual rules was allowed
+func (s *PatientService) disclosureReason(patientID, requestedByUserID uint, emergencyAccess bool) string {
+	if emergencyAccess {
+		return "emergency_access"
+	}
+	return s.careTeam.DelegatedAccessReason(patientID, requestedByUserID)
+}
+
 // Helper methods for role-based access control
 func (s *PatientService) canAccessPatientData(role models.UserRole) bool {
-	return role == models.RoleDoctor || role == models.RoleNurse
+	switch role {
+	case models.RoleDoctor, models.RoleNurse, models.RoleFrontDesk, models.RoleBilling:
+		return true
+	}
+	return false
 }
 
 func (s *PatientService) canUpdatePatientData(role models.UserRole) bool {
@@ -413,4 +902,12 @@ func (s *PatientService) canDeletePatientData(role models.UserRole) bool {
 
 func (s *PatientService) canAccessSensitiveData(role models.UserRole) bool {
 	return role == models.RoleDoctor
-}
\ No newline at end of file
+}
+// patientIDsOf lists the IDs of patients returned by a list or search
+func patientIDsOf(patients []models.Patient) []uint {
+	ids := make([]uint, len(patients))
+	for i, patient := range patients {
+		ids[i] = patient.ID
+	}
+	return ids
+}
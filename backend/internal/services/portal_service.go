@@ -0,0 +1,362 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// portalAccessReason marks audit rows written for a patient reading their
+// own chart through the portal
+const portalAccessReason = "patient_portal"
+
+// PortalService serves a patient portal account its own chart and the charts
+// it is a proxy for. A patient ID of zero means the account's own chart.
+type PortalService struct {
+	db          *gorm.DB
+	audit       *AuditService
+	fieldPolicy models.FieldPolicy
+	proxyRules  models.ProxyAgeRules
+}
+
+// PortalDependent is a patient whose chart a portal account may view as a
+// proxy
+type PortalDependent struct {
+	PatientID    uint                     `json:"patient_id"`
+	PublicID     string                   `json:"public_id"`
+	FirstName    string                   `json:"first_name"`
+	LastName     string                   `json:"last_name"`
+	Relationship models.ProxyRelationship `json:"relationship"`
+	Scope        models.ProxyScope        `json:"scope"`
+	EndsAt       *time.Time               `json:"ends_at,omitempty"`
+}
+
+// portalSubject is the chart a portal request reads and, for proxies, the
+// grant it is read through
+type portalSubject struct {
+	patient *models.Patient
+	proxy   *models.PatientProxy
+}
+
+// reason is the audit reason for reads of the subject's chart
+func (ps *portalSubject) reason() string {
+	if ps.proxy != nil {
+		return ps.proxy.AuditReason()
+	}
+	return portalAccessReason
+}
+
+// PortalAccessLogEntry is one access to the patient's chart as shown to the
+// patient. Staff IP addresses, devices and free-text reasons are left out.
+type PortalAccessLogEntry struct {
+	Timestamp       time.Time           `json:"timestamp"`
+	UserName        string              `json:"user_name"`
+	UserRole        models.UserRole     `json:"user_role"`
+	Action          models.AuditAction  `json:"action"`
+	Resource        string              `json:"resource"`
+	Purpose         models.PurposeOfUse `json:"purpose,omitempty"`
+	EmergencyUse    bool                `json:"emergency_use"`
+	DisclosedFields []string            `json:"disclosed_fields,omitempty"`
+}
+
+func NewPortalService(db *gorm.DB, audit *AuditService, fieldPolicy models.FieldPolicy, proxyRules models.ProxyAgeRules) *PortalService {
+	return &PortalService{
+		db:          db,
+		audit:       audit,
+		fieldPolicy: fieldPolicy,
+		proxyRules:  proxyRules,
+	}
+}
+
+// GetMyPatient returns the patient's demographics
+func (s *PortalService) GetMyPatient(userID uint, role models.UserRole, patientID uint, ipAddress, userAgent string) (models.Projection, error) {
+	subject, err := s.portalSubject(userID, role, patientID, models.PortalSectionDemographics, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	patient := subject.patient
+	if err := s.db.Where("patient_id = ?", patient.ID).Order("id").Find(&patient.Identifiers).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve patient identifiers: %w", err)
+	}
+
+	projection, disclosed := s.fieldPolicy.Project(models.ResourcePatient, models.RolePatient, patient, nil)
+	s.audit.WithPurpose(models.PurposePatientRequest).LogPatientDisclosure(userID, patient.ID, ipAddress, userAgent, false, subject.reason(), disclosed)
+
+	return projection, nil
+}
+
+// GetMyRecords lists the patient's signed medical records, most recent first.
+// Drafts and records awaiting co-signature are not part of the chart yet.
+// Proxies never see sensitive records, which include those an adolescent
+// shared in confidence.
+func (s *PortalService) GetMyRecords(userID uint, role models.UserRole, patientID uint, ipAddress, userAgent string, page, limit int) ([]models.Projection, int64, error) {
+	subject, err := s.portalSubject(userID, role, patientID, models.PortalSectionRecords, ipAddress, userAgent)
+	if err != nil {
+		return nil, 0, err
+	}
+	patient := subject.patient
+
+	db := s.db.Model(&models.MedicalRecord{}).Where("patient_id = ? AND status = ?", patient.ID, models.RecordStatusSigned)
+	if subject.proxy != nil {
+		db = db.Where("sensitivity = ?", models.SensitivityNormal)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count medical records: %w", err)
+	}
+
+	var records []models.MedicalRecord
+	offset := (page - 1) * limit
+	if err := db.Preload("Doctor").Preload("Diagnoses", models.PreloadDiagnoses).
+		Order("created_at DESC").Offset(offset).Limit(limit).Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve medical records: %w", err)
+	}
+
+	return s.projectList(models.ResourceMedicalRecord, "records", records, subject, userID, ipAddress, userAgent), total, nil
+}
+
+// GetMyMedications lists the patient's medication orders, most recent first.
+// Orders entered in error are not shown.
+func (s *PortalService) GetMyMedications(userID uint, role models.UserRole, patientID uint, ipAddress, userAgent string) ([]models.Projection, error) {
+	subject, err := s.portalSubject(userID, role, patientID, models.PortalSectionMedications, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	patient := subject.patient
+
+	var orders []models.MedicationOrder
+	if err := s.db.Where("patient_id = ?", patient.ID).Preload("Prescriber").Order("start_date DESC").Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve medications: %w", err)
+	}
+
+	return s.projectList(models.ResourceMedication, "medications", orders, subject, userID, ipAddress, userAgent), nil
+}
+
+// GetMyResults lists the patient's vital signs and lab results, most recent
+// first
+func (s *PortalService) GetMyResults(userID uint, role models.UserRole, patientID uint, ipAddress, userAgent string, page, limit int) ([]models.Projection, int64, error) {
+	subject, err := s.portalSubject(userID, role, patientID, models.PortalSectionResults, ipAddress, userAgent)
+	if err != nil {
+		return nil, 0, err
+	}
+	patient := subject.patient
+
+	db := s.db.Model(&models.Observation{}).Where("patient_id = ?", patient.ID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count results: %w", err)
+	}
+
+	var observations []models.Observation
+	offset := (page - 1) * limit
+	if err := db.Order("effective_at DESC").Offset(offset).Limit(limit).Find(&observations).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve results: %w", err)
+	}
+
+	return s.projectList(models.ResourceObservation, "results", observations, subject, userID, ipAddress, userAgent), total, nil
+}
+
+// GetMyAccessLog lists who accessed the account's own chart, most recent
+// first. Reading the log is itself audited but the patient's own portal reads
+// are included so the log is complete. Proxies cannot read it.
+func (s *PortalService) GetMyAccessLog(userID uint, role models.UserRole, ipAddress, userAgent string, page, limit int) ([]PortalAccessLogEntry, int64, error) {
+	patient, err := s.portalPatient(userID, role, ipAddress, userAgent)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	chartIDs, err := mergedChartIDs(s.db, patient.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	db := s.db.Model(&models.AuditLog{}).Where("patient_id IN ?", chartIDs)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count access log: %w", err)
+	}
+
+	var logs []models.AuditLog
+	query := db.Preload("User").Order("timestamp DESC")
+	if limit > 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve access log: %w", err)
+	}
+
+	entries := make([]PortalAccessLogEntry, 0, len(logs))
+	for _, log := range logs {
+		entry := PortalAccessLogEntry{
+			Timestamp:    log.Timestamp,
+			UserName:     log.User.Name,
+			UserRole:     log.User.Role,
+			Action:       log.Action,
+			Resource:     log.Resource,
+			Purpose:      log.Purpose,
+			EmergencyUse: log.EmergencyUse,
+		}
+		if log.DisclosedFields != "" {
+			entry.DisclosedFields = strings.Split(log.DisclosedFields, ",")
+		}
+		entries = append(entries, entry)
+	}
+
+	s.audit.WithPurpose(models.PurposePatientRequest).LogPatientDataAccess(userID, patient.ID, fmt.Sprintf("access_log:patient_%d", patient.ID),
+		models.ActionView, ipAddress, userAgent, false, portalAccessReason, nil)
+
+	return entries, total, nil
+}
+
+// LogAccessLogDownload records that the patient exported their access log
+func (s *PortalService) LogAccessLogDownload(userID uint, role models.UserRole, ipAddress, userAgent string) error {
+	patient, err := s.portalPatient(userID, role, ipAddress, userAgent)
+	if err != nil {
+		return err
+	}
+	return s.audit.WithPurpose(models.PurposePatientRequest).LogPatientAccess(userID, patient.ID, models.ActionDownload, ipAddress, userAgent, false, "portal_access_log")
+}
+
+// portalPatient returns the patient linked to an active portal account.
+// Staff accounts have no chart of their own and are refused.
+func (s *PortalService) portalPatient(userID uint, role models.UserRole, ipAddress, userAgent string) (*models.Patient, error) {
+	if role != models.RolePatient {
+		s.audit.LogUnauthorizedAccess(userID, "patient_portal", ipAddress, userAgent, "not_a_portal_account")
+		return nil, fmt.Errorf("only patient portal accounts can use the portal")
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ? AND role = ? AND active = ?", userID, models.RolePatient, true).First(&user).Error; err != nil || user.PatientID == nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", *user.PatientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+	return &patient, nil
+}
+
+// GetMyDependents lists the patients the account can currently view as a
+// proxy, with the scope age rules leave it today
+func (s *PortalService) GetMyDependents(userID uint, role models.UserRole, ipAddress, userAgent string) ([]PortalDependent, error) {
+	if role != models.RolePatient {
+		s.audit.LogUnauthorizedAccess(userID, "patient_portal", ipAddress, userAgent, "not_a_portal_account")
+		return nil, fmt.Errorf("only patient portal accounts can use the portal")
+	}
+
+	now := time.Now()
+	var proxies []models.PatientProxy
+	if err := s.db.Where("proxy_user_id = ? AND revoked_at IS NULL AND starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", userID, now, now).
+		Order("id").Find(&proxies).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve proxies: %w", err)
+	}
+
+	dependents := make([]PortalDependent, 0, len(proxies))
+	for _, proxy := range proxies {
+		var patient models.Patient
+		if err := s.db.Where("id = ?", proxy.PatientID).First(&patient).Error; err != nil {
+			continue
+		}
+		scope := proxy.EffectiveScopeAt(now, patient.DateOfBirth, s.proxyRules)
+		if scope == models.ProxyScopeNone {
+			continue
+		}
+		dependents = append(dependents, PortalDependent{
+			PatientID:    patient.ID,
+			PublicID:     patient.PublicID,
+			FirstName:    patient.FirstName,
+			LastName:     patient.LastName,
+			Relationship: proxy.Relationship,
+			Scope:        scope,
+			EndsAt:       proxy.EndsAt,
+		})
+	}
+
+	var disclosed []string
+	dependentIDs := make([]uint, len(dependents))
+	for i, dependent := range dependents {
+		dependentIDs[i] = dependent.PatientID
+	}
+	if len(dependents) > 0 {
+		disclosed = []string{"first_name", "last_name", "public_id"}
+	}
+	s.audit.WithPurpose(models.PurposePatientRequest).LogListDisclosure(userID, "portal_dependents", ipAddress, userAgent, portalAccessReason, dependentIDs, disclosed)
+
+	return dependents, nil
+}
+
+// portalSubject resolves the chart a portal request reads. A patient ID of
+// zero, or the account's own, is the account's own chart; any other patient
+// needs a proxy grant whose scope today covers the section. Missing grants
+// are reported as a missing patient so the portal does not reveal which
+// charts exist.
+func (s *PortalService) portalSubject(userID uint, role models.UserRole, patientID uint, section string, ipAddress, userAgent string) (*portalSubject, error) {
+	if patientID == 0 {
+		patient, err := s.portalPatient(userID, role, ipAddress, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		return &portalSubject{patient: patient}, nil
+	}
+
+	if role != models.RolePatient {
+		s.audit.LogUnauthorizedAccess(userID, "patient_portal", ipAddress, userAgent, "not_a_portal_account")
+		return nil, fmt.Errorf("only patient portal accounts can use the portal")
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ? AND role = ? AND active = ?", userID, models.RolePatient, true).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+	if user.PatientID != nil && *user.PatientID == patientID {
+		return s.portalSubject(userID, role, 0, section, ipAddress, userAgent)
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", patientID).First(&patient).Error; err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	now := time.Now()
+	var proxies []models.PatientProxy
+	if err := s.db.Where("proxy_user_id = ? AND patient_id = ? AND revoked_at IS NULL", userID, patientID).Find(&proxies).Error; err != nil {
+		return nil, fmt.Errorf("failed to check proxy access: %w", err)
+	}
+	for i := range proxies {
+		scope := proxies[i].EffectiveScopeAt(now, patient.DateOfBirth, s.proxyRules)
+		if scope == models.ProxyScopeNone {
+			continue
+		}
+		if !scope.Allows(section) {
+			s.audit.LogUnauthorizedAccess(userID, fmt.Sprintf("portal_%s:patient_%d", section, patientID), ipAddress, userAgent, "proxy_scope_"+string(scope))
+			return nil, fmt.Errorf("proxy access does not include %s", section)
+		}
+		return &portalSubject{patient: &patient, proxy: &proxies[i]}, nil
+	}
+
+	s.audit.LogUnauthorizedAccess(userID, fmt.Sprintf("portal:patient_%d", patientID), ipAddress, userAgent, "no_active_proxy")
+	return nil, fmt.Errorf("patient not found")
+}
+
+// projectList renders entries through the patient's field rules and logs the
+// fields disclosed. Proxy reads are logged under the proxy's account on
+// behalf of the patient.
+func (s *PortalService) projectList(resource, list string, entries interface{}, subject *portalSubject, userID uint, ipAddress, userAgent string) []models.Projection {
+	projections, disclosed := s.fieldPolicy.ProjectAll(resource, models.RolePatient, entries, nil)
+	if projections == nil {
+		projections = []models.Projection{}
+	}
+
+	patientID := subject.patient.ID
+	s.audit.WithPurpose(models.PurposePatientRequest).LogPatientDataAccess(userID, patientID, fmt.Sprintf("portal_%s:patient_%d", list, patientID),
+		models.ActionView, ipAddress, userAgent, false, subject.reason(), disclosed)
+
+	return projections
+}
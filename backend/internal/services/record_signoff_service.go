@@ -0,0 +1,272 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"healthsecure/configs"
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RecordSignoffService signs, cosigns and amends medical records. Signing
+// locks a record; later corrections are made through addenda.
+type RecordSignoffService struct {
+	db      *gorm.DB
+	audit   *AuditService
+	records *MedicalRecordService
+	config  *configs.Config
+}
+
+type SignRecordRequest struct {
+	CosignerID *uint `json:"cosigner_id,omitempty"`
+}
+
+type CreateAddendumRequest struct {
+	Content string `json:"content" binding:"required"`
+	Reason  string `json:"reason"`
+}
+
+// WorkQueueItem identifies a record needing the user's signature without
+// disclosing its clinical content
+type WorkQueueItem struct {
+	RecordID  uint                 `json:"record_id"`
+	PatientID uint                 `json:"patient_id"`
+	DoctorID  uint                 `json:"doctor_id"`
+	Status    models.RecordStatus  `json:"status"`
+	Severity  models.SeverityLevel `json:"severity"`
+	CreatedAt time.Time            `json:"created_at"`
+	SignedAt  *time.Time           `json:"signed_at,omitempty"`
+}
+
+// WorkQueue lists a doctor's overdue unsigned drafts and the residents'
+// records waiting for their cosignature
+type WorkQueue struct {
+	UnsignedDrafts []WorkQueueItem `json:"unsigned_drafts"`
+	AwaitingCosign []WorkQueueItem `json:"awaiting_cosign"`
+	OverdueAfter   string          `json:"overdue_after"`
+}
+
+func NewRecordSignoffService(db *gorm.DB, audit *AuditService, records *MedicalRecordService, config *configs.Config) *RecordSignoffService {
+	return &RecordSignoffService{
+		db:      db,
+		audit:   audit,
+		records: records,
+		config:  config,
+	}
+}
+
+// SignMedicalRecord signs and locks a draft for its author. Residents must
+// name the attending who will cosign.
+func (s *RecordSignoffService) SignMedicalRecord(recordID uint, req *SignRecordRequest, signedByUserID uint, signedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.MedicalRecord, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if signedByRole != models.RoleDoctor {
+		audit.LogUnauthorizedAccess(signedByUserID, fmt.Sprintf("medical_record:%d:sign", recordID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to sign medical record")
+	}
+
+	record, err := s.loadRecord(recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	var signer models.User
+	if err := s.db.Where("id = ?", signedByUserID).First(&signer).Error; err != nil {
+		return nil, fmt.Errorf("signer not found")
+	}
+
+	if record.DoctorID != signer.ID {
+		audit.LogUnauthorizedAccess(signedByUserID, fmt.Sprintf("medical_record:%d:sign", recordID), ipAddress, userAgent, "not_creating_doctor")
+		return nil, fmt.Errorf("only the authoring doctor can sign this record")
+	}
+
+	if req.CosignerID != nil && signer.IsResident() {
+		var cosigner models.User
+		if err := s.db.Where("id = ? AND active = ?", *req.CosignerID, true).First(&cosigner).Error; err != nil {
+			return nil, fmt.Errorf("cosigner not found")
+		}
+		if !cosigner.IsAttending() {
+			return nil, fmt.Errorf("cosigner must be an attending physician")
+		}
+	}
+
+	if err := record.Sign(&signer, req.CosignerID); err != nil {
+		return nil, err
+	}
+
+	// Only a draft may be signed, even if another request got there first
+	result := s.db.Model(record).Where("status = ?", models.RecordStatusDraft).
+		Select("status", "signed_by", "signed_at", "content_hash", "cosigner_id").Omit("Diagnoses").Updates(record)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to sign medical record: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, models.ErrRecordLocked
+	}
+
+	reason := "medical_record_signed"
+	if record.Status == models.RecordStatusPendingCosign {
+		reason = fmt.Sprintf("medical_record_signed:awaiting_cosign_by_%d", *record.CosignerID)
+	}
+	s.records.logRecordAccess(record, signedByUserID, models.ActionUpdate, ipAddress, userAgent, false, reason, purpose)
+
+	return record, nil
+}
+
+// CosignMedicalRecord completes sign-off of a resident's record by the
+// attending it names
+func (s *RecordSignoffService) CosignMedicalRecord(recordID uint, cosignedByUserID uint, cosignedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*models.MedicalRecord, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if cosignedByRole != models.RoleDoctor {
+		audit.LogUnauthorizedAccess(cosignedByUserID, fmt.Sprintf("medical_record:%d:cosign", recordID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to cosign medical record")
+	}
+
+	record, err := s.loadRecord(recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attending models.User
+	if err := s.db.Where("id = ?", cosignedByUserID).First(&attending).Error; err != nil {
+		return nil, fmt.Errorf("cosigner not found")
+	}
+
+	if err := record.Cosign(&attending); err != nil {
+		audit.LogUnauthorizedAccess(cosignedByUserID, fmt.Sprintf("medical_record:%d:cosign", recordID), ipAddress, userAgent, "cosign_rejected")
+		return nil, err
+	}
+
+	result := s.db.Model(record).Where("status = ?", models.RecordStatusPendingCosign).
+		Select("status", "cosigned_by", "cosigned_at").Omit("Diagnoses").Updates(record)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cosign medical record: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("record is not awaiting cosignature")
+	}
+
+	s.records.logRecordAccess(record, cosignedByUserID, models.ActionUpdate, ipAddress, userAgent, false, "medical_record_cosigned", purpose)
+
+	return record, nil
+}
+
+// AddAddendum appends a signed correction to a locked record. Any doctor who
+// may read the record can amend it.
+func (s *RecordSignoffService) AddAddendum(recordID uint, req *CreateAddendumRequest, authorID uint, authorRole models.UserRole, ipAddress, userAgent, accessReason string, purpose models.PurposeOfUse) (*models.RecordAddendum, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if authorRole != models.RoleDoctor {
+		audit.LogUnauthorizedAccess(authorID, fmt.Sprintf("medical_record:%d:addendum", recordID), ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to amend medical record")
+	}
+
+	record, err := s.records.authorizeRecordAccess(recordID, authorID, authorRole, ipAddress, userAgent, false, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !record.IsLocked() {
+		return nil, fmt.Errorf("draft records are edited directly; addenda are only added to signed records")
+	}
+
+	addendum := models.RecordAddendum{
+		RecordID: record.ID,
+		AuthorID: authorID,
+		Content:  req.Content,
+		Reason:   req.Reason,
+	}
+	if err := s.db.Create(&addendum).Error; err != nil {
+		return nil, fmt.Errorf("failed to add addendum: %w", err)
+	}
+
+	s.records.logRecordAccess(record, authorID, models.ActionCreate, ipAddress, userAgent, false, fmt.Sprintf("addendum_added:%d", addendum.ID), purpose)
+
+	return &addendum, nil
+}
+
+// GetAddenda lists a record's addenda, oldest first. Addenda carry clinical
+// narrative, so only roles that may read record notes see them.
+func (s *RecordSignoffService) GetAddenda(recordID uint, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, emergencyAccess bool, accessReason string, purpose models.PurposeOfUse) ([]models.RecordAddendum, error) {
+	audit := s.audit.WithPurpose(purpose)
+	policy := s.records.fieldPolicy.ForPurpose(purpose)
+
+	record, err := s.records.authorizeRecordAccess(recordID, requestedByUserID, requestedByRole, ipAddress, userAgent, emergencyAccess, accessReason, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !policy.Allows(models.ResourceMedicalRecord, requestedByRole, "notes") {
+		audit.LogUnauthorizedAccess(requestedByUserID, fmt.Sprintf("medical_record:%d:addenda", recordID), ipAddress, userAgent, "field_policy")
+		return nil, fmt.Errorf("insufficient permissions to view addenda")
+	}
+
+	var addenda []models.RecordAddendum
+	if err := s.db.Where("record_id = ?", record.ID).Preload("Author").Order("created_at ASC").Find(&addenda).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve addenda: %w", err)
+	}
+
+	reason := historyReason(s.records.disclosureReason(record.PatientID, requestedByUserID, emergencyAccess), "addenda_listed")
+	audit.LogMedicalRecordDisclosure(record, requestedByUserID, ipAddress, userAgent, emergencyAccess, reason, []string{"addenda"})
+
+	return addenda, nil
+}
+
+// GetWorkQueue lists the caller's drafts left unsigned past the configured
+// age and the records waiting for the caller's cosignature
+func (s *RecordSignoffService) GetWorkQueue(userID uint, role models.UserRole) (*WorkQueue, error) {
+	if role != models.RoleDoctor {
+		return nil, fmt.Errorf("insufficient permissions to view the signing work queue")
+	}
+
+	overdueAfter := s.config.Records.UnsignedDraftAfter
+
+	var drafts []models.MedicalRecord
+	if err := s.db.Where("doctor_id = ? AND status = ? AND created_at <= ?", userID, models.RecordStatusDraft, time.Now().Add(-overdueAfter)).
+		Order("created_at ASC").Find(&drafts).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve unsigned drafts: %w", err)
+	}
+
+	var pending []models.MedicalRecord
+	if err := s.db.Where("cosigner_id = ? AND status = ?", userID, models.RecordStatusPendingCosign).
+		Order("signed_at ASC").Find(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve records awaiting cosignature: %w", err)
+	}
+
+	return &WorkQueue{
+		UnsignedDrafts: workQueueItems(drafts),
+		AwaitingCosign: workQueueItems(pending),
+		OverdueAfter:   overdueAfter.String(),
+	}, nil
+}
+
+func (s *RecordSignoffService) loadRecord(recordID uint) (*models.MedicalRecord, error) {
+	var record models.MedicalRecord
+	// Coded diagnoses are part of the signed content
+	if err := s.db.Where("id = ?", recordID).Preload("Diagnoses", models.PreloadDiagnoses).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("medical record not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve medical record: %w", err)
+	}
+	return &record, nil
+}
+
+func workQueueItems(records []models.MedicalRecord) []WorkQueueItem {
+	items := make([]WorkQueueItem, 0, len(records))
+	for _, record := range records {
+		items = append(items, WorkQueueItem{
+			RecordID:  record.ID,
+			PatientID: record.PatientID,
+			DoctorID:  record.DoctorID,
+			Status:    record.Status,
+			Severity:  record.Severity,
+			CreatedAt: record.CreatedAt,
+			SignedAt:  record.SignedAt,
+		})
+	}
+	return items
+}
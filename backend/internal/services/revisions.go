@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"healthsecure/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RevisionSummary describes a revision without its contents
+type RevisionSummary struct {
+	Version       int       `json:"version"`
+	AuthorID      uint      `json:"author_id"`
+	AuthorName    string    `json:"author_name,omitempty"`
+	Reason        string    `json:"reason"`
+	ChangedFields []string  `json:"changed_fields"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RevisionDiff is the field-level difference between two revisions
+type RevisionDiff struct {
+	From    RevisionSummary      `json:"from"`
+	To      RevisionSummary      `json:"to"`
+	Changes []models.FieldChange `json:"changes"`
+}
+
+// ensureBaselineRevision snapshots the current state of a resource that was
+// written before revision history existed, so its first update does not lose
+// the prior version.
+func ensureBaselineRevision(tx *gorm.DB, resource string, resourceID uint, current interface{}, lastChanged time.Time, authorID uint) error {
+	var count int64
+	if err := tx.Model(&models.Revision{}).
+		Where("resource_type = ? AND resource_id = ?", resource, resourceID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	revision, err := models.NewRevision(resource, resourceID, current, nil, authorID, "revision_history_baseline")
+	if err != nil {
+		return err
+	}
+	revision.CreatedAt = lastChanged
+	return tx.Create(revision).Error
+}
+
+// recordRevision appends the resource's new state to its history
+func recordRevision(tx *gorm.DB, resource string, resourceID uint, value interface{}, authorID uint, reason string) error {
+	var previous *models.Revision
+	var latest models.Revision
+	err := tx.Where("resource_type = ? AND resource_id = ?", resource, resourceID).
+		Order("version DESC").First(&latest).Error
+	if err == nil {
+		previous = &latest
+	} else if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	revision, err := models.NewRevision(resource, resourceID, value, previous, authorID, reason)
+	if err != nil {
+		return err
+	}
+	return tx.Create(revision).Error
+}
+
+// listRevisions returns a resource's revisions, newest first, with changed
+// fields limited to those the role may see
+func listRevisions(db *gorm.DB, policy models.FieldPolicy, role models.UserRole, resource string, resourceID uint, page, limit int) ([]RevisionSummary, int64, error) {
+	query := db.Model(&models.Revision{}).Where("resource_type = ? AND resource_id = ?", resource, resourceID)
+
+	var total int64
+	query.Count(&total)
+
+	var revisions []models.Revision
+	offset := (page - 1) * limit
+	if err := query.Preload("Author").Order("version DESC").
+		Offset(offset).Limit(limit).Find(&revisions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve revisions: %w", err)
+	}
+
+	summaries := make([]RevisionSummary, 0, len(revisions))
+	for i := range revisions {
+		summaries = append(summaries, summarizeRevision(&revisions[i], policy, role))
+	}
+	return summaries, total, nil
+}
+
+// revisionAsOf returns the revision that was current at the given time
+func revisionAsOf(db *gorm.DB, resource string, resourceID uint, at time.Time) (*models.Revision, error) {
+	var revision models.Revision
+	if err := db.Where("resource_type = ? AND resource_id = ? AND created_at <= ?", resource, resourceID, at).
+		Preload("Author").Order("version DESC").First(&revision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("no revision exists at %s", at.Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("failed to retrieve revision: %w", err)
+	}
+	return &revision, nil
+}
+
+func revisionVersion(db *gorm.DB, resource string, resourceID uint, version int) (*models.Revision, error) {
+	var revision models.Revision
+	if err := db.Where("resource_type = ? AND resource_id = ? AND version = ?", resource, resourceID, version).
+		Preload("Author").First(&revision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("revision %d not found", version)
+		}
+		return nil, fmt.Errorf("failed to retrieve revision: %w", err)
+	}
+	return &revision, nil
+}
+
+func summarizeRevision(revision *models.Revision, policy models.FieldPolicy, role models.UserRole) RevisionSummary {
+	changed := make([]string, 0)
+	for _, field := range revision.ChangedFieldList() {
+		if policy.Allows(revision.ResourceType, role, field) {
+			changed = append(changed, field)
+		}
+	}
+
+	return RevisionSummary{
+		Version:       revision.Version,
+		AuthorID:      revision.AuthorID,
+		AuthorName:    revision.Author.Name,
+		Reason:        revision.Reason,
+		ChangedFields: changed,
+		CreatedAt:     revision.CreatedAt,
+	}
+}
+
+// changedFieldNames lists the fields disclosed by a diff
+func changedFieldNames(changes []models.FieldChange) []string {
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	return fields
+}
+
+// historyReason tags an audit reason with the historical view that was served
+func historyReason(reason, view string) string {
+	if reason == "" {
+		return view
+	}
+	return reason + ";" + view
+}
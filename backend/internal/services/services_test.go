@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"healthsecure/internal/database"
+	"healthsecure/internal/encryption"
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	testIP        = "10.0.0.1"
+	testUserAgent = "service-test"
+)
+
+func TestMain(m *testing.M) {
+	encryption.SetDefault(encryption.NewStaticKeyring("default", map[int][]byte{1: make([]byte, 32)}, 1, make([]byte, 32)))
+	os.Exit(m.Run())
+}
+
+// testModels are the tables the service tests create, in dependency order
+var testModels = []interface{}{
+	&models.User{},
+	&models.Patient{},
+	&models.PatientIdentifier{},
+	&models.MRNSequence{},
+	&models.PatientProxy{},
+	&models.Encounter{},
+	&models.NoteTemplate{},
+	&models.MedicalRecord{},
+	&models.RecordDiagnosis{},
+	&models.Attachment{},
+	&models.AuditLog{},
+	&models.EmergencyAccess{},
+	&models.PatientConsent{},
+	&models.CareTeamMember{},
+	&models.AccessDelegation{},
+	&models.AccessDelegationPatient{},
+	&models.PatientPurgeRequest{},
+	&models.PatientMerge{},
+	&models.PatientMergeRow{},
+	&models.Revision{},
+	&models.RecordAddendum{},
+	&models.AmendmentRequest{},
+	&models.MedicationOrder{},
+	&models.Allergy{},
+	&models.Problem{},
+	&models.Observation{},
+	&models.Notification{},
+	&database.SecurityEvent{},
+}
+
+// newTestDB opens an empty SQLite database with the application schema.
+// SQLite has no enum type, so enum columns are created as text.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, encryption.RegisterCallbacks(db))
+
+	for _, model := range testModels {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasPrefix(string(field.DataType), "enum") {
+				field.DataType = schema.String
+			}
+		}
+		require.NoError(t, db.AutoMigrate(model))
+	}
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return db
+}
+
+func createUser(t *testing.T, db *gorm.DB, role models.UserRole) *models.User {
+	t.Helper()
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	user := &models.User{
+		Email:    fmt.Sprintf("%s%d@hospital.test", role, count+1),
+		Password: "hashed",
+		Role:     role,
+		Name:     fmt.Sprintf("%s %d", role, count+1),
+	}
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
+
+func createPatient(t *testing.T, db *gorm.DB, confidential bool) *models.Patient {
+	t.Helper()
+
+	patient := &models.Patient{
+		FirstName:    "Jane",
+		LastName:     "Doe",
+		DateOfBirth:  time.Date(1980, 5, 15, 0, 0, 0, 0, time.UTC),
+		Confidential: confidential,
+	}
+	require.NoError(t, db.Create(patient).Error)
+	return patient
+}
+
+// createRecord files a signed record by the doctor on the patient's chart
+func createRecord(t *testing.T, db *gorm.DB, patient *models.Patient, doctor *models.User, sensitivity models.SensitivityLevel, severity models.SeverityLevel) *models.MedicalRecord {
+	t.Helper()
+
+	record := &models.MedicalRecord{
+		PatientID:   patient.ID,
+		DoctorID:    doctor.ID,
+		Diagnosis:   "Diagnosis",
+		Treatment:   "Treatment",
+		Notes:       "Notes",
+		Severity:    severity,
+		Sensitivity: sensitivity,
+	}
+	require.NoError(t, db.Create(record).Error)
+	require.NoError(t, record.Sign(doctor, nil))
+	require.NoError(t, db.Save(record).Error)
+	return record
+}
+
+func addToCareTeam(t *testing.T, db *gorm.DB, patient *models.Patient, user *models.User) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.CareTeamMember{PatientID: patient.ID, UserID: user.ID, AddedBy: user.ID}).Error)
+}
+
+// createEncounter opens an active outpatient encounter attended by the doctor
+func createEncounter(t *testing.T, db *gorm.DB, patient *models.Patient, attending *models.User) *models.Encounter {
+	t.Helper()
+
+	encounter := &models.Encounter{
+		PatientID:   patient.ID,
+		Class:       models.EncounterOutpatient,
+		Status:      models.EncounterActive,
+		AdmitAt:     time.Now(),
+		Facility:    "Main Hospital",
+		Department:  "Clinic",
+		AttendingID: attending.ID,
+		CreatedBy:   attending.ID,
+	}
+	require.NoError(t, db.Create(encounter).Error)
+	return encounter
+}
+
+// auditEntries returns the audit entries a user left, oldest first
+func auditEntries(t *testing.T, db *gorm.DB, userID uint) []models.AuditLog {
+	t.Helper()
+
+	var logs []models.AuditLog
+	require.NoError(t, db.Where("user_id = ?", userID).Order("id").Find(&logs).Error)
+	return logs
+}
+
+// lastAuditEntry returns the most recent audit entry a user left
+func lastAuditEntry(t *testing.T, db *gorm.DB, userID uint) models.AuditLog {
+	t.Helper()
+
+	logs := auditEntries(t, db, userID)
+	require.NotEmpty(t, logs)
+	return logs[len(logs)-1]
+}
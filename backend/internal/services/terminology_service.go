@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"healthsecure/internal/models"
+	"healthsecure/internal/terminology"
+
+	"gorm.io/gorm"
+)
+
+// maxDiagnosesPerRecord follows the UB-04 limit of one principal and
+// twenty-four other diagnosis codes
+const maxDiagnosesPerRecord = 25
+
+// TerminologyService serves code lookup and search from the local
+// terminology, and diagnosis reports grouped by code hierarchy
+type TerminologyService struct {
+	db    *gorm.DB
+	audit *AuditService
+	terms *terminology.Terminology
+}
+
+// DiagnosisInput codes one diagnosis on a medical record. The display is
+// taken from the terminology.
+type DiagnosisInput struct {
+	System  models.CodeSystem `json:"system" binding:"required"`
+	Code    string            `json:"code" binding:"required"`
+	Primary bool              `json:"primary"`
+}
+
+type TerminologySearchQuery struct {
+	Query  string `form:"q" binding:"required"`
+	System string `form:"system"`
+	Limit  int    `form:"limit,default=20"`
+}
+
+type DiagnosisReportQuery struct {
+	System string     `form:"system" binding:"required"`
+	Level  string     `form:"level"`
+	From   *time.Time `form:"from"`
+	To     *time.Time `form:"to"`
+}
+
+// ConceptDetail is a looked up concept with its ancestors, nearest first
+type ConceptDetail struct {
+	terminology.Concept
+	Ancestors []terminology.Concept `json:"ancestors"`
+	Version   string                `json:"version"`
+}
+
+// DiagnosisReportRow counts the records and distinct patients coded with a
+// code at or below Group
+type DiagnosisReportRow struct {
+	Group    terminology.Concept `json:"group"`
+	Records  int                 `json:"records"`
+	Patients int                 `json:"patients"`
+}
+
+type DiagnosisReport struct {
+	System  models.CodeSystem    `json:"system"`
+	Level   terminology.Level    `json:"level"`
+	Version string               `json:"version"`
+	From    *time.Time           `json:"from,omitempty"`
+	To      *time.Time           `json:"to,omitempty"`
+	Rows    []DiagnosisReportRow `json:"rows"`
+}
+
+func NewTerminologyService(db *gorm.DB, audit *AuditService, terms *terminology.Terminology) *TerminologyService {
+	return &TerminologyService{
+		db:    db,
+		audit: audit,
+		terms: terms,
+	}
+}
+
+// Lookup returns a concept and its ancestors
+func (s *TerminologyService) Lookup(system models.CodeSystem, code string) (*ConceptDetail, error) {
+	if !models.IsValidCodeSystem(system) {
+		return nil, fmt.Errorf("invalid code system")
+	}
+
+	concept, err := s.terms.Lookup(system, code)
+	if err != nil {
+		return nil, fmt.Errorf("code not found")
+	}
+	ancestors, err := s.terms.Ancestors(system, concept.Code)
+	if err != nil {
+		return nil, fmt.Errorf("code not found")
+	}
+
+	return &ConceptDetail{Concept: *concept, Ancestors: ancestors, Version: s.terms.Versions()[system]}, nil
+}
+
+// Search finds concepts by code prefix or by words of their display
+func (s *TerminologyService) Search(query *TerminologySearchQuery) ([]terminology.Match, error) {
+	system := models.CodeSystem(query.System)
+	if system != "" && !models.IsValidCodeSystem(system) {
+		return nil, fmt.Errorf("invalid code system")
+	}
+	if len([]rune(query.Query)) < 2 {
+		return nil, fmt.Errorf("search needs at least two characters")
+	}
+
+	return s.terms.Search(system, query.Query, query.Limit), nil
+}
+
+// GetDiagnosisReport counts coded diagnoses on medical records, rolled up to
+// the requested level of the code hierarchy. Classified records are left
+// out, as they are from default exports. The report holds no patient
+// identifiers.
+func (s *TerminologyService) GetDiagnosisReport(query *DiagnosisReportQuery, requestedByUserID uint, requestedByRole models.UserRole, ipAddress, userAgent string, purpose models.PurposeOfUse) (*DiagnosisReport, error) {
+	audit := s.audit.WithPurpose(purpose)
+
+	if requestedByRole != models.RoleDoctor && requestedByRole != models.RoleBilling {
+		audit.LogUnauthorizedAccess(requestedByUserID, "diagnosis_report", ipAddress, userAgent, "insufficient_role")
+		return nil, fmt.Errorf("insufficient permissions to run diagnosis reports")
+	}
+
+	system := models.CodeSystem(query.System)
+	if !models.IsValidCodeSystem(system) {
+		return nil, fmt.Errorf("invalid code system")
+	}
+	level := terminology.Level(query.Level)
+	if level == "" {
+		level = terminology.LevelCategory
+	}
+	if !terminology.IsValidLevel(level) {
+		return nil, fmt.Errorf("invalid report level")
+	}
+
+	db := s.db.Table("record_diagnoses").
+		Select("record_diagnoses.code, record_diagnoses.record_id, record_diagnoses.patient_id").
+		Joins("JOIN medical_records ON medical_records.id = record_diagnoses.record_id AND medical_records.deleted_at IS NULL").
+		Where("record_diagnoses.code_system = ? AND medical_records.sensitivity = ?", system, models.SensitivityNormal)
+	if query.From != nil {
+		db = db.Where("medical_records.created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("medical_records.created_at <= ?", *query.To)
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run diagnosis report: %w", err)
+	}
+	defer rows.Close()
+
+	type tally struct {
+		group    terminology.Concept
+		records  map[uint]bool
+		patients map[uint]bool
+	}
+	tallies := make(map[string]*tally)
+	groupsByCode := make(map[string][]terminology.Concept)
+	for rows.Next() {
+		var code string
+		var recordID, patientID uint
+		if err := rows.Scan(&code, &recordID, &patientID); err != nil {
+			return nil, fmt.Errorf("failed to run diagnosis report: %w", err)
+		}
+
+		groups, ok := groupsByCode[code]
+		if !ok {
+			groups = s.terms.Groups(system, code, level)
+			groupsByCode[code] = groups
+		}
+		for _, group := range groups {
+			t, ok := tallies[group.Code]
+			if !ok {
+				t = &tally{group: group, records: make(map[uint]bool), patients: make(map[uint]bool)}
+				tallies[group.Code] = t
+			}
+			t.records[recordID] = true
+			t.patients[patientID] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to run diagnosis report: %w", err)
+	}
+
+	report := &DiagnosisReport{
+		System:  system,
+		Level:   level,
+		Version: s.terms.Versions()[system],
+		From:    query.From,
+		To:      query.To,
+		Rows:    make([]DiagnosisReportRow, 0, len(tallies)),
+	}
+	for _, t := range tallies {
+		report.Rows = append(report.Rows, DiagnosisReportRow{Group: t.group, Records: len(t.records), Patients: len(t.patients)})
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].Records != report.Rows[j].Records {
+			return report.Rows[i].Records > report.Rows[j].Records
+		}
+		return report.Rows[i].Group.Code < report.Rows[j].Group.Code
+	})
+
+	audit.LogUserAction(requestedByUserID, models.ActionView, fmt.Sprintf("diagnosis_report:%s:%s", system, level), ipAddress, userAgent, true, "aggregate_report")
+
+	return report, nil
+}
+
+// codeDiagnoses validates diagnosis codes against the terminology and
+// returns them ready to attach to a record. The first diagnosis is primary
+// unless another is marked.
+func codeDiagnoses(terms *terminology.Terminology, patientID uint, inputs []DiagnosisInput) ([]models.RecordDiagnosis, error) {
+	if len(inputs) > maxDiagnosesPerRecord {
+		return nil, fmt.Errorf("a record may have at most %d coded diagnoses", maxDiagnosesPerRecord)
+	}
+
+	diagnoses := make([]models.RecordDiagnosis, 0, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	primary := -1
+	for i, input := range inputs {
+		if !models.IsValidCodeSystem(input.System) {
+			return nil, fmt.Errorf("invalid code system %q", input.System)
+		}
+		concept, err := terms.Lookup(input.System, input.Code)
+		if err != nil {
+			return nil, fmt.Errorf("unknown %s code %q", input.System, input.Code)
+		}
+
+		key := string(concept.System) + "|" + concept.Code
+		if seen[key] {
+			return nil, fmt.Errorf("diagnosis %s %s is listed twice", concept.System, concept.Code)
+		}
+		seen[key] = true
+
+		if input.Primary {
+			if primary >= 0 {
+				return nil, fmt.Errorf("only one diagnosis may be primary")
+			}
+			primary = i
+		}
+
+		diagnoses = append(diagnoses, models.RecordDiagnosis{
+			PatientID: patientID,
+			System:    concept.System,
+			Code:      concept.Code,
+			Display:   concept.Display,
+			Position:  i,
+		})
+	}
+
+	if len(diagnoses) > 0 {
+		if primary < 0 {
+			primary = 0
+		}
+		diagnoses[primary].Primary = true
+	}
+	return diagnoses, nil
+}
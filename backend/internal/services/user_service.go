@@ -22,12 +22,16 @@ type CreateUserRequest struct {
 	Password string           `json:"password" binding:"required,min=8"`
 	Name     string           `json:"name" binding:"required"`
 	Role     models.UserRole  `json:"role" binding:"required"`
+	Resident bool             `json:"resident"`
+	// PatientID links a patient portal account to its chart
+	PatientID *uint `json:"patient_id,omitempty"`
 }
 
 type UpdateUserRequest struct {
-	Name   *string          `json:"name,omitempty"`
-	Role   *models.UserRole `json:"role,omitempty"`
-	Active *bool            `json:"active,omitempty"`
+	Name     *string          `json:"name,omitempty"`
+	Role     *models.UserRole `json:"role,omitempty"`
+	Active   *bool            `json:"active,omitempty"`
+	Resident *bool            `json:"resident,omitempty"`
 }
 
 type ChangePasswordRequest struct {
@@ -38,6 +42,7 @@ type ChangePasswordRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	Purpose  string `json:"purpose,omitempty"`
 }
 
 func NewUserService(db *gorm.DB, jwtService *auth.JWTService, audit *AuditService) *UserService {
@@ -50,6 +55,11 @@ func NewUserService(db *gorm.DB, jwtService *auth.JWTService, audit *AuditServic
 
 // Login authenticates a user and returns JWT tokens
 func (s *UserService) Login(req *LoginRequest, ipAddress, userAgent string) (*auth.AuthResponse, error) {
+	purpose, err := models.ParsePurposeOfUse(req.Purpose)
+	if err != nil {
+		return nil, err
+	}
+
 	var user models.User
 	
 	// Find user by email
@@ -72,7 +82,7 @@ func (s *UserService) Login(req *LoginRequest, ipAddress, userAgent string) (*au
 	}
 
 	// Generate tokens
-	tokens, err := s.jwtService.GenerateTokens(&user)
+	tokens, err := s.jwtService.GenerateTokens(&user, purpose)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -82,7 +92,7 @@ func (s *UserService) Login(req *LoginRequest, ipAddress, userAgent string) (*au
 	s.db.Save(&user)
 
 	// Log successful login
-	s.audit.LogUserAction(user.ID, models.ActionLogin, "authentication", ipAddress, userAgent, true, "")
+	s.audit.WithPurpose(purpose).LogUserAction(user.ID, models.ActionLogin, "authentication", ipAddress, userAgent, true, "")
 
 	// Create user session
 	sessionID := fmt.Sprintf("session_%d_%d", user.ID, time.Now().Unix())
@@ -119,6 +129,10 @@ func (s *UserService) CreateUser(req *CreateUserRequest, createdByUserID uint) (
 		return nil, fmt.Errorf("password validation failed: %w", err)
 	}
 
+	if err := s.validatePortalLink(req.Role, req.PatientID); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	var existingUser models.User
 	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
@@ -138,6 +152,10 @@ func (s *UserService) CreateUser(req *CreateUserRequest, createdByUserID uint) (
 		Name:     req.Name,
 		Role:     req.Role,
 		Active:   true,
+		Resident: req.Resident && req.Role == models.RoleDoctor,
+	}
+	if req.Role == models.RolePatient {
+		user.PatientID = req.PatientID
 	}
 
 	if err := s.db.Create(&user).Error; err != nil {
@@ -184,7 +202,7 @@ func (s *UserService) GetAllUsers(requestedByRole models.UserRole, page, limit i
 
 	// Get paginated users
 	offset := (page - 1) * limit
-	if err := s.db.Select("id, email, name, role, active, last_login, created_at, updated_at").
+	if err := s.db.Select("id, email, name, role, active, patient_id, last_login, created_at, updated_at").
 		Offset(offset).Limit(limit).Find(&users).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to retrieve users: %w", err)
 	}
@@ -207,8 +225,8 @@ func (s *UserService) UpdateUser(userID uint, req *UpdateUserRequest, updatedByU
 	} else if userID == updatedByUserID {
 		canUpdate = true // Users can update their own profile (limited fields)
 		// Non-admins can only update their name
-		if req.Role != nil || req.Active != nil {
-			return nil, fmt.Errorf("insufficient permissions to modify role or active status")
+		if req.Role != nil || req.Active != nil || req.Resident != nil {
+			return nil, fmt.Errorf("insufficient permissions to modify role, active or resident status")
 		}
 	}
 
@@ -216,6 +234,12 @@ func (s *UserService) UpdateUser(userID uint, req *UpdateUserRequest, updatedByU
 		return nil, fmt.Errorf("insufficient permissions to update user")
 	}
 
+	// A portal account is tied to one chart; turning it into a staff account
+	// or the reverse would carry that link across
+	if req.Role != nil && (*req.Role == models.RolePatient) != user.IsPatient() {
+		return nil, fmt.Errorf("patient portal accounts cannot change to or from a staff role")
+	}
+
 	// Update fields
 	updates := make(map[string]interface{})
 	if req.Name != nil {
@@ -224,6 +248,9 @@ func (s *UserService) UpdateUser(userID uint, req *UpdateUserRequest, updatedByU
 	if req.Role != nil && updatedByRole == models.RoleAdmin {
 		updates["role"] = *req.Role
 	}
+	if req.Resident != nil && updatedByRole == models.RoleAdmin {
+		updates["resident"] = *req.Resident
+	}
 	if req.Active != nil && updatedByRole == models.RoleAdmin {
 		updates["active"] = *req.Active
 		
@@ -359,6 +386,16 @@ func (s *UserService) ValidateUserPermissions(userID uint, action string, resour
 			"records":  {"read", "update"},
 			"audit":    {"read_own"},
 		},
+		models.RoleFrontDesk: {
+			"patients": {"read"},
+		},
+		models.RoleBilling: {
+			"patients": {"read"},
+			"records":  {"read"},
+		},
+		models.RolePatient: {
+			"portal": {"read"},
+		},
 	}
 
 	rolePermissions, roleExists := permissions[user.Role]
@@ -378,4 +415,33 @@ func (s *UserService) ValidateUserPermissions(userID uint, action string, resour
 	}
 
 	return false, nil
-}
\ No newline at end of file
+}
+
+// validatePortalLink checks that only patient portal accounts are linked to a
+// chart, and that a chart has at most one active portal account. A portal
+// account without a chart of its own can still act as a proxy.
+func (s *UserService) validatePortalLink(role models.UserRole, patientID *uint) error {
+	if role != models.RolePatient {
+		if patientID != nil {
+			return fmt.Errorf("only patient portal accounts can be linked to a patient")
+		}
+		return nil
+	}
+	if patientID == nil {
+		return nil
+	}
+
+	var patient models.Patient
+	if err := s.db.Where("id = ?", *patientID).First(&patient).Error; err != nil {
+		return fmt.Errorf("patient not found")
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.User{}).Where("patient_id = ? AND active = ?", *patientID, true).Count(&existing).Error; err != nil {
+		return fmt.Errorf("failed to check portal accounts: %w", err)
+	}
+	if existing > 0 {
+		return fmt.Errorf("patient already has an active portal account")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Package storage keeps attachment blobs outside the database. Blobs are
+// written already encrypted; a store only has to keep bytes under a key.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"healthsecure/configs"
+)
+
+// ErrNotFound is returned when no blob is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a flat key-value blob store
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// keyPattern keeps keys safe as both file paths and URL paths: slash
+// separated segments of lower case letters, digits, dots and dashes
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*(/[a-z0-9][a-z0-9.-]*)*$`)
+
+// ValidKey reports whether key may be used with any store
+func ValidKey(key string) bool {
+	return len(key) <= 512 && keyPattern.MatchString(key)
+}
+
+func checkKey(key string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
+
+// Open returns the store selected by the configuration
+func Open(config configs.StorageConfig) (Store, error) {
+	switch config.Backend {
+	case "local":
+		return NewLocalStore(config.LocalPath)
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:  config.S3Endpoint,
+			Region:    config.S3Region,
+			Bucket:    config.S3Bucket,
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+		})
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", config.Backend)
+}
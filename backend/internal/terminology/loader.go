@@ -0,0 +1,236 @@
+package terminology
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"healthsecure/internal/models"
+)
+
+// Load imports ICD-10-CM and SNOMED CT from local files. An empty path loads
+// the small built-in subset for that code system instead.
+func Load(icd10Path, snomedPath string) (*Terminology, error) {
+	icd10 := DefaultICD10CM()
+	if icd10Path != "" {
+		loaded, err := LoadICD10CM(icd10Path)
+		if err != nil {
+			return nil, err
+		}
+		icd10 = loaded
+	}
+
+	snomed := DefaultSNOMED()
+	if snomedPath != "" {
+		loaded, err := LoadSNOMED(snomedPath)
+		if err != nil {
+			return nil, err
+		}
+		snomed = loaded
+	}
+
+	return New(icd10, snomed), nil
+}
+
+// LoadICD10CM reads a CMS ICD-10-CM release file. Both the order file
+// (icd10cm_order_YYYY.txt), which includes the non-billable header codes, and
+// the codes file (icd10cm_codes_YYYY.txt), which lists billable codes only,
+// are accepted. The file name is used as the version.
+func LoadICD10CM(path string) (*CodeSystem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ICD-10-CM file: %w", err)
+	}
+	defer file.Close()
+
+	cs := newCodeSystem(models.CodeSystemICD10CM, filepath.Base(path))
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var concept Concept
+		if isOrderLine(line) {
+			// Fixed width: order number, code, header flag, short and long
+			// descriptions
+			concept = Concept{
+				Code:     strings.TrimSpace(line[6:14]),
+				Billable: line[14] == '1',
+				Display:  strings.TrimSpace(line[16:]),
+			}
+			if len(line) > 77 {
+				concept.Display = strings.TrimSpace(line[77:])
+			}
+		} else {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("ICD-10-CM line %d has no description", lineNumber)
+			}
+			concept = Concept{
+				Code:     fields[0],
+				Billable: true,
+				Display:  strings.Join(fields[1:], " "),
+			}
+		}
+
+		concept.Code = Normalize(models.CodeSystemICD10CM, concept.Code)
+		if !IsWellFormed(models.CodeSystemICD10CM, concept.Code) {
+			return nil, fmt.Errorf("ICD-10-CM line %d has invalid code %q", lineNumber, concept.Code)
+		}
+		cs.add(concept)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ICD-10-CM file: %w", err)
+	}
+	if cs.Len() == 0 {
+		return nil, fmt.Errorf("ICD-10-CM file %s has no codes", path)
+	}
+
+	return cs, nil
+}
+
+func isOrderLine(line string) bool {
+	if len(line) < 17 || line[5] != ' ' || line[13] != ' ' || line[15] != ' ' {
+		return false
+	}
+	for i := 0; i < 5; i++ {
+		if line[i] < '0' || line[i] > '9' {
+			return false
+		}
+	}
+	return line[14] == '0' || line[14] == '1'
+}
+
+// LoadSNOMED reads a SNOMED CT subset as tab separated concept id, preferred
+// term and a comma separated list of is-a parent ids. A header row is
+// skipped. Parents outside the subset are allowed but the root concept is
+// the only one the hierarchy can end at.
+func LoadSNOMED(path string) (*CodeSystem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SNOMED file: %w", err)
+	}
+	defer file.Close()
+
+	cs := newCodeSystem(models.CodeSystemSNOMED, filepath.Base(path))
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if lineNumber == 1 && !snomedPattern.MatchString(strings.TrimSpace(fields[0])) {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("SNOMED line %d needs a concept id and term", lineNumber)
+		}
+
+		concept := Concept{Code: strings.TrimSpace(fields[0]), Display: strings.TrimSpace(fields[1])}
+		if !IsWellFormed(models.CodeSystemSNOMED, concept.Code) {
+			return nil, fmt.Errorf("SNOMED line %d has invalid concept id %q", lineNumber, concept.Code)
+		}
+		if len(fields) > 2 {
+			for _, parent := range strings.Split(fields[2], ",") {
+				parent = strings.TrimSpace(parent)
+				if parent == "" {
+					continue
+				}
+				if !IsWellFormed(models.CodeSystemSNOMED, parent) {
+					return nil, fmt.Errorf("SNOMED line %d has invalid parent id %q", lineNumber, parent)
+				}
+				concept.Parents = append(concept.Parents, parent)
+			}
+			sort.Strings(concept.Parents)
+		}
+		cs.add(concept)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read SNOMED file: %w", err)
+	}
+	if cs.Len() == 0 {
+		return nil, fmt.Errorf("SNOMED file %s has no concepts", path)
+	}
+
+	return cs, nil
+}
+
+// DefaultICD10CM is a starter subset of common ICD-10-CM codes. Production
+// sites should import the CMS release through TERMINOLOGY_ICD10CM_PATH.
+func DefaultICD10CM() *CodeSystem {
+	cs := newCodeSystem(models.CodeSystemICD10CM, "builtin-1")
+	for _, concept := range []Concept{
+		{Code: "E11", Display: "Type 2 diabetes mellitus"},
+		{Code: "E11.6", Display: "Type 2 diabetes mellitus with other specified complications"},
+		{Code: "E11.65", Display: "Type 2 diabetes mellitus with hyperglycemia", Billable: true},
+		{Code: "E11.9", Display: "Type 2 diabetes mellitus without complications", Billable: true},
+		{Code: "E78", Display: "Disorders of lipoprotein metabolism and other lipidemias"},
+		{Code: "E78.5", Display: "Hyperlipidemia, unspecified", Billable: true},
+		{Code: "F10", Display: "Alcohol related disorders"},
+		{Code: "F10.2", Display: "Alcohol dependence"},
+		{Code: "F10.20", Display: "Alcohol dependence, uncomplicated", Billable: true},
+		{Code: "F32", Display: "Major depressive disorder, single episode"},
+		{Code: "F32.9", Display: "Major depressive disorder, single episode, unspecified", Billable: true},
+		{Code: "I10", Display: "Essential (primary) hypertension", Billable: true},
+		{Code: "I21", Display: "Acute myocardial infarction"},
+		{Code: "I21.9", Display: "Acute myocardial infarction, unspecified", Billable: true},
+		{Code: "I48", Display: "Atrial fibrillation and flutter"},
+		{Code: "I48.9", Display: "Unspecified atrial fibrillation and atrial flutter"},
+		{Code: "I48.91", Display: "Unspecified atrial fibrillation", Billable: true},
+		{Code: "J18", Display: "Pneumonia, unspecified organism"},
+		{Code: "J18.9", Display: "Pneumonia, unspecified organism", Billable: true},
+		{Code: "J45", Display: "Asthma"},
+		{Code: "J45.9", Display: "Other and unspecified asthma"},
+		{Code: "J45.90", Display: "Unspecified asthma"},
+		{Code: "J45.909", Display: "Unspecified asthma, uncomplicated", Billable: true},
+		{Code: "R07", Display: "Pain in throat and chest"},
+		{Code: "R07.9", Display: "Chest pain, unspecified", Billable: true},
+		{Code: "U07", Display: "Emergency use of U07"},
+		{Code: "U07.1", Display: "COVID-19", Billable: true},
+		{Code: "Z00", Display: "Encounter for general examination without complaint, suspected or reported diagnosis"},
+		{Code: "Z00.0", Display: "Encounter for general adult medical examination"},
+		{Code: "Z00.00", Display: "Encounter for general adult medical examination without abnormal findings", Billable: true},
+	} {
+		cs.add(concept)
+	}
+	return cs
+}
+
+// DefaultSNOMED is a starter subset of SNOMED CT disorders. Parents are
+// simplified to concepts within the subset. Production sites should import
+// their licensed subset through TERMINOLOGY_SNOMED_PATH.
+func DefaultSNOMED() *CodeSystem {
+	cs := newCodeSystem(models.CodeSystemSNOMED, "builtin-1")
+	for _, concept := range []Concept{
+		{Code: "404684003", Display: "Clinical finding", Parents: []string{snomedRoot}},
+		{Code: "64572001", Display: "Disease", Parents: []string{"404684003"}},
+		{Code: "49601007", Display: "Disorder of cardiovascular system", Parents: []string{"64572001"}},
+		{Code: "50043002", Display: "Disorder of respiratory system", Parents: []string{"64572001"}},
+		{Code: "74732009", Display: "Mental disorder", Parents: []string{"64572001"}},
+		{Code: "73211009", Display: "Diabetes mellitus", Parents: []string{"64572001"}},
+		{Code: "44054006", Display: "Diabetes mellitus type 2", Parents: []string{"73211009"}},
+		{Code: "38341003", Display: "Hypertensive disorder", Parents: []string{"49601007"}},
+		{Code: "59621000", Display: "Essential hypertension", Parents: []string{"38341003"}},
+		{Code: "22298006", Display: "Myocardial infarction", Parents: []string{"49601007"}},
+		{Code: "49436004", Display: "Atrial fibrillation", Parents: []string{"49601007"}},
+		{Code: "195967001", Display: "Asthma", Parents: []string{"50043002"}},
+		{Code: "233604007", Display: "Pneumonia", Parents: []string{"50043002"}},
+		{Code: "35489007", Display: "Depressive disorder", Parents: []string{"74732009"}},
+		{Code: "29857009", Display: "Chest pain", Parents: []string{"404684003"}},
+		{Code: "840539006", Display: "COVID-19", Parents: []string{"233604007"}},
+	} {
+		cs.add(concept)
+	}
+	return cs
+}
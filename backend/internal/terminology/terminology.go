@@ -0,0 +1,503 @@
+package terminology
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"healthsecure/internal/models"
+)
+
+// ErrUnknownCode is returned when a code is not in the loaded code system
+var ErrUnknownCode = errors.New("unknown code")
+
+// Level selects how far up the hierarchy codes are rolled up in reports
+type Level string
+
+const (
+	// LevelCode keeps each code on its own
+	LevelCode Level = "code"
+	// LevelCategory groups ICD-10-CM codes by their three character category
+	// and SNOMED concepts by their parents
+	LevelCategory Level = "category"
+	// LevelChapter groups ICD-10-CM codes by chapter and SNOMED concepts by
+	// top level hierarchy
+	LevelChapter Level = "chapter"
+)
+
+func IsValidLevel(level Level) bool {
+	switch level {
+	case LevelCode, LevelCategory, LevelChapter:
+		return true
+	}
+	return false
+}
+
+// Concept is a code with its preferred display and, for SNOMED, its is-a
+// parents. Billable is set on ICD-10-CM codes that are valid for claims.
+type Concept struct {
+	System   models.CodeSystem `json:"system"`
+	Code     string            `json:"code"`
+	Display  string            `json:"display"`
+	Billable bool              `json:"billable,omitempty"`
+	Parents  []string          `json:"parents,omitempty"`
+}
+
+// CodeSystem holds one imported terminology in memory
+type CodeSystem struct {
+	System   models.CodeSystem
+	Version  string
+	concepts map[string]*Concept
+	codes    []string
+	// search tokens of each concept's display, in codes order
+	tokens [][]string
+}
+
+func newCodeSystem(system models.CodeSystem, version string) *CodeSystem {
+	return &CodeSystem{System: system, Version: version, concepts: make(map[string]*Concept)}
+}
+
+func (cs *CodeSystem) add(concept Concept) {
+	concept.System = cs.System
+	cs.concepts[concept.Code] = &concept
+}
+
+// index sorts the codes and tokenizes displays for search once loading is
+// complete
+func (cs *CodeSystem) index() {
+	cs.codes = make([]string, 0, len(cs.concepts))
+	for code := range cs.concepts {
+		cs.codes = append(cs.codes, code)
+	}
+	sort.Strings(cs.codes)
+
+	cs.tokens = make([][]string, len(cs.codes))
+	for i, code := range cs.codes {
+		cs.tokens[i] = tokenize(cs.concepts[code].Display)
+	}
+}
+
+func (cs *CodeSystem) Len() int {
+	return len(cs.concepts)
+}
+
+// Terminology looks up, searches and walks the hierarchy of the loaded code
+// systems. It makes no network calls.
+type Terminology struct {
+	systems map[models.CodeSystem]*CodeSystem
+}
+
+func New(systems ...*CodeSystem) *Terminology {
+	t := &Terminology{systems: make(map[models.CodeSystem]*CodeSystem, len(systems))}
+	for _, cs := range systems {
+		cs.index()
+		t.systems[cs.System] = cs
+	}
+	return t
+}
+
+// Versions reports the version of each loaded code system
+func (t *Terminology) Versions() map[models.CodeSystem]string {
+	versions := make(map[models.CodeSystem]string, len(t.systems))
+	for system, cs := range t.systems {
+		versions[system] = cs.Version
+	}
+	return versions
+}
+
+var icd10Pattern = regexp.MustCompile(`^[A-Z][0-9][0-9A-Z](\.[0-9A-Z]{1,4})?$`)
+var snomedPattern = regexp.MustCompile(`^[1-9][0-9]{5,17}$`)
+
+// Normalize returns code in the canonical form of its system: ICD-10-CM
+// codes are upper case with a dot after the category, SNOMED identifiers are
+// trimmed. It does not check that the code exists.
+func Normalize(system models.CodeSystem, code string) string {
+	code = strings.TrimSpace(code)
+	if system != models.CodeSystemICD10CM {
+		return code
+	}
+
+	code = strings.ToUpper(strings.NewReplacer(".", "", " ", "").Replace(code))
+	if len(code) > 3 {
+		code = code[:3] + "." + code[3:]
+	}
+	return code
+}
+
+// IsWellFormed reports whether code has the shape of a code in system. SNOMED
+// identifiers must carry a valid Verhoeff check digit and a concept partition.
+func IsWellFormed(system models.CodeSystem, code string) bool {
+	switch system {
+	case models.CodeSystemICD10CM:
+		return icd10Pattern.MatchString(code)
+	case models.CodeSystemSNOMED:
+		if !snomedPattern.MatchString(code) || !verhoeffValid(code) {
+			return false
+		}
+		partition := code[len(code)-3 : len(code)-1]
+		return partition == "00" || partition == "10"
+	}
+	return false
+}
+
+// Lookup returns the concept for code, which is normalized first
+func (t *Terminology) Lookup(system models.CodeSystem, code string) (*Concept, error) {
+	cs, ok := t.systems[system]
+	if !ok {
+		return nil, ErrUnknownCode
+	}
+	concept, ok := cs.concepts[Normalize(system, code)]
+	if !ok {
+		return nil, ErrUnknownCode
+	}
+	return concept, nil
+}
+
+// Match is a search result ranked by Score, higher first
+type Match struct {
+	Concept
+	Score int `json:"score"`
+}
+
+// Search finds concepts whose code starts with query, or whose display
+// matches every word of query by prefix or, for words of four letters or
+// more, within a small edit distance. An empty system searches every loaded
+// code system.
+func (t *Terminology) Search(system models.CodeSystem, query string, limit int) []Match {
+	query = strings.TrimSpace(query)
+	if query == "" || limit <= 0 {
+		return []Match{}
+	}
+
+	words := tokenize(query)
+	matches := make([]Match, 0)
+	for _, cs := range t.systems {
+		if system != "" && cs.System != system {
+			continue
+		}
+
+		codePrefix := Normalize(cs.System, query)
+		for i, code := range cs.codes {
+			score := 0
+			switch {
+			case code == codePrefix:
+				score = 1000
+			case strings.HasPrefix(code, codePrefix):
+				score = 900 - (len(code) - len(codePrefix))
+			default:
+				score = matchWords(words, cs.tokens[i])
+			}
+			if score > 0 {
+				matches = append(matches, Match{Concept: *cs.concepts[code], Score: score})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if len(matches[i].Code) != len(matches[j].Code) {
+			return len(matches[i].Code) < len(matches[j].Code)
+		}
+		return matches[i].Code < matches[j].Code
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// matchWords scores a display against the query words. Every word must match
+// some token; exact matches score highest and fuzzy matches lowest.
+func matchWords(words, tokens []string) int {
+	if len(words) == 0 {
+		return 0
+	}
+
+	total := 0
+	for _, word := range words {
+		best := 0
+		for _, token := range tokens {
+			score := 0
+			switch {
+			case token == word:
+				score = 100
+			case strings.HasPrefix(token, word):
+				score = 80
+			case len(word) >= 4 && withinDistance(word, token, maxDistance(word)):
+				score = 50
+			}
+			if score > best {
+				best = score
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	// Prefer concise displays when the query matches several
+	return total/len(words) - len(tokens)
+}
+
+func maxDistance(word string) int {
+	if len(word) >= 8 {
+		return 2
+	}
+	return 1
+}
+
+// withinDistance reports whether the Levenshtein distance between a and b is
+// at most max
+func withinDistance(a, b string, max int) bool {
+	if diff := len(a) - len(b); diff > max || -diff > max {
+		return false
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if current[j] < rowMin {
+				rowMin = current[j]
+			}
+		}
+		if rowMin > max {
+			return false
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)] <= max
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+}
+
+// Ancestors returns the concepts above code, nearest first. ICD-10-CM codes
+// walk up through their shorter codes to the category and then the chapter;
+// SNOMED concepts follow every is-a parent.
+func (t *Terminology) Ancestors(system models.CodeSystem, code string) ([]Concept, error) {
+	concept, err := t.Lookup(system, code)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors := make([]Concept, 0)
+	switch system {
+	case models.CodeSystemICD10CM:
+		cs := t.systems[system]
+		stripped := strings.Replace(concept.Code, ".", "", 1)
+		for length := len(stripped) - 1; length >= 3; length-- {
+			if parent, ok := cs.concepts[Normalize(system, stripped[:length])]; ok {
+				ancestors = append(ancestors, *parent)
+			}
+		}
+		if chapter, ok := icd10Chapter(concept.Code); ok {
+			ancestors = append(ancestors, chapter)
+		}
+	case models.CodeSystemSNOMED:
+		cs := t.systems[system]
+		seen := map[string]bool{concept.Code: true}
+		queue := append([]string{}, concept.Parents...)
+		for len(queue) > 0 {
+			code := queue[0]
+			queue = queue[1:]
+			if seen[code] {
+				continue
+			}
+			seen[code] = true
+			if parent, ok := cs.concepts[code]; ok {
+				ancestors = append(ancestors, *parent)
+				queue = append(queue, parent.Parents...)
+			}
+		}
+	}
+	return ancestors, nil
+}
+
+// Groups returns the concepts code rolls up to at level. A SNOMED concept
+// with several parents belongs to several groups. Codes not in the loaded
+// code system are grouped on their own.
+func (t *Terminology) Groups(system models.CodeSystem, code string, level Level) []Concept {
+	concept, err := t.Lookup(system, code)
+	if err != nil {
+		return []Concept{{System: system, Code: Normalize(system, code)}}
+	}
+
+	switch {
+	case level == LevelCode:
+		return []Concept{*concept}
+	case system == models.CodeSystemICD10CM && level == LevelCategory:
+		category := concept.Code
+		if len(category) > 3 {
+			category = category[:3]
+		}
+		if parent, ok := t.systems[system].concepts[category]; ok {
+			return []Concept{*parent}
+		}
+		return []Concept{{System: system, Code: category}}
+	case system == models.CodeSystemICD10CM && level == LevelChapter:
+		if chapter, ok := icd10Chapter(concept.Code); ok {
+			return []Concept{chapter}
+		}
+	case system == models.CodeSystemSNOMED && level == LevelCategory:
+		if len(concept.Parents) == 0 {
+			return []Concept{*concept}
+		}
+		groups := make([]Concept, 0, len(concept.Parents))
+		for _, parent := range concept.Parents {
+			if p, ok := t.systems[system].concepts[parent]; ok {
+				groups = append(groups, *p)
+			} else {
+				groups = append(groups, Concept{System: system, Code: parent})
+			}
+		}
+		return groups
+	case system == models.CodeSystemSNOMED && level == LevelChapter:
+		return t.snomedTopLevel(concept)
+	}
+	return []Concept{*concept}
+}
+
+// snomedTopLevel returns the ancestors of concept that sit directly under the
+// root, or the concept itself if it is top level
+func (t *Terminology) snomedTopLevel(concept *Concept) []Concept {
+	cs := t.systems[models.CodeSystemSNOMED]
+	if len(concept.Parents) == 0 || (len(concept.Parents) == 1 && concept.Parents[0] == snomedRoot) {
+		return []Concept{*concept}
+	}
+
+	tops := make([]Concept, 0)
+	seen := make(map[string]bool)
+	var walk func(c *Concept)
+	walk = func(c *Concept) {
+		if seen[c.Code] {
+			return
+		}
+		seen[c.Code] = true
+		for _, parent := range c.Parents {
+			if parent == snomedRoot {
+				tops = append(tops, *c)
+				return
+			}
+		}
+		for _, parent := range c.Parents {
+			if p, ok := cs.concepts[parent]; ok {
+				walk(p)
+			}
+		}
+	}
+	walk(concept)
+
+	if len(tops) == 0 {
+		return []Concept{*concept}
+	}
+	sort.Slice(tops, func(i, j int) bool { return tops[i].Code < tops[j].Code })
+	return tops
+}
+
+// snomedRoot is the SNOMED CT root concept, which is never a group itself
+const snomedRoot = "138875005"
+
+type chapter struct {
+	first, last string
+	display     string
+}
+
+// icd10Chapters are the ICD-10-CM chapters by category range
+var icd10Chapters = []chapter{
+	{"A00", "B99", "Certain infectious and parasitic diseases"},
+	{"C00", "D49", "Neoplasms"},
+	{"D50", "D89", "Diseases of the blood and blood-forming organs and certain disorders involving the immune mechanism"},
+	{"E00", "E89", "Endocrine, nutritional and metabolic diseases"},
+	{"F01", "F99", "Mental, behavioral and neurodevelopmental disorders"},
+	{"G00", "G99", "Diseases of the nervous system"},
+	{"H00", "H59", "Diseases of the eye and adnexa"},
+	{"H60", "H95", "Diseases of the ear and mastoid process"},
+	{"I00", "I99", "Diseases of the circulatory system"},
+	{"J00", "J99", "Diseases of the respiratory system"},
+	{"K00", "K95", "Diseases of the digestive system"},
+	{"L00", "L99", "Diseases of the skin and subcutaneous tissue"},
+	{"M00", "M99", "Diseases of the musculoskeletal system and connective tissue"},
+	{"N00", "N99", "Diseases of the genitourinary system"},
+	{"O00", "O9A", "Pregnancy, childbirth and the puerperium"},
+	{"P00", "P96", "Certain conditions originating in the perinatal period"},
+	{"Q00", "Q99", "Congenital malformations, deformations and chromosomal abnormalities"},
+	{"R00", "R99", "Symptoms, signs and abnormal clinical and laboratory findings, not elsewhere classified"},
+	{"S00", "T88", "Injury, poisoning and certain other consequences of external causes"},
+	{"U00", "U85", "Codes for special purposes"},
+	{"V00", "Y99", "External causes of morbidity"},
+	{"Z00", "Z99", "Factors influencing health status and contact with health services"},
+}
+
+func icd10Chapter(code string) (Concept, bool) {
+	if len(code) < 3 {
+		return Concept{}, false
+	}
+	category := code[:3]
+	for _, ch := range icd10Chapters {
+		if category >= ch.first && category <= ch.last {
+			return Concept{System: models.CodeSystemICD10CM, Code: ch.first + "-" + ch.last, Display: ch.display}, true
+		}
+	}
+	return Concept{}, false
+}
+
+var verhoeffD = [10][10]int{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+	{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+	{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+	{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+	{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+	{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+	{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+	{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+	{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+	{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+}
+
+var verhoeffP = [8][10]int{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+	{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+	{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+	{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+	{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+	{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+	{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+	{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+}
+
+// verhoeffValid checks the Verhoeff check digit SNOMED CT identifiers end with
+func verhoeffValid(digits string) bool {
+	c := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		c = verhoeffD[c][verhoeffP[i%8][digit]]
+	}
+	return c == 0
+}
@@ -0,0 +1,130 @@
+package terminology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"healthsecure/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAndValidate(t *testing.T) {
+	assert.Equal(t, "E11.65", Normalize(models.CodeSystemICD10CM, " e1165 "))
+	assert.Equal(t, "I10", Normalize(models.CodeSystemICD10CM, "I10"))
+	assert.True(t, IsWellFormed(models.CodeSystemICD10CM, "J45.909"))
+	assert.False(t, IsWellFormed(models.CodeSystemICD10CM, "J4"))
+
+	assert.True(t, IsWellFormed(models.CodeSystemSNOMED, "44054006"))
+	assert.False(t, IsWellFormed(models.CodeSystemSNOMED, "44054007"), "bad check digit")
+}
+
+func TestLookupAndSearch(t *testing.T) {
+	terms := New(DefaultICD10CM(), DefaultSNOMED())
+
+	t.Run("Lookup", func(t *testing.T) {
+		concept, err := terms.Lookup(models.CodeSystemICD10CM, "e11.65")
+		require.NoError(t, err)
+		assert.Equal(t, "E11.65", concept.Code)
+		assert.True(t, concept.Billable)
+
+		_, err = terms.Lookup(models.CodeSystemICD10CM, "E11.99")
+		assert.ErrorIs(t, err, ErrUnknownCode)
+	})
+
+	t.Run("CodePrefix", func(t *testing.T) {
+		matches := terms.Search(models.CodeSystemICD10CM, "E11", 10)
+		require.NotEmpty(t, matches)
+		assert.Equal(t, "E11", matches[0].Code)
+		assert.Len(t, matches, 4)
+	})
+
+	t.Run("FuzzyDisplay", func(t *testing.T) {
+		matches := terms.Search("", "diabetis type 2", 10)
+		require.NotEmpty(t, matches)
+		codes := make([]string, 0, len(matches))
+		for _, match := range matches {
+			codes = append(codes, match.Code)
+		}
+		assert.Contains(t, codes, "E11")
+		assert.Contains(t, codes, "44054006")
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		assert.Empty(t, terms.Search("", "fracture", 10))
+	})
+}
+
+func TestHierarchy(t *testing.T) {
+	terms := New(DefaultICD10CM(), DefaultSNOMED())
+
+	t.Run("ICD10Ancestors", func(t *testing.T) {
+		ancestors, err := terms.Ancestors(models.CodeSystemICD10CM, "J45.909")
+		require.NoError(t, err)
+		codes := make([]string, 0, len(ancestors))
+		for _, ancestor := range ancestors {
+			codes = append(codes, ancestor.Code)
+		}
+		assert.Equal(t, []string{"J45.90", "J45.9", "J45", "J00-J99"}, codes)
+	})
+
+	t.Run("ICD10Groups", func(t *testing.T) {
+		assert.Equal(t, "E11", terms.Groups(models.CodeSystemICD10CM, "E11.65", LevelCategory)[0].Code)
+		assert.Equal(t, "E00-E89", terms.Groups(models.CodeSystemICD10CM, "E11.65", LevelChapter)[0].Code)
+		assert.Equal(t, "E11.65", terms.Groups(models.CodeSystemICD10CM, "E11.65", LevelCode)[0].Code)
+	})
+
+	t.Run("SNOMEDGroups", func(t *testing.T) {
+		assert.Equal(t, "73211009", terms.Groups(models.CodeSystemSNOMED, "44054006", LevelCategory)[0].Code)
+		assert.Equal(t, "404684003", terms.Groups(models.CodeSystemSNOMED, "44054006", LevelChapter)[0].Code)
+	})
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	order := "00001 A00     0 Cholera                                                      Cholera\n" +
+		"00002 A000    1 Cholera due to Vibrio cholerae 01, biovar cholerae           Cholera due to Vibrio cholerae 01, biovar cholerae\n"
+	orderPath := filepath.Join(dir, "icd10cm_order_2024.txt")
+	require.NoError(t, os.WriteFile(orderPath, []byte(order), 0600))
+
+	codes := "A000    Cholera due to Vibrio cholerae 01, biovar cholerae\nI10     Essential (primary) hypertension\n"
+	codesPath := filepath.Join(dir, "icd10cm_codes_2024.txt")
+	require.NoError(t, os.WriteFile(codesPath, []byte(codes), 0600))
+
+	snomed := "id\tterm\tparents\n404684003\tClinical finding\t138875005\n64572001\tDisease\t404684003\n"
+	snomedPath := filepath.Join(dir, "snomed_subset.tsv")
+	require.NoError(t, os.WriteFile(snomedPath, []byte(snomed), 0600))
+
+	t.Run("OrderFile", func(t *testing.T) {
+		cs, err := LoadICD10CM(orderPath)
+		require.NoError(t, err)
+		assert.Equal(t, "icd10cm_order_2024.txt", cs.Version)
+		assert.False(t, cs.concepts["A00"].Billable)
+		assert.True(t, cs.concepts["A00.0"].Billable)
+	})
+
+	t.Run("CodesFile", func(t *testing.T) {
+		cs, err := LoadICD10CM(codesPath)
+		require.NoError(t, err)
+		assert.Equal(t, "Essential (primary) hypertension", cs.concepts["I10"].Display)
+	})
+
+	t.Run("SNOMEDSubset", func(t *testing.T) {
+		terms, err := Load("", snomedPath)
+		require.NoError(t, err)
+		concept, err := terms.Lookup(models.CodeSystemSNOMED, "64572001")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"404684003"}, concept.Parents)
+		assert.Equal(t, "builtin-1", terms.Versions()[models.CodeSystemICD10CM])
+	})
+
+	t.Run("InvalidConceptID", func(t *testing.T) {
+		bad := filepath.Join(dir, "bad.tsv")
+		require.NoError(t, os.WriteFile(bad, []byte("64572002\tDisease\n"), 0600))
+		_, err := LoadSNOMED(bad)
+		assert.Error(t, err)
+	})
+}
@@ -10,34 +10,154 @@ CREATE TABLE IF NOT EXISTS users (
     id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
     email VARCHAR(255) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
-    role ENUM('doctor', 'nurse', 'admin') NOT NULL,
+    role ENUM('doctor', 'nurse', 'admin', 'front_desk', 'billing', 'patient') NOT NULL,
     name VARCHAR(255) NOT NULL,
     active BOOLEAN DEFAULT TRUE,
+    resident BOOLEAN DEFAULT FALSE, -- residents' records need an attending's cosignature
+    patient_id INT UNSIGNED NULL, -- chart of a patient portal account
     last_login TIMESTAMP NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
     INDEX idx_users_email (email),
     INDEX idx_users_role (role),
-    INDEX idx_users_active (active)
+    INDEX idx_users_active (active),
+    INDEX idx_users_patient_id (patient_id)
 );
 
 -- Patients table with sensitive data protection
 CREATE TABLE IF NOT EXISTS patients (
     id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    public_id VARCHAR(32), -- opaque random ID accepted in API URLs
     first_name VARCHAR(255) NOT NULL,
     last_name VARCHAR(255) NOT NULL,
     date_of_birth DATE NOT NULL,
-    ssn VARCHAR(11) UNIQUE, -- Sensitive data, doctor access only
-    phone VARCHAR(20),
+    ssn VARCHAR(255), -- AES-GCM encrypted by the application, doctor access only
+    phone VARCHAR(255), -- AES-GCM encrypted by the application
     address TEXT,
     emergency_contact VARCHAR(255),
+    confidential BOOLEAN DEFAULT FALSE, -- VIP / confidential chart
+    employee_user_id INT UNSIGNED NULL, -- Set when the patient is a staff member
+    ssn_index VARCHAR(64) NULL, -- HMAC blind indexes for exact-match search
+    phone_index VARCHAR(64),
+    demographic_index VARCHAR(64), -- date of birth + first and last name
+    merged_into_id INT UNSIGNED NULL, -- set on a duplicate merged into another chart
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL, -- soft delete; rows are only removed by an approved purge
     
+    FOREIGN KEY (employee_user_id) REFERENCES users(id) ON DELETE SET NULL,
+    
+    UNIQUE INDEX idx_patients_public_id (public_id),
     INDEX idx_patients_name (last_name, first_name),
     INDEX idx_patients_dob (date_of_birth),
-    INDEX idx_patients_ssn (ssn)
+    INDEX idx_patients_confidential (confidential),
+    INDEX idx_patients_employee (employee_user_id),
+    UNIQUE INDEX idx_patients_ssn_index (ssn_index),
+    INDEX idx_patients_phone_index (phone_index),
+    INDEX idx_patients_demographic_index (demographic_index),
+    INDEX idx_patients_merged_into_id (merged_into_id),
+    INDEX idx_patients_deleted_at (deleted_at)
+);
+
+-- Portal accounts lose their chart, but keep their login history, when a
+-- patient is purged
+ALTER TABLE users ADD CONSTRAINT fk_users_patient
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE SET NULL;
+
+-- Identifiers a patient is known by: our facility MRNs, other hospitals' MRNs
+-- and insurance member IDs. A system and value identify one patient.
+CREATE TABLE IF NOT EXISTS patient_identifiers (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    type ENUM('mrn', 'external_mrn', 'insurance_member', 'other') NOT NULL,
+    identifier_system VARCHAR(255) NOT NULL, -- issuer URI, urn:healthsecure:mrn:<facility> for our MRNs
+    value VARCHAR(255) NOT NULL, -- AES-GCM encrypted by the application
+    value_index VARCHAR(64) NOT NULL, -- HMAC blind index of system and value
+    created_by INT UNSIGNED,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+
+    INDEX idx_patient_identifiers_patient_id (patient_id),
+    UNIQUE INDEX idx_identifier_value (identifier_system, value_index)
+);
+
+-- Next MRN sequence number per facility
+CREATE TABLE IF NOT EXISTS mrn_sequences (
+    facility VARCHAR(16) PRIMARY KEY,
+    next_value BIGINT UNSIGNED NOT NULL
+);
+
+-- Portal accounts that may view another patient's chart, such as parents and
+-- caregivers. Parent and guardian access narrows and ends with the patient's
+-- age, which is applied when the chart is read.
+CREATE TABLE IF NOT EXISTS patient_proxies (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    proxy_user_id INT UNSIGNED NOT NULL,
+    patient_id INT UNSIGNED NOT NULL,
+    relationship ENUM('parent', 'guardian', 'caregiver', 'healthcare_agent') NOT NULL,
+    scope ENUM('limited', 'full') NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NULL,
+    created_by INT UNSIGNED NOT NULL,
+    revoked_by INT UNSIGNED NULL,
+    revoked_at TIMESTAMP NULL,
+    revoke_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (proxy_user_id) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_patient_proxies_proxy_user_id (proxy_user_id),
+    INDEX idx_patient_proxies_patient_id (patient_id),
+    INDEX idx_patient_proxies_ends_at (ends_at)
+);
+
+-- Visits, admissions and ED stays; clinical data is filed under an encounter
+CREATE TABLE IF NOT EXISTS encounters (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    class ENUM('outpatient', 'inpatient', 'emergency') NOT NULL,
+    status ENUM('active', 'discharged') DEFAULT 'active',
+    admit_at TIMESTAMP NOT NULL,
+    discharge_at TIMESTAMP NULL,
+    facility VARCHAR(100) NOT NULL,
+    department VARCHAR(100) NOT NULL,
+    location VARCHAR(50),
+    attending_id INT UNSIGNED NOT NULL,
+    reason TEXT, -- AES-GCM encrypted by the application
+    created_by INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (attending_id) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_encounters_patient_id (patient_id),
+    INDEX idx_encounter_census (status, facility, department),
+    INDEX idx_encounters_admit_at (admit_at),
+    INDEX idx_encounters_attending_id (attending_id)
+);
+
+-- Admin-managed note templates. Each row is one immutable version; notes keep
+-- the id of the version they were written against.
+CREATE TABLE IF NOT EXISTS note_templates (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    template_key VARCHAR(64) NOT NULL,
+    version INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    sections TEXT NOT NULL, -- JSON section definitions
+    created_by INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP NULL,
+
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    UNIQUE INDEX idx_note_template_version (template_key, version)
 );
 
 -- Medical records with severity-based access control
@@ -45,21 +165,88 @@ CREATE TABLE IF NOT EXISTS medical_records (
     id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
     patient_id INT UNSIGNED NOT NULL,
     doctor_id INT UNSIGNED NOT NULL,
-    diagnosis TEXT,
+    encounter_id INT UNSIGNED NULL,
+    diagnosis TEXT, -- Clinical text is AES-GCM encrypted by the application
     treatment TEXT,
     notes TEXT,
+    template_id INT UNSIGNED NULL,
+    sections TEXT, -- structured template content, encrypted JSON
     medications TEXT,
     severity ENUM('low', 'medium', 'high', 'critical') DEFAULT 'low',
+    sensitivity ENUM('normal', 'restricted', 'very_restricted', 'substance_use',
+                     'mental_health', 'reproductive') DEFAULT 'normal',
+    status ENUM('draft', 'pending_cosign', 'signed') DEFAULT 'draft',
+    signed_by INT UNSIGNED NULL,
+    signed_at TIMESTAMP NULL,
+    content_hash VARCHAR(64), -- SHA-256 of the clinical content at signing
+    cosigner_id INT UNSIGNED NULL,
+    cosigned_by INT UNSIGNED NULL,
+    cosigned_at TIMESTAMP NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL, -- set together with the patient's deleted_at
     
-    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
     FOREIGN KEY (doctor_id) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (encounter_id) REFERENCES encounters(id) ON DELETE SET NULL,
+    FOREIGN KEY (template_id) REFERENCES note_templates(id) ON DELETE RESTRICT,
     
     INDEX idx_medical_patient (patient_id),
+    INDEX idx_medical_encounter (encounter_id),
+    INDEX idx_medical_records_template_id (template_id),
     INDEX idx_medical_doctor (doctor_id),
     INDEX idx_medical_severity (severity),
-    INDEX idx_medical_created (created_at)
+    INDEX idx_medical_sensitivity (sensitivity),
+    INDEX idx_medical_status (status),
+    INDEX idx_medical_cosigner (cosigner_id),
+    INDEX idx_medical_created (created_at),
+    INDEX idx_medical_deleted_at (deleted_at)
+);
+
+-- Coded diagnoses on medical records. Codes are kept in clear for reporting;
+-- the narrative diagnosis stays encrypted.
+CREATE TABLE IF NOT EXISTS record_diagnoses (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    record_id INT UNSIGNED NOT NULL,
+    patient_id INT UNSIGNED NOT NULL,
+    code_system ENUM('icd10cm', 'snomed') NOT NULL,
+    code VARCHAR(20) NOT NULL,
+    display VARCHAR(255) NOT NULL,
+    is_primary BOOLEAN DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (record_id) REFERENCES medical_records(id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+
+    INDEX idx_record_diagnoses_record_id (record_id),
+    INDEX idx_record_diagnoses_patient_id (patient_id),
+    INDEX idx_diagnosis_code (code_system, code)
+);
+
+-- Documents filed on a chart or a medical record. The encrypted blob lives in
+-- the attachment store under storage_key.
+CREATE TABLE IF NOT EXISTS attachments (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    record_id INT UNSIGNED NULL,
+    category ENUM('referral_letter', 'imaging_report', 'lab_report', 'consent_form', 'other') DEFAULT 'other',
+    filename TEXT NOT NULL, -- AES-GCM encrypted by the application
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    uploaded_by INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (record_id) REFERENCES medical_records(id) ON DELETE RESTRICT,
+    FOREIGN KEY (uploaded_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_attachments_patient_id (patient_id),
+    INDEX idx_attachments_record_id (record_id),
+    INDEX idx_attachments_deleted_at (deleted_at)
 );
 
 -- Comprehensive audit logging for HIPAA compliance
@@ -69,25 +256,33 @@ CREATE TABLE IF NOT EXISTS audit_logs (
     patient_id INT UNSIGNED NULL,
     record_id INT UNSIGNED NULL,
     action ENUM('LOGIN', 'LOGOUT', 'VIEW', 'CREATE', 'UPDATE', 'DELETE', 
-                'EMERGENCY_REQUEST', 'EMERGENCY_ACCESS', 'UNAUTHORIZED_ACCESS') NOT NULL,
+                'EMERGENCY_REQUEST', 'EMERGENCY_ACCESS', 'UNAUTHORIZED_ACCESS',
+                'ALERT_OVERRIDE', 'DOWNLOAD', 'EXPORT') NOT NULL,
     resource VARCHAR(255) NOT NULL,
     ip_address VARCHAR(45) NOT NULL, -- IPv6 compatible
     user_agent TEXT,
     emergency_use BOOLEAN DEFAULT FALSE,
+    sensitive_access BOOLEAN DEFAULT FALSE,
+    sensitivity VARCHAR(32),
+    disclosed_fields TEXT,
+    recipient VARCHAR(255), -- outside party an export was sent to
+    purpose VARCHAR(32),
     reason TEXT,
     success BOOLEAN DEFAULT TRUE,
     error_message TEXT,
     timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     
+    -- No foreign keys on patient_id or record_id: audit rows must survive a purge
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT,
-    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE,
-    FOREIGN KEY (record_id) REFERENCES medical_records(id) ON DELETE CASCADE,
     
     INDEX idx_audit_user (user_id),
     INDEX idx_audit_patient (patient_id),
+    INDEX idx_audit_record (record_id),
     INDEX idx_audit_action (action),
     INDEX idx_audit_timestamp (timestamp),
+    INDEX idx_audit_purpose (purpose),
     INDEX idx_audit_emergency (emergency_use),
+    INDEX idx_audit_sensitive (sensitive_access),
     INDEX idx_audit_success (success),
     INDEX idx_audit_ip (ip_address)
 );
@@ -107,7 +302,7 @@ CREATE TABLE IF NOT EXISTS emergency_access (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT,
-    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
     FOREIGN KEY (revoked_by) REFERENCES users(id) ON DELETE SET NULL,
     
     INDEX idx_emergency_user (user_id),
@@ -118,6 +313,354 @@ CREATE TABLE IF NOT EXISTS emergency_access (
     INDEX idx_emergency_created (created_at)
 );
 
+-- Patient consent for disclosure of sensitive record categories (42 CFR Part 2)
+CREATE TABLE IF NOT EXISTS patient_consents (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    category ENUM('restricted', 'very_restricted', 'substance_use',
+                  'mental_health', 'reproductive') NOT NULL,
+    grantee_user_id INT UNSIGNED NULL, -- NULL covers the whole treating team
+    purpose TEXT NOT NULL,
+    recorded_by INT UNSIGNED NOT NULL,
+    expires_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    revoked_by INT UNSIGNED NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (grantee_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (recorded_by) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (revoked_by) REFERENCES users(id) ON DELETE SET NULL,
+
+    INDEX idx_consent_patient (patient_id),
+    INDEX idx_consent_category (category),
+    INDEX idx_consent_grantee (grantee_user_id),
+    INDEX idx_consent_expires (expires_at)
+);
+
+-- Care team assignments used to gate confidential patient charts
+CREATE TABLE IF NOT EXISTS care_team_members (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    user_id INT UNSIGNED NOT NULL,
+    relationship ENUM('attending', 'primary_nurse', 'consultant', 'member') DEFAULT 'member',
+    added_by INT UNSIGNED NOT NULL,
+    ended_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (added_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    UNIQUE INDEX idx_care_team_patient_user (patient_id, user_id),
+    INDEX idx_care_team_user (user_id)
+);
+
+-- Time-bound delegation of a doctor's patient access to a covering colleague
+CREATE TABLE IF NOT EXISTS access_delegations (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    delegator_id INT UNSIGNED NOT NULL,
+    delegate_id INT UNSIGNED NOT NULL,
+    reason TEXT NOT NULL,
+    status ENUM('pending', 'active', 'rejected', 'revoked', 'expired') DEFAULT 'pending',
+    starts_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    reviewed_by INT UNSIGNED NULL,
+    reviewed_at TIMESTAMP NULL,
+    revoked_by INT UNSIGNED NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (delegator_id) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (delegate_id) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (reviewed_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (revoked_by) REFERENCES users(id) ON DELETE SET NULL,
+
+    INDEX idx_delegation_delegator (delegator_id),
+    INDEX idx_delegation_delegate (delegate_id),
+    INDEX idx_delegation_status (status),
+    INDEX idx_delegation_expires (expires_at)
+);
+
+-- Patients a delegation is limited to; none means the delegator's whole panel
+CREATE TABLE IF NOT EXISTS access_delegation_patients (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    delegation_id INT UNSIGNED NOT NULL,
+    patient_id INT UNSIGNED NOT NULL,
+
+    FOREIGN KEY (delegation_id) REFERENCES access_delegations(id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+
+    INDEX idx_delegation_patients_delegation (delegation_id),
+    INDEX idx_delegation_patients_patient (patient_id)
+);
+
+-- Tenant data keys for field encryption, wrapped by the KMS master key
+CREATE TABLE IF NOT EXISTS encryption_data_keys (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    tenant VARCHAR(64) NOT NULL,
+    kind VARCHAR(16) NOT NULL DEFAULT 'data', -- 'data' keys rotate; the 'index' key for blind indexes does not
+    version INT NOT NULL,
+    wrapped_key BLOB NOT NULL,
+    master_key_id VARCHAR(64) NOT NULL,
+    active BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP NULL,
+
+    UNIQUE INDEX idx_data_key_version (tenant, kind, version)
+);
+
+-- Two-admin approval for permanently purging soft-deleted patients
+CREATE TABLE IF NOT EXISTS patient_purge_requests (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL, -- no foreign key: the request outlives the patient
+    reason TEXT NOT NULL,
+    status ENUM('pending', 'completed', 'rejected', 'cancelled') DEFAULT 'pending',
+    requested_by INT UNSIGNED NOT NULL,
+    reviewed_by INT UNSIGNED NULL,
+    reviewed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (requested_by) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (reviewed_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_purge_patient (patient_id),
+    INDEX idx_purge_status (status)
+);
+
+-- Duplicate charts merged into a surviving chart. Patient ids carry no foreign
+-- key: the history outlives a purge of either chart.
+CREATE TABLE IF NOT EXISTS patient_merges (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    survivor_id INT UNSIGNED NOT NULL,
+    merged_id INT UNSIGNED NOT NULL,
+    score INT,
+    reason TEXT NOT NULL,
+    status ENUM('merged', 'undone') DEFAULT 'merged',
+    rows_moved INT DEFAULT 0,
+    merged_by INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    undone_by INT UNSIGNED NULL,
+    undone_at TIMESTAMP NULL,
+    undo_reason TEXT,
+
+    FOREIGN KEY (merged_by) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (undone_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_patient_merges_survivor_id (survivor_id),
+    INDEX idx_patient_merges_merged_id (merged_id),
+    INDEX idx_patient_merges_status (status),
+    INDEX idx_patient_merges_merged_by (merged_by)
+);
+
+-- Rows moved by a merge, so that undo moves back exactly these rows
+CREATE TABLE IF NOT EXISTS patient_merge_rows (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    merge_id INT UNSIGNED NOT NULL,
+    source_table VARCHAR(64) NOT NULL,
+    row_id INT UNSIGNED NOT NULL,
+
+    FOREIGN KEY (merge_id) REFERENCES patient_merges(id) ON DELETE CASCADE,
+
+    INDEX idx_merge_row (merge_id, source_table)
+);
+
+-- Immutable revision history for patients and medical records.
+-- No foreign key on resource_id: it points at either table.
+CREATE TABLE IF NOT EXISTS revisions (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    resource_type VARCHAR(32) NOT NULL,
+    resource_id INT UNSIGNED NOT NULL,
+    version INT NOT NULL,
+    snapshot MEDIUMTEXT NOT NULL, -- encrypted JSON snapshot
+    changed_fields TEXT,
+    author_id INT UNSIGNED NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE RESTRICT,
+
+    UNIQUE INDEX idx_revision_version (resource_type, resource_id, version),
+    INDEX idx_revision_author (author_id),
+    INDEX idx_revision_created (created_at)
+);
+
+-- Signed corrections to locked medical records. Addenda are never edited.
+CREATE TABLE IF NOT EXISTS medical_record_addenda (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    record_id INT UNSIGNED NOT NULL,
+    author_id INT UNSIGNED NOT NULL,
+    content TEXT NOT NULL, -- encrypted
+    reason TEXT,
+    content_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (record_id) REFERENCES medical_records(id) ON DELETE RESTRICT,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_addenda_record (record_id),
+    INDEX idx_addenda_author (author_id)
+);
+
+-- Patient requests to amend a medical record (45 CFR 164.526).
+-- Clinical text columns are encrypted.
+CREATE TABLE IF NOT EXISTS amendment_requests (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    record_id INT UNSIGNED NOT NULL,
+    patient_id INT UNSIGNED NOT NULL,
+    requested_change TEXT NOT NULL,
+    justification TEXT,
+    status ENUM('submitted', 'under_review', 'accepted', 'denied') DEFAULT 'submitted',
+    received_at TIMESTAMP NOT NULL,
+    due_at TIMESTAMP NOT NULL, -- 60 days after receipt, plus one optional 30-day extension
+    extension_reason TEXT,
+    submitted_by INT UNSIGNED NOT NULL,
+    reviewer_id INT UNSIGNED NULL,
+    review_started_at TIMESTAMP NULL,
+    decided_by INT UNSIGNED NULL,
+    decided_at TIMESTAMP NULL,
+    denial_reason TEXT,
+    disagreement_statement TEXT,
+    disagreement_filed_at TIMESTAMP NULL,
+    addendum_id INT UNSIGNED NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (record_id) REFERENCES medical_records(id) ON DELETE RESTRICT,
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (submitted_by) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (addendum_id) REFERENCES medical_record_addenda(id) ON DELETE SET NULL,
+
+    INDEX idx_amendment_record (record_id),
+    INDEX idx_amendment_patient (patient_id),
+    INDEX idx_amendment_status (status),
+    INDEX idx_amendment_due (due_at)
+);
+
+-- Structured medication list; replaces the free-text medical_records.medications
+CREATE TABLE IF NOT EXISTS medication_orders (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    encounter_id INT UNSIGNED NULL,
+    drug TEXT NOT NULL,
+    dose VARCHAR(100) NOT NULL,
+    route VARCHAR(32) NOT NULL,
+    frequency VARCHAR(100) NOT NULL,
+    start_date TIMESTAMP NOT NULL,
+    stop_date TIMESTAMP NULL,
+    status ENUM('active', 'discontinued') DEFAULT 'active',
+    prescriber_id INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL, -- entered in error
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (prescriber_id) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (encounter_id) REFERENCES encounters(id) ON DELETE SET NULL,
+
+    INDEX idx_medication_patient (patient_id),
+    INDEX idx_medication_encounter (encounter_id),
+    INDEX idx_medication_prescriber (prescriber_id),
+    INDEX idx_medication_status (status),
+    INDEX idx_medication_deleted (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS allergies (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    substance TEXT NOT NULL,
+    reaction TEXT,
+    severity ENUM('mild', 'moderate', 'severe', 'life_threatening') NOT NULL,
+    status ENUM('active', 'inactive') DEFAULT 'active',
+    recorded_by INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL, -- entered in error
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (recorded_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_allergy_patient (patient_id),
+    INDEX idx_allergy_status (status),
+    INDEX idx_allergy_deleted (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS problems (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    condition_text TEXT NOT NULL, -- "condition" is reserved in MySQL
+    onset_date TIMESTAMP NULL,
+    status ENUM('active', 'inactive', 'resolved') DEFAULT 'active',
+    resolved_date TIMESTAMP NULL,
+    recorded_by INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL, -- entered in error
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (recorded_by) REFERENCES users(id) ON DELETE RESTRICT,
+
+    INDEX idx_problem_patient (patient_id),
+    INDEX idx_problem_status (status),
+    INDEX idx_problem_deleted (deleted_at)
+);
+
+-- Vital signs and lab results keyed by LOINC code
+CREATE TABLE IF NOT EXISTS observations (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT UNSIGNED NOT NULL,
+    encounter_id INT UNSIGNED NULL,
+    category ENUM('vital_signs', 'laboratory') NOT NULL,
+    code VARCHAR(16) NOT NULL,
+    display VARCHAR(255) NOT NULL,
+    value_quantity DOUBLE NULL,
+    value_string TEXT,
+    unit VARCHAR(32),
+    reference_low DOUBLE NULL,
+    reference_high DOUBLE NULL,
+    critical_low DOUBLE NULL,
+    critical_high DOUBLE NULL,
+    interpretation ENUM('normal', 'low', 'high', 'abnormal', 'critical_low', 'critical_high', 'critical') DEFAULT 'normal',
+    effective_at TIMESTAMP NOT NULL,
+    comment TEXT,
+    recorded_by INT UNSIGNED NOT NULL,
+    ordering_doctor_id INT UNSIGNED NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL, -- entered in error
+
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+    FOREIGN KEY (recorded_by) REFERENCES users(id) ON DELETE RESTRICT,
+    FOREIGN KEY (ordering_doctor_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (encounter_id) REFERENCES encounters(id) ON DELETE SET NULL,
+
+    INDEX idx_observation_trend (patient_id, code, effective_at),
+    INDEX idx_observation_encounter (encounter_id),
+    INDEX idx_observation_interpretation (interpretation),
+    INDEX idx_observation_ordering_doctor (ordering_doctor_id),
+    INDEX idx_observation_deleted (deleted_at)
+);
+
+-- Per-user inbox; messages carry no clinical values
+CREATE TABLE IF NOT EXISTS notifications (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT UNSIGNED NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    severity ENUM('low', 'medium', 'high', 'critical') DEFAULT 'medium',
+    patient_id INT UNSIGNED NULL,
+    resource VARCHAR(255),
+    message VARCHAR(255) NOT NULL,
+    read_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE RESTRICT,
+
+    INDEX idx_notification_user (user_id, read_at),
+    INDEX idx_notification_severity (severity),
+    INDEX idx_notification_created (created_at)
+);
+
 -- Session management for JWT token blacklisting
 CREATE TABLE IF NOT EXISTS blacklisted_tokens (
     id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
@@ -169,7 +712,8 @@ CREATE TABLE IF NOT EXISTS system_settings (
 CREATE TABLE IF NOT EXISTS security_events (
     id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
     event_type ENUM('FAILED_LOGIN', 'SUSPICIOUS_ACTIVITY', 'UNAUTHORIZED_ACCESS', 
-                    'EMERGENCY_ACCESS', 'DATA_BREACH', 'SYSTEM_ALERT') NOT NULL,
+                    'EMERGENCY_ACCESS', 'DATA_BREACH', 'SYSTEM_ALERT',
+                    'SENSITIVE_RECORD_ACCESS', 'CONFIDENTIAL_PATIENT_ACCESS') NOT NULL,
     severity ENUM('LOW', 'MEDIUM', 'HIGH', 'CRITICAL') DEFAULT 'MEDIUM',
     user_id INT UNSIGNED NULL,
     ip_address VARCHAR(45),
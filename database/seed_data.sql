@@ -12,6 +12,8 @@ INSERT INTO users (email, password, role, name, active, last_login) VALUES
 ('admin.davis@hospital.local', '$2a$12$LQv3c1yqBWVHxkd0LHAkCOYz6TtxMQJqhN8/LewdBPj.2CRBPqe5e', 'admin', 'Administrator Jane Davis', TRUE, DATE_SUB(NOW(), INTERVAL 6 HOUR));
 
 -- Insert sample patients (using fake data for testing)
+-- Public IDs are assigned at startup. MRNs are encrypted, so sample patients
+-- get theirs through POST /api/patients/:id/identifiers/mrn.
 INSERT INTO patients (first_name, last_name, date_of_birth, ssn, phone, address, emergency_contact) VALUES
 ('John', 'Doe', '1985-03-15', '123-45-6789', '+1-555-0123', '123 Main St, Anytown, ST 12345', 'Jane Doe (Wife) - +1-555-0124'),
 ('Jane', 'Smith', '1978-07-22', '234-56-7890', '+1-555-0234', '456 Oak Ave, Somewhere, ST 23456', 'Bob Smith (Husband) - +1-555-0235'),
@@ -22,6 +24,19 @@ INSERT INTO patients (first_name, last_name, date_of_birth, ssn, phone, address,
 ('James', 'Miller', '1976-06-18', '789-01-2345', '+1-555-0789', '147 Birch St, Elsewhere, ST 78901', 'Anna Miller (Wife) - +1-555-0790'),
 ('Linda', 'Garcia', '1988-04-25', '890-12-3456', '+1-555-0890', '258 Spruce Ave, Nowhere, ST 89012', 'Carlos Garcia (Husband) - +1-555-0891');
 
+-- Patient portal account for John Doe; portal accounts only see their own chart
+INSERT INTO users (email, password, role, name, active, patient_id) VALUES
+('john.doe@example.com', '$2a$12$LQv3c1yqBWVHxkd0LHAkCOYz6TtxMQJqhN8/LewdBPj.2CRBPqe5e', 'patient', 'John Doe', TRUE, 1);
+
+-- Standard note templates; section definitions are plain JSON
+INSERT INTO note_templates (template_key, version, name, description, sections, created_by) VALUES
+('soap', 1, 'SOAP Note', 'Subjective, objective, assessment and plan',
+ '[{"key":"subjective","title":"Subjective","type":"text","required":true},{"key":"objective","title":"Objective","type":"text","required":true},{"key":"pain_score","title":"Pain Score","type":"number","unit":"/10"},{"key":"assessment","title":"Assessment","type":"text","required":true},{"key":"plan","title":"Plan","type":"text","required":true}]', 1),
+('history_physical', 1, 'History and Physical', 'Admission history and physical examination',
+ '[{"key":"chief_complaint","title":"Chief Complaint","type":"text","required":true},{"key":"hpi","title":"History of Present Illness","type":"text","required":true},{"key":"past_history","title":"Past Medical History","type":"text"},{"key":"social_history","title":"Social History","type":"text"},{"key":"review_of_systems","title":"Review of Systems","type":"text"},{"key":"examination","title":"Physical Examination","type":"text","required":true},{"key":"assessment_plan","title":"Assessment and Plan","type":"text","required":true}]', 1),
+('discharge_summary', 1, 'Discharge Summary', 'Summary of an inpatient stay at discharge',
+ '[{"key":"admission_date","title":"Admission Date","type":"date","required":true},{"key":"discharge_date","title":"Discharge Date","type":"date","required":true},{"key":"hospital_course","title":"Hospital Course","type":"text","required":true},{"key":"condition","title":"Condition at Discharge","type":"choice","options":["Improved","Stable","Unchanged","Deteriorated"],"required":true},{"key":"disposition","title":"Disposition","type":"choice","options":["Home","Home with services","Skilled nursing facility","Rehabilitation","Transfer"],"required":true},{"key":"follow_up","title":"Follow-up","type":"text"},{"key":"pending_results","title":"Results Pending at Discharge","type":"boolean"}]', 1);
+
 -- Insert sample medical records
 INSERT INTO medical_records (patient_id, doctor_id, diagnosis, treatment, notes, medications, severity) VALUES
 -- John Doe's records